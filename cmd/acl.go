@@ -0,0 +1,381 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pltanton/lingti-bot/internal/acl"
+	"github.com/pltanton/lingti-bot/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var aclCmd = &cobra.Command{
+	Use:   "acl",
+	Short: "Manage who may talk to the bot and which tools they get",
+	Long: `Manage the access control list in ~/.lingti.yaml.
+
+Rules allow or deny senders by platform, channel, user, linked identity or
+group; the most specific matching rule decides. Allowed senders hold a role
+(admin, member, guest or a custom one) that limits their tools.
+
+Examples:
+  lingti-bot acl default none
+  lingti-bot acl allow --platform slack --user U123 --role admin
+  lingti-bot acl allow --platform telegram --channel -1001234 --role guest
+  lingti-bot acl deny --platform discord
+  lingti-bot acl group add oncall slack:U123 user-3f9a1c2b7d4e
+  lingti-bot acl check --platform slack --user U123`,
+}
+
+// acl allow/deny/check flags
+var (
+	aclMatch   config.ACLMatch
+	aclRole    string
+	aclComment string
+)
+
+// acl role flags
+var (
+	aclAllowTools string
+	aclDenyTools  string
+)
+
+var aclListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List ACL rules, groups and roles",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		a := cfg.ACL
+		if !a.Enabled() {
+			fmt.Println("Access control is off: everyone may use every tool. Use 'lingti-bot acl allow' or 'lingti-bot acl default' to turn it on.")
+			return nil
+		}
+
+		defaultRole := a.DefaultRole
+		if defaultRole == "" {
+			defaultRole = acl.RoleMember
+		}
+		fmt.Printf("Default role: %s\n", defaultRole)
+
+		if len(a.Rules) > 0 {
+			fmt.Println()
+			fmt.Printf("%-4s  %s\n", "#", "RULE")
+			fmt.Printf("%-4s  %s\n", "-", "----")
+			for i, r := range a.Rules {
+				line := acl.Describe(r)
+				if r.Comment != "" {
+					line += "  # " + r.Comment
+				}
+				fmt.Printf("%-4d  %s\n", i+1, line)
+			}
+		}
+
+		if len(a.Groups) > 0 {
+			fmt.Println()
+			fmt.Printf("%-12s  %s\n", "GROUP", "MEMBERS")
+			fmt.Printf("%-12s  %s\n", "-----", "-------")
+			for _, name := range sortedKeys(a.Groups) {
+				fmt.Printf("%-12s  %s\n", name, strings.Join(a.Groups[name], ", "))
+			}
+		}
+
+		l, err := acl.New(a)
+		if err != nil {
+			return err
+		}
+		fmt.Println()
+		fmt.Printf("%-12s  %-30s  %s\n", "ROLE", "ALLOW_TOOLS", "DENY_TOOLS")
+		fmt.Printf("%-12s  %-30s  %s\n", "----", "-----------", "----------")
+		for _, name := range l.Roles() {
+			p, _ := l.Role(name)
+			allow := strings.Join(p.Allow, ",")
+			if allow == "" {
+				allow = "(all)"
+			}
+			fmt.Printf("%-12s  %-30s  %s\n", name, allow, strings.Join(p.Deny, ","))
+		}
+		return nil
+	},
+}
+
+func newACLRuleCmd(action string) *cobra.Command {
+	return &cobra.Command{
+		Use:   action,
+		Short: fmt.Sprintf("Add a rule that %ss matching senders", action),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if action == "deny" && aclRole != "" {
+				return fmt.Errorf("--role only applies to allow rules")
+			}
+			return updateACL(func(a *config.ACLConfig) (string, error) {
+				rule := config.ACLRule{Action: action, Role: aclRole, Comment: aclComment, Match: aclMatch}
+				for _, r := range a.Rules {
+					if r.Action == rule.Action && r.Match == rule.Match && r.Role == rule.Role {
+						return "", fmt.Errorf("rule %q already exists", acl.Describe(rule))
+					}
+				}
+				a.Rules = append(a.Rules, rule)
+				return "Added rule: " + acl.Describe(rule), nil
+			})
+		},
+	}
+}
+
+var aclRemoveCmd = &cobra.Command{
+	Use:   "remove <number>",
+	Short: "Remove a rule by its number in 'acl list'",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid rule number %q", args[0])
+		}
+		return updateACL(func(a *config.ACLConfig) (string, error) {
+			if n < 1 || n > len(a.Rules) {
+				return "", fmt.Errorf("no rule %d (have %d)", n, len(a.Rules))
+			}
+			removed := a.Rules[n-1]
+			a.Rules = append(a.Rules[:n-1], a.Rules[n:]...)
+			return "Removed rule: " + acl.Describe(removed), nil
+		})
+	},
+}
+
+var aclDefaultCmd = &cobra.Command{
+	Use:   "default <role|none>",
+	Short: "Set the role of senders no rule matches (none refuses them)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateACL(func(a *config.ACLConfig) (string, error) {
+			a.DefaultRole = args[0]
+			return "Default role set to " + args[0], nil
+		})
+	},
+}
+
+var aclGroupCmd = &cobra.Command{
+	Use:   "group",
+	Short: "Manage named groups of users",
+}
+
+var aclGroupAddCmd = &cobra.Command{
+	Use:   "add <group> <member>...",
+	Short: "Add members (platform:userID or a /link identity) to a group",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		return updateACL(func(a *config.ACLConfig) (string, error) {
+			if a.Groups == nil {
+				a.Groups = make(map[string][]string)
+			}
+			added := 0
+			for _, m := range args[1:] {
+				if !strings.Contains(m, ":") && !strings.HasPrefix(m, "user-") {
+					return "", fmt.Errorf("member %q must be platform:userID or an identity (user-...)", m)
+				}
+				if contains(a.Groups[name], m) {
+					continue
+				}
+				a.Groups[name] = append(a.Groups[name], m)
+				added++
+			}
+			return fmt.Sprintf("Added %d member(s) to group %q", added, name), nil
+		})
+	},
+}
+
+var aclGroupRemoveCmd = &cobra.Command{
+	Use:   "remove <group> [member...]",
+	Short: "Remove members from a group, or the whole group",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		return updateACL(func(a *config.ACLConfig) (string, error) {
+			if _, ok := a.Groups[name]; !ok {
+				return "", fmt.Errorf("group %q not found", name)
+			}
+			if len(args) == 1 {
+				delete(a.Groups, name)
+				return fmt.Sprintf("Removed group %q", name), nil
+			}
+			var kept []string
+			for _, m := range a.Groups[name] {
+				if !contains(args[1:], m) {
+					kept = append(kept, m)
+				}
+			}
+			removed := len(a.Groups[name]) - len(kept)
+			a.Groups[name] = kept
+			return fmt.Sprintf("Removed %d member(s) from group %q", removed, name), nil
+		})
+	},
+}
+
+var aclRoleCmd = &cobra.Command{
+	Use:   "role",
+	Short: "Manage roles and their tools",
+}
+
+var aclRoleSetCmd = &cobra.Command{
+	Use:   "set <role>",
+	Short: "Define a role, or override a builtin one",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateACL(func(a *config.ACLConfig) (string, error) {
+			if a.Roles == nil {
+				a.Roles = make(map[string]config.ACLRole)
+			}
+			a.Roles[args[0]] = config.ACLRole{
+				AllowTools: splitList(aclAllowTools),
+				DenyTools:  splitList(aclDenyTools),
+			}
+			return fmt.Sprintf("Role %q saved", args[0]), nil
+		})
+	},
+}
+
+var aclRoleRemoveCmd = &cobra.Command{
+	Use:   "remove <role>",
+	Short: "Remove a custom role, or restore a builtin one",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateACL(func(a *config.ACLConfig) (string, error) {
+			if _, ok := a.Roles[args[0]]; !ok {
+				return "", fmt.Errorf("role %q is not defined in the config", args[0])
+			}
+			delete(a.Roles, args[0])
+			return fmt.Sprintf("Role %q removed", args[0]), nil
+		})
+	},
+}
+
+var aclCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Explain what the ACL decides for a sender",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		if !cfg.ACL.Enabled() {
+			fmt.Println("Access control is off: everyone may use every tool.")
+			return nil
+		}
+		l, err := acl.New(cfg.ACL)
+		if err != nil {
+			return err
+		}
+
+		req := acl.Request{
+			Platform:  aclMatch.Platform,
+			ChannelID: aclMatch.ChannelID,
+			UserID:    aclMatch.UserID,
+			Identity:  aclMatch.Identity,
+		}
+		if req.Identity == "" && req.UserID != "" {
+			if store := loadIdentities(); store != nil {
+				req.Identity = store.Resolve(req.Platform, req.UserID)
+			}
+		}
+		d := l.Check(req)
+		if !d.Allowed {
+			fmt.Printf("Refused: %s\n", d.Reason())
+			return nil
+		}
+		fmt.Printf("Allowed as %s: %s\n", d.Role, d.Reason())
+		if len(d.Tools.Allow) > 0 {
+			fmt.Printf("  Tools: %s\n", strings.Join(d.Tools.Allow, ", "))
+		}
+		if len(d.Tools.Deny) > 0 {
+			fmt.Printf("  Denied tools: %s\n", strings.Join(d.Tools.Deny, ", "))
+		}
+		return nil
+	},
+}
+
+// updateACL loads the config, applies fn to its ACL and, if the result is
+// still a valid ACL, saves it and prints the message fn returned.
+func updateACL(fn func(a *config.ACLConfig) (string, error)) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	msg, err := fn(&cfg.ACL)
+	if err != nil {
+		return err
+	}
+	if _, err := acl.New(cfg.ACL); err != nil {
+		return err
+	}
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	fmt.Println(msg)
+	return nil
+}
+
+// loadACL builds the ACL from the config, or nil if access control is off.
+func loadACL(cfg *config.Config) (*acl.ACL, error) {
+	if cfg == nil || !cfg.ACL.Enabled() {
+		return nil, nil
+	}
+	return acl.New(cfg.ACL)
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func init() {
+	rootCmd.AddCommand(aclCmd)
+	aclAllowCmd := newACLRuleCmd("allow")
+	aclDenyCmd := newACLRuleCmd("deny")
+	aclCmd.AddCommand(aclListCmd, aclAllowCmd, aclDenyCmd, aclRemoveCmd, aclDefaultCmd, aclGroupCmd, aclRoleCmd, aclCheckCmd)
+	aclGroupCmd.AddCommand(aclGroupAddCmd, aclGroupRemoveCmd)
+	aclRoleCmd.AddCommand(aclRoleSetCmd, aclRoleRemoveCmd)
+
+	// acl allow/deny/check flags
+	for _, c := range []*cobra.Command{aclAllowCmd, aclDenyCmd, aclCheckCmd} {
+		c.Flags().StringVar(&aclMatch.Platform, "platform", "", "Platform, e.g. slack, telegram")
+		c.Flags().StringVar(&aclMatch.ChannelID, "channel", "", "Channel ID")
+		c.Flags().StringVar(&aclMatch.UserID, "user", "", "User ID on the platform")
+		c.Flags().StringVar(&aclMatch.Identity, "identity", "", "Linked identity from /whoami, e.g. user-3f9a1c2b7d4e")
+	}
+	for _, c := range []*cobra.Command{aclAllowCmd, aclDenyCmd} {
+		c.Flags().StringVar(&aclMatch.Group, "group", "", "Group name from 'acl group add'")
+		c.Flags().StringVar(&aclComment, "comment", "", "Note shown when the rule decides")
+	}
+	aclAllowCmd.Flags().StringVar(&aclRole, "role", "", "Role of allowed senders (default: default role, or member)")
+
+	// acl role flags
+	aclRoleSetCmd.Flags().StringVar(&aclAllowTools, "allow-tools", "", "Comma-separated tool whitelist; * matches a prefix, e.g. web_*")
+	aclRoleSetCmd.Flags().StringVar(&aclDenyTools, "deny-tools", "", "Comma-separated tool blacklist")
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pltanton/lingti-bot/internal/browser"
+	"github.com/pltanton/lingti-bot/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// browser profile flags
+var (
+	profilePassphrase string
+	profileOutput     string
+	profileImportName string
+	profileForce      bool
+)
+
+var browserCmd = &cobra.Command{
+	Use:   "browser",
+	Short: "Manage browser automation state",
+}
+
+var browserProfileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage named browser login profiles",
+	Long: `Manage named browser login profiles.
+
+A profile holds the cookies and localStorage of the chats that use it, so one
+bot can stay logged in to the same site with several accounts. Profiles are
+saved in ~/.lingti/browser-profiles/ and picked by browser_start (profile),
+browser.profile or agents[].browser_profile in ~/.lingti.yaml.
+
+Use export and import to move a login to another machine. Bundles are
+encrypted with a passphrase from --passphrase, $LINGTI_PROFILE_PASSPHRASE,
+or a prompt.`,
+}
+
+var browserProfileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved browser profiles",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profiles := browser.ListProfiles()
+		if len(profiles) == 0 {
+			fmt.Println("No browser profiles saved. Use browser_start with a profile to create one.")
+			return nil
+		}
+
+		usedBy := make(map[string][]string)
+		if cfg, err := config.Load(); err == nil {
+			if cfg.Browser.Profile != "" {
+				usedBy[cfg.Browser.Profile] = append(usedBy[cfg.Browser.Profile], "(default)")
+			}
+			for _, a := range cfg.Agents {
+				if a.BrowserProfile != "" {
+					usedBy[a.BrowserProfile] = append(usedBy[a.BrowserProfile], a.ID)
+				}
+			}
+		}
+
+		fmt.Printf("%-16s  %-8s  %-17s  %-16s  %s\n", "NAME", "COOKIES", "SAVED", "USED BY", "SITES")
+		fmt.Printf("%-16s  %-8s  %-17s  %-16s  %s\n", "----", "-------", "-----", "-------", "-----")
+		for _, p := range profiles {
+			if p.Owner != "" {
+				usedBy[p.Name] = append(usedBy[p.Name], p.Owner)
+			}
+			sites := p.Origins()
+			if len(sites) > 4 {
+				sites = append(sites[:4], fmt.Sprintf("+%d more", len(p.Origins())-4))
+			}
+			fmt.Printf("%-16s  %-8d  %-17s  %-16s  %s\n", p.Name, len(p.Cookies), p.SavedAt.Format("2006-01-02 15:04"),
+				strings.Join(usedBy[p.Name], ","), strings.Join(sites, ", "))
+		}
+		return nil
+	},
+}
+
+var browserProfileExportCmd = &cobra.Command{
+	Use:   "export <name>",
+	Short: "Export a profile as an encrypted bundle",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		bundle, err := browser.ExportProfile(name, profilePassphraseOrPrompt())
+		if err != nil {
+			return fmt.Errorf("failed to export profile: %w", err)
+		}
+
+		out := profileOutput
+		if out == "" {
+			out = name + ".lingti-profile"
+		}
+		if err := os.WriteFile(out, bundle, 0600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", out, err)
+		}
+		fmt.Printf("Profile %q exported to %s\n", name, out)
+		return nil
+	},
+}
+
+var browserProfileImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a profile bundle made by export",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bundle, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", args[0], err)
+		}
+		p, err := browser.ImportProfile(bundle, profilePassphraseOrPrompt(), profileImportName, profileForce)
+		if err != nil {
+			return fmt.Errorf("failed to import profile: %w", err)
+		}
+		fmt.Printf("Profile %q imported (%d cookies, %d sites)\n", p.Name, len(p.Cookies), len(p.Origins()))
+		return nil
+	},
+}
+
+// profilePassphraseOrPrompt returns the bundle passphrase from the flag, the
+// environment, or an interactive prompt.
+func profilePassphraseOrPrompt() string {
+	if profilePassphrase != "" {
+		return profilePassphrase
+	}
+	if p := os.Getenv("LINGTI_PROFILE_PASSPHRASE"); p != "" {
+		return p
+	}
+	initScanner()
+	return promptText("Bundle passphrase", "")
+}
+
+func init() {
+	rootCmd.AddCommand(browserCmd)
+	browserCmd.AddCommand(browserProfileCmd)
+	browserProfileCmd.AddCommand(browserProfileListCmd)
+	browserProfileCmd.AddCommand(browserProfileExportCmd)
+	browserProfileCmd.AddCommand(browserProfileImportCmd)
+
+	browserProfileCmd.PersistentFlags().StringVar(&profilePassphrase, "passphrase", "", "Bundle passphrase (default: $LINGTI_PROFILE_PASSPHRASE or prompt)")
+	browserProfileExportCmd.Flags().StringVarP(&profileOutput, "output", "o", "", "Output file (default: <name>.lingti-profile)")
+	browserProfileImportCmd.Flags().StringVar(&profileImportName, "name", "", "Save under this name instead of the exported one")
+	browserProfileImportCmd.Flags().BoolVar(&profileForce, "force", false, "Replace an existing profile of the same name")
+}
@@ -22,7 +22,7 @@ var (
 	caBotToken        string // slack
 	caAppToken        string // slack
 	caAppID           string // feishu, teams, zalo, googlechat
-	caAppSecret       string // feishu
+	caAppSecret       string // feishu, whatsapp
 	caAppPassword     string // teams
 	caTenantID        string // teams
 	caClientID        string // dingtalk
@@ -50,12 +50,17 @@ var (
 	caPrivateKey      string // nostr
 	caRelays          string // nostr
 	caSecretKey       string // zalo
-	caUsername        string // nextcloud
-	caPassword        string // nextcloud
+	caUsername        string // nextcloud, email
+	caPassword        string // nextcloud, email
 	caRoomToken       string // nextcloud
 	caProjectID       string // googlechat
 	caCredentialsFile string // googlechat
+	caAudience        string // googlechat
 	caAuthToken       string // webapp
+	caAddress         string // email
+	caIMAPServer      string // email
+	caSMTPServer      string // email
+	caAllowedSenders  string // email
 )
 
 // anyFlagChanged returns true if any of the named flags were explicitly set.
@@ -163,7 +168,7 @@ var channelsAddCmd = &cobra.Command{
 				stepWecom(cfg)
 			}
 		case "whatsapp":
-			if anyFlagChanged(cmd, "phone-id", "access-token", "verify-token") {
+			if anyFlagChanged(cmd, "phone-id", "access-token", "verify-token", "app-secret") {
 				if caPhoneID != "" {
 					cfg.Platforms.WhatsApp.PhoneNumberID = caPhoneID
 				}
@@ -173,6 +178,9 @@ var channelsAddCmd = &cobra.Command{
 				if caVerifyToken != "" {
 					cfg.Platforms.WhatsApp.VerifyToken = caVerifyToken
 				}
+				if caAppSecret != "" {
+					cfg.Platforms.WhatsApp.AppSecret = caAppSecret
+				}
 			} else {
 				stepWhatsApp(cfg)
 			}
@@ -307,14 +315,40 @@ var channelsAddCmd = &cobra.Command{
 			} else {
 				stepNextcloud(cfg)
 			}
+		case "email":
+			if anyFlagChanged(cmd, "address", "username", "password", "imap-server", "smtp-server", "allowed-senders") {
+				if caAddress != "" {
+					cfg.Platforms.Email.Address = caAddress
+				}
+				if caUsername != "" {
+					cfg.Platforms.Email.Username = caUsername
+				}
+				if caPassword != "" {
+					cfg.Platforms.Email.Password = caPassword
+				}
+				if caIMAPServer != "" {
+					cfg.Platforms.Email.IMAPServer = caIMAPServer
+				}
+				if caSMTPServer != "" {
+					cfg.Platforms.Email.SMTPServer = caSMTPServer
+				}
+				if caAllowedSenders != "" {
+					cfg.Platforms.Email.AllowedSenders = splitList(caAllowedSenders)
+				}
+			} else {
+				stepEmail(cfg)
+			}
 		case "googlechat":
-			if anyFlagChanged(cmd, "project-id", "credentials-file") {
+			if anyFlagChanged(cmd, "project-id", "credentials-file", "audience") {
 				if caProjectID != "" {
 					cfg.Platforms.GoogleChat.ProjectID = caProjectID
 				}
 				if caCredentialsFile != "" {
 					cfg.Platforms.GoogleChat.CredentialsFile = caCredentialsFile
 				}
+				if caAudience != "" {
+					cfg.Platforms.GoogleChat.Audience = caAudience
+				}
 			} else {
 				stepGoogleChat(cfg)
 			}
@@ -387,6 +421,7 @@ var channelsListCmd = &cobra.Command{
 			{"zalo", cfg.Platforms.Zalo.AppID != "", cfg.Platforms.Zalo.AppID},
 			{"nextcloud", cfg.Platforms.Nextcloud.ServerURL != "", cfg.Platforms.Nextcloud.ServerURL},
 			{"googlechat", cfg.Platforms.GoogleChat.ProjectID != "", cfg.Platforms.GoogleChat.ProjectID},
+			{"email", cfg.Platforms.Email.Address != "", cfg.Platforms.Email.Address},
 			{"webapp", cfg.Platforms.Webapp.Port != 0, fmt.Sprintf("port=%d", cfg.Platforms.Webapp.Port)},
 		}
 
@@ -457,6 +492,8 @@ var channelsRemoveCmd = &cobra.Command{
 			cfg.Platforms.Nextcloud = config.NextcloudConfig{}
 		case "googlechat":
 			cfg.Platforms.GoogleChat = config.GoogleChatConfig{}
+		case "email":
+			cfg.Platforms.Email = config.EmailConfig{}
 		case "webapp":
 			cfg.Platforms.Webapp = config.WebappConfig{}
 		default:
@@ -489,7 +526,7 @@ func init() {
 	f.StringVar(&caBotToken, "bot-token", "", "Bot token (slack)")
 	f.StringVar(&caAppToken, "app-token", "", "App-level token (slack)")
 	f.StringVar(&caAppID, "app-id", "", "App ID (feishu, teams, zalo, googlechat)")
-	f.StringVar(&caAppSecret, "app-secret", "", "App secret (feishu)")
+	f.StringVar(&caAppSecret, "app-secret", "", "App secret (feishu, whatsapp)")
 	f.StringVar(&caAppPassword, "app-password", "", "App password (teams)")
 	f.StringVar(&caTenantID, "tenant-id", "", "Tenant ID (teams)")
 	f.StringVar(&caClientID, "client-id", "", "Client ID (dingtalk)")
@@ -517,10 +554,15 @@ func init() {
 	f.StringVar(&caPrivateKey, "private-key", "", "Private key (nostr)")
 	f.StringVar(&caRelays, "relays", "", "Relay URLs comma-separated (nostr)")
 	f.StringVar(&caSecretKey, "secret-key", "", "Secret key (zalo)")
-	f.StringVar(&caUsername, "username", "", "Username (nextcloud)")
-	f.StringVar(&caPassword, "password", "", "Password (nextcloud)")
+	f.StringVar(&caUsername, "username", "", "Username (nextcloud, email)")
+	f.StringVar(&caPassword, "password", "", "Password (nextcloud, email)")
 	f.StringVar(&caRoomToken, "room-token", "", "Room token (nextcloud)")
 	f.StringVar(&caProjectID, "project-id", "", "Project ID (googlechat)")
 	f.StringVar(&caCredentialsFile, "credentials-file", "", "Credentials JSON file path (googlechat)")
+	f.StringVar(&caAudience, "audience", "", "Token audience, the project number (googlechat)")
 	f.StringVar(&caAuthToken, "auth-token", "", "Auth token (webapp)")
+	f.StringVar(&caAddress, "address", "", "Bot mailbox address (email)")
+	f.StringVar(&caIMAPServer, "imap-server", "", "IMAP server host:port (email)")
+	f.StringVar(&caSMTPServer, "smtp-server", "", "SMTP server host:port (email)")
+	f.StringVar(&caAllowedSenders, "allowed-senders", "", "Addresses or @domains the bot answers, comma-separated (email)")
 }
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/pltanton/lingti-bot/internal/config"
+	"github.com/pltanton/lingti-bot/internal/dedup"
+	"github.com/pltanton/lingti-bot/internal/logger"
+	"github.com/pltanton/lingti-bot/internal/router"
+)
+
+// setupInbound configures how the router filters and groups incoming
+// messages: redeliveries are dropped unless the config turns that off, and
+// quick consecutive messages are merged if debounce_ms is set.
+func setupInbound(r *router.Router, cfg *config.Config) {
+	var cc config.ConversationConfig
+	if cfg != nil {
+		cc = cfg.Conversation
+	}
+	r.SetDebounce(time.Duration(cc.DebounceMs) * time.Millisecond)
+	if cc.Dedup.Disabled {
+		return
+	}
+	store, err := dedup.Default()
+	if err != nil {
+		logger.Warn("Message deduplication disabled: %v", err)
+		return
+	}
+	store.SetLimits(time.Duration(cc.Dedup.TTLHours)*time.Hour, cc.Dedup.MaxEntries)
+	if _, err := store.Prune(); err != nil {
+		logger.Warn("Failed to prune seen message IDs: %v", err)
+	}
+	r.SetDedup(store)
+}
@@ -226,6 +226,9 @@ func detectPlatforms(cfg *config.Config) []string {
 	if cfg.Platforms.Nextcloud.ServerURL != "" {
 		platforms = append(platforms, "nextcloud")
 	}
+	if cfg.Platforms.Email.Address != "" {
+		platforms = append(platforms, "email")
+	}
 	return platforms
 }
 
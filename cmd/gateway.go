@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/pltanton/lingti-bot/internal/agent"
@@ -16,9 +17,11 @@ import (
 	"github.com/pltanton/lingti-bot/internal/config"
 	cronpkg "github.com/pltanton/lingti-bot/internal/cron"
 	"github.com/pltanton/lingti-bot/internal/gateway"
+	"github.com/pltanton/lingti-bot/internal/ingress"
 	"github.com/pltanton/lingti-bot/internal/logger"
 	"github.com/pltanton/lingti-bot/internal/platforms/dingtalk"
 	"github.com/pltanton/lingti-bot/internal/platforms/discord"
+	"github.com/pltanton/lingti-bot/internal/platforms/email"
 	"github.com/pltanton/lingti-bot/internal/platforms/feishu"
 	"github.com/pltanton/lingti-bot/internal/platforms/googlechat"
 	"github.com/pltanton/lingti-bot/internal/platforms/imessage"
@@ -48,6 +51,12 @@ var (
 	gatewayRefreshBotID  bool
 )
 
+// Shared webhook ingress (see startIngress).
+var (
+	ingressAddr string
+	ingressOnly bool // webhook platforms are served by the ingress only, not their own ports
+)
+
 // Platform credential vars — used by gateway and the deprecated router alias.
 var (
 	slackBotToken        string
@@ -74,6 +83,7 @@ var (
 	matrixAccessToken    string
 	googlechatProjectID       string
 	googlechatCredentialsFile string
+	googlechatAudience        string
 	mattermostServerURL  string
 	mattermostToken      string
 	mattermostTeamName   string
@@ -93,9 +103,20 @@ var (
 	nextcloudUsername    string
 	nextcloudPassword    string
 	nextcloudRoomToken   string
+	emailAddress         string
+	emailUsername        string
+	emailPassword        string
+	emailIMAPServer      string
+	emailSMTPServer      string
+	emailMailbox         string
+	emailPollSecs        int
+	emailAllowedSenders  string
+	emailAuthServID      string
+	emailAllowUnverified bool
 	whatsappPhoneID      string
 	whatsappAccessToken  string
 	whatsappVerifyToken  string
+	whatsappAppSecret    string
 	aiProvider           string
 	aiAPIKey             string
 	aiBaseURL            string
@@ -119,8 +140,12 @@ The gateway is the unified run command that:
 Subcommands:
   restart   Send SIGHUP to a running gateway to reload config
 
+  - Optionally serves webhook platforms on one shared HTTP ingress under
+    /webhooks/<platform> (use --ingress-addr or ingress.addr in config)
+
 Environment variables:
   GATEWAY_ADDR        Address for WebSocket server (default: :18789)
+  INGRESS_ADDR        Address for the shared webhook ingress (default: disabled)
   GATEWAY_AUTH_TOKEN  Single authentication token
   GATEWAY_AUTH_TOKENS Comma-separated authentication tokens`,
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -175,6 +200,7 @@ func init() {
 	gatewayCmd.Flags().StringVar(&gatewayAuthToken, "auth-token", "", "Single authentication token (or GATEWAY_AUTH_TOKEN env)")
 	gatewayCmd.Flags().StringSliceVar(&gatewayAuthTokens, "auth-tokens", nil, "Multiple authentication tokens (or GATEWAY_AUTH_TOKENS env)")
 	gatewayCmd.Flags().BoolVar(&gatewayNoWS, "no-ws", false, "Disable WebSocket server")
+	gatewayCmd.Flags().StringVar(&ingressAddr, "ingress-addr", "", "Shared webhook ingress address, e.g. :8443 (or INGRESS_ADDR env, default: disabled)")
 	gatewayCmd.Flags().BoolVar(&gatewayRefreshBotID, "refresh-bot-id", false, "Generate a new bot ID (invalidates existing share links)")
 
 	gatewayCmd.Flags().StringVar(&aiProvider, "provider", "", "AI provider: claude, deepseek, kimi, qwen (or AI_PROVIDER env)")
@@ -211,6 +237,7 @@ func init() {
 	gatewayCmd.Flags().StringVar(&matrixAccessToken, "matrix-access-token", "", "Matrix Access Token (or MATRIX_ACCESS_TOKEN env)")
 	gatewayCmd.Flags().StringVar(&googlechatProjectID, "googlechat-project-id", "", "Google Chat Project ID (or GOOGLE_CHAT_PROJECT_ID env)")
 	gatewayCmd.Flags().StringVar(&googlechatCredentialsFile, "googlechat-credentials-file", "", "Google Chat Credentials File (or GOOGLE_CHAT_CREDENTIALS_FILE env)")
+	gatewayCmd.Flags().StringVar(&googlechatAudience, "googlechat-audience", "", "Google Chat token audience, the project number (or GOOGLE_CHAT_AUDIENCE env)")
 	gatewayCmd.Flags().StringVar(&mattermostServerURL, "mattermost-server-url", "", "Mattermost Server URL (or MATTERMOST_SERVER_URL env)")
 	gatewayCmd.Flags().StringVar(&mattermostToken, "mattermost-token", "", "Mattermost Token (or MATTERMOST_TOKEN env)")
 	gatewayCmd.Flags().StringVar(&mattermostTeamName, "mattermost-team-name", "", "Mattermost Team Name (or MATTERMOST_TEAM_NAME env)")
@@ -230,9 +257,15 @@ func init() {
 	gatewayCmd.Flags().StringVar(&nextcloudUsername, "nextcloud-username", "", "Nextcloud Username (or NEXTCLOUD_USERNAME env)")
 	gatewayCmd.Flags().StringVar(&nextcloudPassword, "nextcloud-password", "", "Nextcloud Password (or NEXTCLOUD_PASSWORD env)")
 	gatewayCmd.Flags().StringVar(&nextcloudRoomToken, "nextcloud-room-token", "", "Nextcloud Room Token (or NEXTCLOUD_ROOM_TOKEN env)")
+	gatewayCmd.Flags().StringVar(&emailAddress, "email-address", "", "Bot email address (or EMAIL_ADDRESS env)")
+	gatewayCmd.Flags().StringVar(&emailPassword, "email-password", "", "Email password or app password (or EMAIL_PASSWORD env)")
+	gatewayCmd.Flags().StringVar(&emailIMAPServer, "email-imap-server", "", "IMAP server host:port (or EMAIL_IMAP_SERVER env)")
+	gatewayCmd.Flags().StringVar(&emailSMTPServer, "email-smtp-server", "", "SMTP server host:port (or EMAIL_SMTP_SERVER env)")
+	gatewayCmd.Flags().StringVar(&emailAllowedSenders, "email-allowed-senders", "", "Comma-separated addresses or @domains to answer (or EMAIL_ALLOWED_SENDERS env)")
 	gatewayCmd.Flags().StringVar(&whatsappPhoneID, "whatsapp-phone-id", "", "WhatsApp Phone Number ID (or WHATSAPP_PHONE_NUMBER_ID env)")
 	gatewayCmd.Flags().StringVar(&whatsappAccessToken, "whatsapp-access-token", "", "WhatsApp Access Token (or WHATSAPP_ACCESS_TOKEN env)")
 	gatewayCmd.Flags().StringVar(&whatsappVerifyToken, "whatsapp-verify-token", "", "WhatsApp Verify Token (or WHATSAPP_VERIFY_TOKEN env)")
+	gatewayCmd.Flags().StringVar(&whatsappAppSecret, "whatsapp-app-secret", "", "WhatsApp App Secret for webhook signatures (or WHATSAPP_APP_SECRET env)")
 }
 
 func runGateway(cmd *cobra.Command, args []string) {
@@ -263,6 +296,17 @@ func runGateway(cmd *cobra.Command, args []string) {
 	if cfgErr == nil {
 		applyRouterConfigFallbacks(savedCfg)
 	}
+	var ingressCfg config.IngressConfig
+	if cfgErr == nil {
+		ingressCfg = savedCfg.Ingress
+	}
+	if ingressAddr == "" {
+		ingressAddr = os.Getenv("INGRESS_ADDR")
+		if ingressAddr == "" {
+			ingressAddr = ingressCfg.Addr
+		}
+	}
+	ingressOnly = ingressAddr != "" && ingressCfg.DisablePlatformPorts
 
 	// Generate or refresh bot ID
 	if cfgErr == nil {
@@ -312,6 +356,21 @@ func runGateway(cmd *cobra.Command, args []string) {
 		DisableFileTools:   loadDisableFileTools(),
 		CallTimeoutSecs:    aiCallTimeout,
 	}
+	transcriber, synthesizer := loadSpeech(savedCfg)
+	if synthesizer != nil {
+		agentCfg.Synthesizer = synthesizer
+		agentCfg.VoiceReply = savedCfg.Speech.VoiceReply
+	}
+	identities := loadIdentities()
+	if identities != nil {
+		agentCfg.Identities = identities
+	}
+	accessList, err := loadACL(savedCfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error in access control config: %v\n", err)
+		os.Exit(1)
+	}
+	agentCfg.ACL = accessList
 	aiAgent, err := agent.New(agentCfg)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating agent: %v\n", err)
@@ -328,6 +387,19 @@ func runGateway(cmd *cobra.Command, args []string) {
 
 	pool := agent.NewAgentPool(aiAgent, agentCfg, savedCfg)
 	r := router.New(pool.HandleMessage)
+	if cfgErr == nil {
+		r.SetFileThreshold(savedCfg.Delivery.FileThreshold)
+		r.SetQueueMode(savedCfg.Conversation.QueueMode)
+		r.SetConversationScope(savedCfg.Conversation.Scope, savedCfg.Conversation.Scopes)
+		r.SetChannelContext(savedCfg.Conversation.ChannelContext)
+	}
+	r.SetTranscriber(transcriber)
+	if identities != nil {
+		r.SetIdentities(identities)
+	}
+	r.SetACL(accessList)
+	setupInbound(r, savedCfg)
+	setupOutbox(r, savedCfg)
 
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -366,6 +438,12 @@ func runGateway(cmd *cobra.Command, args []string) {
 	}
 	logger.Info("[Gateway] Platform bots started. AI Provider: %s, Model: %s", providerName, modelName)
 
+	ing, err := startIngress(ctx, r, ingressCfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error starting webhook ingress: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Write PID file for `gateway restart`
 	writePIDFile()
 	defer removePIDFile()
@@ -444,11 +522,51 @@ func runGateway(cmd *cobra.Command, args []string) {
 	logger.Info("Shutting down...")
 	cronScheduler.Stop()
 	r.Stop()
+	if ing != nil {
+		ing.Stop()
+	}
 	if gw != nil {
 		gw.Stop()
 	}
 }
 
+// startIngress serves every webhook platform registered with r on the shared
+// ingress under /webhooks/<platform>. It returns nil when no ingress address
+// is configured.
+func startIngress(ctx context.Context, r *router.Router, cfg config.IngressConfig) (*ingress.Server, error) {
+	if ingressAddr == "" {
+		return nil, nil
+	}
+	icfg := ingress.Config{
+		Addr:         ingressAddr,
+		MaxBodyBytes: cfg.MaxBodyBytes,
+		CertFile:     cfg.TLS.CertFile,
+		KeyFile:      cfg.TLS.KeyFile,
+	}
+	if acme := cfg.TLS.ACME; len(acme.Domains) > 0 {
+		icfg.ACME = &ingress.ACMEConfig{
+			Domains:      acme.Domains,
+			Email:        acme.Email,
+			CacheDir:     acme.CacheDir,
+			DirectoryURL: acme.DirectoryURL,
+			CARootsFile:  acme.CARootsFile,
+		}
+	}
+	s, err := ingress.New(icfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range r.Platforms() {
+		if wp, ok := p.(ingress.WebhookPlatform); ok {
+			s.Mount(wp.Name(), wp.WebhookHandler())
+		}
+	}
+	if err := s.Start(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
 // resolveRouterEnvVars fills platform credential vars from environment (flags take priority).
 func resolveRouterEnvVars() {
 	if slackBotToken == "" {
@@ -507,6 +625,24 @@ func resolveRouterEnvVars() {
 	if nextcloudRoomToken == "" {
 		nextcloudRoomToken = os.Getenv("NEXTCLOUD_ROOM_TOKEN")
 	}
+	if emailAddress == "" {
+		emailAddress = os.Getenv("EMAIL_ADDRESS")
+	}
+	if emailUsername == "" {
+		emailUsername = os.Getenv("EMAIL_USERNAME")
+	}
+	if emailPassword == "" {
+		emailPassword = os.Getenv("EMAIL_PASSWORD")
+	}
+	if emailIMAPServer == "" {
+		emailIMAPServer = os.Getenv("EMAIL_IMAP_SERVER")
+	}
+	if emailSMTPServer == "" {
+		emailSMTPServer = os.Getenv("EMAIL_SMTP_SERVER")
+	}
+	if emailAllowedSenders == "" {
+		emailAllowedSenders = os.Getenv("EMAIL_ALLOWED_SENDERS")
+	}
 	if zaloAppID == "" {
 		zaloAppID = os.Getenv("ZALO_APP_ID")
 	}
@@ -558,6 +694,9 @@ func resolveRouterEnvVars() {
 	if googlechatCredentialsFile == "" {
 		googlechatCredentialsFile = os.Getenv("GOOGLE_CHAT_CREDENTIALS_FILE")
 	}
+	if googlechatAudience == "" {
+		googlechatAudience = os.Getenv("GOOGLE_CHAT_AUDIENCE")
+	}
 	if matrixHomeserverURL == "" {
 		matrixHomeserverURL = os.Getenv("MATRIX_HOMESERVER_URL")
 	}
@@ -591,6 +730,9 @@ func resolveRouterEnvVars() {
 	if whatsappVerifyToken == "" {
 		whatsappVerifyToken = os.Getenv("WHATSAPP_VERIFY_TOKEN")
 	}
+	if whatsappAppSecret == "" {
+		whatsappAppSecret = os.Getenv("WHATSAPP_APP_SECRET")
+	}
 	if webappPort == 0 {
 		if port := os.Getenv("WEBAPP_PORT"); port != "" {
 			fmt.Sscanf(port, "%d", &webappPort)
@@ -724,6 +866,36 @@ func applyRouterConfigFallbacks(savedCfg *config.Config) {
 	if nextcloudRoomToken == "" {
 		nextcloudRoomToken = p.Nextcloud.RoomToken
 	}
+	if emailAddress == "" {
+		emailAddress = p.Email.Address
+	}
+	if emailUsername == "" {
+		emailUsername = p.Email.Username
+	}
+	if emailPassword == "" {
+		emailPassword = p.Email.Password
+	}
+	if emailIMAPServer == "" {
+		emailIMAPServer = p.Email.IMAPServer
+	}
+	if emailSMTPServer == "" {
+		emailSMTPServer = p.Email.SMTPServer
+	}
+	if emailAllowedSenders == "" {
+		emailAllowedSenders = strings.Join(p.Email.AllowedSenders, ",")
+	}
+	if emailMailbox == "" {
+		emailMailbox = p.Email.Mailbox
+	}
+	if emailPollSecs == 0 {
+		emailPollSecs = p.Email.PollIntervalSecs
+	}
+	if emailAuthServID == "" {
+		emailAuthServID = p.Email.AuthServID
+	}
+	if !emailAllowUnverified {
+		emailAllowUnverified = p.Email.AllowUnverified
+	}
 	if zaloAppID == "" {
 		zaloAppID = p.Zalo.AppID
 	}
@@ -775,6 +947,9 @@ func applyRouterConfigFallbacks(savedCfg *config.Config) {
 	if googlechatCredentialsFile == "" {
 		googlechatCredentialsFile = p.GoogleChat.CredentialsFile
 	}
+	if googlechatAudience == "" {
+		googlechatAudience = p.GoogleChat.Audience
+	}
 	if matrixHomeserverURL == "" {
 		matrixHomeserverURL = p.Matrix.HomeserverURL
 	}
@@ -808,6 +983,9 @@ func applyRouterConfigFallbacks(savedCfg *config.Config) {
 	if whatsappVerifyToken == "" {
 		whatsappVerifyToken = p.WhatsApp.VerifyToken
 	}
+	if whatsappAppSecret == "" {
+		whatsappAppSecret = p.WhatsApp.AppSecret
+	}
 	if webappPort == 0 && p.Webapp.Port != 0 {
 		webappPort = p.Webapp.Port
 	}
@@ -815,13 +993,25 @@ func applyRouterConfigFallbacks(savedCfg *config.Config) {
 
 // registerPlatforms registers all configured platforms with the router.
 func registerPlatforms(r *router.Router) {
+	// -1 keeps webhook platforms off their own ports; the ingress serves them
+	webhookPort := 0
+	if ingressOnly {
+		webhookPort = -1
+		if wecomPort == 0 {
+			wecomPort = -1
+		}
+		if webappPort > 0 {
+			webappPort = -1
+		}
+	}
+
 	if slackBotToken != "" && slackAppToken != "" {
 		p, err := slack.New(slack.Config{BotToken: slackBotToken, AppToken: slackAppToken})
 		if err != nil {
 			logger.Warn("Error creating Slack platform: %v, skipping", err)
-			return
+		} else {
+			r.Register(p)
 		}
-		r.Register(p)
 	} else {
 		logger.Info("Slack tokens not provided, skipping Slack integration")
 	}
@@ -830,9 +1020,9 @@ func registerPlatforms(r *router.Router) {
 		p, err := feishu.New(feishu.Config{AppID: feishuAppID, AppSecret: feishuAppSecret})
 		if err != nil {
 			logger.Warn("Error creating Feishu platform: %v, skipping", err)
-			return
+		} else {
+			r.Register(p)
 		}
-		r.Register(p)
 	} else {
 		logger.Info("Feishu tokens not provided, skipping Feishu integration")
 	}
@@ -841,9 +1031,9 @@ func registerPlatforms(r *router.Router) {
 		p, err := telegram.New(telegram.Config{Token: telegramToken})
 		if err != nil {
 			logger.Warn("Error creating Telegram platform: %v, skipping", err)
-			return
+		} else {
+			r.Register(p)
 		}
-		r.Register(p)
 	} else {
 		logger.Info("Telegram token not provided, skipping Telegram integration")
 	}
@@ -852,9 +1042,9 @@ func registerPlatforms(r *router.Router) {
 		p, err := discord.New(discord.Config{Token: discordToken})
 		if err != nil {
 			logger.Warn("Error creating Discord platform: %v, skipping", err)
-			return
+		} else {
+			r.Register(p)
 		}
-		r.Register(p)
 	} else {
 		logger.Info("Discord token not provided, skipping Discord integration")
 	}
@@ -866,9 +1056,9 @@ func registerPlatforms(r *router.Router) {
 		})
 		if err != nil {
 			logger.Warn("Error creating WeCom platform: %v, skipping", err)
-			return
+		} else {
+			r.Register(p)
 		}
-		r.Register(p)
 	} else {
 		logger.Info("WeCom tokens not provided, skipping WeCom integration")
 	}
@@ -877,9 +1067,9 @@ func registerPlatforms(r *router.Router) {
 		p, err := dingtalk.New(dingtalk.Config{ClientID: dingtalkClientID, ClientSecret: dingtalkClientSecret})
 		if err != nil {
 			logger.Warn("Error creating DingTalk platform: %v, skipping", err)
-			return
+		} else {
+			r.Register(p)
 		}
-		r.Register(p)
 	} else {
 		logger.Info("DingTalk tokens not provided, skipping DingTalk integration")
 	}
@@ -891,20 +1081,38 @@ func registerPlatforms(r *router.Router) {
 		})
 		if err != nil {
 			logger.Warn("Error creating Nextcloud Talk platform: %v, skipping", err)
-			return
+		} else {
+			r.Register(p)
 		}
-		r.Register(p)
 	} else {
 		logger.Info("Nextcloud Talk tokens not provided, skipping Nextcloud Talk integration")
 	}
 
+	if emailAddress != "" && emailPassword != "" && emailIMAPServer != "" && emailSMTPServer != "" {
+		p, err := email.New(email.Config{
+			Address: emailAddress, Username: emailUsername, Password: emailPassword,
+			IMAPServer: emailIMAPServer, SMTPServer: emailSMTPServer, Mailbox: emailMailbox,
+			PollInterval:    time.Duration(emailPollSecs) * time.Second,
+			AllowedSenders:  splitList(emailAllowedSenders),
+			AuthServID:      emailAuthServID,
+			AllowUnverified: emailAllowUnverified,
+		})
+		if err != nil {
+			logger.Warn("Error creating Email platform: %v, skipping", err)
+		} else {
+			r.Register(p)
+		}
+	} else {
+		logger.Info("Email credentials not provided, skipping Email integration")
+	}
+
 	if zaloAppID != "" && zaloAccessToken != "" {
-		p, err := zalo.New(zalo.Config{AppID: zaloAppID, SecretKey: zaloSecretKey, AccessToken: zaloAccessToken})
+		p, err := zalo.New(zalo.Config{AppID: zaloAppID, SecretKey: zaloSecretKey, AccessToken: zaloAccessToken, WebhookPort: webhookPort})
 		if err != nil {
 			logger.Warn("Error creating Zalo platform: %v, skipping", err)
-			return
+		} else {
+			r.Register(p)
 		}
-		r.Register(p)
 	} else {
 		logger.Info("Zalo tokens not provided, skipping Zalo integration")
 	}
@@ -913,9 +1121,9 @@ func registerPlatforms(r *router.Router) {
 		p, err := nostr.New(nostr.Config{PrivateKey: nostrPrivateKey, Relays: nostrRelays})
 		if err != nil {
 			logger.Warn("Error creating NOSTR platform: %v, skipping", err)
-			return
+		} else {
+			r.Register(p)
 		}
-		r.Register(p)
 	} else {
 		logger.Info("NOSTR tokens not provided, skipping NOSTR integration")
 	}
@@ -924,9 +1132,9 @@ func registerPlatforms(r *router.Router) {
 		p, err := twitch.New(twitch.Config{Token: twitchToken, Channel: twitchChannel, BotName: twitchBotName})
 		if err != nil {
 			logger.Warn("Error creating Twitch platform: %v, skipping", err)
-			return
+		} else {
+			r.Register(p)
 		}
-		r.Register(p)
 	} else {
 		logger.Info("Twitch tokens not provided, skipping Twitch integration")
 	}
@@ -935,9 +1143,9 @@ func registerPlatforms(r *router.Router) {
 		p, err := signalplatform.New(signalplatform.Config{APIURL: signalAPIURL, PhoneNumber: signalPhoneNumber})
 		if err != nil {
 			logger.Warn("Error creating Signal platform: %v, skipping", err)
-			return
+		} else {
+			r.Register(p)
 		}
-		r.Register(p)
 	} else {
 		logger.Info("Signal tokens not provided, skipping Signal integration")
 	}
@@ -946,9 +1154,9 @@ func registerPlatforms(r *router.Router) {
 		p, err := imessage.New(imessage.Config{BlueBubblesURL: blueBubblesURL, BlueBubblesPassword: blueBubblesPassword})
 		if err != nil {
 			logger.Warn("Error creating iMessage platform: %v, skipping", err)
-			return
+		} else {
+			r.Register(p)
 		}
-		r.Register(p)
 	} else {
 		logger.Info("iMessage tokens not provided, skipping iMessage integration")
 	}
@@ -957,20 +1165,20 @@ func registerPlatforms(r *router.Router) {
 		p, err := mattermost.New(mattermost.Config{ServerURL: mattermostServerURL, Token: mattermostToken, TeamName: mattermostTeamName})
 		if err != nil {
 			logger.Warn("Error creating Mattermost platform: %v, skipping", err)
-			return
+		} else {
+			r.Register(p)
 		}
-		r.Register(p)
 	} else {
 		logger.Info("Mattermost tokens not provided, skipping Mattermost integration")
 	}
 
 	if googlechatProjectID != "" {
-		p, err := googlechat.New(googlechat.Config{ProjectID: googlechatProjectID, CredentialsFile: googlechatCredentialsFile})
+		p, err := googlechat.New(googlechat.Config{ProjectID: googlechatProjectID, CredentialsFile: googlechatCredentialsFile, Audience: googlechatAudience, WebhookPort: webhookPort})
 		if err != nil {
 			logger.Warn("Error creating Google Chat platform: %v, skipping", err)
-			return
+		} else {
+			r.Register(p)
 		}
-		r.Register(p)
 	} else {
 		logger.Info("Google Chat tokens not provided, skipping Google Chat integration")
 	}
@@ -979,53 +1187,56 @@ func registerPlatforms(r *router.Router) {
 		p, err := matrix.New(matrix.Config{HomeserverURL: matrixHomeserverURL, UserID: matrixUserID, AccessToken: matrixAccessToken})
 		if err != nil {
 			logger.Warn("Error creating Matrix platform: %v, skipping", err)
-			return
+		} else {
+			r.Register(p)
 		}
-		r.Register(p)
 	} else {
 		logger.Info("Matrix tokens not provided, skipping Matrix integration")
 	}
 
 	if teamsAppID != "" && teamsAppPassword != "" {
-		p, err := teams.New(teams.Config{AppID: teamsAppID, AppPassword: teamsAppPassword, TenantID: teamsTenantID})
+		p, err := teams.New(teams.Config{AppID: teamsAppID, AppPassword: teamsAppPassword, TenantID: teamsTenantID, WebhookPort: webhookPort})
 		if err != nil {
 			logger.Warn("Error creating Teams platform: %v, skipping", err)
-			return
+		} else {
+			r.Register(p)
 		}
-		r.Register(p)
 	} else {
 		logger.Info("Teams tokens not provided, skipping Teams integration")
 	}
 
 	if lineChannelSecret != "" && lineChannelToken != "" {
-		p, err := line.New(line.Config{ChannelSecret: lineChannelSecret, ChannelToken: lineChannelToken})
+		p, err := line.New(line.Config{ChannelSecret: lineChannelSecret, ChannelToken: lineChannelToken, WebhookPort: webhookPort})
 		if err != nil {
 			logger.Warn("Error creating LINE platform: %v, skipping", err)
-			return
+		} else {
+			r.Register(p)
 		}
-		r.Register(p)
 	} else {
 		logger.Info("LINE tokens not provided, skipping LINE integration")
 	}
 
-	if whatsappPhoneID != "" && whatsappAccessToken != "" {
-		p, err := whatsapp.New(whatsapp.Config{PhoneNumberID: whatsappPhoneID, AccessToken: whatsappAccessToken, VerifyToken: whatsappVerifyToken})
+	if whatsappPhoneID != "" && whatsappAccessToken != "" && whatsappAppSecret == "" {
+		// Webhooks cannot be verified without the app secret
+		logger.Warn("WhatsApp app secret not provided (--whatsapp-app-secret or WHATSAPP_APP_SECRET env), skipping WhatsApp integration")
+	} else if whatsappPhoneID != "" && whatsappAccessToken != "" {
+		p, err := whatsapp.New(whatsapp.Config{PhoneNumberID: whatsappPhoneID, AccessToken: whatsappAccessToken, VerifyToken: whatsappVerifyToken, AppSecret: whatsappAppSecret, WebhookPort: webhookPort})
 		if err != nil {
 			logger.Warn("Error creating WhatsApp platform: %v, skipping", err)
-			return
+		} else {
+			r.Register(p)
 		}
-		r.Register(p)
 	} else {
 		logger.Info("WhatsApp tokens not provided, skipping WhatsApp integration")
 	}
 
-	if webappPort > 0 {
+	if webappPort != 0 {
 		p, err := webapp.New(webapp.Config{Port: webappPort})
 		if err != nil {
 			logger.Warn("Error creating webapp platform: %v, skipping", err)
-			return
+		} else {
+			r.Register(p)
 		}
-		r.Register(p)
 	}
 }
 
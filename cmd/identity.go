@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"github.com/pltanton/lingti-bot/internal/identity"
+	"github.com/pltanton/lingti-bot/internal/logger"
+)
+
+// loadIdentities opens the identity store behind /link. If it cannot be
+// opened the bot runs without account linking and returns nil.
+func loadIdentities() *identity.Store {
+	store, err := identity.Default()
+	if err != nil {
+		logger.Warn("Account linking disabled: %v", err)
+		return nil
+	}
+	return store
+}
@@ -46,6 +46,7 @@ var (
 	onboardWhatsAppPhoneID     string
 	onboardWhatsAppAccessToken string
 	onboardWhatsAppVerifyToken string
+	onboardWhatsAppAppSecret   string
 	// LINE
 	onboardLINEChannelSecret string
 	onboardLINEChannelToken  string
@@ -60,6 +61,7 @@ var (
 	// Google Chat
 	onboardGoogleChatProjectID       string
 	onboardGoogleChatCredentialsFile string
+	onboardGoogleChatAudience        string
 	// Mattermost
 	onboardMattermostServerURL string
 	onboardMattermostToken     string
@@ -141,6 +143,7 @@ func init() {
 	onboardCmd.Flags().StringVar(&onboardWhatsAppPhoneID, "whatsapp-phone-id", "", "WhatsApp Phone Number ID")
 	onboardCmd.Flags().StringVar(&onboardWhatsAppAccessToken, "whatsapp-access-token", "", "WhatsApp Access Token")
 	onboardCmd.Flags().StringVar(&onboardWhatsAppVerifyToken, "whatsapp-verify-token", "", "WhatsApp Verify Token")
+	onboardCmd.Flags().StringVar(&onboardWhatsAppAppSecret, "whatsapp-app-secret", "", "WhatsApp App Secret")
 	// LINE
 	onboardCmd.Flags().StringVar(&onboardLINEChannelSecret, "line-channel-secret", "", "LINE Channel Secret")
 	onboardCmd.Flags().StringVar(&onboardLINEChannelToken, "line-channel-token", "", "LINE Channel Token")
@@ -155,6 +158,7 @@ func init() {
 	// Google Chat
 	onboardCmd.Flags().StringVar(&onboardGoogleChatProjectID, "googlechat-project-id", "", "Google Chat Project ID")
 	onboardCmd.Flags().StringVar(&onboardGoogleChatCredentialsFile, "googlechat-credentials-file", "", "Google Chat Credentials File")
+	onboardCmd.Flags().StringVar(&onboardGoogleChatAudience, "googlechat-audience", "", "Google Chat Audience (project number)")
 	// Mattermost
 	onboardCmd.Flags().StringVar(&onboardMattermostServerURL, "mattermost-server-url", "", "Mattermost Server URL")
 	onboardCmd.Flags().StringVar(&onboardMattermostToken, "mattermost-token", "", "Mattermost Token")
@@ -384,6 +388,9 @@ func applyOnboardFlags(cfg *config.Config) {
 		if onboardWhatsAppVerifyToken != "" {
 			cfg.Platforms.WhatsApp.VerifyToken = onboardWhatsAppVerifyToken
 		}
+		if onboardWhatsAppAppSecret != "" {
+			cfg.Platforms.WhatsApp.AppSecret = onboardWhatsAppAppSecret
+		}
 	case "line":
 		if onboardLINEChannelSecret != "" {
 			cfg.Platforms.LINE.ChannelSecret = onboardLINEChannelSecret
@@ -418,6 +425,9 @@ func applyOnboardFlags(cfg *config.Config) {
 		if onboardGoogleChatCredentialsFile != "" {
 			cfg.Platforms.GoogleChat.CredentialsFile = onboardGoogleChatCredentialsFile
 		}
+		if onboardGoogleChatAudience != "" {
+			cfg.Platforms.GoogleChat.Audience = onboardGoogleChatAudience
+		}
 	case "mattermost":
 		if onboardMattermostServerURL != "" {
 			cfg.Platforms.Mattermost.ServerURL = onboardMattermostServerURL
@@ -709,6 +719,7 @@ var platformOptions = []platformInfo{
 	{"nostr", "nostr     (NOSTR)"},
 	{"zalo", "zalo      (Zalo)"},
 	{"nextcloud", "nextcloud (Nextcloud Talk)"},
+	{"email", "email     (IMAP/SMTP)"},
 	{"skip", "skip      (configure later)"},
 }
 
@@ -762,6 +773,8 @@ func stepPlatform(cfg *config.Config) {
 		stepZalo(cfg)
 	case "nextcloud":
 		stepNextcloud(cfg)
+	case "email":
+		stepEmail(cfg)
 	case "skip":
 		fmt.Println("\n  > Platform configuration skipped")
 	}
@@ -815,6 +828,7 @@ func stepWhatsApp(cfg *config.Config) {
 	cfg.Platforms.WhatsApp.PhoneNumberID = promptText("WhatsApp Phone Number ID", cfg.Platforms.WhatsApp.PhoneNumberID)
 	cfg.Platforms.WhatsApp.AccessToken = promptText("WhatsApp Access Token", cfg.Platforms.WhatsApp.AccessToken)
 	cfg.Platforms.WhatsApp.VerifyToken = promptText("WhatsApp Verify Token", cfg.Platforms.WhatsApp.VerifyToken)
+	cfg.Platforms.WhatsApp.AppSecret = promptText("WhatsApp App Secret", cfg.Platforms.WhatsApp.AppSecret)
 	fmt.Println("\n  > WhatsApp configured")
 }
 
@@ -845,6 +859,7 @@ func stepGoogleChat(cfg *config.Config) {
 	fmt.Println()
 	cfg.Platforms.GoogleChat.ProjectID = promptText("Google Chat Project ID", cfg.Platforms.GoogleChat.ProjectID)
 	cfg.Platforms.GoogleChat.CredentialsFile = promptText("Google Chat Credentials File", cfg.Platforms.GoogleChat.CredentialsFile)
+	cfg.Platforms.GoogleChat.Audience = promptText("Google Chat Audience (project number)", cfg.Platforms.GoogleChat.Audience)
 	fmt.Println("\n  > Google Chat configured")
 }
 
@@ -903,6 +918,18 @@ func stepNextcloud(cfg *config.Config) {
 	fmt.Println("\n  > Nextcloud Talk configured")
 }
 
+func stepEmail(cfg *config.Config) {
+	fmt.Println()
+	e := &cfg.Platforms.Email
+	e.Address = promptText("Bot email address", e.Address)
+	e.Password = promptText("Email password (or app password)", e.Password)
+	e.IMAPServer = promptText("IMAP server (host:port)", e.IMAPServer)
+	e.SMTPServer = promptText("SMTP server (host:port)", e.SMTPServer)
+	senders := promptText("Allowed senders (addresses or @domains, comma-separated)", strings.Join(e.AllowedSenders, ","))
+	e.AllowedSenders = splitList(senders)
+	fmt.Println("\n  > Email configured")
+}
+
 func stepWeChat(cfg *config.Config) {
 	fmt.Println()
 	fmt.Println("  WeChat works via the cloud relay service.")
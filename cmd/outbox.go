@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pltanton/lingti-bot/internal/config"
+	"github.com/pltanton/lingti-bot/internal/logger"
+	"github.com/pltanton/lingti-bot/internal/outbox"
+	"github.com/pltanton/lingti-bot/internal/router"
+	"github.com/spf13/cobra"
+)
+
+var outboxCmd = &cobra.Command{
+	Use:   "outbox",
+	Short: "Inspect the outbound message queue and its dead letters",
+	Long: `Inspect the outbound message queue in ~/.lingti.db.
+
+Responses and cron results wait in the queue until the platform accepts
+them. A message that still fails after its last retry becomes a dead letter;
+it can be retried once the problem is fixed, or dropped. A running gateway
+picks up retried messages within 30 seconds.`,
+}
+
+var outboxRetryAll bool
+
+var outboxListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List messages waiting to be sent",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := outbox.Default()
+		if err != nil {
+			return err
+		}
+		items, err := store.Pending()
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			fmt.Println("No messages waiting.")
+			return nil
+		}
+		fmt.Printf("%-6s  %-24s  %-8s  %-20s  %s\n", "ID", "CHANNEL", "ATTEMPTS", "NEXT ATTEMPT", "TEXT")
+		fmt.Printf("%-6s  %-24s  %-8s  %-20s  %s\n", "--", "-------", "--------", "------------", "----")
+		for _, it := range items {
+			fmt.Printf("%-6d  %-24s  %-8d  %-20s  %s\n", it.ID, it.Platform+":"+it.ChannelID, it.Attempts,
+				it.NextAttempt.Local().Format(time.DateTime), preview(it.Payload, 40))
+		}
+		return nil
+	},
+}
+
+var outboxDeadCmd = &cobra.Command{
+	Use:   "dead",
+	Short: "List dead letters: messages that could not be sent",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := outbox.Default()
+		if err != nil {
+			return err
+		}
+		items, err := store.Dead()
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			fmt.Println("No dead letters.")
+			return nil
+		}
+		fmt.Printf("%-6s  %-24s  %-20s  %-30s  %s\n", "ID", "CHANNEL", "QUEUED", "TEXT", "LAST ERROR")
+		fmt.Printf("%-6s  %-24s  %-20s  %-30s  %s\n", "--", "-------", "------", "----", "----------")
+		for _, it := range items {
+			fmt.Printf("%-6d  %-24s  %-20s  %-30s  %s\n", it.ID, it.Platform+":"+it.ChannelID,
+				it.CreatedAt.Local().Format(time.DateTime), preview(it.Payload, 30), it.LastError)
+		}
+		fmt.Println("\nUse 'lingti-bot outbox show <id>' for the full message, 'outbox retry' or 'outbox drop' to resolve it.")
+		return nil
+	},
+}
+
+var outboxShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show a queued message in full",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid message ID %q", args[0])
+		}
+		store, err := outbox.Default()
+		if err != nil {
+			return err
+		}
+		it, err := store.Get(id)
+		if err != nil {
+			return err
+		}
+		if it == nil {
+			return fmt.Errorf("message %d not found", id)
+		}
+		resp, err := router.QueuedResponse(it.Payload)
+		if err != nil {
+			return fmt.Errorf("message %d is unreadable: %w", id, err)
+		}
+
+		state := "pending"
+		if it.Dead {
+			state = "dead"
+		}
+		fmt.Printf("ID:        %d (%s)\n", it.ID, state)
+		fmt.Printf("Channel:   %s:%s\n", it.Platform, it.ChannelID)
+		fmt.Printf("Queued:    %s\n", it.CreatedAt.Local().Format(time.DateTime))
+		fmt.Printf("Attempts:  %d\n", it.Attempts)
+		if it.LastError != "" {
+			fmt.Printf("Error:     %s\n", it.LastError)
+		}
+		if resp.ThreadID != "" {
+			fmt.Printf("Thread:    %s\n", resp.ThreadID)
+		}
+		for _, f := range resp.Files {
+			fmt.Printf("File:      %s\n", f.Path)
+		}
+		fmt.Printf("\n%s\n", resp.Text)
+		return nil
+	},
+}
+
+var outboxRetryCmd = &cobra.Command{
+	Use:   "retry [id...]",
+	Short: "Send dead letters again",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && !outboxRetryAll {
+			return fmt.Errorf("give message IDs or --all")
+		}
+		store, err := outbox.Default()
+		if err != nil {
+			return err
+		}
+		ids, err := parseIDs(args)
+		if err != nil {
+			return err
+		}
+		if outboxRetryAll {
+			dead, err := store.Dead()
+			if err != nil {
+				return err
+			}
+			for _, it := range dead {
+				ids = append(ids, it.ID)
+			}
+		}
+		for _, id := range ids {
+			ok, err := store.Requeue(id)
+			if err != nil {
+				return err
+			}
+			if ok {
+				fmt.Printf("Requeued message %d\n", id)
+			} else {
+				fmt.Printf("No dead letter %d\n", id)
+			}
+		}
+		return nil
+	},
+}
+
+var outboxDropCmd = &cobra.Command{
+	Use:   "drop <id>...",
+	Short: "Delete queued messages or dead letters",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ids, err := parseIDs(args)
+		if err != nil {
+			return err
+		}
+		store, err := outbox.Default()
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			it, err := store.Get(id)
+			if err != nil {
+				return err
+			}
+			ok, err := store.Delete(id)
+			if err != nil {
+				return err
+			}
+			if ok {
+				if it != nil {
+					router.RemoveQueuedFiles(it.Payload)
+				}
+				fmt.Printf("Dropped message %d\n", id)
+			} else {
+				fmt.Printf("No message %d\n", id)
+			}
+		}
+		return nil
+	},
+}
+
+var outboxPurgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Delete all dead letters",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := outbox.Default()
+		if err != nil {
+			return err
+		}
+		n, err := store.PurgeDead()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d dead letter(s)\n", n)
+		return nil
+	},
+}
+
+// setupOutbox gives the router its outbound queue unless the config turns
+// it off. If the queue cannot be opened each message gets a single attempt.
+func setupOutbox(r *router.Router, cfg *config.Config) {
+	var qc config.DeliveryQueueConfig
+	if cfg != nil {
+		qc = cfg.Delivery.Queue
+	}
+	if qc.Disabled {
+		return
+	}
+	store, err := outbox.Default()
+	if err != nil {
+		logger.Warn("Outbound queue disabled: %v", err)
+		return
+	}
+	platforms := make(map[string]router.RetryPolicy, len(qc.Platforms))
+	for name, p := range qc.Platforms {
+		platforms[name] = retryPolicy(p, qc.RetryPolicy)
+	}
+	r.SetOutbox(store, retryPolicy(qc.RetryPolicy, config.RetryPolicy{}), platforms)
+}
+
+// retryPolicy converts a configured policy, taking unset fields from base.
+func retryPolicy(p, base config.RetryPolicy) router.RetryPolicy {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = base.MaxAttempts
+	}
+	if p.MinDelaySecs == 0 {
+		p.MinDelaySecs = base.MinDelaySecs
+	}
+	if p.MaxDelaySecs == 0 {
+		p.MaxDelaySecs = base.MaxDelaySecs
+	}
+	return router.RetryPolicy{
+		MaxAttempts: p.MaxAttempts,
+		MinDelay:    time.Duration(p.MinDelaySecs) * time.Second,
+		MaxDelay:    time.Duration(p.MaxDelaySecs) * time.Second,
+	}
+}
+
+// preview returns the first line of a queued message's text, shortened.
+func preview(payload []byte, n int) string {
+	resp, err := router.QueuedResponse(payload)
+	if err != nil {
+		return "(unreadable)"
+	}
+	text, _, _ := strings.Cut(strings.TrimSpace(resp.Text), "\n")
+	if r := []rune(text); len(r) > n {
+		text = string(r[:n-1]) + "…"
+	}
+	if text == "" && len(resp.Files) > 0 {
+		text = fmt.Sprintf("(%d file(s))", len(resp.Files))
+	}
+	return text
+}
+
+func parseIDs(args []string) ([]int64, error) {
+	ids := make([]int64, 0, len(args))
+	for _, a := range args {
+		id, err := strconv.ParseInt(a, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid message ID %q", a)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func init() {
+	rootCmd.AddCommand(outboxCmd)
+	outboxCmd.AddCommand(outboxListCmd, outboxDeadCmd, outboxShowCmd, outboxRetryCmd, outboxDropCmd, outboxPurgeCmd)
+
+	outboxRetryCmd.Flags().BoolVar(&outboxRetryAll, "all", false, "Retry every dead letter")
+}
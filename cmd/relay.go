@@ -430,6 +430,21 @@ func runRelay(cmd *cobra.Command, args []string) {
 		CallTimeoutSecs:    relayCallTimeout,
 		MCPServers:         mcpServers,
 	}
+	transcriber, synthesizer := loadSpeech(savedCfg)
+	if synthesizer != nil {
+		agentCfg.Synthesizer = synthesizer
+		agentCfg.VoiceReply = savedCfg.Speech.VoiceReply
+	}
+	identities := loadIdentities()
+	if identities != nil {
+		agentCfg.Identities = identities
+	}
+	accessList, err := loadACL(savedCfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error in access control config: %v\n", err)
+		os.Exit(1)
+	}
+	agentCfg.ACL = accessList
 	aiAgent, err := agent.New(agentCfg)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating agent: %v\n", err)
@@ -451,6 +466,19 @@ func runRelay(cmd *cobra.Command, args []string) {
 
 	// Create the router with the pool as message handler
 	r := router.New(pool.HandleMessage)
+	if cfgErr == nil {
+		r.SetFileThreshold(savedCfg.Delivery.FileThreshold)
+		r.SetQueueMode(savedCfg.Conversation.QueueMode)
+		r.SetConversationScope(savedCfg.Conversation.Scope, savedCfg.Conversation.Scopes)
+		r.SetChannelContext(savedCfg.Conversation.ChannelContext)
+	}
+	r.SetTranscriber(transcriber)
+	if identities != nil {
+		r.SetIdentities(identities)
+	}
+	r.SetACL(accessList)
+	setupInbound(r, savedCfg)
+	setupOutbox(r, savedCfg)
 
 	// Initialize cron scheduler
 	homeDir, err := os.UserHomeDir()
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"github.com/pltanton/lingti-bot/internal/config"
+	"github.com/pltanton/lingti-bot/internal/logger"
+	"github.com/pltanton/lingti-bot/internal/speech"
+)
+
+// loadSpeech builds the configured speech engines. An engine that is not
+// configured, or fails to build, is nil; the bot then runs without it.
+func loadSpeech(cfg *config.Config) (speech.Transcriber, speech.Synthesizer) {
+	if cfg == nil {
+		return nil, nil
+	}
+	var (
+		transcriber speech.Transcriber
+		synthesizer speech.Synthesizer
+		err         error
+	)
+	if e := cfg.Speech.Transcribe; e.Engine != "" {
+		if transcriber, err = speech.NewTranscriber(speechEngine(e)); err != nil {
+			logger.Warn("Voice transcription disabled: %v", err)
+		} else {
+			logger.Info("Voice transcription enabled (%s)", e.Engine)
+		}
+	}
+	if e := cfg.Speech.Synthesize; e.Engine != "" {
+		if synthesizer, err = speech.NewSynthesizer(speechEngine(e)); err != nil {
+			logger.Warn("Voice replies disabled: %v", err)
+		}
+	}
+	return transcriber, synthesizer
+}
+
+func speechEngine(e config.SpeechEngineConfig) speech.Config {
+	return speech.Config{
+		Engine:   e.Engine,
+		Binary:   e.Binary,
+		Model:    e.Model,
+		Language: e.Language,
+		BaseURL:  e.BaseURL,
+		APIKey:   e.APIKey,
+		Voice:    e.Voice,
+	}
+}
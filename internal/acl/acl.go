@@ -0,0 +1,278 @@
+// Package acl decides who may talk to the bot and which tools they get.
+//
+// Rules allow or deny senders by platform, channel, user, linked identity
+// or named group, and the most specific matching rule decides. An allowed
+// sender holds a role, and the role's tool policy narrows what the agent
+// may do on their behalf.
+package acl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pltanton/lingti-bot/internal/config"
+)
+
+// Builtin roles.
+const (
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+	RoleGuest  = "guest"
+
+	// RoleNone as the default role refuses senders that no rule allows.
+	RoleNone = "none"
+)
+
+// builtinRoles: admins get every tool; members everything except running
+// code and touching the host's files, clipboard and screen; guests only
+// read-only web and weather tools. Macros and site actions can run scripts,
+// so members cannot use them either.
+var builtinRoles = map[string]ToolPolicy{
+	RoleAdmin: {},
+	RoleMember: {Deny: []string{
+		"shell_execute", "file_*", "clipboard_*", "screenshot",
+		"browser_execute_js", "browser_upload", "browser_download",
+		"browser_macro_*", "browser_site_action",
+	}},
+	RoleGuest: {Allow: []string{"web_search", "web_fetch", "weather_*"}},
+}
+
+// ToolPolicy is an allowlist and a denylist of tool names. A name ending in
+// * matches every tool with that prefix.
+type ToolPolicy struct {
+	Allow []string // empty = allow all
+	Deny  []string // checked after Allow
+}
+
+// Allows reports whether the policy permits a tool.
+func (p ToolPolicy) Allows(tool string) bool {
+	if len(p.Allow) > 0 && !matchAny(p.Allow, tool) {
+		return false
+	}
+	return !matchAny(p.Deny, tool)
+}
+
+func matchAny(patterns []string, tool string) bool {
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(tool, prefix) {
+				return true
+			}
+		} else if p == tool {
+			return true
+		}
+	}
+	return false
+}
+
+// Request describes the sender of a message.
+type Request struct {
+	Platform  string
+	ChannelID string
+	UserID    string
+	Identity  string // canonical user from /link; "" means the account itself
+}
+
+// Decision is the outcome of an access check.
+type Decision struct {
+	Allowed   bool
+	Role      string     // role of an allowed sender
+	Tools     ToolPolicy // tools of Role
+	MatchedBy string     // the deciding rule, e.g. "deny platform=telegram"; "" for the default role
+	Comment   string     // comment of the deciding rule
+}
+
+// Reason explains the decision in one line, for /whoami, refusals and
+// `lingti-bot acl check`.
+func (d Decision) Reason() string {
+	var s string
+	switch {
+	case d.MatchedBy != "":
+		s = "rule: " + d.MatchedBy
+	case d.Allowed:
+		s = "default role"
+	default:
+		s = "no rule allows this user (default_role: none)"
+	}
+	if d.Comment != "" {
+		s += " (" + d.Comment + ")"
+	}
+	return s
+}
+
+// ACL evaluates access rules. A nil *ACL allows everyone every tool.
+type ACL struct {
+	defaultRole string
+	rules       []config.ACLRule
+	groups      map[string]map[string]bool
+	roles       map[string]ToolPolicy
+}
+
+// New builds an ACL from its configuration, checking that every action,
+// role and group it refers to exists.
+func New(cfg config.ACLConfig) (*ACL, error) {
+	l := &ACL{
+		defaultRole: cfg.DefaultRole,
+		rules:       cfg.Rules,
+		groups:      make(map[string]map[string]bool, len(cfg.Groups)),
+		roles:       make(map[string]ToolPolicy, len(builtinRoles)+len(cfg.Roles)),
+	}
+	if l.defaultRole == "" {
+		l.defaultRole = RoleMember
+	}
+	for name, policy := range builtinRoles {
+		l.roles[name] = policy
+	}
+	for name, role := range cfg.Roles {
+		if name == RoleNone {
+			return nil, fmt.Errorf("acl: role name %q is reserved", RoleNone)
+		}
+		l.roles[name] = ToolPolicy{Allow: role.AllowTools, Deny: role.DenyTools}
+	}
+	for name, members := range cfg.Groups {
+		set := make(map[string]bool, len(members))
+		for _, m := range members {
+			set[m] = true
+		}
+		l.groups[name] = set
+	}
+
+	if _, ok := l.roles[l.defaultRole]; !ok && l.defaultRole != RoleNone {
+		return nil, fmt.Errorf("acl: unknown default role %q", l.defaultRole)
+	}
+	for i, r := range cfg.Rules {
+		switch r.Action {
+		case "allow", "deny":
+		default:
+			return nil, fmt.Errorf("acl: rule %d: action must be allow or deny, got %q", i+1, r.Action)
+		}
+		if _, ok := l.roles[r.Role]; r.Role != "" && !ok {
+			return nil, fmt.Errorf("acl: rule %d: unknown role %q", i+1, r.Role)
+		}
+		if _, ok := l.groups[r.Match.Group]; r.Match.Group != "" && !ok {
+			return nil, fmt.Errorf("acl: rule %d: unknown group %q", i+1, r.Match.Group)
+		}
+	}
+	return l, nil
+}
+
+// Roles lists the names of the roles the ACL knows, sorted.
+func (l *ACL) Roles() []string {
+	names := make([]string, 0, len(l.roles))
+	for name := range l.roles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Role returns the tool policy of a role.
+func (l *ACL) Role(name string) (ToolPolicy, bool) {
+	p, ok := l.roles[name]
+	return p, ok
+}
+
+// Check decides whether a sender may use the bot and with which tools.
+//
+// Rules are weighted like agent bindings:
+//
+//	platform=1, channelID=2, group=4, identity=8, userID=16
+//
+// The highest scoring matching rule decides; a deny beats an allow of the
+// same score. Without a matching rule the sender gets the default role.
+func (l *ACL) Check(req Request) Decision {
+	if l == nil {
+		return Decision{Allowed: true, Role: RoleAdmin}
+	}
+	account := req.Platform + ":" + req.UserID
+	if req.Identity == "" {
+		req.Identity = account
+	}
+
+	best := -1
+	var rule config.ACLRule
+	var desc string
+	for _, r := range l.rules {
+		m := r.Match
+		// All non-empty fields must match.
+		if m.Platform != "" && m.Platform != req.Platform {
+			continue
+		}
+		if m.ChannelID != "" && m.ChannelID != req.ChannelID {
+			continue
+		}
+		if m.UserID != "" && m.UserID != req.UserID {
+			continue
+		}
+		if m.Identity != "" && m.Identity != req.Identity {
+			continue
+		}
+		if m.Group != "" && !l.groups[m.Group][account] && !l.groups[m.Group][req.Identity] {
+			continue
+		}
+
+		score := 0
+		if m.Platform != "" {
+			score += 1
+		}
+		if m.ChannelID != "" {
+			score += 2
+		}
+		if m.Group != "" {
+			score += 4
+		}
+		if m.Identity != "" {
+			score += 8
+		}
+		if m.UserID != "" {
+			score += 16
+		}
+		if score > best || score == best && r.Action == "deny" && rule.Action != "deny" {
+			best, rule, desc = score, r, Describe(r)
+		}
+	}
+
+	if best < 0 {
+		if l.defaultRole == RoleNone {
+			return Decision{}
+		}
+		return Decision{Allowed: true, Role: l.defaultRole, Tools: l.roles[l.defaultRole]}
+	}
+	if rule.Action == "deny" {
+		return Decision{MatchedBy: desc, Comment: rule.Comment}
+	}
+	role := rule.Role
+	if role == "" {
+		role = l.defaultRole
+		if role == RoleNone {
+			role = RoleMember
+		}
+	}
+	return Decision{Allowed: true, Role: role, Tools: l.roles[role], MatchedBy: desc, Comment: rule.Comment}
+}
+
+// Describe renders a rule as "action field=value ...", e.g.
+// "allow platform=slack user=U123 role=admin".
+func Describe(r config.ACLRule) string {
+	parts := []string{r.Action}
+	m := r.Match
+	for _, f := range []struct{ key, val string }{
+		{"platform", m.Platform},
+		{"channel", m.ChannelID},
+		{"group", m.Group},
+		{"identity", m.Identity},
+		{"user", m.UserID},
+	} {
+		if f.val != "" {
+			parts = append(parts, f.key+"="+f.val)
+		}
+	}
+	if len(parts) == 1 {
+		parts = append(parts, "everyone")
+	}
+	if r.Role != "" {
+		parts = append(parts, "role="+r.Role)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,146 @@
+package acl
+
+import (
+	"testing"
+
+	"github.com/pltanton/lingti-bot/internal/config"
+)
+
+func TestToolPolicy_Allows(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy ToolPolicy
+		tool   string
+		want   bool
+	}{
+		{"empty allows all", ToolPolicy{}, "shell_execute", true},
+		{"allowlist hit", ToolPolicy{Allow: []string{"web_search"}}, "web_search", true},
+		{"allowlist miss", ToolPolicy{Allow: []string{"web_search"}}, "web_fetch", false},
+		{"allow prefix", ToolPolicy{Allow: []string{"weather_*"}}, "weather_forecast", true},
+		{"deny exact", ToolPolicy{Deny: []string{"shell_execute"}}, "shell_execute", false},
+		{"deny prefix", ToolPolicy{Deny: []string{"browser_*"}}, "browser_click", false},
+		{"deny after allow", ToolPolicy{Allow: []string{"file_*"}, Deny: []string{"file_write"}}, "file_write", false},
+		{"prefix is not substring", ToolPolicy{Deny: []string{"file_*"}}, "profile_read", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Allows(tt.tool); got != tt.want {
+				t.Errorf("Allows(%q) = %v, want %v", tt.tool, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	cfg := config.ACLConfig{
+		Rules: []config.ACLRule{
+			{Action: "deny", Match: config.ACLMatch{Platform: "telegram"}, Comment: "public bot"},
+			{Action: "allow", Role: "guest", Match: config.ACLMatch{Platform: "telegram", ChannelID: "-100lobby"}},
+			{Action: "allow", Role: "admin", Match: config.ACLMatch{Platform: "slack", UserID: "U_OPS"}},
+			{Action: "allow", Role: "admin", Match: config.ACLMatch{Group: "oncall"}},
+			{Action: "deny", Match: config.ACLMatch{Group: "oncall", ChannelID: "C_PUBLIC"}},
+			{Action: "allow", Match: config.ACLMatch{Identity: "user-abc"}},
+			{Action: "deny", Match: config.ACLMatch{Platform: "discord", UserID: "troll"}},
+			{Action: "allow", Role: "admin", Match: config.ACLMatch{Platform: "discord", UserID: "troll"}},
+		},
+		Groups: map[string][]string{"oncall": {"slack:U_SRE", "user-def"}},
+	}
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		req       Request
+		allowed   bool
+		role      string
+		matchedBy string
+	}{
+		{"default role", Request{Platform: "slack", UserID: "U_ANY"}, true, RoleMember, ""},
+		{"platform deny", Request{Platform: "telegram", UserID: "1"}, false, "", "deny platform=telegram"},
+		{"channel allow beats platform deny", Request{Platform: "telegram", ChannelID: "-100lobby", UserID: "1"}, true, RoleGuest, "allow platform=telegram channel=-100lobby role=guest"},
+		{"user rule", Request{Platform: "slack", UserID: "U_OPS"}, true, RoleAdmin, "allow platform=slack user=U_OPS role=admin"},
+		{"group by account", Request{Platform: "slack", UserID: "U_SRE"}, true, RoleAdmin, "allow group=oncall role=admin"},
+		{"group by identity", Request{Platform: "wecom", UserID: "zhang", Identity: "user-def"}, true, RoleAdmin, "allow group=oncall role=admin"},
+		{"group in channel", Request{Platform: "slack", ChannelID: "C_PUBLIC", UserID: "U_SRE"}, false, "", "deny channel=C_PUBLIC group=oncall"},
+		{"identity outranks group", Request{Platform: "slack", UserID: "U_SRE", Identity: "user-abc"}, true, RoleMember, "allow identity=user-abc"},
+		{"deny wins tie", Request{Platform: "discord", UserID: "troll"}, false, "", "deny platform=discord user=troll"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := l.Check(tt.req)
+			if d.Allowed != tt.allowed || d.Role != tt.role || d.MatchedBy != tt.matchedBy {
+				t.Errorf("Check() = {allowed %v, role %q, matched %q}, want {%v, %q, %q}",
+					d.Allowed, d.Role, d.MatchedBy, tt.allowed, tt.role, tt.matchedBy)
+			}
+		})
+	}
+}
+
+func TestCheck_DefaultNone(t *testing.T) {
+	l, err := New(config.ACLConfig{
+		DefaultRole: RoleNone,
+		Rules:       []config.ACLRule{{Action: "allow", Match: config.ACLMatch{Platform: "slack"}}},
+		Roles:       map[string]config.ACLRole{"member": {AllowTools: []string{"web_*"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := l.Check(Request{Platform: "telegram", UserID: "1"})
+	if d.Allowed {
+		t.Fatal("unmatched sender allowed with default_role none")
+	}
+	if d.Reason() != "no rule allows this user (default_role: none)" {
+		t.Errorf("Reason() = %q", d.Reason())
+	}
+
+	d = l.Check(Request{Platform: "slack", UserID: "U1"})
+	if !d.Allowed || d.Role != RoleMember {
+		t.Fatalf("Check(slack) = %+v, want member", d)
+	}
+	if d.Tools.Allows("shell_execute") || !d.Tools.Allows("web_fetch") {
+		t.Errorf("overridden member role not applied: %+v", d.Tools)
+	}
+}
+
+func TestBuiltinMember_NoScripts(t *testing.T) {
+	member := builtinRoles[RoleMember]
+	for _, tool := range []string{"browser_execute_js", "browser_macro_run", "browser_macro_save", "browser_site_action"} {
+		if member.Allows(tool) {
+			t.Errorf("member may use %s", tool)
+		}
+	}
+	if !member.Allows("browser_click") {
+		t.Error("member may not use browser_click")
+	}
+}
+
+func TestCheck_Nil(t *testing.T) {
+	var l *ACL
+	d := l.Check(Request{Platform: "slack", UserID: "U1"})
+	if !d.Allowed || !d.Tools.Allows("shell_execute") {
+		t.Errorf("nil ACL = %+v, want everything allowed", d)
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.ACLConfig
+	}{
+		{"default role", config.ACLConfig{DefaultRole: "owner"}},
+		{"action", config.ACLConfig{Rules: []config.ACLRule{{Action: "permit"}}}},
+		{"rule role", config.ACLConfig{Rules: []config.ACLRule{{Action: "allow", Role: "owner"}}}},
+		{"group", config.ACLConfig{Rules: []config.ACLRule{{Action: "deny", Match: config.ACLMatch{Group: "ops"}}}}},
+		{"reserved role", config.ACLConfig{Roles: map[string]config.ACLRole{"none": {}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg); err == nil {
+				t.Error("New() succeeded, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/pltanton/lingti-bot/internal/acl"
+	"github.com/pltanton/lingti-bot/internal/router"
+)
+
+// checkAccess evaluates the ACL for the sender of a message.
+func (a *Agent) checkAccess(msg router.Message) acl.Decision {
+	id := msg.Identity
+	if id == "" && msg.UserID != "" {
+		id = a.identityOf(msg.Platform, msg.UserID)
+	}
+	return a.acl.Check(acl.Request{
+		Platform:  msg.Platform,
+		ChannelID: msg.ChannelID,
+		UserID:    msg.UserID,
+		Identity:  id,
+	})
+}
+
+// accessSummary describes a sender's access for /whoami.
+func accessSummary(d acl.Decision) string {
+	if !d.Allowed {
+		return "- 权限: 已拒绝（" + d.Reason() + "）"
+	}
+	return fmt.Sprintf("- 角色: %s（%s）", d.Role, d.Reason())
+}
+
+// toolAllowed reports whether a tool may run for a sender: both the agent's
+// allow_tools/deny_tools and the sender's role must permit it.
+func (a *Agent) toolAllowed(d acl.Decision, name string) bool {
+	return a.tools.Allows(name) && d.Tools.Allows(name)
+}
+
+// checkToolAccess returns an error if a tool may not run for a sender.
+func (a *Agent) checkToolAccess(d acl.Decision, name string) error {
+	if a.toolAllowed(d, name) {
+		return nil
+	}
+	if !a.tools.Allows(name) {
+		return fmt.Errorf("ACCESS DENIED: tool %s is not enabled for this agent. Do NOT retry. Inform the user that this tool is unavailable.", name)
+	}
+	return fmt.Errorf("ACCESS DENIED: tool %s is not available to role %s. Do NOT retry. Inform the user that their role does not allow it.", name, d.Role)
+}
+
+// filterTools drops the tools that may not run for a sender.
+func (a *Agent) filterTools(d acl.Decision, tools []Tool) []Tool {
+	allowed := tools[:0]
+	for _, t := range tools {
+		if a.toolAllowed(d, t.Name) {
+			allowed = append(allowed, t)
+		}
+	}
+	return allowed
+}
@@ -9,13 +9,18 @@ import (
 	"strings"
 	"time"
 
+	"github.com/pltanton/lingti-bot/internal/acl"
 	"github.com/pltanton/lingti-bot/internal/agent/mcpclient"
+	"github.com/pltanton/lingti-bot/internal/browser"
 	"github.com/pltanton/lingti-bot/internal/config"
 	cronpkg "github.com/pltanton/lingti-bot/internal/cron"
+	"github.com/pltanton/lingti-bot/internal/identity"
 	"github.com/pltanton/lingti-bot/internal/logger"
 	"github.com/pltanton/lingti-bot/internal/router"
 	"github.com/pltanton/lingti-bot/internal/security"
 	"github.com/pltanton/lingti-bot/internal/skills"
+	"github.com/pltanton/lingti-bot/internal/speech"
+	"github.com/pltanton/lingti-bot/internal/tools"
 )
 
 // Agent processes messages using AI providers and tools
@@ -26,13 +31,17 @@ type Agent struct {
 	autoApprove        bool
 	customInstructions string
 	cronScheduler      *cronpkg.Scheduler
-	currentMsg         router.Message // set during HandleMessage for cron_create context
-	cronCreatedCount   int            // tracks cron_create calls per HandleMessage turn
 	pathChecker        *security.PathChecker
 	disableFileTools   bool
 	maxToolRounds      int
 	callTimeoutSecs    int
 	mcpManager         *mcpclient.Manager
+	browserProfile     string
+	synthesizer        speech.Synthesizer
+	voiceReply         string
+	identities         *identity.Store
+	tools              acl.ToolPolicy // allow_tools/deny_tools of this agent
+	acl                *acl.ACL
 }
 
 // Config holds agent configuration
@@ -51,6 +60,11 @@ type Config struct {
 	AllowTools         []string // Tool whitelist; empty = allow all
 	DenyTools          []string // Tool blacklist; applied after allowlist
 	Workspace          string   // Working directory for this agent
+	BrowserProfile     string   // Browser login profile for this agent's chats (optional)
+	Synthesizer        speech.Synthesizer // Speaks replies when VoiceReply is set (optional)
+	VoiceReply         string             // "voice" (answer voice with voice), "always" or "" (never)
+	Identities         *identity.Store    // Links accounts across platforms for /link (optional)
+	ACL                *acl.ACL           // Who may talk to the agent and with which tools (optional)
 }
 
 // New creates a new Agent with the specified provider
@@ -79,6 +93,12 @@ func New(cfg Config) (*Agent, error) {
 		maxToolRounds:      maxRounds,
 		callTimeoutSecs:    cfg.CallTimeoutSecs,
 		mcpManager:         mcpclient.New(cfg.MCPServers),
+		browserProfile:     cfg.BrowserProfile,
+		synthesizer:        cfg.Synthesizer,
+		voiceReply:         cfg.VoiceReply,
+		identities:         cfg.Identities,
+		tools:              acl.ToolPolicy{Allow: cfg.AllowTools, Deny: cfg.DenyTools},
+		acl:                cfg.ACL,
 	}, nil
 }
 
@@ -170,18 +190,26 @@ func createProvider(cfg Config) (Provider, error) {
 }
 
 // handleBuiltinCommand handles special commands without calling AI
-func (a *Agent) handleBuiltinCommand(msg router.Message) (router.Response, bool) {
+func (a *Agent) handleBuiltinCommand(msg router.Message, convKey string) (router.Response, bool) {
 	text := strings.TrimSpace(msg.Text)
 	textLower := strings.ToLower(text)
-	convKey := ConversationKey(msg.Platform, msg.ChannelID, msg.UserID)
+
+	if resp, handled := a.handleLinkCommand(msg, textLower); handled {
+		return resp, true
+	}
 
 	// Exact match commands
 	switch textLower {
 	case "/whoami", "whoami", "我是谁", "我的id":
-		return router.Response{
-			Text: fmt.Sprintf("用户信息:\n- 用户ID: %s\n- 用户名: %s\n- 平台: %s\n- 频道ID: %s",
-				msg.UserID, msg.Username, msg.Platform, msg.ChannelID),
-		}, true
+		text := fmt.Sprintf("用户信息:\n- 用户ID: %s\n- 用户名: %s\n- 平台: %s\n- 频道ID: %s",
+			msg.UserID, msg.Username, msg.Platform, msg.ChannelID)
+		if msg.Identity != "" {
+			text += "\n- 身份: " + msg.Identity
+		}
+		if a.acl != nil {
+			text += "\n" + accessSummary(a.checkAccess(msg))
+		}
+		return router.Response{Text: text}, true
 
 	case "/help", "help", "帮助", "/commands":
 		return router.Response{
@@ -190,6 +218,7 @@ func (a *Agent) handleBuiltinCommand(msg router.Message) (router.Response, bool)
 会话管理:
   /new, /reset    开始新对话，清除历史
   /status         查看当前会话状态
+  /stop, /cancel  停止正在执行的任务
 
 思考模式:
   /think off      关闭深度思考
@@ -203,6 +232,8 @@ func (a *Agent) handleBuiltinCommand(msg router.Message) (router.Response, bool)
 
 其他:
   /whoami         查看用户信息
+  /link           关联其他平台的账号
+  /unlink         解除本账号的关联
   /model          查看当前模型
   /tools          列出可用工具
   /help           显示帮助
@@ -318,9 +349,32 @@ func (a *Agent) ExecuteTool(ctx context.Context, toolName string, arguments map[
 	return result, nil
 }
 
+// ExecuteMacro replays a saved browser macro in the browser session of the
+// given chat, so an AI fallback for the same chat continues on the same page.
+// Used by cron scheduler for macro-based jobs.
+func (a *Agent) ExecuteMacro(ctx context.Context, platform, channelID, userID, scope, name string) (string, error) {
+	ctx = a.browserContext(ctx, a.jobConversationKey(platform, channelID, userID, scope), platform, userID)
+	defer browser.Instance().Hold(ctx)()
+	return tools.RunMacro(ctx, name)
+}
+
+// browserContext points browser tools at the conversation's session and, if
+// the agent has one, its login profile, and gives them the sender's macros
+// and profiles.
+func (a *Agent) browserContext(ctx context.Context, convKey, platform, userID string) context.Context {
+	ctx = browser.ContextWithSession(ctx, convKey)
+	if userID != "" {
+		ctx = browser.ContextWithOwner(ctx, a.identityOf(platform, userID))
+	}
+	if a.browserProfile != "" {
+		ctx = browser.ContextWithProfile(ctx, a.browserProfile)
+	}
+	return ctx
+}
+
 // ExecutePrompt runs a full AI conversation with tools and returns the text response.
 // Used by cron scheduler for prompt-based jobs.
-func (a *Agent) ExecutePrompt(ctx context.Context, platform, channelID, userID, prompt string) (string, error) {
+func (a *Agent) ExecutePrompt(ctx context.Context, platform, channelID, userID, scope, prompt string) (string, error) {
 	msg := router.Message{
 		Platform:  platform,
 		ChannelID: channelID,
@@ -328,29 +382,88 @@ func (a *Agent) ExecutePrompt(ctx context.Context, platform, channelID, userID,
 		Username:  "cron",
 		Text:      prompt,
 	}
-	resp, err := a.HandleMessage(ctx, msg)
+	ctx = router.ContextWithConversationKey(ctx, a.jobConversationKey(platform, channelID, userID, scope))
+	ctx = router.ContextWithConversationScope(ctx, scope)
+	resp, err := a.handleMessage(ctx, msg)
 	if err != nil {
 		return "", err
 	}
 	return resp.Text, nil
 }
 
+// jobConversationKey returns the key of the conversation a cron job was
+// created in, built the way the router built it under the job's scope.
+func (a *Agent) jobConversationKey(platform, channelID, userID, scope string) string {
+	msg := router.Message{Platform: platform, ChannelID: channelID, UserID: userID}
+	if scope == router.ScopeIdentity {
+		msg.Identity = a.identityOf(platform, userID)
+	}
+	return router.ScopedConversationKey(msg, scope)
+}
+
 // HandleMessage processes a message and returns a response
 func (a *Agent) HandleMessage(ctx context.Context, msg router.Message) (router.Response, error) {
-	a.currentMsg = msg
-	a.cronCreatedCount = 0
+	resp, err := a.handleMessage(ctx, msg)
+	if err == nil {
+		a.addVoiceReply(ctx, msg, &resp)
+	}
+	return resp, err
+}
+
+// turn is the state of one message being handled. It travels in the
+// context, so conversations handled at the same time each see their own
+// sender and access.
+type turn struct {
+	msg         router.Message
+	access      acl.Decision
+	scope       string // conversation scope the router keyed the turn with
+	cronCreated int    // cron_create calls so far
+}
+
+type turnKey struct{}
+
+func contextWithTurn(ctx context.Context, t *turn) context.Context {
+	return context.WithValue(ctx, turnKey{}, t)
+}
+
+// turnFromContext returns the turn of ctx, or an empty one outside
+// handleMessage, whose zero access does not restrict tools.
+func turnFromContext(ctx context.Context) *turn {
+	if t, ok := ctx.Value(turnKey{}).(*turn); ok {
+		return t
+	}
+	return &turn{}
+}
+
+// handleMessage runs the agent loop for a message
+func (a *Agent) handleMessage(ctx context.Context, msg router.Message) (router.Response, error) {
 	logger.Info("[Agent] Processing message from %s: %s (provider: %s)", msg.Username, msg.Text, a.provider.Name())
 
+	// Use the router's conversation key so history follows its scoping
+	convKey := router.ConversationKeyFromContext(ctx)
+	if convKey == "" {
+		convKey = ConversationKey(msg.Platform, msg.ChannelID, msg.UserID)
+	}
+	// Browser tools work in this conversation's own session, which is kept
+	// open until the turn ends
+	ctx = a.browserContext(ctx, convKey, msg.Platform, msg.UserID)
+	defer browser.Instance().Hold(ctx)()
+
+	// Refuse senders the ACL does not allow, except for /whoami and /link
+	access := a.checkAccess(msg)
+	if !access.Allowed && !router.RefusedMayUse(msg.Text) {
+		logger.Info("[Agent] Refused %s:%s (%s)", msg.Platform, msg.UserID, access.Reason())
+		return router.Response{Text: router.RefusalText(access)}, nil
+	}
+	ctx = contextWithTurn(ctx, &turn{msg: msg, access: access, scope: router.ConversationScopeFromContext(ctx)})
+
 	// Handle built-in commands
-	if resp, handled := a.handleBuiltinCommand(msg); handled {
+	if resp, handled := a.handleBuiltinCommand(msg, convKey); handled {
 		return resp, nil
 	}
 
-	// Generate conversation key
-	convKey := ConversationKey(msg.Platform, msg.ChannelID, msg.UserID)
-
-	// Build the tools list
-	tools := a.buildToolsList()
+	// Build the tools list, keeping only what the agent and sender may use
+	tools := a.filterTools(access, a.buildToolsList())
 
 	// Get conversation history
 	history := a.memory.GetHistory(convKey)
@@ -464,6 +577,9 @@ func (a *Agent) HandleMessage(ctx context.Context, msg router.Message) (router.R
 - music_volume: Set volume
 - music_search: Search and play
 
+### Task State
+- task_checkpoint: Save/load progress of long multi-step tasks (resume after restart)
+
 ### Scheduled Tasks (Cron)
 - cron_create: Create ONE scheduled task with 'prompt' parameter. The AI runs a full conversation each trigger (can use web_search, weather, etc.) and sends the result to the user. For raw tool execution, use 'tool'+'arguments' instead.
 - cron_list: List all scheduled tasks with their status
@@ -474,25 +590,33 @@ func (a *Agent) HandleMessage(ctx context.Context, msg router.Message) (router.R
 ### Browser Automation (snapshot-then-act pattern)
 - browser_start: Start new browser or connect to existing Chrome via cdp_url (e.g. "127.0.0.1:9222")
 - browser_navigate: Navigate to a URL (auto-connects to Chrome on port 9222 if available, otherwise launches new)
-- browser_snapshot: Capture accessibility tree with numbered refs
+- browser_snapshot: Capture accessibility tree with numbered refs (mode=diff for only what changed; scope_ref/scope_selector, viewport_only, interactive_only to narrow it)
 - browser_click: Click an element by ref number
 - browser_type: Type text into element by ref number (optional submit with Enter)
 - browser_press: Press keyboard key (Enter, Tab, Escape, etc.)
 - browser_execute_js: Run JavaScript on the page (dismiss modals, extract data, etc.)
 - browser_click_all: Click ALL elements matching a CSS selector with delay (batch like/follow)
+- browser_upload: Attach local files to a file input or upload button by ref
+- browser_download: Click a ref or open a URL and save the downloaded file; it is sent to the user automatically
+- browser_extract: Extract tables/lists as clean JSON or CSV (CSS/ARIA field mapping or JSON schema, pagination, dedup); also works on a URL without the browser
+- browser_network: Record XHR/fetch responses (start, wait, list, get, stop) to read the JSON behind a page
+- browser_site_action: Run a verified action from the current site's recipe (offered only on sites with a recipe)
+- browser_macro_save: Save the browser actions done so far as a replayable macro
+- browser_macro_run: Replay a saved macro without re-planning each step
+- browser_macro_list: List saved macros and the unsaved recording
 - browser_screenshot: Take page screenshot
 - browser_tabs: List all open tabs
 - browser_tab_open: Open new tab
 - browser_tab_close: Close a tab
 - browser_status: Check browser state
-- browser_stop: Close browser (or disconnect from external Chrome)
+- browser_stop: Close this chat's browser session
 
 ## Browser Automation Rules
 You MUST follow the **snapshot-then-act** pattern for ALL browser interactions:
 1. **Navigate** to the target website using browser_navigate (skip if the browser is already on the right site)
 2. **Snapshot** the page using browser_snapshot to discover UI elements and their ref numbers
 3. **Interact** with elements step by step using browser_click / browser_type / browser_press
-4. **Re-snapshot** after any page change (click, navigation, form submit) to get updated refs
+4. **Re-snapshot** after any page change (click, navigation, form submit) to get updated refs — prefer browser_snapshot mode="diff" on the same page, since refs stay stable and only changes are returned
 
 **CRITICAL: If the browser is already open on a website, continue working on that page — do NOT re-navigate.**
 - If you previously called browser_navigate to 知乎 and the user now says "搜索XXX", take browser_snapshot on the current 知乎 page, find the search input, and type into it.
@@ -514,36 +638,7 @@ Use the page's UI elements (search boxes, buttons, menus) to accomplish the task
 - Seeing a page snapshot in a tool result means: "here is the current state — what should I do next?"
 - If you see a login modal or any obstacle, handle it (dismiss, log in, or report to user) — do not silently stop.
 
-**Zhihu (知乎) commenting — EXACT VERIFIED METHOD:**
-Zhihu uses a Draft.js editor. Direct DOM manipulation (innerHTML, value=, execCommand insertText) does NOT update Draft.js internal state — the 发布 button will stay DISABLED. You MUST use ClipboardEvent paste.
-
-PREFERRED: call browser_comment_zhihu(comment="...") — handles everything automatically.
-To reply to a specific person's comment (nested reply), use browser_comment_zhihu(comment="...", reply_to="username"). The tool will find that user's 回复 button and post a nested reply instead of a top-level comment.
-
-If using mcp_chrome_evaluate_script instead, use EXACTLY this 3-step sequence:
-
-Step 1 — Click the comment trigger (button with 评论 in text):
-  function: "() => { var btn = Array.from(document.querySelectorAll('button,span')).find(function(e){ var t = e.textContent.replace(/\u200b/g,'').trim(); return /^[\d\s]*条?评论$/.test(t) || t === '添加评论'; }); if(btn){btn.click();return 'clicked:'+btn.textContent.trim();} return 'not found'; }"
-
-Step 2 — Wait ~1s for editor to appear, then paste via ClipboardEvent (CRITICAL — do NOT use execCommand or innerHTML):
-  function: "() => { var ed = document.querySelector('.public-DraftEditor-content'); if(!ed){return 'editor not found';} ed.click(); ed.focus(); document.execCommand('selectAll',false); var dt = new DataTransfer(); dt.setData('text/plain', 'COMMENT_TEXT'); ed.dispatchEvent(new ClipboardEvent('paste',{clipboardData:dt,bubbles:true,cancelable:true})); return 'pasted'; }"
-
-Step 3 — Wait ~600ms then click 发布 by text match (querySelector returns search button first, MUST find by text):
-  function: "() => { var btn = Array.from(document.querySelectorAll('button')).find(function(b){ return b.textContent.replace(/\u200b/g,'').trim() === '发布'; }); if(btn&&!btn.disabled){btn.click();return 'submitted';} if(btn&&btn.disabled){return 'button disabled — paste step likely failed';} return 'submit btn not found'; }"
-
-Replace COMMENT_TEXT with actual comment. If 发布 is disabled after paste, retry step 2 — the editor may not have focused properly.
-DO NOT click "写回答" — that writes a full answer, not a comment.
-
-**Xiaohongshu (小红书) commenting — EXACT VERIFIED METHOD:**
-Xiaohongshu uses a contenteditable <p id="content-textarea">. Direct DOM manipulation (textContent=, innerText=) does NOT update the framework state — the 发送 button stays DISABLED. You MUST use ClipboardEvent paste.
-
-PREFERRED: call browser_comment_xiaohongshu(comment="...") — handles everything automatically.
-
-If using mcp_chrome_evaluate_script instead, use EXACTLY this 2-step sequence:
-Step 1 — Paste comment via ClipboardEvent:
-  function: "() => { var ed = document.querySelector('#content-textarea'); if(!ed) return 'not found'; ed.focus(); ed.textContent=''; ed.dispatchEvent(new Event('input',{bubbles:true})); var dt = new DataTransfer(); dt.setData('text/plain', 'COMMENT_TEXT'); ed.dispatchEvent(new ClipboardEvent('paste',{clipboardData:dt,bubbles:true,cancelable:true})); return 'pasted'; }"
-Step 2 — Click 发送:
-  function: "() => { var btn = Array.from(document.querySelectorAll('button')).find(function(b){ return b.textContent.trim() === '发送'; }); if(btn&&!btn.disabled){btn.click();return 'submitted';} if(btn&&btn.disabled){return 'button disabled — paste step likely failed';} return 'submit btn not found'; }"
+**Site recipes:** Sites with known quirks have recipes. While the browser is on such a site, its notes appear under "Site Recipe" below and browser_site_action runs its verified actions (commenting, opening items, …). ALWAYS prefer browser_site_action over manual clicking and typing when it offers the action you need.
 
 **Handling modals/overlays:** If an element is blocked by a modal or overlay (error message mentions "element covered by"), use browser_execute_js to dismiss it. Example scripts:
 - document.querySelector('.modal-overlay').remove()
@@ -563,21 +658,11 @@ Do NOT waste rounds — try clicking first, inspect only if it fails.
 3. Click the article to open it, perform the action (comment, like, etc.)
 4. Call browser_visited(action="mark", url=...) to record it
 5. Navigate back to search results and continue with next article
-This prevents re-processing articles and survives page reloads within the same session.
-
-**CRITICAL: Xiaohongshu (小红书) anti-bot protection — NEVER navigate to XHS article URLs directly.**
-XHS blocks direct URL navigation and returns 404 security pages. You MUST open articles by CLICKING on them from the search results page.
-Correct workflow:
-1. Navigate to https://www.xiaohongshu.com and search via the search box
-2. On the search results page, use browser_execute_js to click on article covers/titles:
-   function: "() => { var items = document.querySelectorAll('section.note-item a.cover'); if(items.length > INDEX) { items[INDEX].click(); return 'clicked item INDEX'; } return 'not found'; }"
-   Replace INDEX with the 0-based index of the article to open.
-3. After the article detail opens (as an overlay/modal), perform actions (comment, like)
-4. Close the overlay by pressing Escape or clicking outside, then continue with the next article
-5. NEVER use browser_navigate to go to a xiaohongshu.com/explore/... URL — it WILL fail with 404
-
-**Xiaohongshu (小红书) like/点赞:** On a note detail page, click the heart icon. Use browser_execute_js:
-  function: "() => { var likeBtn = document.querySelector('.like-wrapper:not(.active), [class*=\"like\"]:not(.active)'); if(likeBtn){likeBtn.click();return 'liked';} return 'already liked or not found'; }"
+This prevents re-processing articles; the visited set survives restarts. Pass the same task name to keep separate jobs apart.
+
+**Macros:** Successful browser actions are recorded. When the user wants a browser task repeated (e.g. "每天帮我签到"), finish it once, then save it with browser_macro_save and schedule it with cron_create(macro=..., prompt=...) — the macro replays without AI and the prompt is only used if replay fails. If browser_macro_run fails, finish the listed remaining steps yourself.
+
+**Long tasks:** For tasks with many steps, call task_checkpoint(action="load", task=...) first and resume from the saved progress if any. After each completed item call task_checkpoint(action="save", ...) with what is done and what remains, and clear it when the task is finished.
 
 ## Important Rules
 1. **ALWAYS use tools** - Never tell users to do things manually
@@ -595,15 +680,26 @@ Correct workflow:
 
 Current date: %s%s%s`, autoApprovalNotice, runtime.GOOS, runtime.GOARCH, homeDir, homeDir, homeDir, homeDir, msg.Username, time.Now().Format("2006-01-02"), thinkingPrompt, formatSkillsSection())
 
+	if supportsVision(a.provider) {
+		systemPrompt += browserVisionPrompt
+	}
+
 	if a.customInstructions != "" {
 		systemPrompt += "\n\n## Custom Instructions\n" + a.customInstructions
 	}
 
+	// Group chats: show the discussion around the message the bot was asked about
+	if history := router.ChannelHistoryFromContext(ctx); len(history) > 0 {
+		systemPrompt += "\n\n## Recent Channel Messages\nThe messages before the user's request in this chat, oldest first, for context:\n" +
+			router.FormatChannelHistory(history)
+	}
+
 	// Call AI provider
+	turnPrompt, turnTools := withSiteRecipes(ctx, systemPrompt, tools)
 	resp, err := a.provider.Chat(ctx, ChatRequest{
 		Messages:       messages,
-		SystemPrompt:   systemPrompt,
-		Tools:          tools,
+		SystemPrompt:   turnPrompt,
+		Tools:          turnTools,
 		MaxTokens:      4096,
 		ThinkingBudget: thinkingBudget,
 	})
@@ -619,6 +715,10 @@ Current date: %s%s%s`, autoApprovalNotice, runtime.GOOS, runtime.GOARCH, homeDir
 		if resp.FinishReason != "tool_use" {
 			break
 		}
+		// Stop between rounds once the turn is cancelled (e.g. by /stop)
+		if err := ctx.Err(); err != nil {
+			return router.Response{}, err
+		}
 
 		// Process tool calls and track counts; detect stalls
 		stallHint := ""
@@ -630,10 +730,9 @@ Current date: %s%s%s`, autoApprovalNotice, runtime.GOOS, runtime.GOARCH, homeDir
 			}
 			if count >= 3 && strings.HasPrefix(tc.Name, "browser_") {
 				stallHint = fmt.Sprintf(
-					"\n\n[SYSTEM HINT] You have called %s %d times in a row. STOP and use the dedicated comment tool instead. "+
-						"For Zhihu: call browser_comment_zhihu(comment=\"...\"). "+
-						"For Xiaohongshu: call browser_comment_xiaohongshu(comment=\"...\"). "+
-						"These handle everything automatically. Do NOT keep clicking buttons or interacting manually.",
+					"\n\n[SYSTEM HINT] You have called %s %d times in a row. STOP and check whether browser_site_action offers the action you need "+
+						"(e.g. browser_site_action(action=\"comment\", args={\"comment\": \"...\"})). "+
+						"Site actions handle everything automatically. Do NOT keep clicking buttons or interacting manually.",
 					tc.Name, count,
 				)
 			}
@@ -689,10 +788,12 @@ Current date: %s%s%s`, autoApprovalNotice, runtime.GOOS, runtime.GOARCH, homeDir
 		}
 		callTimeout := baseTimeout + time.Duration(min(len(messages), 90))*time.Second
 		logger.Info("[Agent] Calling AI (round %d/%d, forceToolUse=%v, timeout=%s, user: %s)", round+2, maxToolRounds, hasBrowserTool, callTimeout, msg.Username)
+		// The page may have changed this round, so re-match site recipes
+		turnPrompt, turnTools = withSiteRecipes(ctx, systemPrompt, tools)
 		chatReq := ChatRequest{
 			Messages:       messages,
-			SystemPrompt:   systemPrompt,
-			Tools:          tools,
+			SystemPrompt:   turnPrompt,
+			Tools:          turnTools,
 			MaxTokens:      4096,
 			ForceToolUse:   hasBrowserTool,
 			ThinkingBudget: thinkingBudget,
@@ -778,6 +879,62 @@ func formatSkillsSection() string {
 	return sb.String()
 }
 
+// withSiteRecipes adds the notes and the browser_site_action tool of the site
+// recipes matching the page this conversation's browser is on.
+func withSiteRecipes(ctx context.Context, systemPrompt string, tools []Tool) (string, []Tool) {
+	pageURL := browser.Instance().PageURL(ctx)
+	if pageURL == "" {
+		return systemPrompt, tools
+	}
+	matched := browser.MatchRecipes(browser.LoadRecipes(), pageURL)
+	if len(matched) == 0 {
+		return systemPrompt, tools
+	}
+
+	var sb strings.Builder
+	var sites, actions []string
+	for _, r := range matched {
+		sites = append(sites, r.Name)
+		fmt.Fprintf(&sb, "\n\n## Site Recipe: %s\n%s\n", r.Name, r.Description)
+		if r.Notes != "" {
+			sb.WriteString(strings.TrimSpace(r.Notes) + "\n")
+		}
+		if len(r.Actions) > 0 {
+			sb.WriteString("Actions (call browser_site_action):\n")
+		}
+		for _, name := range r.ActionNames() {
+			action := r.Actions[name]
+			actions = append(actions, name)
+			fmt.Fprintf(&sb, "- %s: %s\n", name, action.Description)
+			for _, p := range action.Params {
+				required := ""
+				if p.Required {
+					required = " (required)"
+				}
+				fmt.Fprintf(&sb, "  - args.%s%s: %s\n", p.Name, required, p.Description)
+			}
+		}
+	}
+	if len(actions) == 0 {
+		return systemPrompt + sb.String(), tools
+	}
+
+	siteAction := Tool{
+		Name:        "browser_site_action",
+		Description: "Run a verified action from the site recipe of the current page (" + strings.Join(sites, ", ") + "). See the Site Recipe section of the system prompt for each action's arguments. Prefer this over manual clicking and typing.",
+		InputSchema: jsonSchema(map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"action": map[string]any{"type": "string", "enum": actions, "description": "Action name"},
+				"site":   map[string]string{"type": "string", "description": "Recipe name, only needed when several recipes match the page"},
+				"args":   map[string]string{"type": "object", "description": "Action arguments, e.g. {\"comment\": \"...\"}"},
+			},
+			"required": []string{"action"},
+		}),
+	}
+	return systemPrompt + sb.String(), append(tools[:len(tools):len(tools)], siteAction)
+}
+
 // buildToolsList creates the tools list for the AI provider
 func (a *Agent) buildToolsList() []Tool {
 	tools := []Tool{
@@ -1251,6 +1408,7 @@ func (a *Agent) buildToolsList() []Tool {
 					"cdp_url":  map[string]string{"type": "string", "description": "CDP address of existing Chrome (e.g. 127.0.0.1:9222). Chrome must be started with --remote-debugging-port flag."},
 					"headless": map[string]string{"type": "boolean", "description": "Launch in headless mode (default: false, ignored when using cdp_url)"},
 					"url":      map[string]string{"type": "string", "description": "Initial URL to navigate to"},
+					"profile":  map[string]string{"type": "string", "description": "Login profile for this chat (e.g. 'zhihu-work'): its saved cookies and localStorage are loaded, and logins made now are saved to it"},
 				},
 			}),
 		},
@@ -1265,8 +1423,17 @@ func (a *Agent) buildToolsList() []Tool {
 		},
 		{
 			Name:        "browser_snapshot",
-			Description: "Capture the page accessibility tree with numbered refs. Use these ref numbers with browser_click/browser_type to interact with elements. MUST re-run after any page change.",
-			InputSchema: jsonSchema(map[string]any{"type": "object", "properties": map[string]any{}}),
+			Description: "Capture the page accessibility tree with numbered refs. Use these ref numbers with browser_click/browser_type to interact with elements. MUST re-run after any page change. Refs stay the same for an element across snapshots of one page, so mode=diff returns only added (+), removed (-) and changed (~) elements.",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"mode":             map[string]any{"type": "string", "enum": []string{"full", "diff"}, "description": "full (default) or diff: only changes since the previous snapshot with the same scope"},
+					"scope_ref":        map[string]string{"type": "number", "description": "Only the subtree of this ref (e.g. a dialog or list)"},
+					"scope_selector":   map[string]string{"type": "string", "description": "Only the subtree of the first element matching this CSS selector"},
+					"viewport_only":    map[string]string{"type": "boolean", "description": "Only elements currently visible on screen"},
+					"interactive_only": map[string]string{"type": "boolean", "description": "Only interactive elements (buttons, links, inputs), no headings or text"},
+				},
+			}),
 		},
 		{
 			Name:        "browser_click",
@@ -1322,36 +1489,76 @@ func (a *Agent) buildToolsList() []Tool {
 			}),
 		},
 		{
-			Name:        "browser_comment_zhihu",
-			Description: "Post a top-level comment OR a nested reply on Zhihu. Must already be on the Zhihu page. For a top-level comment omit reply_to. To reply to a specific person's comment, set reply_to to their username (e.g. \"Jockery\") — the tool will find their 回复 button and post a nested reply. Handles both Draft.js and plain textarea editors automatically.",
+			Name:        "browser_extract",
+			Description: "Extract structured rows (tables, lists, search results) as clean JSON or CSV instead of scraping with browser_execute_js or reading a truncated snapshot. Works on the current browser page, on a url fetched without the browser, or on raw html. Without items/fields it returns the rows of the largest table. Follows pagination (next_ref, next_selector or scroll) and removes duplicate rows.",
 			InputSchema: jsonSchema(map[string]any{
 				"type": "object",
 				"properties": map[string]any{
-					"comment":  map[string]string{"type": "string", "description": "The comment text to post"},
-					"reply_to": map[string]string{"type": "string", "description": "Username to reply to (nested reply). Omit for a top-level comment."},
+					"items":         map[string]string{"type": "string", "description": "CSS selector matching one record, e.g. '.search-result' (default: rows of the largest table)"},
+					"fields":        map[string]string{"type": "object", "description": "Field name → selector within a record: 'h3 a' (text), 'h3 a@href' (attribute), '@data-id' (attribute of the record), 'role:heading' (ARIA role). With a table, a field without selector is matched to a column header."},
+					"schema":        map[string]string{"type": "object", "description": "JSON schema of a record (or an array of records). Properties name the fields and set types (string, number, integer, boolean); a property may carry a 'selector'."},
+					"url":           map[string]string{"type": "string", "description": "Fetch this URL over HTTP and extract from it, without the browser"},
+					"html":          map[string]string{"type": "string", "description": "Extract from this HTML instead of a page"},
+					"next_ref":      map[string]string{"type": "number", "description": "Ref of the 'next page' button to click between pages (browser only)"},
+					"next_selector": map[string]string{"type": "string", "description": "CSS selector of the 'next page' link or button (browser or url)"},
+					"scroll":        map[string]string{"type": "boolean", "description": "Scroll down to load more between pages (infinite scroll, browser only)"},
+					"max_pages":     map[string]string{"type": "number", "description": "Pages to read (default: 1, or 5 with pagination; max 50)"},
+					"limit":         map[string]string{"type": "number", "description": "Maximum rows to return"},
+					"dedupe_by":     map[string]any{"type": "array", "items": map[string]string{"type": "string"}, "description": "Fields identifying a row, e.g. ['url'] (default: all fields)"},
+					"format":        map[string]string{"type": "string", "description": "json (default) or csv"},
+					"save_to":       map[string]string{"type": "string", "description": "Write the result to this file instead of returning it"},
 				},
-				"required": []string{"comment"},
 			}),
 		},
 		{
-			Name:        "browser_comment_xiaohongshu",
-			Description: "Post a comment on a Xiaohongshu (小红书) note. Must already be on a Xiaohongshu note detail page (with the comment input visible at the bottom). Automatically types the comment into the editor via ClipboardEvent paste and clicks 发送 to submit. Use this instead of browser_click + browser_type for Xiaohongshu commenting.",
+			Name:        "browser_upload",
+			Description: "Attach local files to a file input, or to an upload button that opens a file chooser, identified by ref from browser_snapshot. Most sites still need a submit click afterwards.",
 			InputSchema: jsonSchema(map[string]any{
 				"type": "object",
 				"properties": map[string]any{
-					"comment": map[string]string{"type": "string", "description": "The comment text to post"},
+					"ref":   map[string]string{"type": "number", "description": "Ref number of the file input or upload button"},
+					"paths": map[string]any{"type": "array", "items": map[string]string{"type": "string"}, "description": "Local file paths to upload"},
 				},
-				"required": []string{"comment"},
+				"required": []string{"ref", "paths"},
+			}),
+		},
+		{
+			Name:        "browser_download",
+			Description: "Download a file by clicking a ref (e.g. an 'Export' button) or opening a URL. The file is saved in the download directory and sent to the user automatically.",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"ref":         map[string]string{"type": "number", "description": "Ref number of the element that starts the download"},
+					"url":         map[string]string{"type": "string", "description": "URL to download instead of clicking a ref"},
+					"dir":         map[string]string{"type": "string", "description": "Directory to save to (default: browser.download_dir or ~/.lingti/downloads)"},
+					"timeout_sec": map[string]string{"type": "number", "description": "Seconds to wait for the download to finish (default: 60)"},
+				},
+			}),
+		},
+		{
+			Name:        "browser_network",
+			Description: "Record XHR/fetch responses of the current tab to read the data behind a page (e.g. the JSON of a report table) instead of scraping it. 'start' with an optional url_pattern regexp, trigger the requests (navigate, click, scroll), then 'wait' or 'list', and 'get' a body by index. 'stop' when done.",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"action":      map[string]string{"type": "string", "description": "One of: start, wait, list, get, stop"},
+					"url_pattern": map[string]string{"type": "string", "description": "Regexp the request URL must match (start only, default: all), e.g. 'api/report'"},
+					"index":       map[string]string{"type": "number", "description": "Entry number from list (get only)"},
+					"save_to":     map[string]string{"type": "string", "description": "Write the full body to this file instead of returning it (get only)"},
+					"timeout_sec": map[string]string{"type": "number", "description": "Seconds to wait for a new response (wait only, default: 10)"},
+				},
+				"required": []string{"action"},
 			}),
 		},
 		{
 			Name:        "browser_visited",
-			Description: "Track visited URLs during iterative browser operations (e.g., commenting on all search results). Use 'check' before processing a page to skip already-visited ones, 'mark' after processing, 'list' to see all visited URLs, 'clear' to reset. URLs are normalized (query params stripped) so the same page is recognized regardless of navigation path.",
+			Description: "Track visited URLs during iterative browser operations (e.g., commenting on all search results). Use 'check' before processing a page to skip already-visited ones, 'mark' after processing, 'list' to see all visited URLs, 'clear' to reset. URLs are normalized per site so the same page is recognized regardless of navigation path. Persisted across restarts.",
 			InputSchema: jsonSchema(map[string]any{
 				"type": "object",
 				"properties": map[string]any{
 					"action": map[string]string{"type": "string", "description": "One of: check, mark, list, clear"},
 					"url":    map[string]string{"type": "string", "description": "The URL to check or mark (required for check/mark, ignored for list/clear)"},
+					"task":   map[string]string{"type": "string", "description": "Task name to keep separate visited sets for different jobs in the same chat (optional)"},
 				},
 				"required": []string{"action"},
 			}),
@@ -1395,10 +1602,55 @@ func (a *Agent) buildToolsList() []Tool {
 		},
 		{
 			Name:        "browser_stop",
-			Description: "Close the browser",
+			Description: "Close this chat's browser session (tabs and page state); the browser keeps running for other chats",
+			InputSchema: jsonSchema(map[string]any{"type": "object", "properties": map[string]any{}}),
+		},
+
+		{
+			Name:        "browser_macro_save",
+			Description: "Save the browser actions recorded in this chat since the last save (navigate, click, type, press, execute_js, click_all, site_action) as a replayable macro. Elements are stored as selectors with role/name fallbacks, not ref numbers. Use browser_macro_list to see the recording and from_step to drop exploratory steps at the start.",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name":        map[string]string{"type": "string", "description": "Macro name (letters, digits, - and _), e.g. 'daily-checkin'"},
+					"description": map[string]string{"type": "string", "description": "What the macro does"},
+					"from_step":   map[string]string{"type": "number", "description": "First recorded step to include, 1-based (default: 1)"},
+				},
+				"required": []string{"name"},
+			}),
+		},
+		{
+			Name:        "browser_macro_run",
+			Description: "Replay a saved browser macro deterministically, without planning each step. If a step fails, the result lists the failed step and the remaining ones — finish those with the normal browser tools.",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name": map[string]string{"type": "string", "description": "Macro name"},
+				},
+				"required": []string{"name"},
+			}),
+		},
+		{
+			Name:        "browser_macro_list",
+			Description: "List saved browser macros and the steps recorded in this chat that are not saved yet",
 			InputSchema: jsonSchema(map[string]any{"type": "object", "properties": map[string]any{}}),
 		},
 
+		// === TASK STATE ===
+		{
+			Name:        "task_checkpoint",
+			Description: "Save and restore progress of a long multi-step task so it can resume after a crash or restart. Call 'load' before starting a long task, and 'save' after each completed item with what is done and what remains. 'clear' when the task is finished. Persisted per chat.",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"action": map[string]string{"type": "string", "description": "One of: save, load, clear, list"},
+					"task":   map[string]string{"type": "string", "description": "Task name, e.g. 'xhs-comments-AI' (required except for list)"},
+					"data":   map[string]string{"type": "string", "description": "Progress to save, e.g. JSON with completed items and next step (required for save)"},
+				},
+				"required": []string{"action"},
+			}),
+		},
+
 		// === SCHEDULED TASKS (CRON) ===
 		{
 			Name:        "cron_create",
@@ -1410,6 +1662,7 @@ func (a *Agent) buildToolsList() []Tool {
 					"schedule":  map[string]string{"type": "string", "description": "Cron expression (e.g., '43 * * * *' for every hour at :43, '0 9 * * 1-5' for weekdays at 9am)"},
 					"prompt":    map[string]string{"type": "string", "description": "What the AI should do each time this job triggers. AI runs a full conversation and sends the result to the user. Example: '生成一条独特的编程激励鸡汤'"},
 					"tool":      map[string]string{"type": "string", "description": "MCP tool to execute periodically (for raw tool execution without AI)"},
+					"macro":     map[string]string{"type": "string", "description": "Saved browser macro to replay without AI (see browser_macro_save). 'prompt' then describes the task for the AI to finish if replay fails"},
 					"arguments": map[string]string{"type": "object", "description": "Arguments for the tool (when using tool parameter)"},
 				},
 				"required": []string{"name", "schedule"},
@@ -1449,6 +1702,11 @@ func (a *Agent) buildToolsList() []Tool {
 		},
 	}
 
+	// Screenshot tools only help models that can look at images
+	if supportsVision(a.provider) {
+		tools = append(tools, browserVisionTools()...)
+	}
+
 	// Append tools from external MCP servers
 	for _, t := range a.mcpManager.AllTools() {
 		schema := json.RawMessage(t.InputSchema)
@@ -1462,12 +1720,62 @@ func (a *Agent) buildToolsList() []Tool {
 	return tools
 }
 
+// browserVisionPrompt tells vision-capable models when to fall back to
+// marked-up screenshots.
+const browserVisionPrompt = `
+
+## Browser Vision
+When browser_snapshot returns "(no interactive elements found)" or misses what you can expect on the page (canvas apps, games, maps, icon-only toolbars), call browser_marks to look at a screenshot with numbered boxes over clickable elements. Click with browser_click_mark(mark=N); for spots without a box (e.g. inside a canvas) use browser_click_xy with pixel coordinates from that screenshot. Prefer refs from browser_snapshot whenever it shows the element — it is cheaper and more reliable.`
+
+// browserVisionTools are the screenshot-based browser tools offered to
+// vision-capable providers.
+func browserVisionTools() []Tool {
+	return []Tool{
+		{
+			Name:        "browser_marks",
+			Description: "Screenshot the visible page with red numbered boxes (marks) over clickable elements and look at it. Use when browser_snapshot shows few or no elements (canvas apps, unlabelled buttons, icons).",
+			InputSchema: jsonSchema(map[string]any{"type": "object", "properties": map[string]any{}}),
+		},
+		{
+			Name:        "browser_click_mark",
+			Description: "Click an element by its mark number from browser_marks",
+			InputSchema: jsonSchema(map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"mark": map[string]string{"type": "number", "description": "Mark number from browser_marks"}},
+				"required":   []string{"mark"},
+			}),
+		},
+		{
+			Name:        "browser_click_xy",
+			Description: "Click a point of the page by its pixel coordinates in the last browser_marks screenshot, for targets without a mark (e.g. inside a canvas)",
+			InputSchema: jsonSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"x": map[string]string{"type": "number", "description": "X in screenshot pixels"},
+					"y": map[string]string{"type": "number", "description": "Y in screenshot pixels"},
+				},
+				"required": []string{"x", "y"},
+			}),
+		},
+	}
+}
+
 // processToolCalls executes tool calls and returns results plus any file attachments
 func (a *Agent) processToolCalls(ctx context.Context, toolCalls []ToolCall) ([]ToolResult, []router.FileAttachment) {
 	results := make([]ToolResult, 0, len(toolCalls))
 	var files []router.FileAttachment
+	step := router.StepFromContext(ctx)
+	access := turnFromContext(ctx).access
 
 	for _, tc := range toolCalls {
+		if err := a.checkToolAccess(access, tc.Name); err != nil {
+			results = append(results, ToolResult{
+				ToolCallID: tc.ID,
+				Content:    err.Error(),
+				IsError:    true,
+			})
+			continue
+		}
 		if tc.Name == "file_send" {
 			content, file := executeFileSend(tc.Input)
 			if file != nil {
@@ -1480,8 +1788,48 @@ func (a *Agent) processToolCalls(ctx context.Context, toolCalls []ToolCall) ([]T
 			})
 			continue
 		}
+		if tc.Name == "browser_marks" {
+			if step != nil {
+				step(tc.Name, false)
+			}
+			content, img := executeBrowserMarks(ctx)
+			if step != nil {
+				step(tc.Name, true)
+			}
+			results = append(results, ToolResult{
+				ToolCallID: tc.ID,
+				Content:    content,
+				IsError:    img == nil,
+				Image:      img,
+			})
+			continue
+		}
+		if tc.Name == "browser_download" {
+			if step != nil {
+				step(tc.Name, false)
+			}
+			content, file := a.executeBrowserDownload(ctx, tc.Input)
+			if step != nil {
+				step(tc.Name, true)
+			}
+			if file != nil {
+				files = append(files, *file)
+			}
+			results = append(results, ToolResult{
+				ToolCallID: tc.ID,
+				Content:    content,
+				IsError:    file == nil,
+			})
+			continue
+		}
 
+		if step != nil {
+			step(tc.Name, false)
+		}
 		result := a.executeTool(ctx, tc.Name, tc.Input)
+		if step != nil {
+			step(tc.Name, true)
+		}
 		results = append(results, ToolResult{
 			ToolCallID: tc.ID,
 			Content:    result,
@@ -1502,32 +1850,27 @@ func (a *Agent) executeTool(ctx context.Context, name string, input json.RawMess
 		return fmt.Sprintf("Error parsing arguments: %v", err)
 	}
 
+	t := turnFromContext(ctx)
+	if err := a.checkToolAccess(t.access, name); err != nil {
+		return err.Error()
+	}
+
 	// Handle cron tools that need Agent context
 	switch name {
 	case "cron_create":
-		return a.executeCronCreate(args)
+		return a.executeCronCreate(t, args)
 	case "cron_list":
-		return a.executeCronList()
+		return a.executeCronList(t.msg)
 	case "cron_delete":
-		return a.executeCronDelete(args)
+		return a.executeCronDelete(t.msg, args)
 	case "cron_pause":
-		return a.executeCronPause(args)
+		return a.executeCronPause(t.msg, args)
 	case "cron_resume":
-		return a.executeCronResume(args)
+		return a.executeCronResume(t.msg, args)
 	}
 
-	// Block file tools entirely if disabled
-	if a.disableFileTools {
-		if _, ok := fileToolPaths[name]; ok {
-			return "ACCESS DENIED: file operations are disabled by security policy. Do NOT retry. Inform the user that file access is disabled."
-		}
-	}
-
-	// Enforce allowed_paths restrictions
-	if a.pathChecker.HasRestrictions() {
-		if err := a.checkToolPathAccess(name, args); err != nil {
-			return err.Error()
-		}
+	if err := a.checkFileAccess(name, args); err != nil {
+		return err.Error()
 	}
 
 	// Call tools directly
@@ -1554,6 +1897,23 @@ var fileToolPaths = map[string]string{
 	"file_info":     "path",
 }
 
+// checkFileAccess applies disable_file_tools and allowed_paths to a tool call.
+func (a *Agent) checkFileAccess(name string, args map[string]any) error {
+	// Block file tools entirely if disabled
+	if a.disableFileTools {
+		_, isFileTool := fileToolPaths[name]
+		if isFileTool || len(tools.BrowserFilePaths(name, args)) > 0 {
+			return fmt.Errorf("ACCESS DENIED: file operations are disabled by security policy. Do NOT retry. Inform the user that file access is disabled.")
+		}
+	}
+
+	// Enforce allowed_paths restrictions
+	if a.pathChecker.HasRestrictions() {
+		return a.checkToolPathAccess(name, args)
+	}
+	return nil
+}
+
 // checkToolPathAccess validates that tool arguments respect allowed_paths.
 func (a *Agent) checkToolPathAccess(name string, args map[string]any) error {
 	if pathKey, ok := fileToolPaths[name]; ok {
@@ -1568,6 +1928,11 @@ func (a *Agent) checkToolPathAccess(name string, args map[string]any) error {
 			return a.pathChecker.CheckPath(wd)
 		}
 	}
+	for _, p := range tools.BrowserFilePaths(name, args) {
+		if err := a.pathChecker.CheckPath(p); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -1800,13 +2165,17 @@ func (a *Agent) callToolDirect(ctx context.Context, name string, args map[string
 		}
 		return executeBrowserNavigate(ctx, url)
 	case "browser_snapshot":
-		return executeBrowserSnapshot(ctx)
+		return executeBrowserSnapshot(ctx, args)
 	case "browser_click":
 		ref := 0
 		if r, ok := args["ref"].(float64); ok {
 			ref = int(r)
 		}
 		return executeBrowserClick(ctx, ref)
+	case "browser_click_mark":
+		return executeBrowserClickMark(ctx, args)
+	case "browser_click_xy":
+		return executeBrowserClickXY(ctx, args)
 	case "browser_type":
 		ref := 0
 		text := ""
@@ -1833,17 +2202,26 @@ func (a *Agent) callToolDirect(ctx context.Context, name string, args map[string
 			script = s
 		}
 		return executeBrowserExecuteJS(ctx, script)
-	case "browser_comment_zhihu":
-		comment, _ := args["comment"].(string)
-		replyTo, _ := args["reply_to"].(string)
-		return executeBrowserCommentZhihu(ctx, comment, replyTo)
-	case "browser_comment_xiaohongshu":
-		comment, _ := args["comment"].(string)
-		return executeBrowserCommentXiaohongshu(ctx, comment)
+	case "browser_site_action":
+		return executeBrowserSiteAction(ctx, args)
 	case "browser_visited":
 		return executeBrowserVisited(ctx, args)
+	case "task_checkpoint":
+		return executeTaskCheckpoint(ctx, args)
+	case "browser_macro_save":
+		return executeBrowserMacroSave(ctx, args)
+	case "browser_macro_run":
+		return executeBrowserMacroRun(ctx, args)
+	case "browser_macro_list":
+		return executeBrowserMacroList(ctx)
 	case "browser_click_all":
 		return executeBrowserClickAll(ctx, args)
+	case "browser_extract":
+		return executeBrowserExtract(ctx, args)
+	case "browser_upload":
+		return executeBrowserUpload(ctx, args)
+	case "browser_network":
+		return executeBrowserNetwork(ctx, args)
 	case "browser_screenshot":
 		return executeBrowserScreenshot(ctx, args)
 	case "browser_tabs":
@@ -1,10 +1,23 @@
 package agent
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
 	"testing"
 
+	"github.com/pltanton/lingti-bot/internal/acl"
+	"github.com/pltanton/lingti-bot/internal/config"
+	cronpkg "github.com/pltanton/lingti-bot/internal/cron"
+	"github.com/pltanton/lingti-bot/internal/identity"
 	"github.com/pltanton/lingti-bot/internal/router"
+	"github.com/pltanton/lingti-bot/internal/speech"
 )
 
 func TestCreateProvider_ValidProviders(t *testing.T) {
@@ -54,6 +67,72 @@ func TestCreateProvider_OllamaNoKey(t *testing.T) {
 	}
 }
 
+func TestSupportsVision(t *testing.T) {
+	tests := []struct {
+		provider, model string
+		want            bool
+	}{
+		{"claude", "", true},
+		{"openai", "", true}, // gpt-4o
+		{"gemini", "", true},
+		{"zhipu", "glm-4v-plus", true},
+		{"siliconflow", "Qwen/Qwen2.5-VL-72B-Instruct", true},
+		{"zhipu", "", false}, // glm-4-flash
+		{"ollama", "", false},
+		{"deepseek", "", false},
+	}
+	for _, tt := range tests {
+		p, err := createProvider(Config{Provider: tt.provider, APIKey: "test-key", Model: tt.model})
+		if err != nil {
+			t.Fatalf("createProvider(%q): %v", tt.provider, err)
+		}
+		if got := supportsVision(p); got != tt.want {
+			t.Errorf("supportsVision(%s %s) = %v, want %v", tt.provider, tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestOpenAICompat_ToolResultImages(t *testing.T) {
+	var sent struct {
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+	}))
+	defer srv.Close()
+
+	p, err := createProvider(Config{Provider: "openai", APIKey: "test-key", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.Chat(t.Context(), ChatRequest{Messages: []Message{
+		{Role: "user", Content: "open the map"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "a", Name: "browser_marks"}, {ID: "b", Name: "browser_snapshot"}}},
+		{Role: "user", ToolResult: &ToolResult{ToolCallID: "a", Content: "Marks: 0", Image: &Image{MediaType: "image/jpeg", Data: []byte{0xff, 0xd8}}}},
+		{Role: "user", ToolResult: &ToolResult{ToolCallID: "b", Content: "(no interactive elements found)"}},
+	}})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+
+	var roles []string
+	for _, m := range sent.Messages {
+		roles = append(roles, m.Role)
+	}
+	// The image may only follow once every tool call has its result
+	if got := strings.Join(roles, ","); got != "user,assistant,tool,tool,user" {
+		t.Fatalf("roles = %s", got)
+	}
+	if last := string(sent.Messages[4].Content); !strings.Contains(last, "data:image/jpeg;base64,/9g=") {
+		t.Errorf("image message = %s", last)
+	}
+}
+
 func TestHandleBuiltinCommand(t *testing.T) {
 	agent, err := New(Config{Provider: "claude", APIKey: "test-key"})
 	if err != nil {
@@ -76,9 +155,250 @@ func TestHandleBuiltinCommand(t *testing.T) {
 	}
 	for _, tt := range tests {
 		msg := router.Message{Text: tt.text, Platform: "test", ChannelID: "c1", UserID: "u1", Username: "tester"}
-		_, handled := agent.handleBuiltinCommand(msg)
+		_, handled := agent.handleBuiltinCommand(msg, ConversationKey(msg.Platform, msg.ChannelID, msg.UserID))
 		if handled != tt.handled {
 			t.Errorf("handleBuiltinCommand(%q): got handled=%v, want %v", tt.text, handled, tt.handled)
 		}
 	}
 }
+
+func TestLinkCommand(t *testing.T) {
+	store, err := identity.NewStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	agent, err := New(Config{Provider: "claude", APIKey: "test-key", Identities: store})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	send := func(platform, userID, text string) string {
+		msg := router.Message{Text: text, Platform: platform, ChannelID: "c1", UserID: userID}
+		resp, handled := agent.handleBuiltinCommand(msg, ConversationKey(msg.Platform, msg.ChannelID, msg.UserID))
+		if !handled {
+			t.Fatalf("%q was not handled", text)
+		}
+		return resp.Text
+	}
+
+	code := regexp.MustCompile(`[A-Z2-9]{8}`).FindString(send("slack", "U1", "/link"))
+	if code == "" {
+		t.Fatal("/link did not return a code")
+	}
+	wrong := "00000000" // 0 is never used in codes
+	if got := send("feishu", "ou_1", "/link "+wrong); !strings.Contains(got, "无效") {
+		t.Errorf("wrong code: %s", got)
+	}
+	if got := send("feishu", "ou_1", "/link "+code); !strings.Contains(got, "slack:U1") || !strings.Contains(got, "feishu:ou_1") {
+		t.Errorf("confirm reply = %s", got)
+	}
+	if store.Resolve("slack", "U1") != store.Resolve("feishu", "ou_1") {
+		t.Fatal("accounts were not linked")
+	}
+
+	// Cron jobs follow the owner across linked accounts.
+	job := &cronpkg.Job{ID: "j1", Platform: "slack", UserID: "U1"}
+	if !agent.ownsJob(router.Message{Platform: "feishu", UserID: "ou_1"}, job) {
+		t.Error("linked account does not own the job")
+	}
+	if agent.ownsJob(router.Message{Platform: "feishu", UserID: "ou_2"}, job) {
+		t.Error("another user owns the job")
+	}
+	if !agent.ownsJob(router.Message{Platform: "feishu", UserID: "ou_2"}, &cronpkg.Job{ID: "j2", Tool: "weather_current"}) {
+		t.Error("a job without an owner is not shared")
+	}
+
+	// Their jobs continue the conversation they were created in.
+	for _, scope := range []string{"", router.ScopeChannel, router.ScopeIdentity} {
+		msg := router.Message{Platform: "feishu", ChannelID: "c1", UserID: "ou_1", Identity: store.Resolve("feishu", "ou_1")}
+		if got, want := agent.jobConversationKey("feishu", "c1", "ou_1", scope), router.ScopedConversationKey(msg, scope); got != want {
+			t.Errorf("scope %q: job key = %q, want %q", scope, got, want)
+		}
+	}
+
+	if got := send("feishu", "ou_1", "/unlink"); !strings.Contains(got, "已解除") {
+		t.Errorf("/unlink = %s", got)
+	}
+	if store.Resolve("feishu", "ou_1") != "feishu:ou_1" {
+		t.Error("account still linked after /unlink")
+	}
+}
+
+func TestAccessControl(t *testing.T) {
+	rules, err := acl.New(config.ACLConfig{
+		Rules: []config.ACLRule{
+			{Action: "deny", Match: config.ACLMatch{Platform: "telegram"}, Comment: "public bot"},
+			{Action: "allow", Role: acl.RoleGuest, Match: config.ACLMatch{Platform: "slack", ChannelID: "C_LOBBY"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent, err := New(Config{Provider: "claude", APIKey: "test-key", ACL: rules, DenyTools: []string{"web_fetch"}})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	ctx := context.Background()
+
+	// Refused senders get an explanation and may still ask who they are.
+	refused := router.Message{Platform: "telegram", ChannelID: "1", UserID: "1", Text: "run ls"}
+	resp, err := agent.handleMessage(ctx, refused)
+	if err != nil || !strings.Contains(resp.Text, "没有使用此机器人的权限") || !strings.Contains(resp.Text, "public bot") {
+		t.Errorf("refusal = %q, %v", resp.Text, err)
+	}
+	refused.Text = "/whoami"
+	if resp, _ := agent.handleMessage(ctx, refused); !strings.Contains(resp.Text, "已拒绝（rule: deny platform=telegram (public bot)）") {
+		t.Errorf("/whoami for refused sender = %q", resp.Text)
+	}
+	// ...but may not confirm a link code, which would join them to an
+	// allowed identity.
+	refused.Text = "/link ABCD2345"
+	if resp, _ := agent.handleMessage(ctx, refused); !strings.Contains(resp.Text, "没有使用此机器人的权限") {
+		t.Errorf("/link <code> for refused sender = %q", resp.Text)
+	}
+
+	guest := router.Message{Platform: "slack", ChannelID: "C_LOBBY", UserID: "U1", Text: "/whoami"}
+	if resp, _ := agent.handleMessage(ctx, guest); !strings.Contains(resp.Text, "角色: guest") {
+		t.Errorf("/whoami for guest = %q", resp.Text)
+	}
+
+	// The guest role and the agent's deny_tools intersect.
+	access := agent.checkAccess(guest)
+	var names []string
+	for _, tool := range agent.filterTools(access, agent.buildToolsList()) {
+		names = append(names, tool.Name)
+	}
+	if got := strings.Join(names, ","); got != "weather_current,weather_forecast,web_search" {
+		t.Errorf("guest tools = %s", got)
+	}
+	results, _ := agent.processToolCalls(contextWithTurn(ctx, &turn{msg: guest, access: access}), []ToolCall{
+		{ID: "1", Name: "shell_execute", Input: json.RawMessage(`{"command":"id"}`)},
+		{ID: "2", Name: "web_fetch", Input: json.RawMessage(`{"url":"http://example.com"}`)},
+	})
+	if !results[0].IsError || !strings.Contains(results[0].Content, "role guest") {
+		t.Errorf("shell_execute result = %+v", results[0])
+	}
+	if !results[1].IsError || !strings.Contains(results[1].Content, "not enabled for this agent") {
+		t.Errorf("web_fetch result = %+v", results[1])
+	}
+}
+
+// aclProvider calls cron_list once both senders' turns have reached the
+// model, then replies with the tool result.
+type aclProvider struct {
+	arrived sync.WaitGroup
+	mu      sync.Mutex
+	offered map[string]bool // user message -> cron_list was offered
+}
+
+func (p *aclProvider) Name() string { return "fake" }
+
+func (p *aclProvider) Chat(ctx context.Context, req ChatRequest) (ChatResponse, error) {
+	last := req.Messages[len(req.Messages)-1]
+	if last.ToolResult != nil {
+		return ChatResponse{Content: last.ToolResult.Content, FinishReason: "stop"}, nil
+	}
+	offered := false
+	for _, tool := range req.Tools {
+		offered = offered || tool.Name == "cron_list"
+	}
+	p.mu.Lock()
+	p.offered[last.Content] = offered
+	p.mu.Unlock()
+	p.arrived.Done()
+	p.arrived.Wait()
+	return ChatResponse{
+		ToolCalls:    []ToolCall{{ID: "1", Name: "cron_list", Input: json.RawMessage(`{}`)}},
+		FinishReason: "tool_use",
+	}, nil
+}
+
+func TestAccessControl_ConcurrentSenders(t *testing.T) {
+	rules, err := acl.New(config.ACLConfig{
+		Rules: []config.ACLRule{
+			{Action: "allow", Role: acl.RoleAdmin, Match: config.ACLMatch{Platform: "slack", UserID: "U_ADMIN"}},
+			{Action: "allow", Role: acl.RoleGuest, Match: config.ACLMatch{Platform: "slack", UserID: "U_GUEST"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent, err := New(Config{Provider: "claude", APIKey: "test-key", ACL: rules})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	provider := &aclProvider{offered: map[string]bool{}}
+	provider.arrived.Add(2)
+	agent.provider = provider
+
+	// Both turns are in flight at once; each must keep its own role.
+	var wg sync.WaitGroup
+	replies := map[string]string{}
+	var mu sync.Mutex
+	for _, user := range []string{"U_ADMIN", "U_GUEST"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			msg := router.Message{Platform: "slack", ChannelID: "C1", UserID: user, Text: "list jobs " + user}
+			resp, err := agent.handleMessage(context.Background(), msg)
+			if err != nil {
+				t.Errorf("%s: %v", user, err)
+			}
+			mu.Lock()
+			replies[user] = resp.Text
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if !provider.offered["list jobs U_ADMIN"] || provider.offered["list jobs U_GUEST"] {
+		t.Errorf("cron_list offered = %v", provider.offered)
+	}
+	if got := replies["U_ADMIN"]; strings.Contains(got, "ACCESS DENIED") {
+		t.Errorf("admin reply = %q", got)
+	}
+	if got := replies["U_GUEST"]; !strings.Contains(got, "role guest") {
+		t.Errorf("guest reply = %q", got)
+	}
+}
+
+type fakeSynthesizer struct{ said []string }
+
+func (f *fakeSynthesizer) Synthesize(ctx context.Context, text string) (speech.Audio, error) {
+	f.said = append(f.said, text)
+	return speech.Audio{Data: []byte("OggS"), Format: "ogg"}, nil
+}
+
+func TestAddVoiceReply(t *testing.T) {
+	voice := router.Message{MediaType: "voice"}
+	tests := []struct {
+		name      string
+		mode      string
+		msg       router.Message
+		text      string
+		wantVoice bool
+	}{
+		{"voice for voice", VoiceReplyVoice, voice, "**好的**", true},
+		{"text for text", VoiceReplyVoice, router.Message{}, "好的", false},
+		{"always", VoiceReplyAlways, router.Message{}, "好的", true},
+		{"off", "", voice, "好的", false},
+		{"too long", VoiceReplyAlways, voice, strings.Repeat("字", maxVoiceRunes+1), false},
+	}
+	for _, tt := range tests {
+		synth := &fakeSynthesizer{}
+		a := &Agent{synthesizer: synth, voiceReply: tt.mode}
+		resp := router.Response{Text: tt.text}
+		a.addVoiceReply(context.Background(), tt.msg, &resp)
+
+		if got := len(resp.Files) == 1; got != tt.wantVoice {
+			t.Errorf("%s: files = %+v, want voice %v", tt.name, resp.Files, tt.wantVoice)
+			continue
+		}
+		if tt.wantVoice {
+			if resp.Files[0].MediaType != "voice" || synth.said[0] != "好的" {
+				t.Errorf("%s: attached %+v after saying %q", tt.name, resp.Files[0], synth.said)
+			}
+			os.Remove(resp.Files[0].Path)
+		}
+	}
+}
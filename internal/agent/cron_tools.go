@@ -4,17 +4,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+
+	cronpkg "github.com/pltanton/lingti-bot/internal/cron"
+	"github.com/pltanton/lingti-bot/internal/router"
 )
 
 // executeCronCreate creates a new scheduled task
-func (a *Agent) executeCronCreate(args map[string]any) string {
+func (a *Agent) executeCronCreate(t *turn, args map[string]any) string {
 	if a.cronScheduler == nil {
 		return "Error: cron scheduler not available"
 	}
 
 	// Enforce: only ONE cron_create per user request
-	a.cronCreatedCount++
-	if a.cronCreatedCount > 1 {
+	t.cronCreated++
+	if t.cronCreated > 1 {
 		return "Error: You already created a cron job for this request. Only ONE cron job per user request is allowed. If you need varied/random content each time, use the 'prompt' parameter instead of creating multiple 'message' jobs."
 	}
 
@@ -23,6 +26,7 @@ func (a *Agent) executeCronCreate(args map[string]any) string {
 	message, _ := args["message"].(string)
 	tool, _ := args["tool"].(string)
 	prompt, _ := args["prompt"].(string)
+	macro, _ := args["macro"].(string)
 
 	if name == "" {
 		return "Error: name is required"
@@ -30,19 +34,42 @@ func (a *Agent) executeCronCreate(args map[string]any) string {
 	if schedule == "" {
 		return "Error: schedule is required"
 	}
+	// Jobs may only run tools their creator could run now
+	if tool != "" {
+		if err := a.checkToolAccess(t.access, tool); err != nil {
+			return err.Error()
+		}
+	}
+	if macro != "" {
+		if err := a.checkToolAccess(t.access, "browser_macro_run"); err != nil {
+			return err.Error()
+		}
+	}
 
 	// Auto-upgrade: if AI sent 'message' but no 'prompt' or 'tool',
 	// wrap the message in a generation instruction so AI creates fresh content each time
-	if message != "" && prompt == "" && tool == "" {
+	if message != "" && prompt == "" && tool == "" && macro == "" {
 		prompt = fmt.Sprintf("用户想要定期收到类似以下风格的内容，请每次生成一条全新的、独特的、不重复的内容：\n%s", message)
 		message = ""
 	}
 
+	// Macro-based job: replay a recorded browser macro, prompt is the fallback
+	if macro != "" {
+		job, err := a.cronScheduler.AddJobWithMacro(
+			name, schedule, macro, prompt,
+			t.msg.Platform, t.msg.ChannelID, t.msg.UserID, t.scope,
+		)
+		if err != nil {
+			return fmt.Sprintf("Error creating scheduled task: %v", err)
+		}
+		return fmt.Sprintf("Scheduled macro task created:\n- ID: %s\n- Name: %s\n- Schedule: %s\n- Macro: %s", job.ID, job.Name, job.Schedule, job.Macro)
+	}
+
 	// Prompt-based job: run full AI conversation on schedule
 	if prompt != "" {
 		job, err := a.cronScheduler.AddJobWithPrompt(
 			name, schedule, prompt,
-			a.currentMsg.Platform, a.currentMsg.ChannelID, a.currentMsg.UserID,
+			t.msg.Platform, t.msg.ChannelID, t.msg.UserID, t.scope,
 		)
 		if err != nil {
 			return fmt.Sprintf("Error creating scheduled task: %v", err)
@@ -54,7 +81,7 @@ func (a *Agent) executeCronCreate(args map[string]any) string {
 	if message != "" {
 		job, err := a.cronScheduler.AddJobWithMessage(
 			name, schedule, message,
-			a.currentMsg.Platform, a.currentMsg.ChannelID, a.currentMsg.UserID,
+			t.msg.Platform, t.msg.ChannelID, t.msg.UserID, t.scope,
 		)
 		if err != nil {
 			return fmt.Sprintf("Error creating scheduled task: %v", err)
@@ -83,16 +110,21 @@ func (a *Agent) executeCronCreate(args map[string]any) string {
 		return fmt.Sprintf("Scheduled task created:\n- ID: %s\n- Name: %s\n- Schedule: %s\n- Tool: %s", job.ID, job.Name, job.Schedule, job.Tool)
 	}
 
-	return "Error: either 'prompt', 'message', or 'tool' is required"
+	return "Error: either 'prompt', 'message', 'tool', or 'macro' is required"
 }
 
 // executeCronList lists all scheduled tasks
-func (a *Agent) executeCronList() string {
+func (a *Agent) executeCronList(sender router.Message) string {
 	if a.cronScheduler == nil {
 		return "Error: cron scheduler not available"
 	}
 
-	jobs := a.cronScheduler.ListJobs()
+	var jobs []*cronpkg.Job
+	for _, job := range a.cronScheduler.ListJobs() {
+		if a.ownsJob(sender, job) {
+			jobs = append(jobs, job)
+		}
+	}
 	if len(jobs) == 0 {
 		return "No scheduled tasks."
 	}
@@ -115,6 +147,9 @@ func (a *Agent) executeCronList() string {
 		if job.Tool != "" {
 			sb.WriteString(fmt.Sprintf("  Tool: %s\n", job.Tool))
 		}
+		if job.Macro != "" {
+			sb.WriteString(fmt.Sprintf("  Macro: %s\n", job.Macro))
+		}
 		if job.LastRun != nil {
 			sb.WriteString(fmt.Sprintf("  Last run: %s\n", job.LastRun.Format("2006-01-02 15:04:05")))
 		}
@@ -128,7 +163,7 @@ func (a *Agent) executeCronList() string {
 }
 
 // executeCronDelete deletes a scheduled task
-func (a *Agent) executeCronDelete(args map[string]any) string {
+func (a *Agent) executeCronDelete(sender router.Message, args map[string]any) string {
 	if a.cronScheduler == nil {
 		return "Error: cron scheduler not available"
 	}
@@ -138,6 +173,9 @@ func (a *Agent) executeCronDelete(args map[string]any) string {
 		return "Error: id is required"
 	}
 
+	if msg := a.checkJobOwner(sender, id); msg != "" {
+		return msg
+	}
 	if err := a.cronScheduler.RemoveJob(id); err != nil {
 		return fmt.Sprintf("Error: %v", err)
 	}
@@ -145,7 +183,7 @@ func (a *Agent) executeCronDelete(args map[string]any) string {
 }
 
 // executeCronPause pauses a scheduled task
-func (a *Agent) executeCronPause(args map[string]any) string {
+func (a *Agent) executeCronPause(sender router.Message, args map[string]any) string {
 	if a.cronScheduler == nil {
 		return "Error: cron scheduler not available"
 	}
@@ -155,6 +193,9 @@ func (a *Agent) executeCronPause(args map[string]any) string {
 		return "Error: id is required"
 	}
 
+	if msg := a.checkJobOwner(sender, id); msg != "" {
+		return msg
+	}
 	if err := a.cronScheduler.PauseJob(id); err != nil {
 		return fmt.Sprintf("Error: %v", err)
 	}
@@ -162,7 +203,7 @@ func (a *Agent) executeCronPause(args map[string]any) string {
 }
 
 // executeCronResume resumes a paused scheduled task
-func (a *Agent) executeCronResume(args map[string]any) string {
+func (a *Agent) executeCronResume(sender router.Message, args map[string]any) string {
 	if a.cronScheduler == nil {
 		return "Error: cron scheduler not available"
 	}
@@ -172,6 +213,9 @@ func (a *Agent) executeCronResume(args map[string]any) string {
 		return "Error: id is required"
 	}
 
+	if msg := a.checkJobOwner(sender, id); msg != "" {
+		return msg
+	}
 	if err := a.cronScheduler.ResumeJob(id); err != nil {
 		return fmt.Sprintf("Error: %v", err)
 	}
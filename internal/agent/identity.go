@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	cronpkg "github.com/pltanton/lingti-bot/internal/cron"
+	"github.com/pltanton/lingti-bot/internal/identity"
+	"github.com/pltanton/lingti-bot/internal/router"
+)
+
+// handleLinkCommand handles /link (issue a code), /link <code> (confirm it
+// from another account) and /unlink.
+func (a *Agent) handleLinkCommand(msg router.Message, text string) (router.Response, bool) {
+	cmd, arg, _ := strings.Cut(text, " ")
+	if cmd != "/link" && cmd != "/unlink" {
+		return router.Response{}, false
+	}
+	if a.identities == nil || msg.UserID == "" {
+		return router.Response{Text: "账号关联不可用。"}, true
+	}
+	arg = strings.TrimSpace(arg)
+
+	switch {
+	case cmd == "/unlink":
+		unlinked, err := a.identities.Unlink(msg.Platform, msg.UserID)
+		if err != nil {
+			return router.Response{Text: fmt.Sprintf("解除关联失败: %v", err)}, true
+		}
+		if !unlinked {
+			return router.Response{Text: "本账号未关联其他账号。"}, true
+		}
+		return router.Response{Text: "已解除本账号的关联。"}, true
+
+	case arg == "":
+		code, err := a.identities.NewCode(msg.Platform, msg.UserID)
+		if err != nil {
+			return router.Response{Text: fmt.Sprintf("生成关联码失败: %v", err)}, true
+		}
+		text := fmt.Sprintf("关联码：%s（%d 分钟内有效）\n在另一个平台上向我发送 /link %s，即可把那个账号与本账号关联，共享会话、定时任务和设置。\n请勿在群聊中公开关联码。",
+			code, int(identity.CodeTTL.Minutes()), code)
+		if linked := a.linkedAccounts(a.identities.Resolve(msg.Platform, msg.UserID)); linked != "" {
+			text += "\n\n已关联账号:\n" + linked
+		}
+		return router.Response{Text: text}, true
+
+	default:
+		id, err := a.identities.Link(arg, msg.Platform, msg.UserID)
+		switch {
+		case errors.Is(err, identity.ErrInvalidCode):
+			return router.Response{Text: "关联码无效或已过期，请在原账号上重新发送 /link 获取。"}, true
+		case errors.Is(err, identity.ErrSameAccount):
+			return router.Response{Text: "这是本账号生成的关联码，请在另一个平台的账号上发送。"}, true
+		case errors.Is(err, identity.ErrTooManyAttempts):
+			return router.Response{Text: "尝试次数过多，请稍后再试。"}, true
+		case err != nil:
+			return router.Response{Text: fmt.Sprintf("关联失败: %v", err)}, true
+		}
+		return router.Response{Text: fmt.Sprintf("账号已关联（身份 %s）:\n%s", id, a.linkedAccounts(id))}, true
+	}
+}
+
+// linkedAccounts lists an identity's accounts, one per line.
+func (a *Agent) linkedAccounts(id string) string {
+	accounts, err := a.identities.Accounts(id)
+	if err != nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, acct := range accounts {
+		fmt.Fprintf(&sb, "- %s\n", acct)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// identityOf resolves an account to its canonical user.
+func (a *Agent) identityOf(platform, userID string) string {
+	if a.identities != nil {
+		return a.identities.Resolve(platform, userID)
+	}
+	return identity.Account{Platform: platform, UserID: userID}.String()
+}
+
+// ownsJob reports whether a sender may see and manage a cron job, from any
+// of their linked accounts. Jobs without an owner are shared, and callers
+// outside a chat (the scheduler, MCP) see every job.
+func (a *Agent) ownsJob(sender router.Message, job *cronpkg.Job) bool {
+	if job.UserID == "" || sender.UserID == "" {
+		return true
+	}
+	return a.identityOf(job.Platform, job.UserID) == a.identityOf(sender.Platform, sender.UserID)
+}
+
+// checkJobOwner returns an error message if a sender does not own the job
+// with the given ID, or "".
+func (a *Agent) checkJobOwner(sender router.Message, id string) string {
+	for _, job := range a.cronScheduler.ListJobs() {
+		if job.ID == id && !a.ownsJob(sender, job) {
+			return fmt.Sprintf("Error: scheduled task %s belongs to another user", id)
+		}
+	}
+	return ""
+}
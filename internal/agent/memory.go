@@ -3,6 +3,8 @@ package agent
 import (
 	"sync"
 	"time"
+
+	"github.com/pltanton/lingti-bot/internal/router"
 )
 
 // ConversationMemory stores conversation history per user/channel
@@ -147,7 +149,5 @@ func (m *ConversationMemory) cleanup() {
 
 // ConversationKey generates a unique key for a conversation
 func ConversationKey(platform, channelID, userID string) string {
-	// Use channel+user for unique conversations
-	// This means each user has their own context per channel
-	return platform + ":" + channelID + ":" + userID
+	return router.ConversationKey(platform, channelID, userID)
 }
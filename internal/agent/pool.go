@@ -81,7 +81,7 @@ func (p *AgentPool) handleWithAgentRouting(ctx context.Context, msg router.Messa
 		platform = ap
 	}
 
-	result := routing.ResolveRoute(p.fullCfg, platform, msg.ChannelID, msg.UserID)
+	result := routing.ResolveRouteWithIdentity(p.fullCfg, platform, msg.ChannelID, msg.UserID, msg.Identity)
 
 	agentID := result.AgentID
 	if agentID == "" {
@@ -156,6 +156,12 @@ func (p *AgentPool) getOrCreateByID(id string, entry config.AgentEntry) *Agent {
 	if entry.Workspace != "" {
 		cfg.Workspace = entry.Workspace
 	}
+	if entry.BrowserProfile != "" {
+		cfg.BrowserProfile = entry.BrowserProfile
+	}
+	if entry.VoiceReply != "" {
+		cfg.VoiceReply = entry.VoiceReply
+	}
 
 	a, err := New(cfg)
 	if err != nil {
@@ -58,6 +58,26 @@ type ToolResult struct {
 	ToolCallID string
 	Content    string
 	IsError    bool
+	Image      *Image // Shown to the model by providers that support vision
+}
+
+// Image is an inline image in a tool result, such as a marked-up browser
+// screenshot.
+type Image struct {
+	MediaType string // "image/png" or "image/jpeg"
+	Data      []byte
+}
+
+// VisionProvider is implemented by providers that can show tool result
+// images to their model.
+type VisionProvider interface {
+	SupportsVision() bool
+}
+
+// supportsVision reports whether p's model can look at images.
+func supportsVision(p Provider) bool {
+	v, ok := p.(VisionProvider)
+	return ok && v.SupportsVision()
 }
 
 // Tool defines a tool that can be used by the model
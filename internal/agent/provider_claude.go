@@ -3,6 +3,7 @@ package agent
 import (
 	"context"
 	"crypto/tls"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -175,6 +176,11 @@ func (p *ClaudeProvider) Name() string {
 	return "claude"
 }
 
+// SupportsVision reports that Claude models can look at tool result images.
+func (p *ClaudeProvider) SupportsVision() bool {
+	return true
+}
+
 // Chat sends messages and returns a response
 func (p *ClaudeProvider) Chat(ctx context.Context, req ChatRequest) (ChatResponse, error) {
 	// Convert messages to Anthropic format
@@ -274,15 +280,22 @@ func (p *ClaudeProvider) toAnthropicMessage(msg Message) anthropic.Message {
 	case "user":
 		if msg.ToolResult != nil {
 			// Tool result message
+			result := anthropic.NewToolResultMessageContent(
+				msg.ToolResult.ToolCallID,
+				msg.ToolResult.Content,
+				msg.ToolResult.IsError,
+			)
+			if img := msg.ToolResult.Image; img != nil {
+				result.MessageContentToolResult.Content = append(result.MessageContentToolResult.Content,
+					anthropic.NewImageMessageContent(anthropic.NewMessageContentSource(
+						anthropic.MessagesContentSourceTypeBase64,
+						img.MediaType,
+						base64.StdEncoding.EncodeToString(img.Data),
+					)))
+			}
 			return anthropic.Message{
-				Role: anthropic.RoleUser,
-				Content: []anthropic.MessageContent{
-					anthropic.NewToolResultMessageContent(
-						msg.ToolResult.ToolCallID,
-						msg.ToolResult.Content,
-						msg.ToolResult.IsError,
-					),
-				},
+				Role:    anthropic.RoleUser,
+				Content: []anthropic.MessageContent{result},
 			}
 		}
 		return anthropic.Message{
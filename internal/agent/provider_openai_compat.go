@@ -3,11 +3,13 @@ package agent
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/sashabaranov/go-openai"
 )
@@ -68,6 +70,47 @@ func (p *OpenAICompatProvider) Name() string {
 	return p.providerName
 }
 
+// visionModelHints are model name fragments of OpenAI-compatible models
+// that accept image input.
+var visionModelHints = []string{
+	"gpt-4o", "gpt-4.1", "gpt-4-turbo", "gpt-5", "o3", "o4",
+	"gemini", "vision", "-vl", "vl-", "glm-4v", "llava", "pixtral", "claude",
+}
+
+// SupportsVision reports whether the configured model accepts images.
+func (p *OpenAICompatProvider) SupportsVision() bool {
+	model := strings.ToLower(p.model)
+	for _, hint := range visionModelHints {
+		if strings.Contains(model, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// imagePart returns img as an inline data URL message part.
+func imagePart(img *Image) openai.ChatMessagePart {
+	return openai.ChatMessagePart{
+		Type: openai.ChatMessagePartTypeImageURL,
+		ImageURL: &openai.ChatMessageImageURL{
+			URL:    "data:" + img.MediaType + ";base64," + base64.StdEncoding.EncodeToString(img.Data),
+			Detail: openai.ImageURLDetailAuto,
+		},
+	}
+}
+
+// toolImagesMessage carries the images of the preceding tool results.
+func toolImagesMessage(images []openai.ChatMessagePart) openai.ChatCompletionMessage {
+	parts := append([]openai.ChatMessagePart{{
+		Type: openai.ChatMessagePartTypeText,
+		Text: "Screenshots returned by the tool calls above:",
+	}}, images...)
+	return openai.ChatCompletionMessage{
+		Role:         openai.ChatMessageRoleUser,
+		MultiContent: parts,
+	}
+}
+
 // Chat sends messages and returns a response
 func (p *OpenAICompatProvider) Chat(ctx context.Context, req ChatRequest) (ChatResponse, error) {
 	messages := make([]openai.ChatCompletionMessage, 0, len(req.Messages)+1)
@@ -79,8 +122,21 @@ func (p *OpenAICompatProvider) Chat(ctx context.Context, req ChatRequest) (ChatR
 		})
 	}
 
+	// Tool messages can't hold images, so screenshots from a round of tool
+	// results follow them in a user message
+	var images []openai.ChatMessagePart
 	for _, msg := range req.Messages {
+		if len(images) > 0 && msg.ToolResult == nil {
+			messages = append(messages, toolImagesMessage(images))
+			images = nil
+		}
 		messages = append(messages, p.toOpenAIMessage(msg))
+		if msg.ToolResult != nil && msg.ToolResult.Image != nil && p.SupportsVision() {
+			images = append(images, imagePart(msg.ToolResult.Image))
+		}
+	}
+	if len(images) > 0 {
+		messages = append(messages, toolImagesMessage(images))
 	}
 
 	tools := make([]openai.Tool, 0, len(req.Tools))
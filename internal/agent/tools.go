@@ -3,6 +3,7 @@ package agent
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -496,9 +497,9 @@ func executeBrowserNavigate(ctx context.Context, url string) string {
 	return extractText(result)
 }
 
-func executeBrowserSnapshot(ctx context.Context) string {
+func executeBrowserSnapshot(ctx context.Context, args map[string]any) string {
 	req := mcp.CallToolRequest{}
-	req.Params.Arguments = map[string]interface{}{}
+	req.Params.Arguments = args
 	result, err := tools.BrowserSnapshot(ctx, req)
 	if err != nil {
 		return "Error: " + err.Error()
@@ -546,30 +547,153 @@ func executeBrowserExecuteJS(ctx context.Context, script string) string {
 	return extractText(result)
 }
 
-func executeBrowserCommentZhihu(ctx context.Context, comment, replyTo string) string {
+func executeBrowserSiteAction(ctx context.Context, args map[string]any) string {
 	req := mcp.CallToolRequest{}
-	req.Params.Arguments = map[string]interface{}{"comment": comment, "reply_to": replyTo}
-	result, err := tools.BrowserCommentZhihu(ctx, req)
+	req.Params.Arguments = args
+	result, err := tools.BrowserSiteAction(ctx, req)
 	if err != nil {
 		return "Error: " + err.Error()
 	}
 	return extractText(result)
 }
 
-func executeBrowserCommentXiaohongshu(ctx context.Context, comment string) string {
+func executeBrowserVisited(ctx context.Context, args map[string]any) string {
 	req := mcp.CallToolRequest{}
-	req.Params.Arguments = map[string]interface{}{"comment": comment}
-	result, err := tools.BrowserCommentXiaohongshu(ctx, req)
+	req.Params.Arguments = args
+	result, err := tools.BrowserVisited(ctx, req)
 	if err != nil {
 		return "Error: " + err.Error()
 	}
 	return extractText(result)
 }
 
-func executeBrowserVisited(ctx context.Context, args map[string]any) string {
+func executeTaskCheckpoint(ctx context.Context, args map[string]any) string {
 	req := mcp.CallToolRequest{}
 	req.Params.Arguments = args
-	result, err := tools.BrowserVisited(ctx, req)
+	result, err := tools.TaskCheckpoint(ctx, req)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	return extractText(result)
+}
+
+func executeBrowserMacroSave(ctx context.Context, args map[string]any) string {
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = args
+	result, err := tools.BrowserMacroSave(ctx, req)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	return extractText(result)
+}
+
+func executeBrowserMacroRun(ctx context.Context, args map[string]any) string {
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = args
+	result, err := tools.BrowserMacroRun(ctx, req)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	return extractText(result)
+}
+
+func executeBrowserMacroList(ctx context.Context) string {
+	result, err := tools.BrowserMacroList(ctx, mcp.CallToolRequest{})
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	return extractText(result)
+}
+
+// executeBrowserMarks returns the mark list and the marked-up screenshot,
+// which is passed to the model as an image.
+func executeBrowserMarks(ctx context.Context) (string, *Image) {
+	result, err := tools.BrowserMarks(ctx, mcp.CallToolRequest{})
+	if err != nil {
+		return "Error: " + err.Error(), nil
+	}
+	if result.IsError {
+		return "Error: " + extractText(result), nil
+	}
+	for _, content := range result.Content {
+		if img, ok := content.(mcp.ImageContent); ok {
+			data, err := base64.StdEncoding.DecodeString(img.Data)
+			if err != nil {
+				return "Error: invalid screenshot data", nil
+			}
+			return extractText(result), &Image{MediaType: img.MIMEType, Data: data}
+		}
+	}
+	return "Error: no screenshot captured", nil
+}
+
+func executeBrowserClickMark(ctx context.Context, args map[string]any) string {
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = args
+	result, err := tools.BrowserClickMark(ctx, req)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	return extractText(result)
+}
+
+func executeBrowserClickXY(ctx context.Context, args map[string]any) string {
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = args
+	result, err := tools.BrowserClickXY(ctx, req)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	return extractText(result)
+}
+
+func executeBrowserExtract(ctx context.Context, args map[string]any) string {
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = args
+	result, err := tools.BrowserExtract(ctx, req)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	return extractText(result)
+}
+
+func executeBrowserUpload(ctx context.Context, args map[string]any) string {
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = args
+	result, err := tools.BrowserUpload(ctx, req)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	return extractText(result)
+}
+
+// executeBrowserDownload runs browser_download and returns the saved file as
+// a FileAttachment. Like file_send, it is handled in processToolCalls.
+func (a *Agent) executeBrowserDownload(ctx context.Context, input json.RawMessage) (string, *router.FileAttachment) {
+	var args map[string]any
+	if err := json.Unmarshal(input, &args); err != nil {
+		return fmt.Sprintf("Error parsing arguments: %v", err), nil
+	}
+	if err := a.checkFileAccess("browser_download", args); err != nil {
+		return err.Error(), nil
+	}
+
+	path, err := tools.DownloadFile(ctx, args)
+	if err != nil {
+		return "Error: " + err.Error(), nil
+	}
+	logger.Info("[Agent] browser_download: queued %s", path)
+	return tools.DownloadMessage(path) + " — it will be sent to the user.", &router.FileAttachment{
+		Path:      path,
+		Name:      filepath.Base(path),
+		MediaType: "file",
+	}
+}
+
+func executeBrowserNetwork(ctx context.Context, args map[string]any) string {
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = args
+	result, err := tools.BrowserNetwork(ctx, req)
 	if err != nil {
 		return "Error: " + err.Error()
 	}
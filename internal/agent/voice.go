@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"context"
+	"unicode/utf8"
+
+	"github.com/pltanton/lingti-bot/internal/logger"
+	"github.com/pltanton/lingti-bot/internal/markdown"
+	"github.com/pltanton/lingti-bot/internal/router"
+	"github.com/pltanton/lingti-bot/internal/speech"
+)
+
+// Voice reply modes
+const (
+	VoiceReplyVoice  = "voice"  // answer voice messages with voice
+	VoiceReplyAlways = "always" // answer every message with voice
+)
+
+// maxVoiceRunes is the longest reply that is also spoken. Longer replies are
+// usually code or lists, which nobody wants read aloud.
+const maxVoiceRunes = 1000
+
+// addVoiceReply attaches a spoken version of resp.Text, as a voice file,
+// when the agent's voice reply mode asks for one. The text is sent as well.
+func (a *Agent) addVoiceReply(ctx context.Context, msg router.Message, resp *router.Response) {
+	if !wantsVoiceReply(a.voiceReply, msg) || a.synthesizer == nil {
+		return
+	}
+	text := markdown.Plain(resp.Text)
+	if text == "" || utf8.RuneCountInString(text) > maxVoiceRunes {
+		return
+	}
+
+	audio, err := a.synthesizer.Synthesize(ctx, text)
+	if err != nil {
+		logger.Warn("[Agent] Failed to synthesize voice reply: %v", err)
+		return
+	}
+	path, err := speech.WriteTemp(audio)
+	if err != nil {
+		logger.Warn("[Agent] Failed to save voice reply: %v", err)
+		return
+	}
+	resp.Files = append(resp.Files, router.FileAttachment{Path: path, Name: "voice." + audio.Format, MediaType: "voice", Temp: true})
+}
+
+// wantsVoiceReply reports whether mode asks for a spoken answer to msg.
+func wantsVoiceReply(mode string, msg router.Message) bool {
+	switch mode {
+	case VoiceReplyAlways:
+		return true
+	case VoiceReplyVoice:
+		return msg.MediaType == "voice"
+	}
+	return false
+}
@@ -23,12 +23,19 @@ func waitStable(page *rod.Page, interval, maxWait time.Duration) {
 
 // Click clicks the element identified by the given ref number.
 // It scrolls the element into view, waits for it to be interactable, then clicks.
-func Click(page *rod.Page, b *Browser, ref int) error {
-	el, err := resolveRef(page, b, ref)
+func Click(page *rod.Page, s *Session, ref int) error {
+	el, err := resolveRef(page, s, ref)
 	if err != nil {
-		return captureErrorScreenshot(page, b, "click_resolve", ref, err)
+		return captureErrorScreenshot(page, s.browser, "click_resolve", ref, err)
 	}
+	return clickElement(page, el, func(action string, err error) error {
+		return captureErrorScreenshot(page, s.browser, action, ref, fmt.Errorf("ref %d: %w", ref, err))
+	})
+}
 
+// clickElement clicks el. Failures go through fail, which receives a short
+// action name for debug screenshots.
+func clickElement(page *rod.Page, el *rod.Element, fail func(action string, err error) error) error {
 	// Wrap all element operations in a bounded context so that clicking elements that
 	// trigger heavy AJAX (e.g. Zhihu comment panels) never hangs indefinitely.
 	opCtx, opCancel := context.WithTimeout(context.Background(), 8*time.Second)
@@ -44,7 +51,7 @@ func Click(page *rod.Page, b *Browser, ref int) error {
 	if _, err := bel.Interactable(); err != nil {
 		time.Sleep(300 * time.Millisecond)
 		if _, err := bel.Interactable(); err != nil {
-			return captureErrorScreenshot(page, b, "click_not_interactable", ref, fmt.Errorf("element [%d] not interactable: %w", ref, err))
+			return fail("click_not_interactable", fmt.Errorf("element not interactable: %w", err))
 		}
 	}
 
@@ -55,7 +62,7 @@ func Click(page *rod.Page, b *Browser, ref int) error {
 	if _, err := bel.Eval(`() => { this.click(); return true; }`); err != nil {
 		// Fall back to rod mouse click if JS eval fails
 		if err2 := bel.Click(proto.InputMouseButtonLeft, 1); err2 != nil {
-			return captureErrorScreenshot(page, b, "click_failed", ref, fmt.Errorf("click failed: %w", err2))
+			return fail("click_failed", fmt.Errorf("click failed: %w", err2))
 		}
 	}
 
@@ -67,12 +74,19 @@ func Click(page *rod.Page, b *Browser, ref int) error {
 
 // Type inputs text into the element identified by the given ref number.
 // It clicks the element first to ensure focus, then types.
-func Type(page *rod.Page, b *Browser, ref int, text string, submit bool) error {
-	el, err := resolveRef(page, b, ref)
+func Type(page *rod.Page, s *Session, ref int, text string, submit bool) error {
+	el, err := resolveRef(page, s, ref)
 	if err != nil {
-		return captureErrorScreenshot(page, b, "type_resolve", ref, err)
+		return captureErrorScreenshot(page, s.browser, "type_resolve", ref, err)
 	}
+	return typeInto(page, el, text, submit, func(action string, err error) error {
+		return captureErrorScreenshot(page, s.browser, action, ref, fmt.Errorf("ref %d: %w", ref, err))
+	})
+}
 
+// typeInto focuses el, replaces its content with text and optionally presses
+// Enter. Failures go through fail, as in clickElement.
+func typeInto(page *rod.Page, el *rod.Element, text string, submit bool, fail func(action string, err error) error) error {
 	opCtx, opCancel := context.WithTimeout(context.Background(), 8*time.Second)
 	defer opCancel()
 	bel := el.Context(opCtx)
@@ -86,7 +100,7 @@ func Type(page *rod.Page, b *Browser, ref int, text string, submit bool) error {
 	if err := bel.Click(proto.InputMouseButtonLeft, 1); err != nil {
 		// Try Focus as fallback
 		if err := bel.Focus(); err != nil {
-			return captureErrorScreenshot(page, b, "type_focus", ref, fmt.Errorf("failed to focus element [%d]: %w", ref, err))
+			return fail("type_focus", fmt.Errorf("failed to focus element: %w", err))
 		}
 	}
 
@@ -100,13 +114,13 @@ func Type(page *rod.Page, b *Browser, ref int, text string, submit bool) error {
 
 	// Input text
 	if err := bel.Input(text); err != nil {
-		return captureErrorScreenshot(page, b, "type_input", ref, fmt.Errorf("failed to type text: %w", err))
+		return fail("type_input", fmt.Errorf("failed to type text: %w", err))
 	}
 
 	if submit {
 		time.Sleep(100 * time.Millisecond)
 		if err := bel.Type(input.Enter); err != nil {
-			return captureErrorScreenshot(page, b, "type_submit", ref, fmt.Errorf("failed to press Enter: %w", err))
+			return fail("type_submit", fmt.Errorf("failed to press Enter: %w", err))
 		}
 		waitStable(page, 500*time.Millisecond, 3*time.Second)
 	}
@@ -129,8 +143,8 @@ func Press(page *rod.Page, key string) error {
 }
 
 // Hover moves the mouse over the element identified by the given ref number.
-func Hover(page *rod.Page, b *Browser, ref int) error {
-	el, err := resolveRef(page, b, ref)
+func Hover(page *rod.Page, s *Session, ref int) error {
+	el, err := resolveRef(page, s, ref)
 	if err != nil {
 		return err
 	}
@@ -206,9 +220,43 @@ func ClickAll(page *rod.Page, selector string, delay time.Duration, skipSelector
 	return clicked, nil
 }
 
-// resolveRef looks up a ref number in the browser's ref map and returns the corresponding element.
-func resolveRef(page *rod.Page, b *Browser, ref int) (*rod.Element, error) {
-	entry, ok := b.GetRef(ref)
+// nextPageWait bounds how long NextPage looks for the next-page control.
+const nextPageWait = 3 * time.Second
+
+// NextPage advances a paginated listing. With loc it clicks the "next"
+// control loc points at, and reports false if there is none or it is
+// disabled. Without loc it scrolls to the bottom to load more (infinite
+// scroll) and always reports true; callers stop when no new items appear.
+func NextPage(ctx context.Context, page *rod.Page, loc *Locator) (bool, error) {
+	if loc == nil {
+		if _, err := page.Eval(`() => window.scrollTo(0, document.body.scrollHeight)`); err != nil {
+			return false, err
+		}
+		time.Sleep(1500 * time.Millisecond)
+		waitStable(page, 500*time.Millisecond, 3*time.Second)
+		return true, nil
+	}
+
+	findCtx, cancel := context.WithTimeout(ctx, nextPageWait)
+	defer cancel()
+	el, err := resolveLocator(findCtx, page, loc)
+	if err != nil {
+		return false, nil
+	}
+	disabled, err := el.Eval(`() => this.disabled === true || this.getAttribute('aria-disabled') === 'true' || this.classList.contains('disabled')`)
+	if err == nil && disabled.Value.Bool() {
+		return false, nil
+	}
+	if err := clickElement(page, el, func(_ string, err error) error { return err }); err != nil {
+		return false, err
+	}
+	waitStable(page, 500*time.Millisecond, 5*time.Second)
+	return true, nil
+}
+
+// resolveRef looks up a ref number in the session's ref map and returns the corresponding element.
+func resolveRef(page *rod.Page, s *Session, ref int) (*rod.Element, error) {
+	entry, ok := s.GetRef(ref)
 	if !ok {
 		return nil, fmt.Errorf("ref %d not found in snapshot (run browser_snapshot first, or page may have changed)", ref)
 	}
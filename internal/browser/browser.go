@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pltanton/lingti-bot/internal/config"
 
@@ -26,12 +27,18 @@ type Browser struct {
 	connected bool // true when attached to external Chrome (don't close on Stop)
 	dataDir   string
 
-	// currentPage is the page most recently opened/navigated by the bot.
-	// snapshot/click/type all operate on this page so they stay on the same tab.
-	currentPage *rod.Page
+	// sessions holds per-conversation state (current page, snapshot refs,
+	// browser context), keyed by conversation. See SessionFor.
+	sessions    map[string]*Session
+	maxSessions int
+	sessionIdle time.Duration
+	isolation   string
+	held        map[string]int // turns in flight per session key, see Hold
 
-	// refs holds the latest snapshot ref map (ref number → RefEntry).
-	refs map[int]RefEntry
+	// Login profiles: browser.profile from config, and per-conversation
+	// choices made with browser_start.
+	defaultProfile   string
+	profileOverrides map[string]string
 
 	// Debug mode configuration
 	debugMode bool
@@ -48,10 +55,15 @@ func Instance() *Browser {
 	once.Do(func() {
 		home, _ := os.UserHomeDir()
 		instance = &Browser{
-			headless: false,
-			dataDir:  filepath.Join(home, ".lingti-bot", "browser"),
-			refs:     make(map[int]RefEntry),
+			headless:         false,
+			dataDir:          filepath.Join(home, ".lingti-bot", "browser"),
+			sessions:         make(map[string]*Session),
+			maxSessions:      defaultMaxSessions,
+			sessionIdle:      defaultSessionIdle,
+			profileOverrides: make(map[string]string),
+			held:             make(map[string]int),
 		}
+		go instance.sweepLoop()
 	})
 	return instance
 }
@@ -73,6 +85,9 @@ func (b *Browser) Start(opts StartOptions) error {
 		return fmt.Errorf("browser already running")
 	}
 
+	cfg, _ := config.Load()
+	b.applySessionConfig(cfg.Browser)
+
 	// Connect to existing Chrome via CDP
 	if opts.ConnectURL != "" {
 		return b.connectLocked(opts.ConnectURL, opts.URL)
@@ -89,7 +104,6 @@ func (b *Browser) Start(opts StartOptions) error {
 		Headless(opts.Headless)
 
 	// Apply screen size from config (default: fullscreen)
-	cfg, _ := config.Load()
 	screenSize := cfg.Browser.ScreenSize
 	if screenSize == "" {
 		screenSize = "fullscreen"
@@ -122,7 +136,7 @@ func (b *Browser) Start(opts StartOptions) error {
 	b.browser = brow
 	b.running = true
 	b.connected = false
-	b.refs = make(map[int]RefEntry)
+	b.sessions = make(map[string]*Session)
 
 	if opts.URL != "" {
 		page, err := brow.Page(proto.TargetCreateTarget{URL: opts.URL, Background: true})
@@ -152,7 +166,7 @@ func (b *Browser) connectLocked(addr string, initialURL string) error {
 	b.browser = brow
 	b.running = true
 	b.connected = true
-	b.refs = make(map[int]RefEntry)
+	b.sessions = make(map[string]*Session)
 
 	if initialURL != "" {
 		page, err := brow.Page(proto.TargetCreateTarget{URL: initialURL, Background: true})
@@ -175,6 +189,11 @@ func (b *Browser) Stop() error {
 		return fmt.Errorf("browser not running")
 	}
 
+	// Dispose session contexts first so they don't linger in an external Chrome
+	for _, s := range b.sessions {
+		s.close()
+	}
+
 	if !b.connected {
 		if err := b.browser.Close(); err != nil {
 			return fmt.Errorf("failed to close browser: %w", err)
@@ -185,11 +204,25 @@ func (b *Browser) Stop() error {
 	b.browser = nil
 	b.running = false
 	b.connected = false
-	b.currentPage = nil
-	b.refs = make(map[int]RefEntry)
+	b.sessions = make(map[string]*Session)
 	return nil
 }
 
+// applySessionConfig reads session limits from config. Must be called with
+// b.mu held.
+func (b *Browser) applySessionConfig(cfg config.BrowserConfig) {
+	b.maxSessions = defaultMaxSessions
+	if cfg.MaxSessions > 0 {
+		b.maxSessions = cfg.MaxSessions
+	}
+	b.sessionIdle = defaultSessionIdle
+	if cfg.SessionIdleMinutes > 0 {
+		b.sessionIdle = time.Duration(cfg.SessionIdleMinutes) * time.Minute
+	}
+	b.isolation = cfg.Isolation
+	b.defaultProfile = cfg.Profile
+}
+
 // EnsureRunning starts the browser if not already running.
 // Resolution order:
 //  1. cfg.Browser.CDPURL  — user-configured CDP address (highest priority)
@@ -246,117 +279,13 @@ func (b *Browser) Rod() *rod.Browser {
 	return b.browser
 }
 
-// PageCount returns the number of open tabs.
-func (b *Browser) PageCount() int {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	if b.browser == nil {
-		return 0
-	}
-	pages, err := b.browser.Pages()
-	if err != nil {
-		return 0
-	}
-	return len(pages)
-}
-
-// SwitchToNewestPage updates currentPage to the most recently opened tab,
-// if a new tab has appeared since lastCount. Returns true if switched.
-func (b *Browser) SwitchToNewestPage(lastCount int) bool {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	if b.browser == nil {
-		return false
-	}
-	pages, err := b.browser.Pages()
-	if err != nil || len(pages) <= lastCount {
-		return false
-	}
-	// The newest tab is the last one
-	newest := pages[len(pages)-1]
-	b.currentPage = newest
-	b.refs = make(map[int]RefEntry) // invalidate refs for old page
-	return true
-}
-
-// ActivePage returns the page the bot is currently working on.
-// Returns currentPage if one has been set (by browser_navigate).
-// Falls back to the first available tab, or creates a blank one if none exist.
-func (b *Browser) ActivePage() (*rod.Page, error) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	if !b.running {
-		return nil, fmt.Errorf("browser not running")
-	}
-
-	if b.currentPage != nil {
-		return b.currentPage, nil
-	}
-
-	pages, err := b.browser.Pages()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get pages: %w", err)
-	}
-	if len(pages) == 0 {
-		page, err := b.browser.Page(proto.TargetCreateTarget{URL: "about:blank", Background: true})
-		if err != nil {
-			return nil, fmt.Errorf("failed to create page: %w", err)
-		}
-		return page, nil
-	}
-	return pages.First(), nil
-}
-
-// SetCurrentPage records the page the bot is currently working on.
-// Called by browser_navigate after opening/navigating a tab.
-func (b *Browser) SetCurrentPage(page *rod.Page) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	b.currentPage = page
-}
-
-// NavigationPage returns the page to use for a browser_navigate call.
-// In connected mode (user's existing Chrome) it opens a fresh tab so the
-// user's current page is never hijacked.
-// In launched mode it reuses/creates the first tab for workflow continuity.
-func (b *Browser) NavigationPage() (*rod.Page, error) {
-	b.mu.Lock()
-	connected := b.connected
-	brow := b.browser
-	b.mu.Unlock()
-
-	if connected {
-		page, err := brow.Page(proto.TargetCreateTarget{URL: "about:blank", Background: true})
-		if err != nil {
-			return nil, fmt.Errorf("failed to open new tab: %w", err)
-		}
-		return page, nil
-	}
-	return b.ActivePage()
-}
-
-// SetRefs stores the ref map from a snapshot.
-func (b *Browser) SetRefs(refs map[int]RefEntry) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	b.refs = refs
-}
-
-// GetRef returns a ref entry by number.
-func (b *Browser) GetRef(ref int) (RefEntry, bool) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	entry, ok := b.refs[ref]
-	return entry, ok
-}
-
 // StatusInfo holds browser status details.
 type StatusInfo struct {
 	Running   bool   `json:"running"`
 	Headless  bool   `json:"headless"`
 	Connected bool   `json:"connected"` // attached to external Chrome (vs launched)
 	Pages     int    `json:"pages"`
+	Sessions  int    `json:"sessions"` // active per-conversation sessions
 	ActiveURL string `json:"active_url"`
 }
 
@@ -428,6 +357,7 @@ func (b *Browser) Status() StatusInfo {
 		Running:   b.running,
 		Headless:  b.headless,
 		Connected: b.connected,
+		Sessions:  len(b.sessions),
 	}
 
 	if !b.running {
@@ -0,0 +1,299 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/launcher"
+)
+
+// The tests in this file drive a real headless Chromium against the HTML
+// fixtures in testdata. They look for a browser in $LINGTI_TEST_CHROME, then
+// the usual install locations, and skip when there is none (or with -short).
+
+// testBrowserBin returns the Chromium binary to test with, or "" for none.
+func testBrowserBin() string {
+	if bin := os.Getenv("LINGTI_TEST_CHROME"); bin != "" {
+		return bin
+	}
+	if bin := detectChrome(); bin != "" {
+		return bin
+	}
+	bin, _ := launcher.LookPath()
+	return bin
+}
+
+// startTestBrowser launches headless Chromium and returns the default
+// session of a Browser wrapping it. Everything is torn down with the test.
+func startTestBrowser(t *testing.T) *Session {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("browser integration test skipped in -short mode")
+	}
+	bin := testBrowserBin()
+	if bin == "" {
+		t.Skip("no Chrome/Chromium found; set LINGTI_TEST_CHROME to run browser integration tests")
+	}
+
+	l := launcher.New().
+		Bin(bin).
+		Headless(true).
+		NoSandbox(true).
+		Leakless(false).
+		UserDataDir(t.TempDir())
+	controlURL, err := l.Launch()
+	if err != nil {
+		t.Fatalf("failed to launch %s: %v", bin, err)
+	}
+	brow := rod.New().ControlURL(controlURL)
+	if err := brow.Connect(); err != nil {
+		l.Kill()
+		t.Fatalf("failed to connect to browser: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = brow.Close()
+		l.Kill()
+	})
+
+	b := &Browser{
+		browser:          brow,
+		running:          true,
+		headless:         true,
+		sessions:         make(map[string]*Session),
+		maxSessions:      defaultMaxSessions,
+		sessionIdle:      defaultSessionIdle,
+		profileOverrides: make(map[string]string),
+		held:             make(map[string]int),
+	}
+	s, err := b.SessionFor(context.Background())
+	if err != nil {
+		t.Fatalf("SessionFor: %v", err)
+	}
+	return s
+}
+
+// openFixture opens testdata/<name> in a new tab of s, served over HTTP so
+// the page behaves as it would on a real site.
+func openFixture(t *testing.T, s *Session, name string) *rod.Page {
+	t.Helper()
+	srv := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	t.Cleanup(srv.Close)
+
+	page, err := s.OpenPage(srv.URL + "/" + name)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", name, err)
+	}
+	page = page.Timeout(time.Minute)
+	if err := page.WaitLoad(); err != nil {
+		t.Fatalf("failed to load %s: %v", name, err)
+	}
+	// Focus the tab: editors only accept execCommand input in a focused page
+	_, _ = page.Activate()
+	s.SetCurrentPage(page)
+	return page
+}
+
+// snapshotRef returns the ref of the first snapshot node with role and name.
+func snapshotRef(t *testing.T, snap *SnapshotResult, role, name string) int {
+	t.Helper()
+	for _, n := range snap.Nodes {
+		if n.Role == role && n.Name == name {
+			return n.Ref
+		}
+	}
+	t.Fatalf("no %s %q in snapshot:\n%s", role, name, snap.String())
+	return 0
+}
+
+// evalString evaluates a JS function on page and returns its result as text.
+func evalString(t *testing.T, page *rod.Page, js string) string {
+	t.Helper()
+	res, err := page.Eval(js)
+	if err != nil {
+		t.Fatalf("eval %s: %v", js, err)
+	}
+	return res.Value.String()
+}
+
+func TestIntegration_SnapshotTypeAndDiff(t *testing.T) {
+	s := startTestBrowser(t)
+	page := openFixture(t, s, "search.html")
+
+	snap, err := s.Snapshot(page, SnapshotOptions{})
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	if snap.HasPrevious {
+		t.Error("first snapshot of a page has a previous snapshot")
+	}
+	box := snapshotRef(t, snap, "textbox", "关键词")
+	snapshotRef(t, snap, "button", "搜索")
+
+	interactive, err := s.Snapshot(page, SnapshotOptions{InteractiveOnly: true})
+	if err != nil {
+		t.Fatalf("interactive Snapshot: %v", err)
+	}
+	if got := interactive.String(); strings.Contains(got, "list") || !strings.Contains(got, "textbox") {
+		t.Errorf("interactive-only snapshot =\n%s", got)
+	}
+
+	if err := Type(page, s, box, "golang", true); err != nil {
+		t.Fatalf("Type: %v", err)
+	}
+	if got := evalString(t, page, `() => document.querySelector('input').value`); got != "golang" {
+		t.Errorf("input value = %q, want golang", got)
+	}
+
+	after, err := s.Snapshot(page, SnapshotOptions{})
+	if err != nil {
+		t.Fatalf("Snapshot after search: %v", err)
+	}
+	if ref := snapshotRef(t, after, "textbox", "关键词"); ref != box {
+		t.Errorf("textbox ref changed from %d to %d", box, ref)
+	}
+	link := snapshotRef(t, after, "link", "golang 的结果")
+	if diff := after.Diff(); !strings.Contains(diff, fmt.Sprintf("+ [%d] link \"golang 的结果\"", link)) {
+		t.Errorf("diff does not show the new result:\n%s", diff)
+	}
+
+	// Refs from the snapshot resolve to the live elements
+	if err := Click(page, s, link); err != nil {
+		t.Fatalf("Click(link): %v", err)
+	}
+	if got := evalString(t, page, `() => location.hash`); got != "#r" {
+		t.Errorf("location.hash = %q after clicking the result", got)
+	}
+	if err := Click(page, s, 999); err == nil || !strings.Contains(err.Error(), "not found in snapshot") {
+		t.Errorf("Click(999) error = %v", err)
+	}
+}
+
+func TestIntegration_ClickModal(t *testing.T) {
+	s := startTestBrowser(t)
+	page := openFixture(t, s, "modal.html")
+
+	snap, err := s.Snapshot(page, SnapshotOptions{})
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	subscribe := snapshotRef(t, snap, "button", "订阅")
+	closeBtn := snapshotRef(t, snap, "button", "关闭")
+
+	// The overlay covers the button, so the click must fail instead of
+	// silently going to the overlay
+	err = Click(page, s, subscribe)
+	if err == nil || !strings.Contains(err.Error(), "not interactable") {
+		t.Fatalf("Click under modal error = %v, want not interactable", err)
+	}
+	if got := evalString(t, page, `() => document.getElementById('status').textContent`); got != "未订阅" {
+		t.Fatalf("status = %q, covered button was clicked", got)
+	}
+
+	if err := Click(page, s, closeBtn); err != nil {
+		t.Fatalf("Click(关闭): %v", err)
+	}
+	if err := Click(page, s, subscribe); err != nil {
+		t.Fatalf("Click(订阅) after closing the modal: %v", err)
+	}
+	if got := evalString(t, page, `() => document.getElementById('status').textContent`); got != "已订阅 1" {
+		t.Errorf("status = %q, want 已订阅 1", got)
+	}
+}
+
+func TestIntegration_ClickAll(t *testing.T) {
+	s := startTestBrowser(t)
+	page := openFixture(t, s, "feed.html")
+
+	// Ten items load in total, two of them already liked
+	clicked, err := ClickAll(page, ".like-wrapper", 0, ".active")
+	if err != nil {
+		t.Fatalf("ClickAll: %v", err)
+	}
+	if clicked != 8 {
+		t.Errorf("clicked = %d, want 8", clicked)
+	}
+	if got := evalString(t, page, `() => String(window.likes)`); got != "8" {
+		t.Errorf("likes = %s, want 8", got)
+	}
+	if got := evalString(t, page, `() => String(document.querySelectorAll('.like-wrapper:not(.active)').length)`); got != "0" {
+		t.Errorf("%s items left unliked", got)
+	}
+}
+
+// bundledRecipe returns the embedded recipe with the given name.
+func bundledRecipe(t *testing.T, name string) *Recipe {
+	t.Helper()
+	data, err := bundledRecipes.ReadFile("recipes/" + name + ".yaml")
+	if err != nil {
+		t.Fatalf("no bundled recipe %s: %v", name, err)
+	}
+	r, err := ParseRecipe(data)
+	if err != nil {
+		t.Fatalf("ParseRecipe(%s): %v", name, err)
+	}
+	return r
+}
+
+func TestIntegration_ZhihuComment(t *testing.T) {
+	s := startTestBrowser(t)
+	page := openFixture(t, s, "zhihu.html")
+	r := bundledRecipe(t, "zhihu")
+
+	// Top-level comment: expand the comments, open the Draft.js editor,
+	// paste and publish
+	if _, err := r.RunAction(page, "comment", map[string]any{"comment": "写得好"}); err != nil {
+		t.Fatalf("comment: %v", err)
+	}
+	if got := evalString(t, page, `() => Array.from(document.querySelectorAll('#comments > .posted')).map(e => e.textContent).join('|')`); got != "写得好" {
+		t.Errorf("posted comments = %q", got)
+	}
+
+	// Nested reply to the second commenter through the plain textarea
+	if _, err := r.RunAction(page, "comment", map[string]any{"comment": "同意", "reply_to": "李四"}); err != nil {
+		t.Fatalf("reply: %v", err)
+	}
+	got := evalString(t, page, `() => Array.from(document.querySelectorAll('.comment')).map(c => {
+		const r = c.querySelector('.posted'); return r ? r.textContent : '';
+	}).join('|')`)
+	if got != "|同意" {
+		t.Errorf("replies per comment = %q, want |同意", got)
+	}
+
+	if _, err := r.RunAction(page, "comment", map[string]any{"comment": "x", "reply_to": "王五"}); err == nil || !strings.Contains(err.Error(), "user not found") {
+		t.Errorf("reply to unknown user error = %v", err)
+	}
+}
+
+func TestIntegration_XiaohongshuComment(t *testing.T) {
+	s := startTestBrowser(t)
+	page := openFixture(t, s, "xiaohongshu.html")
+	r := bundledRecipe(t, "xiaohongshu")
+
+	if _, err := r.RunAction(page, "open_note", map[string]any{"index": 1}); err != nil {
+		t.Fatalf("open_note: %v", err)
+	}
+	if got := evalString(t, page, `() => document.getElementById('note-title').textContent`); got != "笔记 1" {
+		t.Fatalf("opened note = %q, want 笔记 1", got)
+	}
+
+	if _, err := r.RunAction(page, "comment", map[string]any{"comment": "好看"}); err != nil {
+		t.Fatalf("comment: %v", err)
+	}
+	if got := evalString(t, page, `() => document.getElementById('comments').textContent`); got != "好看" {
+		t.Errorf("comments = %q, want 好看", got)
+	}
+
+	for _, want := range []string{"liked", "already liked"} {
+		got, err := r.RunAction(page, "like", nil)
+		if err != nil || got != want {
+			t.Errorf("like = %q, %v; want %q", got, err, want)
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+	"gopkg.in/yaml.v3"
+
+	"github.com/pltanton/lingti-bot/internal/logger"
+)
+
+// Macro is a recorded sequence of browser actions that can be replayed
+// without the model. Elements are stored as locators rather than snapshot
+// ref numbers, which change on every page load.
+type Macro struct {
+	Name        string      `yaml:"name"`
+	Description string      `yaml:"description,omitempty"`
+	Owner       string      `yaml:"owner,omitempty"` // identity of the chat user who saved it; "" for the CLI and MCP
+	CreatedAt   time.Time   `yaml:"created_at"`
+	Steps       []MacroStep `yaml:"steps"`
+}
+
+// MacroStep is one recorded action. Action names the browser tool it came
+// from, without the browser_ prefix.
+type MacroStep struct {
+	Action     string         `yaml:"action"`                // navigate, click, type, press, execute_js, click_all, site_action
+	URL        string         `yaml:"url,omitempty"`         // navigate
+	Target     *Locator       `yaml:"target,omitempty"`      // click, type
+	Text       string         `yaml:"text,omitempty"`        // type
+	Submit     bool           `yaml:"submit,omitempty"`      // type
+	Key        string         `yaml:"key,omitempty"`         // press
+	Script     string         `yaml:"script,omitempty"`      // execute_js
+	Selector   string         `yaml:"selector,omitempty"`    // click_all
+	Skip       string         `yaml:"skip,omitempty"`        // click_all
+	DelayMS    int            `yaml:"delay_ms,omitempty"`    // click_all
+	Site       string         `yaml:"site,omitempty"`        // site_action: recipe name
+	SiteAction string         `yaml:"site_action,omitempty"` // site_action: action name
+	Args       map[string]any `yaml:"args,omitempty"`        // site_action
+}
+
+// Locator finds an element again on a later page load: by CSS selector
+// first, then by accessibility role and name.
+type Locator struct {
+	Selector string `yaml:"selector,omitempty"`
+	Role     string `yaml:"role,omitempty"`
+	Name     string `yaml:"name,omitempty"`
+}
+
+// MacroError reports the step a replay stopped at.
+type MacroError struct {
+	Macro string
+	Step  int // 1-based index of the failed step
+	Steps []MacroStep
+	Err   error
+}
+
+func (e *MacroError) Error() string {
+	return fmt.Sprintf("macro %s failed at step %d/%d (%s): %v", e.Macro, e.Step, len(e.Steps), e.Steps[e.Step-1], e.Err)
+}
+
+func (e *MacroError) Unwrap() error { return e.Err }
+
+// Remaining returns the failed step and the steps after it.
+func (e *MacroError) Remaining() []MacroStep {
+	return e.Steps[e.Step-1:]
+}
+
+const (
+	maxTraceSteps      = 200              // oldest recorded steps are dropped beyond this
+	macroFindTimeout   = 10 * time.Second // how long replay waits for an element to appear
+	macroRetryInterval = 500 * time.Millisecond
+)
+
+var macroNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// macroActions lists the recordable actions and the field each one needs.
+var macroActions = map[string]func(MacroStep) bool{
+	"navigate":    func(st MacroStep) bool { return st.URL != "" },
+	"click":       func(st MacroStep) bool { return st.Target != nil },
+	"type":        func(st MacroStep) bool { return st.Target != nil },
+	"press":       func(st MacroStep) bool { return st.Key != "" },
+	"execute_js":  func(st MacroStep) bool { return st.Script != "" },
+	"click_all":   func(st MacroStep) bool { return st.Selector != "" },
+	"site_action": func(st MacroStep) bool { return st.Site != "" && st.SiteAction != "" },
+}
+
+func (l Locator) String() string {
+	if l.Name != "" {
+		return fmt.Sprintf("%s %q", l.Role, l.Name)
+	}
+	if l.Role != "" && l.Selector == "" {
+		return l.Role
+	}
+	return l.Selector
+}
+
+func (st MacroStep) String() string {
+	switch st.Action {
+	case "navigate":
+		return "navigate " + st.URL
+	case "click":
+		return "click " + st.Target.String()
+	case "type":
+		s := fmt.Sprintf("type %q into %s", st.Text, st.Target)
+		if st.Submit {
+			s += " and submit"
+		}
+		return s
+	case "press":
+		return "press " + st.Key
+	case "click_all":
+		return "click all " + st.Selector
+	case "site_action":
+		return fmt.Sprintf("site action %s/%s", st.Site, st.SiteAction)
+	default:
+		return st.Action
+	}
+}
+
+// Record appends a successful action to the session's trace.
+func (s *Session) Record(step MacroStep) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trace = append(s.trace, step)
+	if len(s.trace) > maxTraceSteps {
+		s.trace = s.trace[len(s.trace)-maxTraceSteps:]
+	}
+}
+
+// Trace returns a copy of the actions recorded since the trace was last
+// cleared.
+func (s *Session) Trace() []MacroStep {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]MacroStep(nil), s.trace...)
+}
+
+// ClearTrace forgets the recorded actions.
+func (s *Session) ClearTrace() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trace = nil
+}
+
+// uniqueSelectorJS builds a CSS selector that matches only the element,
+// preferring ids and stable attributes over positions. Returns "" when no
+// short unique selector exists.
+const uniqueSelectorJS = `() => {
+	const target = this;
+	const parts = [];
+	for (let el = target; el && el.nodeType === 1; el = el.parentElement) {
+		if (el.id) {
+			parts.unshift('#' + CSS.escape(el.id));
+		} else {
+			let part = el.tagName.toLowerCase();
+			const attr = ['data-testid', 'name', 'aria-label', 'placeholder'].find(a => el.getAttribute(a));
+			if (attr) {
+				part += '[' + attr + '=' + JSON.stringify(el.getAttribute(attr)) + ']';
+			} else if (el.parentElement) {
+				const same = Array.from(el.parentElement.children).filter(c => c.tagName === el.tagName);
+				if (same.length > 1) { part += ':nth-of-type(' + (same.indexOf(el) + 1) + ')'; }
+			}
+			parts.unshift(part);
+		}
+		const sel = parts.join(' > ');
+		const found = document.querySelectorAll(sel);
+		if (found.length === 1 && found[0] === target) { return sel; }
+		if (el.id || parts.length >= 8) { break; }
+	}
+	return '';
+}`
+
+// Locate returns a locator for a snapshot ref, for recording. It must be
+// called before the element is acted on, since the action may remove it.
+func (s *Session) Locate(page *rod.Page, ref int) *Locator {
+	entry, ok := s.GetRef(ref)
+	if !ok {
+		return nil
+	}
+	loc := &Locator{Role: entry.Role, Name: entry.Name}
+	if el, err := resolveRef(page, s, ref); err == nil {
+		if v, err := el.Eval(uniqueSelectorJS); err == nil {
+			loc.Selector = v.Value.String()
+		}
+	}
+	return loc
+}
+
+// findRef returns the first ref (lowest number) with the given role and
+// name, so the same page always yields the same element.
+func findRef(refs map[int]RefEntry, role, name string) (RefEntry, bool) {
+	best := -1
+	for n, entry := range refs {
+		if entry.Role == role && entry.Name == name && (best == -1 || n < best) {
+			best = n
+		}
+	}
+	if best == -1 {
+		return RefEntry{}, false
+	}
+	return refs[best], true
+}
+
+// resolveLocator finds the element a locator points at, waiting for it to
+// appear while the page is still loading.
+func resolveLocator(ctx context.Context, page *rod.Page, loc *Locator) (*rod.Element, error) {
+	deadline := time.Now().Add(macroFindTimeout)
+	for {
+		if loc.Selector != "" {
+			if els, err := page.Elements(loc.Selector); err == nil && len(els) > 0 {
+				return els.First(), nil
+			}
+		}
+		// An empty name would match any element of the role
+		if loc.Name != "" {
+			if _, refs, err := Snapshot(page); err == nil {
+				if entry, ok := findRef(refs, loc.Role, loc.Name); ok && entry.BackendDOMNodeID != 0 {
+					if el, err := resolveBackendNode(page, entry.BackendDOMNodeID); err == nil {
+						return el, nil
+					}
+				}
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("element %s not found", loc)
+		}
+		time.Sleep(macroRetryInterval)
+	}
+}
+
+// Replay runs the macro's steps in the session, stopping at the first step
+// that fails with a *MacroError.
+func (m *Macro) Replay(ctx context.Context, s *Session) error {
+	for i, step := range m.Steps {
+		logger.Debug("[Browser] Macro %s step %d/%d: %s", m.Name, i+1, len(m.Steps), step)
+		if err := s.replayStep(ctx, step); err != nil {
+			return &MacroError{Macro: m.Name, Step: i + 1, Steps: m.Steps, Err: err}
+		}
+	}
+	return nil
+}
+
+func (s *Session) replayStep(ctx context.Context, step MacroStep) error {
+	if step.Action == "navigate" {
+		if err := CheckNavigation(LoadRecipes(), step.URL); err != nil {
+			return err
+		}
+		page, err := s.NavigationPage()
+		if err != nil {
+			return err
+		}
+		if err := page.Context(ctx).Navigate(step.URL); err != nil {
+			return fmt.Errorf("failed to navigate: %w", err)
+		}
+		_ = page.Context(ctx).WaitLoad()
+		s.SetCurrentPage(page)
+		return nil
+	}
+
+	page, err := s.ActivePage()
+	if err != nil {
+		return err
+	}
+	page = page.Context(ctx)
+	passErr := func(_ string, err error) error { return err }
+
+	switch step.Action {
+	case "click":
+		el, err := resolveLocator(ctx, page, step.Target)
+		if err != nil {
+			return err
+		}
+		tabsBefore := s.PageCount()
+		if err := clickElement(page, el, passErr); err != nil {
+			return err
+		}
+		time.Sleep(500 * time.Millisecond)
+		s.SwitchToNewestPage(tabsBefore)
+		return nil
+
+	case "type":
+		el, err := resolveLocator(ctx, page, step.Target)
+		if err != nil {
+			return err
+		}
+		return typeInto(page, el, step.Text, step.Submit, passErr)
+
+	case "press":
+		return Press(page, step.Key)
+
+	case "execute_js":
+		_, err := ExecuteJS(page, step.Script)
+		return err
+
+	case "click_all":
+		_, err := ClickAll(page, step.Selector, time.Duration(step.DelayMS)*time.Millisecond, step.Skip)
+		return err
+
+	case "site_action":
+		for _, r := range LoadRecipes() {
+			if r.Name == step.Site {
+				_, err := r.RunAction(page, step.SiteAction, step.Args)
+				return err
+			}
+		}
+		return fmt.Errorf("site recipe %s not found", step.Site)
+
+	default:
+		return fmt.Errorf("unknown action %q", step.Action)
+	}
+}
+
+// ParseMacro parses and validates a macro file.
+func ParseMacro(data []byte) (*Macro, error) {
+	var m Macro
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	if !macroNamePattern.MatchString(m.Name) {
+		return nil, fmt.Errorf("invalid macro name %q (use letters, digits, - and _)", m.Name)
+	}
+	if len(m.Steps) == 0 {
+		return nil, fmt.Errorf("macro %s has no steps", m.Name)
+	}
+	for i, step := range m.Steps {
+		valid, ok := macroActions[step.Action]
+		if !ok {
+			return nil, fmt.Errorf("macro %s step %d: unknown action %q", m.Name, i+1, step.Action)
+		}
+		if !valid(step) {
+			return nil, fmt.Errorf("macro %s step %d: %s is missing its arguments", m.Name, i+1, step.Action)
+		}
+	}
+	return &m, nil
+}
+
+// macroDir is where macros are saved: ~/.lingti/macros/.
+func macroDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".lingti", "macros")
+}
+
+// SaveMacro writes a macro to ~/.lingti/macros/<name>.yaml, replacing any
+// macro with the same name that m.Owner may replace.
+func SaveMacro(m *Macro) error {
+	return saveMacro(macroDir(), m)
+}
+
+// LoadMacro reads a saved macro by name, if owner may use it.
+func LoadMacro(owner, name string) (*Macro, error) {
+	return loadMacroFor(macroDir(), owner, name)
+}
+
+// ListMacros returns the saved macros owner may use, sorted by name.
+// Invalid files are skipped.
+func ListMacros(owner string) []Macro {
+	var macros []Macro
+	for _, m := range listMacros(macroDir()) {
+		if m.usableBy(owner) {
+			macros = append(macros, m)
+		}
+	}
+	return macros
+}
+
+// usableBy reports whether a chat user may run a macro: their own, or one
+// saved from the CLI or MCP. Callers without an owner may run any.
+func (m *Macro) usableBy(owner string) bool {
+	return owner == "" || m.Owner == "" || m.Owner == owner
+}
+
+func saveMacro(dir string, m *Macro) error {
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return err
+	}
+	// Validate what will be read back, so a bad macro fails when saved rather
+	// than when a job replays it
+	if _, err := ParseMacro(data); err != nil {
+		return err
+	}
+	// A chat user may only replace their own macros, since someone else's
+	// cron job may replay it in their logged-in browser session
+	if old, err := loadMacro(dir, m.Name); err == nil && m.Owner != "" && old.Owner != m.Owner {
+		return fmt.Errorf("macro %s belongs to another user; choose another name", m.Name)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return os.WriteFile(filepath.Join(dir, m.Name+".yaml"), data, 0644)
+}
+
+func loadMacroFor(dir, owner, name string) (*Macro, error) {
+	m, err := loadMacro(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	if !m.usableBy(owner) {
+		return nil, fmt.Errorf("macro %s not found", name)
+	}
+	return m, nil
+}
+
+func loadMacro(dir, name string) (*Macro, error) {
+	if !macroNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid macro name %q", name)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name+".yaml"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("macro %s not found", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseMacro(data)
+}
+
+func listMacros(dir string) []Macro {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	var macros []Macro
+	for _, path := range paths {
+		m, err := loadMacro(dir, strings.TrimSuffix(filepath.Base(path), ".yaml"))
+		if err != nil {
+			logger.Warn("[Browser] Skipping macro %s: %v", path, err)
+			continue
+		}
+		macros = append(macros, *m)
+	}
+	sort.Slice(macros, func(i, j int) bool { return macros[i].Name < macros[j].Name })
+	return macros
+}
@@ -0,0 +1,126 @@
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMacroSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	m := &Macro{
+		Name:        "daily-checkin",
+		Description: "check in on the forum",
+		CreatedAt:   time.Now().Truncate(time.Second),
+		Steps: []MacroStep{
+			{Action: "navigate", URL: "https://forum.example/"},
+			{Action: "click", Target: &Locator{Selector: "#checkin", Role: "button", Name: "签到"}},
+			{Action: "type", Target: &Locator{Role: "textbox", Name: "搜索"}, Text: "golang", Submit: true},
+			{Action: "site_action", Site: "zhihu", SiteAction: "comment", Args: map[string]any{"comment": "赞"}},
+		},
+	}
+	if err := saveMacro(dir, m); err != nil {
+		t.Fatalf("saveMacro: %v", err)
+	}
+
+	got, err := loadMacro(dir, "daily-checkin")
+	if err != nil {
+		t.Fatalf("loadMacro: %v", err)
+	}
+	if len(got.Steps) != 4 || *got.Steps[1].Target != *m.Steps[1].Target || !got.Steps[2].Submit || got.Steps[3].Args["comment"] != "赞" {
+		t.Errorf("round trip lost data: %+v", got.Steps)
+	}
+
+	if _, err := loadMacro(dir, "missing"); err == nil {
+		t.Error("loading a missing macro should fail")
+	}
+	if _, err := loadMacro(dir, "../etc/passwd"); err == nil {
+		t.Error("path traversal in macro name not rejected")
+	}
+
+	os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("name: broken\nsteps: []"), 0644)
+	if list := listMacros(dir); len(list) != 1 || list[0].Name != "daily-checkin" {
+		t.Errorf("listMacros = %+v", list)
+	}
+}
+
+func TestMacroOwner(t *testing.T) {
+	dir := t.TempDir()
+	steps := []MacroStep{{Action: "navigate", URL: "https://forum.example/"}}
+	if err := saveMacro(dir, &Macro{Name: "shared", Steps: steps}); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveMacro(dir, &Macro{Name: "alice-job", Owner: "slack:alice", Steps: steps}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nobody replaces someone else's macro, nor one saved from the CLI.
+	for _, name := range []string{"alice-job", "shared"} {
+		if err := saveMacro(dir, &Macro{Name: name, Owner: "slack:mallory", Steps: steps}); err == nil {
+			t.Errorf("mallory replaced %s", name)
+		}
+	}
+	if err := saveMacro(dir, &Macro{Name: "alice-job", Owner: "slack:alice", Steps: steps}); err != nil {
+		t.Errorf("alice could not update her macro: %v", err)
+	}
+	if err := saveMacro(dir, &Macro{Name: "shared", Steps: steps}); err != nil {
+		t.Errorf("the CLI could not replace its macro: %v", err)
+	}
+
+	// Nor runs it.
+	if _, err := loadMacroFor(dir, "slack:mallory", "alice-job"); err == nil {
+		t.Error("mallory loaded alice's macro")
+	}
+	for _, owner := range []string{"slack:alice", ""} {
+		if _, err := loadMacroFor(dir, owner, "alice-job"); err != nil {
+			t.Errorf("%q could not load alice's macro: %v", owner, err)
+		}
+	}
+	if _, err := loadMacroFor(dir, "slack:mallory", "shared"); err != nil {
+		t.Errorf("CLI macro not shared: %v", err)
+	}
+}
+
+func TestParseMacro_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"bad name":        "name: a/b\nsteps: [{action: press, key: Enter}]",
+		"no steps":        "name: a",
+		"unknown action":  "name: a\nsteps: [{action: hover}]",
+		"click no target": "name: a\nsteps: [{action: click}]",
+		"navigate no url": "name: a\nsteps: [{action: navigate}]",
+	}
+	for name, data := range tests {
+		if _, err := ParseMacro([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestFindRef(t *testing.T) {
+	refs := map[int]RefEntry{
+		7: {BackendDOMNodeID: 70, Role: "button", Name: "发布"},
+		3: {BackendDOMNodeID: 30, Role: "button", Name: "发布"},
+		5: {BackendDOMNodeID: 50, Role: "link", Name: "发布"},
+	}
+	if entry, ok := findRef(refs, "button", "发布"); !ok || entry.BackendDOMNodeID != 30 {
+		t.Errorf("findRef = %+v, %v; want the lowest matching ref", entry, ok)
+	}
+	if _, ok := findRef(refs, "button", "取消"); ok {
+		t.Error("findRef matched an absent name")
+	}
+}
+
+func TestSessionTrace(t *testing.T) {
+	s := &Session{}
+	for i := 0; i < maxTraceSteps+5; i++ {
+		s.Record(MacroStep{Action: "press", Key: "Tab"})
+	}
+	if n := len(s.Trace()); n != maxTraceSteps {
+		t.Errorf("trace length = %d, want %d", n, maxTraceSteps)
+	}
+	s.ClearTrace()
+	if n := len(s.Trace()); n != 0 {
+		t.Errorf("trace length after clear = %d", n)
+	}
+}
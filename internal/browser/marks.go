@@ -0,0 +1,264 @@
+package browser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/jpeg" // decode screenshot sizes
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+const (
+	maxMarks       = 150
+	markImageJPEG  = 80
+	markClickLimit = 8 * time.Second
+)
+
+// Mark is a numbered box drawn over a clickable element in a marked-up
+// screenshot. Coordinates are CSS pixels in the viewport.
+type Mark struct {
+	Number int
+	Tag    string
+	Label  string
+	X, Y   float64
+	Width  float64
+	Height float64
+
+	el *rod.Element
+}
+
+// Center returns the middle of the mark's box.
+func (m Mark) Center() (float64, float64) {
+	return m.X + m.Width/2, m.Y + m.Height/2
+}
+
+// markCandidatesJS returns the elements a user could click in the viewport,
+// topmost first: links, buttons, form fields, ARIA widgets, canvases and the
+// outermost element of anything styled with a pointer cursor. Each gets a
+// short label in window.__lingtiMarkLabels.
+const markCandidatesJS = `() => {
+	const selector = 'a[href],button,input:not([type=hidden]),select,textarea,summary,label[for],canvas,' +
+		'[role=button],[role=link],[role=tab],[role=menuitem],[role=checkbox],[role=radio],[role=option],[role=switch],' +
+		'[onclick],[contenteditable=""],[contenteditable=true],[tabindex]:not([tabindex="-1"])';
+	const found = new Set(document.querySelectorAll(selector));
+	let scanned = 0;
+	for (const el of document.body ? document.body.querySelectorAll('*') : []) {
+		if (++scanned > 5000) break;
+		const parent = el.parentElement;
+		if (getComputedStyle(el).cursor === 'pointer' && !(parent && getComputedStyle(parent).cursor === 'pointer')) {
+			found.add(el);
+		}
+	}
+
+	const out = [], labels = [];
+	for (const el of found) {
+		const r = el.getBoundingClientRect();
+		if (r.width < 4 || r.height < 4 || r.bottom <= 0 || r.right <= 0 || r.top >= innerHeight || r.left >= innerWidth) continue;
+		const style = getComputedStyle(el);
+		if (style.visibility === 'hidden' || style.opacity === '0') continue;
+		const x = Math.min(Math.max(r.left + r.width / 2, 0), innerWidth - 1);
+		const y = Math.min(Math.max(r.top + r.height / 2, 0), innerHeight - 1);
+		const hit = document.elementFromPoint(x, y);
+		if (!hit || !(el === hit || el.contains(hit) || hit.contains(el))) continue;
+		// A candidate inside another with the same box is the same target
+		if (out.some(o => o.contains(el) && Math.abs(o.getBoundingClientRect().width - r.width) < 2 && Math.abs(o.getBoundingClientRect().height - r.height) < 2)) continue;
+		out.push(el);
+		const text = el.getAttribute('aria-label') || el.getAttribute('title') || el.getAttribute('placeholder') || el.getAttribute('alt') || el.innerText || el.value || '';
+		labels.push({tag: (el.getAttribute('role') || el.tagName).toLowerCase(), label: String(text).replace(/\s+/g, ' ').trim().slice(0, 60)});
+		if (out.length >= 150) break; // maxMarks
+	}
+	window.__lingtiMarkLabels = labels;
+	return out;
+}`
+
+// markOverlayJS draws the numbered boxes. The overlay ignores the mouse and
+// is removed again by markClearJS.
+const markOverlayJS = `(marks) => {
+	const root = document.createElement('div');
+	root.id = '__lingti_marks';
+	root.style.cssText = 'position:fixed;left:0;top:0;width:0;height:0;z-index:2147483647;pointer-events:none';
+	for (const m of marks) {
+		const box = document.createElement('div');
+		box.style.cssText = 'position:fixed;box-sizing:border-box;border:2px solid #e5202e;pointer-events:none;' +
+			'left:' + m.x + 'px;top:' + m.y + 'px;width:' + m.w + 'px;height:' + m.h + 'px';
+		const tag = document.createElement('span');
+		tag.textContent = m.n;
+		tag.style.cssText = 'position:absolute;left:-2px;top:-2px;background:#e5202e;color:#fff;' +
+			'font:bold 12px/14px sans-serif;padding:0 3px;border-radius:0 0 3px 0';
+		box.appendChild(tag);
+		root.appendChild(box);
+	}
+	document.documentElement.appendChild(root);
+}`
+
+const markClearJS = `() => {
+	const root = document.getElementById('__lingti_marks');
+	if (root) root.remove();
+	delete window.__lingtiMarkLabels;
+}`
+
+// Marks finds the clickable elements in the viewport, numbers them and
+// returns a JPEG screenshot with a numbered box drawn over each. The marks
+// and the screenshot's scale are kept for ClickMark and MarkScale.
+func (s *Session) Marks(page *rod.Page) ([]Mark, []byte, error) {
+	els, err := page.ElementsByJS(rod.Eval(markCandidatesJS))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find clickable elements: %w", err)
+	}
+	var labels []struct {
+		Tag   string `json:"tag"`
+		Label string `json:"label"`
+	}
+	if res, err := page.Eval(`() => JSON.stringify(window.__lingtiMarkLabels || [])`); err == nil {
+		_ = json.Unmarshal([]byte(res.Value.Str()), &labels)
+	}
+
+	var marks []Mark
+	for i, el := range els {
+		if len(marks) >= maxMarks {
+			break
+		}
+		box, err := proto.DOMGetBoxModel{ObjectID: el.Object.ObjectID}.Call(page)
+		if err != nil || box.Model == nil {
+			continue
+		}
+		x, y, w, h := quadBounds(box.Model.Border)
+		if w <= 0 || h <= 0 {
+			continue
+		}
+		m := Mark{Number: len(marks) + 1, X: x, Y: y, Width: w, Height: h, el: el}
+		if i < len(labels) {
+			m.Tag, m.Label = labels[i].Tag, labels[i].Label
+		}
+		marks = append(marks, m)
+	}
+
+	overlay := make([]map[string]any, len(marks))
+	for i, m := range marks {
+		overlay[i] = map[string]any{"n": m.Number, "x": m.X, "y": m.Y, "w": m.Width, "h": m.Height}
+	}
+	if _, err := page.Eval(markOverlayJS, overlay); err != nil {
+		return nil, nil, fmt.Errorf("failed to draw marks: %w", err)
+	}
+	quality := markImageJPEG
+	shot, shotErr := page.Screenshot(false, &proto.PageCaptureScreenshot{
+		Format:  proto.PageCaptureScreenshotFormatJpeg,
+		Quality: &quality,
+	})
+	_, _ = page.Eval(markClearJS)
+	if shotErr != nil {
+		return nil, nil, fmt.Errorf("failed to capture screenshot: %w", shotErr)
+	}
+
+	// On HiDPI screens the screenshot has more pixels than the viewport
+	scale := 1.0
+	metrics, err := proto.PageGetLayoutMetrics{}.Call(page)
+	if err == nil && metrics.CSSLayoutViewport != nil && metrics.CSSLayoutViewport.ClientWidth > 0 {
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(shot)); err == nil {
+			scale = float64(cfg.Width) / float64(metrics.CSSLayoutViewport.ClientWidth)
+		}
+	}
+
+	s.mu.Lock()
+	s.marks = marks
+	s.markScale = scale
+	s.mu.Unlock()
+	return marks, shot, nil
+}
+
+// MarkScale returns the screenshot pixels per CSS pixel of the last Marks
+// screenshot, 1 if there was none.
+func (s *Session) MarkScale() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.markScale <= 0 {
+		return 1
+	}
+	return s.markScale
+}
+
+// ClickMark clicks the middle of a mark from the last Marks call. The box is
+// measured again first, in case the page scrolled or reflowed since. The
+// returned locator, for recording, is nil when the element has no unique
+// selector.
+func (s *Session) ClickMark(page *rod.Page, number int) (Mark, *Locator, error) {
+	s.mu.Lock()
+	var mark Mark
+	found := false
+	for _, m := range s.marks {
+		if m.Number == number {
+			mark, found = m, true
+			break
+		}
+	}
+	s.mu.Unlock()
+	if !found {
+		return Mark{}, nil, fmt.Errorf("mark %d not found (run browser_marks first, or page may have changed)", number)
+	}
+
+	box, err := proto.DOMGetBoxModel{ObjectID: mark.el.Object.ObjectID}.Call(page)
+	if err != nil || box.Model == nil {
+		return Mark{}, nil, fmt.Errorf("mark %d is no longer on the page, run browser_marks again", number)
+	}
+	mark.X, mark.Y, mark.Width, mark.Height = quadBounds(box.Model.Border)
+
+	var loc *Locator
+	if v, err := mark.el.Eval(uniqueSelectorJS); err == nil && v.Value.String() != "" {
+		loc = &Locator{Selector: v.Value.String()}
+	}
+	x, y := mark.Center()
+	return mark, loc, ClickAt(page, x, y)
+}
+
+// ClickAt clicks the viewport point x, y (CSS pixels) with the mouse, for
+// targets that have no element of their own, such as canvas apps.
+func ClickAt(page *rod.Page, x, y float64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), markClickLimit)
+	defer cancel()
+	mouse := page.Context(ctx).Mouse
+	if err := mouse.MoveTo(proto.Point{X: x, Y: y}); err != nil {
+		return fmt.Errorf("failed to move mouse: %w", err)
+	}
+	if err := mouse.Click(proto.InputMouseButtonLeft, 1); err != nil {
+		return fmt.Errorf("click at (%.0f, %.0f) failed: %w", x, y, err)
+	}
+	waitStable(page, 300*time.Millisecond, 2*time.Second)
+	return nil
+}
+
+// FormatMarks lists marks as "[n] tag "label" at x,y" lines, with positions
+// in screenshot pixels.
+func FormatMarks(marks []Mark, scale float64) string {
+	if len(marks) == 0 {
+		return "(no clickable elements found — use browser_click_xy with coordinates from the screenshot)"
+	}
+	var sb strings.Builder
+	for _, m := range marks {
+		x, y := m.Center()
+		fmt.Fprintf(&sb, "[%d] %s", m.Number, m.Tag)
+		if m.Label != "" {
+			fmt.Fprintf(&sb, " %q", m.Label)
+		}
+		fmt.Fprintf(&sb, " at %.0f,%.0f\n", x*scale, y*scale)
+	}
+	return sb.String()
+}
+
+// quadBounds returns the bounding box of a CDP quad (four x, y corners).
+func quadBounds(q proto.DOMQuad) (x, y, w, h float64) {
+	if len(q) < 8 {
+		return 0, 0, 0, 0
+	}
+	minX, minY, maxX, maxY := q[0], q[1], q[0], q[1]
+	for i := 2; i+1 < len(q); i += 2 {
+		minX, maxX = min(minX, q[i]), max(maxX, q[i])
+		minY, maxY = min(minY, q[i+1]), max(maxY, q[i+1])
+	}
+	return minX, minY, maxX - minX, maxY - minY
+}
@@ -0,0 +1,35 @@
+package browser
+
+import (
+	"testing"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+func TestQuadBounds(t *testing.T) {
+	tests := []struct {
+		quad       proto.DOMQuad
+		x, y, w, h float64
+	}{
+		{proto.DOMQuad{10, 20, 110, 20, 110, 60, 10, 60}, 10, 20, 100, 40},
+		{proto.DOMQuad{50, 0, 100, 50, 50, 100, 0, 50}, 0, 0, 100, 100}, // rotated 45°
+		{proto.DOMQuad{1, 2}, 0, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		x, y, w, h := quadBounds(tt.quad)
+		if x != tt.x || y != tt.y || w != tt.w || h != tt.h {
+			t.Errorf("quadBounds(%v) = %v,%v %vx%v", tt.quad, x, y, w, h)
+		}
+	}
+}
+
+func TestFormatMarks(t *testing.T) {
+	marks := []Mark{
+		{Number: 1, Tag: "button", Label: "搜索", X: 10, Y: 10, Width: 40, Height: 20},
+		{Number: 2, Tag: "canvas", X: 0, Y: 100, Width: 800, Height: 600},
+	}
+	want := "[1] button \"搜索\" at 60,40\n[2] canvas at 800,800\n"
+	if got := FormatMarks(marks, 2); got != want {
+		t.Errorf("FormatMarks = %q, want %q", got, want)
+	}
+}
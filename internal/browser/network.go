@@ -0,0 +1,180 @@
+package browser
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+
+	"github.com/pltanton/lingti-bot/internal/logger"
+)
+
+// Limits on what a network capture keeps in memory.
+const (
+	maxCapturedEntries = 200     // oldest entries are dropped beyond this
+	maxCapturedBody    = 1 << 20 // bodies are cut at 1 MiB
+	captureWaitPoll    = 200 * time.Millisecond
+)
+
+// NetworkEntry is one captured XHR or fetch exchange.
+type NetworkEntry struct {
+	Method      string
+	URL         string
+	Status      int
+	MIMEType    string
+	RequestBody string
+	Body        string
+	Truncated   bool // Body was cut at maxCapturedBody
+}
+
+// networkCapture records the XHR/fetch responses of one page whose URL
+// matches pattern.
+type networkCapture struct {
+	pattern *regexp.Regexp
+	stop    context.CancelFunc
+
+	mu      sync.Mutex
+	entries []NetworkEntry
+}
+
+// wants reports whether a request of the given type and URL is recorded.
+func (c *networkCapture) wants(resourceType proto.NetworkResourceType, url string) bool {
+	if resourceType != proto.NetworkResourceTypeXHR && resourceType != proto.NetworkResourceTypeFetch {
+		return false
+	}
+	return c.pattern.MatchString(url)
+}
+
+func (c *networkCapture) add(entry NetworkEntry) {
+	if len(entry.Body) > maxCapturedBody {
+		entry.Body = entry.Body[:maxCapturedBody]
+		entry.Truncated = true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = append(c.entries, entry)
+	if len(c.entries) > maxCapturedEntries {
+		c.entries = c.entries[len(c.entries)-maxCapturedEntries:]
+	}
+}
+
+func (c *networkCapture) snapshot() []NetworkEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]NetworkEntry(nil), c.entries...)
+}
+
+// StartCapture records XHR and fetch responses of page whose URL matches
+// pattern (a regexp; empty matches everything), replacing any running
+// capture. Recording continues until StopCapture or the session closes.
+func (s *Session) StartCapture(page *rod.Page, pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid url_pattern: %w", err)
+	}
+	s.StopCapture()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	page = page.Context(ctx)
+	if err := (proto.NetworkEnable{}).Call(page); err != nil {
+		cancel()
+		return fmt.Errorf("failed to enable network events: %w", err)
+	}
+	c := &networkCapture{pattern: re, stop: cancel}
+
+	// Only touched by the event loop goroutine
+	pending := make(map[proto.NetworkRequestID]*NetworkEntry)
+	wait := page.EachEvent(
+		func(e *proto.NetworkRequestWillBeSent) {
+			if c.wants(e.Type, e.Request.URL) {
+				pending[e.RequestID] = &NetworkEntry{Method: e.Request.Method, URL: e.Request.URL, RequestBody: e.Request.PostData}
+			}
+		},
+		func(e *proto.NetworkResponseReceived) {
+			if entry, ok := pending[e.RequestID]; ok {
+				entry.Status = e.Response.Status
+				entry.MIMEType = e.Response.MIMEType
+			}
+		},
+		func(e *proto.NetworkLoadingFinished) {
+			entry, ok := pending[e.RequestID]
+			if !ok {
+				return
+			}
+			delete(pending, e.RequestID)
+			// Fetch the body off the event loop so events keep flowing
+			go func(id proto.NetworkRequestID) {
+				body, err := proto.NetworkGetResponseBody{RequestID: id}.Call(page)
+				if err != nil {
+					logger.Debug("[Browser] No body for %s: %v", entry.URL, err)
+				} else if body.Base64Encoded {
+					data, _ := base64.StdEncoding.DecodeString(body.Body)
+					entry.Body = string(data)
+				} else {
+					entry.Body = body.Body
+				}
+				c.add(*entry)
+			}(e.RequestID)
+		},
+		func(e *proto.NetworkLoadingFailed) {
+			delete(pending, e.RequestID)
+		},
+	)
+	go wait()
+
+	s.mu.Lock()
+	s.capture = c
+	s.mu.Unlock()
+	return nil
+}
+
+// StopCapture ends the session's network capture and drops what it recorded.
+func (s *Session) StopCapture() {
+	s.mu.Lock()
+	c := s.capture
+	s.capture = nil
+	s.mu.Unlock()
+	if c != nil {
+		c.stop()
+	}
+}
+
+// Captured returns the entries recorded so far, oldest first, or false if no
+// capture is running.
+func (s *Session) Captured() ([]NetworkEntry, bool) {
+	s.mu.Lock()
+	c := s.capture
+	s.mu.Unlock()
+	if c == nil {
+		return nil, false
+	}
+	return c.snapshot(), true
+}
+
+// WaitCaptured waits until the capture holds more than after entries or
+// timeout passes, and returns the entries.
+func (s *Session) WaitCaptured(ctx context.Context, after int, timeout time.Duration) ([]NetworkEntry, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		entries, ok := s.Captured()
+		if !ok {
+			return nil, fmt.Errorf("no network capture running (use action=start first)")
+		}
+		if len(entries) > after {
+			return entries, nil
+		}
+		if time.Now().After(deadline) {
+			return entries, fmt.Errorf("no new matching response within %s", timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return entries, ctx.Err()
+		case <-time.After(captureWaitPoll):
+		}
+	}
+}
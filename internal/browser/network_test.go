@@ -0,0 +1,45 @@
+package browser
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+func TestNetworkCapture_Wants(t *testing.T) {
+	c := &networkCapture{pattern: regexp.MustCompile(`api/report`)}
+	tests := []struct {
+		typ  proto.NetworkResourceType
+		url  string
+		want bool
+	}{
+		{proto.NetworkResourceTypeXHR, "https://x.example/api/report?id=1", true},
+		{proto.NetworkResourceTypeFetch, "https://x.example/api/report", true},
+		{proto.NetworkResourceTypeXHR, "https://x.example/api/user", false},
+		{proto.NetworkResourceTypeDocument, "https://x.example/api/report", false},
+		{proto.NetworkResourceTypeScript, "https://x.example/api/report.js", false},
+	}
+	for _, tt := range tests {
+		if got := c.wants(tt.typ, tt.url); got != tt.want {
+			t.Errorf("wants(%s, %s) = %v, want %v", tt.typ, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestNetworkCapture_Limits(t *testing.T) {
+	c := &networkCapture{}
+	c.add(NetworkEntry{URL: "big", Body: strings.Repeat("x", maxCapturedBody+10)})
+	if e := c.snapshot()[0]; len(e.Body) != maxCapturedBody || !e.Truncated {
+		t.Errorf("body length = %d, truncated = %v", len(e.Body), e.Truncated)
+	}
+
+	for i := 0; i < maxCapturedEntries+5; i++ {
+		c.add(NetworkEntry{URL: "small"})
+	}
+	entries := c.snapshot()
+	if len(entries) != maxCapturedEntries || entries[0].URL != "small" {
+		t.Errorf("kept %d entries, first %q; want %d, oldest dropped", len(entries), entries[0].URL, maxCapturedEntries)
+	}
+}
@@ -0,0 +1,366 @@
+package browser
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod/lib/proto"
+
+	"github.com/pltanton/lingti-bot/internal/logger"
+)
+
+// Profile bundle format: magic, PBKDF2 salt, AES-GCM nonce, then the sealed
+// JSON of a ProfileState.
+const (
+	profileBundleMagic = "LTBP1"
+	profileSaltSize    = 16
+	profileKDFRounds   = 600000
+)
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ProfileState is a named browser login: the cookies and localStorage of a
+// session bound to the profile, saved to ~/.lingti/browser-profiles/.
+type ProfileState struct {
+	Name         string                       `json:"name"`
+	Owner        string                       `json:"owner,omitempty"` // chat user who created it with browser_start; "" for the CLI
+	SavedAt      time.Time                    `json:"saved_at"`
+	Cookies      []*proto.NetworkCookie       `json:"cookies"`
+	LocalStorage map[string]map[string]string `json:"local_storage,omitempty"` // origin → key → value
+}
+
+// Origins returns the sites the profile holds state for, sorted.
+func (p *ProfileState) Origins() []string {
+	seen := make(map[string]bool)
+	for _, c := range p.Cookies {
+		seen[strings.TrimPrefix(c.Domain, ".")] = true
+	}
+	for origin := range p.LocalStorage {
+		seen[strings.TrimPrefix(strings.TrimPrefix(origin, "https://"), "http://")] = true
+	}
+	list := make([]string, 0, len(seen))
+	for site := range seen {
+		list = append(list, site)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// ValidateProfileName rejects names that are not usable as file names.
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q (use letters, digits, - and _)", name)
+	}
+	return nil
+}
+
+type profileKeyType struct{}
+
+// ContextWithProfile tags ctx with the browser profile a new session for the
+// calling conversation should log in with.
+func ContextWithProfile(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, profileKeyType{}, name)
+}
+
+func profileFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(profileKeyType{}).(string)
+	return name
+}
+
+// profileDir returns ~/.lingti/browser-profiles.
+func profileDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".lingti", "browser-profiles")
+}
+
+// LoadProfile reads a saved profile.
+func LoadProfile(name string) (*ProfileState, error) {
+	return loadProfile(profileDir(), name)
+}
+
+// SaveProfile writes a profile, replacing any saved state of the same name.
+func SaveProfile(p *ProfileState) error {
+	return saveProfile(profileDir(), p)
+}
+
+// ListProfiles returns the saved profiles sorted by name.
+func ListProfiles() []*ProfileState {
+	return listProfiles(profileDir())
+}
+
+func loadProfile(dir, name string) (*ProfileState, error) {
+	if err := ValidateProfileName(name); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("profile %q not found", name)
+		}
+		return nil, err
+	}
+	var p ProfileState
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid profile %s: %w", name, err)
+	}
+	p.Name = name
+	return &p, nil
+}
+
+func saveProfile(dir string, p *ProfileState) error {
+	if err := ValidateProfileName(p.Name); err != nil {
+		return err
+	}
+	// Profiles hold login cookies: keep them private to the user
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create profile dir: %w", err)
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, p.Name+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// claimProfile checks that the chat user owner may switch to the named
+// profile. A profile nobody saved yet is created for them; one created by
+// another user, or from the CLI, is refused, since it holds someone else's
+// logins. Profiles named in config need no claim.
+func claimProfile(dir, name, owner string) error {
+	p, err := loadProfile(dir, name)
+	if err != nil {
+		if _, statErr := os.Stat(filepath.Join(dir, name+".json")); !os.IsNotExist(statErr) {
+			return err
+		}
+		return saveProfile(dir, &ProfileState{Name: name, Owner: owner, SavedAt: time.Now()})
+	}
+	if p.Owner != owner {
+		return fmt.Errorf("profile %s belongs to another user; choose another name", name)
+	}
+	return nil
+}
+
+func listProfiles(dir string) []*ProfileState {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var list []*ProfileState
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if e.IsDir() || !ok {
+			continue
+		}
+		if p, err := loadProfile(dir, name); err == nil {
+			list = append(list, p)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// ExportProfile seals a saved profile into a bundle encrypted with passphrase,
+// for moving a login to another machine with ImportProfile.
+func ExportProfile(name, passphrase string) ([]byte, error) {
+	p, err := LoadProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	return sealProfile(p, passphrase)
+}
+
+// ImportProfile opens a bundle made by ExportProfile and saves it as name
+// (the exported name if empty). An existing profile is only replaced when
+// overwrite is set.
+func ImportProfile(bundle []byte, passphrase, name string, overwrite bool) (*ProfileState, error) {
+	p, err := openProfile(bundle, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if name != "" {
+		p.Name = name
+	}
+	if err := ValidateProfileName(p.Name); err != nil {
+		return nil, err
+	}
+	if _, err := LoadProfile(p.Name); err == nil && !overwrite {
+		return nil, fmt.Errorf("profile %q already exists", p.Name)
+	}
+	if err := SaveProfile(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func sealProfile(p *ProfileState, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("a passphrase is required")
+	}
+	plain, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, profileSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := profileCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := append([]byte(profileBundleMagic), salt...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plain, []byte(profileBundleMagic)), nil
+}
+
+func openProfile(bundle []byte, passphrase string) (*ProfileState, error) {
+	if !bytes.HasPrefix(bundle, []byte(profileBundleMagic)) {
+		return nil, fmt.Errorf("not a lingti-bot profile bundle")
+	}
+	rest := bundle[len(profileBundleMagic):]
+	if len(rest) < profileSaltSize {
+		return nil, fmt.Errorf("profile bundle is truncated")
+	}
+	salt, rest := rest[:profileSaltSize], rest[profileSaltSize:]
+	gcm, err := profileCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) < gcm.NonceSize() {
+		return nil, fmt.Errorf("profile bundle is truncated")
+	}
+	nonce, sealed := rest[:gcm.NonceSize()], rest[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, sealed, []byte(profileBundleMagic))
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase or corrupted bundle")
+	}
+	var p ProfileState
+	if err := json.Unmarshal(plain, &p); err != nil {
+		return nil, fmt.Errorf("invalid profile bundle: %w", err)
+	}
+	return &p, nil
+}
+
+func profileCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, profileKDFRounds, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// localStorageJS reads the page's origin and localStorage.
+const localStorageJS = `() => {
+	try {
+		return JSON.stringify({origin: location.origin, items: Object.assign({}, localStorage)});
+	} catch (e) {
+		return JSON.stringify({origin: location.origin, items: {}});
+	}
+}`
+
+// localStorageSeedJS returns a script that fills in the saved localStorage
+// of the page's origin without overwriting keys the site already set.
+func localStorageSeedJS(data map[string]map[string]string) string {
+	encoded, _ := json.Marshal(data)
+	return `(() => {
+	try {
+		const items = (` + string(encoded) + `)[location.origin];
+		if (!items) return;
+		for (const [k, v] of Object.entries(items)) {
+			if (localStorage.getItem(k) === null) localStorage.setItem(k, v);
+		}
+	} catch (e) {}
+})()`
+}
+
+// loadProfile applies the session's profile to its browser context: saved
+// cookies are set now and localStorage is seeded into each tab the session
+// opens. A profile that was never saved starts empty.
+func (s *Session) loadProfile() error {
+	p, err := LoadProfile(s.profile)
+	if err != nil {
+		logger.Debug("[Browser] Profile %s starts empty: %v", s.profile, err)
+		return nil
+	}
+	if len(p.Cookies) > 0 {
+		if err := s.rod.SetCookies(proto.CookiesToParams(p.Cookies)); err != nil {
+			return fmt.Errorf("failed to restore cookies: %w", err)
+		}
+	}
+	if len(p.LocalStorage) > 0 {
+		s.seedJS = localStorageSeedJS(p.LocalStorage)
+	}
+	return nil
+}
+
+// SaveProfile writes the session's cookies and the localStorage of its open
+// tabs to its profile. Sessions without a profile are not saved.
+func (s *Session) SaveProfile() error {
+	if s.profile == "" {
+		return nil
+	}
+	cookies, err := s.rod.GetCookies()
+	if err != nil {
+		return fmt.Errorf("failed to read cookies: %w", err)
+	}
+
+	p, err := LoadProfile(s.profile)
+	if err != nil {
+		p = &ProfileState{Name: s.profile}
+	}
+	if p.LocalStorage == nil {
+		p.LocalStorage = make(map[string]map[string]string)
+	}
+	if pages, err := s.Pages(); err == nil {
+		for _, page := range pages {
+			res, err := page.Eval(localStorageJS)
+			if err != nil {
+				continue
+			}
+			var data struct {
+				Origin string            `json:"origin"`
+				Items  map[string]string `json:"items"`
+			}
+			if json.Unmarshal([]byte(res.Value.Str()), &data) != nil || !strings.HasPrefix(data.Origin, "http") {
+				continue
+			}
+			p.LocalStorage[data.Origin] = data.Items
+		}
+	}
+	p.Cookies = cookies
+	p.SavedAt = time.Now()
+	return SaveProfile(p)
+}
+
+// Profile returns the name of the profile the session is logged in with, or
+// "" if it has none.
+func (s *Session) Profile() string {
+	return s.profile
+}
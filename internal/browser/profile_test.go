@@ -0,0 +1,92 @@
+package browser
+
+import (
+	"testing"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+func TestProfileSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	p := &ProfileState{
+		Name:         "zhihu-work",
+		Cookies:      []*proto.NetworkCookie{{Name: "z_c0", Value: "token", Domain: ".zhihu.com", Path: "/"}},
+		LocalStorage: map[string]map[string]string{"https://www.zhihu.com": {"theme": "dark"}},
+	}
+	if err := saveProfile(dir, p); err != nil {
+		t.Fatalf("saveProfile: %v", err)
+	}
+
+	got, err := loadProfile(dir, "zhihu-work")
+	if err != nil {
+		t.Fatalf("loadProfile: %v", err)
+	}
+	if len(got.Cookies) != 1 || got.Cookies[0].Value != "token" || got.LocalStorage["https://www.zhihu.com"]["theme"] != "dark" {
+		t.Errorf("round trip lost data: %+v", got)
+	}
+	if origins := got.Origins(); len(origins) != 2 || origins[0] != "www.zhihu.com" || origins[1] != "zhihu.com" {
+		t.Errorf("Origins = %v", origins)
+	}
+
+	if _, err := loadProfile(dir, "../secrets"); err == nil {
+		t.Error("path traversal in profile name not rejected")
+	}
+	if list := listProfiles(dir); len(list) != 1 || list[0].Name != "zhihu-work" {
+		t.Errorf("listProfiles = %+v", list)
+	}
+}
+
+func TestClaimProfile(t *testing.T) {
+	dir := t.TempDir()
+	if err := saveProfile(dir, &ProfileState{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := claimProfile(dir, "alice-zhihu", "slack:alice"); err != nil {
+		t.Fatalf("claiming a new profile: %v", err)
+	}
+	if p, err := loadProfile(dir, "alice-zhihu"); err != nil || p.Owner != "slack:alice" {
+		t.Fatalf("claimed profile = %+v, %v", p, err)
+	}
+	if err := claimProfile(dir, "alice-zhihu", "slack:alice"); err != nil {
+		t.Errorf("owner refused their own profile: %v", err)
+	}
+	for _, name := range []string{"alice-zhihu", "admin"} {
+		if err := claimProfile(dir, name, "slack:mallory"); err == nil {
+			t.Errorf("mallory got profile %s", name)
+		}
+	}
+}
+
+func TestProfileBundle(t *testing.T) {
+	p := &ProfileState{
+		Name:    "xhs",
+		Cookies: []*proto.NetworkCookie{{Name: "web_session", Value: "abc", Domain: ".xiaohongshu.com"}},
+	}
+	bundle, err := sealProfile(p, "correct horse")
+	if err != nil {
+		t.Fatalf("sealProfile: %v", err)
+	}
+
+	got, err := openProfile(bundle, "correct horse")
+	if err != nil {
+		t.Fatalf("openProfile: %v", err)
+	}
+	if got.Name != "xhs" || len(got.Cookies) != 1 || got.Cookies[0].Value != "abc" {
+		t.Errorf("bundle round trip = %+v", got)
+	}
+
+	if _, err := openProfile(bundle, "wrong"); err == nil {
+		t.Error("wrong passphrase accepted")
+	}
+	bundle[len(bundle)-1] ^= 1
+	if _, err := openProfile(bundle, "correct horse"); err == nil {
+		t.Error("tampered bundle accepted")
+	}
+	if _, err := openProfile([]byte("LTBP1"), "correct horse"); err == nil {
+		t.Error("truncated bundle accepted")
+	}
+	if _, err := sealProfile(p, ""); err == nil {
+		t.Error("empty passphrase accepted")
+	}
+}
@@ -0,0 +1,323 @@
+package browser
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+	"gopkg.in/yaml.v3"
+
+	"github.com/pltanton/lingti-bot/internal/logger"
+)
+
+//go:embed recipes/*.yaml
+var bundledRecipes embed.FS
+
+// Recipe is declarative knowledge about one website: which pages it applies
+// to, notes for the model, named selectors, scripted actions and anti-bot
+// rules. Recipes are YAML files loaded like skills, so supporting a new site
+// needs no code changes.
+type Recipe struct {
+	Name        string                  `yaml:"name"`
+	Description string                  `yaml:"description"`
+	Match       []string                `yaml:"match"`               // "zhihu.com" or "zhihu.com/question"; subdomains match too
+	Notes       string                  `yaml:"notes,omitempty"`     // added to the system prompt while on a matching page
+	Selectors   map[string]string       `yaml:"selectors,omitempty"` // named CSS selectors, available to scripts as selectors.<name>
+	Rules       []RecipeRule            `yaml:"rules,omitempty"`
+	Actions     map[string]RecipeAction `yaml:"actions,omitempty"`
+	URLs        *URLNormalizer          `yaml:"normalize_url,omitempty"`
+	FilePath    string                  `yaml:"-"`
+}
+
+// URLNormalizer tells browser_visited which of a site's URLs are the same
+// page, e.g. a note reached from search results and from a share link.
+type URLNormalizer struct {
+	IDPattern string   `yaml:"id_pattern,omitempty"` // regexp whose first group identifies the page
+	KeepQuery []string `yaml:"keep_query,omitempty"` // query parameters that identify the page; others are dropped
+}
+
+// RecipeRule is an anti-bot rule enforced by browser_navigate.
+type RecipeRule struct {
+	BlockNavigation string `yaml:"block_navigation"` // regexp matched against the target URL
+	Reason          string `yaml:"reason"`           // shown to the model instead of navigating
+}
+
+// RecipeAction is a named operation exposed through browser_site_action.
+type RecipeAction struct {
+	Description string        `yaml:"description"`
+	Params      []RecipeParam `yaml:"params,omitempty"`
+	Steps       []RecipeStep  `yaml:"steps"`
+}
+
+// RecipeParam declares an argument of a recipe action.
+type RecipeParam struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	Required    bool   `yaml:"required,omitempty"`
+}
+
+// RecipeStep is one step of an action. Exactly one of JS or Click is set.
+//
+// JS is a function body run in the page with params, selectors and prev (the
+// previous step's result) in scope; use "return" to produce a result. Click
+// clicks the first element matching a selector name or CSS selector.
+type RecipeStep struct {
+	JS     string        `yaml:"js,omitempty"`
+	Click  string        `yaml:"click,omitempty"`
+	Poll   time.Duration `yaml:"poll,omitempty"`   // re-run while the result is "waiting", up to this long
+	Expect string        `yaml:"expect,omitempty"` // regexp the result must match, otherwise the action fails
+	Wait   time.Duration `yaml:"wait,omitempty"`   // pause after the step
+}
+
+// recipePollInterval is how often a polling step is re-run.
+const recipePollInterval = 200 * time.Millisecond
+
+// LoadRecipes loads site recipes in precedence order: bundled, then
+// ~/.lingti/recipes/, then ./recipes/ in the working directory. A later
+// recipe replaces an earlier one with the same name.
+func LoadRecipes() []Recipe {
+	byName := make(map[string]Recipe)
+
+	entries, _ := fs.ReadDir(bundledRecipes, "recipes")
+	for _, entry := range entries {
+		path := "recipes/" + entry.Name()
+		data, err := bundledRecipes.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		addRecipe(byName, data, path)
+	}
+
+	home, _ := os.UserHomeDir()
+	loadRecipesFromDir(filepath.Join(home, ".lingti", "recipes"), byName)
+	if cwd, err := os.Getwd(); err == nil {
+		loadRecipesFromDir(filepath.Join(cwd, "recipes"), byName)
+	}
+
+	recipes := make([]Recipe, 0, len(byName))
+	for _, r := range byName {
+		recipes = append(recipes, r)
+	}
+	sort.Slice(recipes, func(i, j int) bool { return recipes[i].Name < recipes[j].Name })
+	return recipes
+}
+
+// loadRecipesFromDir loads every *.yaml file in dir. A missing directory is
+// not an error.
+func loadRecipesFromDir(dir string, byName map[string]Recipe) {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		addRecipe(byName, data, path)
+	}
+}
+
+func addRecipe(byName map[string]Recipe, data []byte, path string) {
+	r, err := ParseRecipe(data)
+	if err != nil {
+		logger.Warn("[Browser] Skipping recipe %s: %v", path, err)
+		return
+	}
+	r.FilePath = path
+	byName[r.Name] = *r
+}
+
+// ParseRecipe parses and validates a recipe file.
+func ParseRecipe(data []byte) (*Recipe, error) {
+	var r Recipe
+	if err := yaml.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	if r.Name == "" {
+		return nil, fmt.Errorf("missing required 'name' field")
+	}
+	if len(r.Match) == 0 {
+		return nil, fmt.Errorf("recipe %s has no 'match' patterns", r.Name)
+	}
+	for _, rule := range r.Rules {
+		if _, err := regexp.Compile(rule.BlockNavigation); err != nil {
+			return nil, fmt.Errorf("recipe %s: invalid block_navigation: %w", r.Name, err)
+		}
+	}
+	if r.URLs != nil && r.URLs.IDPattern != "" {
+		re, err := regexp.Compile(r.URLs.IDPattern)
+		if err != nil {
+			return nil, fmt.Errorf("recipe %s: invalid id_pattern: %w", r.Name, err)
+		}
+		if re.NumSubexp() == 0 {
+			return nil, fmt.Errorf("recipe %s: id_pattern needs a capture group", r.Name)
+		}
+	}
+	for name, action := range r.Actions {
+		if len(action.Steps) == 0 {
+			return nil, fmt.Errorf("recipe %s: action %s has no steps", r.Name, name)
+		}
+		for i, step := range action.Steps {
+			if (step.JS == "") == (step.Click == "") {
+				return nil, fmt.Errorf("recipe %s: action %s step %d needs exactly one of js or click", r.Name, name, i+1)
+			}
+			if _, err := regexp.Compile(step.Expect); err != nil {
+				return nil, fmt.Errorf("recipe %s: action %s step %d: invalid expect: %w", r.Name, name, i+1, err)
+			}
+		}
+	}
+	return &r, nil
+}
+
+// Matches reports whether the recipe applies to pageURL.
+func (r *Recipe) Matches(pageURL string) bool {
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, pattern := range r.Match {
+		patternHost, pathPrefix, _ := strings.Cut(strings.ToLower(pattern), "/")
+		if host != patternHost && !strings.HasSuffix(host, "."+patternHost) {
+			continue
+		}
+		if pathPrefix == "" || strings.HasPrefix(strings.TrimPrefix(u.Path, "/"), pathPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchRecipes returns the recipes that apply to pageURL.
+func MatchRecipes(recipes []Recipe, pageURL string) []Recipe {
+	var matched []Recipe
+	for _, r := range recipes {
+		if r.Matches(pageURL) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+// CheckNavigation returns an error explaining why navigating to target is
+// blocked by a recipe's anti-bot rule, or nil.
+func CheckNavigation(recipes []Recipe, target string) error {
+	for _, r := range recipes {
+		for _, rule := range r.Rules {
+			if regexp.MustCompile(rule.BlockNavigation).MatchString(target) {
+				return fmt.Errorf("navigation blocked by %s recipe: %s", r.Name, rule.Reason)
+			}
+		}
+	}
+	return nil
+}
+
+// NormalizeURL returns the key browser_visited stores for rawURL. Fragments
+// are always dropped; a matching recipe's normalize_url rules reduce the URL
+// further, down to "<recipe>:<id>" when its id_pattern matches.
+func NormalizeURL(recipes []Recipe, rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	u.Fragment = ""
+	for _, r := range MatchRecipes(recipes, rawURL) {
+		if r.URLs == nil {
+			continue
+		}
+		if r.URLs.IDPattern != "" {
+			if m := regexp.MustCompile(r.URLs.IDPattern).FindStringSubmatch(u.String()); m != nil {
+				return r.Name + ":" + m[1]
+			}
+		}
+		query := url.Values{}
+		for _, key := range r.URLs.KeepQuery {
+			if v, ok := u.Query()[key]; ok {
+				query[key] = v
+			}
+		}
+		u.RawQuery = query.Encode()
+		break
+	}
+	return u.String()
+}
+
+// RunAction runs a named action on page and returns the last step's result.
+func (r *Recipe) RunAction(page *rod.Page, name string, args map[string]any) (string, error) {
+	action, ok := r.Actions[name]
+	if !ok {
+		return "", fmt.Errorf("recipe %s has no action %q (available: %s)", r.Name, name, strings.Join(r.ActionNames(), ", "))
+	}
+	params := make(map[string]any, len(action.Params))
+	for _, p := range action.Params {
+		v, ok := args[p.Name]
+		if !ok || v == "" {
+			if p.Required {
+				return "", fmt.Errorf("%s is required", p.Name)
+			}
+			v = ""
+		}
+		params[p.Name] = v
+	}
+
+	result := ""
+	for i, step := range action.Steps {
+		script, err := r.stepScript(step, params, result)
+		if err != nil {
+			return "", err
+		}
+		result, err = ExecuteJS(page, script)
+		for deadline := time.Now().Add(step.Poll); err == nil && result == "waiting" && time.Now().Before(deadline); {
+			time.Sleep(recipePollInterval)
+			result, err = ExecuteJS(page, script)
+		}
+		logger.Debug("[Browser] Recipe %s/%s step %d: %s", r.Name, name, i+1, result)
+		if err != nil {
+			return "", fmt.Errorf("step %d failed: %w", i+1, err)
+		}
+		if result == "waiting" || (step.Expect != "" && !regexp.MustCompile(step.Expect).MatchString(result)) {
+			return "", fmt.Errorf("step %d: %s", i+1, result)
+		}
+		time.Sleep(step.Wait)
+	}
+	return result, nil
+}
+
+// stepScript builds the function run for one step. Values are injected as
+// JSON so arguments can never break out of the script.
+func (r *Recipe) stepScript(step RecipeStep, params map[string]any, prev string) (string, error) {
+	body := step.JS
+	if step.Click != "" {
+		selector := step.Click
+		if named, ok := r.Selectors[selector]; ok {
+			selector = named
+		}
+		sel, _ := json.Marshal(selector)
+		body = fmt.Sprintf(`var el = document.querySelector(%s); if (!el) { return 'not found: ' + %s; } el.click(); return 'clicked';`, sel, sel)
+	}
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	selectorsJSON, _ := json.Marshal(r.Selectors)
+	prevJSON, _ := json.Marshal(prev)
+	return fmt.Sprintf("() => { const params = %s; const selectors = %s; const prev = %s;\n%s\n}",
+		paramsJSON, selectorsJSON, prevJSON, body), nil
+}
+
+// ActionNames returns the recipe's action names in sorted order.
+func (r *Recipe) ActionNames() []string {
+	names := make([]string, 0, len(r.Actions))
+	for name := range r.Actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
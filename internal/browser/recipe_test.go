@@ -0,0 +1,99 @@
+package browser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBundledRecipesParse(t *testing.T) {
+	entries, err := bundledRecipes.ReadDir("recipes")
+	if err != nil || len(entries) == 0 {
+		t.Fatalf("no bundled recipes: %v", err)
+	}
+	for _, entry := range entries {
+		data, _ := bundledRecipes.ReadFile("recipes/" + entry.Name())
+		if _, err := ParseRecipe(data); err != nil {
+			t.Errorf("%s: %v", entry.Name(), err)
+		}
+	}
+}
+
+func TestRecipeMatches(t *testing.T) {
+	r := &Recipe{Name: "zhihu", Match: []string{"zhihu.com", "example.com/forum"}}
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://www.zhihu.com/question/1", true},
+		{"https://zhihu.com/", true},
+		{"https://zhuanlan.zhihu.com/p/2", true},
+		{"https://notzhihu.com/", false},
+		{"https://example.com/forum/thread/3", true},
+		{"https://example.com/blog", false},
+		{"about:blank", false},
+	}
+	for _, tt := range tests {
+		if got := r.Matches(tt.url); got != tt.want {
+			t.Errorf("Matches(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestParseRecipe_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"no name":      "match: [a.com]",
+		"no match":     "name: a",
+		"bad rule":     "name: a\nmatch: [a.com]\nrules:\n  - block_navigation: '('",
+		"empty action": "name: a\nmatch: [a.com]\nactions:\n  go: {description: x}",
+		"js and click": "name: a\nmatch: [a.com]\nactions:\n  go:\n    steps:\n      - {js: 'return 1', click: '#b'}",
+	}
+	for name, data := range tests {
+		if _, err := ParseRecipe([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestCheckNavigation(t *testing.T) {
+	recipes := LoadRecipes()
+	if err := CheckNavigation(recipes, "https://www.xiaohongshu.com/explore/abc123"); err == nil {
+		t.Error("direct note URL should be blocked")
+	}
+	if err := CheckNavigation(recipes, "https://www.xiaohongshu.com"); err != nil {
+		t.Errorf("home page blocked: %v", err)
+	}
+}
+
+func TestStepScript(t *testing.T) {
+	r := &Recipe{Selectors: map[string]string{"like": ".like-wrapper"}}
+
+	script, err := r.stepScript(RecipeStep{JS: "return params.comment;"}, map[string]any{"comment": `"); alert(1); ("`}, "prev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(script, "() => {") || !strings.Contains(script, `"comment":"\"); alert(1); (\""`) {
+		t.Errorf("arguments not JSON-encoded:\n%s", script)
+	}
+
+	script, _ = r.stepScript(RecipeStep{Click: "like"}, nil, "")
+	if !strings.Contains(script, `document.querySelector(".like-wrapper")`) {
+		t.Errorf("named selector not resolved:\n%s", script)
+	}
+}
+
+func TestNormalizeURL(t *testing.T) {
+	recipes := LoadRecipes()
+	tests := []struct {
+		in, want string
+	}{
+		{"https://www.xiaohongshu.com/explore/697ec7e7000000002202d5cc?xsec_token=abc", "xiaohongshu:697ec7e7000000002202d5cc"},
+		{"https://www.xiaohongshu.com/search_result/697ec7e7000000002202d5cc", "xiaohongshu:697ec7e7000000002202d5cc"},
+		{"https://www.zhihu.com/question/1/answer/2?utm_source=x#comments", "https://www.zhihu.com/question/1/answer/2"},
+		{"https://example.com/item?id=7#top", "https://example.com/item?id=7"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeURL(recipes, tt.in); got != tt.want {
+			t.Errorf("NormalizeURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
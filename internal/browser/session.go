@@ -0,0 +1,502 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+
+	"github.com/pltanton/lingti-bot/internal/logger"
+)
+
+// Session isolation modes (browser.isolation in config).
+const (
+	IsolationIncognito = "incognito" // each session gets its own browser context (default)
+	IsolationShared    = "shared"    // sessions share the browser's cookies and logins
+)
+
+// Defaults for session limits when not configured.
+const (
+	defaultMaxSessions  = 8
+	defaultSessionIdle  = 30 * time.Minute
+	sessionSweepPeriod  = time.Minute
+	defaultSessionKey   = ""
+	defaultSessionLabel = "default"
+)
+
+// Session is one conversation's view of the browser: its own current page
+// and snapshot refs and, unless isolation is "shared", its own incognito
+// browser context so cookies and tabs don't leak between conversations.
+// Sessions bound to a profile always get their own context, loaded from and
+// saved back to the profile.
+type Session struct {
+	key     string
+	browser *Browser
+	rod     *rod.Browser // incognito context, or the shared browser
+	ownCtx  bool         // rod is a browser context owned by this session
+	profile string       // named login profile, "" for none
+	seedJS  string       // restores the profile's localStorage in new tabs
+
+	mu          sync.Mutex
+	currentPage *rod.Page
+	refs        map[int]RefEntry
+	alloc       *refAllocator                      // stable refs of the document in snapDoc
+	snapDoc     snapshotDoc                        // document the refs were numbered on
+	lastSnaps   map[SnapshotOptions][]SnapshotNode // previous snapshot per options, for diffs
+	marks       []Mark                             // numbered boxes of the last browser_marks screenshot
+	markScale   float64                            // screenshot pixels per CSS pixel of that screenshot
+	trace       []MacroStep                        // successful actions since the last browser_macro_save
+	capture     *networkCapture                    // XHR/fetch recording started by browser_network
+	lastUsed    time.Time
+}
+
+type sessionKeyType struct{}
+
+// ContextWithSession tags ctx with the conversation whose browser session
+// tools should use.
+func ContextWithSession(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, sessionKeyType{}, key)
+}
+
+// sessionKeyFromContext returns the session key, or the default session's.
+func sessionKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(sessionKeyType{}).(string)
+	return key
+}
+
+type ownerKeyType struct{}
+
+// ContextWithOwner tags ctx with the identity of the chat user making the
+// call, who owns the macros and login profiles it creates and may only use
+// their own. Without one (CLI, MCP) everything is available.
+func ContextWithOwner(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, ownerKeyType{}, identity)
+}
+
+// OwnerFromContext returns the chat user of ctx, or "".
+func OwnerFromContext(ctx context.Context) string {
+	owner, _ := ctx.Value(ownerKeyType{}).(string)
+	return owner
+}
+
+// InConversation reports whether ctx belongs to a chat conversation rather
+// than the default session of MCP clients and the CLI.
+func InConversation(ctx context.Context) bool {
+	return sessionKeyFromContext(ctx) != defaultSessionKey
+}
+
+// Hold marks the calling conversation's session as in use by a turn until
+// release is called. Held sessions are never evicted to make room or for
+// being idle, so a turn doesn't lose its page halfway through.
+func (b *Browser) Hold(ctx context.Context) (release func()) {
+	key := sessionKeyFromContext(ctx)
+	b.mu.Lock()
+	b.held[key]++
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			if b.held[key]--; b.held[key] <= 0 {
+				delete(b.held, key)
+			}
+			b.mu.Unlock()
+		})
+	}
+}
+
+// SessionFor returns the browser session of the conversation the calling turn
+// belongs to, creating it if needed. Calls without a conversation (MCP
+// clients, CLI) share a default session on the browser's own context. The
+// browser must be running.
+//
+// A new session logs in with the profile chosen by browser_start for the
+// conversation, else the one ctx carries (agents[].browser_profile), else
+// browser.profile from config.
+func (b *Browser) SessionFor(ctx context.Context) (*Session, error) {
+	key := sessionKeyFromContext(ctx)
+
+	// Evicted sessions are saved and closed once b.mu is released
+	var evicted []*Session
+	defer func() {
+		for _, s := range evicted {
+			s.close()
+		}
+	}()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.running {
+		return nil, fmt.Errorf("browser not running")
+	}
+
+	if s, ok := b.sessions[key]; ok {
+		s.touch()
+		return s, nil
+	}
+
+	// Make room by evicting the least recently used sessions. If every
+	// session is held by a turn, go over the limit rather than break one.
+	for len(b.sessions) >= b.maxSessions {
+		victim := b.oldestSessionLocked()
+		if victim == nil {
+			logger.Debug("[Browser] All %d sessions are in use, exceeding the limit", len(b.sessions))
+			break
+		}
+		b.detachLocked(victim)
+		evicted = append(evicted, victim)
+	}
+
+	profile, ok := b.profileOverrides[key]
+	if !ok {
+		profile = profileFromContext(ctx)
+	}
+	if profile == "" {
+		profile = b.defaultProfile
+	}
+
+	s := &Session{
+		key:      key,
+		browser:  b,
+		rod:      b.browser,
+		profile:  profile,
+		refs:     make(map[int]RefEntry),
+		lastUsed: time.Now(),
+	}
+	if profile != "" || (key != defaultSessionKey && b.isolation != IsolationShared) {
+		incognito, err := b.browser.Incognito()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create browser context: %w", err)
+		}
+		s.rod = incognito
+		s.ownCtx = true
+	}
+	if profile != "" {
+		if err := s.loadProfile(); err != nil {
+			_ = proto.TargetDisposeBrowserContext{BrowserContextID: s.rod.BrowserContextID}.Call(b.browser)
+			return nil, fmt.Errorf("failed to load profile %s: %w", profile, err)
+		}
+	}
+	b.sessions[key] = s
+	logger.Debug("[Browser] Created session %s (%d active)", s.label(), len(b.sessions))
+	return s, nil
+}
+
+// UseProfile makes the calling conversation log in with the named profile
+// ("" to go back to the default). Its current session is saved and closed so
+// the next browser call starts fresh with the profile. A chat user may only
+// pick the configured profiles and those they created.
+func (b *Browser) UseProfile(ctx context.Context, name string) error {
+	if name != "" {
+		if err := ValidateProfileName(name); err != nil {
+			return err
+		}
+	}
+	key := sessionKeyFromContext(ctx)
+
+	b.mu.Lock()
+	configured := name == "" || name == b.defaultProfile || name == profileFromContext(ctx)
+	b.mu.Unlock()
+	if owner := OwnerFromContext(ctx); owner != "" && !configured {
+		if err := claimProfile(profileDir(), name, owner); err != nil {
+			return err
+		}
+	}
+
+	b.mu.Lock()
+	if name == "" {
+		delete(b.profileOverrides, key)
+	} else {
+		b.profileOverrides[key] = name
+	}
+	s, ok := b.sessions[key]
+	if ok && s.profile != name {
+		b.detachLocked(s)
+	}
+	b.mu.Unlock()
+
+	if ok && s.profile != name {
+		s.close()
+	}
+	return nil
+}
+
+// CloseSession saves and closes the calling conversation's session, leaving
+// the browser and other conversations' sessions running. The next browser
+// call starts a fresh session. It reports whether there was one to close.
+func (b *Browser) CloseSession(ctx context.Context) bool {
+	b.mu.Lock()
+	s, ok := b.sessions[sessionKeyFromContext(ctx)]
+	if ok {
+		b.detachLocked(s)
+	}
+	b.mu.Unlock()
+
+	if ok {
+		s.close()
+	}
+	return ok
+}
+
+// PageURL returns the URL of the page the calling conversation is working on,
+// or "" if it has none yet. Unlike SessionFor it never starts a session.
+func (b *Browser) PageURL(ctx context.Context) string {
+	b.mu.Lock()
+	s, ok := b.sessions[sessionKeyFromContext(ctx)]
+	b.mu.Unlock()
+	if !ok {
+		return ""
+	}
+
+	s.mu.Lock()
+	page := s.currentPage
+	s.mu.Unlock()
+	if page == nil {
+		pages, err := s.Pages()
+		if err != nil || len(pages) == 0 {
+			return ""
+		}
+		page = pages.First()
+	}
+	info, err := page.Info()
+	if err != nil {
+		return ""
+	}
+	return info.URL
+}
+
+// sweepLoop periodically closes sessions idle for longer than the configured
+// timeout.
+func (b *Browser) sweepLoop() {
+	ticker := time.NewTicker(sessionSweepPeriod)
+	defer ticker.Stop()
+	for range ticker.C {
+		var idle, profiled []*Session
+		b.mu.Lock()
+		for _, s := range b.sessions {
+			if s.idle() > b.sessionIdle && b.held[s.key] == 0 {
+				b.detachLocked(s)
+				idle = append(idle, s)
+			} else if s.profile != "" {
+				profiled = append(profiled, s)
+			}
+		}
+		b.mu.Unlock()
+
+		for _, s := range idle {
+			s.close()
+		}
+		// Save logins regularly so they survive a crash or kill
+		for _, s := range profiled {
+			if err := s.SaveProfile(); err != nil {
+				logger.Debug("[Browser] Failed to save profile %s: %v", s.profile, err)
+			}
+		}
+	}
+}
+
+// oldestSessionLocked returns the least recently used session no turn holds,
+// or nil if every session is held. Must be called with b.mu held.
+func (b *Browser) oldestSessionLocked() *Session {
+	var free []*Session
+	for _, s := range b.sessions {
+		if b.held[s.key] == 0 {
+			free = append(free, s)
+		}
+	}
+	if len(free) == 0 {
+		return nil
+	}
+	sort.Slice(free, func(i, j int) bool { return free[i].idle() > free[j].idle() })
+	return free[0]
+}
+
+// detachLocked forgets a session so no new call can get it. The caller
+// closes it with close after releasing b.mu, since saving the profile and
+// disposing the context are CDP round trips. Must be called with b.mu held.
+func (b *Browser) detachLocked(s *Session) {
+	delete(b.sessions, s.key)
+}
+
+// close saves the session's profile and closes its browser context.
+func (s *Session) close() {
+	s.StopCapture()
+	if err := s.SaveProfile(); err != nil {
+		logger.Warn("[Browser] Failed to save profile %s: %v", s.profile, err)
+	}
+	if s.ownCtx {
+		_ = proto.TargetDisposeBrowserContext{BrowserContextID: s.rod.BrowserContextID}.Call(s.rod)
+	}
+	logger.Debug("[Browser] Closed session %s (idle %s)", s.label(), s.idle().Round(time.Second))
+}
+
+func (s *Session) label() string {
+	if s.key == defaultSessionKey {
+		return defaultSessionLabel
+	}
+	return s.key
+}
+
+func (s *Session) touch() {
+	s.mu.Lock()
+	s.lastUsed = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *Session) idle() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Since(s.lastUsed)
+}
+
+// Pages returns the tabs belonging to this session.
+func (s *Session) Pages() (rod.Pages, error) {
+	if !s.ownCtx {
+		return s.rod.Pages()
+	}
+	list, err := proto.TargetGetTargets{}.Call(s.rod)
+	if err != nil {
+		return nil, err
+	}
+	var pages rod.Pages
+	for _, target := range list.TargetInfos {
+		if target.Type != proto.TargetTargetInfoTypePage || target.BrowserContextID != s.rod.BrowserContextID {
+			continue
+		}
+		page, err := s.rod.PageFromTarget(target.TargetID)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, page)
+	}
+	return pages, nil
+}
+
+// OpenPage opens a new background tab in this session.
+func (s *Session) OpenPage(url string) (*rod.Page, error) {
+	if s.seedJS == "" {
+		return s.rod.Page(proto.TargetCreateTarget{URL: url, Background: true})
+	}
+	// Seed the profile's localStorage before the first load
+	page, err := s.rod.Page(proto.TargetCreateTarget{URL: "about:blank", Background: true})
+	if err != nil {
+		return nil, err
+	}
+	s.seed(page)
+	if url != "" && url != "about:blank" {
+		if err := page.Navigate(url); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// seed installs the profile's localStorage restore script on page.
+func (s *Session) seed(page *rod.Page) {
+	if s.seedJS == "" {
+		return
+	}
+	if _, err := page.EvalOnNewDocument(s.seedJS); err != nil {
+		logger.Debug("[Browser] Failed to seed localStorage: %v", err)
+	}
+}
+
+// PageCount returns the number of open tabs in this session.
+func (s *Session) PageCount() int {
+	pages, err := s.Pages()
+	if err != nil {
+		return 0
+	}
+	return len(pages)
+}
+
+// SwitchToNewestPage updates currentPage to the most recently opened tab,
+// if a new tab has appeared since lastCount. Returns true if switched.
+func (s *Session) SwitchToNewestPage(lastCount int) bool {
+	pages, err := s.Pages()
+	if err != nil || len(pages) <= lastCount {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// The newest tab is the last one
+	s.currentPage = pages[len(pages)-1]
+	s.seed(s.currentPage)
+	s.refs = make(map[int]RefEntry) // invalidate refs for old page
+	return true
+}
+
+// ActivePage returns the page the session is currently working on.
+// Returns currentPage if one has been set (by browser_navigate).
+// Falls back to the first tab in the session, or creates a blank one.
+func (s *Session) ActivePage() (*rod.Page, error) {
+	s.mu.Lock()
+	current := s.currentPage
+	s.mu.Unlock()
+	if current != nil {
+		return current, nil
+	}
+
+	pages, err := s.Pages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pages: %w", err)
+	}
+	if len(pages) > 0 {
+		return pages.First(), nil
+	}
+	page, err := s.OpenPage("about:blank")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create page: %w", err)
+	}
+	s.SetCurrentPage(page)
+	return page, nil
+}
+
+// SetCurrentPage records the page the session is currently working on.
+// Called by browser_navigate after opening/navigating a tab.
+func (s *Session) SetCurrentPage(page *rod.Page) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.currentPage = page
+}
+
+// NavigationPage returns the page to use for a browser_navigate call.
+// When sharing the user's existing Chrome it opens a fresh tab so the user's
+// current page is never hijacked. Otherwise it reuses the session's page for
+// workflow continuity.
+func (s *Session) NavigationPage() (*rod.Page, error) {
+	if !s.ownCtx && s.browser.IsConnected() {
+		page, err := s.OpenPage("about:blank")
+		if err != nil {
+			return nil, fmt.Errorf("failed to open new tab: %w", err)
+		}
+		return page, nil
+	}
+	return s.ActivePage()
+}
+
+// SetRefs stores the ref map from a snapshot.
+func (s *Session) SetRefs(refs map[int]RefEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refs = refs
+}
+
+// GetRef returns a ref entry by number.
+func (s *Session) GetRef(ref int) (RefEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.refs[ref]
+	return entry, ok
+}
+
+// Browser returns the browser the session belongs to.
+func (s *Session) Browser() *Browser {
+	return s.browser
+}
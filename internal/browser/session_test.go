@@ -0,0 +1,92 @@
+package browser
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pltanton/lingti-bot/internal/config"
+)
+
+func TestApplySessionConfig(t *testing.T) {
+	b := &Browser{}
+	b.applySessionConfig(config.BrowserConfig{})
+	if b.maxSessions != defaultMaxSessions || b.sessionIdle != defaultSessionIdle {
+		t.Errorf("defaults = (%d, %s)", b.maxSessions, b.sessionIdle)
+	}
+
+	b.applySessionConfig(config.BrowserConfig{MaxSessions: 2, SessionIdleMinutes: 5, Isolation: IsolationShared})
+	if b.maxSessions != 2 || b.sessionIdle != 5*time.Minute || b.isolation != IsolationShared {
+		t.Errorf("configured = (%d, %s, %q)", b.maxSessions, b.sessionIdle, b.isolation)
+	}
+}
+
+func TestSessionFor(t *testing.T) {
+	b := &Browser{sessions: make(map[string]*Session)}
+	if _, err := b.SessionFor(context.Background()); err == nil {
+		t.Fatal("expected an error when the browser is not running")
+	}
+
+	// Shared isolation never touches the underlying browser, so no Chrome is needed.
+	b.running = true
+	b.applySessionConfig(config.BrowserConfig{MaxSessions: 2, Isolation: IsolationShared})
+
+	ctxA := ContextWithSession(context.Background(), "slack:C1")
+	ctxB := ContextWithSession(context.Background(), "slack:C2")
+	a1, _ := b.SessionFor(ctxA)
+	a1.SetRefs(map[int]RefEntry{1: {Role: "button"}})
+	time.Sleep(time.Millisecond)
+	b2, _ := b.SessionFor(ctxB)
+
+	if _, ok := b2.GetRef(1); ok {
+		t.Error("refs leaked between sessions")
+	}
+	if again, _ := b.SessionFor(ctxA); again != a1 {
+		t.Error("same conversation got a different session")
+	}
+
+	// ctxA was just used, so the cap evicts ctxB's session.
+	time.Sleep(time.Millisecond)
+	def, _ := b.SessionFor(context.Background())
+	if def.label() != defaultSessionLabel {
+		t.Errorf("label = %q", def.label())
+	}
+	if _, ok := b.sessions["slack:C2"]; ok || len(b.sessions) != 2 {
+		t.Errorf("least recently used session not evicted: %d sessions", len(b.sessions))
+	}
+}
+
+func TestSessionFor_Held(t *testing.T) {
+	b := &Browser{sessions: make(map[string]*Session), held: make(map[string]int), running: true}
+	b.applySessionConfig(config.BrowserConfig{MaxSessions: 1, Isolation: IsolationShared})
+
+	ctxA := ContextWithSession(context.Background(), "slack:C1")
+	ctxB := ContextWithSession(context.Background(), "slack:C2")
+	release := b.Hold(ctxA)
+	a, _ := b.SessionFor(ctxA)
+	time.Sleep(time.Millisecond)
+
+	// A turn is still using ctxA's session, so it is kept over the limit.
+	b.SessionFor(ctxB)
+	if b.sessions["slack:C1"] != a || len(b.sessions) != 2 {
+		t.Fatalf("held session evicted: %d sessions", len(b.sessions))
+	}
+
+	release()
+	release()
+	if len(b.held) != 0 {
+		t.Errorf("held = %v after release", b.held)
+	}
+	b.SessionFor(ctxB)
+	b.SessionFor(context.Background())
+	if _, ok := b.sessions["slack:C1"]; ok || len(b.sessions) != 1 {
+		t.Errorf("released sessions not evicted: %d sessions", len(b.sessions))
+	}
+
+	if b.CloseSession(ctxB) {
+		t.Error("closed a session ctxB no longer has")
+	}
+	if !b.CloseSession(context.Background()) || len(b.sessions) != 0 {
+		t.Error("CloseSession did not close the session")
+	}
+}
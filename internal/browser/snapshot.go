@@ -60,38 +60,186 @@ var visibleRoles = map[string]bool{
 	"cell":          true,
 }
 
+// SnapshotOptions narrows a snapshot to the part of the page a tool needs.
+// The zero value captures the whole page.
+type SnapshotOptions struct {
+	Root            proto.DOMBackendNodeID // only this element's subtree; 0 for the whole page
+	InteractiveOnly bool                   // only interactive elements, no headings/landmarks
+	ViewportOnly    bool                   // only elements currently on screen
+}
+
+// SnapshotNode is one element line of a snapshot.
+type SnapshotNode struct {
+	Ref              int
+	Role             string
+	Name             string
+	Depth            int
+	BackendDOMNodeID proto.DOMBackendNodeID
+}
+
+// SnapshotResult is a snapshot together with the previous snapshot of the
+// same document taken with the same options, for diffing.
+type SnapshotResult struct {
+	Nodes       []SnapshotNode
+	Previous    []SnapshotNode
+	HasPrevious bool
+}
+
+// String formats the snapshot as indented "[ref] role "name"" lines.
+func (r *SnapshotResult) String() string {
+	return formatSnapshot(r.Nodes)
+}
+
+// Diff formats the nodes added, removed and changed since the previous
+// snapshot.
+func (r *SnapshotResult) Diff() string {
+	return diffSnapshots(r.Previous, r.Nodes)
+}
+
+// Refs returns the number of refs the snapshot can be acted on by.
+func (r *SnapshotResult) Refs() int {
+	n := 0
+	for _, node := range r.Nodes {
+		if node.BackendDOMNodeID != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// refAllocator hands out refs keyed on backend DOM node IDs, so an element
+// keeps its ref for as long as it stays in the document.
+type refAllocator struct {
+	ids  map[proto.DOMBackendNodeID]int
+	next int
+}
+
+func newRefAllocator() *refAllocator {
+	return &refAllocator{ids: make(map[proto.DOMBackendNodeID]int), next: 1}
+}
+
+// ref returns the node's ref, assigning the next free one on first sight.
+// Nodes without a backend ID get a fresh ref every time.
+func (a *refAllocator) ref(id proto.DOMBackendNodeID) int {
+	if r, ok := a.ids[id]; ok && id != 0 {
+		return r
+	}
+	r := a.next
+	a.next++
+	if id != 0 {
+		a.ids[id] = r
+	}
+	return r
+}
+
+// snapshotDoc identifies the document a session's refs were numbered on.
+type snapshotDoc struct {
+	target proto.TargetTargetID
+	root   proto.DOMBackendNodeID
+}
+
 // Snapshot captures the accessibility tree and returns formatted text with refs.
+// Refs are numbered from 1 on every call; tools use Session.Snapshot, which
+// keeps them stable across snapshots.
 func Snapshot(page *rod.Page) (string, map[int]RefEntry, error) {
-	// Get the full accessibility tree
-	nodes, err := proto.AccessibilityGetFullAXTree{}.Call(page)
+	tree, err := proto.AccessibilityGetFullAXTree{}.Call(page)
 	if err != nil {
 		return "", nil, fmt.Errorf("failed to get accessibility tree: %w", err)
 	}
 
-	if len(nodes.Nodes) == 0 {
+	if len(tree.Nodes) == 0 {
 		return "(empty page)", nil, nil
 	}
 
+	nodes := walkAXTree(tree.Nodes, axFilter{}, newRefAllocator().ref)
+	return formatSnapshot(nodes), refsOf(nodes), nil
+}
+
+// Snapshot captures the page's accessibility tree narrowed by opts and stores
+// its refs in the session. Refs are keyed on backend DOM node IDs, so an
+// element keeps its ref across snapshots of the same document; numbering
+// restarts from 1 when the tab navigates to a new document.
+func (s *Session) Snapshot(page *rod.Page, opts SnapshotOptions) (*SnapshotResult, error) {
+	tree, err := proto.AccessibilityGetFullAXTree{}.Call(page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accessibility tree: %w", err)
+	}
+
+	filter := axFilter{interactiveOnly: opts.InteractiveOnly}
+	if opts.Root != 0 {
+		if filter.scope, err = subtreeNodes(page, opts.Root); err != nil {
+			return nil, err
+		}
+	}
+	if opts.ViewportOnly {
+		if filter.visible, err = viewportNodes(page); err != nil {
+			return nil, err
+		}
+	}
+
+	doc := snapshotDoc{target: page.TargetID}
+	if len(tree.Nodes) > 0 {
+		doc.root = tree.Nodes[0].BackendDOMNodeID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if doc != s.snapDoc || s.alloc == nil {
+		s.snapDoc = doc
+		s.alloc = newRefAllocator()
+		s.lastSnaps = make(map[SnapshotOptions][]SnapshotNode)
+		s.refs = make(map[int]RefEntry)
+	}
+
+	nodes := walkAXTree(tree.Nodes, filter, s.alloc.ref)
+	result := &SnapshotResult{Nodes: nodes}
+	result.Previous, result.HasPrevious = s.lastSnaps[opts]
+	s.lastSnaps[opts] = nodes
+
+	// A full snapshot replaces the refs; a narrowed one only adds to them,
+	// since refs of elements outside it are still valid
+	if opts == (SnapshotOptions{}) {
+		s.refs = refsOf(nodes)
+	} else {
+		for ref, entry := range refsOf(nodes) {
+			s.refs[ref] = entry
+		}
+	}
+	return result, nil
+}
+
+// axFilter drops nodes from a snapshot. A nil set lets every node through.
+type axFilter struct {
+	interactiveOnly bool
+	scope           map[proto.DOMBackendNodeID]bool // nodes in the scoped subtree
+	visible         map[proto.DOMBackendNodeID]bool // nodes laid out in the viewport
+}
+
+func (f axFilter) active() bool {
+	return f.interactiveOnly || f.scope != nil || f.visible != nil
+}
+
+// walkAXTree walks the accessibility tree depth-first and returns the nodes
+// worth showing, numbered by ref.
+func walkAXTree(nodes []*proto.AccessibilityAXNode, filter axFilter, ref func(proto.DOMBackendNodeID) int) []SnapshotNode {
+	if len(nodes) == 0 {
+		return nil
+	}
+
 	// Build parent→children map
 	childMap := make(map[proto.AccessibilityAXNodeID][]proto.AccessibilityAXNodeID)
 	nodeMap := make(map[proto.AccessibilityAXNodeID]*proto.AccessibilityAXNode)
-	var rootID proto.AccessibilityAXNodeID
+	rootID := nodes[0].NodeID
 
-	for i, node := range nodes.Nodes {
+	for _, node := range nodes {
 		nodeMap[node.NodeID] = node
-		if i == 0 {
-			rootID = node.NodeID
-		}
 		for _, childID := range node.ChildIDs {
 			childMap[node.NodeID] = append(childMap[node.NodeID], childID)
 		}
 	}
 
-	refs := make(map[int]RefEntry)
-	refCounter := 1
-	var sb strings.Builder
+	var out []SnapshotNode
 
-	// Walk the tree depth-first
 	var walk func(id proto.AccessibilityAXNodeID, depth int)
 	walk = func(id proto.AccessibilityAXNodeID, depth int) {
 		node, ok := nodeMap[id]
@@ -129,43 +277,170 @@ func Snapshot(page *rod.Page) (string, map[int]RefEntry, error) {
 
 		// Determine if this node gets a ref (interactive or named visible)
 		isInteractive := interactiveRoles[role]
-		isVisible := visibleRoles[role] && name != ""
-
-		indent := strings.Repeat("  ", depth)
-
-		if isInteractive || isVisible {
-			ref := refCounter
-			refCounter++
+		isVisible := visibleRoles[role] && name != "" && !filter.interactiveOnly
 
-			if node.BackendDOMNodeID != 0 {
-				refs[ref] = RefEntry{
-					BackendDOMNodeID: node.BackendDOMNodeID,
-					Role:             role,
-					Name:             name,
-				}
-			}
+		show := isInteractive || isVisible
+		if filter.scope != nil && !filter.scope[node.BackendDOMNodeID] {
+			show = false
+		}
+		if filter.visible != nil && !filter.visible[node.BackendDOMNodeID] {
+			show = false
+		}
 
-			if name != "" {
-				fmt.Fprintf(&sb, "%s[%d] %s %q\n", indent, ref, role, name)
-			} else {
-				fmt.Fprintf(&sb, "%s[%d] %s\n", indent, ref, role)
-			}
+		if show {
+			out = append(out, SnapshotNode{
+				Ref:              ref(node.BackendDOMNodeID),
+				Role:             role,
+				Name:             name,
+				Depth:            depth,
+				BackendDOMNodeID: node.BackendDOMNodeID,
+			})
 		}
 
-		// Walk children
+		// Filtered snapshots only indent under the nodes they show
+		childDepth := depth + 1
+		if filter.active() && !show {
+			childDepth = depth
+		}
 		for _, childID := range childMap[id] {
-			walk(childID, depth+1)
+			walk(childID, childDepth)
 		}
 	}
 
 	walk(rootID, 0)
+	return out
+}
+
+// refsOf returns the ref map of snapshot nodes that can be acted on.
+func refsOf(nodes []SnapshotNode) map[int]RefEntry {
+	refs := make(map[int]RefEntry)
+	for _, n := range nodes {
+		if n.BackendDOMNodeID != 0 {
+			refs[n.Ref] = RefEntry{BackendDOMNodeID: n.BackendDOMNodeID, Role: n.Role, Name: n.Name}
+		}
+	}
+	return refs
+}
+
+func formatNode(n SnapshotNode) string {
+	if n.Name != "" {
+		return fmt.Sprintf("[%d] %s %q", n.Ref, n.Role, n.Name)
+	}
+	return fmt.Sprintf("[%d] %s", n.Ref, n.Role)
+}
+
+func formatSnapshot(nodes []SnapshotNode) string {
+	if len(nodes) == 0 {
+		return "(no interactive elements found)"
+	}
+	var sb strings.Builder
+	for _, n := range nodes {
+		sb.WriteString(strings.Repeat("  ", n.Depth))
+		sb.WriteString(formatNode(n))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// diffSnapshots lists the nodes of cur that are new (+) or whose role or
+// name changed (~) since prev, then the nodes that are gone (-).
+func diffSnapshots(prev, cur []SnapshotNode) string {
+	before := make(map[int]SnapshotNode, len(prev))
+	for _, n := range prev {
+		before[n.Ref] = n
+	}
+	seen := make(map[int]bool, len(cur))
 
-	result := sb.String()
-	if result == "" {
-		result = "(no interactive elements found)"
+	var lines []string
+	added, removed, changed := 0, 0, 0
+	for _, n := range cur {
+		seen[n.Ref] = true
+		old, ok := before[n.Ref]
+		switch {
+		case !ok:
+			added++
+			lines = append(lines, "+ "+formatNode(n))
+		case old.Role != n.Role || old.Name != n.Name:
+			changed++
+			lines = append(lines, fmt.Sprintf("~ %s (was %s %q)", formatNode(n), old.Role, old.Name))
+		}
+	}
+	for _, n := range prev {
+		if !seen[n.Ref] {
+			removed++
+			lines = append(lines, "- "+formatNode(n))
+		}
 	}
 
-	return result, refs, nil
+	if len(lines) == 0 {
+		return "No changes since the last snapshot.\n"
+	}
+	return fmt.Sprintf("Changes since the last snapshot: %d added, %d removed, %d changed\n%s\n",
+		added, removed, changed, strings.Join(lines, "\n"))
+}
+
+// subtreeNodes returns the backend IDs of root and everything under it,
+// including shadow roots and iframe documents.
+func subtreeNodes(page *rod.Page, root proto.DOMBackendNodeID) (map[proto.DOMBackendNodeID]bool, error) {
+	depth := -1
+	res, err := proto.DOMDescribeNode{BackendNodeID: root, Depth: &depth, Pierce: true}.Call(page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve snapshot scope: %w", err)
+	}
+	ids := make(map[proto.DOMBackendNodeID]bool)
+	var collect func(n *proto.DOMNode)
+	collect = func(n *proto.DOMNode) {
+		if n == nil {
+			return
+		}
+		ids[n.BackendNodeID] = true
+		for _, c := range n.Children {
+			collect(c)
+		}
+		for _, c := range n.ShadowRoots {
+			collect(c)
+		}
+		collect(n.ContentDocument)
+	}
+	collect(res.Node)
+	return ids, nil
+}
+
+// viewportNodes returns the backend IDs of the main document's elements
+// whose layout box overlaps the viewport.
+func viewportNodes(page *rod.Page) (map[proto.DOMBackendNodeID]bool, error) {
+	metrics, err := proto.PageGetLayoutMetrics{}.Call(page)
+	if err != nil || metrics.CSSLayoutViewport == nil {
+		return nil, fmt.Errorf("failed to get viewport: %w", err)
+	}
+	snap, err := proto.DOMSnapshotCaptureSnapshot{ComputedStyles: []string{}}.Call(page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page layout: %w", err)
+	}
+
+	ids := make(map[proto.DOMBackendNodeID]bool)
+	if len(snap.Documents) == 0 || snap.Documents[0].Nodes == nil || snap.Documents[0].Layout == nil {
+		return ids, nil
+	}
+	// Layout bounds of the main document are in page coordinates
+	doc := snap.Documents[0]
+	vp := metrics.CSSLayoutViewport
+	viewport := proto.DOMSnapshotRectangle{float64(vp.PageX), float64(vp.PageY), float64(vp.ClientWidth), float64(vp.ClientHeight)}
+	for i, nodeIndex := range doc.Layout.NodeIndex {
+		if i < len(doc.Layout.Bounds) && nodeIndex < len(doc.Nodes.BackendNodeID) && overlaps(doc.Layout.Bounds[i], viewport) {
+			ids[doc.Nodes.BackendNodeID[nodeIndex]] = true
+		}
+	}
+	return ids, nil
+}
+
+// overlaps reports whether two non-empty x, y, width, height rectangles
+// intersect.
+func overlaps(a, b proto.DOMSnapshotRectangle) bool {
+	if len(a) < 4 || len(b) < 4 || a[2] <= 0 || a[3] <= 0 {
+		return false
+	}
+	return a[0] < b[0]+b[2] && b[0] < a[0]+a[2] && a[1] < b[1]+b[3] && b[1] < a[1]+a[3]
 }
 
 // axValueString extracts the string value from an accessibility Value.
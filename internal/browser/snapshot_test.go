@@ -0,0 +1,122 @@
+package browser
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// searchPageAX is a trimmed Accessibility.getFullAXTree result: a search
+// form and a results list.
+const searchPageAX = `[
+  {"nodeId": "1", "ignored": false, "role": {"type": "role", "value": "RootWebArea"}, "childIds": ["2", "6"], "backendDOMNodeId": 1},
+  {"nodeId": "2", "ignored": false, "role": {"type": "role", "value": "form"}, "name": {"type": "computedString", "value": "搜索"}, "childIds": ["3", "4", "5"], "backendDOMNodeId": 10},
+  {"nodeId": "3", "ignored": false, "role": {"type": "role", "value": "textbox"}, "name": {"type": "computedString", "value": "关键词"}, "backendDOMNodeId": 11},
+  {"nodeId": "4", "ignored": false, "role": {"type": "role", "value": "button"}, "name": {"type": "computedString", "value": "搜索"}, "backendDOMNodeId": 12},
+  {"nodeId": "5", "ignored": false, "role": {"type": "role", "value": "StaticText"}, "name": {"type": "computedString", "value": "热门"}, "backendDOMNodeId": 13},
+  {"nodeId": "6", "ignored": true, "childIds": ["7"]},
+  {"nodeId": "7", "ignored": false, "role": {"type": "role", "value": "list"}, "name": {"type": "computedString", "value": "结果"}, "childIds": ["8"], "backendDOMNodeId": 20},
+  {"nodeId": "8", "ignored": false, "role": {"type": "role", "value": "link"}, "name": {"type": "computedString", "value": "第一条"}, "backendDOMNodeId": 21}
+]`
+
+func parseAX(t *testing.T, data string) []*proto.AccessibilityAXNode {
+	t.Helper()
+	var nodes []*proto.AccessibilityAXNode
+	if err := json.Unmarshal([]byte(data), &nodes); err != nil {
+		t.Fatalf("invalid AX fixture: %v", err)
+	}
+	return nodes
+}
+
+func TestWalkAXTree_Filters(t *testing.T) {
+	nodes := parseAX(t, searchPageAX)
+	tests := []struct {
+		name   string
+		filter axFilter
+		want   string
+	}{
+		{
+			name:   "full",
+			filter: axFilter{},
+			want:   "[1] form \"搜索\"\n  [2] textbox \"关键词\"\n  [3] button \"搜索\"\n[4] list \"结果\"\n  [5] link \"第一条\"\n",
+		},
+		{
+			name:   "interactive only",
+			filter: axFilter{interactiveOnly: true},
+			want:   "[1] textbox \"关键词\"\n[2] button \"搜索\"\n[3] link \"第一条\"\n",
+		},
+		{
+			name:   "scoped",
+			filter: axFilter{scope: map[proto.DOMBackendNodeID]bool{20: true, 21: true}},
+			want:   "[1] list \"结果\"\n  [2] link \"第一条\"\n",
+		},
+		{
+			name:   "viewport",
+			filter: axFilter{visible: map[proto.DOMBackendNodeID]bool{1: true, 10: true, 12: true}},
+			want:   "[1] form \"搜索\"\n  [2] button \"搜索\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatSnapshot(walkAXTree(nodes, tt.filter, newRefAllocator().ref))
+			if got != tt.want {
+				t.Errorf("snapshot =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkAXTree_StableRefs(t *testing.T) {
+	alloc := newRefAllocator()
+	first := walkAXTree(parseAX(t, searchPageAX), axFilter{}, alloc.ref)
+
+	// The search runs: the list gets a new first result, the old link is
+	// renamed and the button goes away
+	after := strings.NewReplacer(
+		`"childIds": ["3", "4", "5"]`, `"childIds": ["3", "5"]`,
+		`"childIds": ["8"]`, `"childIds": ["9", "8"]`,
+		`"value": "第一条"}`, `"value": "第二条"}`,
+	).Replace(searchPageAX)
+	after = strings.TrimSuffix(after, "]") + `,
+  {"nodeId": "9", "ignored": false, "role": {"type": "role", "value": "link"}, "name": {"type": "computedString", "value": "新结果"}, "backendDOMNodeId": 22}
+]`
+	second := walkAXTree(parseAX(t, after), axFilter{}, alloc.ref)
+
+	refs := refsOf(second)
+	if refs[2].BackendDOMNodeID != 11 || refs[5].BackendDOMNodeID != 21 || refs[6].BackendDOMNodeID != 22 {
+		t.Errorf("refs not stable: %v", refs)
+	}
+
+	want := "Changes since the last snapshot: 1 added, 1 removed, 1 changed\n" +
+		"+ [6] link \"新结果\"\n" +
+		"~ [5] link \"第二条\" (was link \"第一条\")\n" +
+		"- [3] button \"搜索\"\n"
+	if got := diffSnapshots(first, second); got != want {
+		t.Errorf("diff =\n%s\nwant\n%s", got, want)
+	}
+	if got := diffSnapshots(second, second); got != "No changes since the last snapshot.\n" {
+		t.Errorf("diff of identical snapshots = %q", got)
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	viewport := proto.DOMSnapshotRectangle{0, 800, 1280, 720}
+	tests := []struct {
+		rect proto.DOMSnapshotRectangle
+		want bool
+	}{
+		{proto.DOMSnapshotRectangle{10, 900, 100, 20}, true},
+		{proto.DOMSnapshotRectangle{10, 780, 100, 40}, true}, // partly scrolled off
+		{proto.DOMSnapshotRectangle{10, 100, 100, 20}, false},
+		{proto.DOMSnapshotRectangle{10, 1520, 100, 20}, false},
+		{proto.DOMSnapshotRectangle{10, 900, 0, 0}, false},
+		{proto.DOMSnapshotRectangle{10, 900}, false},
+	}
+	for _, tt := range tests {
+		if got := overlaps(tt.rect, viewport); got != tt.want {
+			t.Errorf("overlaps(%v) = %v, want %v", tt.rect, got, tt.want)
+		}
+	}
+}
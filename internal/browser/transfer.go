@@ -0,0 +1,120 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+
+	"github.com/pltanton/lingti-bot/internal/config"
+)
+
+// fileChooserTimeout is how long Upload waits for a clicked element to open
+// a file chooser.
+const fileChooserTimeout = 5 * time.Second
+
+// Upload attaches files to the element identified by ref. A file input gets
+// the files directly; anything else (a styled "upload" button or label) is
+// clicked and the file chooser it opens is filled instead.
+func Upload(page *rod.Page, s *Session, ref int, paths []string) error {
+	el, err := resolveRef(page, s, ref)
+	if err != nil {
+		return err
+	}
+	if v, err := el.Eval(`() => this.tagName === 'INPUT' && this.type === 'file'`); err == nil && v.Value.Bool() {
+		return el.SetFiles(paths)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fileChooserTimeout)
+	defer cancel()
+	setFiles, err := page.Context(ctx).HandleFileDialog()
+	if err != nil {
+		return fmt.Errorf("failed to intercept file chooser: %w", err)
+	}
+	// Never leave the user's file dialogs intercepted
+	defer func() { _ = proto.PageSetInterceptFileChooserDialog{Enabled: false}.Call(page) }()
+
+	// A real mouse click: Chrome only opens file choosers on user gestures
+	if err := el.Context(ctx).Click(proto.InputMouseButtonLeft, 1); err != nil {
+		return fmt.Errorf("failed to click ref %d: %w", ref, err)
+	}
+	if err := setFiles(paths); err != nil {
+		return fmt.Errorf("ref %d is not a file input and did not open a file chooser: %w", ref, err)
+	}
+	return nil
+}
+
+// DownloadDir returns the directory downloads are saved to: dir if given,
+// otherwise browser.download_dir from config, otherwise ~/.lingti/downloads.
+func DownloadDir(dir string) string {
+	if dir == "" {
+		cfg, _ := config.Load()
+		if cfg != nil {
+			dir = cfg.Browser.DownloadDir
+		}
+	}
+	home, _ := os.UserHomeDir()
+	if dir == "" {
+		return filepath.Join(home, ".lingti", "downloads")
+	}
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		dir = filepath.Join(home, dir[1:])
+	}
+	return dir
+}
+
+// Download runs trigger (a click or navigation) and waits up to timeout for
+// the download it starts. The file is saved in dir under the name the site
+// suggested, made unique, and its path is returned.
+func (s *Session) Download(ctx context.Context, dir string, timeout time.Duration, trigger func() error) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create download dir: %w", err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	wait := s.rod.Context(waitCtx).WaitDownload(dir)
+	if err := trigger(); err != nil {
+		cancel()
+		wait() // restores the download behavior
+		return "", err
+	}
+
+	info := wait()
+	if info == nil {
+		return "", fmt.Errorf("no download finished within %s", timeout)
+	}
+
+	path := uniquePath(dir, safeFilename(info.SuggestedFilename))
+	if err := os.Rename(filepath.Join(dir, info.GUID), path); err != nil {
+		return "", fmt.Errorf("failed to save download: %w", err)
+	}
+	return path, nil
+}
+
+// safeFilename reduces a site-suggested file name to a plain base name.
+func safeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == ".." || name == "/" || name == "" {
+		return "download"
+	}
+	return name
+}
+
+// uniquePath returns dir/name, or dir/name (2).ext etc. if that exists.
+func uniquePath(dir, name string) string {
+	path := filepath.Join(dir, name)
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", stem, i, ext))
+	}
+}
@@ -0,0 +1,35 @@
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSafeFilename(t *testing.T) {
+	tests := map[string]string{
+		"report.csv":           "report.csv",
+		"../../etc/passwd":     "passwd",
+		`C:\Users\a\file.xlsx`: "file.xlsx",
+		"":                     "download",
+		"..":                   "download",
+		"/":                    "download",
+	}
+	for in, want := range tests {
+		if got := safeFilename(in); got != want {
+			t.Errorf("safeFilename(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestUniquePath(t *testing.T) {
+	dir := t.TempDir()
+	if got := uniquePath(dir, "a.csv"); got != filepath.Join(dir, "a.csv") {
+		t.Errorf("uniquePath = %q", got)
+	}
+	os.WriteFile(filepath.Join(dir, "a.csv"), nil, 0644)
+	os.WriteFile(filepath.Join(dir, "a (2).csv"), nil, 0644)
+	if got := uniquePath(dir, "a.csv"); got != filepath.Join(dir, "a (3).csv") {
+		t.Errorf("uniquePath = %q, want a (3).csv", got)
+	}
+}
@@ -9,20 +9,25 @@ import (
 )
 
 type Config struct {
-	Transport string                    `yaml:"transport"` // "stdio" or "sse"
-	Port      int                       `yaml:"port"`
-	Security  SecurityConfig            `yaml:"security"`
-	Logging   LoggingConfig             `yaml:"logging"`
-	AI        AIConfig                  `yaml:"ai,omitempty"`
-	Providers map[string]ProviderEntry  `yaml:"providers,omitempty"`
-	Platforms PlatformConfig            `yaml:"platforms,omitempty"`
-	Mode      string                    `yaml:"mode,omitempty"` // "relay" or "router"
-	Relay     RelayConfig               `yaml:"relay,omitempty"`
-	Skills    SkillsConfig              `yaml:"skills,omitempty"`
-	Browser   BrowserConfig             `yaml:"browser,omitempty"`
-	Agents    []AgentEntry              `yaml:"agents,omitempty"`
-	Bindings  []AgentBinding            `yaml:"bindings,omitempty"`
-	BotID     string                    `yaml:"bot_id,omitempty"`
+	Transport    string                   `yaml:"transport"` // "stdio" or "sse"
+	Port         int                      `yaml:"port"`
+	Security     SecurityConfig           `yaml:"security"`
+	Logging      LoggingConfig            `yaml:"logging"`
+	AI           AIConfig                 `yaml:"ai,omitempty"`
+	Providers    map[string]ProviderEntry `yaml:"providers,omitempty"`
+	Platforms    PlatformConfig           `yaml:"platforms,omitempty"`
+	Mode         string                   `yaml:"mode,omitempty"` // "relay" or "router"
+	Relay        RelayConfig              `yaml:"relay,omitempty"`
+	Skills       SkillsConfig             `yaml:"skills,omitempty"`
+	Browser      BrowserConfig            `yaml:"browser,omitempty"`
+	Agents       []AgentEntry             `yaml:"agents,omitempty"`
+	Bindings     []AgentBinding           `yaml:"bindings,omitempty"`
+	Delivery     DeliveryConfig           `yaml:"delivery,omitempty"`
+	Conversation ConversationConfig       `yaml:"conversation,omitempty"`
+	Ingress      IngressConfig            `yaml:"ingress,omitempty"`
+	Speech       SpeechConfig             `yaml:"speech,omitempty"`
+	ACL          ACLConfig                `yaml:"acl,omitempty"`
+	BotID        string                   `yaml:"bot_id,omitempty"`
 }
 
 // ProviderEntry defines a named AI provider configuration.
@@ -45,6 +50,157 @@ type BrowserConfig struct {
 	// instead of launching a new one. The Chrome must be started with
 	// --remote-debugging-port=<port>.
 	CDPURL string `yaml:"cdp_url,omitempty"`
+
+	// Isolation controls how conversations share the browser: "incognito"
+	// (default) gives each conversation its own browser context; "shared" keeps
+	// the browser's cookies and logins but still separates tabs and refs.
+	Isolation string `yaml:"isolation,omitempty"`
+
+	// MaxSessions caps concurrent conversation sessions; the least recently
+	// used one is closed to make room. Default: 8
+	MaxSessions int `yaml:"max_sessions,omitempty"`
+
+	// SessionIdleMinutes closes sessions unused for this long. Default: 30
+	SessionIdleMinutes int `yaml:"session_idle_minutes,omitempty"`
+
+	// Profile is the login profile sessions use unless an agent
+	// (agents[].browser_profile) or browser_start picks another. Profiles
+	// keep cookies and localStorage in ~/.lingti/browser-profiles/.
+	Profile string `yaml:"profile,omitempty"`
+
+	// DownloadDir is where browser_download saves files unless the call
+	// names a directory. Default: ~/.lingti/downloads
+	DownloadDir string `yaml:"download_dir,omitempty"`
+}
+
+// DeliveryConfig controls how responses are delivered to chat platforms.
+type DeliveryConfig struct {
+	// FileThreshold sends a response as a Markdown file attachment when it
+	// would otherwise be split into more than this many messages. Only applies
+	// to platforms that support files. 0 disables the fallback.
+	FileThreshold int `yaml:"file_threshold,omitempty"`
+
+	// Queue keeps outgoing messages in ~/.lingti.db until the platform
+	// accepts them, retrying failed sends.
+	Queue DeliveryQueueConfig `yaml:"queue,omitempty"`
+}
+
+// DeliveryQueueConfig controls the outbound queue. It is on by default.
+type DeliveryQueueConfig struct {
+	RetryPolicy `yaml:",inline"`
+
+	Disabled  bool                   `yaml:"disabled,omitempty"`  // send each message once, without the queue
+	Platforms map[string]RetryPolicy `yaml:"platforms,omitempty"` // per-platform overrides
+}
+
+// RetryPolicy says how often and how fast failed sends are retried.
+// A platform's rate-limit response (e.g. Telegram retry_after, Slack
+// Retry-After) takes precedence over the backoff.
+type RetryPolicy struct {
+	MaxAttempts  int `yaml:"max_attempts,omitempty"`   // attempts before a message becomes a dead letter (default 8)
+	MinDelaySecs int `yaml:"min_delay_secs,omitempty"` // delay after the first failure, doubled after each one (default 2)
+	MaxDelaySecs int `yaml:"max_delay_secs,omitempty"` // longest delay between attempts (default 300)
+}
+
+// ConversationConfig controls how messages within one conversation are handled.
+type ConversationConfig struct {
+	// QueueMode decides what a message does while an earlier one is still
+	// running: "wait" (default) queues it, "interrupt" cancels the running turn.
+	QueueMode string `yaml:"queue_mode,omitempty"`
+
+	// Scope decides which messages share agent history: "user" (default,
+	// each user per channel), "channel", "thread", "user+thread" or
+	// "identity" (each user across all accounts linked with /link).
+	Scope string `yaml:"scope,omitempty"`
+	// Scopes overrides Scope per platform, e.g. {slack: thread}.
+	Scopes map[string]string `yaml:"scopes,omitempty"`
+
+	// ChannelContext is how many recent channel messages to show the agent
+	// alongside a group-chat message, on platforms that expose history
+	// (Slack, Discord, Telegram groups, Matrix). 0 disables it.
+	ChannelContext int `yaml:"channel_context,omitempty"`
+
+	// DebounceMs waits until a user has paused this long before answering
+	// their text messages, so a request typed as several quick messages
+	// becomes one turn. 0 disables it.
+	DebounceMs int `yaml:"debounce_ms,omitempty"`
+
+	// Dedup drops messages a platform delivers more than once.
+	Dedup DedupConfig `yaml:"dedup,omitempty"`
+}
+
+// DedupConfig configures inbound message deduplication. Message IDs are
+// remembered in ~/.lingti.db, so redeliveries are recognised after a restart.
+type DedupConfig struct {
+	Disabled   bool `yaml:"disabled,omitempty"`
+	TTLHours   int  `yaml:"ttl_hours,omitempty"`   // how long IDs are remembered (default 24)
+	MaxEntries int  `yaml:"max_entries,omitempty"` // how many IDs are kept (default 10000)
+}
+
+// SpeechConfig configures voice message transcription and spoken replies.
+type SpeechConfig struct {
+	// Transcribe turns incoming voice messages into text before the agent
+	// sees them (WeCom, Telegram, WhatsApp, Feishu). Empty engine disables it.
+	Transcribe SpeechEngineConfig `yaml:"transcribe,omitempty"`
+
+	// Synthesize speaks replies for agents with a voice reply mode.
+	Synthesize SpeechEngineConfig `yaml:"synthesize,omitempty"`
+
+	// VoiceReply is the default voice reply mode of agents: "voice" answers
+	// voice messages with a voice note as well as text, "always" answers
+	// every message that way. Empty never speaks.
+	VoiceReply string `yaml:"voice_reply,omitempty"`
+}
+
+// SpeechEngineConfig selects a speech engine.
+type SpeechEngineConfig struct {
+	Engine   string `yaml:"engine,omitempty"`   // transcribe: whisper, vosk, openai; synthesize: piper, openai
+	Binary   string `yaml:"binary,omitempty"`   // local engine executable (default: whisper-cli, vosk-transcriber, piper)
+	Model    string `yaml:"model,omitempty"`    // model file/directory for local engines, model name for openai
+	Language string `yaml:"language,omitempty"` // spoken language hint, e.g. "zh"
+	BaseURL  string `yaml:"base_url,omitempty"` // openai: API base URL
+	APIKey   string `yaml:"api_key,omitempty"`  // openai: API key
+	Voice    string `yaml:"voice,omitempty"`    // openai: synthesis voice
+}
+
+// IngressConfig configures the gateway's shared HTTP server for webhook
+// platforms (WhatsApp, LINE, Teams, Google Chat, Zalo, WeCom, webapp). Each
+// is served under /webhooks/<platform>, next to /healthz.
+type IngressConfig struct {
+	// Addr is the listen address, e.g. ":8443". Empty disables the ingress.
+	Addr string `yaml:"addr,omitempty"`
+
+	// MaxBodyBytes limits webhook request bodies. Default: 1 MiB
+	MaxBodyBytes int64 `yaml:"max_body_bytes,omitempty"`
+
+	// DisablePlatformPorts stops platforms from also listening on their own
+	// webhook ports (8084-8088, WeCom's callback_port, the webapp port).
+	// By default both keep working, so existing webhook URLs stay valid.
+	DisablePlatformPorts bool `yaml:"disable_platform_ports,omitempty"`
+
+	TLS IngressTLSConfig `yaml:"tls,omitempty"`
+}
+
+// IngressTLSConfig serves the ingress over HTTPS, either with a certificate
+// file pair or with certificates obtained over ACME.
+type IngressTLSConfig struct {
+	CertFile string     `yaml:"cert_file,omitempty"`
+	KeyFile  string     `yaml:"key_file,omitempty"`
+	ACME     ACMEConfig `yaml:"acme,omitempty"`
+}
+
+// ACMEConfig obtains certificates automatically. The ingress must be
+// reachable on port 443 for the listed domains.
+type ACMEConfig struct {
+	Domains  []string `yaml:"domains,omitempty"`
+	Email    string   `yaml:"email,omitempty"`
+	CacheDir string   `yaml:"cache_dir,omitempty"` // default: ~/.lingti/acme
+
+	// DirectoryURL defaults to Let's Encrypt. Point it at a local test CA
+	// such as Pebble, and CARootsFile at that CA's root certificate, to try
+	// the setup first.
+	DirectoryURL string `yaml:"directory_url,omitempty"`
+	CARootsFile  string `yaml:"ca_roots_file,omitempty"`
 }
 
 type RelayConfig struct {
@@ -96,6 +252,9 @@ type AgentEntry struct {
 	Workspace    string   `yaml:"workspace,omitempty"`    // workspace directory for this agent
 	AllowTools   []string `yaml:"allow_tools,omitempty"`  // whitelist; empty = allow all
 	DenyTools    []string `yaml:"deny_tools,omitempty"`   // blacklist; checked after allowlist
+
+	BrowserProfile string `yaml:"browser_profile,omitempty"` // browser login profile for this agent's chats
+	VoiceReply     string `yaml:"voice_reply,omitempty"`     // overrides speech.voice_reply for this agent
 }
 
 // AgentBindingMatch holds the filter criteria for a binding.
@@ -104,6 +263,51 @@ type AgentBindingMatch struct {
 	Platform  string `yaml:"platform,omitempty"`
 	ChannelID string `yaml:"channel_id,omitempty"`
 	UserID    string `yaml:"user_id,omitempty"`
+	Identity  string `yaml:"identity,omitempty"` // canonical user from /link, e.g. "user-3f9a0c2b1d4e"
+}
+
+// ACLConfig controls who may talk to the bot and which tools they get.
+// Without rules or a default role it is off: everyone may talk to the bot
+// and gets every tool the agent has.
+type ACLConfig struct {
+	// DefaultRole is the role of users no rule matches (default: member).
+	// "none" refuses them, turning the allow rules into an allowlist.
+	DefaultRole string              `yaml:"default_role,omitempty"`
+	Rules       []ACLRule           `yaml:"rules,omitempty"`
+	Groups      map[string][]string `yaml:"groups,omitempty"` // named user groups of "platform:userID" accounts or /link identities
+	Roles       map[string]ACLRole  `yaml:"roles,omitempty"`  // custom roles, or overrides of admin, member and guest
+}
+
+// Enabled reports whether access control is configured.
+func (c ACLConfig) Enabled() bool {
+	return len(c.Rules) > 0 || c.DefaultRole != ""
+}
+
+// ACLRule allows or denies the senders it matches. The most specific
+// matching rule decides, as with bindings; a deny wins a tie.
+type ACLRule struct {
+	Action  string   `yaml:"action"`         // "allow" or "deny"
+	Role    string   `yaml:"role,omitempty"` // role of allowed senders (default: default_role, or member)
+	Comment string   `yaml:"comment,omitempty"`
+	Match   ACLMatch `yaml:"match"`
+}
+
+// ACLMatch holds the filter criteria for an ACL rule.
+// All non-empty fields must match for the rule to apply.
+type ACLMatch struct {
+	Platform  string `yaml:"platform,omitempty"`
+	ChannelID string `yaml:"channel_id,omitempty"`
+	UserID    string `yaml:"user_id,omitempty"`
+	Identity  string `yaml:"identity,omitempty"` // canonical user from /link
+	Group     string `yaml:"group,omitempty"`    // name from acl.groups
+}
+
+// ACLRole limits the tools of the users who hold it. It narrows the
+// agent's own allow_tools/deny_tools; it cannot add tools the agent lacks.
+// Tool names may end in * to match a prefix, e.g. "browser_*".
+type ACLRole struct {
+	AllowTools []string `yaml:"allow_tools,omitempty"` // whitelist; empty = allow all
+	DenyTools  []string `yaml:"deny_tools,omitempty"`  // blacklist; checked after allowlist
 }
 
 // AgentBinding maps a match pattern to an agent ID.
@@ -276,6 +480,7 @@ type PlatformConfig struct {
 	NOSTR      NOSTRConfig      `yaml:"nostr,omitempty"`
 	Zalo       ZaloConfig       `yaml:"zalo,omitempty"`
 	Nextcloud  NextcloudConfig  `yaml:"nextcloud,omitempty"`
+	Email      EmailConfig      `yaml:"email,omitempty"`
 	Webapp     WebappConfig     `yaml:"webapp,omitempty"`
 }
 
@@ -320,6 +525,7 @@ type WhatsAppConfig struct {
 	PhoneNumberID string `yaml:"phone_number_id,omitempty"`
 	AccessToken   string `yaml:"access_token,omitempty"`
 	VerifyToken   string `yaml:"verify_token,omitempty"`
+	AppSecret     string `yaml:"app_secret,omitempty"`
 }
 
 type LINEConfig struct {
@@ -342,6 +548,7 @@ type MatrixConfig struct {
 type GoogleChatConfig struct {
 	ProjectID       string `yaml:"project_id,omitempty"`
 	CredentialsFile string `yaml:"credentials_file,omitempty"`
+	Audience        string `yaml:"audience,omitempty"`
 }
 
 type MattermostConfig struct {
@@ -384,6 +591,19 @@ type NextcloudConfig struct {
 	RoomToken string `yaml:"room_token,omitempty"`
 }
 
+type EmailConfig struct {
+	Address          string   `yaml:"address,omitempty"`
+	Username         string   `yaml:"username,omitempty"` // defaults to address
+	Password         string   `yaml:"password,omitempty"`
+	IMAPServer       string   `yaml:"imap_server,omitempty"` // host:port, e.g. imap.gmail.com:993
+	SMTPServer       string   `yaml:"smtp_server,omitempty"` // host:port, e.g. smtp.gmail.com:465
+	Mailbox          string   `yaml:"mailbox,omitempty"`     // default INBOX
+	PollIntervalSecs int      `yaml:"poll_interval_secs,omitempty"`
+	AllowedSenders   []string `yaml:"allowed_senders,omitempty"`  // addresses or @domains; "*" answers anyone
+	AuthServID       string   `yaml:"auth_serv_id,omitempty"`     // server name in Authentication-Results, e.g. mx.google.com
+	AllowUnverified  bool     `yaml:"allow_unverified,omitempty"` // answer senders not verified by DMARC or DKIM
+}
+
 type WebappConfig struct {
 	Port  int    `yaml:"port,omitempty"`
 	Token string `yaml:"token,omitempty"`
@@ -15,9 +15,11 @@ type Job struct {
 	Arguments map[string]any `json:"arguments,omitempty"` // Tool arguments
 	Message   string         `json:"message,omitempty"`   // Direct message to send (no tool execution)
 	Prompt    string         `json:"prompt,omitempty"`    // AI prompt to execute (full conversation with tools)
+	Macro     string         `json:"macro,omitempty"`     // Browser macro to replay; Prompt is then the fallback if replay fails
 	Platform  string         `json:"platform,omitempty"`  // Target platform ("slack", "wecom", etc.)
 	ChannelID string         `json:"channel_id,omitempty"` // Target channel/user to send to
 	UserID    string         `json:"user_id,omitempty"`   // User who created the job
+	Scope     string         `json:"scope,omitempty"`     // Conversation scope of the chat it was created in
 	Enabled   bool                   `json:"enabled"`             // Whether job is active
 	CreatedAt time.Time              `json:"created_at"`          // Job creation timestamp
 	LastRun   *time.Time             `json:"last_run,omitempty"`  // Last execution timestamp
@@ -36,9 +38,11 @@ func (j *Job) Clone() *Job {
 		Tool:      j.Tool,
 		Message:   j.Message,
 		Prompt:    j.Prompt,
+		Macro:     j.Macro,
 		Platform:  j.Platform,
 		ChannelID: j.ChannelID,
 		UserID:    j.UserID,
+		Scope:     j.Scope,
 		Enabled:   j.Enabled,
 		CreatedAt: j.CreatedAt,
 		LastError: j.LastError,
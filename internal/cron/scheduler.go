@@ -18,9 +18,17 @@ type ToolExecutor interface {
 	ExecuteTool(ctx context.Context, toolName string, arguments map[string]any) (any, error)
 }
 
-// PromptExecutor interface for running full AI conversations
+// PromptExecutor interface for running full AI conversations. scope is the
+// conversation scope the job was created under (Job.Scope), so the run
+// continues the same conversation.
 type PromptExecutor interface {
-	ExecutePrompt(ctx context.Context, platform, channelID, userID, prompt string) (string, error)
+	ExecutePrompt(ctx context.Context, platform, channelID, userID, scope, prompt string) (string, error)
+}
+
+// MacroExecutor replays recorded browser macros without the AI. A
+// ToolExecutor may implement it to support macro jobs.
+type MacroExecutor interface {
+	ExecuteMacro(ctx context.Context, platform, channelID, userID, scope, name string) (string, error)
 }
 
 // ChatNotifier interface for sending messages to chat
@@ -112,7 +120,7 @@ func (s *Scheduler) AddJob(name, schedule, tool string, arguments map[string]any
 }
 
 // AddJobWithMessage adds a new message-based job that sends text to a chat user
-func (s *Scheduler) AddJobWithMessage(name, schedule, message, platform, channelID, userID string) (*Job, error) {
+func (s *Scheduler) AddJobWithMessage(name, schedule, message, platform, channelID, userID, scope string) (*Job, error) {
 	return s.addJob(&Job{
 		Name:      name,
 		Schedule:  schedule,
@@ -120,18 +128,36 @@ func (s *Scheduler) AddJobWithMessage(name, schedule, message, platform, channel
 		Platform:  platform,
 		ChannelID: channelID,
 		UserID:    userID,
+		Scope:     scope,
 	})
 }
 
 // AddJobWithPrompt adds a new prompt-based job that runs a full AI conversation
-func (s *Scheduler) AddJobWithPrompt(name, schedule, prompt, platform, channelID, userID string) (*Job, error) {
+func (s *Scheduler) AddJobWithPrompt(name, schedule, prompt, platform, channelID, userID, scope string) (*Job, error) {
+	return s.addJob(&Job{
+		Name:      name,
+		Schedule:  schedule,
+		Prompt:    prompt,
+		Platform:  platform,
+		ChannelID: channelID,
+		UserID:    userID,
+		Scope:     scope,
+	})
+}
+
+// AddJobWithMacro adds a job that replays a recorded browser macro. If the
+// replay fails, prompt (or a generic instruction when empty) is run as a full
+// AI conversation to finish the task from the failed step.
+func (s *Scheduler) AddJobWithMacro(name, schedule, macro, prompt, platform, channelID, userID, scope string) (*Job, error) {
 	return s.addJob(&Job{
 		Name:      name,
 		Schedule:  schedule,
+		Macro:     macro,
 		Prompt:    prompt,
 		Platform:  platform,
 		ChannelID: channelID,
 		UserID:    userID,
+		Scope:     scope,
 	})
 }
 
@@ -328,6 +354,12 @@ func (s *Scheduler) executeJob(job *Job) {
 		return
 	}
 
+	// Macro-based job: replay without the AI, fall back to it on failure
+	if job.Macro != "" {
+		s.executeMacroJob(job, now)
+		return
+	}
+
 	// Prompt-based job: run full AI conversation
 	if job.Prompt != "" {
 		log.Printf("[CRON] Running AI prompt for job: %s (%s)", job.ID, job.Name)
@@ -350,7 +382,7 @@ func (s *Scheduler) executeJob(job *Job) {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 		defer cancel()
 
-		result, err := s.promptExecutor.ExecutePrompt(ctx, job.Platform, job.ChannelID, job.UserID, job.Prompt)
+		result, err := s.promptExecutor.ExecutePrompt(ctx, job.Platform, job.ChannelID, job.UserID, job.Scope, job.Prompt)
 		if err != nil {
 			s.mu.Lock()
 			job.LastError = err.Error()
@@ -421,6 +453,68 @@ func (s *Scheduler) executeJob(job *Job) {
 	}
 }
 
+// executeMacroJob replays a job's browser macro. When a step fails and a
+// prompt executor is available, the AI takes over with the failure details so
+// it can finish the remaining steps.
+func (s *Scheduler) executeMacroJob(job *Job, now time.Time) {
+	log.Printf("[CRON] Replaying macro for job: %s (%s) - macro: %s", job.ID, job.Name, job.Macro)
+
+	s.mu.Lock()
+	job.LastRun = &now
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var result string
+	err := fmt.Errorf("macro executor not available")
+	if me, ok := s.toolExecutor.(MacroExecutor); ok {
+		result, err = me.ExecuteMacro(ctx, job.Platform, job.ChannelID, job.UserID, job.Scope, job.Macro)
+	}
+
+	lastError := ""
+	if err != nil {
+		log.Printf("[CRON] Job macro failed: %s (%s) - error: %v", job.ID, job.Name, err)
+		lastError = err.Error()
+		if s.promptExecutor != nil {
+			log.Printf("[CRON] Falling back to AI for job: %s (%s)", job.ID, job.Name)
+			result, err = s.promptExecutor.ExecutePrompt(ctx, job.Platform, job.ChannelID, job.UserID, job.Scope, macroFallbackPrompt(job, err))
+			if err == nil {
+				lastError = "macro replay failed, completed by AI: " + lastError
+			} else {
+				lastError = err.Error()
+			}
+		}
+	}
+
+	s.mu.Lock()
+	job.LastError = lastError
+	s.mu.Unlock()
+
+	if s.chatNotifier != nil && job.Platform != "" && job.ChannelID != "" {
+		if err != nil {
+			s.chatNotifier.NotifyChatUser(job.Platform, job.ChannelID, job.UserID,
+				fmt.Sprintf("⚠️ Scheduled macro '%s' failed: %v", job.Name, err))
+		} else {
+			s.chatNotifier.NotifyChatUser(job.Platform, job.ChannelID, job.UserID, result)
+		}
+	}
+
+	if err := s.store.SaveJob(job); err != nil {
+		log.Printf("[CRON] Failed to save job: %v", err)
+	}
+}
+
+// macroFallbackPrompt builds the AI prompt run when a macro replay fails.
+func macroFallbackPrompt(job *Job, replayErr error) string {
+	task := job.Prompt
+	if task == "" {
+		task = fmt.Sprintf("Finish the scheduled browser task %q.", job.Name)
+	}
+	return fmt.Sprintf("%s\n\nThe recorded browser macro %q was replayed first and stopped with:\n%v\n\nThe browser is still on the page where it stopped. Continue from the failed step instead of starting over.",
+		task, job.Macro, replayErr)
+}
+
 // countEnabled returns the number of enabled jobs
 func (s *Scheduler) countEnabled() int {
 	count := 0
@@ -1,6 +1,12 @@
 package cron
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
 
 func TestNormalizeCron(t *testing.T) {
 	tests := []struct {
@@ -18,3 +24,71 @@ func TestNormalizeCron(t *testing.T) {
 		}
 	}
 }
+
+// fakeExecutor records the macros and prompts a scheduler runs.
+type fakeExecutor struct {
+	macroErr error
+	macros   []string
+	prompts  []string
+}
+
+func (f *fakeExecutor) ExecuteTool(context.Context, string, map[string]any) (any, error) {
+	return nil, nil
+}
+
+func (f *fakeExecutor) ExecuteMacro(_ context.Context, _, _, _, _ string, name string) (string, error) {
+	f.macros = append(f.macros, name)
+	return "replayed " + name, f.macroErr
+}
+
+func (f *fakeExecutor) ExecutePrompt(_ context.Context, _, _, _, _ string, prompt string) (string, error) {
+	f.prompts = append(f.prompts, prompt)
+	return "done by AI", nil
+}
+
+func TestExecuteJob_Macro(t *testing.T) {
+	t.Setenv("HOME", t.TempDir()) // keep NewStore away from a real ~/.lingti/crons.json
+
+	tests := []struct {
+		name        string
+		macroErr    error
+		wantPrompts int
+		wantError   string
+	}{
+		{"replay succeeds", nil, 0, ""},
+		{"replay fails", errors.New("step 3: element not found"), 1, "completed by AI"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewStore(filepath.Join(t.TempDir(), "test.db"))
+			if err != nil {
+				t.Fatalf("NewStore: %v", err)
+			}
+			defer store.Close()
+
+			exec := &fakeExecutor{macroErr: tt.macroErr}
+			s := NewScheduler(store, exec, exec, nil)
+			job := &Job{ID: "j", Name: "checkin", Macro: "daily-checkin", Prompt: "check in on the forum"}
+			s.executeJob(job)
+
+			if len(exec.macros) != 1 || exec.macros[0] != "daily-checkin" {
+				t.Errorf("macros run = %v", exec.macros)
+			}
+			if len(exec.prompts) != tt.wantPrompts {
+				t.Fatalf("prompts run = %v, want %d", exec.prompts, tt.wantPrompts)
+			}
+			if tt.wantPrompts > 0 {
+				p := exec.prompts[0]
+				if !strings.HasPrefix(p, "check in on the forum") || !strings.Contains(p, "element not found") {
+					t.Errorf("fallback prompt lacks the task or the failure:\n%s", p)
+				}
+			}
+			if !strings.Contains(job.LastError, tt.wantError) || (tt.wantError == "" && job.LastError != "") {
+				t.Errorf("LastError = %q, want %q", job.LastError, tt.wantError)
+			}
+			if job.LastRun == nil {
+				t.Error("LastRun not set")
+			}
+		})
+	}
+}
@@ -27,7 +27,9 @@ func NewStore(path string) (*Store, error) {
 		return nil, fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite", path)
+	// Other stores write to the same file; wait for their locks instead of
+	// failing with SQLITE_BUSY
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -62,16 +64,26 @@ func (s *Store) init() error {
 			arguments  TEXT,
 			message    TEXT,
 			prompt     TEXT,
+			macro      TEXT,
 			platform   TEXT,
 			channel_id TEXT,
 			user_id    TEXT,
+			scope      TEXT,
 			enabled    INTEGER NOT NULL DEFAULT 1,
 			created_at TEXT NOT NULL,
 			last_run   TEXT,
 			last_error TEXT
 		)
 	`)
-	return err
+	if err != nil {
+		return err
+	}
+
+	// Columns added after the table was first released; the ALTER fails
+	// harmlessly when the column already exists.
+	_, _ = s.db.Exec(`ALTER TABLE jobs ADD COLUMN macro TEXT`)
+	_, _ = s.db.Exec(`ALTER TABLE jobs ADD COLUMN scope TEXT`)
+	return nil
 }
 
 // migrateFromJSON imports jobs from the legacy crons.json if it exists
@@ -120,8 +132,8 @@ func (s *Store) Load() ([]*Job, error) {
 	defer s.mu.RUnlock()
 
 	rows, err := s.db.Query(`
-		SELECT id, name, schedule, tool, arguments, message, prompt,
-		       platform, channel_id, user_id, enabled, created_at, last_run, last_error
+		SELECT id, name, schedule, tool, arguments, message, prompt, macro,
+		       platform, channel_id, user_id, scope, enabled, created_at, last_run, last_error
 		FROM jobs
 	`)
 	if err != nil {
@@ -174,18 +186,20 @@ func (s *Store) SaveJob(job *Job) error {
 	}
 
 	_, err = s.db.Exec(`
-		INSERT INTO jobs (id, name, schedule, tool, arguments, message, prompt,
-		                  platform, channel_id, user_id, enabled, created_at, last_run, last_error)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO jobs (id, name, schedule, tool, arguments, message, prompt, macro,
+		                  platform, channel_id, user_id, scope, enabled, created_at, last_run, last_error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			name=excluded.name, schedule=excluded.schedule, tool=excluded.tool,
 			arguments=excluded.arguments, message=excluded.message, prompt=excluded.prompt,
+			macro=excluded.macro,
 			platform=excluded.platform, channel_id=excluded.channel_id, user_id=excluded.user_id,
+			scope=excluded.scope,
 			enabled=excluded.enabled, created_at=excluded.created_at,
 			last_run=excluded.last_run, last_error=excluded.last_error
 	`,
-		job.ID, job.Name, job.Schedule, job.Tool, string(argsJSON), job.Message, job.Prompt,
-		job.Platform, job.ChannelID, job.UserID, enabled, job.CreatedAt.Format(time.RFC3339),
+		job.ID, job.Name, job.Schedule, job.Tool, string(argsJSON), job.Message, job.Prompt, job.Macro,
+		job.Platform, job.ChannelID, job.UserID, job.Scope, enabled, job.CreatedAt.Format(time.RFC3339),
 		lastRun, lastError,
 	)
 	return err
@@ -227,9 +241,11 @@ func scanJob(s scanner) (*Job, error) {
 		tool      sql.NullString
 		message   sql.NullString
 		prompt    sql.NullString
+		macro     sql.NullString
 		platform  sql.NullString
 		channelID sql.NullString
 		userID    sql.NullString
+		scope     sql.NullString
 		enabled   int
 		createdAt string
 		lastRun   sql.NullString
@@ -237,8 +253,8 @@ func scanJob(s scanner) (*Job, error) {
 	)
 
 	err := s.Scan(
-		&job.ID, &job.Name, &job.Schedule, &tool, &argsJSON, &message, &prompt,
-		&platform, &channelID, &userID, &enabled, &createdAt, &lastRun, &lastError,
+		&job.ID, &job.Name, &job.Schedule, &tool, &argsJSON, &message, &prompt, &macro,
+		&platform, &channelID, &userID, &scope, &enabled, &createdAt, &lastRun, &lastError,
 	)
 	if err != nil {
 		return nil, err
@@ -247,9 +263,11 @@ func scanJob(s scanner) (*Job, error) {
 	job.Tool = tool.String
 	job.Message = message.String
 	job.Prompt = prompt.String
+	job.Macro = macro.String
 	job.Platform = platform.String
 	job.ChannelID = channelID.String
 	job.UserID = userID.String
+	job.Scope = scope.String
 	job.Enabled = enabled != 0
 	job.LastError = lastError.String
 
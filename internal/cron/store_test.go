@@ -1,6 +1,7 @@
 package cron
 
 import (
+	"database/sql"
 	"path/filepath"
 	"testing"
 	"time"
@@ -68,3 +69,38 @@ func TestStore_Delete(t *testing.T) {
 		t.Errorf("expected 0 jobs after delete, got %d", len(jobs))
 	}
 }
+
+func TestStore_ColumnMigration(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	// A database created before jobs had macro and scope columns
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	if _, err := db.Exec(`CREATE TABLE jobs (
+		id TEXT PRIMARY KEY, name TEXT NOT NULL, schedule TEXT NOT NULL, tool TEXT, arguments TEXT,
+		message TEXT, prompt TEXT, platform TEXT, channel_id TEXT, user_id TEXT,
+		enabled INTEGER NOT NULL DEFAULT 1, created_at TEXT NOT NULL, last_run TEXT, last_error TEXT)`); err != nil {
+		t.Fatalf("create legacy table: %v", err)
+	}
+	db.Close()
+
+	store, err := NewStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	defer store.Close()
+
+	job := &Job{ID: "m-1", Name: "checkin", Schedule: "0 0 9 * * *", Macro: "daily-checkin", Prompt: "check in", Scope: "channel", CreatedAt: time.Now()}
+	if err := store.SaveJob(job); err != nil {
+		t.Fatalf("SaveJob: %v", err)
+	}
+	jobs, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].Macro != "daily-checkin" || jobs[0].Prompt != "check in" || jobs[0].Scope != "channel" {
+		t.Errorf("unexpected jobs: %+v", jobs)
+	}
+}
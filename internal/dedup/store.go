@@ -0,0 +1,171 @@
+// Package dedup remembers the IDs of incoming chat messages, so a message a
+// platform delivers twice is only handled once. WeCom, Feishu and DingTalk
+// redeliver callbacks that were not answered in time, and the relay can
+// replay messages after it reconnects.
+//
+// The IDs are kept in SQLite, so redeliveries are recognised across
+// restarts, and are forgotten once they are older than the TTL or the store
+// holds more than its limit.
+package dedup
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Default limits.
+const (
+	DefaultTTL        = 24 * time.Hour
+	DefaultMaxEntries = 10000
+
+	// pruneEvery is how many new IDs are recorded between prunes.
+	pruneEvery = 100
+)
+
+// Store is a SQLite-backed set of recently seen message IDs.
+type Store struct {
+	db  *sql.DB
+	mu  sync.Mutex
+	now func() time.Time
+
+	ttl        time.Duration
+	maxEntries int
+	added      int // IDs recorded since the last prune
+}
+
+var (
+	defaultStore    *Store
+	defaultStoreErr error
+	defaultOnce     sync.Once
+)
+
+// Default returns the store in ~/.lingti.db, shared with the cron scheduler,
+// opening it on first use.
+func Default() (*Store, error) {
+	defaultOnce.Do(func() {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			defaultStoreErr = fmt.Errorf("failed to find home directory: %w", err)
+			return
+		}
+		defaultStore, defaultStoreErr = NewStore(filepath.Join(home, ".lingti.db"))
+	})
+	return defaultStore, defaultStoreErr
+}
+
+// NewStore opens (or creates) a store at the given path, with the default
+// limits.
+func NewStore(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Other stores write to the same file; wait for their locks instead of
+	// failing with SQLITE_BUSY
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to set WAL mode: %w", err)
+	}
+
+	s := &Store{db: db, now: time.Now, ttl: DefaultTTL, maxEntries: DefaultMaxEntries}
+	if err := s.init(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	return s, nil
+}
+
+// init creates the tables if they don't exist
+func (s *Store) init() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS inbound_seen (
+			platform   TEXT NOT NULL,
+			channel_id TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			seen_at    INTEGER NOT NULL,
+			PRIMARY KEY (platform, channel_id, message_id)
+		);
+		CREATE INDEX IF NOT EXISTS inbound_seen_at ON inbound_seen (seen_at);
+	`)
+	return err
+}
+
+// Close closes the database connection
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// SetLimits sets how long IDs are remembered and how many are kept. Zero
+// values keep the defaults.
+func (s *Store) SetLimits(ttl time.Duration, maxEntries int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ttl > 0 {
+		s.ttl = ttl
+	}
+	if maxEntries > 0 {
+		s.maxEntries = maxEntries
+	}
+}
+
+// Seen records a message ID and reports whether it was already recorded
+// within the TTL. Message IDs are only unique per chat on some platforms,
+// so the channel is part of the key.
+func (s *Store) Seen(platform, channelID, messageID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	res, err := s.db.Exec(`INSERT INTO inbound_seen (platform, channel_id, message_id, seen_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (platform, channel_id, message_id) DO UPDATE SET seen_at = excluded.seen_at
+		WHERE seen_at < ?`,
+		platform, channelID, messageID, now.UnixNano(), now.Add(-s.ttl).UnixNano())
+	if err != nil {
+		return false, fmt.Errorf("failed to record message ID: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return true, nil
+	}
+
+	s.added++
+	if s.added >= pruneEvery {
+		s.added = 0
+		if _, err := s.prune(); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// Prune forgets IDs older than the TTL and the oldest IDs beyond the limit.
+// It returns how many were removed.
+func (s *Store) Prune() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.prune()
+}
+
+func (s *Store) prune() (int, error) {
+	expired, err := s.db.Exec("DELETE FROM inbound_seen WHERE seen_at < ?", s.now().Add(-s.ttl).UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune message IDs: %w", err)
+	}
+	excess, err := s.db.Exec(`DELETE FROM inbound_seen WHERE rowid NOT IN
+		(SELECT rowid FROM inbound_seen ORDER BY seen_at DESC LIMIT ?)`, s.maxEntries)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune message IDs: %w", err)
+	}
+	n1, _ := expired.RowsAffected()
+	n2, _ := excess.RowsAffected()
+	return int(n1 + n2), nil
+}
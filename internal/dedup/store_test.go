@@ -0,0 +1,74 @@
+package dedup
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) (*Store, *time.Time) {
+	t.Helper()
+	s, err := NewStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestStore_Seen(t *testing.T) {
+	s, now := newTestStore(t)
+	s.SetLimits(time.Hour, 0)
+
+	tests := []struct {
+		name                       string
+		advance                    time.Duration
+		platform, channel, message string
+		want                       bool
+	}{
+		{"first delivery", 0, "wecom", "c1", "m1", false},
+		{"redelivery", time.Minute, "wecom", "c1", "m1", true},
+		{"same ID in another chat", 0, "wecom", "c2", "m1", false},
+		{"same ID on another platform", 0, "feishu", "c1", "m1", false},
+		{"after the TTL", 2 * time.Hour, "wecom", "c1", "m1", false},
+		{"renewed after the TTL", time.Minute, "wecom", "c1", "m1", true},
+	}
+	for _, tt := range tests {
+		*now = now.Add(tt.advance)
+		got, err := s.Seen(tt.platform, tt.channel, tt.message)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: Seen() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStore_Prune(t *testing.T) {
+	s, now := newTestStore(t)
+	s.SetLimits(time.Hour, 3)
+
+	for i := range 5 {
+		*now = now.Add(time.Second)
+		s.Seen("dingtalk", "c1", fmt.Sprintf("m%d", i))
+	}
+	if n, err := s.Prune(); n != 2 || err != nil {
+		t.Fatalf("Prune() = %d, %v; want 2 over the limit", n, err)
+	}
+	// The newest IDs are kept.
+	if dup, _ := s.Seen("dingtalk", "c1", "m4"); !dup {
+		t.Error("newest ID was pruned")
+	}
+	if dup, _ := s.Seen("dingtalk", "c1", "m0"); dup {
+		t.Error("oldest ID was kept")
+	}
+
+	*now = now.Add(2 * time.Hour)
+	if n, err := s.Prune(); n != 4 || err != nil {
+		t.Errorf("Prune() after the TTL = %d, %v; want 4", n, err)
+	}
+}
@@ -0,0 +1,408 @@
+// Package identity links chat accounts on different platforms to one
+// canonical user, so conversation memory, cron jobs and agent bindings can
+// follow a person instead of a single account.
+//
+// Accounts are linked with a one-time code: /link on one platform issues
+// it, /link <code> on another confirms it. An account that was never linked
+// is its own identity, "platform:userID".
+package identity
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Link code settings. Failed attempts are limited per confirming account,
+// across all accounts, and per live code: every wrong guess counts against
+// each code that could have been guessed, and a code is burned after
+// maxCodeMisses, so no code can be guessed within its lifetime.
+const (
+	CodeTTL           = 10 * time.Minute
+	codeLength        = 8
+	codeAlphabet      = "ABCDEFGHJKMNPQRSTUVWXYZ23456789" // no 0/O, 1/I/L
+	maxAttempts       = 5                                 // per confirming account
+	maxGlobalAttempts = 30                                // across all accounts
+	maxCodeMisses     = 10                                // per live code
+)
+
+var (
+	ErrInvalidCode     = errors.New("link code is invalid or has expired")
+	ErrSameAccount     = errors.New("link code was issued to this account")
+	ErrTooManyAttempts = errors.New("too many failed link attempts")
+)
+
+// Resolver maps a platform account to its canonical user ID.
+type Resolver interface {
+	Resolve(platform, userID string) string
+}
+
+// Account is one chat account.
+type Account struct {
+	Platform string
+	UserID   string
+}
+
+// String returns "platform:userID", which is also the identity of an
+// account that is not linked.
+func (a Account) String() string {
+	return a.Platform + ":" + a.UserID
+}
+
+// Store is a SQLite-backed identity store.
+type Store struct {
+	db  *sql.DB
+	mu  sync.Mutex
+	now func() time.Time
+
+	// failed link attempts per account, and in total, since the window
+	// started
+	attempts map[Account]*attempt
+	global   attempt
+}
+
+type attempt struct {
+	count int
+	since time.Time
+}
+
+var (
+	defaultStore    *Store
+	defaultStoreErr error
+	defaultOnce     sync.Once
+)
+
+// Default returns the store in ~/.lingti.db, shared with the cron scheduler,
+// opening it on first use.
+func Default() (*Store, error) {
+	defaultOnce.Do(func() {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			defaultStoreErr = fmt.Errorf("failed to find home directory: %w", err)
+			return
+		}
+		defaultStore, defaultStoreErr = NewStore(filepath.Join(home, ".lingti.db"))
+	})
+	return defaultStore, defaultStoreErr
+}
+
+// NewStore opens (or creates) an identity store at the given path.
+func NewStore(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Other stores write to the same file; wait for their locks instead of
+	// failing with SQLITE_BUSY
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to set WAL mode: %w", err)
+	}
+
+	s := &Store{db: db, now: time.Now, attempts: make(map[Account]*attempt)}
+	if err := s.init(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	return s, nil
+}
+
+// init creates the tables if they don't exist
+func (s *Store) init() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS identity_links (
+			platform  TEXT NOT NULL,
+			user_id   TEXT NOT NULL,
+			identity  TEXT NOT NULL,
+			linked_at TEXT NOT NULL,
+			PRIMARY KEY (platform, user_id)
+		);
+		CREATE INDEX IF NOT EXISTS identity_links_identity ON identity_links (identity);
+		CREATE TABLE IF NOT EXISTS identity_codes (
+			code       TEXT PRIMARY KEY,
+			platform   TEXT NOT NULL,
+			user_id    TEXT NOT NULL,
+			expires_at TEXT NOT NULL,
+			misses     INTEGER NOT NULL DEFAULT 0
+		);
+	`)
+	if err != nil {
+		return err
+	}
+	// Migration: add misses column if missing
+	_, _ = s.db.Exec(`ALTER TABLE identity_codes ADD COLUMN misses INTEGER NOT NULL DEFAULT 0`)
+	return nil
+}
+
+// Close closes the database connection
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Resolve returns the canonical user ID of an account: the identity it is
+// linked to, or "platform:userID" if it is not linked.
+func (s *Store) Resolve(platform, userID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, err := s.lookup(s.db, Account{platform, userID})
+	if err != nil || id == "" {
+		return Account{platform, userID}.String()
+	}
+	return id
+}
+
+// Accounts lists the accounts linked to an identity, in the order they were
+// linked. An identity that is a single unlinked account lists nothing.
+func (s *Store) Accounts(identity string) ([]Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.Query("SELECT platform, user_id FROM identity_links WHERE identity = ? ORDER BY linked_at, rowid", identity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []Account
+	for rows.Next() {
+		var a Account
+		if err := rows.Scan(&a.Platform, &a.UserID); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
+
+// NewCode issues a one-time link code for an account, replacing any code it
+// was issued before. The code is valid for CodeTTL.
+func (s *Store) NewCode(platform, userID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if _, err := s.db.Exec("DELETE FROM identity_codes WHERE expires_at < ? OR (platform = ? AND user_id = ?)",
+		now.Format(time.RFC3339), platform, userID); err != nil {
+		return "", err
+	}
+	for {
+		code, err := newCode()
+		if err != nil {
+			return "", err
+		}
+		res, err := s.db.Exec(`
+			INSERT INTO identity_codes (code, platform, user_id, expires_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT(code) DO NOTHING
+		`, code, platform, userID, now.Add(CodeTTL).Format(time.RFC3339))
+		if err != nil {
+			return "", err
+		}
+		if n, _ := res.RowsAffected(); n == 1 {
+			return code, nil
+		}
+	}
+}
+
+// Link confirms a code from another account: the confirming account, along
+// with everything already linked to it, joins the identity of the account
+// the code was issued to. If that account is not linked yet, it joins the
+// confirming account's identity instead, so an existing identity (and any
+// ACL rule naming it) is kept. It returns the identity. Codes are not case
+// sensitive.
+func (s *Store) Link(code, platform, userID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	code = strings.ToUpper(strings.TrimSpace(code))
+	confirmer := Account{platform, userID}
+	now := s.now()
+	if a := s.attempts[confirmer]; a != nil && now.Sub(a.since) < CodeTTL && a.count >= maxAttempts {
+		return "", ErrTooManyAttempts
+	}
+	if now.Sub(s.global.since) < CodeTTL && s.global.count >= maxGlobalAttempts {
+		return "", ErrTooManyAttempts
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var issuer Account
+	var expiresAt string
+	err = tx.QueryRow("SELECT platform, user_id, expires_at FROM identity_codes WHERE code = ?", code).
+		Scan(&issuer.Platform, &issuer.UserID, &expiresAt)
+	if err == sql.ErrNoRows {
+		return "", s.failedAttempt(tx, confirmer, now)
+	}
+	if err != nil {
+		return "", err
+	}
+	if exp, _ := time.Parse(time.RFC3339, expiresAt); !now.Before(exp) {
+		return "", s.failedAttempt(tx, confirmer, now)
+	}
+	if issuer == confirmer {
+		return "", ErrSameAccount
+	}
+	if _, err := tx.Exec("DELETE FROM identity_codes WHERE code = ?", code); err != nil {
+		return "", err
+	}
+
+	linkedAt := now.Format(time.RFC3339Nano)
+	identity, err := s.lookup(tx, issuer)
+	if err != nil {
+		return "", err
+	}
+	if identity == "" {
+		if identity, err = s.lookup(tx, confirmer); err != nil {
+			return "", err
+		}
+		if identity == "" {
+			if identity, err = newIdentity(); err != nil {
+				return "", err
+			}
+		}
+		if _, err := tx.Exec("INSERT INTO identity_links (platform, user_id, identity, linked_at) VALUES (?, ?, ?, ?)",
+			issuer.Platform, issuer.UserID, identity, linkedAt); err != nil {
+			return "", err
+		}
+	}
+
+	previous, err := s.lookup(tx, confirmer)
+	if err != nil {
+		return "", err
+	}
+	switch previous {
+	case identity:
+		// already linked
+	case "":
+		if _, err := tx.Exec("INSERT INTO identity_links (platform, user_id, identity, linked_at) VALUES (?, ?, ?, ?)",
+			confirmer.Platform, confirmer.UserID, identity, linkedAt); err != nil {
+			return "", err
+		}
+	default:
+		if _, err := tx.Exec("UPDATE identity_links SET identity = ? WHERE identity = ?", identity, previous); err != nil {
+			return "", err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	delete(s.attempts, confirmer)
+	return identity, nil
+}
+
+// Unlink detaches an account from its identity, making it its own identity
+// again. It reports whether the account was linked.
+func (s *Store) Unlink(platform, userID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	identity, err := s.lookup(tx, Account{platform, userID})
+	if err != nil || identity == "" {
+		return false, err
+	}
+	if _, err := tx.Exec("DELETE FROM identity_links WHERE platform = ? AND user_id = ?", platform, userID); err != nil {
+		return false, err
+	}
+	// An identity left with a single account is just that account again.
+	if _, err := tx.Exec(`
+		DELETE FROM identity_links WHERE identity = ?1
+		AND (SELECT COUNT(*) FROM identity_links WHERE identity = ?1) < 2
+	`, identity); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+type querier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// lookup returns the identity an account is linked to, or "".
+func (s *Store) lookup(q querier, a Account) (string, error) {
+	var identity string
+	err := q.QueryRow("SELECT identity FROM identity_links WHERE platform = ? AND user_id = ?", a.Platform, a.UserID).Scan(&identity)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return identity, err
+}
+
+// failedAttempt records a wrong or expired code against the confirming
+// account, the global window and every live code, burning the codes that
+// have had too many misses. It returns ErrInvalidCode unless recording fails.
+func (s *Store) failedAttempt(tx *sql.Tx, a Account, now time.Time) error {
+	for acct, at := range s.attempts {
+		if now.Sub(at.since) >= CodeTTL {
+			delete(s.attempts, acct)
+		}
+	}
+	at := s.attempts[a]
+	if at == nil || now.Sub(at.since) >= CodeTTL {
+		at = &attempt{since: now}
+		s.attempts[a] = at
+	}
+	at.count++
+	if now.Sub(s.global.since) >= CodeTTL {
+		s.global = attempt{since: now}
+	}
+	s.global.count++
+
+	if _, err := tx.Exec("UPDATE identity_codes SET misses = misses + 1"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM identity_codes WHERE misses >= ? OR expires_at < ?",
+		maxCodeMisses, now.Format(time.RFC3339)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return ErrInvalidCode
+}
+
+// newCode returns a random link code.
+func newCode() (string, error) {
+	b := make([]byte, codeLength)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(codeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = codeAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// newIdentity returns a fresh canonical user ID.
+func newIdentity() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "user-" + hex.EncodeToString(b), nil
+}
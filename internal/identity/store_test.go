@@ -0,0 +1,196 @@
+package identity
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	return store, path
+}
+
+func TestStore_LinkAcrossPlatforms(t *testing.T) {
+	store, path := openTestStore(t)
+
+	if got := store.Resolve("slack", "U1"); got != "slack:U1" {
+		t.Fatalf("unlinked Resolve = %q, want slack:U1", got)
+	}
+
+	code, err := store.NewCode("slack", "U1")
+	if err != nil {
+		t.Fatalf("NewCode: %v", err)
+	}
+	if len(code) != codeLength {
+		t.Errorf("code = %q, want %d characters", code, codeLength)
+	}
+	if _, err := store.Link(code, "slack", "U1"); !errors.Is(err, ErrSameAccount) {
+		t.Errorf("Link from the issuing account: err = %v, want ErrSameAccount", err)
+	}
+	id, err := store.Link(code, "feishu", "ou_1")
+	if err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+	if _, err := store.Link(code, "webapp", "w1"); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("reusing a code: err = %v, want ErrInvalidCode", err)
+	}
+
+	// A third account joins the same identity.
+	code, _ = store.NewCode("feishu", "ou_1")
+	if got, err := store.Link(code, "webapp", "w1"); err != nil || got != id {
+		t.Fatalf("second Link = %q, %v; want %q", got, err, id)
+	}
+	store.Close()
+
+	store, err = NewStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer store.Close()
+	for _, a := range []Account{{"slack", "U1"}, {"feishu", "ou_1"}, {"webapp", "w1"}} {
+		if got := store.Resolve(a.Platform, a.UserID); got != id {
+			t.Errorf("Resolve(%s) = %q, want %q", a, got, id)
+		}
+	}
+	accounts, _ := store.Accounts(id)
+	if len(accounts) != 3 || accounts[0] != (Account{"slack", "U1"}) {
+		t.Errorf("Accounts = %v", accounts)
+	}
+
+	// Unlinking one account leaves the others linked; unlinking down to a
+	// single account dissolves the identity.
+	if ok, err := store.Unlink("webapp", "w1"); !ok || err != nil {
+		t.Fatalf("Unlink = %v, %v", ok, err)
+	}
+	if got := store.Resolve("webapp", "w1"); got != "webapp:w1" {
+		t.Errorf("after Unlink, Resolve = %q", got)
+	}
+	store.Unlink("feishu", "ou_1")
+	if got := store.Resolve("slack", "U1"); got != "slack:U1" {
+		t.Errorf("last account still resolves to %q", got)
+	}
+	if ok, _ := store.Unlink("slack", "U1"); ok {
+		t.Error("Unlink of an unlinked account reported true")
+	}
+}
+
+func TestStore_LinkMergesIdentities(t *testing.T) {
+	store, _ := openTestStore(t)
+	defer store.Close()
+
+	link := func(from, to Account) string {
+		t.Helper()
+		code, _ := store.NewCode(from.Platform, from.UserID)
+		id, err := store.Link(code, to.Platform, to.UserID)
+		if err != nil {
+			t.Fatalf("Link %s -> %s: %v", from, to, err)
+		}
+		return id
+	}
+	a := link(Account{"slack", "U1"}, Account{"feishu", "F1"})
+	b := link(Account{"discord", "D1"}, Account{"telegram", "T1"})
+	if a == b {
+		t.Fatal("separate links share an identity")
+	}
+
+	// Confirming from an account that is already linked brings its whole
+	// identity along.
+	if got := link(Account{"slack", "U1"}, Account{"telegram", "T1"}); got != a {
+		t.Fatalf("merge identity = %q, want %q", got, a)
+	}
+	if got := store.Resolve("discord", "D1"); got != a {
+		t.Errorf("discord:D1 resolves to %q after merge, want %q", got, a)
+	}
+
+	// A new account that issues the code joins the confirmer's identity,
+	// keeping its ID.
+	if got := link(Account{"webapp", "w1"}, Account{"slack", "U1"}); got != a {
+		t.Errorf("new account joined %q, want the confirmer's %q", got, a)
+	}
+}
+
+func TestStore_CodeExpiryAndAttempts(t *testing.T) {
+	store, _ := openTestStore(t)
+	defer store.Close()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	code, _ := store.NewCode("slack", "U1")
+	now = now.Add(CodeTTL + time.Second)
+	if _, err := store.Link(code, "feishu", "F1"); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("expired code: err = %v, want ErrInvalidCode", err)
+	}
+
+	code, _ = store.NewCode("slack", "U1")
+	for i := 0; i < maxAttempts; i++ {
+		store.Link("bad", "feishu", "F2")
+	}
+	if _, err := store.Link(code, "feishu", "F2"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("after %d failures: err = %v, want ErrTooManyAttempts", maxAttempts, err)
+	}
+	now = now.Add(CodeTTL)
+	code, _ = store.NewCode("slack", "U1")
+	if _, err := store.Link(code, "feishu", "F2"); err != nil {
+		t.Errorf("after the attempt window: %v", err)
+	}
+}
+
+func TestStore_CodeFormat(t *testing.T) {
+	store, _ := openTestStore(t)
+	defer store.Close()
+
+	code, _ := store.NewCode("slack", "U1")
+	if len(code) != codeLength || strings.Trim(code, codeAlphabet) != "" {
+		t.Fatalf("code %q is not %d characters from the code alphabet", code, codeLength)
+	}
+	if _, err := store.Link(" "+strings.ToLower(code)+" ", "feishu", "F1"); err != nil {
+		t.Errorf("lowercase code: %v", err)
+	}
+}
+
+func TestStore_CodeBurnedAfterMisses(t *testing.T) {
+	store, _ := openTestStore(t)
+	defer store.Close()
+
+	// Each guesser stays under its own limit, but the code is burned
+	code, _ := store.NewCode("slack", "U1")
+	for i := range maxCodeMisses {
+		store.Link("WRONG", "nostr", fmt.Sprintf("npub%d", i))
+	}
+	if _, err := store.Link(code, "feishu", "F1"); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("code after %d misses: err = %v, want ErrInvalidCode", maxCodeMisses, err)
+	}
+	code, _ = store.NewCode("slack", "U1")
+	if _, err := store.Link(code, "feishu", "F1"); err != nil {
+		t.Errorf("new code: %v", err)
+	}
+}
+
+func TestStore_GlobalAttemptCap(t *testing.T) {
+	store, _ := openTestStore(t)
+	defer store.Close()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	for i := range maxGlobalAttempts {
+		store.Link("WRONG", "nostr", fmt.Sprintf("npub%d", i))
+	}
+	code, _ := store.NewCode("slack", "U1")
+	if _, err := store.Link(code, "feishu", "F1"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("after %d failures from any account: err = %v, want ErrTooManyAttempts", maxGlobalAttempts, err)
+	}
+	now = now.Add(CodeTTL)
+	code, _ = store.NewCode("slack", "U1")
+	if _, err := store.Link(code, "feishu", "F1"); err != nil {
+		t.Errorf("after the attempt window: %v", err)
+	}
+}
@@ -0,0 +1,279 @@
+// Package ingress is the gateway's shared HTTP server for platforms that
+// receive events over webhooks. Each platform is mounted under
+// /webhooks/<platform>, so one listen address (and one TLS setup) serves
+// them all.
+package ingress
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/pltanton/lingti-bot/internal/logger"
+	"github.com/pltanton/lingti-bot/internal/webhookauth"
+)
+
+// DefaultMaxBodyBytes caps webhook request bodies when Config leaves it unset.
+const DefaultMaxBodyBytes = 1 << 20
+
+// WebhookPlatform is implemented by platforms that can be served by the
+// ingress. WebhookHandler serves the platform's endpoints relative to its
+// mount point.
+type WebhookPlatform interface {
+	Name() string
+	WebhookHandler() http.Handler
+}
+
+// Config configures the ingress server.
+type Config struct {
+	Addr         string // listen address, e.g. ":8443"
+	MaxBodyBytes int64  // request body limit (default: 1 MiB)
+
+	// CertFile and KeyFile serve HTTPS with a fixed certificate.
+	CertFile string
+	KeyFile  string
+
+	// ACME obtains certificates automatically instead. Ignored when
+	// CertFile is set.
+	ACME *ACMEConfig
+}
+
+// ACMEConfig configures automatic certificates over ACME (TLS-ALPN-01, so
+// the ingress must be reachable on port 443 for the listed domains).
+type ACMEConfig struct {
+	Domains  []string // hostnames to request certificates for
+	Email    string   // contact address for the ACME account
+	CacheDir string   // certificate cache (default: ~/.lingti/acme)
+
+	// DirectoryURL is the ACME directory (default: Let's Encrypt). Point it
+	// at a local test CA such as Pebble to try the setup without touching
+	// production.
+	DirectoryURL string
+	// CARootsFile is a PEM bundle trusted when talking to DirectoryURL, for
+	// test CAs with self-signed roots.
+	CARootsFile string
+}
+
+// Server is the shared webhook HTTP server.
+type Server struct {
+	cfg       Config
+	mux       *http.ServeMux
+	tlsConfig *tls.Config
+	acme      *autocert.Manager
+
+	mu       sync.Mutex
+	mounted  []string
+	server   *http.Server
+	listener net.Listener
+}
+
+// New creates an ingress server. TLS settings are checked here, so a bad
+// certificate fails at startup rather than on the first request.
+func New(cfg Config) (*Server, error) {
+	if cfg.Addr == "" {
+		return nil, fmt.Errorf("ingress address is required")
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+
+	s := &Server{cfg: cfg, mux: http.NewServeMux()}
+	s.mux.HandleFunc("/healthz", s.handleHealth)
+	s.mux.HandleFunc("/metrics", handleMetrics)
+
+	switch {
+	case cfg.CertFile != "" || cfg.KeyFile != "":
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		s.tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	case cfg.ACME != nil:
+		m, err := newACMEManager(*cfg.ACME)
+		if err != nil {
+			return nil, err
+		}
+		s.acme = m
+		s.tlsConfig = m.TLSConfig()
+		s.tlsConfig.MinVersion = tls.VersionTLS12
+	}
+	return s, nil
+}
+
+// newACMEManager builds the autocert manager for cfg.
+func newACMEManager(cfg ACMEConfig) (*autocert.Manager, error) {
+	if len(cfg.Domains) == 0 {
+		return nil, fmt.Errorf("ACME needs at least one domain")
+	}
+	if cfg.CacheDir == "" {
+		home, _ := os.UserHomeDir()
+		cfg.CacheDir = filepath.Join(home, ".lingti", "acme")
+	}
+
+	client := &acme.Client{DirectoryURL: cfg.DirectoryURL}
+	if cfg.CARootsFile != "" {
+		pem, err := os.ReadFile(cfg.CARootsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ACME CA roots: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in ACME CA roots file %s", cfg.CARootsFile)
+		}
+		client.HTTPClient = &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}},
+		}
+	}
+
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(cfg.Domains...),
+		Cache:      autocert.DirCache(cfg.CacheDir),
+		Email:      cfg.Email,
+		Client:     client,
+	}, nil
+}
+
+// Mount serves h under /webhooks/<name>. The prefix is stripped, so h sees
+// /webhooks/<name>/ws as /ws and the bare prefix as /.
+func (s *Server) Mount(name string, h http.Handler) {
+	prefix := "/webhooks/" + name
+	limited := s.limitBody(h)
+	strip := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, prefix), "/")
+		r2.URL.RawPath = ""
+		limited.ServeHTTP(w, r2)
+	})
+	s.mux.Handle(prefix, strip)
+	s.mux.Handle(prefix+"/", strip)
+
+	s.mu.Lock()
+	s.mounted = append(s.mounted, name)
+	sort.Strings(s.mounted)
+	s.mu.Unlock()
+	logger.Info("[Ingress] Mounted %s at %s", name, prefix)
+}
+
+// limitBody rejects bodies over the configured size. Declared lengths are
+// refused up front; chunked bodies fail when the handler reads past it.
+func (s *Server) limitBody(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > s.cfg.MaxBodyBytes {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, s.cfg.MaxBodyBytes)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// Handler returns the ingress's root handler.
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// Mounted returns the names of the mounted platforms.
+func (s *Server) Mounted() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.mounted...)
+}
+
+// TLS reports whether the ingress serves HTTPS.
+func (s *Server) TLS() bool {
+	return s.tlsConfig != nil
+}
+
+// Start binds the listen address and serves in the background. Binding
+// happens before Start returns, so a port in use is reported to the caller.
+func (s *Server) Start(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.cfg.Addr, err)
+	}
+	if s.tlsConfig != nil {
+		ln = tls.NewListener(ln, s.tlsConfig)
+	}
+
+	srv := &http.Server{
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	s.mu.Lock()
+	s.server = srv
+	s.listener = ln
+	s.mu.Unlock()
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("[Ingress] Server error: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		_ = s.Stop()
+	}()
+
+	scheme := "http"
+	if s.tlsConfig != nil {
+		scheme = "https"
+	}
+	logger.Info("[Ingress] Listening on %s (%s), webhooks under /webhooks/<platform>", ln.Addr(), scheme)
+	return nil
+}
+
+// Addr returns the address the server is listening on, or "" before Start.
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Stop shuts the server down, waiting briefly for requests in flight.
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	srv := s.server
+	s.mu.Unlock()
+	if srv == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
+
+// handleHealth reports that the ingress is up and what it serves.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":    "ok",
+		"platforms": s.Mounted(),
+		"tls":       s.TLS(),
+	})
+}
+
+// handleMetrics reports webhook verification counters in the Prometheus
+// text format.
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	webhookauth.WriteMetrics(w)
+}
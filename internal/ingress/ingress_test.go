@@ -0,0 +1,218 @@
+package ingress
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoPath answers with the path the platform handler saw.
+var echoPath = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if _, err := io.ReadAll(r.Body); err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	io.WriteString(w, r.URL.Path)
+})
+
+func TestMount(t *testing.T) {
+	s, err := New(Config{Addr: ":0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Mount("whatsapp", echoPath)
+	s.Mount("webapp", echoPath)
+
+	tests := []struct {
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{"/webhooks/whatsapp", http.StatusOK, "/"},
+		{"/webhooks/whatsapp/", http.StatusOK, "/"},
+		{"/webhooks/webapp/ws", http.StatusOK, "/ws"},
+		{"/webhooks/line", http.StatusNotFound, ""},
+		{"/webhooks/whatsappx", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader("{}")))
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s: status %d, want %d", tt.path, rec.Code, tt.wantCode)
+			continue
+		}
+		if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+			t.Errorf("%s: handler saw %q, want %q", tt.path, rec.Body.String(), tt.wantBody)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	var health struct {
+		Status    string   `json:"status"`
+		Platforms []string `json:"platforms"`
+		TLS       bool     `json:"tls"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&health); err != nil {
+		t.Fatalf("healthz: %v", err)
+	}
+	if health.Status != "ok" || strings.Join(health.Platforms, ",") != "webapp,whatsapp" || health.TLS {
+		t.Errorf("healthz = %+v", health)
+	}
+
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), "# TYPE lingti_webhook_requests_total counter") {
+		t.Errorf("metrics = %q", rec.Body.String())
+	}
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	s, err := New(Config{Addr: ":0", MaxBodyBytes: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Mount("line", echoPath)
+
+	tests := []struct {
+		name     string
+		body     io.Reader
+		length   int64
+		wantCode int
+	}{
+		{"small", strings.NewReader("{}"), 2, http.StatusOK},
+		{"declared too large", strings.NewReader(strings.Repeat("x", 32)), 32, http.StatusRequestEntityTooLarge},
+		{"chunked too large", io.MultiReader(strings.NewReader(strings.Repeat("x", 32))), -1, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/line", tt.body)
+		req.ContentLength = tt.length
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.wantCode)
+		}
+	}
+}
+
+// writeSelfSigned writes a certificate for 127.0.0.1 and its key to dir.
+func writeSelfSigned(t *testing.T, dir string) (certFile, keyFile string, pool *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "lingti test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	cert, _ := x509.ParseCertificate(der)
+	pool = x509.NewCertPool()
+	pool.AddCert(cert)
+	return certFile, keyFile, pool
+}
+
+func TestStartTLS(t *testing.T) {
+	certFile, keyFile, pool := writeSelfSigned(t, t.TempDir())
+	s, err := New(Config{Addr: "127.0.0.1:0", CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Mount("teams", echoPath)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := s.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	resp, err := client.Post("https://"+s.Addr()+"/webhooks/teams/api/messages", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("HTTPS request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "/api/messages" {
+		t.Errorf("got %d %q", resp.StatusCode, body)
+	}
+}
+
+func TestNewTLSErrors(t *testing.T) {
+	dir := t.TempDir()
+	roots := filepath.Join(dir, "roots.pem")
+	os.WriteFile(roots, []byte("not a certificate"), 0600)
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{"no addr", Config{}, "address is required"},
+		{"missing cert", Config{Addr: ":0", CertFile: filepath.Join(dir, "missing.pem"), KeyFile: filepath.Join(dir, "missing.key")}, "failed to load TLS certificate"},
+		{"acme without domains", Config{Addr: ":0", ACME: &ACMEConfig{}}, "at least one domain"},
+		{"bad acme roots", Config{Addr: ":0", ACME: &ACMEConfig{Domains: []string{"bot.example.com"}, CARootsFile: roots}}, "no certificates"},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestACMEManager(t *testing.T) {
+	certFile, _, _ := writeSelfSigned(t, t.TempDir())
+	s, err := New(Config{Addr: ":0", ACME: &ACMEConfig{
+		Domains:      []string{"bot.example.com"},
+		CacheDir:     t.TempDir(),
+		DirectoryURL: "https://127.0.0.1:14000/dir",
+		CARootsFile:  certFile,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.TLS() || s.acme.Client.DirectoryURL != "https://127.0.0.1:14000/dir" || s.acme.Client.HTTPClient == nil {
+		t.Fatalf("ACME manager not configured for the test CA: %+v", s.acme.Client)
+	}
+	if err := s.acme.HostPolicy(context.Background(), "bot.example.com"); err != nil {
+		t.Errorf("configured domain rejected: %v", err)
+	}
+	if err := s.acme.HostPolicy(context.Background(), "evil.example.com"); err == nil {
+		t.Error("unlisted domain accepted")
+	}
+}
@@ -0,0 +1,95 @@
+// Package markdown parses the Markdown produced by the agent into a small AST
+// and renders it into the dialect each chat platform understands.
+//
+// Only the subset that models actually emit is supported: ATX headings,
+// paragraphs, fenced code blocks, bullet/ordered lists, block quotes,
+// horizontal rules, pipe tables and the usual inline emphasis, code spans and
+// links. Anything else is passed through as text and escaped by the renderer.
+package markdown
+
+import "strings"
+
+// BlockKind identifies the type of a block node.
+type BlockKind int
+
+const (
+	Paragraph BlockKind = iota
+	Heading
+	CodeBlock
+	List
+	Quote
+	Rule
+	Table
+)
+
+// Block is a block-level node.
+type Block struct {
+	Kind     BlockKind
+	Level    int       // Heading level (1-6)
+	Inlines  []Inline  // Paragraph and Heading content
+	Lang     string    // CodeBlock info string
+	Code     string    // CodeBlock content, without the trailing newline
+	Ordered  bool      // List: numbered list
+	Start    int       // List: first number of an ordered list
+	Items    [][]Block // List items, each a sequence of blocks
+	Children []Block   // Quote content
+	Header   [][]Inline
+	Rows     [][][]Inline
+}
+
+// InlineKind identifies the type of an inline node.
+type InlineKind int
+
+const (
+	Text InlineKind = iota
+	Strong
+	Emphasis
+	Strike
+	Code
+	Link
+	LineBreak
+)
+
+// Inline is an inline node. Text and Code carry Text; Link carries URL and
+// Children; Strong, Emphasis and Strike carry Children.
+type Inline struct {
+	Kind     InlineKind
+	Text     string
+	URL      string
+	Children []Inline
+}
+
+// Document is a parsed Markdown document.
+type Document struct {
+	Blocks []Block
+}
+
+// PlainText flattens inline nodes into unformatted text.
+func PlainText(inlines []Inline) string {
+	var sb strings.Builder
+	writePlain(&sb, inlines)
+	return sb.String()
+}
+
+func writePlain(sb *strings.Builder, inlines []Inline) {
+	for _, in := range inlines {
+		switch in.Kind {
+		case Text, Code:
+			sb.WriteString(in.Text)
+		case LineBreak:
+			sb.WriteByte('\n')
+		default:
+			writePlain(sb, in.Children)
+		}
+	}
+}
+
+// safeURL reports whether a link target may be rendered as a clickable link.
+// Anything other than web and mail links (javascript:, data:, file:) is
+// reduced to its label.
+func safeURL(url string) bool {
+	lower := strings.ToLower(url)
+	return strings.HasPrefix(lower, "http://") ||
+		strings.HasPrefix(lower, "https://") ||
+		strings.HasPrefix(lower, "mailto:")
+}
@@ -0,0 +1,256 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+const ruleLine = "──────────"
+
+var bareURLRe = regexp.MustCompile(`https?://[^\s<>]+`)
+
+// Plain renders Markdown as unformatted text, for channels that display the
+// message verbatim (SMS-like APIs, WeCom customer service, logs).
+func Plain(src string) string {
+	return plainDialect.render(Parse(src))
+}
+
+// Slack renders Markdown as Slack mrkdwn. The result must be sent with
+// escaping disabled, since &, < and > are already entity-encoded.
+func Slack(src string) string {
+	return slackDialect.render(Parse(src))
+}
+
+// TelegramHTML renders Markdown for Telegram's "HTML" parse mode.
+func TelegramHTML(src string) string {
+	return telegramDialect.render(Parse(src))
+}
+
+// Discord renders Markdown in the subset Discord displays, escaping literal
+// markup characters in text.
+func Discord(src string) string {
+	return discordDialect.render(Parse(src))
+}
+
+// DingTalk renders Markdown for DingTalk's markdown message type, which lacks
+// code, strikethrough and tables.
+func DingTalk(src string) string {
+	return dingtalkDialect.render(Parse(src))
+}
+
+// WeCom renders Markdown for WeCom's application markdown message type.
+func WeCom(src string) string {
+	return wecomDialect.render(Parse(src))
+}
+
+// Title derives a short title from the first heading or line of Markdown, for
+// message types that require one (DingTalk markdown, Feishu post).
+func Title(src string, maxRunes int) string {
+	doc := Parse(src)
+	var title string
+	for _, b := range doc.Blocks {
+		if b.Kind == Heading || b.Kind == Paragraph {
+			title = PlainText(b.Inlines)
+			break
+		}
+	}
+	title, _, _ = strings.Cut(strings.TrimSpace(title), "\n")
+	if r := []rune(title); len(r) > maxRunes {
+		title = string(r[:maxRunes]) + "…"
+	}
+	return title
+}
+
+var plainDialect = &dialect{
+	escape:   identity,
+	strong:   identity,
+	emphasis: identity,
+	strike:   identity,
+	code:     identity,
+	link: func(label, url string) string {
+		if label == url {
+			return url
+		}
+		return label + " (" + url + ")"
+	},
+	heading:   plainHeading(identity),
+	codeBlock: func(_, code string) string { return code },
+	quote:     func(s string) string { return prefixLines(s, "> ") },
+	table:     func(b Block, _ *dialect) string { return alignedTable(b) },
+	lineBreak: "\n",
+	bullet:    "- ",
+	rule:      ruleLine,
+}
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+var slackDialect = &dialect{
+	escape:   slackEscaper.Replace,
+	strong:   wrap("*"),
+	emphasis: wrap("_"),
+	strike:   wrap("~"),
+	code:     func(s string) string { return "`" + slackEscaper.Replace(s) + "`" },
+	link: func(label, url string) string {
+		url = strings.NewReplacer("&", "&amp;", "|", "%7C", "<", "%3C", ">", "%3E").Replace(url)
+		if label == url {
+			return "<" + url + ">"
+		}
+		return "<" + url + "|" + label + ">"
+	},
+	heading: plainHeading(wrap("*")),
+	codeBlock: func(_, code string) string {
+		return "```\n" + slackEscaper.Replace(code) + "\n```"
+	},
+	quote: func(s string) string { return prefixLines(s, "> ") },
+	table: func(b Block, _ *dialect) string {
+		return "```\n" + slackEscaper.Replace(alignedTable(b)) + "\n```"
+	},
+	lineBreak: "\n",
+	bullet:    "• ",
+	rule:      ruleLine,
+}
+
+var telegramDialect = &dialect{
+	escape:   escapeHTML,
+	strong:   tag("b"),
+	emphasis: tag("i"),
+	strike:   tag("s"),
+	code:     func(s string) string { return "<code>" + escapeHTML(s) + "</code>" },
+	link: func(label, url string) string {
+		return `<a href="` + html.EscapeString(url) + `">` + label + "</a>"
+	},
+	heading: plainHeading(tag("b")),
+	codeBlock: func(lang, code string) string {
+		if lang == "" {
+			return "<pre>" + escapeHTML(code) + "</pre>"
+		}
+		return `<pre><code class="language-` + html.EscapeString(lang) + `">` + escapeHTML(code) + "</code></pre>"
+	},
+	quote: tag("blockquote"),
+	table: func(b Block, _ *dialect) string {
+		return "<pre>" + escapeHTML(alignedTable(b)) + "</pre>"
+	},
+	lineBreak: "\n",
+	bullet:    "• ",
+	rule:      ruleLine,
+}
+
+var discordDialect = &dialect{
+	escape:   escapeDiscord,
+	strong:   wrap("**"),
+	emphasis: wrap("*"),
+	strike:   wrap("~~"),
+	code: func(s string) string {
+		if strings.Contains(s, "`") {
+			return "`` " + s + " ``"
+		}
+		return "`" + s + "`"
+	},
+	link: func(label, url string) string {
+		if label == escapeDiscord(url) {
+			return url
+		}
+		return "[" + label + "](<" + url + ">)"
+	},
+	heading: func(level int, inlines []Inline, d *dialect) string {
+		// Discord only renders three heading levels.
+		if level > 3 {
+			return "**" + d.inlines(inlines) + "**"
+		}
+		return strings.Repeat("#", level) + " " + d.inlines(inlines)
+	},
+	codeBlock: fencedCode,
+	quote:     func(s string) string { return prefixLines(s, "> ") },
+	table: func(b Block, _ *dialect) string {
+		return fencedCode("", alignedTable(b))
+	},
+	lineBreak: "\n",
+	bullet:    "- ",
+	rule:      ruleLine,
+}
+
+var dingtalkDialect = &dialect{
+	escape:   identity,
+	strong:   wrap("**"),
+	emphasis: wrap("*"),
+	strike:   identity,
+	code:     identity,
+	link:     markdownLink,
+	heading: func(level int, inlines []Inline, d *dialect) string {
+		return strings.Repeat("#", level) + " " + d.inlines(inlines)
+	},
+	codeBlock: func(_, code string) string { return prefixLines(code, "> ") },
+	quote:     func(s string) string { return prefixLines(s, "> ") },
+	table:     listTable,
+	// DingTalk collapses single newlines; a trailing double space forces a break.
+	lineBreak: "  \n",
+	bullet:    "- ",
+	rule:      ruleLine,
+}
+
+var wecomDialect = &dialect{
+	escape:   identity,
+	strong:   wrap("**"),
+	emphasis: identity,
+	strike:   identity,
+	code:     wrap("`"),
+	link:     markdownLink,
+	heading: func(level int, inlines []Inline, d *dialect) string {
+		return strings.Repeat("#", level) + " " + d.inlines(inlines)
+	},
+	codeBlock: func(_, code string) string { return prefixLines(code, "> ") },
+	quote:     func(s string) string { return prefixLines(s, "> ") },
+	table:     listTable,
+	lineBreak: "\n",
+	bullet:    "- ",
+	rule:      ruleLine,
+}
+
+func markdownLink(label, url string) string {
+	if label == url {
+		return url
+	}
+	return "[" + label + "](" + url + ")"
+}
+
+func fencedCode(lang, code string) string {
+	// A literal fence inside the code would close the block early.
+	code = strings.ReplaceAll(code, "```", "`​``")
+	return "```" + lang + "\n" + code + "\n```"
+}
+
+func tag(name string) func(string) string {
+	return func(s string) string {
+		if s == "" {
+			return ""
+		}
+		return "<" + name + ">" + s + "</" + name + ">"
+	}
+}
+
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escapeHTML escapes text content. Quotes are left alone since they are only
+// significant inside attributes.
+func escapeHTML(s string) string {
+	return htmlEscaper.Replace(s)
+}
+
+var discordEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "~", `\~`, "`", "\\`", "|", `\|`,
+)
+
+// escapeDiscord backslash-escapes markup characters, leaving bare URLs intact
+// so Discord still auto-links them.
+func escapeDiscord(s string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range bareURLRe.FindAllStringIndex(s, -1) {
+		sb.WriteString(discordEscaper.Replace(s[last:loc[0]]))
+		sb.WriteString(s[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	sb.WriteString(discordEscaper.Replace(s[last:]))
+	return sb.String()
+}
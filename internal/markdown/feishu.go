@@ -0,0 +1,153 @@
+package markdown
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// postElement is one element of a Feishu rich-text ("post") paragraph.
+type postElement struct {
+	Tag      string   `json:"tag"`
+	Text     string   `json:"text"`
+	Href     string   `json:"href,omitempty"`
+	Language string   `json:"language,omitempty"`
+	Style    []string `json:"style,omitempty"`
+}
+
+type postBody struct {
+	Title   string          `json:"title"`
+	Content [][]postElement `json:"content"`
+}
+
+// FeishuPost renders Markdown as the JSON content of a Feishu/Lark "post"
+// message. Each output paragraph is one line; code blocks and tables become
+// code_block elements.
+func FeishuPost(src string) (string, error) {
+	var w postWriter
+	for i, b := range Parse(src).Blocks {
+		if i > 0 {
+			w.blank()
+		}
+		w.block(b, "")
+	}
+	data, err := json.Marshal(map[string]postBody{
+		"zh_cn": {Content: w.lines},
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+type postWriter struct {
+	lines [][]postElement
+}
+
+func (w *postWriter) blank() {
+	w.lines = append(w.lines, []postElement{{Tag: "text", Text: ""}})
+}
+
+// block writes b, prefixing each produced line with prefix (used for list
+// indentation and quotes).
+func (w *postWriter) block(b Block, prefix string) {
+	switch b.Kind {
+	case Heading:
+		w.inlineLines(b.Inlines, prefix, []string{"bold"})
+	case CodeBlock:
+		w.code(b.Lang, b.Code, prefix)
+	case List:
+		for i, item := range b.Items {
+			marker := "• "
+			if b.Ordered {
+				marker = strconv.Itoa(b.Start+i) + ". "
+			}
+			base := prefix
+			if i > 0 {
+				base = continuation(prefix)
+			}
+			for j, child := range item {
+				p := continuation(base + marker)
+				if j == 0 {
+					p = base + marker
+				}
+				w.block(child, p)
+			}
+		}
+	case Quote:
+		for _, child := range b.Children {
+			w.block(child, prefix+"┃ ")
+		}
+	case Rule:
+		w.lines = append(w.lines, []postElement{{Tag: "hr"}})
+	case Table:
+		w.code("", alignedTable(b), prefix)
+	default:
+		w.inlineLines(b.Inlines, prefix, nil)
+	}
+}
+
+func (w *postWriter) code(lang, code, prefix string) {
+	if prefix != "" {
+		code = prefixLines(code, continuation(prefix))
+	}
+	if lang == "" {
+		lang = "plain_text"
+	}
+	w.lines = append(w.lines, []postElement{{Tag: "code_block", Language: strings.ToUpper(lang), Text: code}})
+}
+
+// inlineLines converts inlines to post elements, starting a new paragraph at
+// every line break.
+func (w *postWriter) inlineLines(inlines []Inline, prefix string, style []string) {
+	line := []postElement{}
+	if prefix != "" {
+		line = append(line, postElement{Tag: "text", Text: prefix})
+	}
+	var walk func(ins []Inline, style []string)
+	walk = func(ins []Inline, style []string) {
+		for _, in := range ins {
+			switch in.Kind {
+			case Text, Code:
+				line = append(line, postElement{Tag: "text", Text: in.Text, Style: style})
+			case LineBreak:
+				w.lines = append(w.lines, line)
+				line = []postElement{}
+				if prefix != "" {
+					line = append(line, postElement{Tag: "text", Text: continuation(prefix)})
+				}
+			case Strong:
+				walk(in.Children, withStyle(style, "bold"))
+			case Emphasis:
+				walk(in.Children, withStyle(style, "italic"))
+			case Strike:
+				walk(in.Children, withStyle(style, "lineThrough"))
+			case Link:
+				if !safeURL(in.URL) {
+					walk(in.Children, style)
+					continue
+				}
+				line = append(line, postElement{Tag: "a", Text: PlainText(in.Children), Href: in.URL, Style: style})
+			}
+		}
+	}
+	walk(inlines, style)
+	w.lines = append(w.lines, line)
+}
+
+// continuation turns a line prefix into the prefix for following lines of
+// the same block: list markers become spaces, quote bars are kept.
+func continuation(prefix string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '┃' {
+			return r
+		}
+		return ' '
+	}, prefix)
+}
+
+func withStyle(style []string, s string) []string {
+	out := make([]string, 0, len(style)+1)
+	out = append(out, style...)
+	return append(out, s)
+}
@@ -0,0 +1,123 @@
+package markdown
+
+import (
+	"html"
+	"strconv"
+	"strings"
+)
+
+// HTML renders Markdown as the HTML subset Matrix clients accept in
+// formatted_body (org.matrix.custom.html).
+func HTML(src string) string {
+	var sb strings.Builder
+	writeHTMLBlocks(&sb, Parse(src).Blocks)
+	return strings.TrimSpace(sb.String())
+}
+
+func writeHTMLBlocks(sb *strings.Builder, blocks []Block) {
+	for _, b := range blocks {
+		writeHTMLBlock(sb, b)
+	}
+}
+
+func writeHTMLBlock(sb *strings.Builder, b Block) {
+	switch b.Kind {
+	case Heading:
+		h := "h" + strconv.Itoa(b.Level)
+		sb.WriteString("<" + h + ">")
+		writeHTMLInlines(sb, b.Inlines)
+		sb.WriteString("</" + h + ">\n")
+	case CodeBlock:
+		if b.Lang != "" {
+			sb.WriteString(`<pre><code class="language-` + html.EscapeString(b.Lang) + `">`)
+		} else {
+			sb.WriteString("<pre><code>")
+		}
+		sb.WriteString(escapeHTML(b.Code))
+		sb.WriteString("</code></pre>\n")
+	case List:
+		tag := "ul"
+		if b.Ordered {
+			tag = "ol"
+			if b.Start != 1 {
+				sb.WriteString(`<ol start="` + strconv.Itoa(b.Start) + `">` + "\n")
+			} else {
+				sb.WriteString("<ol>\n")
+			}
+		} else {
+			sb.WriteString("<ul>\n")
+		}
+		for _, item := range b.Items {
+			sb.WriteString("<li>")
+			// Tight list items hold a single paragraph; skip the <p> wrapper.
+			if len(item) == 1 && item[0].Kind == Paragraph {
+				writeHTMLInlines(sb, item[0].Inlines)
+			} else {
+				writeHTMLBlocks(sb, item)
+			}
+			sb.WriteString("</li>\n")
+		}
+		sb.WriteString("</" + tag + ">\n")
+	case Quote:
+		sb.WriteString("<blockquote>\n")
+		writeHTMLBlocks(sb, b.Children)
+		sb.WriteString("</blockquote>\n")
+	case Rule:
+		sb.WriteString("<hr>\n")
+	case Table:
+		sb.WriteString("<table>\n<thead>\n<tr>")
+		for _, cell := range b.Header {
+			sb.WriteString("<th>")
+			writeHTMLInlines(sb, cell)
+			sb.WriteString("</th>")
+		}
+		sb.WriteString("</tr>\n</thead>\n<tbody>\n")
+		for _, row := range b.Rows {
+			sb.WriteString("<tr>")
+			for _, cell := range row {
+				sb.WriteString("<td>")
+				writeHTMLInlines(sb, cell)
+				sb.WriteString("</td>")
+			}
+			sb.WriteString("</tr>\n")
+		}
+		sb.WriteString("</tbody>\n</table>\n")
+	default:
+		sb.WriteString("<p>")
+		writeHTMLInlines(sb, b.Inlines)
+		sb.WriteString("</p>\n")
+	}
+}
+
+func writeHTMLInlines(sb *strings.Builder, inlines []Inline) {
+	for _, in := range inlines {
+		switch in.Kind {
+		case Text:
+			sb.WriteString(escapeHTML(in.Text))
+		case LineBreak:
+			sb.WriteString("<br>\n")
+		case Code:
+			sb.WriteString("<code>" + escapeHTML(in.Text) + "</code>")
+		case Strong:
+			sb.WriteString("<strong>")
+			writeHTMLInlines(sb, in.Children)
+			sb.WriteString("</strong>")
+		case Emphasis:
+			sb.WriteString("<em>")
+			writeHTMLInlines(sb, in.Children)
+			sb.WriteString("</em>")
+		case Strike:
+			sb.WriteString("<del>")
+			writeHTMLInlines(sb, in.Children)
+			sb.WriteString("</del>")
+		case Link:
+			if !safeURL(in.URL) {
+				writeHTMLInlines(sb, in.Children)
+				continue
+			}
+			sb.WriteString(`<a href="` + html.EscapeString(in.URL) + `">`)
+			writeHTMLInlines(sb, in.Children)
+			sb.WriteString("</a>")
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package markdown
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// parseInlines parses a single line of inline Markdown.
+func parseInlines(s string) []Inline {
+	var out []Inline
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			out = append(out, Inline{Kind: Text, Text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			text.WriteByte(s[i+1])
+			i += 2
+			continue
+
+		case c == '`':
+			n := runLength(s, i, '`')
+			if end := findBacktickRun(s, i+n, n); end >= 0 {
+				flush()
+				out = append(out, Inline{Kind: Code, Text: trimCodeSpan(s[i+n : end])})
+				i = end + n
+			} else {
+				text.WriteString(s[i : i+n])
+				i += n
+			}
+			continue
+
+		case c == '*' || c == '_' || c == '~':
+			if node, next, ok := parseDelimited(s, i); ok {
+				flush()
+				out = append(out, node)
+				i = next
+				continue
+			}
+			// Emit the whole delimiter run so a failed "**" is not retried as "*".
+			n := runLength(s, i, c)
+			text.WriteString(s[i : i+n])
+			i += n
+			continue
+
+		case c == '[' || (c == '!' && i+1 < len(s) && s[i+1] == '['):
+			if node, next, ok := parseLink(s, i); ok {
+				flush()
+				out = append(out, node)
+				i = next
+				continue
+			}
+
+		case c == '<':
+			if m := autolinkRe.FindStringSubmatch(s[i:]); m != nil {
+				flush()
+				out = append(out, Inline{Kind: Link, URL: m[1], Children: []Inline{{Kind: Text, Text: m[1]}}})
+				i += len(m[0])
+				continue
+			}
+		}
+		text.WriteByte(c)
+		i++
+	}
+	flush()
+	return out
+}
+
+// parseDelimited parses **strong**, __strong__, *em*, _em_ and ~~strike~~
+// starting at s[i].
+func parseDelimited(s string, i int) (Inline, int, bool) {
+	c := s[i]
+	n := 1
+	if i+1 < len(s) && s[i+1] == c {
+		n = 2
+	}
+	if c == '~' && n != 2 {
+		return Inline{}, 0, false
+	}
+	kind := Emphasis
+	switch {
+	case c == '~':
+		kind = Strike
+	case n == 2:
+		kind = Strong
+	}
+
+	start := i + n
+	if start >= len(s) || isSpace(s[start]) {
+		return Inline{}, 0, false
+	}
+	// Intraword underscores (snake_case) are never emphasis.
+	if c == '_' && i > 0 && isWordByte(s, i-1) {
+		return Inline{}, 0, false
+	}
+
+	for j := start + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+			continue
+		case '`':
+			k := runLength(s, j, '`')
+			if end := findBacktickRun(s, j+k, k); end >= 0 {
+				j = end + k - 1
+			}
+			continue
+		case c:
+		default:
+			continue
+		}
+		run := runLength(s, j, c)
+		if run < n || isSpace(s[j-1]) || (n == 1 && run == 2) {
+			// Too short, not right-flanking, or a nested strong span.
+			j += run - 1
+			continue
+		}
+		// Close at the end of the run so "***x***" nests as strong(em(x)).
+		end := j + run - n
+		if c == '_' && end+n < len(s) && isWordByte(s, end+n) {
+			j += run - 1
+			continue
+		}
+		return Inline{Kind: kind, Children: parseInlines(s[start:end])}, end + n, true
+	}
+	return Inline{}, 0, false
+}
+
+// parseLink parses [text](url) and ![alt](url) starting at s[i]. Images are
+// returned as links labelled with their alt text.
+func parseLink(s string, i int) (Inline, int, bool) {
+	image := s[i] == '!'
+	open := i
+	if image {
+		open++
+	}
+	depth := 0
+	closeBracket := -1
+	for j := open; j < len(s) && closeBracket < 0; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closeBracket = j
+			}
+		}
+	}
+	if closeBracket < 0 || closeBracket+1 >= len(s) || s[closeBracket+1] != '(' {
+		return Inline{}, 0, false
+	}
+	depth = 0
+	closeParen := -1
+	for j := closeBracket + 1; j < len(s) && closeParen < 0; j++ {
+		switch s[j] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				closeParen = j
+			}
+		}
+	}
+	if closeParen < 0 {
+		return Inline{}, 0, false
+	}
+
+	dest := strings.TrimSpace(s[closeBracket+2 : closeParen])
+	if strings.HasPrefix(dest, "<") {
+		if k := strings.IndexByte(dest, '>'); k > 0 {
+			dest = dest[1:k]
+		}
+	} else if k := strings.IndexAny(dest, " \t"); k >= 0 {
+		dest = dest[:k] // drop an optional "title"
+	}
+	if dest == "" {
+		return Inline{}, 0, false
+	}
+
+	label := s[open+1 : closeBracket]
+	var children []Inline
+	if image {
+		if label == "" {
+			label = dest
+		}
+		children = []Inline{{Kind: Text, Text: label}}
+	} else {
+		children = parseInlines(label)
+		if len(children) == 0 {
+			children = []Inline{{Kind: Text, Text: dest}}
+		}
+	}
+	return Inline{Kind: Link, URL: dest, Children: children}, closeParen + 1, true
+}
+
+func runLength(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return n
+}
+
+// findBacktickRun returns the index of the next run of exactly n backticks at
+// or after from, or -1.
+func findBacktickRun(s string, from, n int) int {
+	for j := from; j < len(s); {
+		if s[j] != '`' {
+			j++
+			continue
+		}
+		k := runLength(s, j, '`')
+		if k == n {
+			return j
+		}
+		j += k
+	}
+	return -1
+}
+
+// trimCodeSpan strips one leading and trailing space, which CommonMark uses
+// to allow backticks at the edges of a code span.
+func trimCodeSpan(code string) string {
+	if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+		return code[1 : len(code)-1]
+	}
+	return code
+}
+
+func isASCIIPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+// isWordByte reports whether the character containing s[i] is a letter or
+// digit. CJK text counts as word characters, which keeps "中_文" literal.
+func isWordByte(s string, i int) bool {
+	for i > 0 && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
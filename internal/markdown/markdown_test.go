@@ -0,0 +1,140 @@
+package markdown
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func feishuIndented(src string) string {
+	out, err := FeishuPost(src)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(out), "", "  "); err != nil {
+		return "error: " + err.Error()
+	}
+	return buf.String()
+}
+
+var renderers = map[string]func(string) string{
+	"plain":    Plain,
+	"slack":    Slack,
+	"telegram": TelegramHTML,
+	"discord":  Discord,
+	"dingtalk": DingTalk,
+	"wecom":    WeCom,
+	"matrix":   HTML,
+	"feishu":   feishuIndented,
+}
+
+// TestGolden renders every testdata/*.md input with every dialect and
+// compares against testdata/<name>.<dialect>.golden. Run with -update to
+// regenerate after an intentional change.
+func TestGolden(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/*.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no golden inputs found")
+	}
+	for _, input := range inputs {
+		src, err := os.ReadFile(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		base := strings.TrimSuffix(input, ".md")
+		for name, render := range renderers {
+			golden := base + "." + name + ".golden"
+			got := render(string(src)) + "\n"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Errorf("%s: %v (run go test -update)", golden, err)
+				continue
+			}
+			if got != string(want) {
+				t.Errorf("%s mismatch\n--- got ---\n%s\n--- want ---\n%s", golden, got, want)
+			}
+		}
+	}
+}
+
+func TestParseInlines(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"**bold**", "<strong>bold</strong>"},
+		{"*em* and _em_", "<em>em</em> and <em>em</em>"},
+		{"***both***", "<strong><em>both</em></strong>"},
+		{"*a **b** c*", "<em>a <strong>b</strong> c</em>"},
+		{"snake_case_name", "snake_case_name"},
+		{"2 * 3 * 4", "2 * 3 * 4"},
+		{"unclosed **bold", "unclosed **bold"},
+		{"`a*b*c`", "<code>a*b*c</code>"},
+		{"``a ` b``", "<code>a ` b</code>"},
+		{`\*lit\*`, "*lit*"},
+		{"[x](https://a.b/c_(d))", `<a href="https://a.b/c_(d)">x</a>`},
+		{"![alt](https://a.b/i.png)", `<a href="https://a.b/i.png">alt</a>`},
+		{"<https://a.b>", `<a href="https://a.b">https://a.b</a>`},
+		{"[bad](javascript:x)", "bad"},
+		{"~~gone~~ ~one~", "<del>gone</del> ~one~"},
+		{"中文**加粗**文字", "中文<strong>加粗</strong>文字"},
+	}
+	for _, tt := range tests {
+		got := strings.TrimSuffix(strings.TrimPrefix(HTML(tt.in), "<p>"), "</p>")
+		if got != tt.want {
+			t.Errorf("HTML(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseBlocks(t *testing.T) {
+	doc := Parse("# Title\n\ntext\n- a\n- b\n\n```sh\nls\n```\n> q\n\n---\n| h |\n|---|\n| c |")
+	var kinds []BlockKind
+	for _, b := range doc.Blocks {
+		kinds = append(kinds, b.Kind)
+	}
+	want := []BlockKind{Heading, Paragraph, List, CodeBlock, Quote, Rule, Table}
+	if len(kinds) != len(want) {
+		t.Fatalf("got %d blocks %v, want %v", len(kinds), kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Errorf("block %d: got kind %d, want %d", i, kinds[i], want[i])
+		}
+	}
+	if doc.Blocks[3].Lang != "sh" || doc.Blocks[3].Code != "ls" {
+		t.Errorf("code block = %q/%q", doc.Blocks[3].Lang, doc.Blocks[3].Code)
+	}
+}
+
+func TestUnclosedFence(t *testing.T) {
+	got := Plain("```\nstill code **here**")
+	if got != "still code **here**" {
+		t.Errorf("Plain = %q", got)
+	}
+}
+
+func TestTitle(t *testing.T) {
+	if got := Title("## 报告 **重要**\n\nbody", 20); got != "报告 重要" {
+		t.Errorf("Title = %q", got)
+	}
+	if got := Title("abcdefghij", 4); got != "abcd…" {
+		t.Errorf("Title = %q", got)
+	}
+}
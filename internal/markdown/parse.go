@@ -0,0 +1,304 @@
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	headingRe     = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*))?$`)
+	closingHashRe = regexp.MustCompile(`[ \t]+#+[ \t]*$`)
+	fenceRe       = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ \t]*([^`\\s]*)")
+	listItemRe    = regexp.MustCompile(`^( *)([-*+]|\d{1,9}[.)])(?:[ \t]+(.*))?$`)
+	quoteRe       = regexp.MustCompile(`^ {0,3}> ?(.*)$`)
+	tableSepRe    = regexp.MustCompile(`^ *\|? *:?-+:? *(\| *:?-+:? *)*\|? *$`)
+	autolinkRe    = regexp.MustCompile(`^<(https?://[^\s<>]+)>`)
+)
+
+// Parse parses Markdown source into a Document. It never fails: input that
+// does not form a recognised construct is kept as literal text.
+func Parse(src string) Document {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\t", "    ")
+	return Document{Blocks: parseBlocks(strings.Split(src, "\n"))}
+}
+
+func parseBlocks(lines []string) []Block {
+	var blocks []Block
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		if isBlank(line) {
+			i++
+			continue
+		}
+
+		if m := fenceRe.FindStringSubmatch(line); m != nil {
+			b, next := parseFence(lines, i, m)
+			blocks = append(blocks, b)
+			i = next
+			continue
+		}
+
+		if m := headingRe.FindStringSubmatch(line); m != nil {
+			text := closingHashRe.ReplaceAllString(strings.TrimSpace(m[2]), "")
+			if strings.Trim(text, "#") == "" {
+				text = ""
+			}
+			blocks = append(blocks, Block{Kind: Heading, Level: len(m[1]), Inlines: parseInlines(text)})
+			i++
+			continue
+		}
+
+		if isRule(line) {
+			blocks = append(blocks, Block{Kind: Rule})
+			i++
+			continue
+		}
+
+		if quoteRe.MatchString(line) {
+			var inner []string
+			for i < len(lines) {
+				m := quoteRe.FindStringSubmatch(lines[i])
+				if m == nil {
+					break
+				}
+				inner = append(inner, m[1])
+				i++
+			}
+			blocks = append(blocks, Block{Kind: Quote, Children: parseBlocks(inner)})
+			continue
+		}
+
+		if listItemRe.MatchString(line) {
+			b, next := parseList(lines, i)
+			blocks = append(blocks, b)
+			i = next
+			continue
+		}
+
+		if isTableStart(lines, i) {
+			b, next := parseTable(lines, i)
+			blocks = append(blocks, b)
+			i = next
+			continue
+		}
+
+		// Paragraph: runs until a blank line or the start of another block.
+		var para []string
+		for i < len(lines) && !isBlank(lines[i]) {
+			if len(para) > 0 && startsBlock(lines, i) {
+				break
+			}
+			para = append(para, strings.TrimSpace(lines[i]))
+			i++
+		}
+		blocks = append(blocks, Block{Kind: Paragraph, Inlines: parseLines(para)})
+	}
+	return blocks
+}
+
+// parseLines parses each line as inline content and joins them with hard
+// line breaks; chat clients show newlines literally, so they are preserved.
+func parseLines(lines []string) []Inline {
+	var out []Inline
+	for i, l := range lines {
+		if i > 0 {
+			out = append(out, Inline{Kind: LineBreak})
+		}
+		out = append(out, parseInlines(l)...)
+	}
+	return out
+}
+
+func parseFence(lines []string, i int, m []string) (Block, int) {
+	indent := len(m[1])
+	fence := m[2]
+	b := Block{Kind: CodeBlock, Lang: m[3]}
+	var code []string
+	i++
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			i++
+			break
+		}
+		code = append(code, trimIndent(lines[i], indent))
+	}
+	b.Code = strings.Join(code, "\n")
+	return b, i
+}
+
+func parseList(lines []string, i int) (Block, int) {
+	first := listItemRe.FindStringSubmatch(lines[i])
+	baseIndent := len(first[1])
+	ordered := isOrderedMarker(first[2])
+	b := Block{Kind: List, Ordered: ordered}
+	if ordered {
+		b.Start, _ = strconv.Atoi(first[2][:len(first[2])-1])
+	}
+
+	var item []string
+	flush := func() {
+		if item != nil {
+			b.Items = append(b.Items, parseBlocks(item))
+		}
+		item = nil
+	}
+
+	for i < len(lines) {
+		line := lines[i]
+		if isBlank(line) {
+			// A blank line only continues the list if the next content line
+			// is indented into the item or is another item of this list.
+			j := i + 1
+			for j < len(lines) && isBlank(lines[j]) {
+				j++
+			}
+			if j == len(lines) {
+				i = j
+				break
+			}
+			if indentOf(lines[j]) <= baseIndent && !isSibling(lines[j], baseIndent, ordered) {
+				break
+			}
+			item = append(item, "")
+			i++
+			continue
+		}
+
+		if m := listItemRe.FindStringSubmatch(line); m != nil && len(m[1]) <= baseIndent {
+			if isOrderedMarker(m[2]) != ordered {
+				break
+			}
+			flush()
+			item = []string{m[3]}
+			i++
+			continue
+		}
+
+		if indentOf(line) > baseIndent {
+			item = append(item, trimIndent(line, baseIndent+len(first[2])+1))
+			i++
+			continue
+		}
+
+		// Lazy continuation of the item's paragraph.
+		if startsBlock(lines, i) || (len(item) > 0 && item[len(item)-1] == "") {
+			break
+		}
+		item = append(item, strings.TrimSpace(line))
+		i++
+	}
+	flush()
+	return b, i
+}
+
+func isSibling(line string, baseIndent int, ordered bool) bool {
+	m := listItemRe.FindStringSubmatch(line)
+	return m != nil && len(m[1]) <= baseIndent && isOrderedMarker(m[2]) == ordered
+}
+
+func isOrderedMarker(marker string) bool {
+	return marker[0] >= '0' && marker[0] <= '9'
+}
+
+func isTableStart(lines []string, i int) bool {
+	return i+1 < len(lines) &&
+		strings.Contains(lines[i], "|") &&
+		strings.Contains(lines[i+1], "|") &&
+		tableSepRe.MatchString(lines[i+1])
+}
+
+func parseTable(lines []string, i int) (Block, int) {
+	b := Block{Kind: Table}
+	for _, cell := range splitRow(lines[i]) {
+		b.Header = append(b.Header, parseInlines(cell))
+	}
+	i += 2
+	for ; i < len(lines); i++ {
+		if isBlank(lines[i]) || !strings.Contains(lines[i], "|") {
+			break
+		}
+		var row [][]Inline
+		for _, cell := range splitRow(lines[i]) {
+			row = append(row, parseInlines(cell))
+		}
+		b.Rows = append(b.Rows, row)
+	}
+	return b, i
+}
+
+// splitRow splits a pipe table row into trimmed cells, honouring "\|".
+func splitRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	var cells []string
+	var cur strings.Builder
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' && i+1 < len(line) && line[i+1] == '|' {
+			cur.WriteByte('|')
+			i++
+			continue
+		}
+		if line[i] == '|' {
+			cells = append(cells, strings.TrimSpace(cur.String()))
+			cur.Reset()
+			continue
+		}
+		cur.WriteByte(line[i])
+	}
+	return append(cells, strings.TrimSpace(cur.String()))
+}
+
+// startsBlock reports whether lines[i] begins a block that interrupts a
+// paragraph.
+func startsBlock(lines []string, i int) bool {
+	line := lines[i]
+	return fenceRe.MatchString(line) ||
+		headingRe.MatchString(line) ||
+		isRule(line) ||
+		quoteRe.MatchString(line) ||
+		listItemRe.MatchString(line) ||
+		isTableStart(lines, i)
+}
+
+func isRule(line string) bool {
+	s := strings.TrimSpace(line)
+	if len(s) < 3 || indentOf(line) > 3 {
+		return false
+	}
+	c := s[0]
+	if c != '-' && c != '*' && c != '_' {
+		return false
+	}
+	n := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case c:
+			n++
+		case ' ':
+		default:
+			return false
+		}
+	}
+	return n >= 3
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func trimIndent(line string, n int) string {
+	if k := indentOf(line); k < n {
+		n = k
+	}
+	return line[n:]
+}
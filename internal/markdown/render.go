@@ -0,0 +1,243 @@
+package markdown
+
+import (
+	"strconv"
+	"strings"
+)
+
+// dialect describes a line-oriented chat markup. Block structure (lists,
+// quotes, blank lines between blocks) is shared; each platform only supplies
+// how inline spans, headings and code blocks are written and how literal text
+// is escaped.
+type dialect struct {
+	escape    func(s string) string
+	strong    func(s string) string
+	emphasis  func(s string) string
+	strike    func(s string) string
+	code      func(s string) string
+	link      func(label, url string) string
+	heading   func(level int, inlines []Inline, d *dialect) string
+	codeBlock func(lang, code string) string
+	quote     func(s string) string
+	table     func(b Block, d *dialect) string
+	lineBreak string
+	bullet    string
+	rule      string
+}
+
+func (d *dialect) render(doc Document) string {
+	return strings.TrimSpace(d.blocks(doc.Blocks))
+}
+
+func (d *dialect) blocks(blocks []Block) string {
+	parts := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		parts = append(parts, d.block(b))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func (d *dialect) block(b Block) string {
+	switch b.Kind {
+	case Heading:
+		return d.heading(b.Level, b.Inlines, d)
+	case CodeBlock:
+		return d.codeBlock(b.Lang, b.Code)
+	case List:
+		return d.list(b)
+	case Quote:
+		return d.quote(d.blocks(b.Children))
+	case Rule:
+		return d.rule
+	case Table:
+		return d.table(b, d)
+	default:
+		return d.inlines(b.Inlines)
+	}
+}
+
+// list renders a tight list; nested blocks are indented under the marker.
+func (d *dialect) list(b Block) string {
+	var sb strings.Builder
+	for i, item := range b.Items {
+		marker := d.bullet
+		if b.Ordered {
+			marker = strconv.Itoa(b.Start+i) + ". "
+		}
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		var parts []string
+		for _, child := range item {
+			parts = append(parts, d.block(child))
+		}
+		body := strings.Join(parts, "\n")
+		pad := strings.Repeat(" ", len([]rune(marker)))
+		for j, line := range strings.Split(body, "\n") {
+			if j == 0 {
+				sb.WriteString(marker + line)
+			} else if line == "" {
+				sb.WriteString("\n")
+			} else {
+				sb.WriteString("\n" + pad + line)
+			}
+		}
+	}
+	return sb.String()
+}
+
+func (d *dialect) inlines(inlines []Inline) string {
+	var sb strings.Builder
+	for _, in := range inlines {
+		switch in.Kind {
+		case Text:
+			sb.WriteString(d.escape(in.Text))
+		case LineBreak:
+			sb.WriteString(d.lineBreak)
+		case Code:
+			sb.WriteString(d.code(in.Text))
+		case Strong:
+			sb.WriteString(d.strong(d.inlines(in.Children)))
+		case Emphasis:
+			sb.WriteString(d.emphasis(d.inlines(in.Children)))
+		case Strike:
+			sb.WriteString(d.strike(d.inlines(in.Children)))
+		case Link:
+			if !safeURL(in.URL) {
+				sb.WriteString(d.inlines(in.Children))
+				continue
+			}
+			sb.WriteString(d.link(d.inlines(in.Children), in.URL))
+		}
+	}
+	return sb.String()
+}
+
+// prefixLines prepends prefix to every line of s.
+func prefixLines(s, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		if l == "" {
+			lines[i] = strings.TrimRight(prefix, " ")
+		} else {
+			lines[i] = prefix + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func wrap(marker string) func(string) string {
+	return func(s string) string {
+		if s == "" {
+			return ""
+		}
+		return marker + s + marker
+	}
+}
+
+func identity(s string) string { return s }
+
+// plainHeading renders a heading as escaped text with formatting stripped,
+// for dialects that have no heading syntax.
+func plainHeading(style func(string) string) func(int, []Inline, *dialect) string {
+	return func(_ int, inlines []Inline, d *dialect) string {
+		return style(d.escape(PlainText(inlines)))
+	}
+}
+
+// cellTexts flattens a table into plain-text cells, header row first.
+func cellTexts(b Block) [][]string {
+	rows := make([][]string, 0, len(b.Rows)+1)
+	var header []string
+	for _, c := range b.Header {
+		header = append(header, PlainText(c))
+	}
+	rows = append(rows, header)
+	for _, r := range b.Rows {
+		var row []string
+		for _, c := range r {
+			row = append(row, PlainText(c))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// alignedTable lays a table out as monospace text, for platforms without
+// native tables. Column widths account for double-width CJK characters.
+func alignedTable(b Block) string {
+	rows := cellTexts(b)
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], displayWidth(cell))
+		}
+	}
+	var lines []string
+	for r, row := range rows {
+		var cells []string
+		for i, w := range widths {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			cells = append(cells, cell+strings.Repeat(" ", w-displayWidth(cell)))
+		}
+		lines = append(lines, strings.TrimRight(strings.Join(cells, " | "), " "))
+		if r == 0 {
+			var sep []string
+			for _, w := range widths {
+				sep = append(sep, strings.Repeat("-", w))
+			}
+			lines = append(lines, strings.Join(sep, "-+-"))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// listTable renders each row as "header: value" pairs, which reads better
+// than a misaligned grid on proportional-font clients.
+func listTable(b Block, d *dialect) string {
+	rows := cellTexts(b)
+	header := rows[0]
+	var lines []string
+	for _, row := range rows[1:] {
+		var pairs []string
+		for i, cell := range row {
+			if i < len(header) && header[i] != "" {
+				pairs = append(pairs, d.escape(header[i])+": "+d.escape(cell))
+			} else {
+				pairs = append(pairs, d.escape(cell))
+			}
+		}
+		lines = append(lines, d.bullet+strings.Join(pairs, ", "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func displayWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		if isWide(r) {
+			w += 2
+		} else {
+			w++
+		}
+	}
+	return w
+}
+
+func isWide(r rune) bool {
+	return r >= 0x1100 && (r <= 0x115f ||
+		(r >= 0x2e80 && r <= 0xa4cf) ||
+		(r >= 0xac00 && r <= 0xd7a3) ||
+		(r >= 0xf900 && r <= 0xfaff) ||
+		(r >= 0xfe30 && r <= 0xfe4f) ||
+		(r >= 0xff00 && r <= 0xff60) ||
+		(r >= 0xffe0 && r <= 0xffe6) ||
+		(r >= 0x1f300 && r <= 0x1faff) ||
+		(r >= 0x20000 && r <= 0x3fffd))
+}
@@ -131,6 +131,12 @@ func (s *Server) ExecuteTool(ctx context.Context, toolName string, arguments map
 	return nil, nil
 }
 
+// ExecuteMacro implements the MacroExecutor interface for the cron scheduler.
+// MCP mode has a single browser session, so the chat target is ignored.
+func (s *Server) ExecuteMacro(ctx context.Context, _, _, _, _ string, name string) (string, error) {
+	return tools.RunMacro(ctx, name)
+}
+
 // NotifyChat implements the ChatNotifier interface for the cron scheduler
 func (s *Server) NotifyChat(message string) error {
 	// In MCP mode, we just log to console as there's no persistent chat session
@@ -170,6 +176,8 @@ func (s *Server) addTool(tool mcp.Tool, handler ToolHandler) {
 		}
 	} else if tool.Name == "shell_execute" {
 		wrappedHandler = s.wrapPathCheck("working_directory", handler)
+	} else if tool.Name == "browser_upload" || tool.Name == "browser_download" || tool.Name == "browser_network" || tool.Name == "browser_extract" {
+		wrappedHandler = s.wrapBrowserPathCheck(tool.Name, handler)
 	}
 	s.mcpServer.AddTool(tool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return wrappedHandler(ctx, req)
@@ -192,6 +200,25 @@ func (s *Server) wrapPathCheck(argKey string, handler ToolHandler) ToolHandler {
 	}
 }
 
+// wrapBrowserPathCheck applies disable_file_tools and allowed_paths to the
+// local files a browser tool call reads or writes.
+func (s *Server) wrapBrowserPathCheck(name string, handler ToolHandler) ToolHandler {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		paths := tools.BrowserFilePaths(name, req.Params.Arguments)
+		if s.disableFileTools && len(paths) > 0 {
+			return mcp.NewToolResultError("ACCESS DENIED: file operations are disabled by security policy. Do NOT retry. Inform the user that file access is disabled."), nil
+		}
+		if s.pathChecker.HasRestrictions() {
+			for _, p := range paths {
+				if err := s.pathChecker.CheckPath(p); err != nil {
+					return mcp.NewToolResultError(err.Error()), nil
+				}
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
 func registerFilesystemTools(s *Server) {
 	// file_read
 	s.addTool(mcp.NewTool("file_read",
@@ -394,6 +421,7 @@ func registerBrowserTools(s *Server) {
 		mcp.WithBoolean("headless", mcp.Description("Run in headless mode without visible window (default: true)")),
 		mcp.WithString("url", mcp.Description("Initial URL to navigate to after launch")),
 		mcp.WithString("executable_path", mcp.Description("Path to browser executable (auto-detected if omitted)")),
+		mcp.WithString("profile", mcp.Description("Login profile to use (cookies and localStorage saved in ~/.lingti/browser-profiles/)")),
 	), tools.BrowserStart)
 
 	// browser_stop
@@ -414,7 +442,12 @@ func registerBrowserTools(s *Server) {
 
 	// browser_snapshot
 	s.addTool(mcp.NewTool("browser_snapshot",
-		mcp.WithDescription("Capture the page accessibility tree with numbered refs. Use these refs with browser_click/browser_type to interact with elements. Re-run after page changes."),
+		mcp.WithDescription("Capture the page accessibility tree with numbered refs. Use these refs with browser_click/browser_type to interact with elements. Re-run after page changes. Refs stay the same for an element across snapshots of one page, so mode=diff returns only added, removed and changed elements."),
+		mcp.WithString("mode", mcp.Enum("full", "diff"), mcp.Description("full (default) or diff: only changes since the previous snapshot with the same scope")),
+		mcp.WithNumber("scope_ref", mcp.Description("Only the subtree of this ref (e.g. a dialog or list)")),
+		mcp.WithString("scope_selector", mcp.Description("Only the subtree of the first element matching this CSS selector")),
+		mcp.WithBoolean("viewport_only", mcp.Description("Only elements currently visible on screen")),
+		mcp.WithBoolean("interactive_only", mcp.Description("Only interactive elements (buttons, links, inputs), no headings or text")),
 	), tools.BrowserSnapshot)
 
 	// browser_screenshot
@@ -430,6 +463,24 @@ func registerBrowserTools(s *Server) {
 		mcp.WithNumber("ref", mcp.Required(), mcp.Description("Element ref number from browser_snapshot")),
 	), tools.BrowserClick)
 
+	// browser_marks
+	s.addTool(mcp.NewTool("browser_marks",
+		mcp.WithDescription("Screenshot the visible page with red numbered boxes (marks) over clickable elements. Use when browser_snapshot shows few or no elements (canvas apps, unlabelled buttons, icons)."),
+	), tools.BrowserMarks)
+
+	// browser_click_mark
+	s.addTool(mcp.NewTool("browser_click_mark",
+		mcp.WithDescription("Click an element by its mark number from browser_marks"),
+		mcp.WithNumber("mark", mcp.Required(), mcp.Description("Mark number from browser_marks")),
+	), tools.BrowserClickMark)
+
+	// browser_click_xy
+	s.addTool(mcp.NewTool("browser_click_xy",
+		mcp.WithDescription("Click a point of the page by its pixel coordinates in the last browser_marks screenshot, for targets without a mark (e.g. inside a canvas)"),
+		mcp.WithNumber("x", mcp.Required(), mcp.Description("X in screenshot pixels")),
+		mcp.WithNumber("y", mcp.Required(), mcp.Description("Y in screenshot pixels")),
+	), tools.BrowserClickXY)
+
 	// browser_type
 	s.addTool(mcp.NewTool("browser_type",
 		mcp.WithDescription("Type text into an element by its ref number from browser_snapshot"),
@@ -450,6 +501,93 @@ func registerBrowserTools(s *Server) {
 		mcp.WithString("script", mcp.Required(), mcp.Description("JavaScript code to execute as function body (use 'return' to get values back)")),
 	), tools.BrowserExecuteJS)
 
+	// browser_upload
+	s.addTool(mcp.NewTool("browser_upload",
+		mcp.WithDescription("Attach local files to a file input, or to an upload button that opens a file chooser, by its ref number from browser_snapshot"),
+		mcp.WithNumber("ref", mcp.Required(), mcp.Description("Ref number of the file input or upload button")),
+		mcp.WithArray("paths", mcp.Required(), mcp.Items(map[string]any{"type": "string"}), mcp.Description("Local file paths to upload")),
+	), tools.BrowserUpload)
+
+	// browser_download
+	s.addTool(mcp.NewTool("browser_download",
+		mcp.WithDescription("Download a file by clicking a ref or opening a URL, and save it in the download directory"),
+		mcp.WithNumber("ref", mcp.Description("Ref number of the element that starts the download")),
+		mcp.WithString("url", mcp.Description("URL to download instead of clicking a ref")),
+		mcp.WithString("dir", mcp.Description("Directory to save to (default: browser.download_dir or ~/.lingti/downloads)")),
+		mcp.WithNumber("timeout_sec", mcp.Description("Seconds to wait for the download to finish (default: 60)")),
+	), tools.BrowserDownload)
+
+	// browser_network
+	s.addTool(mcp.NewTool("browser_network",
+		mcp.WithDescription("Record XHR/fetch responses of the current tab: start, then wait or list, get a body by index, stop"),
+		mcp.WithString("action", mcp.Required(), mcp.Description("One of: start, wait, list, get, stop")),
+		mcp.WithString("url_pattern", mcp.Description("Regexp the request URL must match (start only, default: all)")),
+		mcp.WithNumber("index", mcp.Description("Entry number from list (get only)")),
+		mcp.WithString("save_to", mcp.Description("Write the full body to this file instead of returning it (get only)")),
+		mcp.WithNumber("timeout_sec", mcp.Description("Seconds to wait for a new response (wait only, default: 10)")),
+	), tools.BrowserNetwork)
+
+	// browser_extract
+	s.addTool(mcp.NewTool("browser_extract",
+		mcp.WithDescription("Extract structured rows (tables, lists, search results) as JSON or CSV from the current browser page, a URL fetched without the browser, or raw HTML. Follows pagination and removes duplicate rows."),
+		mcp.WithString("items", mcp.Description("CSS selector matching one record (default: rows of the largest table)")),
+		mcp.WithObject("fields", mcp.Description("Field name to selector within a record: 'css', 'css@attr', '@attr', or 'role:link@href' (default: table columns)")),
+		mcp.WithObject("schema", mcp.Description("JSON schema of a record; properties set field names and types and may carry a 'selector'")),
+		mcp.WithString("url", mcp.Description("Fetch this URL over HTTP instead of using the browser")),
+		mcp.WithString("html", mcp.Description("Extract from this HTML instead of a page")),
+		mcp.WithNumber("next_ref", mcp.Description("Ref of the 'next page' control to click between pages")),
+		mcp.WithString("next_selector", mcp.Description("CSS selector of the 'next page' link or button")),
+		mcp.WithBoolean("scroll", mcp.Description("Scroll to load more (infinite scroll) between pages")),
+		mcp.WithNumber("max_pages", mcp.Description("Pages to read (default: 1, or 5 with pagination; max 50)")),
+		mcp.WithNumber("limit", mcp.Description("Maximum rows to return")),
+		mcp.WithArray("dedupe_by", mcp.Items(map[string]any{"type": "string"}), mcp.Description("Fields identifying a row for deduplication (default: all fields)")),
+		mcp.WithString("format", mcp.Description("json (default) or csv")),
+		mcp.WithString("save_to", mcp.Description("Write the result to this file instead of returning it")),
+	), tools.BrowserExtract)
+
+	// browser_visited
+	s.addTool(mcp.NewTool("browser_visited",
+		mcp.WithDescription("Track visited URLs during iterative browser tasks: 'check' before processing a page, 'mark' after, 'list' or 'clear'. Persisted across restarts."),
+		mcp.WithString("action", mcp.Required(), mcp.Description("One of: check, mark, list, clear")),
+		mcp.WithString("url", mcp.Description("URL to check or mark")),
+		mcp.WithString("task", mcp.Description("Task name to keep separate visited sets (optional)")),
+	), tools.BrowserVisited)
+
+	// task_checkpoint
+	s.addTool(mcp.NewTool("task_checkpoint",
+		mcp.WithDescription("Save and restore progress of a long multi-step task so it can resume after a restart"),
+		mcp.WithString("action", mcp.Required(), mcp.Description("One of: save, load, clear, list")),
+		mcp.WithString("task", mcp.Description("Task name (required except for list)")),
+		mcp.WithString("data", mcp.Description("Progress to save (required for save)")),
+	), tools.TaskCheckpoint)
+
+	// browser_macro_save
+	s.addTool(mcp.NewTool("browser_macro_save",
+		mcp.WithDescription("Save the browser actions recorded since the last save as a replayable macro in ~/.lingti/macros/"),
+		mcp.WithString("name", mcp.Required(), mcp.Description("Macro name (letters, digits, - and _)")),
+		mcp.WithString("description", mcp.Description("What the macro does")),
+		mcp.WithNumber("from_step", mcp.Description("First recorded step to include, 1-based (default: 1)")),
+	), tools.BrowserMacroSave)
+
+	// browser_macro_run
+	s.addTool(mcp.NewTool("browser_macro_run",
+		mcp.WithDescription("Replay a saved browser macro step by step. On failure, reports the failed step and the steps still to do."),
+		mcp.WithString("name", mcp.Required(), mcp.Description("Macro name")),
+	), tools.BrowserMacroRun)
+
+	// browser_macro_list
+	s.addTool(mcp.NewTool("browser_macro_list",
+		mcp.WithDescription("List saved browser macros and the unsaved recording"),
+	), tools.BrowserMacroList)
+
+	// browser_site_action
+	s.addTool(mcp.NewTool("browser_site_action",
+		mcp.WithDescription("Run a named action (e.g. comment, open_note) from the site recipe matching the current page. Recipes are YAML files in ~/.lingti/recipes/ or ./recipes/."),
+		mcp.WithString("action", mcp.Required(), mcp.Description("Action name defined by the site recipe")),
+		mcp.WithString("site", mcp.Description("Recipe name, only needed when several recipes match the page")),
+		mcp.WithObject("args", mcp.Description("Action arguments, e.g. {\"comment\": \"...\"}")),
+	), tools.BrowserSiteAction)
+
 	// browser_tabs
 	s.addTool(mcp.NewTool("browser_tabs",
 		mcp.WithDescription("List all open browser tabs with their target IDs and URLs"),
@@ -0,0 +1,275 @@
+// Package outbox persists outgoing chat messages until a platform accepts
+// them, so responses and cron results survive failed sends and restarts.
+//
+// Messages of one channel are delivered in the order they were queued. A
+// message that keeps failing becomes a dead letter: it stops blocking its
+// channel and waits in the store until it is retried or dropped.
+package outbox
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Item is a queued message. Its payload is opaque to the store.
+type Item struct {
+	ID          int64
+	Platform    string
+	ChannelID   string
+	Payload     []byte
+	Attempts    int
+	NextAttempt time.Time
+	LastError   string
+	CreatedAt   time.Time
+	Dead        bool
+}
+
+// Channel identifies the queue of one chat.
+type Channel struct {
+	Platform  string
+	ChannelID string
+}
+
+// Store is a SQLite-backed outbound queue.
+type Store struct {
+	db  *sql.DB
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+var (
+	defaultStore    *Store
+	defaultStoreErr error
+	defaultOnce     sync.Once
+)
+
+// Default returns the store in ~/.lingti.db, shared with the cron scheduler,
+// opening it on first use.
+func Default() (*Store, error) {
+	defaultOnce.Do(func() {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			defaultStoreErr = fmt.Errorf("failed to find home directory: %w", err)
+			return
+		}
+		defaultStore, defaultStoreErr = NewStore(filepath.Join(home, ".lingti.db"))
+	})
+	return defaultStore, defaultStoreErr
+}
+
+// NewStore opens (or creates) an outbound queue at the given path.
+func NewStore(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Other stores write to the same file; wait for their locks instead of
+	// failing with SQLITE_BUSY
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to set WAL mode: %w", err)
+	}
+
+	s := &Store{db: db, now: time.Now}
+	if err := s.init(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	return s, nil
+}
+
+// init creates the tables if they don't exist
+func (s *Store) init() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS outbox (
+			id           INTEGER PRIMARY KEY AUTOINCREMENT,
+			platform     TEXT NOT NULL,
+			channel_id   TEXT NOT NULL,
+			payload      BLOB NOT NULL,
+			attempts     INTEGER NOT NULL DEFAULT 0,
+			next_attempt TEXT NOT NULL,
+			last_error   TEXT NOT NULL DEFAULT '',
+			created_at   TEXT NOT NULL,
+			dead         INTEGER NOT NULL DEFAULT 0
+		);
+		CREATE INDEX IF NOT EXISTS outbox_channel ON outbox (dead, platform, channel_id, id);
+	`)
+	return err
+}
+
+// Close closes the database connection
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Enqueue adds a message to the end of a channel's queue, due now.
+func (s *Store) Enqueue(platform, channelID string, payload []byte) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now().Format(time.RFC3339Nano)
+	res, err := s.db.Exec("INSERT INTO outbox (platform, channel_id, payload, next_attempt, created_at) VALUES (?, ?, ?, ?, ?)",
+		platform, channelID, payload, now, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to queue message: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// Next returns the oldest pending message of a channel, or nil if there is
+// none. It may not be due yet; the caller waits for NextAttempt.
+func (s *Store) Next(platform, channelID string) (*Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items, err := s.query("WHERE dead = 0 AND platform = ? AND channel_id = ? ORDER BY id LIMIT 1", platform, channelID)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+// Channels lists the channels with pending messages.
+func (s *Store) Channels() ([]Channel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.Query("SELECT DISTINCT platform, channel_id FROM outbox WHERE dead = 0 ORDER BY platform, channel_id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query channels: %w", err)
+	}
+	defer rows.Close()
+
+	var channels []Channel
+	for rows.Next() {
+		var c Channel
+		if err := rows.Scan(&c.Platform, &c.ChannelID); err != nil {
+			return nil, err
+		}
+		channels = append(channels, c)
+	}
+	return channels, rows.Err()
+}
+
+// Done removes a delivered message.
+func (s *Store) Done(id int64) error {
+	return s.exec("DELETE FROM outbox WHERE id = ?", id)
+}
+
+// Retry records a failed attempt and schedules the next one.
+func (s *Store) Retry(id int64, next time.Time, cause error) error {
+	return s.exec("UPDATE outbox SET attempts = attempts + 1, next_attempt = ?, last_error = ? WHERE id = ?",
+		next.Format(time.RFC3339Nano), cause.Error(), id)
+}
+
+// Kill records a final failed attempt and makes the message a dead letter.
+func (s *Store) Kill(id int64, cause error) error {
+	return s.exec("UPDATE outbox SET attempts = attempts + 1, last_error = ?, dead = 1 WHERE id = ?", cause.Error(), id)
+}
+
+// Pending lists the messages waiting to be delivered, oldest first.
+func (s *Store) Pending() ([]Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.query("WHERE dead = 0 ORDER BY id")
+}
+
+// Dead lists the dead letters, oldest first.
+func (s *Store) Dead() ([]Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.query("WHERE dead = 1 ORDER BY id")
+}
+
+// Get returns a message by ID, or nil if it does not exist.
+func (s *Store) Get(id int64) (*Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items, err := s.query("WHERE id = ?", id)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+// Requeue gives a dead letter a fresh set of attempts, due now. It reports
+// whether there was such a dead letter.
+func (s *Store) Requeue(id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res, err := s.db.Exec("UPDATE outbox SET attempts = 0, next_attempt = ?, dead = 0 WHERE id = ? AND dead = 1",
+		s.now().Format(time.RFC3339Nano), id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// Delete drops a message, pending or dead. It reports whether it existed.
+func (s *Store) Delete(id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res, err := s.db.Exec("DELETE FROM outbox WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// PurgeDead drops every dead letter and returns how many there were.
+func (s *Store) PurgeDead() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res, err := s.db.Exec("DELETE FROM outbox WHERE dead = 1")
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+func (s *Store) exec(query string, args ...any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.db.Exec(query, args...)
+	return err
+}
+
+func (s *Store) query(where string, args ...any) ([]Item, error) {
+	rows, err := s.db.Query(`SELECT id, platform, channel_id, payload, attempts, next_attempt, last_error, created_at, dead
+		FROM outbox `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox: %w", err)
+	}
+	defer rows.Close()
+
+	var items []Item
+	for rows.Next() {
+		var it Item
+		var next, created string
+		if err := rows.Scan(&it.ID, &it.Platform, &it.ChannelID, &it.Payload, &it.Attempts,
+			&next, &it.LastError, &created, &it.Dead); err != nil {
+			return nil, err
+		}
+		it.NextAttempt, _ = time.Parse(time.RFC3339Nano, next)
+		it.CreatedAt, _ = time.Parse(time.RFC3339Nano, created)
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,100 @@
+package outbox
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := NewStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestStore_ChannelOrder(t *testing.T) {
+	s := newTestStore(t)
+	for _, m := range []struct{ channel, text string }{
+		{"c1", "first"}, {"c2", "other"}, {"c1", "second"},
+	} {
+		if _, err := s.Enqueue("slack", m.channel, []byte(m.text)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	channels, err := s.Channels()
+	if err != nil || len(channels) != 2 || channels[0] != (Channel{"slack", "c1"}) {
+		t.Fatalf("Channels() = %v, %v", channels, err)
+	}
+
+	var got []string
+	for {
+		it, err := s.Next("slack", "c1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if it == nil {
+			break
+		}
+		got = append(got, string(it.Payload))
+		if err := s.Done(it.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(got) != 2 || got[0] != "first" || got[1] != "second" {
+		t.Errorf("c1 delivered %v, want [first second]", got)
+	}
+}
+
+func TestStore_RetryAndDeadLetter(t *testing.T) {
+	s := newTestStore(t)
+	id, err := s.Enqueue("telegram", "42", []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Enqueue("telegram", "42", []byte("behind"))
+
+	next := time.Now().Add(time.Minute).Truncate(time.Second)
+	if err := s.Retry(id, next, errors.New("timeout")); err != nil {
+		t.Fatal(err)
+	}
+	it, _ := s.Next("telegram", "42")
+	if it.ID != id || it.Attempts != 1 || it.LastError != "timeout" || !it.NextAttempt.Equal(next) {
+		t.Fatalf("after Retry: %+v", it)
+	}
+
+	// A dead letter stops blocking its channel.
+	if err := s.Kill(id, errors.New("chat not found")); err != nil {
+		t.Fatal(err)
+	}
+	if it, _ := s.Next("telegram", "42"); it == nil || string(it.Payload) != "behind" {
+		t.Fatalf("Next after Kill = %+v", it)
+	}
+	dead, err := s.Dead()
+	if err != nil || len(dead) != 1 || dead[0].Attempts != 2 || dead[0].LastError != "chat not found" {
+		t.Fatalf("Dead() = %+v, %v", dead, err)
+	}
+
+	if ok, err := s.Requeue(id); !ok || err != nil {
+		t.Fatalf("Requeue() = %v, %v", ok, err)
+	}
+	if it, _ := s.Next("telegram", "42"); it.ID != id || it.Attempts != 0 {
+		t.Errorf("requeued message is not first: %+v", it)
+	}
+	if ok, _ := s.Requeue(id); ok {
+		t.Error("Requeue succeeded on a pending message")
+	}
+
+	s.Kill(id, errors.New("again"))
+	if n, err := s.PurgeDead(); n != 1 || err != nil {
+		t.Errorf("PurgeDead() = %d, %v", n, err)
+	}
+	if it, _ := s.Get(id); it != nil {
+		t.Error("purged message still exists")
+	}
+}
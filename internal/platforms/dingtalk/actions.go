@@ -0,0 +1,77 @@
+package dingtalk
+
+import (
+	"net/url"
+
+	"github.com/pltanton/lingti-bot/internal/markdown"
+	"github.com/pltanton/lingti-bot/internal/router"
+)
+
+// DingTalk has no callbacks for buttons in session webhook replies. Instead
+// each button is a link that makes the client send its value as a message
+// from the user, and the platform remembers which values it offered in each
+// conversation to recognise the answer.
+
+// actionCard builds an actionCard reply body. Options of a select become
+// buttons of their own.
+func actionCard(text string, actions []router.Action) map[string]interface{} {
+	var btns []map[string]string
+	for _, a := range actions {
+		switch {
+		case a.Kind == router.ActionSelect:
+			for _, o := range a.Options {
+				btns = append(btns, map[string]string{"title": o.Label, "actionURL": sendMessageURL(o.Value)})
+			}
+		case a.URL != "":
+			btns = append(btns, map[string]string{"title": a.Label, "actionURL": a.URL})
+		default:
+			btns = append(btns, map[string]string{"title": a.Label, "actionURL": sendMessageURL(a.Value)})
+		}
+	}
+	return map[string]interface{}{
+		"msgtype": "actionCard",
+		"actionCard": map[string]interface{}{
+			"title":          markdown.Title(text, 20),
+			"text":           markdown.DingTalk(text),
+			"btnOrientation": "0",
+			"btns":           btns,
+		},
+	}
+}
+
+// sendMessageURL returns a link that sends text as the user when tapped.
+func sendMessageURL(text string) string {
+	return "dtmd://dingtalkclient/sendMessage?content=" + url.QueryEscape(text)
+}
+
+// offerActions remembers the values offered in a conversation, replacing
+// any earlier offer.
+func (p *Platform) offerActions(conversationID string, actions []router.Action) {
+	offered := make(map[string]string)
+	for _, a := range actions {
+		switch {
+		case a.Kind == router.ActionSelect:
+			for _, o := range a.Options {
+				offered[o.Value] = a.ID
+			}
+		case a.URL == "":
+			offered[a.Value] = a.ID
+		}
+	}
+	p.mu.Lock()
+	p.offers[conversationID] = offered
+	p.mu.Unlock()
+}
+
+// takeAction reports whether text answers the last offer in a conversation.
+// A matching answer consumes the offer.
+func (p *Platform) takeAction(conversationID, text string) (*router.ActionEvent, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	id, ok := p.offers[conversationID][text]
+	if !ok {
+		return nil, false
+	}
+	delete(p.offers, conversationID)
+	return &router.ActionEvent{ID: id, Value: text}, true
+}
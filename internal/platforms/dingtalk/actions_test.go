@@ -0,0 +1,49 @@
+package dingtalk
+
+import (
+	"testing"
+
+	"github.com/pltanton/lingti-bot/internal/router"
+)
+
+func TestActionOffers(t *testing.T) {
+	p := &Platform{offers: make(map[string]map[string]string)}
+	p.offerActions("c1", router.NormalizeActions([]router.Action{
+		{ID: "approve", Label: "批准", Value: "yes"},
+		{Label: "文档", URL: "https://example.com"},
+		{Kind: router.ActionSelect, ID: "env", Label: "环境", Options: []router.ActionOption{{Label: "prod"}}},
+	}))
+
+	tests := []struct {
+		conversation, text string
+		wantID             string
+	}{
+		{"c2", "yes", ""},
+		{"c1", "文档", ""},
+		{"c1", "prod", "env"},
+		{"c1", "yes", ""}, // the offer was consumed
+	}
+	for _, tt := range tests {
+		action, ok := p.takeAction(tt.conversation, tt.text)
+		if ok != (tt.wantID != "") || (ok && (action.ID != tt.wantID || action.Value != tt.text)) {
+			t.Errorf("takeAction(%q, %q) = %+v, %v; want ID %q", tt.conversation, tt.text, action, ok, tt.wantID)
+		}
+	}
+}
+
+func TestActionCard(t *testing.T) {
+	body := actionCard("要部署吗？", []router.Action{
+		{Label: "批准", Value: "批准 部署"},
+		{Label: "文档", URL: "https://example.com"},
+	})
+	btns := body["actionCard"].(map[string]interface{})["btns"].([]map[string]string)
+	if len(btns) != 2 {
+		t.Fatalf("btns = %+v", btns)
+	}
+	if got := btns[0]["actionURL"]; got != "dtmd://dingtalkclient/sendMessage?content=%E6%89%B9%E5%87%86+%E9%83%A8%E7%BD%B2" {
+		t.Errorf("button URL = %q", got)
+	}
+	if got := btns[1]["actionURL"]; got != "https://example.com" {
+		t.Errorf("link URL = %q", got)
+	}
+}
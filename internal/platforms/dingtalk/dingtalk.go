@@ -9,6 +9,7 @@ import (
 
 	"github.com/open-dingtalk/dingtalk-stream-sdk-go/chatbot"
 	"github.com/open-dingtalk/dingtalk-stream-sdk-go/client"
+	"github.com/pltanton/lingti-bot/internal/markdown"
 	"github.com/pltanton/lingti-bot/internal/router"
 )
 
@@ -16,7 +17,8 @@ import (
 type Platform struct {
 	cli            *client.StreamClient
 	messageHandler func(msg router.Message)
-	webhooks       map[string]string // conversationID -> sessionWebhook
+	webhooks       map[string]string            // conversationID -> sessionWebhook
+	offers         map[string]map[string]string // conversationID -> offered value -> action ID
 	mu             sync.RWMutex
 	ctx            context.Context
 	cancel         context.CancelFunc
@@ -36,6 +38,7 @@ func New(cfg Config) (*Platform, error) {
 
 	p := &Platform{
 		webhooks: make(map[string]string),
+		offers:   make(map[string]map[string]string),
 	}
 
 	// Create stream client
@@ -55,6 +58,16 @@ func (p *Platform) Name() string {
 	return "dingtalk"
 }
 
+// Capabilities returns the platform's delivery limits
+func (p *Platform) Capabilities() router.Capabilities {
+	// DingTalk markdown replies are limited to about 5000 characters
+	return router.Capabilities{
+		MaxMessageLength: 5000,
+		SupportsActions:  true,
+		Render:           markdown.DingTalk,
+	}
+}
+
 // SetMessageHandler sets the callback for incoming messages
 func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 	p.messageHandler = handler
@@ -103,7 +116,12 @@ func (p *Platform) Send(ctx context.Context, channelID string, resp router.Respo
 	}
 
 	replier := chatbot.NewChatbotReplier()
-	return replier.SimpleReplyText(ctx, sessionWebhook, []byte(resp.Text))
+	if len(resp.Actions) > 0 {
+		p.offerActions(channelID, resp.Actions)
+		return replier.ReplyMessage(ctx, sessionWebhook, actionCard(resp.Text, resp.Actions))
+	}
+	title := markdown.Title(resp.Text, 20)
+	return replier.SimpleReplyMarkdown(ctx, sessionWebhook, []byte(title), []byte(markdown.DingTalk(resp.Text)))
 }
 
 // onChatBotMessageReceived handles incoming chatbot messages
@@ -118,14 +136,17 @@ func (p *Platform) onChatBotMessageReceived(ctx context.Context, data *chatbot.B
 		return []byte(""), nil
 	}
 
+	// Clean @mention from text
+	text = p.cleanMention(text)
+
+	// Button presses arrive as plain messages without an @mention
+	action, isAction := p.takeAction(data.ConversationId, text)
+
 	// Check if we should respond
-	if !p.shouldRespond(data) {
+	if !isAction && !p.shouldRespond(data) {
 		return []byte(""), nil
 	}
 
-	// Clean @mention from text
-	text = p.cleanMention(text)
-
 	// Store session webhook for later use in Send()
 	p.mu.Lock()
 	p.webhooks[data.ConversationId] = data.SessionWebhook
@@ -141,6 +162,7 @@ func (p *Platform) onChatBotMessageReceived(ctx context.Context, data *chatbot.B
 			Username:  data.SenderNick,
 			Text:      text,
 			ThreadID:  "",
+			Action:    action,
 			Metadata: map[string]string{
 				"conversation_type":  data.ConversationType, // "1" = private, "2" = group
 				"session_webhook":    data.SessionWebhook,
@@ -0,0 +1,130 @@
+package discord
+
+import (
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pltanton/lingti-bot/internal/router"
+)
+
+// Discord component limits
+const (
+	maxCustomID      = 100
+	maxRows          = 5
+	buttonsPerRow    = 5
+	maxSelectOptions = 25
+)
+
+// components renders actions as message components: buttons in rows of
+// five, and each select menu in a row of its own.
+func components(actions []router.Action) []discordgo.MessageComponent {
+	var rows []discordgo.MessageComponent
+	var row []discordgo.MessageComponent
+	flush := func() {
+		if len(row) > 0 {
+			rows = append(rows, discordgo.ActionsRow{Components: row})
+			row = nil
+		}
+	}
+	for _, a := range actions {
+		switch {
+		case a.Kind == router.ActionSelect:
+			flush()
+			menu := discordgo.SelectMenu{
+				MenuType:    discordgo.StringSelectMenu,
+				CustomID:    router.EncodeActionPayload(a.ID, "", maxCustomID),
+				Placeholder: a.Label,
+			}
+			for i, o := range a.Options {
+				if i == maxSelectOptions {
+					break
+				}
+				menu.Options = append(menu.Options, discordgo.SelectMenuOption{Label: o.Label, Value: o.Value})
+			}
+			rows = append(rows, discordgo.ActionsRow{Components: []discordgo.MessageComponent{menu}})
+		case a.URL != "":
+			row = append(row, discordgo.Button{Label: a.Label, Style: discordgo.LinkButton, URL: a.URL})
+		default:
+			style := discordgo.SecondaryButton
+			switch a.Style {
+			case "primary":
+				style = discordgo.PrimaryButton
+			case "danger":
+				style = discordgo.DangerButton
+			}
+			row = append(row, discordgo.Button{
+				Label:    a.Label,
+				Style:    style,
+				CustomID: router.EncodeActionPayload(a.ID, a.Value, maxCustomID),
+			})
+		}
+		if len(row) == buttonsPerRow {
+			flush()
+		}
+	}
+	flush()
+	if len(rows) > maxRows {
+		log.Printf("[Discord] Dropping action rows beyond %d", maxRows)
+		rows = rows[:maxRows]
+	}
+	return rows
+}
+
+// handleInteraction turns a press on one of our components into a message.
+// The components are removed from the message in the same response, so the
+// choice cannot be made twice.
+func (p *Platform) handleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionMessageComponent {
+		return
+	}
+	data := i.MessageComponentData()
+	id, value, ok := router.DecodeActionPayload(data.CustomID)
+	if ok && len(data.Values) > 0 {
+		value = data.Values[0]
+	}
+
+	resp := &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{Components: []discordgo.MessageComponent{}},
+	}
+	if !ok {
+		resp = &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Content: "该操作已过期", Flags: discordgo.MessageFlagsEphemeral},
+		}
+	}
+	if err := s.InteractionRespond(i.Interaction, resp); err != nil {
+		log.Printf("[Discord] Failed to respond to interaction: %v", err)
+	}
+	if !ok || p.messageHandler == nil {
+		return
+	}
+
+	user := i.User
+	if i.Member != nil {
+		user = i.Member.User
+	}
+	if user == nil {
+		return
+	}
+	messageID := ""
+	if i.Message != nil {
+		messageID = i.Message.ID
+	}
+	p.messageHandler(router.Message{
+		ID:        i.ID,
+		Platform:  "discord",
+		ChannelID: i.ChannelID,
+		UserID:    user.ID,
+		Username:  user.Username,
+		Text:      value,
+		Action: &router.ActionEvent{
+			ID:        id,
+			Value:     value,
+			MessageID: messageID,
+		},
+		Metadata: map[string]string{
+			"guild_id": i.GuildID,
+		},
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/pltanton/lingti-bot/internal/markdown"
 	"github.com/pltanton/lingti-bot/internal/router"
 )
 
@@ -50,6 +51,18 @@ func (p *Platform) Name() string {
 	return "discord"
 }
 
+// Capabilities returns the platform's delivery limits
+func (p *Platform) Capabilities() router.Capabilities {
+	// Discord rejects message content over 2000 characters
+	return router.Capabilities{
+		MaxMessageLength: 2000,
+		SupportsEdit:     true,
+		SupportsThreads:  true,
+		SupportsActions:  true,
+		Render:           markdown.Discord,
+	}
+}
+
 // SetMessageHandler sets the callback for incoming messages
 func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 	p.messageHandler = handler
@@ -59,8 +72,9 @@ func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 func (p *Platform) Start(ctx context.Context) error {
 	p.ctx, p.cancel = context.WithCancel(ctx)
 
-	// Add message handler
+	// Add message and component handlers
 	p.session.AddHandler(p.handleMessage)
+	p.session.AddHandler(p.handleInteraction)
 
 	// Open connection
 	if err := p.session.Open(); err != nil {
@@ -97,12 +111,39 @@ func (p *Platform) Send(ctx context.Context, channelID string, resp router.Respo
 	}
 
 	_, err := p.session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
-		Content:   resp.Text,
-		Reference: reference,
+		Content:    markdown.Discord(resp.Text),
+		Reference:  reference,
+		Components: components(resp.Actions),
 	})
 	return err
 }
 
+// SendEditable sends a message and returns its ID for later edits
+func (p *Platform) SendEditable(ctx context.Context, channelID string, resp router.Response) (string, error) {
+	var reference *discordgo.MessageReference
+	if resp.ThreadID != "" {
+		reference = &discordgo.MessageReference{
+			MessageID: resp.ThreadID,
+			ChannelID: channelID,
+		}
+	}
+
+	msg, err := p.session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content:   markdown.Discord(resp.Text),
+		Reference: reference,
+	}, discordgo.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	return msg.ID, nil
+}
+
+// Edit replaces the content of a previously sent message
+func (p *Platform) Edit(ctx context.Context, channelID, messageID string, resp router.Response) error {
+	_, err := p.session.ChannelMessageEdit(channelID, messageID, markdown.Discord(resp.Text), discordgo.WithContext(ctx))
+	return err
+}
+
 // handleMessage processes incoming Discord messages
 func (p *Platform) handleMessage(s *discordgo.Session, m *discordgo.MessageCreate) {
 	// Ignore messages from bots
@@ -190,3 +231,43 @@ func (p *Platform) cleanMention(text string) string {
 	text = strings.ReplaceAll(text, mention2, "")
 	return strings.TrimSpace(text)
 }
+
+// RecentMessages returns recent messages from a guild channel, oldest first.
+// Direct messages return nil since the agent keeps their history.
+func (p *Platform) RecentMessages(ctx context.Context, channelID, threadID string, limit int) ([]router.Message, error) {
+	channel, err := p.session.Channel(channelID, discordgo.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if channel.Type == discordgo.ChannelTypeDM {
+		return nil, nil
+	}
+
+	// Discord caps a page at 100 messages
+	history, err := p.session.ChannelMessages(channelID, min(limit, 100), "", "", "", discordgo.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	// Messages come newest first
+	msgs := make([]router.Message, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		m := history[i]
+		if m.Content == "" || m.Author == nil {
+			continue
+		}
+		username := m.Author.Username
+		if m.Author.ID == p.botUserID {
+			username = "bot"
+		}
+		msgs = append(msgs, router.Message{
+			ID:        m.ID,
+			Platform:  "discord",
+			ChannelID: channelID,
+			UserID:    m.Author.ID,
+			Username:  username,
+			Text:      p.cleanMention(m.Content),
+		})
+	}
+	return msgs, nil
+}
@@ -0,0 +1,114 @@
+package email
+
+import "strings"
+
+// The From address of a mail is whatever the sender wrote. It is only
+// trusted when the receiving mail server vouches for it in an
+// Authentication-Results header (RFC 8601): DMARC passed for the From
+// domain, or a DKIM signature of an aligned domain verified.
+
+// authResult is one result of an Authentication-Results header, such as
+// "dkim=pass header.d=example.com".
+type authResult struct {
+	method string
+	result string
+	props  map[string]string // e.g. "header.d" -> "example.com"
+}
+
+// parseAuthResults splits an Authentication-Results value into the
+// authserv-id of the server that added it and its results.
+func parseAuthResults(v string) (string, []authResult) {
+	parts := strings.Split(stripComments(v), ";")
+	fields := strings.Fields(parts[0]) // authserv-id [version]
+	if len(fields) == 0 {
+		return "", nil
+	}
+	var results []authResult
+	for _, part := range parts[1:] {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		method, result, ok := strings.Cut(fields[0], "=")
+		if !ok {
+			continue // "none"
+		}
+		r := authResult{
+			method: strings.ToLower(method),
+			result: strings.ToLower(result),
+			props:  make(map[string]string),
+		}
+		for _, f := range fields[1:] {
+			if k, val, ok := strings.Cut(f, "="); ok {
+				r.props[strings.ToLower(k)] = strings.ToLower(strings.Trim(val, `"`))
+			}
+		}
+		results = append(results, r)
+	}
+	return strings.ToLower(fields[0]), results
+}
+
+// stripComments removes the (comments) of a header value.
+func stripComments(v string) string {
+	var b strings.Builder
+	depth := 0
+	for _, c := range v {
+		switch {
+		case c == '(':
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// senderVerified reports whether the receiving server vouched for the From
+// address from. headers are the mail's Authentication-Results, topmost
+// first. Anyone can add such a header before the mail reaches the server,
+// so only the server's own counts: the one with authServID or, without it,
+// the topmost, which the server added last.
+func senderVerified(headers []string, authServID, from string) bool {
+	_, domain, _ := strings.Cut(strings.ToLower(from), "@")
+	if domain == "" {
+		return false
+	}
+	for i, h := range headers {
+		if authServID == "" && i > 0 {
+			break
+		}
+		id, results := parseAuthResults(h)
+		if authServID != "" && id != strings.ToLower(authServID) {
+			continue
+		}
+		for _, r := range results {
+			if r.result != "pass" {
+				continue
+			}
+			switch r.method {
+			case "dmarc":
+				if r.props["header.from"] == domain {
+					return true
+				}
+			case "dkim":
+				d := r.props["header.d"]
+				if d == "" {
+					_, d, _ = strings.Cut(r.props["header.i"], "@")
+				}
+				if aligned(domain, d) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// aligned reports whether a DKIM signing domain d is aligned with the From
+// domain: the same domain, a parent or a subdomain of it, as in DMARC's
+// relaxed mode.
+func aligned(domain, d string) bool {
+	return d != "" && (domain == d || strings.HasSuffix(domain, "."+d) || strings.HasSuffix(d, "."+domain))
+}
@@ -0,0 +1,61 @@
+package email
+
+import (
+	"testing"
+
+	"github.com/pltanton/lingti-bot/internal/router"
+)
+
+func TestSenderVerified(t *testing.T) {
+	tests := []struct {
+		name       string
+		headers    []string
+		authServID string
+		from       string
+		want       bool
+	}{
+		{"dmarc pass", []string{"mx.google.com; dmarc=pass (p=REJECT sp=REJECT dis=NONE) header.from=example.com"}, "", "a@example.com", true},
+		{"dmarc for another domain", []string{"mx.google.com; dmarc=pass header.from=evil.test"}, "", "a@example.com", false},
+		{"dkim pass", []string{"mx.google.com;\r\n dkim=pass header.i=@example.com header.s=s1 header.b=abc"}, "", "a@example.com", true},
+		{"dkim of a parent domain", []string{"mx; dkim=pass header.d=example.com"}, "", "a@eu.example.com", true},
+		{"dkim of an unrelated domain", []string{"mx; dkim=pass header.d=sendgrid.net"}, "", "a@example.com", false},
+		{"dkim of a look-alike domain", []string{"mx; dkim=pass header.d=badexample.com"}, "", "a@example.com", false},
+		{"spf only", []string{"mx; spf=pass smtp.mailfrom=example.com"}, "", "a@example.com", false},
+		{"dkim fail", []string{"mx; dkim=fail header.d=example.com; dmarc=fail header.from=example.com"}, "", "a@example.com", false},
+		{"pass inside a comment", []string{"mx; dkim=none (dkim=pass header.d=example.com)"}, "", "a@example.com", false},
+		{"no header", nil, "", "a@example.com", false},
+		{"forged header below the server's", []string{"mx; dmarc=fail header.from=example.com", "mx; dmarc=pass header.from=example.com"}, "", "a@example.com", false},
+		{"forged header with another id", []string{"evil.test; dmarc=pass header.from=example.com", "mx.google.com; dmarc=fail header.from=example.com"}, "mx.google.com", "a@example.com", false},
+		{"server's header below others", []string{"relay.example.net; dkim=fail", "MX.Google.com 1; dmarc=pass header.from=example.com"}, "mx.google.com", "a@example.com", true},
+	}
+	for _, tt := range tests {
+		if got := senderVerified(tt.headers, tt.authServID, tt.from); got != tt.want {
+			t.Errorf("%s: senderVerified() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseMail_AuthResultsOrder(t *testing.T) {
+	m, err := parseMail([]byte("Authentication-Results: top; dmarc=pass header.from=example.com\r\n" +
+		"Authentication-Results: bottom; dmarc=fail header.from=example.com\r\n" +
+		"From: a@example.com\r\n\r\nhi\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.AuthResults) != 2 || m.AuthResults[0][:4] != "top;" {
+		t.Errorf("AuthResults = %q", m.AuthResults)
+	}
+}
+
+func TestHandleMail_AllowUnverified(t *testing.T) {
+	raw := []byte("From: a@example.com\r\nMessage-ID: <u1@example.com>\r\n\r\nhi\r\n")
+	for _, allow := range []bool{false, true} {
+		p := &Platform{config: Config{Address: "bot@example.org", AllowedSenders: []string{"*"}, AllowUnverified: allow}}
+		var got []router.Message
+		p.SetMessageHandler(func(msg router.Message) { got = append(got, msg) })
+		p.handleMail(1, raw)
+		if len(got) == 1 != allow {
+			t.Errorf("AllowUnverified=%v: handled %d messages", allow, len(got))
+		}
+	}
+}
@@ -0,0 +1,234 @@
+// Package email connects the bot to a mailbox: it reads new mail over IMAP,
+// waiting with IDLE where the server supports it, and replies over SMTP in
+// the same thread.
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-sasl"
+	"github.com/emersion/go-smtp"
+	"github.com/pltanton/lingti-bot/internal/router"
+	"github.com/pltanton/lingti-bot/internal/speech"
+)
+
+// Platform implements router.Platform for email
+type Platform struct {
+	config         Config
+	messageHandler func(msg router.Message)
+	now            func() time.Time
+	ctx            context.Context
+	cancel         context.CancelFunc
+	done           chan struct{}
+}
+
+// Config holds email configuration
+type Config struct {
+	Address        string        // Bot mailbox address, used as From
+	Username       string        // IMAP/SMTP login (defaults to Address)
+	Password       string        // IMAP/SMTP password or app password
+	IMAPServer     string        // host:port; port 993 uses TLS, others STARTTLS
+	SMTPServer     string        // host:port; port 465 uses TLS, others STARTTLS
+	Mailbox        string        // Folder to watch (default INBOX)
+	PollInterval   time.Duration // How often to check for mail besides IDLE (default 1m)
+	AllowedSenders []string      // Addresses or @domains the bot answers; "*" answers anyone
+	AttachmentDir  string        // Where incoming attachments are saved (default: temp dir)
+
+	// AuthServID names the receiving server in its Authentication-Results
+	// headers, e.g. mx.google.com (default: trust the topmost header).
+	AuthServID string
+	// AllowUnverified answers mail whose sender the server did not verify
+	// with DMARC or DKIM. Anyone can then write as an allowed sender.
+	AllowUnverified bool
+}
+
+// New creates a new email platform
+func New(cfg Config) (*Platform, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("email address is required")
+	}
+	if cfg.Password == "" {
+		return nil, fmt.Errorf("email password is required")
+	}
+	if cfg.IMAPServer == "" || cfg.SMTPServer == "" {
+		return nil, fmt.Errorf("email IMAP and SMTP servers are required")
+	}
+	if len(cfg.AllowedSenders) == 0 {
+		return nil, fmt.Errorf("email allowed senders are required (use \"*\" to answer anyone)")
+	}
+	if cfg.Username == "" {
+		cfg.Username = cfg.Address
+	}
+	if cfg.Mailbox == "" {
+		cfg.Mailbox = "INBOX"
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Minute
+	}
+	if cfg.AttachmentDir == "" {
+		cfg.AttachmentDir = filepath.Join(os.TempDir(), "lingti-bot", "email")
+	}
+	cfg.IMAPServer = withPort(cfg.IMAPServer, "993")
+	cfg.SMTPServer = withPort(cfg.SMTPServer, "465")
+
+	return &Platform{config: cfg, now: time.Now}, nil
+}
+
+// Name returns the platform name
+func (p *Platform) Name() string {
+	return "email"
+}
+
+// Capabilities returns the platform's delivery limits
+func (p *Platform) Capabilities() router.Capabilities {
+	// One reply is one mail: no splitting, and no progress mails in between
+	return router.Capabilities{
+		SupportsThreads: true,
+		SupportsFiles:   true,
+		NoProgress:      true,
+	}
+}
+
+// SetMessageHandler sets the callback for incoming messages
+func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
+	p.messageHandler = handler
+}
+
+// Start logs in to the mailbox and starts watching it for new mail
+func (p *Platform) Start(ctx context.Context) error {
+	c, err := p.connect()
+	if err != nil {
+		return err
+	}
+
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	p.done = make(chan struct{})
+	go p.run(c)
+
+	log.Printf("[Email] Watching %s/%s as %s", p.config.IMAPServer, p.config.Mailbox, p.config.Address)
+	return nil
+}
+
+// Stop closes the mailbox connection
+func (p *Platform) Stop() error {
+	if p.cancel != nil {
+		p.cancel()
+		<-p.done
+	}
+	return nil
+}
+
+// Send mails a response to channelID, the sender's address. Responses to a
+// received mail carry its Message-ID and subject in Metadata and are sent as
+// replies in the same thread.
+func (p *Platform) Send(ctx context.Context, channelID string, resp router.Response) error {
+	if resp.Text == "" && len(resp.Files) == 0 {
+		return nil
+	}
+	raw, err := p.compose(channelID, resp)
+	if err != nil {
+		return err
+	}
+	return p.sendMail(ctx, channelID, raw)
+}
+
+// DownloadMedia returns an attachment of an incoming mail, so voice
+// attachments can be transcribed.
+func (p *Platform) DownloadMedia(ctx context.Context, msg router.Message) (speech.Audio, error) {
+	data, err := os.ReadFile(msg.MediaID)
+	if err != nil {
+		return speech.Audio{}, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	format := strings.TrimPrefix(filepath.Ext(msg.MediaID), ".")
+	return speech.Audio{Data: data, Format: format}, nil
+}
+
+// allowed reports whether mail from addr should be answered.
+func (p *Platform) allowed(addr string) bool {
+	addr = strings.ToLower(addr)
+	if addr == strings.ToLower(p.config.Address) {
+		return false
+	}
+	_, domain, _ := strings.Cut(addr, "@")
+	for _, s := range p.config.AllowedSenders {
+		s = strings.ToLower(strings.TrimSpace(s))
+		switch {
+		case s == "*", s == addr:
+			return true
+		case strings.HasPrefix(s, "@") && s[1:] == domain:
+			return true
+		}
+	}
+	return false
+}
+
+// sendMail delivers a composed mail over SMTP.
+func (p *Platform) sendMail(ctx context.Context, to string, raw []byte) error {
+	addr := p.config.SMTPServer
+	host, port, _ := net.SplitHostPort(addr)
+	tlsConfig := &tls.Config{ServerName: host}
+
+	dialer := net.Dialer{Timeout: 30 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	var c *smtp.Client
+	switch {
+	case port == "465":
+		c = smtp.NewClient(tls.Client(conn, tlsConfig))
+	case isLoopback(host):
+		c = smtp.NewClient(conn)
+	default:
+		if c, err = smtp.NewClientStartTLS(conn, tlsConfig); err != nil {
+			conn.Close()
+			return fmt.Errorf("SMTP STARTTLS failed: %w", err)
+		}
+	}
+	defer c.Close()
+
+	switch {
+	case c.SupportsAuth(sasl.Plain):
+		err = c.Auth(sasl.NewPlainClient("", p.config.Username, p.config.Password))
+	case c.SupportsAuth(sasl.Login):
+		err = c.Auth(sasl.NewLoginClient(p.config.Username, p.config.Password))
+	}
+	if err != nil {
+		return fmt.Errorf("SMTP login failed: %w", err)
+	}
+	if err := c.SendMail(p.config.Address, []string{to}, bytes.NewReader(raw)); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return c.Quit()
+}
+
+// withPort adds the default port to a server address without one.
+func withPort(addr, port string) string {
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr
+	}
+	return net.JoinHostPort(addr, port)
+}
+
+// isLoopback reports whether host is this machine, where a server without
+// TLS is acceptable.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,345 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/backend/memory"
+	imapserver "github.com/emersion/go-imap/server"
+	"github.com/emersion/go-message/mail"
+	"github.com/emersion/go-sasl"
+	"github.com/emersion/go-smtp"
+	"github.com/pltanton/lingti-bot/internal/router"
+)
+
+// fakeIMAP serves the in-memory backend's INBOX, logged in as
+// username/password.
+func fakeIMAP(t *testing.T, mails ...string) (addr string, inbox *memory.Mailbox) {
+	t.Helper()
+	be := memory.New()
+	user, _ := be.Login(nil, "username", "password")
+	mbox, _ := user.GetMailbox("INBOX")
+	inbox = mbox.(*memory.Mailbox)
+	for _, m := range mails {
+		m = strings.ReplaceAll(m, "\n", "\r\n")
+		if err := inbox.CreateMessage(nil, time.Now(), bytes.NewBufferString(m)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srv := imapserver.New(be)
+	srv.AllowInsecureAuth = true
+	srv.ErrorLog = log.New(io.Discard, "", 0)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+	return l.Addr().String(), inbox
+}
+
+// fakeSMTP records the mails it accepts from username/password.
+type fakeSMTP struct {
+	mu    sync.Mutex
+	mails []sentMail
+}
+
+type sentMail struct {
+	from string
+	to   []string
+	data []byte
+}
+
+func (f *fakeSMTP) NewSession(*smtp.Conn) (smtp.Session, error) {
+	return &smtpSession{server: f}, nil
+}
+
+func (f *fakeSMTP) sent() []sentMail {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]sentMail(nil), f.mails...)
+}
+
+type smtpSession struct {
+	server *fakeSMTP
+	authed bool
+	mail   sentMail
+}
+
+func (s *smtpSession) AuthMechanisms() []string { return []string{sasl.Plain} }
+
+func (s *smtpSession) Auth(mech string) (sasl.Server, error) {
+	return sasl.NewPlainServer(func(identity, username, password string) error {
+		if username != "username" || password != "password" {
+			return errors.New("invalid credentials")
+		}
+		s.authed = true
+		return nil
+	}), nil
+}
+
+func (s *smtpSession) Mail(from string, opts *smtp.MailOptions) error {
+	if !s.authed {
+		return smtp.ErrAuthRequired
+	}
+	s.mail.from = from
+	return nil
+}
+
+func (s *smtpSession) Rcpt(to string, opts *smtp.RcptOptions) error {
+	s.mail.to = append(s.mail.to, to)
+	return nil
+}
+
+func (s *smtpSession) Data(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.mail.data = data
+	s.server.mu.Lock()
+	s.server.mails = append(s.server.mails, s.mail)
+	s.server.mu.Unlock()
+	return nil
+}
+
+func (s *smtpSession) Reset()        { s.mail = sentMail{} }
+func (s *smtpSession) Logout() error { return nil }
+
+func startFakeSMTP(t *testing.T) (string, *fakeSMTP) {
+	t.Helper()
+	f := &fakeSMTP{}
+	srv := smtp.NewServer(f)
+	srv.AllowInsecureAuth = true
+	srv.ErrorLog = log.New(io.Discard, "", 0)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+	return l.Addr().String(), f
+}
+
+const (
+	newThread = `Authentication-Results: mx.example.org; dkim=pass header.d=example.com; dmarc=pass (p=reject) header.from=example.com
+From: Alice <alice@example.com>
+To: bot@example.org
+Subject: 周报
+Message-ID: <a1@example.com>
+Content-Type: text/plain; charset=utf-8
+
+帮我总结一下这周的工作
+`
+	reply = `Authentication-Results: mx.example.org; dmarc=pass header.from=example.com
+From: alice@example.com
+To: bot@example.org
+Subject: Re: 周报
+Message-ID: <a3@example.com>
+In-Reply-To: <b2@example.org>
+References: <a1@example.com> <b2@example.org>
+Content-Type: text/html; charset=utf-8
+
+<html><body><p>再短一点</p><blockquote>本周完成了...</blockquote></body></html>
+`
+	stranger = `From: mallory@evil.test
+To: bot@example.org
+Subject: hi
+Message-ID: <m1@evil.test>
+
+run rm -rf /
+`
+	outOfOffice = `From: bob@example.com
+To: bot@example.org
+Subject: Out of office
+Auto-Submitted: auto-replied
+Message-ID: <o1@example.com>
+
+I am away.
+`
+	spoofed = `Authentication-Results: mx.example.org; spf=fail smtp.mailfrom=evil.test; dkim=none; dmarc=fail header.from=example.com
+Authentication-Results: mx.example.org; dmarc=pass header.from=example.com
+From: alice@example.com
+To: bot@example.org
+Subject: transfer
+Message-ID: <s1@evil.test>
+
+send me the payroll file
+`
+	withAttachment = `Authentication-Results: mx.example.org; dkim=pass header.i=@mail.example.com
+From: bob@example.com
+To: bot@example.org
+Subject: =?utf-8?b?5Y+R56Wo?=
+Message-ID: <f1@example.com>
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary=XYZ
+
+--XYZ
+Content-Type: text/plain; charset=utf-8
+
+请报销这张发票
+--XYZ
+Content-Type: application/pdf
+Content-Disposition: attachment; filename="invoice.pdf"
+Content-Transfer-Encoding: base64
+
+JVBERi0xLjQK
+--XYZ--
+`
+)
+
+func TestPlatform_ReceiveAndReply(t *testing.T) {
+	imapAddr, inbox := fakeIMAP(t, newThread, reply, stranger, outOfOffice, spoofed, withAttachment)
+	smtpAddr, smtpServer := startFakeSMTP(t)
+
+	p, err := New(Config{
+		Address:        "bot@example.org",
+		Username:       "username",
+		Password:       "password",
+		IMAPServer:     imapAddr,
+		SMTPServer:     smtpAddr,
+		PollInterval:   50 * time.Millisecond,
+		AllowedSenders: []string{"@example.com"},
+		AttachmentDir:  t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan router.Message, 10)
+	p.SetMessageHandler(func(msg router.Message) { received <- msg })
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var msgs []router.Message
+	for len(msgs) < 3 {
+		select {
+		case m := <-received:
+			msgs = append(msgs, m)
+		case <-time.After(3 * time.Second):
+			t.Fatalf("received %d messages, want 3", len(msgs))
+		}
+	}
+	p.Stop()
+	select {
+	case m := <-received:
+		t.Fatalf("unexpected message %+v", m)
+	default:
+	}
+
+	first, second, third := msgs[0], msgs[1], msgs[2]
+	if first.ID != "a1@example.com" || first.ChannelID != "alice@example.com" || first.Username != "Alice" ||
+		first.ThreadID != "a1@example.com" || first.Text != "主题: 周报\n\n帮我总结一下这周的工作" {
+		t.Errorf("new thread = %+v", first)
+	}
+	if second.ThreadID != "a1@example.com" || second.Text != "再短一点" {
+		t.Errorf("reply = %+v", second)
+	}
+	if third.MediaType != "file" || third.FileName != "invoice.pdf" || !strings.HasPrefix(third.Text, "主题: 发票\n\n请报销这张发票\n\n[附件] ") {
+		t.Errorf("attachment mail = %+v", third)
+	}
+	if data, err := os.ReadFile(third.MediaID); err != nil || !strings.HasPrefix(string(data), "%PDF") {
+		t.Errorf("saved attachment = %q, %v", data, err)
+	}
+
+	// Everything was read, including the mails that were ignored
+	for _, m := range inbox.Messages {
+		seen := false
+		for _, f := range m.Flags {
+			seen = seen || f == imap.SeenFlag
+		}
+		if !seen {
+			t.Errorf("mail %d was not marked as read", m.Uid)
+		}
+	}
+
+	// Reply in the thread of the second mail, with a file
+	report := filepath.Join(t.TempDir(), "report.csv")
+	os.WriteFile(report, []byte("week,hours\n1,40\n"), 0644)
+	resp := router.Response{
+		Text:     "**本周**：完成 3 项任务",
+		ThreadID: second.ThreadID,
+		Metadata: second.Metadata,
+		Files:    []router.FileAttachment{{Path: report}},
+	}
+	if err := p.Send(context.Background(), second.ChannelID, resp); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := smtpServer.sent()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d mails", len(sent))
+	}
+	if sent[0].from != "bot@example.org" || len(sent[0].to) != 1 || sent[0].to[0] != "alice@example.com" {
+		t.Errorf("envelope = %s -> %v", sent[0].from, sent[0].to)
+	}
+	mr, err := mail.CreateReader(bytes.NewReader(sent[0].data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, _ := mr.Header.Subject()
+	inReplyTo, _ := mr.Header.MsgIDList("In-Reply-To")
+	refs, _ := mr.Header.MsgIDList("References")
+	if subject != "Re: 周报" || strings.Join(inReplyTo, ",") != "a3@example.com" ||
+		strings.Join(refs, ",") != "a1@example.com,b2@example.org,a3@example.com" {
+		t.Errorf("subject %q, In-Reply-To %v, References %v", subject, inReplyTo, refs)
+	}
+	var plain, attached string
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		data, _ := io.ReadAll(part.Body)
+		switch h := part.Header.(type) {
+		case *mail.InlineHeader:
+			if ct, _, _ := h.ContentType(); ct == "text/plain" {
+				plain = string(data)
+			}
+		case *mail.AttachmentHeader:
+			name, _ := h.Filename()
+			attached = name + ":" + string(data)
+		}
+	}
+	if plain != "本周：完成 3 项任务" {
+		t.Errorf("text part = %q", plain)
+	}
+	if attached != "report.csv:week,hours\n1,40\n" {
+		t.Errorf("attachment = %q", attached)
+	}
+}
+
+func TestPlatform_Allowed(t *testing.T) {
+	p := &Platform{config: Config{
+		Address:        "bot@example.org",
+		AllowedSenders: []string{"boss@corp.com", "@example.com"},
+	}}
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"boss@corp.com", true},
+		{"Boss@Corp.com", true},
+		{"intern@corp.com", false},
+		{"anyone@example.com", true},
+		{"someone@sub.example.com", false},
+		{"bot@example.org", false},
+	}
+	for _, tt := range tests {
+		if got := p.allowed(tt.addr); got != tt.want {
+			t.Errorf("allowed(%q) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package email
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+// reconnectDelay is how long to wait before logging in again after the
+// IMAP connection fails.
+const reconnectDelay = 30 * time.Second
+
+// connect logs in to the IMAP server and selects the watched mailbox.
+func (p *Platform) connect() (*client.Client, error) {
+	addr := p.config.IMAPServer
+	host, port, _ := net.SplitHostPort(addr)
+	tlsConfig := &tls.Config{ServerName: host}
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+
+	var c *client.Client
+	var err error
+	if port == "993" {
+		c, err = client.DialWithDialerTLS(dialer, addr, tlsConfig)
+	} else {
+		c, err = client.DialWithDialer(dialer, addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to IMAP server: %w", err)
+	}
+
+	if port != "993" {
+		if ok, _ := c.SupportStartTLS(); ok {
+			err = c.StartTLS(tlsConfig)
+		} else if !isLoopback(host) {
+			err = fmt.Errorf("server does not offer STARTTLS")
+		}
+		if err != nil {
+			c.Logout()
+			return nil, fmt.Errorf("IMAP STARTTLS failed: %w", err)
+		}
+	}
+	if err := c.Login(p.config.Username, p.config.Password); err != nil {
+		c.Logout()
+		return nil, fmt.Errorf("IMAP login failed: %w", err)
+	}
+	if _, err := c.Select(p.config.Mailbox, false); err != nil {
+		c.Logout()
+		return nil, fmt.Errorf("failed to open mailbox %s: %w", p.config.Mailbox, err)
+	}
+	return c, nil
+}
+
+// run watches the mailbox until Stop, logging in again whenever the
+// connection fails.
+func (p *Platform) run(c *client.Client) {
+	defer close(p.done)
+	for {
+		if err := p.watch(c); err != nil {
+			log.Printf("[Email] Mailbox connection failed: %v", err)
+		}
+		c.Logout()
+
+		for c = nil; c == nil; {
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-time.After(reconnectDelay):
+			}
+			var err error
+			if c, err = p.connect(); err != nil {
+				log.Printf("[Email] Reconnect failed: %v", err)
+			}
+		}
+	}
+}
+
+// watch reads new mail, then waits in IDLE until the server reports more
+// or the poll interval passes. It returns nil once the platform stops.
+func (p *Platform) watch(c *client.Client) error {
+	// The client blocks while its update channel is full, so updates are
+	// drained into a single "something changed" signal.
+	updates := make(chan client.Update, 16)
+	changed := make(chan struct{}, 1)
+	c.Updates = updates
+	go func() {
+		for {
+			select {
+			case u := <-updates:
+				if _, ok := u.(*client.MailboxUpdate); ok {
+					select {
+					case changed <- struct{}{}:
+					default:
+					}
+				}
+			case <-c.LoggedOut():
+				return
+			}
+		}
+	}()
+
+	for {
+		if err := p.fetchUnseen(c); err != nil {
+			return err
+		}
+
+		stop := make(chan struct{})
+		idle := make(chan error, 1)
+		go func() {
+			idle <- c.Idle(stop, &client.IdleOptions{PollInterval: p.config.PollInterval})
+		}()
+
+		timer := time.NewTimer(p.config.PollInterval)
+		select {
+		case <-p.ctx.Done():
+		case <-changed:
+		case <-timer.C:
+		case err := <-idle:
+			timer.Stop()
+			if err == nil {
+				err = fmt.Errorf("IDLE ended unexpectedly")
+			}
+			return err
+		}
+		timer.Stop()
+		close(stop)
+		if err := <-idle; err != nil {
+			return err
+		}
+		if p.ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// fetchUnseen hands every unread mail to the message handler and marks it
+// read.
+func (p *Platform) fetchUnseen(c *client.Client) error {
+	criteria := imap.NewSearchCriteria()
+	criteria.WithoutFlags = []string{imap.SeenFlag}
+	uids, err := c.UidSearch(criteria)
+	if err != nil {
+		return fmt.Errorf("failed to search mailbox: %w", err)
+	}
+	if len(uids) == 0 {
+		return nil
+	}
+
+	seqset := new(imap.SeqSet)
+	seqset.AddNum(uids...)
+	section := &imap.BodySectionName{Peek: true}
+	messages := make(chan *imap.Message, len(uids))
+	if err := c.UidFetch(seqset, []imap.FetchItem{imap.FetchUid, section.FetchItem()}, messages); err != nil {
+		return fmt.Errorf("failed to fetch mail: %w", err)
+	}
+
+	done := new(imap.SeqSet)
+	for m := range messages {
+		body := m.GetBody(section)
+		if body == nil {
+			continue
+		}
+		raw, err := io.ReadAll(body)
+		if err != nil {
+			log.Printf("[Email] Failed to read mail %d: %v", m.Uid, err)
+			continue
+		}
+		p.handleMail(m.Uid, raw)
+		done.AddNum(m.Uid)
+	}
+	if done.Empty() {
+		return nil
+	}
+
+	flags := []interface{}{imap.SeenFlag}
+	if err := c.UidStore(done, imap.FormatFlagsOp(imap.AddFlags, true), flags, nil); err != nil {
+		return fmt.Errorf("failed to mark mail as read: %w", err)
+	}
+	return nil
+}
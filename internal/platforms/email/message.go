@@ -0,0 +1,412 @@
+package email
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	_ "github.com/emersion/go-message/charset" // decode GBK, Big5, ISO-8859-x, ... bodies
+	"github.com/emersion/go-message/mail"
+	"github.com/pltanton/lingti-bot/internal/markdown"
+	"github.com/pltanton/lingti-bot/internal/router"
+	"golang.org/x/net/html"
+)
+
+// Metadata keys carried from a received mail to the reply.
+const (
+	metaMessageID  = "message_id"
+	metaSubject    = "subject"
+	metaReferences = "references" // space-separated, oldest first
+)
+
+// maxAttachmentSize caps each saved attachment.
+const maxAttachmentSize = 25 << 20
+
+// inbound is a parsed mail.
+type inbound struct {
+	MessageID   string
+	From        *mail.Address
+	Subject     string
+	InReplyTo   []string
+	References  []string
+	Text        string
+	HTML        string
+	Attachments []attachment
+	Automatic   bool     // sent by an autoresponder or mailing list
+	AuthResults []string // Authentication-Results headers, topmost first
+}
+
+type attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// threadID returns the Message-ID of the mail that started the thread.
+func (m *inbound) threadID() string {
+	switch {
+	case len(m.References) > 0:
+		return m.References[0]
+	case len(m.InReplyTo) > 0:
+		return m.InReplyTo[0]
+	}
+	return m.MessageID
+}
+
+// body returns the new text of the mail, without the quoted conversation.
+func (m *inbound) body() string {
+	text := m.Text
+	if strings.TrimSpace(text) == "" && m.HTML != "" {
+		text = htmlToText(m.HTML)
+	}
+	return stripQuoted(text)
+}
+
+// parseMail reads a raw RFC 5322 mail.
+func parseMail(raw []byte) (*inbound, error) {
+	mr, err := mail.CreateReader(bytes.NewReader(raw))
+	if mr == nil {
+		return nil, err
+	}
+	defer mr.Close()
+
+	m := &inbound{}
+	h := mr.Header
+	m.MessageID, _ = h.MessageID()
+	m.Subject, _ = h.Subject()
+	m.InReplyTo, _ = h.MsgIDList("In-Reply-To")
+	m.References, _ = h.MsgIDList("References")
+	if from, err := h.AddressList("From"); err == nil && len(from) > 0 {
+		m.From = from[0]
+	} else {
+		return nil, fmt.Errorf("mail has no sender")
+	}
+	m.AuthResults = h.Values("Authentication-Results")
+	auto := strings.ToLower(h.Get("Auto-Submitted"))
+	precedence := strings.ToLower(h.Get("Precedence"))
+	m.Automatic = (auto != "" && auto != "no") || precedence == "bulk" || precedence == "list" ||
+		precedence == "junk" || h.Get("List-Id") != ""
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if part == nil {
+			return nil, err
+		}
+		switch ph := part.Header.(type) {
+		case *mail.InlineHeader:
+			ctype, _, _ := ph.ContentType()
+			data, _ := io.ReadAll(io.LimitReader(part.Body, maxAttachmentSize))
+			switch {
+			case ctype == "text/plain" && m.Text == "":
+				m.Text = string(data)
+			case ctype == "text/html" && m.HTML == "":
+				m.HTML = string(data)
+			}
+		case *mail.AttachmentHeader:
+			name, _ := ph.Filename()
+			ctype, _, _ := ph.ContentType()
+			data, err := io.ReadAll(io.LimitReader(part.Body, maxAttachmentSize))
+			if err != nil {
+				continue
+			}
+			m.Attachments = append(m.Attachments, attachment{Name: name, ContentType: ctype, Data: data})
+		}
+	}
+	return m, nil
+}
+
+// handleMail turns a received mail into a router message.
+func (p *Platform) handleMail(uid uint32, raw []byte) {
+	m, err := parseMail(raw)
+	if err != nil {
+		log.Printf("[Email] Skipping unreadable mail %d: %v", uid, err)
+		return
+	}
+	from := strings.ToLower(m.From.Address)
+	if m.Automatic {
+		log.Printf("[Email] Ignoring automatic mail from %s", from)
+		return
+	}
+	if !p.allowed(from) {
+		log.Printf("[Email] Ignoring mail from %s (not in allowed senders)", from)
+		return
+	}
+	if !p.config.AllowUnverified && !senderVerified(m.AuthResults, p.config.AuthServID, from) {
+		log.Printf("[Email] Ignoring mail from %s (sender not verified by DMARC or DKIM)", from)
+		return
+	}
+	if p.messageHandler == nil {
+		return
+	}
+
+	id := m.MessageID
+	if id == "" {
+		id = fmt.Sprintf("uid-%d", uid)
+	}
+	text := m.body()
+	if len(m.InReplyTo) == 0 && len(m.References) == 0 && m.Subject != "" {
+		text = "主题: " + m.Subject + "\n\n" + text
+	}
+
+	msg := router.Message{
+		ID:        id,
+		Platform:  "email",
+		ChannelID: from,
+		UserID:    from,
+		Username:  m.From.Name,
+		ThreadID:  m.threadID(),
+		Metadata: map[string]string{
+			metaMessageID:  m.MessageID,
+			metaSubject:    m.Subject,
+			metaReferences: strings.Join(append(m.References, m.MessageID), " "),
+		},
+	}
+	if msg.Username == "" {
+		msg.Username = from
+	}
+
+	// Attachments are saved so the agent's file tools can open them; the
+	// first one is also the message's media.
+	for _, a := range m.Attachments {
+		path, err := p.saveAttachment(id, a)
+		if err != nil {
+			log.Printf("[Email] Failed to save attachment %q: %v", a.Name, err)
+			continue
+		}
+		text += "\n\n[附件] " + path
+		if msg.MediaID == "" {
+			msg.MediaID = path
+			msg.MediaType = mediaType(a.ContentType)
+			msg.FileName = filepath.Base(path)
+		}
+	}
+	msg.Text = strings.TrimSpace(text)
+	if msg.Text == "" {
+		return
+	}
+
+	p.messageHandler(msg)
+}
+
+// saveAttachment writes an attachment under AttachmentDir, in a directory
+// per mail, and returns its path.
+func (p *Platform) saveAttachment(messageID string, a attachment) (string, error) {
+	sum := sha256.Sum256([]byte(messageID))
+	dir := filepath.Join(p.config.AttachmentDir, hex.EncodeToString(sum[:8]))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	name := filepath.Base(a.Name)
+	if name == "." || name == "/" || name == "" {
+		name = "attachment"
+		if exts, _ := mime.ExtensionsByType(a.ContentType); len(exts) > 0 {
+			name += exts[0]
+		}
+	}
+	path := filepath.Join(dir, name)
+	return path, os.WriteFile(path, a.Data, 0600)
+}
+
+// mediaType maps a MIME type to a router media type.
+func mediaType(contentType string) string {
+	switch {
+	case strings.HasPrefix(contentType, "image/"):
+		return "image"
+	case strings.HasPrefix(contentType, "audio/"):
+		return "voice"
+	case strings.HasPrefix(contentType, "video/"):
+		return "video"
+	}
+	return "file"
+}
+
+// compose builds the mail for a response: Markdown is sent as plain text
+// with an HTML alternative, files as attachments.
+func (p *Platform) compose(to string, resp router.Response) ([]byte, error) {
+	var h mail.Header
+	h.SetDate(p.now())
+	h.SetAddressList("From", []*mail.Address{{Address: p.config.Address}})
+	h.SetAddressList("To", []*mail.Address{{Address: to}})
+	// Keeps autoresponders from answering the bot (RFC 3834)
+	h.Set("Auto-Submitted", "auto-replied")
+
+	_, domain, _ := strings.Cut(p.config.Address, "@")
+	if err := h.GenerateMessageIDWithHostname(domain); err != nil {
+		return nil, err
+	}
+
+	subject := resp.Metadata[metaSubject]
+	if parent := resp.Metadata[metaMessageID]; parent != "" {
+		h.SetMsgIDList("In-Reply-To", []string{parent})
+		h.SetMsgIDList("References", strings.Fields(resp.Metadata[metaReferences]))
+		if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+			subject = "Re: " + subject
+		}
+	} else if subject == "" {
+		subject = markdown.Title(resp.Text, 60)
+	}
+	if subject == "" {
+		subject = "lingti-bot"
+	}
+	h.SetSubject(subject)
+
+	var buf bytes.Buffer
+	mw, err := mail.CreateWriter(&buf, h)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Text != "" {
+		if err := writeText(mw, resp.Text); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range resp.Files {
+		if err := writeAttachment(mw, f); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeText(mw *mail.Writer, text string) error {
+	tw, err := mw.CreateInline()
+	if err != nil {
+		return err
+	}
+	parts := []struct{ ctype, body string }{
+		{"text/plain", markdown.Plain(text)},
+		{"text/html", "<html><body>" + markdown.HTML(text) + "</body></html>"},
+	}
+	for _, part := range parts {
+		var ph mail.InlineHeader
+		ph.SetContentType(part.ctype, map[string]string{"charset": "utf-8"})
+		w, err := tw.CreatePart(ph)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, part.body); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func writeAttachment(mw *mail.Writer, f router.FileAttachment) error {
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read attachment: %w", err)
+	}
+	name := f.Name
+	if name == "" {
+		name = filepath.Base(f.Path)
+	}
+	ctype := mime.TypeByExtension(filepath.Ext(name))
+	if ctype == "" {
+		ctype = "application/octet-stream"
+	}
+
+	var ah mail.AttachmentHeader
+	ah.Set("Content-Type", ctype)
+	ah.SetFilename(name)
+	w, err := mw.CreateAttachment(ah)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// quoteHeaderRe matches the line mail clients put above the quoted mail.
+var quoteHeaderRe = regexp.MustCompile(`^(On .+ wrote:|在.+写道[:：]|-+ ?(Original Message|原始邮件) ?-+|_{10,})$`)
+
+// stripQuoted removes the quoted conversation below a reply.
+func stripQuoted(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var out []string
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if quoteHeaderRe.MatchString(trimmed) {
+			break
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// htmlToText extracts readable text from an HTML body, with a line break
+// per block element.
+func htmlToText(src string) string {
+	doc, err := html.Parse(strings.NewReader(src))
+	if err != nil {
+		return src
+	}
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			// Collapse whitespace as a browser would
+			text := strings.Join(strings.Fields(n.Data), " ")
+			if strings.TrimLeft(n.Data, " \t\r\n") != n.Data {
+				text = " " + text
+			}
+			if strings.TrimRight(n.Data, " \t\r\n") != n.Data {
+				text += " "
+			}
+			sb.WriteString(text)
+			return
+		case html.ElementNode:
+			switch n.Data {
+			case "script", "style", "head", "blockquote":
+				return
+			case "br":
+				sb.WriteString("\n")
+				return
+			case "li":
+				sb.WriteString("\n- ")
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "p", "div", "tr", "ul", "ol", "table", "h1", "h2", "h3", "h4", "h5", "h6":
+				sb.WriteString("\n")
+			}
+		}
+	}
+	walk(doc)
+
+	lines := strings.Split(sb.String(), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(l)
+	}
+	text := strings.Join(lines, "\n")
+	for strings.Contains(text, "\n\n\n") {
+		text = strings.ReplaceAll(text, "\n\n\n", "\n\n")
+	}
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,55 @@
+package email
+
+import "testing"
+
+func TestStripQuoted(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"plain", "hello\r\nworld", "hello\nworld"},
+		{"gmail", "ok, do it\n\nOn Mon, Jan 5, 2026 at 10:00 Bot <bot@example.org> wrote:\n> earlier", "ok, do it"},
+		{"chinese client", "好的\n\n在 2026年1月5日 10:00，Bot 写道：\n> 之前的内容", "好的"},
+		{"qq mail", "收到\n------------------ 原始邮件 ------------------\n发件人: bot", "收到"},
+		{"outlook", "thanks\n________________________________\nFrom: Bot", "thanks"},
+		{"inline quotes", "> question one\nanswer one\n> question two\nanswer two", "answer one\nanswer two"},
+	}
+	for _, tt := range tests {
+		if got := stripQuoted(tt.in); got != tt.want {
+			t.Errorf("%s: stripQuoted() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"<p>first</p><p>second</p>", "first\nsecond"},
+		{"<div>line<br>break</div>", "line\nbreak"},
+		{"<ul><li>one</li><li>two</li></ul>", "- one\n- two"},
+		{"<style>p{color:red}</style><p>styled   text</p>", "styled text"},
+		{"<p>reply</p><blockquote>quoted</blockquote>", "reply"},
+		{"<b>bold</b> and <i>italic</i>", "bold and italic"},
+	}
+	for _, tt := range tests {
+		if got := htmlToText(tt.in); got != tt.want {
+			t.Errorf("htmlToText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestThreadID(t *testing.T) {
+	tests := []struct {
+		m    inbound
+		want string
+	}{
+		{inbound{MessageID: "a"}, "a"},
+		{inbound{MessageID: "b", InReplyTo: []string{"a"}}, "a"},
+		{inbound{MessageID: "c", InReplyTo: []string{"b"}, References: []string{"a", "b"}}, "a"},
+	}
+	for _, tt := range tests {
+		if got := tt.m.threadID(); got != tt.want {
+			t.Errorf("threadID(%+v) = %q, want %q", tt.m, got, tt.want)
+		}
+	}
+}
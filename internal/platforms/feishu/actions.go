@@ -0,0 +1,116 @@
+package feishu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/larksuite/oapi-sdk-go/v3/event/dispatcher/callback"
+	"github.com/pltanton/lingti-bot/internal/router"
+)
+
+// actionCard renders text and actions as the content of an interactive
+// message card: the text as a markdown element, then one action element.
+// Button and select values carry the action ID so callbacks can be mapped
+// back without any state.
+func actionCard(text string, actions []router.Action) (string, error) {
+	var elements []map[string]any
+	for _, a := range actions {
+		label := plainText(a.Label)
+		switch {
+		case a.Kind == router.ActionSelect:
+			var opts []map[string]any
+			for _, o := range a.Options {
+				opts = append(opts, map[string]any{"text": plainText(o.Label), "value": o.Value})
+			}
+			elements = append(elements, map[string]any{
+				"tag":         "select_static",
+				"placeholder": label,
+				"options":     opts,
+				"value":       map[string]string{"id": a.ID},
+			})
+		case a.URL != "":
+			elements = append(elements, map[string]any{
+				"tag":  "button",
+				"text": label,
+				"type": "default",
+				"url":  a.URL,
+			})
+		default:
+			style := "default"
+			if a.Style == "primary" || a.Style == "danger" {
+				style = a.Style
+			}
+			elements = append(elements, map[string]any{
+				"tag":   "button",
+				"text":  label,
+				"type":  style,
+				"value": map[string]string{"id": a.ID, "value": a.Value},
+			})
+		}
+	}
+
+	card := map[string]any{
+		"config": map[string]any{"wide_screen_mode": true},
+		"elements": []map[string]any{
+			{"tag": "markdown", "content": text},
+			{"tag": "action", "actions": elements},
+		},
+	}
+	data, err := json.Marshal(card)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func plainText(s string) map[string]string {
+	return map[string]string{"tag": "plain_text", "content": s}
+}
+
+// handleCardAction turns a press on a card button or a select choice into
+// a message. Feishu shows the returned toast to the user who acted.
+func (p *Platform) handleCardAction(ctx context.Context, event *callback.CardActionTriggerEvent) (*callback.CardActionTriggerResponse, error) {
+	if event == nil || event.Event == nil || event.Event.Action == nil {
+		return nil, nil
+	}
+	action := event.Event.Action
+	id, _ := action.Value["id"].(string)
+	if id == "" {
+		return nil, nil
+	}
+	value, _ := action.Value["value"].(string)
+	if action.Option != "" {
+		value = action.Option
+	}
+
+	var chatID, messageID string
+	if c := event.Event.Context; c != nil {
+		chatID, messageID = c.OpenChatID, c.OpenMessageID
+	}
+	var userID string
+	if event.Event.Operator != nil {
+		userID = event.Event.Operator.OpenID
+	}
+
+	if p.messageHandler != nil && chatID != "" {
+		p.messageHandler(router.Message{
+			ID:        fmt.Sprintf("%s:%s", messageID, id),
+			Platform:  "feishu",
+			ChannelID: chatID,
+			UserID:    userID,
+			Username:  p.getUsername(ctx, userID),
+			Text:      value,
+			Action: &router.ActionEvent{
+				ID:        id,
+				Value:     value,
+				MessageID: messageID,
+			},
+			Metadata: map[string]string{},
+		})
+	}
+
+	return &callback.CardActionTriggerResponse{
+		Toast: &callback.Toast{Type: "success", Content: "已选择：" + value},
+	}, nil
+}
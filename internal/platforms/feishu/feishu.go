@@ -7,6 +7,7 @@ import (
 	"log"
 	"strings"
 
+	"github.com/pltanton/lingti-bot/internal/markdown"
 	"github.com/pltanton/lingti-bot/internal/router"
 
 	lark "github.com/larksuite/oapi-sdk-go/v3"
@@ -66,6 +67,26 @@ func (p *Platform) Name() string {
 	return "feishu"
 }
 
+// Capabilities returns the platform's delivery limits
+func (p *Platform) Capabilities() router.Capabilities {
+	// Feishu limits post message content to 30KB
+	return router.Capabilities{
+		MaxMessageBytes: 30000,
+		SupportsEdit:    true,
+		SupportsActions: true,
+		Render:          renderPost,
+	}
+}
+
+// renderPost returns the post content Send would upload for text.
+func renderPost(text string) string {
+	content, err := markdown.FeishuPost(text)
+	if err != nil {
+		return text
+	}
+	return content
+}
+
 // SetMessageHandler sets the callback for incoming messages
 func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 	p.messageHandler = handler
@@ -95,7 +116,12 @@ func (p *Platform) Stop() error {
 
 // Send sends a message to a Feishu chat
 func (p *Platform) Send(ctx context.Context, chatID string, resp router.Response) error {
-	content, err := json.Marshal(map[string]string{"text": resp.Text})
+	msgType := larkim.MsgTypePost
+	content, err := markdown.FeishuPost(resp.Text)
+	if len(resp.Actions) > 0 {
+		msgType = larkim.MsgTypeInteractive
+		content, err = actionCard(resp.Text, resp.Actions)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to marshal message content: %w", err)
 	}
@@ -104,8 +130,8 @@ func (p *Platform) Send(ctx context.Context, chatID string, resp router.Response
 		ReceiveIdType(larkim.ReceiveIdTypeChatId).
 		Body(larkim.NewCreateMessageReqBodyBuilder().
 			ReceiveId(chatID).
-			MsgType(larkim.MsgTypeText).
-			Content(string(content)).
+			MsgType(msgType).
+			Content(content).
 			Build()).
 		Build()
 
@@ -118,6 +144,62 @@ func (p *Platform) Send(ctx context.Context, chatID string, resp router.Response
 		return fmt.Errorf("failed to send message: code=%d, msg=%s", result.Code, result.Msg)
 	}
 
+	return p.sendVoice(ctx, chatID, resp.Files)
+}
+
+// SendEditable sends a message and returns its message ID for later edits
+func (p *Platform) SendEditable(ctx context.Context, chatID string, resp router.Response) (string, error) {
+	content, err := markdown.FeishuPost(resp.Text)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal message content: %w", err)
+	}
+
+	req := larkim.NewCreateMessageReqBuilder().
+		ReceiveIdType(larkim.ReceiveIdTypeChatId).
+		Body(larkim.NewCreateMessageReqBodyBuilder().
+			ReceiveId(chatID).
+			MsgType(larkim.MsgTypePost).
+			Content(content).
+			Build()).
+		Build()
+
+	result, err := p.client.Im.Message.Create(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send message: %w", err)
+	}
+	if !result.Success() {
+		return "", fmt.Errorf("failed to send message: code=%d, msg=%s", result.Code, result.Msg)
+	}
+	if result.Data == nil || result.Data.MessageId == nil {
+		return "", fmt.Errorf("failed to send message: no message ID returned")
+	}
+
+	return *result.Data.MessageId, nil
+}
+
+// Edit replaces the content of a previously sent message
+func (p *Platform) Edit(ctx context.Context, chatID, messageID string, resp router.Response) error {
+	content, err := markdown.FeishuPost(resp.Text)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message content: %w", err)
+	}
+
+	req := larkim.NewUpdateMessageReqBuilder().
+		MessageId(messageID).
+		Body(larkim.NewUpdateMessageReqBodyBuilder().
+			MsgType(larkim.MsgTypePost).
+			Content(content).
+			Build()).
+		Build()
+
+	result, err := p.client.Im.Message.Update(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to edit message: %w", err)
+	}
+	if !result.Success() {
+		return fmt.Errorf("failed to edit message: code=%d, msg=%s", result.Code, result.Msg)
+	}
+
 	return nil
 }
 
@@ -125,6 +207,7 @@ func (p *Platform) Send(ctx context.Context, chatID string, resp router.Response
 func (p *Platform) buildEventHandler() *dispatcher.EventDispatcher {
 	handler := dispatcher.NewEventDispatcher("", "")
 	handler.OnP2MessageReceiveV1(p.handleMessageEvent)
+	handler.OnP2CardActionTrigger(p.handleCardAction)
 	return handler
 }
 
@@ -157,6 +240,11 @@ func (p *Platform) handleMessageEvent(ctx context.Context, event *larkim.P2Messa
 	// Clean @mention from text
 	text = p.cleanMention(text)
 
+	mediaID, mediaType := voiceMedia(msg)
+	if mediaID != "" {
+		text = "[语音]"
+	}
+
 	if p.messageHandler != nil {
 		userID := ""
 		username := ""
@@ -187,6 +275,8 @@ func (p *Platform) handleMessageEvent(ctx context.Context, event *larkim.P2Messa
 			Username:  username,
 			Text:      text,
 			ThreadID:  "", // Feishu doesn't have traditional threading like Slack
+			MediaID:   mediaID,
+			MediaType: mediaType,
 			Metadata: map[string]string{
 				"chat_type": chatType,
 			},
@@ -0,0 +1,105 @@
+package feishu
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pltanton/lingti-bot/internal/router"
+	"github.com/pltanton/lingti-bot/internal/speech"
+
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+)
+
+// voiceMedia returns the file key of an audio message, and the media type
+// to report it as.
+func voiceMedia(msg *larkim.EventMessage) (fileKey, mediaType string) {
+	if msg.MessageType == nil || *msg.MessageType != larkim.MsgTypeAudio || msg.Content == nil {
+		return "", ""
+	}
+	var content struct {
+		FileKey string `json:"file_key"`
+	}
+	if err := json.Unmarshal([]byte(*msg.Content), &content); err != nil || content.FileKey == "" {
+		return "", ""
+	}
+	return content.FileKey, "voice"
+}
+
+// DownloadMedia implements router.MediaDownloader. Feishu audio messages
+// are Opus in an Ogg container.
+func (p *Platform) DownloadMedia(ctx context.Context, msg router.Message) (speech.Audio, error) {
+	req := larkim.NewGetMessageResourceReqBuilder().
+		MessageId(msg.ID).
+		FileKey(msg.MediaID).
+		Type("file").
+		Build()
+
+	result, err := p.client.Im.MessageResource.Get(ctx, req)
+	if err != nil {
+		return speech.Audio{}, fmt.Errorf("failed to download audio: %w", err)
+	}
+	if !result.Success() {
+		return speech.Audio{}, fmt.Errorf("failed to download audio: code=%d, msg=%s", result.Code, result.Msg)
+	}
+	data, err := speech.ReadAudio(result.File)
+	if err != nil {
+		return speech.Audio{}, err
+	}
+	return speech.Audio{Data: data, Format: "ogg"}, nil
+}
+
+// sendVoice uploads the voice attachments of a response and sends them as
+// audio messages. Feishu only plays Opus, so other formats are converted.
+// Other attachments are not supported and are skipped.
+func (p *Platform) sendVoice(ctx context.Context, chatID string, files []router.FileAttachment) error {
+	for _, file := range files {
+		if file.MediaType != "voice" {
+			continue
+		}
+		data, err := os.ReadFile(file.Path)
+		if err != nil {
+			return err
+		}
+		ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(file.Path)), ".")
+		audio, err := speech.Convert(ctx, speech.Audio{Data: data, Format: ext}, "ogg")
+		if err != nil {
+			return fmt.Errorf("failed to convert voice to Opus: %w", err)
+		}
+
+		upload, err := p.client.Im.File.Create(ctx, larkim.NewCreateFileReqBuilder().
+			Body(larkim.NewCreateFileReqBodyBuilder().
+				FileType(larkim.FileTypeOpus).
+				FileName("voice.opus").
+				File(bytes.NewReader(audio.Data)).
+				Build()).
+			Build())
+		if err != nil {
+			return fmt.Errorf("failed to upload voice: %w", err)
+		}
+		if !upload.Success() || upload.Data == nil || upload.Data.FileKey == nil {
+			return fmt.Errorf("failed to upload voice: code=%d, msg=%s", upload.Code, upload.Msg)
+		}
+
+		content, _ := json.Marshal(map[string]string{"file_key": *upload.Data.FileKey})
+		result, err := p.client.Im.Message.Create(ctx, larkim.NewCreateMessageReqBuilder().
+			ReceiveIdType(larkim.ReceiveIdTypeChatId).
+			Body(larkim.NewCreateMessageReqBodyBuilder().
+				ReceiveId(chatID).
+				MsgType(larkim.MsgTypeAudio).
+				Content(string(content)).
+				Build()).
+			Build())
+		if err != nil {
+			return fmt.Errorf("failed to send voice: %w", err)
+		}
+		if !result.Success() {
+			return fmt.Errorf("failed to send voice: code=%d, msg=%s", result.Code, result.Msg)
+		}
+	}
+	return nil
+}
@@ -8,9 +8,18 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/pltanton/lingti-bot/internal/router"
+	"github.com/pltanton/lingti-bot/internal/webhookauth"
+)
+
+// Google Chat signs its requests as this service account, with the keys
+// published at chatJWKS.
+const (
+	chatIssuer = "chat@system.gserviceaccount.com"
+	chatJWKS   = "https://www.googleapis.com/service_accounts/v1/jwk/chat@system.gserviceaccount.com"
 )
 
 // Platform implements router.Platform for Google Chat
@@ -19,6 +28,7 @@ type Platform struct {
 	messageHandler func(msg router.Message)
 	httpClient     *http.Client
 	server         *http.Server
+	verifier       *webhookauth.JWTVerifier
 	ctx            context.Context
 	cancel         context.CancelFunc
 }
@@ -27,7 +37,15 @@ type Platform struct {
 type Config struct {
 	ProjectID       string // Google Cloud project ID
 	CredentialsFile string // Path to service account credentials JSON
-	WebhookPort     int    // Port for incoming webhooks (default: 8087)
+	WebhookPort     int    // Port for incoming webhooks (default: 8087, -1 = shared ingress only)
+
+	// Audience is the token audience set in the Chat app's connection
+	// settings: the numeric project number (not the project ID), or the
+	// HTTPS app URL. Required.
+	Audience string
+	// Keys verifies the tokens on incoming requests (default: the keys
+	// Google publishes for Chat, fetched and cached)
+	Keys webhookauth.KeySource
 }
 
 // New creates a new Google Chat platform
@@ -38,20 +56,57 @@ func New(cfg Config) (*Platform, error) {
 	if cfg.WebhookPort == 0 {
 		cfg.WebhookPort = 8087
 	}
+	if err := checkAudience(cfg.Audience); err != nil {
+		return nil, err
+	}
+	if cfg.Keys == nil {
+		cfg.Keys = webhookauth.NewJWKS(chatJWKS)
+	}
 
 	return &Platform{
 		config: cfg,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		verifier: &webhookauth.JWTVerifier{
+			Keys:     cfg.Keys,
+			Issuers:  []string{chatIssuer},
+			Audience: cfg.Audience,
+		},
 	}, nil
 }
 
+// checkAudience rejects audiences Google Chat never issues tokens for, such
+// as the project ID, so a misconfigured app fails at startup rather than
+// answering every request with 401.
+func checkAudience(aud string) error {
+	if aud == "" {
+		return fmt.Errorf("Google Chat audience is required: the project number from the Chat app's connection settings")
+	}
+	if strings.HasPrefix(aud, "https://") {
+		return nil
+	}
+	for _, c := range aud {
+		if c < '0' || c > '9' {
+			return fmt.Errorf("Google Chat audience %q is not a project number (digits only) or an https:// app URL", aud)
+		}
+	}
+	return nil
+}
+
 // Name returns the platform name
 func (p *Platform) Name() string {
 	return "googlechat"
 }
 
+// Capabilities returns the platform's delivery limits
+func (p *Platform) Capabilities() router.Capabilities {
+	// Google Chat text messages are limited to 4096 characters
+	return router.Capabilities{
+		MaxMessageLength: 4096,
+	}
+}
+
 // SetMessageHandler sets the callback for incoming messages
 func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 	p.messageHandler = handler
@@ -61,25 +116,35 @@ func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 func (p *Platform) Start(ctx context.Context) error {
 	p.ctx, p.cancel = context.WithCancel(ctx)
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/webhook", p.handleWebhook)
+	// Without a port of its own the platform is only served by the
+	// gateway's ingress, through WebhookHandler
+	if p.config.WebhookPort >= 0 {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/webhook", p.handleWebhook)
 
-	p.server = &http.Server{
-		Addr:    fmt.Sprintf(":%d", p.config.WebhookPort),
-		Handler: mux,
-	}
-
-	go func() {
-		log.Printf("[GoogleChat] Webhook server listening on :%d", p.config.WebhookPort)
-		if err := p.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("[GoogleChat] Server error: %v", err)
+		p.server = &http.Server{
+			Addr:    fmt.Sprintf(":%d", p.config.WebhookPort),
+			Handler: mux,
 		}
-	}()
+
+		go func() {
+			log.Printf("[GoogleChat] Webhook server listening on :%d", p.config.WebhookPort)
+			if err := p.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("[GoogleChat] Server error: %v", err)
+			}
+		}()
+	}
 
 	log.Printf("[GoogleChat] Platform started, project: %s", p.config.ProjectID)
 	return nil
 }
 
+// WebhookHandler serves the webhook endpoint on the gateway's shared
+// ingress, which mounts it at /webhooks/googlechat.
+func (p *Platform) WebhookHandler() http.Handler {
+	return http.HandlerFunc(p.handleWebhook)
+}
+
 // Stop shuts down the Google Chat connection
 func (p *Platform) Stop() error {
 	if p.cancel != nil {
@@ -138,6 +203,12 @@ func (p *Platform) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, err := p.verifier.VerifyRequest(r); err != nil {
+		webhookauth.Reject(w, r, "googlechat", http.StatusUnauthorized, err)
+		return
+	}
+	webhookauth.Accept("googlechat")
+
 	var event chatEvent
 	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
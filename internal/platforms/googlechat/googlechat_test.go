@@ -0,0 +1,77 @@
+package googlechat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pltanton/lingti-bot/internal/router"
+	"github.com/pltanton/lingti-bot/internal/webhookauth/webhookauthtest"
+)
+
+func TestHandleWebhookVerifiesToken(t *testing.T) {
+	signer := webhookauthtest.NewSigner(t)
+	p, err := New(Config{ProjectID: "my-project", Audience: "123456789012", Keys: signer.Keys()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []router.Message
+	p.SetMessageHandler(func(msg router.Message) { got = append(got, msg) })
+
+	now := time.Now()
+	const event = `{"type":"MESSAGE","message":{"name":"spaces/A/messages/1","text":"hi"},"space":{"name":"spaces/A"}}`
+	tests := []struct {
+		name     string
+		claims   map[string]any
+		wantCode int
+	}{
+		// Claims as Google Chat sends them: audience is the project number
+		{"valid", map[string]any{
+			"iss": "chat@system.gserviceaccount.com", "aud": "123456789012",
+			"iat": now.Unix(), "exp": now.Add(time.Hour).Unix(),
+		}, http.StatusOK},
+		{"no token", nil, http.StatusUnauthorized},
+		{"project ID audience", map[string]any{"iss": "chat@system.gserviceaccount.com", "aud": "my-project"}, http.StatusUnauthorized},
+		{"other project", map[string]any{"iss": "chat@system.gserviceaccount.com", "aud": "987654321098"}, http.StatusUnauthorized},
+		{"not from Chat", map[string]any{"iss": "someone@example.iam.gserviceaccount.com", "aud": "123456789012"}, http.StatusUnauthorized},
+		{"expired", map[string]any{
+			"iss": "chat@system.gserviceaccount.com", "aud": "123456789012",
+			"iat": now.Add(-2 * time.Hour).Unix(), "exp": now.Add(-time.Hour).Unix(),
+		}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(event))
+		if tt.claims != nil {
+			req.Header.Set("Authorization", "Bearer "+signer.Token(t, tt.claims))
+		}
+		rec := httptest.NewRecorder()
+		p.WebhookHandler().ServeHTTP(rec, req)
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.wantCode)
+		}
+	}
+	if len(got) != 1 || got[0].Text != "hi" {
+		t.Errorf("delivered %+v, want only the verified message", got)
+	}
+}
+
+func TestNewRequiresProjectNumberAudience(t *testing.T) {
+	tests := []struct {
+		audience string
+		ok       bool
+	}{
+		{"123456789012", true},
+		{"https://bot.example.com/webhook", true},
+		{"", false},
+		{"my-project", false},
+		{"http://bot.example.com/webhook", false},
+	}
+	for _, tt := range tests {
+		_, err := New(Config{ProjectID: "my-project", Audience: tt.audience})
+		if (err == nil) != tt.ok {
+			t.Errorf("New(audience %q) error = %v, want ok %v", tt.audience, err, tt.ok)
+		}
+	}
+}
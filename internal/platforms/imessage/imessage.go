@@ -56,6 +56,12 @@ func (p *Platform) Name() string {
 	return "imessage"
 }
 
+// Capabilities returns the platform's delivery limits
+func (p *Platform) Capabilities() router.Capabilities {
+	// iMessage has no practical text limit
+	return router.Capabilities{}
+}
+
 // SetMessageHandler sets the callback for incoming messages
 func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 	p.messageHandler = handler
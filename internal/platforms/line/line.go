@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/pltanton/lingti-bot/internal/router"
+	"github.com/pltanton/lingti-bot/internal/webhookauth"
 )
 
 // Platform implements router.Platform for LINE Messaging API
@@ -30,7 +31,7 @@ type Platform struct {
 type Config struct {
 	ChannelSecret string // LINE Channel Secret for signature verification
 	ChannelToken  string // LINE Channel Access Token
-	WebhookPort   int    // Port for incoming webhooks (default: 8085)
+	WebhookPort   int    // Port for incoming webhooks (default: 8085, -1 = shared ingress only)
 }
 
 // New creates a new LINE platform
@@ -58,6 +59,14 @@ func (p *Platform) Name() string {
 	return "line"
 }
 
+// Capabilities returns the platform's delivery limits
+func (p *Platform) Capabilities() router.Capabilities {
+	// LINE text messages are limited to 5000 characters
+	return router.Capabilities{
+		MaxMessageLength: 5000,
+	}
+}
+
 // SetMessageHandler sets the callback for incoming messages
 func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 	p.messageHandler = handler
@@ -67,25 +76,35 @@ func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 func (p *Platform) Start(ctx context.Context) error {
 	p.ctx, p.cancel = context.WithCancel(ctx)
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/webhook", p.handleWebhook)
-
-	p.server = &http.Server{
-		Addr:    fmt.Sprintf(":%d", p.config.WebhookPort),
-		Handler: mux,
-	}
+	// Without a port of its own the platform is only served by the
+	// gateway's ingress, through WebhookHandler
+	if p.config.WebhookPort >= 0 {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/webhook", p.handleWebhook)
 
-	go func() {
-		log.Printf("[LINE] Webhook server listening on :%d", p.config.WebhookPort)
-		if err := p.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("[LINE] Server error: %v", err)
+		p.server = &http.Server{
+			Addr:    fmt.Sprintf(":%d", p.config.WebhookPort),
+			Handler: mux,
 		}
-	}()
+
+		go func() {
+			log.Printf("[LINE] Webhook server listening on :%d", p.config.WebhookPort)
+			if err := p.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("[LINE] Server error: %v", err)
+			}
+		}()
+	}
 
 	log.Printf("[LINE] Platform started")
 	return nil
 }
 
+// WebhookHandler serves the webhook endpoint on the gateway's shared
+// ingress, which mounts it at /webhooks/line.
+func (p *Platform) WebhookHandler() http.Handler {
+	return http.HandlerFunc(p.handleWebhook)
+}
+
 // Stop shuts down the LINE connection
 func (p *Platform) Stop() error {
 	if p.cancel != nil {
@@ -203,9 +222,10 @@ func (p *Platform) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	// Verify signature
 	signature := r.Header.Get("X-Line-Signature")
 	if !p.verifySignature(body, signature) {
-		w.WriteHeader(http.StatusForbidden)
+		webhookauth.Reject(w, r, "line", http.StatusForbidden, fmt.Errorf("%w: X-Line-Signature does not match", webhookauth.ErrBadSignature))
 		return
 	}
+	webhookauth.Accept("line")
 
 	var webhook webhookPayload
 	if err := json.Unmarshal(body, &webhook); err != nil {
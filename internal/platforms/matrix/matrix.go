@@ -9,8 +9,10 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"sync/atomic"
 	"time"
 
+	"github.com/pltanton/lingti-bot/internal/markdown"
 	"github.com/pltanton/lingti-bot/internal/router"
 )
 
@@ -57,6 +59,16 @@ func (p *Platform) Name() string {
 	return "matrix"
 }
 
+// Capabilities returns the platform's delivery limits
+func (p *Platform) Capabilities() router.Capabilities {
+	// Matrix events are capped at 64KB, shared by body and formatted_body
+	return router.Capabilities{
+		MaxMessageBytes: 30000,
+		SupportsEdit:    true,
+		Render:          markdown.HTML,
+	}
+}
+
 // SetMessageHandler sets the callback for incoming messages
 func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 	p.messageHandler = handler
@@ -90,42 +102,76 @@ func (p *Platform) Send(ctx context.Context, channelID string, resp router.Respo
 	if resp.Text == "" {
 		return nil
 	}
+	_, err := p.SendEditable(ctx, channelID, resp)
+	return err
+}
 
-	p.txnID++
-	txn := strconv.FormatInt(p.txnID, 10)
+// SendEditable sends a message and returns its event ID for later edits
+func (p *Platform) SendEditable(ctx context.Context, channelID string, resp router.Response) (string, error) {
+	return p.sendEvent(ctx, channelID, textContent(resp.Text))
+}
 
-	url := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
-		p.config.HomeserverURL, channelID, txn)
+// Edit replaces a previously sent message using an m.replace relation
+func (p *Platform) Edit(ctx context.Context, channelID, messageID string, resp router.Response) error {
+	content := textContent(resp.Text)
+	content["m.new_content"] = textContent(resp.Text)
+	content["m.relates_to"] = map[string]string{
+		"rel_type": "m.replace",
+		"event_id": messageID,
+	}
+	// Clients without edit support show the fallback body
+	content["body"] = "* " + content["body"].(string)
+	_, err := p.sendEvent(ctx, channelID, content)
+	return err
+}
 
-	payload := map[string]string{
-		"msgtype": "m.text",
-		"body":    resp.Text,
+// textContent builds m.room.message content with an HTML formatted body
+func textContent(text string) map[string]any {
+	return map[string]any{
+		"msgtype":        "m.text",
+		"body":           markdown.Plain(text),
+		"format":         "org.matrix.custom.html",
+		"formatted_body": markdown.HTML(text),
 	}
+}
+
+// sendEvent sends an m.room.message event and returns its event ID
+func (p *Platform) sendEvent(ctx context.Context, roomID string, content map[string]any) (string, error) {
+	txn := strconv.FormatInt(atomic.AddInt64(&p.txnID, 1), 10)
 
-	body, err := json.Marshal(payload)
+	url := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		p.config.HomeserverURL, roomID, txn)
+
+	body, err := json.Marshal(content)
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return "", fmt.Errorf("failed to marshal message: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(body))
 	if err != nil {
-		return err
+		return "", err
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+p.config.AccessToken)
 
 	httpResp, err := p.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
+		return "", fmt.Errorf("failed to send message: %w", err)
 	}
 	defer httpResp.Body.Close()
 
 	if httpResp.StatusCode >= 400 {
 		respBody, _ := io.ReadAll(httpResp.Body)
-		return fmt.Errorf("Matrix API error %d: %s", httpResp.StatusCode, string(respBody))
+		return "", fmt.Errorf("Matrix API error %d: %s", httpResp.StatusCode, string(respBody))
 	}
 
-	return nil
+	var sent struct {
+		EventID string `json:"event_id"`
+	}
+	if err := json.NewDecoder(httpResp.Body).Decode(&sent); err != nil {
+		return "", fmt.Errorf("failed to decode send response: %w", err)
+	}
+	return sent.EventID, nil
 }
 
 // initialSync performs an initial sync to get the since token
@@ -262,3 +308,74 @@ type matrixEvent struct {
 	Sender  string `json:"sender"`
 	Content any    `json:"content"`
 }
+
+// RecentMessages returns recent text messages from a room, oldest first.
+// Rooms with only the bot and one user are treated as direct messages and
+// return nil, since the agent keeps their history.
+func (p *Platform) RecentMessages(ctx context.Context, channelID, threadID string, limit int) ([]router.Message, error) {
+	var members struct {
+		Joined map[string]any `json:"joined"`
+	}
+	if err := p.getJSON(ctx, fmt.Sprintf("/_matrix/client/v3/rooms/%s/joined_members", channelID), &members); err != nil {
+		return nil, err
+	}
+	if len(members.Joined) <= 2 {
+		return nil, nil
+	}
+
+	var page struct {
+		Chunk []matrixEvent `json:"chunk"`
+	}
+	path := fmt.Sprintf("/_matrix/client/v3/rooms/%s/messages?dir=b&limit=%d", channelID, limit)
+	if err := p.getJSON(ctx, path, &page); err != nil {
+		return nil, err
+	}
+
+	// Events come newest first with dir=b
+	msgs := make([]router.Message, 0, len(page.Chunk))
+	for i := len(page.Chunk) - 1; i >= 0; i-- {
+		event := page.Chunk[i]
+		if event.Type != "m.room.message" {
+			continue
+		}
+		content, _ := event.Content.(map[string]any)
+		body, _ := content["body"].(string)
+		if body == "" {
+			continue
+		}
+		username := event.Sender
+		if event.Sender == p.config.UserID {
+			username = "bot"
+		}
+		msgs = append(msgs, router.Message{
+			ID:        event.EventID,
+			Platform:  "matrix",
+			ChannelID: channelID,
+			UserID:    event.Sender,
+			Username:  username,
+			Text:      body,
+		})
+	}
+	return msgs, nil
+}
+
+// getJSON performs an authenticated GET against the homeserver and decodes the response
+func (p *Platform) getJSON(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.HomeserverURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.config.AccessToken)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Matrix API error %d: %s", resp.StatusCode, string(respBody))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
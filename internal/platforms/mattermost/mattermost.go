@@ -56,6 +56,16 @@ func (p *Platform) Name() string {
 	return "mattermost"
 }
 
+// Capabilities returns the platform's delivery limits
+func (p *Platform) Capabilities() router.Capabilities {
+	// Mattermost posts are limited to 16383 characters
+	return router.Capabilities{
+		MaxMessageLength: 16383,
+		SupportsEdit:     true,
+		SupportsThreads:  true,
+	}
+}
+
 // SetMessageHandler sets the callback for incoming messages
 func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 	p.messageHandler = handler
@@ -97,38 +107,68 @@ func (p *Platform) Send(ctx context.Context, channelID string, resp router.Respo
 	if resp.Text == "" {
 		return nil
 	}
+	_, err := p.SendEditable(ctx, channelID, resp)
+	return err
+}
 
-	apiURL := fmt.Sprintf("%s/api/v4/posts", strings.TrimRight(p.config.ServerURL, "/"))
-
+// SendEditable creates a post and returns its ID for later edits
+func (p *Platform) SendEditable(ctx context.Context, channelID string, resp router.Response) (string, error) {
 	payload := map[string]string{
 		"channel_id": channelID,
 		"message":    resp.Text,
 	}
 
+	respBody, err := p.postJSON(ctx, http.MethodPost, "/api/v4/posts", payload)
+	if err != nil {
+		return "", err
+	}
+
+	var post struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(respBody, &post); err != nil {
+		return "", fmt.Errorf("failed to decode post: %w", err)
+	}
+	return post.ID, nil
+}
+
+// Edit replaces the message of a previously created post
+func (p *Platform) Edit(ctx context.Context, channelID, messageID string, resp router.Response) error {
+	payload := map[string]string{
+		"message": resp.Text,
+	}
+	_, err := p.postJSON(ctx, http.MethodPut, "/api/v4/posts/"+url.PathEscape(messageID)+"/patch", payload)
+	return err
+}
+
+// postJSON sends a JSON request to the Mattermost API and returns the response body
+func (p *Platform) postJSON(ctx context.Context, method, path string, payload any) ([]byte, error) {
+	apiURL := strings.TrimRight(p.config.ServerURL, "/") + path
+
 	body, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, method, apiURL, bytes.NewReader(body))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+p.config.Token)
 
 	httpResp, err := p.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
+		return nil, fmt.Errorf("failed to send message: %w", err)
 	}
 	defer httpResp.Body.Close()
 
+	respBody, _ := io.ReadAll(httpResp.Body)
 	if httpResp.StatusCode >= 400 {
-		respBody, _ := io.ReadAll(httpResp.Body)
-		return fmt.Errorf("Mattermost API error %d: %s", httpResp.StatusCode, string(respBody))
+		return nil, fmt.Errorf("Mattermost API error %d: %s", httpResp.StatusCode, string(respBody))
 	}
 
-	return nil
+	return respBody, nil
 }
 
 // getBotUser retrieves the bot's user ID
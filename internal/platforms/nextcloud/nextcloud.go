@@ -63,6 +63,14 @@ func (p *Platform) Name() string {
 	return "nextcloud"
 }
 
+// Capabilities returns the platform's delivery limits
+func (p *Platform) Capabilities() router.Capabilities {
+	// Nextcloud Talk messages are limited to 32000 characters
+	return router.Capabilities{
+		MaxMessageLength: 32000,
+	}
+}
+
 // SetMessageHandler sets the callback for incoming messages
 func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 	p.messageHandler = handler
@@ -0,0 +1,129 @@
+package nostr
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// Vectors from the NIP-44 test vectors (nip44.vectors.json).
+func TestNIP44ConversationKey(t *testing.T) {
+	tests := []struct{ sec1, pub2, want string }{
+		{
+			sec1: "315e59ff51cb9209768cf7da80791ddcaae56ac9775eb25b6dee1234bc5d2268",
+			pub2: "c2f9d9948dc8c7c38321e4b85c8558872eafa0641cd269db76848a6073e69133",
+			want: "3dfef0ce2a4d80a25e7a328accf73448ef67096f65f79588e358d9a0eb9013f1",
+		},
+	}
+	for _, tt := range tests {
+		key, err := nip44ConversationKey(unhex(t, tt.sec1), unhex(t, tt.pub2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(key); got != tt.want {
+			t.Errorf("conversation key = %s, want %s", got, tt.want)
+		}
+	}
+}
+
+func TestNIP44Encrypt(t *testing.T) {
+	sec1 := unhex(t, "0000000000000000000000000000000000000000000000000000000000000001")
+	sec2 := unhex(t, "0000000000000000000000000000000000000000000000000000000000000002")
+	pub2, _ := publicKey(sec2)
+	pub1, _ := publicKey(sec1)
+
+	convKey, err := nip44ConversationKey(sec1, pub2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hex.EncodeToString(convKey), "c41c775356fd92eadc63ff5a0dc1da211b268cbea22316767095b2871ea1412d"; got != want {
+		t.Fatalf("conversation key = %s, want %s", got, want)
+	}
+	reverse, _ := nip44ConversationKey(sec2, pub1)
+	if hex.EncodeToString(reverse) != hex.EncodeToString(convKey) {
+		t.Error("conversation key is not symmetric")
+	}
+
+	nonce := unhex(t, "0000000000000000000000000000000000000000000000000000000000000001")
+	const want = "AgAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABee0G5VSK0/9YypIObAtDKfYEAjD35uVkHyB0F4DwrcNaCXlCWZKaArsGrY6M9wnuTMxWfp1RTN9Xga8no+kF5Vsb"
+	payload, err := nip44EncryptNonce(convKey, "a", nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload != want {
+		t.Errorf("payload = %s, want %s", payload, want)
+	}
+	if got, err := nip44Decrypt(reverse, want); err != nil || got != "a" {
+		t.Errorf("decrypt = %q, %v", got, err)
+	}
+}
+
+func TestNIP44PaddedLen(t *testing.T) {
+	tests := []struct{ n, want int }{
+		{16, 32}, {32, 32}, {33, 64}, {37, 64}, {45, 64}, {49, 64}, {64, 64},
+		{65, 96}, {100, 128}, {111, 128}, {200, 224}, {250, 256}, {320, 320},
+		{383, 384}, {384, 384}, {400, 448}, {500, 512}, {512, 512}, {515, 640},
+		{700, 768}, {800, 896}, {900, 1024}, {1020, 1024}, {65536, 65536},
+	}
+	for _, tt := range tests {
+		if got := nip44PaddedLen(tt.n); got != tt.want {
+			t.Errorf("nip44PaddedLen(%d) = %d, want %d", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestNIP44Rejects(t *testing.T) {
+	convKey := unhex(t, "c41c775356fd92eadc63ff5a0dc1da211b268cbea22316767095b2871ea1412d")
+	payload, err := nip44Encrypt(convKey, "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := []byte(payload)
+	tampered[60] ^= 1
+	if tampered[60] == '+' || tampered[60] == '/' {
+		tampered[60] = 'A'
+	}
+
+	for name, in := range map[string]string{
+		"tampered":   string(tampered),
+		"version #":  "#" + payload[1:],
+		"too short":  payload[:100],
+		"not base64": strings.Repeat("!", 140),
+	} {
+		if _, err := nip44Decrypt(convKey, in); err == nil {
+			t.Errorf("%s: decrypt succeeded", name)
+		}
+	}
+	if _, err := nip44Encrypt(convKey, ""); err == nil {
+		t.Error("empty plaintext encrypted")
+	}
+}
+
+func TestNIP04RoundTrip(t *testing.T) {
+	alice := unhex(t, "7f7ff03d123792d6ac594bfa67bf6d0c0ab55b6b1fdb6249303fe861f1ccba9a")
+	bob := unhex(t, "1b5b98ba97bf7b3d3b4b7e7cbe9f1fc3cc9b7b8c24a1cd1f2aa79cbd72b9bd8c")
+	alicePK, _ := publicKey(alice)
+	bobPK, _ := publicKey(bob)
+
+	for _, text := range []string{"hello", "你好，世界", strings.Repeat("x", 16), strings.Repeat("long ", 1000)} {
+		content, err := nip04Encrypt(alice, bobPK, text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(content, "?iv=") || strings.Contains(content, text) {
+			t.Fatalf("content %q is not a NIP-04 payload", content)
+		}
+		got, err := nip04Decrypt(bob, alicePK, content)
+		if err != nil || got != text {
+			t.Errorf("decrypt = %q, %v; want %q", got, err, text)
+		}
+	}
+
+	content, _ := nip04Encrypt(alice, bobPK, "secret")
+	if got, err := nip04Decrypt(alice, alicePK, content); err == nil && got == "secret" {
+		t.Error("decrypted with the wrong key")
+	}
+	if _, err := nip04Decrypt(bob, alicePK, "no iv here"); err == nil {
+		t.Error("decrypted content without iv")
+	}
+}
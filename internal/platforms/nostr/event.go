@@ -0,0 +1,158 @@
+package nostr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Event kinds the platform handles.
+const (
+	kindEncryptedDM = 4    // NIP-04 direct message
+	kindSeal        = 13   // NIP-59 seal
+	kindChatMessage = 14   // NIP-17 direct message rumor
+	kindGiftWrap    = 1059 // NIP-59 gift wrap
+)
+
+// event is a NIP-01 event.
+type event struct {
+	ID        string     `json:"id"`
+	PubKey    string     `json:"pubkey"`
+	CreatedAt int64      `json:"created_at"`
+	Kind      int        `json:"kind"`
+	Tags      [][]string `json:"tags"`
+	Content   string     `json:"content"`
+	Sig       string     `json:"sig,omitempty"`
+}
+
+// serialize returns the canonical NIP-01 serialization the ID is hashed
+// from: [0,pubkey,created_at,kind,tags,content] with no whitespace.
+// encoding/json is not used because it escapes <, > and & and NIP-01
+// requires them verbatim.
+func (e *event) serialize() []byte {
+	var sb strings.Builder
+	sb.WriteString(`[0,`)
+	writeJSONString(&sb, e.PubKey)
+	sb.WriteByte(',')
+	sb.WriteString(strconv.FormatInt(e.CreatedAt, 10))
+	sb.WriteByte(',')
+	sb.WriteString(strconv.Itoa(e.Kind))
+	sb.WriteString(`,[`)
+	for i, tag := range e.Tags {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteByte('[')
+		for j, v := range tag {
+			if j > 0 {
+				sb.WriteByte(',')
+			}
+			writeJSONString(&sb, v)
+		}
+		sb.WriteByte(']')
+	}
+	sb.WriteString(`],`)
+	writeJSONString(&sb, e.Content)
+	sb.WriteByte(']')
+	return []byte(sb.String())
+}
+
+// writeJSONString escapes s the way NIP-01 specifies: \n, ", \, \r, \t, \b
+// and \f get short escapes, other control characters \u escapes, and
+// everything else is written as is.
+func writeJSONString(sb *strings.Builder, s string) {
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '\n':
+			sb.WriteString(`\n`)
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\f':
+			sb.WriteString(`\f`)
+		default:
+			if r < 0x20 {
+				sb.WriteString(`\u00`)
+				sb.WriteByte("0123456789abcdef"[r>>4])
+				sb.WriteByte("0123456789abcdef"[r&0xf])
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+}
+
+// hash returns the event ID: the SHA-256 of the serialized event.
+func (e *event) hash() []byte {
+	h := sha256.Sum256(e.serialize())
+	return h[:]
+}
+
+// sign sets the event's pubkey, ID and signature.
+func (e *event) sign(sk []byte) error {
+	pk, err := publicKey(sk)
+	if err != nil {
+		return err
+	}
+	if e.Tags == nil {
+		e.Tags = [][]string{}
+	}
+	e.PubKey = hex.EncodeToString(pk)
+	id := e.hash()
+	sig, err := schnorrSign(sk, id)
+	if err != nil {
+		return err
+	}
+	e.ID = hex.EncodeToString(id)
+	e.Sig = hex.EncodeToString(sig)
+	return nil
+}
+
+// verify checks that the event's ID matches its content and that its
+// signature is valid for its pubkey.
+func (e *event) verify() error {
+	id := e.hash()
+	if e.ID != hex.EncodeToString(id) {
+		return errors.New("event id does not match content")
+	}
+	pk, err := hex.DecodeString(e.PubKey)
+	if err != nil {
+		return errBadPublicKey
+	}
+	sig, err := hex.DecodeString(e.Sig)
+	if err != nil {
+		return errBadSignature
+	}
+	return schnorrVerify(pk, id, sig)
+}
+
+// hasTag reports whether the event has a tag name with the given value.
+func (e *event) hasTag(name, value string) bool {
+	for _, t := range e.Tags {
+		if len(t) >= 2 && t[0] == name && t[1] == value {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON keeps tags an array when there are none; relays reject null.
+func (e event) MarshalJSON() ([]byte, error) {
+	type plain event
+	if e.Tags == nil {
+		e.Tags = [][]string{}
+	}
+	return json.Marshal(plain(e))
+}
@@ -0,0 +1,110 @@
+package nostr
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+)
+
+func TestEventSerialize(t *testing.T) {
+	ev := event{
+		PubKey:    "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+		CreatedAt: 1700000000,
+		Kind:      1,
+		Tags:      [][]string{{"p", "abc"}, {"e", "def", "wss://relay.example"}},
+		Content:   "line\n\"quoted\" \\ tab\t <b>&</b> 你好 \x01",
+	}
+	want := `[0,"79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",1700000000,1,` +
+		`[["p","abc"],["e","def","wss://relay.example"]],"line\n\"quoted\" \\ tab\t <b>&</b> 你好 \u0001"]`
+	if got := string(ev.serialize()); got != want {
+		t.Errorf("serialize =\n%s\nwant\n%s", got, want)
+	}
+
+	// The serialization must be valid JSON that decodes to the same values.
+	var decoded []any
+	if err := json.Unmarshal(ev.serialize(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded[5] != ev.Content {
+		t.Errorf("content round trip = %q", decoded[5])
+	}
+
+	empty := event{PubKey: "aa", CreatedAt: 1, Kind: 4}
+	if got := string(empty.serialize()); got != `[0,"aa",1,4,[],""]` {
+		t.Errorf("serialize without tags = %s", got)
+	}
+}
+
+func TestEventSignVerify(t *testing.T) {
+	sk := unhex(t, "67dea2ed018072d675f5415ecfaed7d2597555e202d85b3d65ea4e58d2d92ffa")
+	ev := event{CreatedAt: 1700000000, Kind: kindEncryptedDM, Tags: [][]string{{"p", "abc"}}, Content: "hi"}
+	if err := ev.sign(sk); err != nil {
+		t.Fatal(err)
+	}
+	if len(ev.ID) != 64 || len(ev.Sig) != 128 || len(ev.PubKey) != 64 {
+		t.Fatalf("signed event has id=%q sig=%q pubkey=%q", ev.ID, ev.Sig, ev.PubKey)
+	}
+	if err := ev.verify(); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	// A relay sees the event as JSON; it must verify after a round trip.
+	data, _ := json.Marshal(ev)
+	var parsed event
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if err := parsed.verify(); err != nil {
+		t.Errorf("verify after JSON round trip: %v", err)
+	}
+
+	tests := map[string]func(e *event){
+		"content":    func(e *event) { e.Content = "bye" },
+		"tags":       func(e *event) { e.Tags = nil },
+		"created_at": func(e *event) { e.CreatedAt++ },
+		"id and sig": func(e *event) {
+			other := event{Kind: 1, Content: "x"}
+			other.sign(sk)
+			e.Sig = other.Sig
+		},
+	}
+	for name, mutate := range tests {
+		tampered := parsed
+		mutate(&tampered)
+		if err := tampered.verify(); err == nil {
+			t.Errorf("verify succeeded after changing %s", name)
+		}
+	}
+}
+
+func TestGiftWrapRoundTrip(t *testing.T) {
+	alice := unhex(t, "67dea2ed018072d675f5415ecfaed7d2597555e202d85b3d65ea4e58d2d92ffa")
+	bob := unhex(t, "0000000000000000000000000000000000000000000000000000000000000002")
+	alicePK, _ := publicKey(alice)
+	bobPK, _ := publicKey(bob)
+
+	wrap, err := giftWrap(alice, bobPK, "hello bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wrap.Kind != kindGiftWrap || wrap.verify() != nil {
+		t.Fatalf("gift wrap kind %d does not verify", wrap.Kind)
+	}
+	if wrap.PubKey == hex.EncodeToString(alicePK) {
+		t.Error("gift wrap is signed by the sender instead of a throwaway key")
+	}
+	if !wrap.hasTag("p", hex.EncodeToString(bobPK)) {
+		t.Error("gift wrap is not tagged for the recipient")
+	}
+
+	rumor, err := unwrapGift(bob, wrap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rumor.Content != "hello bob" || rumor.PubKey != hex.EncodeToString(alicePK) || rumor.Kind != kindChatMessage {
+		t.Errorf("rumor = %+v", rumor)
+	}
+	if _, err := unwrapGift(alice, wrap); err == nil {
+		t.Error("sender could open a gift wrap addressed to the recipient")
+	}
+}
@@ -0,0 +1,146 @@
+package nostr
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// parsePrivateKey accepts a secret key as 64 hex characters or as a NIP-19
+// nsec string.
+func parsePrivateKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	var sk []byte
+	if strings.HasPrefix(strings.ToLower(s), "nsec1") {
+		hrp, data, err := bech32Decode(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid nsec: %w", err)
+		}
+		if hrp != "nsec" {
+			return nil, fmt.Errorf("invalid nsec: prefix %q", hrp)
+		}
+		sk = data
+	} else {
+		var err error
+		if sk, err = hex.DecodeString(s); err != nil {
+			return nil, fmt.Errorf("private key is neither hex nor nsec: %w", err)
+		}
+	}
+	if _, err := secretKey(sk); err != nil {
+		return nil, err
+	}
+	return sk, nil
+}
+
+// npub encodes an x-only public key as a NIP-19 npub string.
+func npub(pk []byte) string {
+	s, err := bech32Encode("npub", pk)
+	if err != nil {
+		return hex.EncodeToString(pk)
+	}
+	return s
+}
+
+// Bech32 (BIP-173), as NIP-19 uses it for nsec and npub.
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	out := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]>>5)
+	}
+	out = append(out, 0)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]&31)
+	}
+	return out
+}
+
+// convertBits regroups a byte slice from one bit width to another.
+func convertBits(data []byte, from, to uint, pad bool) ([]byte, error) {
+	var acc, bits uint
+	var out []byte
+	maxv := uint(1)<<to - 1
+	for _, b := range data {
+		if uint(b)>>from != 0 {
+			return nil, errors.New("invalid data range")
+		}
+		acc = acc<<from | uint(b)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(to-bits)&maxv))
+		}
+	} else if bits >= from || acc<<(to-bits)&maxv != 0 {
+		return nil, errors.New("invalid padding")
+	}
+	return out, nil
+}
+
+func bech32Encode(hrp string, data []byte) (string, error) {
+	values, err := convertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	poly := bech32Polymod(append(append(bech32HRPExpand(hrp), values...), 0, 0, 0, 0, 0, 0)) ^ 1
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, v := range values {
+		sb.WriteByte(bech32Charset[v])
+	}
+	for i := 0; i < 6; i++ {
+		sb.WriteByte(bech32Charset[poly>>(5*(5-i))&31])
+	}
+	return sb.String(), nil
+}
+
+func bech32Decode(s string) (string, []byte, error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, errors.New("mixed case")
+	}
+	s = strings.ToLower(s)
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || sep+7 > len(s) {
+		return "", nil, errors.New("malformed string")
+	}
+	hrp := s[:sep]
+	values := make([]byte, 0, len(s)-sep-1)
+	for _, c := range s[sep+1:] {
+		i := strings.IndexRune(bech32Charset, c)
+		if i < 0 {
+			return "", nil, fmt.Errorf("invalid character %q", c)
+		}
+		values = append(values, byte(i))
+	}
+	if bech32Polymod(append(bech32HRPExpand(hrp), values...)) != 1 {
+		return "", nil, errors.New("bad checksum")
+	}
+	data, err := convertBits(values[:len(values)-6], 5, 8, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return hrp, data, nil
+}
@@ -0,0 +1,70 @@
+package nostr
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// NIP-04 encryption: AES-256-CBC keyed with the raw ECDH x coordinate,
+// encoded as "<base64 ciphertext>?iv=<base64 iv>". It leaks metadata and is
+// deprecated in favour of NIP-44, but most clients still send kind 4 DMs.
+
+func nip04Encrypt(sk, pk []byte, plaintext string) (string, error) {
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+	return nip04EncryptIV(sk, pk, plaintext, iv)
+}
+
+func nip04EncryptIV(sk, pk []byte, plaintext string, iv []byte) (string, error) {
+	key, err := sharedX(sk, pk)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	pad := aes.BlockSize - len(plaintext)%aes.BlockSize
+	data := append([]byte(plaintext), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+	return base64.StdEncoding.EncodeToString(data) + "?iv=" + base64.StdEncoding.EncodeToString(iv), nil
+}
+
+func nip04Decrypt(sk, pk []byte, content string) (string, error) {
+	ctB64, ivB64, ok := strings.Cut(content, "?iv=")
+	if !ok {
+		return "", errors.New("nip04: missing iv")
+	}
+	data, err := base64.StdEncoding.DecodeString(ctB64)
+	if err != nil {
+		return "", errors.New("nip04: invalid ciphertext encoding")
+	}
+	iv, err := base64.StdEncoding.DecodeString(ivB64)
+	if err != nil || len(iv) != aes.BlockSize {
+		return "", errors.New("nip04: invalid iv")
+	}
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return "", errors.New("nip04: invalid ciphertext length")
+	}
+	key, err := sharedX(sk, pk)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(data, data)
+	pad := int(data[len(data)-1])
+	if pad == 0 || pad > aes.BlockSize || !bytes.Equal(data[len(data)-pad:], bytes.Repeat([]byte{byte(pad)}, pad)) {
+		return "", errors.New("nip04: bad padding")
+	}
+	return string(data[:len(data)-pad]), nil
+}
@@ -0,0 +1,127 @@
+package nostr
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// NIP-17 private direct messages: an unsigned kind 14 rumor, sealed (kind 13,
+// NIP-44 encrypted and signed by the sender) and gift wrapped (kind 1059,
+// NIP-44 encrypted and signed by a throwaway key) so relays see neither the
+// sender nor the time it was written.
+
+// giftWrap wraps text from sk to the recipient pk as a NIP-17 DM.
+func giftWrap(sk, pk []byte, text string) (*event, error) {
+	senderPK, err := publicKey(sk)
+	if err != nil {
+		return nil, err
+	}
+	recipient := hex.EncodeToString(pk)
+	rumor := event{
+		PubKey:    hex.EncodeToString(senderPK),
+		CreatedAt: time.Now().Unix(),
+		Kind:      kindChatMessage,
+		Tags:      [][]string{{"p", recipient}},
+		Content:   text,
+	}
+	rumor.ID = hex.EncodeToString(rumor.hash())
+
+	seal, err := sealEvent(sk, pk, kindSeal, nil, rumor)
+	if err != nil {
+		return nil, err
+	}
+	ephemeral := make([]byte, 32)
+	for {
+		if _, err := rand.Read(ephemeral); err != nil {
+			return nil, err
+		}
+		if _, err := secretKey(ephemeral); err == nil {
+			break
+		}
+	}
+	return sealEvent(ephemeral, pk, kindGiftWrap, [][]string{{"p", recipient}}, *seal)
+}
+
+// sealEvent NIP-44 encrypts inner to pk inside a new event signed by sk,
+// dated up to two days in the past.
+func sealEvent(sk, pk []byte, kind int, tags [][]string, inner event) (*event, error) {
+	data, err := json.Marshal(inner)
+	if err != nil {
+		return nil, err
+	}
+	convKey, err := nip44ConversationKey(sk, pk)
+	if err != nil {
+		return nil, err
+	}
+	content, err := nip44Encrypt(convKey, string(data))
+	if err != nil {
+		return nil, err
+	}
+	ev := &event{
+		CreatedAt: randomPast(),
+		Kind:      kind,
+		Tags:      tags,
+		Content:   content,
+	}
+	if err := ev.sign(sk); err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+// unwrapGift opens a gift wrap addressed to sk and returns the rumor, whose
+// pubkey is checked against the seal's signature.
+func unwrapGift(sk []byte, wrap *event) (*event, error) {
+	var seal event
+	if err := openSealed(sk, wrap, &seal); err != nil {
+		return nil, fmt.Errorf("gift wrap: %w", err)
+	}
+	if seal.Kind != kindSeal {
+		return nil, fmt.Errorf("gift wrap: unexpected kind %d inside", seal.Kind)
+	}
+	if err := seal.verify(); err != nil {
+		return nil, fmt.Errorf("seal: %w", err)
+	}
+	var rumor event
+	if err := openSealed(sk, &seal, &rumor); err != nil {
+		return nil, fmt.Errorf("seal: %w", err)
+	}
+	if rumor.PubKey != seal.PubKey {
+		return nil, errors.New("rumor author does not match seal signer")
+	}
+	if rumor.Kind != kindChatMessage {
+		return nil, fmt.Errorf("seal: unexpected kind %d inside", rumor.Kind)
+	}
+	return &rumor, nil
+}
+
+func openSealed(sk []byte, outer *event, inner *event) error {
+	pk, err := hex.DecodeString(outer.PubKey)
+	if err != nil {
+		return errBadPublicKey
+	}
+	convKey, err := nip44ConversationKey(sk, pk)
+	if err != nil {
+		return err
+	}
+	plaintext, err := nip44Decrypt(convKey, outer.Content)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(plaintext), inner)
+}
+
+// randomPast returns a timestamp up to two days before now, as NIP-59
+// recommends for seals and gift wraps.
+func randomPast() int64 {
+	offset, err := rand.Int(rand.Reader, big.NewInt(2*24*60*60))
+	if err != nil {
+		return time.Now().Unix()
+	}
+	return time.Now().Unix() - offset.Int64()
+}
@@ -0,0 +1,135 @@
+package nostr
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/bits"
+
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/hkdf"
+)
+
+// NIP-44 version 2 encryption: a conversation key from HKDF over the ECDH x
+// coordinate, per-message ChaCha20 and HMAC-SHA256 keys from a random nonce,
+// and padding that hides the exact message length.
+
+const nip44Version = 2
+
+var errNIP44 = errors.New("nip44: invalid payload")
+
+// nip44ConversationKey derives the key two parties share for all their
+// messages. It is symmetric: either side's secret with the other's pubkey
+// gives the same key.
+func nip44ConversationKey(sk, pk []byte) ([]byte, error) {
+	shared, err := sharedX(sk, pk)
+	if err != nil {
+		return nil, err
+	}
+	return hkdf.Extract(sha256.New, shared, []byte("nip44-v2")), nil
+}
+
+func nip44MessageKeys(convKey, nonce []byte) (chachaKey, chachaNonce, hmacKey []byte, err error) {
+	keys := make([]byte, 76)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, convKey, nonce), keys); err != nil {
+		return nil, nil, nil, err
+	}
+	return keys[:32], keys[32:44], keys[44:], nil
+}
+
+// nip44PaddedLen rounds a plaintext length up to 32 bytes, then to powers of
+// two up to 256, then to eighths of the next power of two.
+func nip44PaddedLen(n int) int {
+	if n <= 32 {
+		return 32
+	}
+	next := 1 << bits.Len(uint(n-1))
+	chunk := 32
+	if next > 256 {
+		chunk = next / 8
+	}
+	return chunk * ((n-1)/chunk + 1)
+}
+
+func nip44Encrypt(convKey []byte, plaintext string) (string, error) {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return nip44EncryptNonce(convKey, plaintext, nonce)
+}
+
+func nip44EncryptNonce(convKey []byte, plaintext string, nonce []byte) (string, error) {
+	n := len(plaintext)
+	if n < 1 || n > 65535 {
+		return "", errors.New("nip44: plaintext must be 1 to 65535 bytes")
+	}
+	chachaKey, chachaNonce, hmacKey, err := nip44MessageKeys(convKey, nonce)
+	if err != nil {
+		return "", err
+	}
+	padded := make([]byte, 2+nip44PaddedLen(n))
+	binary.BigEndian.PutUint16(padded, uint16(n))
+	copy(padded[2:], plaintext)
+
+	c, err := chacha20.NewUnauthenticatedCipher(chachaKey, chachaNonce)
+	if err != nil {
+		return "", err
+	}
+	c.XORKeyStream(padded, padded)
+
+	payload := make([]byte, 0, 1+32+len(padded)+32)
+	payload = append(payload, nip44Version)
+	payload = append(payload, nonce...)
+	payload = append(payload, padded...)
+	payload = append(payload, nip44MAC(hmacKey, nonce, padded)...)
+	return base64.StdEncoding.EncodeToString(payload), nil
+}
+
+func nip44Decrypt(convKey []byte, payload string) (string, error) {
+	if len(payload) == 0 || payload[0] == '#' {
+		return "", errors.New("nip44: unsupported version")
+	}
+	if len(payload) < 132 || len(payload) > 87472 {
+		return "", errNIP44
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil || len(data) < 99 || len(data) > 65603 {
+		return "", errNIP44
+	}
+	if data[0] != nip44Version {
+		return "", errors.New("nip44: unsupported version")
+	}
+	nonce, ciphertext, mac := data[1:33], data[33:len(data)-32], data[len(data)-32:]
+
+	chachaKey, chachaNonce, hmacKey, err := nip44MessageKeys(convKey, nonce)
+	if err != nil {
+		return "", err
+	}
+	if !hmac.Equal(mac, nip44MAC(hmacKey, nonce, ciphertext)) {
+		return "", errors.New("nip44: invalid MAC")
+	}
+	c, err := chacha20.NewUnauthenticatedCipher(chachaKey, chachaNonce)
+	if err != nil {
+		return "", err
+	}
+	padded := make([]byte, len(ciphertext))
+	c.XORKeyStream(padded, ciphertext)
+
+	n := int(binary.BigEndian.Uint16(padded))
+	if n == 0 || len(padded) != 2+nip44PaddedLen(n) {
+		return "", errors.New("nip44: invalid padding")
+	}
+	return string(padded[2 : 2+n]), nil
+}
+
+func nip44MAC(key, nonce, ciphertext []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(nonce)
+	h.Write(ciphertext)
+	return h.Sum(nil)
+}
@@ -2,6 +2,7 @@ package nostr
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -9,18 +10,26 @@ import (
 	"sync"
 	"time"
 
-	"github.com/gorilla/websocket"
 	"github.com/pltanton/lingti-bot/internal/router"
 )
 
 // Platform implements router.Platform for NOSTR protocol
 type Platform struct {
 	config         Config
+	secretKey      []byte
+	pubKey         string // hex x-only public key
 	messageHandler func(msg router.Message)
-	conns          []*websocket.Conn
-	connsMu        sync.Mutex
-	ctx            context.Context
-	cancel         context.CancelFunc
+	relays         []*relay
+	seen           *seenSet
+	started        int64
+
+	// protocols remembers whether each contact last wrote with NIP-04 or
+	// NIP-17, so replies go back the same way.
+	protocols   map[string]string
+	protocolsMu sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // Config holds NOSTR configuration
@@ -29,18 +38,42 @@ type Config struct {
 	Relays     string // Comma-separated relay URLs
 }
 
+// Protocols recorded in message metadata and used for replies.
+const (
+	protocolNIP04 = "nip04"
+	protocolNIP17 = "nip17"
+)
+
 // New creates a new NOSTR platform
 func New(cfg Config) (*Platform, error) {
 	if cfg.PrivateKey == "" {
 		return nil, fmt.Errorf("NOSTR private key is required")
 	}
-	if cfg.Relays == "" {
-		return nil, fmt.Errorf("at least one NOSTR relay is required")
+	sk, err := parsePrivateKey(cfg.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NOSTR private key: %w", err)
+	}
+	pk, err := publicKey(sk)
+	if err != nil {
+		return nil, err
 	}
 
-	return &Platform{
-		config: cfg,
-	}, nil
+	p := &Platform{
+		config:    cfg,
+		secretKey: sk,
+		pubKey:    hex.EncodeToString(pk),
+		seen:      newSeenSet(4096),
+		protocols: make(map[string]string),
+	}
+	for _, url := range strings.Split(cfg.Relays, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			p.relays = append(p.relays, &relay{url: url})
+		}
+	}
+	if len(p.relays) == 0 {
+		return nil, fmt.Errorf("at least one NOSTR relay is required")
+	}
+	return p, nil
 }
 
 // Name returns the platform name
@@ -48,25 +81,32 @@ func (p *Platform) Name() string {
 	return "nostr"
 }
 
+// Capabilities returns the platform's delivery limits
+func (p *Platform) Capabilities() router.Capabilities {
+	// Relays commonly reject events larger than 64KB, and a NIP-17 DM is
+	// encrypted and base64-encoded twice on the way there
+	return router.Capabilities{
+		MaxMessageBytes: 16000,
+	}
+}
+
 // SetMessageHandler sets the callback for incoming messages
 func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 	p.messageHandler = handler
 }
 
-// Start connects to NOSTR relays and subscribes to DM events
+// Start connects to NOSTR relays and subscribes to DMs addressed to our key
 func (p *Platform) Start(ctx context.Context) error {
 	p.ctx, p.cancel = context.WithCancel(ctx)
+	p.started = time.Now().Unix()
 
-	relays := strings.Split(p.config.Relays, ",")
-	for _, relay := range relays {
-		relay = strings.TrimSpace(relay)
-		if relay == "" {
-			continue
-		}
-		go p.connectRelay(relay)
+	for _, r := range p.relays {
+		r.since = p.started
+		go p.runRelay(r)
 	}
 
-	log.Printf("[NOSTR] Connecting to relays: %s", p.config.Relays)
+	pk, _ := hex.DecodeString(p.pubKey)
+	log.Printf("[NOSTR] Listening as %s on relays: %s", npub(pk), p.config.Relays)
 	return nil
 }
 
@@ -75,168 +115,140 @@ func (p *Platform) Stop() error {
 	if p.cancel != nil {
 		p.cancel()
 	}
-	p.connsMu.Lock()
-	defer p.connsMu.Unlock()
-	for _, conn := range p.conns {
-		conn.Close()
+	for _, r := range p.relays {
+		r.close()
 	}
-	p.conns = nil
 	return nil
 }
 
-// Send publishes a DM event to all connected relays
+// Send publishes an encrypted DM to every connected relay. channelID is the
+// recipient's hex public key. Replies use NIP-17 if the recipient last wrote
+// with it, NIP-04 otherwise.
 func (p *Platform) Send(ctx context.Context, channelID string, resp router.Response) error {
 	if resp.Text == "" {
 		return nil
 	}
+	pk, err := hex.DecodeString(channelID)
+	if err != nil || len(pk) != 32 {
+		return fmt.Errorf("invalid NOSTR recipient pubkey %q", channelID)
+	}
+
+	p.protocolsMu.Lock()
+	protocol := p.protocols[channelID]
+	p.protocolsMu.Unlock()
 
-	// Create a kind 4 (DM) event
-	event := nostrEvent{
-		Kind:      4,
-		Content:   resp.Text,
-		Tags:      [][]string{{"p", channelID}},
+	var ev *event
+	if protocol == protocolNIP17 {
+		ev, err = giftWrap(p.secretKey, pk, resp.Text)
+	} else {
+		ev, err = p.encryptedDM(pk, channelID, resp.Text)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to build DM: %w", err)
+	}
+	return p.publish(ev)
+}
+
+// encryptedDM builds a signed NIP-04 kind 4 event.
+func (p *Platform) encryptedDM(pk []byte, recipient, text string) (*event, error) {
+	content, err := nip04Encrypt(p.secretKey, pk, text)
+	if err != nil {
+		return nil, err
+	}
+	ev := &event{
 		CreatedAt: time.Now().Unix(),
+		Kind:      kindEncryptedDM,
+		Tags:      [][]string{{"p", recipient}},
+		Content:   content,
 	}
+	if err := ev.sign(p.secretKey); err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
 
-	eventJSON, err := json.Marshal([]any{"EVENT", event})
+// publish sends an event to every connected relay. It fails only if no
+// relay took it.
+func (p *Platform) publish(ev *event) error {
+	data, err := json.Marshal([]any{"EVENT", ev})
 	if err != nil {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	p.connsMu.Lock()
-	defer p.connsMu.Unlock()
-
+	var sent int
 	var lastErr error
-	for _, conn := range p.conns {
-		if err := conn.WriteMessage(websocket.TextMessage, eventJSON); err != nil {
-			lastErr = err
+	for _, r := range p.relays {
+		if err := r.write(data); err != nil {
+			lastErr = fmt.Errorf("%s: %w", r.url, err)
+			continue
 		}
+		sent++
 	}
-
-	return lastErr
+	if sent == 0 {
+		return fmt.Errorf("failed to publish to any relay: %w", lastErr)
+	}
+	return nil
 }
 
-// connectRelay connects to a single NOSTR relay
-func (p *Platform) connectRelay(relayURL string) {
-	for {
-		select {
-		case <-p.ctx.Done():
-			return
-		default:
-		}
-
-		conn, _, err := websocket.DefaultDialer.Dial(relayURL, nil)
-		if err != nil {
-			log.Printf("[NOSTR] Failed to connect to %s: %v", relayURL, err)
-			time.Sleep(5 * time.Second)
-			continue
-		}
-
-		p.connsMu.Lock()
-		p.conns = append(p.conns, conn)
-		p.connsMu.Unlock()
-
-		log.Printf("[NOSTR] Connected to relay: %s", relayURL)
-
-		// Subscribe to DM events (kind 4)
-		sub := []any{"REQ", "lingti-dm", map[string]any{
-			"kinds": []int{4},
-			"since": time.Now().Unix(),
-		}}
-		subJSON, _ := json.Marshal(sub)
-		conn.WriteMessage(websocket.TextMessage, subJSON)
-
-		// Read loop
-		p.readRelay(conn, relayURL)
-
-		// Remove connection from list
-		p.connsMu.Lock()
-		for i, c := range p.conns {
-			if c == conn {
-				p.conns = append(p.conns[:i], p.conns[i+1:]...)
-				break
-			}
-		}
-		p.connsMu.Unlock()
-
-		if p.ctx.Err() != nil {
-			return
-		}
-
-		log.Printf("[NOSTR] Disconnected from %s, reconnecting...", relayURL)
-		time.Sleep(5 * time.Second)
+// handleEvent verifies, deduplicates and decrypts an event from a relay and
+// passes the DM inside to the message handler.
+func (p *Platform) handleEvent(relayURL string, ev *event) {
+	if p.seen.has(ev.ID) || !ev.hasTag("p", p.pubKey) {
+		return
+	}
+	if err := ev.verify(); err != nil {
+		log.Printf("[NOSTR] Dropping event %s from %s: %v", ev.ID, relayURL, err)
+		return
+	}
+	if !p.seen.add(ev.ID) {
+		return
 	}
-}
 
-// readRelay reads events from a relay connection
-func (p *Platform) readRelay(conn *websocket.Conn, relayURL string) {
-	for {
-		select {
-		case <-p.ctx.Done():
+	var sender, text, id, protocol string
+	var createdAt int64
+	switch ev.Kind {
+	case kindEncryptedDM:
+		pk, _ := hex.DecodeString(ev.PubKey)
+		plaintext, err := nip04Decrypt(p.secretKey, pk, ev.Content)
+		if err != nil {
+			log.Printf("[NOSTR] Failed to decrypt DM %s: %v", ev.ID, err)
 			return
-		default:
 		}
-
-		_, msg, err := conn.ReadMessage()
+		sender, text, id, createdAt, protocol = ev.PubKey, plaintext, ev.ID, ev.CreatedAt, protocolNIP04
+	case kindGiftWrap:
+		rumor, err := unwrapGift(p.secretKey, ev)
 		if err != nil {
-			if p.ctx.Err() != nil {
-				return
-			}
-			log.Printf("[NOSTR] Read error from %s: %v", relayURL, err)
+			log.Printf("[NOSTR] Failed to unwrap DM %s: %v", ev.ID, err)
 			return
 		}
+		sender, text, id, createdAt, protocol = rumor.PubKey, rumor.Content, rumor.ID, rumor.CreatedAt, protocolNIP17
+	default:
+		return
+	}
 
-		// Parse NOSTR message: ["EVENT", "sub_id", {...event...}]
-		var envelope []json.RawMessage
-		if err := json.Unmarshal(msg, &envelope); err != nil {
-			continue
-		}
-
-		if len(envelope) < 3 {
-			continue
-		}
-
-		var msgType string
-		if err := json.Unmarshal(envelope[0], &msgType); err != nil {
-			continue
-		}
-
-		if msgType != "EVENT" {
-			continue
-		}
-
-		var event nostrEvent
-		if err := json.Unmarshal(envelope[2], &event); err != nil {
-			continue
-		}
-
-		// Process DM events (kind 4)
-		if event.Kind == 4 && event.Content != "" {
-			if p.messageHandler != nil {
-				p.messageHandler(router.Message{
-					ID:        event.ID,
-					Platform:  "nostr",
-					ChannelID: event.Pubkey,
-					UserID:    event.Pubkey,
-					Username:  event.Pubkey[:16],
-					Text:      event.Content,
-					Metadata: map[string]string{
-						"relay":  relayURL,
-						"pubkey": event.Pubkey,
-					},
-				})
-			}
-		}
+	// Gift wraps are backdated, so the subscription also returns DMs from
+	// before we started; skip those, and our own messages.
+	if createdAt < p.started || sender == p.pubKey || text == "" {
+		return
 	}
-}
 
-// nostrEvent represents a NOSTR event
-type nostrEvent struct {
-	ID        string     `json:"id,omitempty"`
-	Pubkey    string     `json:"pubkey,omitempty"`
-	Kind      int        `json:"kind"`
-	Content   string     `json:"content"`
-	Tags      [][]string `json:"tags"`
-	CreatedAt int64      `json:"created_at"`
-	Sig       string     `json:"sig,omitempty"`
+	p.protocolsMu.Lock()
+	p.protocols[sender] = protocol
+	p.protocolsMu.Unlock()
+
+	if p.messageHandler != nil {
+		p.messageHandler(router.Message{
+			ID:        id,
+			Platform:  "nostr",
+			ChannelID: sender,
+			UserID:    sender,
+			Username:  sender[:16],
+			Text:      text,
+			Metadata: map[string]string{
+				"relay":    relayURL,
+				"pubkey":   sender,
+				"protocol": protocol,
+			},
+		})
+	}
 }
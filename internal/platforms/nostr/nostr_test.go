@@ -0,0 +1,241 @@
+package nostr
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pltanton/lingti-bot/internal/router"
+)
+
+// fakeRelay is a websocket server that records REQ filters and published
+// events and lets the test push events to the connected client.
+type fakeRelay struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu        sync.Mutex
+	conns     []*websocket.Conn
+	filters   []json.RawMessage
+	published []event
+	changed   chan struct{}
+}
+
+func newFakeRelay(t *testing.T) *fakeRelay {
+	r := &fakeRelay{t: t, changed: make(chan struct{}, 100)}
+	upgrader := websocket.Upgrader{}
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := upgrader.Upgrade(w, req, nil)
+		if err != nil {
+			return
+		}
+		r.mu.Lock()
+		r.conns = append(r.conns, conn)
+		r.mu.Unlock()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var msg []json.RawMessage
+			json.Unmarshal(data, &msg)
+			var typ string
+			json.Unmarshal(msg[0], &typ)
+			r.mu.Lock()
+			switch typ {
+			case "REQ":
+				r.filters = append(r.filters, msg[2:]...)
+			case "EVENT":
+				var ev event
+				json.Unmarshal(msg[1], &ev)
+				r.published = append(r.published, ev)
+			}
+			r.mu.Unlock()
+			r.changed <- struct{}{}
+		}
+	}))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func (r *fakeRelay) url() string {
+	return "ws" + strings.TrimPrefix(r.server.URL, "http")
+}
+
+// push sends an event on the most recent connection.
+func (r *fakeRelay) push(ev *event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, _ := json.Marshal([]any{"EVENT", "lingti-dm", ev})
+	r.conns[len(r.conns)-1].WriteMessage(websocket.TextMessage, data)
+}
+
+// dropAll closes every client connection.
+func (r *fakeRelay) dropAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.conns {
+		c.Close()
+	}
+}
+
+// waitFor waits until cond holds under the relay lock.
+func (r *fakeRelay) waitFor(what string, cond func() bool) {
+	r.t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		r.mu.Lock()
+		ok := cond()
+		r.mu.Unlock()
+		if ok {
+			return
+		}
+		select {
+		case <-r.changed:
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			r.t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func TestPlatformDMs(t *testing.T) {
+	minBackoff = 10 * time.Millisecond
+	t.Cleanup(func() { minBackoff = 2 * time.Second })
+
+	botSK := unhex(t, "67dea2ed018072d675f5415ecfaed7d2597555e202d85b3d65ea4e58d2d92ffa")
+	botPK, _ := publicKey(botSK)
+	userSK := unhex(t, "0000000000000000000000000000000000000000000000000000000000000003")
+	userPK, _ := publicKey(userSK)
+	user := hex.EncodeToString(userPK)
+
+	relayA, relayB := newFakeRelay(t), newFakeRelay(t)
+	p, err := New(Config{
+		PrivateKey: "nsec1vl029mgpspedva04g90vltkh6fvh240zqtv9k0t9af8935ke9laqsnlfe5",
+		Relays:     relayA.url() + ", " + relayB.url(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan router.Message, 10)
+	p.SetMessageHandler(func(msg router.Message) { received <- msg })
+	if err := p.Start(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	for _, r := range []*fakeRelay{relayA, relayB} {
+		r.waitFor("subscription", func() bool { return len(r.filters) == 2 })
+		for _, f := range r.filters {
+			var filter struct {
+				Kinds []int    `json:"kinds"`
+				P     []string `json:"#p"`
+			}
+			json.Unmarshal(f, &filter)
+			if len(filter.P) != 1 || filter.P[0] != hex.EncodeToString(botPK) {
+				t.Errorf("filter %s is not limited to the bot's pubkey", f)
+			}
+		}
+	}
+
+	// A NIP-04 DM delivered by both relays reaches the handler once.
+	dm := &event{CreatedAt: time.Now().Unix(), Kind: kindEncryptedDM, Tags: [][]string{{"p", hex.EncodeToString(botPK)}}}
+	dm.Content, _ = nip04Encrypt(userSK, botPK, "ping over nip04")
+	dm.sign(userSK)
+	relayA.push(dm)
+	relayB.push(dm)
+
+	// A forged copy with a valid ID but someone else's pubkey is dropped.
+	forged := *dm
+	forged.PubKey = hex.EncodeToString(botPK)
+	forged.ID = hex.EncodeToString(forged.hash())
+	relayA.push(&forged)
+
+	msg := receive(t, received)
+	if msg.Text != "ping over nip04" || msg.UserID != user || msg.Metadata["protocol"] != "nip04" {
+		t.Errorf("nip04 message = %+v", msg)
+	}
+
+	// A NIP-17 gift wrap is unwrapped to the sender's rumor.
+	wrap, err := giftWrap(userSK, botPK, "ping over nip17")
+	if err != nil {
+		t.Fatal(err)
+	}
+	relayB.push(wrap)
+	msg = receive(t, received)
+	if msg.Text != "ping over nip17" || msg.UserID != user || msg.Metadata["protocol"] != "nip17" {
+		t.Errorf("nip17 message = %+v", msg)
+	}
+	select {
+	case extra := <-received:
+		t.Fatalf("unexpected extra message %+v", extra)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// The user last wrote over NIP-17, so the reply is a gift wrap, published
+	// to both relays.
+	if err := p.Send(t.Context(), user, router.Response{Text: "pong"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []*fakeRelay{relayA, relayB} {
+		r.waitFor("reply", func() bool { return len(r.published) == 1 })
+		reply := r.published[0]
+		if err := reply.verify(); err != nil {
+			t.Fatalf("reply does not verify: %v", err)
+		}
+		rumor, err := unwrapGift(userSK, &reply)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rumor.Content != "pong" || rumor.PubKey != hex.EncodeToString(botPK) {
+			t.Errorf("reply rumor = %+v", rumor)
+		}
+	}
+
+	// After a disconnect the relay is redialed and resubscribed, and a
+	// NIP-04 reply goes out encrypted and signed.
+	relayA.dropAll()
+	relayA.waitFor("resubscription", func() bool { return len(relayA.filters) == 4 })
+	p.protocolsMu.Lock()
+	p.protocols[user] = protocolNIP04
+	p.protocolsMu.Unlock()
+	if err := p.Send(t.Context(), user, router.Response{Text: "pong again"}); err != nil {
+		t.Fatal(err)
+	}
+	relayA.waitFor("second reply", func() bool { return len(relayA.published) == 2 })
+	reply := relayA.published[1]
+	if reply.Kind != kindEncryptedDM || reply.verify() != nil || !reply.hasTag("p", user) {
+		t.Fatalf("nip04 reply = %+v", reply)
+	}
+	if text, err := nip04Decrypt(userSK, botPK, reply.Content); err != nil || text != "pong again" {
+		t.Errorf("nip04 reply decrypts to %q, %v", text, err)
+	}
+}
+
+func receive(t *testing.T, ch <-chan router.Message) router.Message {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a message")
+		return router.Message{}
+	}
+}
+
+func TestSeenSet(t *testing.T) {
+	s := newSeenSet(2)
+	if !s.add("a") || s.add("a") || !s.add("b") {
+		t.Fatal("first adds")
+	}
+	s.add("c") // evicts a
+	if s.has("a") || !s.has("b") || !s.has("c") {
+		t.Errorf("after eviction: a=%v b=%v c=%v", s.has("a"), s.has("b"), s.has("c"))
+	}
+}
@@ -0,0 +1,191 @@
+package nostr
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Reconnect backoff per relay. A connection that stays up for stableAfter
+// resets it.
+var (
+	minBackoff  = 2 * time.Second
+	maxBackoff  = 5 * time.Minute
+	stableAfter = time.Minute
+)
+
+// giftWrapSkew is how far back NIP-59 may date a gift wrap, so subscriptions
+// for them have to reach that much further into the past.
+const giftWrapSkew = 2 * 24 * 60 * 60
+
+// relay is one relay connection, redialed with backoff until Stop.
+type relay struct {
+	url   string
+	mu    sync.Mutex
+	conn  *websocket.Conn
+	since int64 // subscribe to events from this time on
+}
+
+// write sends a message if the relay is connected.
+func (r *relay) write(data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn == nil {
+		return errors.New("not connected")
+	}
+	r.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return r.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (r *relay) setConn(conn *websocket.Conn) {
+	r.mu.Lock()
+	r.conn = conn
+	r.mu.Unlock()
+}
+
+func (r *relay) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+	}
+}
+
+// runRelay keeps a relay connected until the platform stops, waiting twice
+// as long after each failed attempt.
+func (p *Platform) runRelay(r *relay) {
+	backoff := minBackoff
+	for p.ctx.Err() == nil {
+		conn, _, err := websocket.DefaultDialer.DialContext(p.ctx, r.url, nil)
+		if err != nil {
+			log.Printf("[NOSTR] Failed to connect to %s: %v (retrying in %s)", r.url, err, backoff)
+		} else {
+			connected := time.Now()
+			r.setConn(conn)
+			log.Printf("[NOSTR] Connected to relay: %s", r.url)
+
+			if err := r.write(p.subscription(r.since)); err != nil {
+				log.Printf("[NOSTR] Failed to subscribe on %s: %v", r.url, err)
+			} else {
+				p.readRelay(r, conn)
+			}
+			r.close()
+			if p.ctx.Err() != nil {
+				return
+			}
+			// Pick up what was missed while disconnected; duplicates are
+			// dropped by ID.
+			r.since = time.Now().Add(-time.Minute).Unix()
+			if time.Since(connected) >= stableAfter {
+				backoff = minBackoff
+			}
+			log.Printf("[NOSTR] Disconnected from %s, reconnecting in %s", r.url, backoff)
+		}
+
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// subscription builds the REQ for DMs addressed to us: NIP-04 kind 4 events
+// and NIP-17 gift wraps.
+func (p *Platform) subscription(since int64) []byte {
+	data, _ := json.Marshal([]any{"REQ", "lingti-dm",
+		map[string]any{"kinds": []int{kindEncryptedDM}, "#p": []string{p.pubKey}, "since": since},
+		map[string]any{"kinds": []int{kindGiftWrap}, "#p": []string{p.pubKey}, "since": since - giftWrapSkew},
+	})
+	return data
+}
+
+// readRelay handles relay messages until the connection fails.
+func (p *Platform) readRelay(r *relay, conn *websocket.Conn) {
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			if p.ctx.Err() == nil {
+				log.Printf("[NOSTR] Read error from %s: %v", r.url, err)
+			}
+			return
+		}
+
+		var envelope []json.RawMessage
+		if err := json.Unmarshal(msg, &envelope); err != nil || len(envelope) < 2 {
+			continue
+		}
+		var msgType string
+		if err := json.Unmarshal(envelope[0], &msgType); err != nil {
+			continue
+		}
+
+		switch msgType {
+		case "EVENT":
+			// ["EVENT", <subscription id>, <event>]
+			if len(envelope) < 3 {
+				continue
+			}
+			var ev event
+			if err := json.Unmarshal(envelope[2], &ev); err != nil {
+				continue
+			}
+			p.handleEvent(r.url, &ev)
+		case "OK":
+			// ["OK", <event id>, <accepted>, <message>]
+			var accepted bool
+			var reason string
+			if len(envelope) >= 4 {
+				json.Unmarshal(envelope[2], &accepted)
+				json.Unmarshal(envelope[3], &reason)
+			}
+			if !accepted {
+				log.Printf("[NOSTR] %s rejected event %s: %s", r.url, envelope[1], reason)
+			}
+		case "NOTICE", "CLOSED":
+			log.Printf("[NOSTR] %s from %s: %s", msgType, r.url, envelope[len(envelope)-1])
+		}
+	}
+}
+
+// seenSet remembers the most recent event IDs so an event delivered by
+// several relays is handled once.
+type seenSet struct {
+	mu    sync.Mutex
+	ids   map[string]struct{}
+	order []string
+	next  int
+}
+
+func newSeenSet(size int) *seenSet {
+	return &seenSet{ids: make(map[string]struct{}, size), order: make([]string, size)}
+}
+
+func (s *seenSet) has(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.ids[id]
+	return ok
+}
+
+// add records id and reports whether it was new.
+func (s *seenSet) add(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.ids[id]; ok {
+		return false
+	}
+	if old := s.order[s.next]; old != "" {
+		delete(s.ids, old)
+	}
+	s.order[s.next] = id
+	s.next = (s.next + 1) % len(s.order)
+	s.ids[id] = struct{}{}
+	return true
+}
@@ -0,0 +1,100 @@
+package nostr
+
+import (
+	"crypto/rand"
+	"errors"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// secp256k1 keys, BIP-340 Schnorr signatures and ECDH. The curve arithmetic
+// is dcrd's secp256k1, whose field and scalar operations are constant time;
+// BIP-340 comes from btcec, which wraps it (dcrd's own schnorr package is
+// Decred's EC-Schnorr-DCRv0, not BIP-340).
+
+var (
+	errBadSecretKey = errors.New("secret key out of range")
+	errBadPublicKey = errors.New("public key is not on the curve")
+	errBadSignature = errors.New("invalid signature")
+)
+
+// secretKey checks a 32-byte secret key and parses it.
+func secretKey(sk []byte) (*secp256k1.PrivateKey, error) {
+	if len(sk) != 32 {
+		return nil, errBadSecretKey
+	}
+	var d secp256k1.ModNScalar
+	if overflow := d.SetByteSlice(sk); overflow || d.IsZero() {
+		return nil, errBadSecretKey
+	}
+	return secp256k1.NewPrivateKey(&d), nil
+}
+
+// publicKey returns the x-only public key of a secret key.
+func publicKey(sk []byte) ([]byte, error) {
+	key, err := secretKey(sk)
+	if err != nil {
+		return nil, err
+	}
+	return schnorr.SerializePubKey(key.PubKey()), nil
+}
+
+// schnorrSign signs a 32-byte message with fresh auxiliary randomness.
+func schnorrSign(sk, msg []byte) ([]byte, error) {
+	aux := make([]byte, 32)
+	if _, err := rand.Read(aux); err != nil {
+		return nil, err
+	}
+	return schnorrSignAux(sk, msg, aux)
+}
+
+// schnorrSignAux is BIP-340 signing with caller-supplied auxiliary data.
+func schnorrSignAux(sk, msg, aux []byte) ([]byte, error) {
+	key, err := secretKey(sk)
+	if err != nil {
+		return nil, err
+	}
+	if len(aux) != 32 {
+		return nil, errors.New("auxiliary data must be 32 bytes")
+	}
+	sig, err := schnorr.Sign(key, msg, schnorr.CustomNonce([32]byte(aux)))
+	if err != nil {
+		return nil, err
+	}
+	return sig.Serialize(), nil
+}
+
+// schnorrVerify checks a BIP-340 signature.
+func schnorrVerify(pk, msg, sig []byte) error {
+	if len(pk) != 32 || len(sig) != 64 {
+		return errBadSignature
+	}
+	pub, err := schnorr.ParsePubKey(pk)
+	if err != nil {
+		return errBadPublicKey
+	}
+	s, err := schnorr.ParseSignature(sig)
+	if err != nil || !s.Verify(msg, pub) {
+		return errBadSignature
+	}
+	return nil
+}
+
+// sharedX is the x coordinate of ECDH between a secret key and an x-only
+// public key, as NIP-04 and NIP-44 use it.
+func sharedX(sk, pk []byte) ([]byte, error) {
+	key, err := secretKey(sk)
+	if err != nil {
+		return nil, err
+	}
+	if len(pk) != 32 {
+		return nil, errBadPublicKey
+	}
+	pub, err := schnorr.ParsePubKey(pk)
+	if err != nil {
+		return nil, errBadPublicKey
+	}
+	return btcec.GenerateSharedSecret(key, pub), nil
+}
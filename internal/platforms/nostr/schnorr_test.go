@@ -0,0 +1,137 @@
+package nostr
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// Vectors from the BIP-340 reference test-vectors.csv.
+func TestSchnorrBIP340Vectors(t *testing.T) {
+	tests := []struct {
+		secret, public, aux, msg, sig string
+	}{
+		{
+			secret: "0000000000000000000000000000000000000000000000000000000000000003",
+			public: "F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9",
+			aux:    "0000000000000000000000000000000000000000000000000000000000000000",
+			msg:    "0000000000000000000000000000000000000000000000000000000000000000",
+			sig:    "E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA821525F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0",
+		},
+		{
+			secret: "B7E151628AED2A6ABF7158809CF4F3C762E7160F38B4DA56A784D9045190CFEF",
+			public: "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+			aux:    "0000000000000000000000000000000000000000000000000000000000000001",
+			msg:    "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+			sig:    "6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE33418906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A",
+		},
+	}
+	for i, tt := range tests {
+		sk := unhex(t, tt.secret)
+		pk, err := publicKey(sk)
+		if err != nil {
+			t.Fatalf("vector %d: publicKey: %v", i, err)
+		}
+		if got := strings.ToUpper(hex.EncodeToString(pk)); got != tt.public {
+			t.Errorf("vector %d: public key = %s, want %s", i, got, tt.public)
+		}
+		sig, err := schnorrSignAux(sk, unhex(t, tt.msg), unhex(t, tt.aux))
+		if err != nil {
+			t.Fatalf("vector %d: sign: %v", i, err)
+		}
+		if got := strings.ToUpper(hex.EncodeToString(sig)); got != tt.sig {
+			t.Errorf("vector %d: signature = %s, want %s", i, got, tt.sig)
+		}
+		if err := schnorrVerify(pk, unhex(t, tt.msg), unhex(t, tt.sig)); err != nil {
+			t.Errorf("vector %d: verify: %v", i, err)
+		}
+	}
+}
+
+func TestSchnorrVerifyRejects(t *testing.T) {
+	pk := unhex(t, "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659")
+	msg := unhex(t, "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89")
+	sig := unhex(t, "6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE33418906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A")
+
+	flipped := append([]byte(nil), sig...)
+	flipped[63] ^= 1
+	otherMsg := append([]byte(nil), msg...)
+	otherMsg[0] ^= 1
+
+	tests := []struct {
+		name        string
+		pk, msg, sg []byte
+	}{
+		{"tampered signature", pk, msg, flipped},
+		{"other message", pk, otherMsg, sig},
+		// BIP-340 vector 5: public key not on the curve.
+		{"off-curve key", unhex(t, "EEFDEA4CDB677750A420FEE807EACF21EB9898AE79B9768766E4FAA04A2D4A34"), msg, sig},
+		{"short signature", pk, msg, sig[:63]},
+	}
+	for _, tt := range tests {
+		if err := schnorrVerify(tt.pk, tt.msg, tt.sg); err == nil {
+			t.Errorf("%s: verify succeeded", tt.name)
+		}
+	}
+}
+
+func TestSecretKeyRange(t *testing.T) {
+	for _, s := range []string{
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141", // n
+	} {
+		if _, err := publicKey(unhex(t, s)); err == nil {
+			t.Errorf("publicKey(%s) succeeded", s)
+		}
+	}
+}
+
+// Examples from NIP-19.
+func TestParsePrivateKey(t *testing.T) {
+	const want = "67dea2ed018072d675f5415ecfaed7d2597555e202d85b3d65ea4e58d2d92ffa"
+	for _, in := range []string{
+		"nsec1vl029mgpspedva04g90vltkh6fvh240zqtv9k0t9af8935ke9laqsnlfe5",
+		want,
+		" " + want + "\n",
+	} {
+		sk, err := parsePrivateKey(in)
+		if err != nil {
+			t.Fatalf("parsePrivateKey(%q): %v", in, err)
+		}
+		if got := hex.EncodeToString(sk); got != want {
+			t.Errorf("parsePrivateKey(%q) = %s, want %s", in, got, want)
+		}
+	}
+
+	for _, in := range []string{
+		"nsec1vl029mgpspedva04g90vltkh6fvh240zqtv9k0t9af8935ke9laqsnlfe6", // bad checksum
+		"npub10elfcs4fr0l0r8af98jlmgdh9c8tcxjvz9qkw038js35mp4dma8qzvjptg", // not a secret
+		"not a key",
+		"00",
+	} {
+		if _, err := parsePrivateKey(in); err == nil {
+			t.Errorf("parsePrivateKey(%q) succeeded", in)
+		}
+	}
+}
+
+func TestNpub(t *testing.T) {
+	pk := unhex(t, "7e7e9c42a91bfef19fa929e5fda1b72e0ebc1a4c1141673e2794234d86addf4e")
+	const want = "npub10elfcs4fr0l0r8af98jlmgdh9c8tcxjvz9qkw038js35mp4dma8qzvjptg"
+	if got := npub(pk); got != want {
+		t.Errorf("npub = %s, want %s", got, want)
+	}
+	hrp, data, err := bech32Decode(want)
+	if err != nil || hrp != "npub" || hex.EncodeToString(data) != hex.EncodeToString(pk) {
+		t.Errorf("bech32Decode(%s) = %q, %x, %v", want, hrp, data, err)
+	}
+}
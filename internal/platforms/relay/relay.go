@@ -236,6 +236,21 @@ func (p *Platform) Name() string {
 	return "relay"
 }
 
+// Capabilities returns the delivery limits of the platform behind the relay
+func (p *Platform) Capabilities() router.Capabilities {
+	switch p.config.Platform {
+	case "wecom", "wechat":
+		// WeCom markdown and WeChat OA customer messages are limited to 2048 bytes
+		return router.Capabilities{MaxMessageBytes: 2048, SupportsFiles: true}
+	case "feishu":
+		return router.Capabilities{MaxMessageBytes: 30000, SupportsFiles: true}
+	case "slack":
+		return router.Capabilities{MaxMessageLength: 4000, SupportsFiles: true}
+	default:
+		return router.Capabilities{SupportsFiles: true}
+	}
+}
+
 // SetMessageHandler sets the callback for incoming messages
 func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 	p.messageHandler = handler
@@ -54,6 +54,14 @@ func (p *Platform) Name() string {
 	return "signal"
 }
 
+// Capabilities returns the platform's delivery limits
+func (p *Platform) Capabilities() router.Capabilities {
+	// Signal sends text over 2000 characters as an attachment; stay below it
+	return router.Capabilities{
+		MaxMessageLength: 2000,
+	}
+}
+
 // SetMessageHandler sets the callback for incoming messages
 func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 	p.messageHandler = handler
@@ -0,0 +1,148 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pltanton/lingti-bot/internal/markdown"
+	"github.com/pltanton/lingti-bot/internal/router"
+	"github.com/slack-go/slack"
+)
+
+// Block Kit limits
+const (
+	maxSectionText    = 3000
+	maxActionElements = 25
+	maxButtonText     = 75
+)
+
+// linkPrefix marks the action IDs of URL buttons. Slack reports clicks on
+// them too, but they have already done their job by opening the link.
+const linkPrefix = "link:"
+
+// actionBlocks renders text and actions as Block Kit blocks: the text in
+// sections, then one actions block.
+func actionBlocks(text string, actions []router.Action) []slack.Block {
+	var blocks []slack.Block
+	mrkdwn := markdown.Slack(text)
+	for mrkdwn != "" {
+		chunk := truncateRunes(mrkdwn, maxSectionText)
+		mrkdwn = mrkdwn[len(chunk):]
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, chunk, false, false), nil, nil))
+	}
+
+	var elements []slack.BlockElement
+	for _, a := range actions {
+		if len(elements) == maxActionElements {
+			log.Printf("[Slack] Dropping actions beyond %d", maxActionElements)
+			break
+		}
+		label := slack.NewTextBlockObject(slack.PlainTextType, truncateRunes(a.Label, maxButtonText), false, false)
+		switch {
+		case a.Kind == router.ActionSelect:
+			var opts []*slack.OptionBlockObject
+			for _, o := range a.Options {
+				opts = append(opts, slack.NewOptionBlockObject(o.Value,
+					slack.NewTextBlockObject(slack.PlainTextType, truncateRunes(o.Label, maxButtonText), false, false), nil))
+			}
+			elements = append(elements, slack.NewOptionsSelectBlockElement(slack.OptTypeStatic, label, a.ID, opts...))
+		case a.URL != "":
+			elements = append(elements, slack.NewButtonBlockElement(linkPrefix+a.ID, a.Value, label).WithURL(a.URL))
+		default:
+			btn := slack.NewButtonBlockElement(a.ID, a.Value, label)
+			switch a.Style {
+			case "primary":
+				btn = btn.WithStyle(slack.StylePrimary)
+			case "danger":
+				btn = btn.WithStyle(slack.StyleDanger)
+			}
+			elements = append(elements, btn)
+		}
+	}
+	if len(elements) > 0 {
+		blocks = append(blocks, slack.NewActionBlock("actions", elements...))
+	}
+	return blocks
+}
+
+// handleInteraction turns a press on one of our buttons or menus into a
+// message, and replaces the buttons with a note of the choice so it cannot
+// be made twice.
+func (p *Platform) handleInteraction(cb slack.InteractionCallback) {
+	if cb.Type != slack.InteractionTypeBlockActions {
+		return
+	}
+	for _, action := range cb.ActionCallback.BlockActions {
+		if strings.HasPrefix(action.ActionID, linkPrefix) {
+			continue
+		}
+		value, label := action.Value, action.Text.Text
+		if action.Type == slack.ActionType(slack.OptTypeStatic) {
+			value, label = action.SelectedOption.Value, action.SelectedOption.Text.Text
+		}
+
+		p.markChosen(cb, label)
+
+		if p.messageHandler != nil {
+			p.messageHandler(router.Message{
+				ID:        cb.TriggerID,
+				Platform:  "slack",
+				ChannelID: cb.Channel.ID,
+				UserID:    cb.User.ID,
+				Username:  p.getUsername(cb.User.ID),
+				Text:      value,
+				ThreadID:  cb.Message.ThreadTimestamp,
+				Action: &router.ActionEvent{
+					ID:        action.ActionID,
+					Value:     value,
+					MessageID: cb.Container.MessageTs,
+				},
+				Metadata: map[string]string{},
+			})
+		}
+	}
+}
+
+// markChosen swaps the actions block of the pressed message for a context
+// line naming who chose what.
+func (p *Platform) markChosen(cb slack.InteractionCallback, label string) {
+	if cb.Container.MessageTs == "" {
+		return
+	}
+	var blocks []slack.Block
+	for _, b := range cb.Message.Blocks.BlockSet {
+		if b.BlockType() != slack.MBTAction {
+			blocks = append(blocks, b)
+		}
+	}
+	note := fmt.Sprintf("✅ <@%s> 选择了 *%s*", cb.User.ID, label)
+	blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, note, false, false)))
+
+	ctx := p.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_, _, _, err := p.client.UpdateMessageContext(ctx, cb.Channel.ID, cb.Container.MessageTs,
+		slack.MsgOptionText(cb.Message.Text, false), slack.MsgOptionBlocks(blocks...))
+	if err != nil {
+		log.Printf("[Slack] Failed to update message after action: %v", err)
+	}
+}
+
+// truncateRunes cuts s to at most n runes.
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	i := 0
+	for pos := range s {
+		if i == n {
+			return s[:pos]
+		}
+		i++
+	}
+	return s
+}
@@ -2,10 +2,12 @@ package slack
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 
+	"github.com/pltanton/lingti-bot/internal/markdown"
 	"github.com/pltanton/lingti-bot/internal/router"
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
@@ -62,6 +64,18 @@ func (p *Platform) Name() string {
 	return "slack"
 }
 
+// Capabilities returns the platform's delivery limits
+func (p *Platform) Capabilities() router.Capabilities {
+	// Slack truncates at 40k characters but recommends keeping messages under 4000
+	return router.Capabilities{
+		MaxMessageLength: 4000,
+		SupportsEdit:     true,
+		SupportsThreads:  true,
+		SupportsActions:  true,
+		Render:           markdown.Slack,
+	}
+}
+
 // SetMessageHandler sets the callback for incoming messages
 func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 	p.messageHandler = handler
@@ -93,7 +107,11 @@ func (p *Platform) Stop() error {
 // Send sends a message to a Slack channel
 func (p *Platform) Send(ctx context.Context, channelID string, resp router.Response) error {
 	options := []slack.MsgOption{
-		slack.MsgOptionText(resp.Text, false),
+		slack.MsgOptionText(markdown.Slack(resp.Text), false),
+	}
+	// The text stays as the notification fallback
+	if len(resp.Actions) > 0 {
+		options = append(options, slack.MsgOptionBlocks(actionBlocks(resp.Text, resp.Actions)...))
 	}
 
 	if resp.ThreadID != "" {
@@ -101,6 +119,36 @@ func (p *Platform) Send(ctx context.Context, channelID string, resp router.Respo
 	}
 
 	_, _, err := p.client.PostMessageContext(ctx, channelID, options...)
+	return sendError(err)
+}
+
+// SendEditable sends a message and returns its timestamp for later edits
+func (p *Platform) SendEditable(ctx context.Context, channelID string, resp router.Response) (string, error) {
+	options := []slack.MsgOption{
+		slack.MsgOptionText(markdown.Slack(resp.Text), false),
+	}
+	if resp.ThreadID != "" {
+		options = append(options, slack.MsgOptionTS(resp.ThreadID))
+	}
+
+	_, ts, err := p.client.PostMessageContext(ctx, channelID, options...)
+	return ts, sendError(err)
+}
+
+// Edit replaces the text of a previously sent message
+func (p *Platform) Edit(ctx context.Context, channelID, messageID string, resp router.Response) error {
+	_, _, _, err := p.client.UpdateMessageContext(ctx, channelID, messageID,
+		slack.MsgOptionText(markdown.Slack(resp.Text), false))
+	return sendError(err)
+}
+
+// sendError reports Slack's rate limit response as a router.RateLimitError,
+// so the outbound queue honours its Retry-After header.
+func sendError(err error) error {
+	var rl *slack.RateLimitedError
+	if errors.As(err, &rl) {
+		return &router.RateLimitError{RetryAfter: rl.RetryAfter, Err: err}
+	}
 	return err
 }
 
@@ -127,6 +175,14 @@ func (p *Platform) handleEvents() {
 				}
 				p.socketClient.Ack(*evt.Request)
 				p.handleSlashCommand(cmd)
+
+			case socketmode.EventTypeInteractive:
+				cb, ok := evt.Data.(slack.InteractionCallback)
+				if !ok {
+					continue
+				}
+				p.socketClient.Ack(*evt.Request)
+				p.handleInteraction(cb)
 			}
 		}
 	}
@@ -232,3 +288,68 @@ func (p *Platform) getUsername(userID string) string {
 	}
 	return user.Name
 }
+
+// RecentMessages returns recent messages from a channel or thread, oldest
+// first. Direct messages return nil since the agent keeps their history.
+func (p *Platform) RecentMessages(ctx context.Context, channelID, threadID string, limit int) ([]router.Message, error) {
+	if strings.HasPrefix(channelID, "D") {
+		return nil, nil
+	}
+
+	var history []slack.Message
+	if threadID != "" {
+		// Replies come oldest first; fetch a page and keep the tail
+		replies, _, _, err := p.client.GetConversationRepliesContext(ctx, &slack.GetConversationRepliesParameters{
+			ChannelID: channelID,
+			Timestamp: threadID,
+			Limit:     200,
+		})
+		if err != nil {
+			return nil, err
+		}
+		history = replies
+	} else {
+		resp, err := p.client.GetConversationHistoryContext(ctx, &slack.GetConversationHistoryParameters{
+			ChannelID: channelID,
+			Limit:     limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		// History comes newest first
+		for i := len(resp.Messages) - 1; i >= 0; i-- {
+			history = append(history, resp.Messages[i])
+		}
+	}
+	if len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+
+	names := make(map[string]string)
+	msgs := make([]router.Message, 0, len(history))
+	for _, m := range history {
+		if m.Text == "" {
+			continue
+		}
+		username := m.Username
+		switch {
+		case m.User == p.botUserID:
+			username = "bot"
+		case m.User != "":
+			if _, ok := names[m.User]; !ok {
+				names[m.User] = p.getUsername(m.User)
+			}
+			username = names[m.User]
+		}
+		msgs = append(msgs, router.Message{
+			ID:        m.Timestamp,
+			Platform:  "slack",
+			ChannelID: channelID,
+			UserID:    m.User,
+			Username:  username,
+			Text:      p.cleanMention(m.Text),
+			ThreadID:  m.ThreadTimestamp,
+		})
+	}
+	return msgs, nil
+}
@@ -0,0 +1,103 @@
+package teams
+
+import (
+	"encoding/json"
+
+	"github.com/pltanton/lingti-bot/internal/router"
+)
+
+// Submit data of our cards: the action ID, and either the value of the
+// pressed button or, for selects, the ID of the input holding the choice.
+const (
+	submitActionID = "actionId"
+	submitValue    = "value"
+	inputPrefix    = "input:"
+)
+
+// adaptiveCard renders text and actions as an Adaptive Card attachment.
+// Buttons become Action.Submit or Action.OpenUrl; a select becomes a
+// ChoiceSet input followed by its own submit button.
+func adaptiveCard(text string, actions []router.Action) map[string]any {
+	body := []map[string]any{
+		{"type": "TextBlock", "text": text, "wrap": true},
+	}
+	var cardActions []map[string]any
+	for _, a := range actions {
+		switch {
+		case a.Kind == router.ActionSelect:
+			var choices []map[string]string
+			for _, o := range a.Options {
+				choices = append(choices, map[string]string{"title": o.Label, "value": o.Value})
+			}
+			body = append(body,
+				map[string]any{
+					"type":        "Input.ChoiceSet",
+					"id":          inputPrefix + a.ID,
+					"placeholder": a.Label,
+					"choices":     choices,
+				},
+				map[string]any{
+					"type": "ActionSet",
+					"actions": []map[string]any{{
+						"type":  "Action.Submit",
+						"title": "确定",
+						"data":  map[string]string{submitActionID: a.ID},
+					}},
+				})
+		case a.URL != "":
+			cardActions = append(cardActions, map[string]any{
+				"type":  "Action.OpenUrl",
+				"title": a.Label,
+				"url":   a.URL,
+			})
+		default:
+			action := map[string]any{
+				"type":  "Action.Submit",
+				"title": a.Label,
+				"data":  map[string]string{submitActionID: a.ID, submitValue: a.Value},
+			}
+			switch a.Style {
+			case "primary":
+				action["style"] = "positive"
+			case "danger":
+				action["style"] = "destructive"
+			}
+			cardActions = append(cardActions, action)
+		}
+	}
+
+	card := map[string]any{
+		"type":    "AdaptiveCard",
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"version": "1.4",
+		"body":    body,
+	}
+	if len(cardActions) > 0 {
+		card["actions"] = cardActions
+	}
+	return map[string]any{
+		"contentType": "application/vnd.microsoft.card.adaptive",
+		"content":     card,
+	}
+}
+
+// parseSubmit extracts the action from the value of a card submission. It
+// reports false for values that did not come from one of our cards.
+func parseSubmit(raw json.RawMessage) (id, value string, ok bool) {
+	if len(raw) == 0 {
+		return "", "", false
+	}
+	var data map[string]any
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return "", "", false
+	}
+	id, _ = data[submitActionID].(string)
+	if id == "" {
+		return "", "", false
+	}
+	value, _ = data[submitValue].(string)
+	if choice, found := data[inputPrefix+id].(string); found {
+		value = choice
+	}
+	return id, value, value != ""
+}
@@ -14,14 +14,20 @@ import (
 	"time"
 
 	"github.com/pltanton/lingti-bot/internal/router"
+	"github.com/pltanton/lingti-bot/internal/webhookauth"
 )
 
+// botFrameworkMetadata is the OpenID configuration naming the keys Bot
+// Framework signs its requests with.
+const botFrameworkMetadata = "https://login.botframework.com/v1/.well-known/openidconfiguration"
+
 // Platform implements router.Platform for Microsoft Teams via Bot Framework
 type Platform struct {
 	config         Config
 	messageHandler func(msg router.Message)
 	httpClient     *http.Client
 	server         *http.Server
+	verifier       *webhookauth.JWTVerifier
 	accessToken    string
 	tokenExpiry    time.Time
 	tokenMu        sync.Mutex
@@ -34,7 +40,11 @@ type Config struct {
 	AppID       string // Bot Framework App ID
 	AppPassword string // Bot Framework App Password
 	TenantID    string // Azure AD Tenant ID
-	WebhookPort int    // Port for incoming webhooks (default: 8086)
+	WebhookPort int    // Port for incoming webhooks (default: 8086, -1 = shared ingress only)
+
+	// Keys verifies the tokens on incoming requests (default: the keys
+	// Bot Framework publishes, fetched and cached)
+	Keys webhookauth.KeySource
 }
 
 // New creates a new Teams platform
@@ -48,12 +58,20 @@ func New(cfg Config) (*Platform, error) {
 	if cfg.WebhookPort == 0 {
 		cfg.WebhookPort = 8086
 	}
+	if cfg.Keys == nil {
+		cfg.Keys = webhookauth.NewOpenIDJWKS(botFrameworkMetadata)
+	}
 
 	return &Platform{
 		config: cfg,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		verifier: &webhookauth.JWTVerifier{
+			Keys:     cfg.Keys,
+			Issuers:  []string{"https://api.botframework.com"},
+			Audience: cfg.AppID,
+		},
 	}, nil
 }
 
@@ -62,6 +80,15 @@ func (p *Platform) Name() string {
 	return "teams"
 }
 
+// Capabilities returns the platform's delivery limits
+func (p *Platform) Capabilities() router.Capabilities {
+	// Teams activities are limited to roughly 28KB
+	return router.Capabilities{
+		MaxMessageBytes: 28000,
+		SupportsActions: true,
+	}
+}
+
 // SetMessageHandler sets the callback for incoming messages
 func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 	p.messageHandler = handler
@@ -71,25 +98,35 @@ func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 func (p *Platform) Start(ctx context.Context) error {
 	p.ctx, p.cancel = context.WithCancel(ctx)
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/api/messages", p.handleMessage)
-
-	p.server = &http.Server{
-		Addr:    fmt.Sprintf(":%d", p.config.WebhookPort),
-		Handler: mux,
-	}
+	// Without a port of its own the platform is only served by the
+	// gateway's ingress, through WebhookHandler
+	if p.config.WebhookPort >= 0 {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/messages", p.handleMessage)
 
-	go func() {
-		log.Printf("[Teams] Webhook server listening on :%d", p.config.WebhookPort)
-		if err := p.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("[Teams] Server error: %v", err)
+		p.server = &http.Server{
+			Addr:    fmt.Sprintf(":%d", p.config.WebhookPort),
+			Handler: mux,
 		}
-	}()
+
+		go func() {
+			log.Printf("[Teams] Webhook server listening on :%d", p.config.WebhookPort)
+			if err := p.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("[Teams] Server error: %v", err)
+			}
+		}()
+	}
 
 	log.Printf("[Teams] Platform started, app_id: %s", p.config.AppID)
 	return nil
 }
 
+// WebhookHandler serves the webhook endpoint on the gateway's shared
+// ingress, which mounts it at /webhooks/teams.
+func (p *Platform) WebhookHandler() http.Handler {
+	return http.HandlerFunc(p.handleMessage)
+}
+
 // Stop shuts down the Teams connection
 func (p *Platform) Stop() error {
 	if p.cancel != nil {
@@ -105,7 +142,7 @@ func (p *Platform) Stop() error {
 
 // Send sends a message via Bot Framework REST API
 func (p *Platform) Send(ctx context.Context, channelID string, resp router.Response) error {
-	if resp.Text == "" {
+	if resp.Text == "" && len(resp.Actions) == 0 {
 		return nil
 	}
 
@@ -127,6 +164,10 @@ func (p *Platform) Send(ctx context.Context, channelID string, resp router.Respo
 		"type": "message",
 		"text": resp.Text,
 	}
+	if len(resp.Actions) > 0 {
+		payload["text"] = ""
+		payload["attachments"] = []map[string]any{adaptiveCard(resp.Text, resp.Actions)}
+	}
 
 	body, err := json.Marshal(payload)
 	if err != nil {
@@ -208,16 +249,40 @@ func (p *Platform) handleMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Bot Framework signs every request with a JWT whose audience is our
+	// app ID and which names the service URL replies must go to
+	claims, err := p.verifier.VerifyRequest(r)
+	if err != nil {
+		webhookauth.Reject(w, r, "teams", http.StatusUnauthorized, err)
+		return
+	}
+
 	var activity botActivity
 	if err := json.NewDecoder(r.Body).Decode(&activity); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	if su := claims.String("serviceurl"); su != activity.ServiceURL {
+		webhookauth.Reject(w, r, "teams", http.StatusUnauthorized,
+			fmt.Errorf("%w: token is for service URL %q, activity names %q", webhookauth.ErrBadAudience, su, activity.ServiceURL))
+		return
+	}
+	webhookauth.Accept("teams")
 
 	w.WriteHeader(http.StatusOK)
 
-	// Only process message activities
-	if activity.Type != "message" || activity.Text == "" {
+	// Only process message activities; card submissions carry a value
+	// instead of text
+	if activity.Type != "message" {
+		return
+	}
+	var action *router.ActionEvent
+	text := activity.Text
+	if id, value, ok := parseSubmit(activity.Value); ok {
+		action = &router.ActionEvent{ID: id, Value: value, MessageID: activity.ReplyToID}
+		text = value
+	}
+	if text == "" {
 		return
 	}
 
@@ -231,7 +296,8 @@ func (p *Platform) handleMessage(w http.ResponseWriter, r *http.Request) {
 			ChannelID: channelID,
 			UserID:    activity.From.ID,
 			Username:  activity.From.Name,
-			Text:      activity.Text,
+			Text:      text,
+			Action:    action,
 			Metadata: map[string]string{
 				"service_url":     activity.ServiceURL,
 				"conversation_id": activity.Conversation.ID,
@@ -246,6 +312,8 @@ type botActivity struct {
 	Type         string `json:"type"`
 	ID           string `json:"id"`
 	Text         string `json:"text"`
+	Value        json.RawMessage `json:"value"`
+	ReplyToID    string `json:"replyToId"`
 	ServiceURL   string `json:"serviceUrl"`
 	From         botAccount `json:"from"`
 	Conversation struct {
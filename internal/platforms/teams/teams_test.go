@@ -0,0 +1,94 @@
+package teams
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pltanton/lingti-bot/internal/router"
+	"github.com/pltanton/lingti-bot/internal/webhookauth/webhookauthtest"
+)
+
+func TestHandleMessageVerifiesToken(t *testing.T) {
+	signer := webhookauthtest.NewSigner(t)
+	p, err := New(Config{AppID: "app-id", AppPassword: "secret", Keys: signer.Keys()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []router.Message
+	p.SetMessageHandler(func(msg router.Message) { got = append(got, msg) })
+
+	const activity = `{"type":"message","id":"1","text":"hi","serviceUrl":"https://smba.example/","from":{"id":"u1"},"conversation":{"id":"c1"}}`
+	claims := func(aud, serviceURL string) map[string]any {
+		return map[string]any{"iss": "https://api.botframework.com", "aud": aud, "serviceurl": serviceURL}
+	}
+
+	tests := []struct {
+		name     string
+		auth     string
+		wantCode int
+	}{
+		{"valid", "Bearer " + signer.Token(t, claims("app-id", "https://smba.example/")), http.StatusOK},
+		{"no token", "", http.StatusUnauthorized},
+		{"other bot", "Bearer " + signer.Token(t, claims("other-app", "https://smba.example/")), http.StatusUnauthorized},
+		{"other service URL", "Bearer " + signer.Token(t, claims("app-id", "https://evil.example/")), http.StatusUnauthorized},
+		{"forged", "Bearer " + webhookauthtest.NewSigner(t).Token(t, claims("app-id", "https://smba.example/")), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/messages", strings.NewReader(activity))
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		rec := httptest.NewRecorder()
+		p.WebhookHandler().ServeHTTP(rec, req)
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.wantCode)
+		}
+	}
+	if len(got) != 1 || got[0].Text != "hi" {
+		t.Errorf("delivered %+v, want only the verified message", got)
+	}
+}
+
+func TestHandleMessageCardSubmit(t *testing.T) {
+	signer := webhookauthtest.NewSigner(t)
+	p, err := New(Config{AppID: "app-id", AppPassword: "secret", Keys: signer.Keys()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []router.Message
+	p.SetMessageHandler(func(msg router.Message) { got = append(got, msg) })
+	token := signer.Token(t, map[string]any{"iss": "https://api.botframework.com", "aud": "app-id", "serviceurl": "https://smba.example/"})
+
+	tests := []struct {
+		value     string
+		wantID    string
+		wantValue string
+	}{
+		{`{"actionId":"approve","value":"yes"}`, "approve", "yes"},
+		{`{"actionId":"env","input:env":"staging"}`, "env", "staging"},
+		{`{"somethingElse":"x"}`, "", ""},
+	}
+	for _, tt := range tests {
+		got = nil
+		activity := `{"type":"message","id":"2","replyToId":"card1","value":` + tt.value +
+			`,"serviceUrl":"https://smba.example/","from":{"id":"u1"},"conversation":{"id":"c1"}}`
+		req := httptest.NewRequest(http.MethodPost, "/api/messages", strings.NewReader(activity))
+		req.Header.Set("Authorization", "Bearer "+token)
+		p.WebhookHandler().ServeHTTP(httptest.NewRecorder(), req)
+
+		if tt.wantID == "" {
+			if len(got) != 0 {
+				t.Errorf("%s: delivered %+v", tt.value, got)
+			}
+			continue
+		}
+		if len(got) != 1 || got[0].Action == nil {
+			t.Fatalf("%s: delivered %+v", tt.value, got)
+		}
+		if a := got[0].Action; a.ID != tt.wantID || a.Value != tt.wantValue || a.MessageID != "card1" || got[0].Text != tt.wantValue {
+			t.Errorf("%s: action %+v, text %q", tt.value, a, got[0].Text)
+		}
+	}
+}
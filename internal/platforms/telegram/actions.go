@@ -0,0 +1,90 @@
+package telegram
+
+import (
+	"fmt"
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pltanton/lingti-bot/internal/router"
+)
+
+// Telegram limits callback data to 64 bytes
+const maxCallbackData = 64
+
+// buttonsPerRow is how many buttons share a keyboard row
+const buttonsPerRow = 3
+
+// inlineKeyboard renders actions as an inline keyboard. Buttons share rows;
+// each option of a select gets a row of its own, since Telegram has no
+// drop-down menus.
+func inlineKeyboard(actions []router.Action) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	flush := func() {
+		if len(row) > 0 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	for _, a := range actions {
+		switch {
+		case a.Kind == router.ActionSelect:
+			flush()
+			for _, o := range a.Options {
+				rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData(o.Label, router.EncodeActionPayload(a.ID, o.Value, maxCallbackData))))
+			}
+		case a.URL != "":
+			row = append(row, tgbotapi.NewInlineKeyboardButtonURL(a.Label, a.URL))
+		default:
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(a.Label, router.EncodeActionPayload(a.ID, a.Value, maxCallbackData)))
+		}
+		if len(row) == buttonsPerRow {
+			flush()
+		}
+	}
+	flush()
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// handleCallback turns a press on an inline keyboard button into a message
+// and removes the keyboard so the choice cannot be made twice.
+func (p *Platform) handleCallback(q *tgbotapi.CallbackQuery) {
+	id, value, ok := router.DecodeActionPayload(q.Data)
+	answer := tgbotapi.NewCallback(q.ID, "")
+	if !ok {
+		answer.Text = "该操作已过期"
+	}
+	if _, err := p.bot.Request(answer); err != nil {
+		log.Printf("[Telegram] Failed to answer callback: %v", err)
+	}
+	if !ok || q.Message == nil {
+		return
+	}
+
+	chatID := q.Message.Chat.ID
+	clear := tgbotapi.NewEditMessageReplyMarkup(chatID, q.Message.MessageID,
+		tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}})
+	if _, err := p.bot.Request(clear); err != nil {
+		log.Printf("[Telegram] Failed to remove keyboard: %v", err)
+	}
+
+	if p.messageHandler != nil {
+		p.messageHandler(router.Message{
+			ID:        q.ID,
+			Platform:  "telegram",
+			ChannelID: fmt.Sprintf("%d", chatID),
+			UserID:    fmt.Sprintf("%d", q.From.ID),
+			Username:  getUsername(q.From),
+			Text:      value,
+			Action: &router.ActionEvent{
+				ID:        id,
+				Value:     value,
+				MessageID: fmt.Sprintf("%d", q.Message.MessageID),
+			},
+			Metadata: map[string]string{
+				"chat_type": q.Message.Chat.Type,
+			},
+		})
+	}
+}
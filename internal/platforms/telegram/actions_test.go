@@ -0,0 +1,38 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pltanton/lingti-bot/internal/router"
+)
+
+func TestInlineKeyboard(t *testing.T) {
+	kb := inlineKeyboard(router.NormalizeActions([]router.Action{
+		{Label: "A"}, {Label: "B"}, {Label: "C"}, {Label: "D", URL: "https://example.com"},
+		{Kind: router.ActionSelect, ID: "env", Label: "环境", Options: []router.ActionOption{
+			{Label: "prod"}, {Label: strings.Repeat("很长的选项", 10)},
+		}},
+	}))
+
+	var widths []int
+	for _, row := range kb.InlineKeyboard {
+		widths = append(widths, len(row))
+	}
+	if got := fmt.Sprint(widths); got != "[3 1 1 1]" {
+		t.Fatalf("row widths = %s, want [3 1 1 1]", got)
+	}
+	if kb.InlineKeyboard[1][0].URL == nil || *kb.InlineKeyboard[1][0].URL != "https://example.com" {
+		t.Errorf("link button = %+v", kb.InlineKeyboard[1][0])
+	}
+	for _, row := range kb.InlineKeyboard[2:] {
+		data := *row[0].CallbackData
+		if len(data) > maxCallbackData {
+			t.Errorf("callback data %q exceeds %d bytes", data, maxCallbackData)
+		}
+		if id, _, ok := router.DecodeActionPayload(data); !ok || id != "env" {
+			t.Errorf("callback data %q decodes to %q, %v", data, id, ok)
+		}
+	}
+}
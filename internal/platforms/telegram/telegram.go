@@ -2,18 +2,28 @@ package telegram
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pltanton/lingti-bot/internal/markdown"
 	"github.com/pltanton/lingti-bot/internal/router"
 )
 
+// historySize is how many recent messages are kept per group chat
+const historySize = 50
+
 // Platform implements router.Platform for Telegram
 type Platform struct {
 	bot            *tgbotapi.BotAPI
 	messageHandler func(msg router.Message)
+	historyMu      sync.Mutex
+	history        map[int64][]router.Message // recent group messages per chat
 	ctx            context.Context
 	cancel         context.CancelFunc
 }
@@ -45,6 +55,18 @@ func (p *Platform) Name() string {
 	return "telegram"
 }
 
+// Capabilities returns the platform's delivery limits
+func (p *Platform) Capabilities() router.Capabilities {
+	// Telegram caps text messages at 4096 characters
+	return router.Capabilities{
+		MaxMessageLength: 4096,
+		SupportsEdit:     true,
+		SupportsThreads:  true,
+		SupportsActions:  true,
+		Render:           markdown.TelegramHTML,
+	}
+}
+
 // SetMessageHandler sets the callback for incoming messages
 func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 	p.messageHandler = handler
@@ -76,15 +98,29 @@ func (p *Platform) Stop() error {
 
 // Send sends a message to a Telegram chat
 func (p *Platform) Send(ctx context.Context, channelID string, resp router.Response) error {
+	if resp.Text != "" || len(resp.Actions) > 0 {
+		if _, err := p.SendEditable(ctx, channelID, resp); err != nil {
+			return err
+		}
+	}
+	return p.sendVoice(channelID, resp.Files)
+}
+
+// SendEditable sends a message and returns its ID for later edits
+func (p *Platform) SendEditable(ctx context.Context, channelID string, resp router.Response) (string, error) {
 	chatID, err := parseChatID(channelID)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	msg := tgbotapi.NewMessage(chatID, resp.Text)
+	msg := tgbotapi.NewMessage(chatID, markdown.TelegramHTML(resp.Text))
+
+	// HTML is the only parse mode whose escaping rules we can satisfy reliably
+	msg.ParseMode = tgbotapi.ModeHTML
 
-	// Enable Markdown formatting
-	msg.ParseMode = "Markdown"
+	if len(resp.Actions) > 0 {
+		msg.ReplyMarkup = inlineKeyboard(resp.Actions)
+	}
 
 	// Reply to specific message if ThreadID is set
 	if resp.ThreadID != "" {
@@ -93,10 +129,92 @@ func (p *Platform) Send(ctx context.Context, channelID string, resp router.Respo
 		}
 	}
 
-	_, err = p.bot.Send(msg)
+	sent, err := p.bot.Send(msg)
+	if err != nil {
+		return "", sendError(err)
+	}
+	id := fmt.Sprintf("%d", sent.MessageID)
+	p.remember(chatID, router.Message{ID: id, Username: "bot", Text: resp.Text})
+	return id, nil
+}
+
+// sendError reports a 429 from the Bot API as a router.RateLimitError, so
+// the outbound queue waits as long as Telegram asks.
+func sendError(err error) error {
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests {
+		return &router.RateLimitError{RetryAfter: time.Duration(apiErr.RetryAfter) * time.Second, Err: err}
+	}
 	return err
 }
 
+// Edit replaces the text of a previously sent message
+func (p *Platform) Edit(ctx context.Context, channelID, messageID string, resp router.Response) error {
+	chatID, err := parseChatID(channelID)
+	if err != nil {
+		return err
+	}
+	msgID, err := parseMessageID(messageID)
+	if err != nil {
+		return err
+	}
+
+	edit := tgbotapi.NewEditMessageText(chatID, msgID, markdown.TelegramHTML(resp.Text))
+	edit.ParseMode = tgbotapi.ModeHTML
+
+	if _, err := p.bot.Send(edit); err != nil {
+		return sendError(err)
+	}
+	p.remember(chatID, router.Message{ID: messageID, Username: "bot", Text: resp.Text})
+	return nil
+}
+
+// RecentMessages returns recent group messages from the chat buffer, oldest
+// first. Private chats return nil since the agent keeps their history.
+func (p *Platform) RecentMessages(ctx context.Context, channelID, threadID string, limit int) ([]router.Message, error) {
+	chatID, err := parseChatID(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	p.historyMu.Lock()
+	defer p.historyMu.Unlock()
+	buf := p.history[chatID]
+	if len(buf) > limit {
+		buf = buf[len(buf)-limit:]
+	}
+	return append([]router.Message(nil), buf...), nil
+}
+
+// remember records a group chat message for RecentMessages. Bots cannot read
+// chat history through the API, so the buffer holds what the bot has seen:
+// every group message if privacy mode is disabled in @BotFather, otherwise
+// only mentions and replies.
+func (p *Platform) remember(chatID int64, msg router.Message) {
+	// Private chats have positive IDs, groups negative ones
+	if chatID > 0 || msg.Text == "" {
+		return
+	}
+
+	p.historyMu.Lock()
+	defer p.historyMu.Unlock()
+	if p.history == nil {
+		p.history = make(map[int64][]router.Message)
+	}
+	buf := p.history[chatID]
+	for i := range buf {
+		if buf[i].ID == msg.ID {
+			buf[i] = msg
+			return
+		}
+	}
+	buf = append(buf, msg)
+	if len(buf) > historySize {
+		buf = buf[len(buf)-historySize:]
+	}
+	p.history[chatID] = buf
+}
+
 // handleUpdates processes incoming Telegram updates
 func (p *Platform) handleUpdates(updates tgbotapi.UpdatesChannel) {
 	for {
@@ -104,6 +222,10 @@ func (p *Platform) handleUpdates(updates tgbotapi.UpdatesChannel) {
 		case <-p.ctx.Done():
 			return
 		case update := <-updates:
+			if update.CallbackQuery != nil {
+				p.handleCallback(update.CallbackQuery)
+				continue
+			}
 			if update.Message == nil {
 				continue
 			}
@@ -113,12 +235,23 @@ func (p *Platform) handleUpdates(updates tgbotapi.UpdatesChannel) {
 				continue
 			}
 
+			p.remember(update.Message.Chat.ID, router.Message{
+				ID:       fmt.Sprintf("%d", update.Message.MessageID),
+				UserID:   fmt.Sprintf("%d", update.Message.From.ID),
+				Username: getUsername(update.Message.From),
+				Text:     update.Message.Text,
+			})
+
 			// Check if we should respond
 			if !p.shouldRespond(update.Message) {
 				continue
 			}
 
 			text := p.cleanMention(update.Message.Text)
+			mediaID, mediaType := voiceMedia(update.Message)
+			if mediaID != "" {
+				text = "[语音]"
+			}
 			if text == "" {
 				continue
 			}
@@ -137,6 +270,8 @@ func (p *Platform) handleUpdates(updates tgbotapi.UpdatesChannel) {
 					Username:  getUsername(update.Message.From),
 					Text:      text,
 					ThreadID:  threadID,
+					MediaID:   mediaID,
+					MediaType: mediaType,
 					Metadata: map[string]string{
 						"chat_type": update.Message.Chat.Type,
 					},
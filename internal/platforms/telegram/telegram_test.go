@@ -0,0 +1,27 @@
+package telegram
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pltanton/lingti-bot/internal/router"
+)
+
+func TestSendError(t *testing.T) {
+	limited := &tgbotapi.Error{
+		Code:               429,
+		Message:            "Too Many Requests: retry after 7",
+		ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 7},
+	}
+	var rl *router.RateLimitError
+	if err := sendError(limited); !errors.As(err, &rl) || rl.RetryAfter != 7*time.Second {
+		t.Errorf("sendError(429) = %v, want RateLimitError after 7s", err)
+	}
+
+	other := &tgbotapi.Error{Code: 400, Message: "Bad Request: chat not found"}
+	if err := sendError(other); err != other {
+		t.Errorf("sendError(400) = %v, want it unchanged", err)
+	}
+}
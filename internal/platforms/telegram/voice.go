@@ -0,0 +1,77 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pltanton/lingti-bot/internal/router"
+	"github.com/pltanton/lingti-bot/internal/speech"
+)
+
+// voiceMedia returns the file ID of a voice note or audio file in msg, and
+// the media type to report it as.
+func voiceMedia(msg *tgbotapi.Message) (fileID, mediaType string) {
+	switch {
+	case msg.Voice != nil:
+		return msg.Voice.FileID, "voice"
+	case msg.Audio != nil:
+		return msg.Audio.FileID, "voice"
+	}
+	return "", ""
+}
+
+// DownloadMedia implements router.MediaDownloader.
+func (p *Platform) DownloadMedia(ctx context.Context, msg router.Message) (speech.Audio, error) {
+	fileURL, err := p.bot.GetFileDirectURL(msg.MediaID)
+	if err != nil {
+		return speech.Audio{}, fmt.Errorf("failed to get file URL: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return speech.Audio{}, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return speech.Audio{}, fmt.Errorf("failed to download file: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return speech.Audio{}, fmt.Errorf("failed to download file: %s", resp.Status)
+	}
+	data, err := speech.ReadAudio(resp.Body)
+	if err != nil {
+		return speech.Audio{}, err
+	}
+
+	// Voice notes are Ogg Opus (.oga); audio files keep their extension
+	format := "ogg"
+	if i := strings.LastIndex(fileURL, "."); i >= 0 {
+		if ext := strings.ToLower(fileURL[i+1:]); ext != "oga" && !strings.Contains(ext, "/") {
+			format = ext
+		}
+	}
+	return speech.Audio{Data: data, Format: format}, nil
+}
+
+// sendVoice sends the voice attachments of a response as voice notes.
+// Other attachments are not supported and are skipped.
+func (p *Platform) sendVoice(channelID string, files []router.FileAttachment) error {
+	for _, file := range files {
+		if file.MediaType != "voice" {
+			continue
+		}
+		chatID, err := parseChatID(channelID)
+		if err != nil {
+			return err
+		}
+		if _, err := p.bot.Send(tgbotapi.NewVoice(chatID, tgbotapi.FilePath(file.Path))); err != nil {
+			log.Printf("[Telegram] Failed to send voice: %v", err)
+			return sendError(err)
+		}
+	}
+	return nil
+}
@@ -50,6 +50,14 @@ func (p *Platform) Name() string {
 	return "twitch"
 }
 
+// Capabilities returns the platform's delivery limits
+func (p *Platform) Capabilities() router.Capabilities {
+	// Twitch chat messages are limited to 500 characters
+	return router.Capabilities{
+		MaxMessageLength: 500,
+	}
+}
+
 // SetMessageHandler sets the callback for incoming messages
 func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 	p.messageHandler = handler
@@ -21,7 +21,7 @@ var indexHTML []byte
 
 // Config holds webapp configuration.
 type Config struct {
-	Port  int    // HTTP port, e.g. 8080
+	Port  int    // HTTP port, e.g. 8080 (-1 = shared ingress only)
 	Token string // Optional Bearer token auth (empty = no auth)
 }
 
@@ -68,8 +68,8 @@ type Platform struct {
 
 // New creates a new webapp Platform.
 func New(cfg Config) (*Platform, error) {
-	if cfg.Port <= 0 {
-		return nil, fmt.Errorf("webapp: port must be > 0")
+	if cfg.Port == 0 {
+		return nil, fmt.Errorf("webapp: port is required")
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	p := &Platform{
@@ -85,14 +85,16 @@ func New(cfg Config) (*Platform, error) {
 
 func (p *Platform) Name() string { return "webapp" }
 
+func (p *Platform) Capabilities() router.Capabilities { return router.Capabilities{} }
+
 func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 	p.messageHandler = handler
 }
 
 func (p *Platform) Start(ctx context.Context) error {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", p.serveIndex)
-	mux.HandleFunc("/ws", p.serveWS)
+	if p.cfg.Port < 0 {
+		return nil // served by the gateway's ingress only
+	}
 
 	// Try ports starting from cfg.Port, incrementing until one is free.
 	port := p.cfg.Port
@@ -112,7 +114,7 @@ func (p *Platform) Start(ctx context.Context) error {
 	}
 
 	p.cfg.Port = port // update so Stop/logs reflect actual port
-	p.server = &http.Server{Handler: mux}
+	p.server = &http.Server{Handler: p.WebhookHandler()}
 
 	go func() {
 		if err := p.server.Serve(ln); err != nil && err != http.ErrServerClosed {
@@ -124,6 +126,15 @@ func (p *Platform) Start(ctx context.Context) error {
 	return nil
 }
 
+// WebhookHandler serves the chat UI and its WebSocket. The gateway's shared
+// ingress mounts it at /webhooks/webapp/.
+func (p *Platform) WebhookHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", p.serveIndex)
+	mux.HandleFunc("/ws", p.serveWS)
+	return mux
+}
+
 func (p *Platform) Stop() error {
 	p.cancel()
 	if p.server != nil {
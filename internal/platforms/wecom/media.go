@@ -2,6 +2,7 @@ package wecom
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,8 +11,11 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/pltanton/lingti-bot/internal/logger"
+	"github.com/pltanton/lingti-bot/internal/router"
+	"github.com/pltanton/lingti-bot/internal/speech"
 )
 
 const (
@@ -139,6 +143,53 @@ func (p *Platform) GetMedia(mediaID string, savePath string) error {
 	return downloadFile(url, savePath)
 }
 
+// DownloadMedia implements router.MediaDownloader. WeCom voice messages are
+// AMR unless the message says otherwise.
+func (p *Platform) DownloadMedia(ctx context.Context, msg router.Message) (speech.Audio, error) {
+	f, err := os.CreateTemp("", "lingti-wecom-media-*")
+	if err != nil {
+		return speech.Audio{}, err
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	if err := p.GetMedia(msg.MediaID, f.Name()); err != nil {
+		return speech.Audio{}, err
+	}
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return speech.Audio{}, err
+	}
+	format := strings.ToLower(msg.Metadata["format"])
+	if format == "" {
+		format = "amr"
+	}
+	return speech.Audio{Data: data, Format: format}, nil
+}
+
+// voiceFile returns a path to an AMR version of a voice file, converting it
+// if needed, since voice messages must be AMR. The returned cleanup removes
+// any converted copy.
+func voiceFile(ctx context.Context, path string) (string, func(), error) {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	if ext == "amr" {
+		return path, func() {}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	amr, err := speech.Convert(ctx, speech.Audio{Data: data, Format: ext}, "amr")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to convert voice to AMR: %w", err)
+	}
+	converted, err := speech.WriteTemp(amr)
+	if err != nil {
+		return "", nil, err
+	}
+	return converted, func() { os.Remove(converted) }, nil
+}
+
 // GetHDVoice downloads a high-definition voice file (speex 16K) by media_id.
 // This provides better quality than GetMedia for voice messages recorded via JSSDK.
 func (p *Platform) GetHDVoice(mediaID string, savePath string) error {
@@ -345,9 +396,16 @@ func downloadFile(url string, savePath string) error {
 	}
 	defer out.Close()
 
-	if _, err := io.Copy(out, resp.Body); err != nil {
+	// Temporary media are at most 20MB, so the voice cap fits every type
+	n, err := io.Copy(out, io.LimitReader(resp.Body, speech.MaxAudioSize+1))
+	if err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
+	if n > speech.MaxAudioSize {
+		out.Close()
+		os.Remove(savePath)
+		return fmt.Errorf("media is larger than %d MB", speech.MaxAudioSize>>20)
+	}
 
 	logger.Info("[WeCom] Downloaded media to %s", savePath)
 	return nil
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/pltanton/lingti-bot/internal/logger"
+	"github.com/pltanton/lingti-bot/internal/markdown"
 	"github.com/pltanton/lingti-bot/internal/router"
 )
 
@@ -92,11 +93,27 @@ func New(cfg Config) (*Platform, error) {
 	return p, nil
 }
 
+// WebhookHandler serves the callback endpoint on the gateway's shared
+// ingress, which mounts it at /webhooks/wecom.
+func (p *Platform) WebhookHandler() http.Handler {
+	return http.HandlerFunc(p.handleCallback)
+}
+
 // Name returns the platform name
 func (p *Platform) Name() string {
 	return "wecom"
 }
 
+// Capabilities returns the platform's delivery limits
+func (p *Platform) Capabilities() router.Capabilities {
+	// WeCom markdown and kf text content is limited to 2048 bytes
+	return router.Capabilities{
+		MaxMessageBytes: 2048,
+		SupportsFiles:   true,
+		Render:          markdown.WeCom,
+	}
+}
+
 // SetMessageHandler sets the callback for incoming messages
 func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 	p.messageHandler = handler
@@ -146,7 +163,8 @@ func (p *Platform) Send(ctx context.Context, userID string, resp router.Response
 	// Handle KF (customer service) messages via kf/send_msg API
 	if resp.Metadata != nil && resp.Metadata["kf"] == "true" {
 		if resp.Text != "" {
-			if err := p.SendKfMessage(resp.Metadata["external_userid"], resp.Metadata["open_kfid"], resp.Text); err != nil {
+			text := markdown.Plain(resp.Text)
+			if err := p.SendKfMessage(resp.Metadata["external_userid"], resp.Metadata["open_kfid"], text); err != nil {
 				return err
 			}
 		}
@@ -155,7 +173,7 @@ func (p *Platform) Send(ctx context.Context, userID string, resp router.Response
 
 	// Send text message if present
 	if resp.Text != "" {
-		if err := p.sendTextMessage(userID, resp.Text); err != nil {
+		if err := p.sendTextMessage(userID, markdown.WeCom(resp.Text)); err != nil {
 			return err
 		}
 	}
@@ -173,7 +191,19 @@ func (p *Platform) Send(ctx context.Context, userID string, resp router.Response
 			name = filepath.Base(file.Path)
 		}
 
-		mediaID, err := p.UploadMedia(file.Path, mediaType)
+		path := file.Path
+		if mediaType == "voice" {
+			converted, cleanup, err := voiceFile(ctx, path)
+			if err != nil {
+				logger.Error("[WeCom] Failed to prepare voice %s: %v", file.Path, err)
+				failCount++
+				continue
+			}
+			defer cleanup()
+			path = converted
+		}
+
+		mediaID, err := p.UploadMedia(path, mediaType)
 		if err != nil {
 			logger.Error("[WeCom] Failed to upload %s: %v", file.Path, err)
 			_ = p.sendTextMessage(userID, fmt.Sprintf("[Error] Failed to send file \"%s\": %v", name, err))
@@ -349,17 +379,21 @@ func (p *Platform) processMessage(plaintext []byte) {
 		// Text is already set via msg.Content
 	case "image":
 		routerMsg.MediaID = msg.MediaId
+		routerMsg.MediaType = "image"
 		routerMsg.Text = "[图片]"
 		routerMsg.Metadata["pic_url"] = msg.PicUrl
 	case "voice":
 		routerMsg.MediaID = msg.MediaId
+		routerMsg.MediaType = "voice"
 		routerMsg.Text = "[语音]"
 		routerMsg.Metadata["format"] = msg.Format
 	case "video":
 		routerMsg.MediaID = msg.MediaId
+		routerMsg.MediaType = "video"
 		routerMsg.Text = "[视频]"
 	case "file":
 		routerMsg.MediaID = msg.MediaId
+		routerMsg.MediaType = "file"
 		routerMsg.FileName = msg.FileName
 		routerMsg.Text = "[文件] " + msg.FileName
 		routerMsg.Metadata["file_size"] = msg.FileSize
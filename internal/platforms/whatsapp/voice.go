@@ -0,0 +1,154 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pltanton/lingti-bot/internal/router"
+	"github.com/pltanton/lingti-bot/internal/speech"
+)
+
+// DownloadMedia implements router.MediaDownloader. Media is fetched in two
+// steps: the media ID resolves to a short-lived URL, which is then
+// downloaded with the same access token.
+func (p *Platform) DownloadMedia(ctx context.Context, msg router.Message) (speech.Audio, error) {
+	var info struct {
+		URL      string `json:"url"`
+		MimeType string `json:"mime_type"`
+	}
+	data, err := p.get(ctx, p.graphURL+"/"+msg.MediaID)
+	if err != nil {
+		return speech.Audio{}, err
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return speech.Audio{}, fmt.Errorf("failed to decode media info: %w", err)
+	}
+	if data, err = p.get(ctx, info.URL); err != nil {
+		return speech.Audio{}, err
+	}
+	return speech.Audio{Data: data, Format: audioFormat(info.MimeType)}, nil
+}
+
+// get fetches an authenticated Graph API or media URL, up to
+// speech.MaxAudioSize
+func (p *Platform) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.config.AccessToken)
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch media: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := speech.ReadAudio(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("WhatsApp API error %d: %s", resp.StatusCode, string(data))
+	}
+	return data, nil
+}
+
+// audioFormat maps a MIME type such as "audio/ogg; codecs=opus" to a format
+func audioFormat(mimeType string) string {
+	mediaType, _, _ := mime.ParseMediaType(mimeType)
+	switch sub := strings.TrimPrefix(mediaType, "audio/"); sub {
+	case "mpeg":
+		return "mp3"
+	case "mp4":
+		return "m4a"
+	case "":
+		return "ogg"
+	default:
+		return sub
+	}
+}
+
+// sendVoice uploads the voice attachments of a response and sends them as
+// audio messages. Other attachments are not supported and are skipped.
+func (p *Platform) sendVoice(ctx context.Context, channelID string, files []router.FileAttachment) error {
+	for _, file := range files {
+		if file.MediaType != "voice" {
+			continue
+		}
+		mediaID, err := p.uploadAudio(ctx, file.Path)
+		if err != nil {
+			return err
+		}
+		if err := p.sendMessage(ctx, map[string]any{
+			"messaging_product": "whatsapp",
+			"to":                channelID,
+			"type":              "audio",
+			"audio":             map[string]string{"id": mediaID},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uploadAudio uploads an audio file and returns its media ID
+func (p *Platform) uploadAudio(ctx context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	mimeType := "audio/ogg"
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".mp3" {
+		mimeType = "audio/mpeg"
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	w.WriteField("messaging_product", "whatsapp")
+	w.WriteField("type", mimeType)
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, filepath.Base(path)))
+	h.Set("Content-Type", mimeType)
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return "", err
+	}
+	part.Write(data)
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	url := fmt.Sprintf("%s/%s/media", p.graphURL, p.config.PhoneNumberID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+p.config.AccessToken)
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload media: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		ID string `json:"id"`
+	}
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("WhatsApp API error %d: %s", resp.StatusCode, string(respBody))
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil || result.ID == "" {
+		return "", fmt.Errorf("failed to decode upload response: %s", string(respBody))
+	}
+	return result.ID, nil
+}
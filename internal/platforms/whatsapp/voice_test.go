@@ -0,0 +1,70 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pltanton/lingti-bot/internal/router"
+)
+
+func TestVoiceRoundTrip(t *testing.T) {
+	var sent []map[string]any
+	mux := http.NewServeMux()
+	var srv *httptest.Server
+	mux.HandleFunc("/media-1", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"url": srv.URL + "/download/media-1", "mime_type": "audio/ogg; codecs=opus"})
+	})
+	mux.HandleFunc("/download/media-1", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("OggS"))
+	})
+	mux.HandleFunc("/1/media", func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := r.FormFile("file"); err != nil || r.FormValue("messaging_product") != "whatsapp" {
+			http.Error(w, "bad upload", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id": "uploaded-1"})
+	})
+	mux.HandleFunc("/1/messages", func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		json.NewDecoder(r.Body).Decode(&payload)
+		sent = append(sent, payload)
+	})
+	srv = httptest.NewServer(mux)
+	defer srv.Close()
+
+	p, err := New(Config{PhoneNumberID: "1", AccessToken: "token", AppSecret: "app-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.graphURL = srv.URL
+
+	audio, err := p.DownloadMedia(context.Background(), router.Message{MediaID: "media-1"})
+	if err != nil || string(audio.Data) != "OggS" || audio.Format != "ogg" {
+		t.Fatalf("DownloadMedia = %+v, %v", audio, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "reply.ogg")
+	os.WriteFile(path, []byte("OggS"), 0o600)
+	resp := router.Response{Text: "你好", Files: []router.FileAttachment{
+		{Path: path, MediaType: "voice"},
+		{Path: "/tmp/report.pdf"},
+	}}
+	if err := p.Send(context.Background(), "8613800000000", resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 2 || sent[0]["type"] != "text" || sent[1]["type"] != "audio" {
+		t.Fatalf("sent %+v, want text then audio", sent)
+	}
+	if id := sent[1]["audio"].(map[string]any)["id"]; id != "uploaded-1" {
+		t.Errorf("audio id = %v", id)
+	}
+}
@@ -3,14 +3,19 @@ package whatsapp
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/pltanton/lingti-bot/internal/router"
+	"github.com/pltanton/lingti-bot/internal/webhookauth"
 )
 
 // Platform implements router.Platform for WhatsApp Business Cloud API
@@ -18,6 +23,7 @@ type Platform struct {
 	config         Config
 	messageHandler func(msg router.Message)
 	httpClient     *http.Client
+	graphURL       string // Graph API base URL
 	server         *http.Server
 	ctx            context.Context
 	cancel         context.CancelFunc
@@ -28,7 +34,8 @@ type Config struct {
 	PhoneNumberID string // WhatsApp Business Phone Number ID
 	AccessToken   string // Meta Graph API access token
 	VerifyToken   string // Webhook verification token
-	WebhookPort   int    // Port for incoming webhooks (default: 8084)
+	AppSecret     string // Meta app secret, signs webhook payloads
+	WebhookPort   int    // Port for incoming webhooks (default: 8084, -1 = shared ingress only)
 }
 
 // New creates a new WhatsApp platform
@@ -39,6 +46,9 @@ func New(cfg Config) (*Platform, error) {
 	if cfg.PhoneNumberID == "" {
 		return nil, fmt.Errorf("WhatsApp phone number ID is required")
 	}
+	if cfg.AppSecret == "" {
+		return nil, fmt.Errorf("WhatsApp app secret is required to verify webhooks")
+	}
 	if cfg.VerifyToken == "" {
 		cfg.VerifyToken = "lingti-bot-verify"
 	}
@@ -51,6 +61,7 @@ func New(cfg Config) (*Platform, error) {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		graphURL: "https://graph.facebook.com/v21.0",
 	}, nil
 }
 
@@ -59,6 +70,14 @@ func (p *Platform) Name() string {
 	return "whatsapp"
 }
 
+// Capabilities returns the platform's delivery limits
+func (p *Platform) Capabilities() router.Capabilities {
+	// WhatsApp text message bodies are limited to 4096 characters
+	return router.Capabilities{
+		MaxMessageLength: 4096,
+	}
+}
+
 // SetMessageHandler sets the callback for incoming messages
 func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 	p.messageHandler = handler
@@ -68,25 +87,35 @@ func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 func (p *Platform) Start(ctx context.Context) error {
 	p.ctx, p.cancel = context.WithCancel(ctx)
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/webhook", p.handleWebhook)
+	// Without a port of its own the platform is only served by the
+	// gateway's ingress, through WebhookHandler
+	if p.config.WebhookPort >= 0 {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/webhook", p.handleWebhook)
 
-	p.server = &http.Server{
-		Addr:    fmt.Sprintf(":%d", p.config.WebhookPort),
-		Handler: mux,
-	}
-
-	go func() {
-		log.Printf("[WhatsApp] Webhook server listening on :%d", p.config.WebhookPort)
-		if err := p.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("[WhatsApp] Server error: %v", err)
+		p.server = &http.Server{
+			Addr:    fmt.Sprintf(":%d", p.config.WebhookPort),
+			Handler: mux,
 		}
-	}()
+
+		go func() {
+			log.Printf("[WhatsApp] Webhook server listening on :%d", p.config.WebhookPort)
+			if err := p.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("[WhatsApp] Server error: %v", err)
+			}
+		}()
+	}
 
 	log.Printf("[WhatsApp] Connected, phone_number_id: %s", p.config.PhoneNumberID)
 	return nil
 }
 
+// WebhookHandler serves the webhook endpoint on the gateway's shared
+// ingress, which mounts it at /webhooks/whatsapp.
+func (p *Platform) WebhookHandler() http.Handler {
+	return http.HandlerFunc(p.handleWebhook)
+}
+
 // Stop shuts down the WhatsApp connection
 func (p *Platform) Stop() error {
 	if p.cancel != nil {
@@ -102,23 +131,27 @@ func (p *Platform) Stop() error {
 
 // Send sends a message via WhatsApp Business API
 func (p *Platform) Send(ctx context.Context, channelID string, resp router.Response) error {
-	if resp.Text == "" {
-		return nil
-	}
-
-	payload := map[string]any{
-		"messaging_product": "whatsapp",
-		"to":                channelID,
-		"type":              "text",
-		"text":              map[string]string{"body": resp.Text},
+	if resp.Text != "" {
+		if err := p.sendMessage(ctx, map[string]any{
+			"messaging_product": "whatsapp",
+			"to":                channelID,
+			"type":              "text",
+			"text":              map[string]string{"body": resp.Text},
+		}); err != nil {
+			return err
+		}
 	}
+	return p.sendVoice(ctx, channelID, resp.Files)
+}
 
+// sendMessage posts a message payload to the Cloud API
+func (p *Platform) sendMessage(ctx context.Context, payload map[string]any) error {
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	url := fmt.Sprintf("https://graph.facebook.com/v21.0/%s/messages", p.config.PhoneNumberID)
+	url := fmt.Sprintf("%s/%s/messages", p.graphURL, p.config.PhoneNumberID)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
 		return err
@@ -140,6 +173,26 @@ func (p *Platform) Send(ctx context.Context, channelID string, resp router.Respo
 	return nil
 }
 
+// verifySignature checks the X-Hub-Signature-256 header Meta sends with
+// every payload: "sha256=" and the hex HMAC-SHA256 of the body keyed with
+// the app secret.
+func (p *Platform) verifySignature(body []byte, header string) error {
+	if header == "" {
+		return fmt.Errorf("%w: no X-Hub-Signature-256 header", webhookauth.ErrMissingCredentials)
+	}
+	signature, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return fmt.Errorf("%w: X-Hub-Signature-256 is not sha256", webhookauth.ErrMalformed)
+	}
+	mac := hmac.New(sha256.New, []byte(p.config.AppSecret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return fmt.Errorf("%w: X-Hub-Signature-256 does not match", webhookauth.ErrBadSignature)
+	}
+	return nil
+}
+
 // handleWebhook processes incoming WhatsApp webhook requests
 func (p *Platform) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	// Webhook verification (GET)
@@ -163,8 +216,19 @@ func (p *Platform) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err := p.verifySignature(body, r.Header.Get("X-Hub-Signature-256")); err != nil {
+		webhookauth.Reject(w, r, "whatsapp", http.StatusForbidden, err)
+		return
+	}
+	webhookauth.Accept("whatsapp")
+
 	var webhook webhookPayload
-	if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
+	if err := json.Unmarshal(body, &webhook); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -178,7 +242,12 @@ func (p *Platform) handleWebhook(w http.ResponseWriter, r *http.Request) {
 				continue
 			}
 			for _, msg := range change.Value.Messages {
-				if msg.Type != "text" {
+				text, mediaID, mediaType := msg.Text.Body, "", ""
+				switch msg.Type {
+				case "text":
+				case "audio":
+					text, mediaID, mediaType = "[语音]", msg.Audio.ID, "voice"
+				default:
 					continue
 				}
 				if p.messageHandler != nil {
@@ -197,7 +266,9 @@ func (p *Platform) handleWebhook(w http.ResponseWriter, r *http.Request) {
 						ChannelID: msg.From,
 						UserID:    msg.From,
 						Username:  username,
-						Text:      msg.Text.Body,
+						Text:      text,
+						MediaID:   mediaID,
+						MediaType: mediaType,
 						Metadata: map[string]string{
 							"phone_number_id": p.config.PhoneNumberID,
 						},
@@ -220,6 +291,10 @@ type webhookPayload struct {
 					Text struct {
 						Body string `json:"body"`
 					} `json:"text"`
+					Audio struct {
+						ID       string `json:"id"`
+						MimeType string `json:"mime_type"`
+					} `json:"audio"`
 				} `json:"messages"`
 				Contacts []struct {
 					WaID    string `json:"wa_id"`
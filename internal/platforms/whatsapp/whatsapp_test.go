@@ -0,0 +1,58 @@
+package whatsapp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pltanton/lingti-bot/internal/router"
+)
+
+func TestHandleWebhookVerifiesSignature(t *testing.T) {
+	p, err := New(Config{PhoneNumberID: "1", AccessToken: "token", AppSecret: "app-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []router.Message
+	p.SetMessageHandler(func(msg router.Message) { got = append(got, msg) })
+
+	const body = `{"entry":[{"changes":[{"value":{"messages":[{"id":"m1","from":"8613800000000","type":"text","text":{"body":"hi"}}]}}]}]}`
+	sign := func(secret string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := []struct {
+		name      string
+		signature string
+		wantCode  int
+	}{
+		{"valid", sign("app-secret"), http.StatusOK},
+		{"no signature", "", http.StatusForbidden},
+		{"wrong secret", sign("guess"), http.StatusForbidden},
+		{"not sha256", "sha1=abc", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		if tt.signature != "" {
+			req.Header.Set("X-Hub-Signature-256", tt.signature)
+		}
+		rec := httptest.NewRecorder()
+		p.WebhookHandler().ServeHTTP(rec, req)
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.wantCode)
+		}
+	}
+	if len(got) != 1 || got[0].Text != "hi" {
+		t.Errorf("delivered %+v, want only the verified message", got)
+	}
+
+	if _, err := New(Config{PhoneNumberID: "1", AccessToken: "token"}); err == nil {
+		t.Error("New accepted a config without an app secret")
+	}
+}
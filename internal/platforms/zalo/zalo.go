@@ -3,17 +3,19 @@ package zalo
 import (
 	"bytes"
 	"context"
-	"crypto/hmac"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/pltanton/lingti-bot/internal/router"
+	"github.com/pltanton/lingti-bot/internal/webhookauth"
 )
 
 // Platform implements router.Platform for Zalo Official Account API
@@ -29,9 +31,9 @@ type Platform struct {
 // Config holds Zalo configuration
 type Config struct {
 	AppID       string // Zalo App ID
-	SecretKey   string // Zalo App Secret Key
+	SecretKey   string // Zalo OA Secret Key, signs webhook events
 	AccessToken string // Zalo OA Access Token
-	WebhookPort int    // Port for incoming webhooks (default: 8088)
+	WebhookPort int    // Port for incoming webhooks (default: 8088, -1 = shared ingress only)
 }
 
 // New creates a new Zalo platform
@@ -62,6 +64,14 @@ func (p *Platform) Name() string {
 	return "zalo"
 }
 
+// Capabilities returns the platform's delivery limits
+func (p *Platform) Capabilities() router.Capabilities {
+	// Zalo OA text messages are limited to 2000 characters
+	return router.Capabilities{
+		MaxMessageLength: 2000,
+	}
+}
+
 // SetMessageHandler sets the callback for incoming messages
 func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 	p.messageHandler = handler
@@ -71,25 +81,35 @@ func (p *Platform) SetMessageHandler(handler func(msg router.Message)) {
 func (p *Platform) Start(ctx context.Context) error {
 	p.ctx, p.cancel = context.WithCancel(ctx)
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/webhook", p.handleWebhook)
-
-	p.server = &http.Server{
-		Addr:    fmt.Sprintf(":%d", p.config.WebhookPort),
-		Handler: mux,
-	}
+	// Without a port of its own the platform is only served by the
+	// gateway's ingress, through WebhookHandler
+	if p.config.WebhookPort >= 0 {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/webhook", p.handleWebhook)
 
-	go func() {
-		log.Printf("[Zalo] Webhook server listening on :%d", p.config.WebhookPort)
-		if err := p.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("[Zalo] Server error: %v", err)
+		p.server = &http.Server{
+			Addr:    fmt.Sprintf(":%d", p.config.WebhookPort),
+			Handler: mux,
 		}
-	}()
+
+		go func() {
+			log.Printf("[Zalo] Webhook server listening on :%d", p.config.WebhookPort)
+			if err := p.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("[Zalo] Server error: %v", err)
+			}
+		}()
+	}
 
 	log.Printf("[Zalo] Platform started, app_id: %s", p.config.AppID)
 	return nil
 }
 
+// WebhookHandler serves the webhook endpoint on the gateway's shared
+// ingress, which mounts it at /webhooks/zalo.
+func (p *Platform) WebhookHandler() http.Handler {
+	return http.HandlerFunc(p.handleWebhook)
+}
+
 // Stop shuts down the Zalo connection
 func (p *Platform) Stop() error {
 	if p.cancel != nil {
@@ -145,12 +165,23 @@ func (p *Platform) Send(ctx context.Context, channelID string, resp router.Respo
 	return nil
 }
 
-// verifySignature verifies the Zalo webhook signature
-func (p *Platform) verifySignature(body []byte, signature string) bool {
-	mac := hmac.New(sha256.New, []byte(p.config.SecretKey))
-	mac.Write(body)
-	expected := hex.EncodeToString(mac.Sum(nil))
-	return hmac.Equal([]byte(expected), []byte(signature))
+// verifySignature checks the X-ZEvent-Signature header Zalo sends with
+// every event: "mac=" and the hex SHA-256 of app ID, raw body, the event's
+// timestamp and the OA secret key, concatenated.
+func (p *Platform) verifySignature(body []byte, timestamp, header string) error {
+	if header == "" {
+		return fmt.Errorf("%w: no X-ZEvent-Signature header", webhookauth.ErrMissingCredentials)
+	}
+	signature, ok := strings.CutPrefix(header, "mac=")
+	if !ok {
+		return fmt.Errorf("%w: X-ZEvent-Signature has no mac", webhookauth.ErrMalformed)
+	}
+	sum := sha256.Sum256([]byte(p.config.AppID + string(body) + timestamp + p.config.SecretKey))
+	expected := hex.EncodeToString(sum[:])
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(signature))) != 1 {
+		return fmt.Errorf("%w: X-ZEvent-Signature does not match", webhookauth.ErrBadSignature)
+	}
+	return nil
 }
 
 // handleWebhook processes incoming Zalo webhook events
@@ -166,19 +197,20 @@ func (p *Platform) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Verify signature if present
-	signature := r.Header.Get("X-ZEvent-Signature")
-	if signature != "" && !p.verifySignature(body, signature) {
-		w.WriteHeader(http.StatusForbidden)
-		return
-	}
-
 	var event zaloEvent
 	if err := json.Unmarshal(body, &event); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
+	// The MAC covers the event's own timestamp, so it is checked after
+	// parsing; nothing is acted on before it passes
+	if err := p.verifySignature(body, event.Timestamp.String(), r.Header.Get("X-ZEvent-Signature")); err != nil {
+		webhookauth.Reject(w, r, "zalo", http.StatusForbidden, err)
+		return
+	}
+	webhookauth.Accept("zalo")
+
 	w.WriteHeader(http.StatusOK)
 
 	// Only process user_send_text events
@@ -203,9 +235,10 @@ func (p *Platform) handleWebhook(w http.ResponseWriter, r *http.Request) {
 
 // Zalo event types
 type zaloEvent struct {
-	EventName string `json:"event_name"`
-	AppID     string `json:"app_id"`
-	MsgID     string `json:"msg_id"`
+	EventName string      `json:"event_name"`
+	AppID     string      `json:"app_id"`
+	MsgID     string      `json:"msg_id"`
+	Timestamp json.Number `json:"timestamp"`
 	Sender    struct {
 		ID string `json:"id"`
 	} `json:"sender"`
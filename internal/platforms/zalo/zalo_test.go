@@ -0,0 +1,52 @@
+package zalo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pltanton/lingti-bot/internal/router"
+)
+
+func TestHandleWebhookVerifiesMAC(t *testing.T) {
+	p, err := New(Config{AppID: "app", SecretKey: "oa-secret", AccessToken: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []router.Message
+	p.SetMessageHandler(func(msg router.Message) { got = append(got, msg) })
+
+	const body = `{"event_name":"user_send_text","app_id":"app","timestamp":"1700000000000","sender":{"id":"u1"},"message":{"text":"hi"}}`
+	mac := func(secret, timestamp string) string {
+		sum := sha256.Sum256([]byte("app" + body + timestamp + secret))
+		return "mac=" + hex.EncodeToString(sum[:])
+	}
+
+	tests := []struct {
+		name      string
+		signature string
+		wantCode  int
+	}{
+		{"valid", mac("oa-secret", "1700000000000"), http.StatusOK},
+		{"no signature", "", http.StatusForbidden},
+		{"wrong secret", mac("guess", "1700000000000"), http.StatusForbidden},
+		{"other timestamp", mac("oa-secret", "1700000000001"), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		if tt.signature != "" {
+			req.Header.Set("X-ZEvent-Signature", tt.signature)
+		}
+		rec := httptest.NewRecorder()
+		p.WebhookHandler().ServeHTTP(rec, req)
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.wantCode)
+		}
+	}
+	if len(got) != 1 || got[0].Text != "hi" {
+		t.Errorf("delivered %+v, want only the verified message", got)
+	}
+}
@@ -0,0 +1,78 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pltanton/lingti-bot/internal/acl"
+	"github.com/pltanton/lingti-bot/internal/logger"
+)
+
+// SetACL makes the router refuse senders the ACL does not allow before
+// anything else is done with their message: voice is not transcribed, and
+// text cannot stop a turn, read channel history or queue behind a turn.
+// Nil disables it.
+func (r *Router) SetACL(l *acl.ACL) {
+	r.acl = l
+}
+
+// RefusedMayUse reports whether a refused sender may still send a command:
+// /whoami shows them what to ask an admin for, and /link issues a code for
+// an allowed account of theirs to confirm. Confirming a code (/link <code>)
+// is not allowed, since it would let the sender join someone else's
+// identity and take on its role.
+func RefusedMayUse(text string) bool {
+	cmd, arg, _ := strings.Cut(strings.ToLower(strings.TrimSpace(text)), " ")
+	switch cmd {
+	case "/whoami", "whoami", "我是谁", "我的id", "/unlink":
+		return true
+	case "/link":
+		return strings.TrimSpace(arg) == ""
+	}
+	return false
+}
+
+// RefusalText is the reply to a sender the ACL refuses.
+func RefusalText(d acl.Decision) string {
+	return fmt.Sprintf("抱歉，你没有使用此机器人的权限（%s）。\n发送 /whoami 查看你的账号信息，并请管理员为你授权。", d.Reason())
+}
+
+// admit reports whether a message may go on to the conversation. A refused
+// sender gets a refusal, or for the commands RefusedMayUse allows, the
+// handler's answer, outside the conversation's turns.
+func (r *Router) admit(platform Platform, platOK bool, msg Message) bool {
+	if r.acl == nil {
+		return true
+	}
+	d := r.acl.Check(acl.Request{
+		Platform:  msg.Platform,
+		ChannelID: msg.ChannelID,
+		UserID:    msg.UserID,
+		Identity:  msg.Identity,
+	})
+	if d.Allowed {
+		return true
+	}
+	logger.Info("[Router] Refused %s:%s (%s)", msg.Platform, msg.UserID, d.Reason())
+	if !platOK {
+		return false
+	}
+	if msg.Action != nil || !RefusedMayUse(msg.Text) {
+		r.reply(platform, msg, RefusalText(d))
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	resp, err := r.handler(ctx, msg)
+	if err != nil {
+		logger.Error("[Router] Error handling message: %v", err)
+		resp = Response{Text: friendlyError(err)}
+	}
+	if resp.Text != "" {
+		r.reply(platform, msg, resp.Text)
+	}
+	return false
+}
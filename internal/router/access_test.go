@@ -0,0 +1,105 @@
+package router
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pltanton/lingti-bot/internal/acl"
+	"github.com/pltanton/lingti-bot/internal/config"
+	"github.com/pltanton/lingti-bot/internal/speech"
+)
+
+type countingTranscriber struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (c *countingTranscriber) Transcribe(ctx context.Context, audio speech.Audio) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	return "/stop", nil
+}
+
+// voiceSyncPlatform is a syncPlatform that can download voice messages.
+type voiceSyncPlatform struct{ syncPlatform }
+
+func (v *voiceSyncPlatform) DownloadMedia(ctx context.Context, msg Message) (speech.Audio, error) {
+	return speech.Audio{Data: []byte(msg.MediaID), Format: "ogg"}, nil
+}
+
+func TestRefusedSender(t *testing.T) {
+	rules, err := acl.New(config.ACLConfig{
+		Rules: []config.ACLRule{{Action: "deny", Match: config.ACLMatch{Platform: "fake", UserID: "u2"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan string, 1)
+	release := make(chan struct{})
+	long := blockingHandler(started, release)
+	var handled []string
+	var mu sync.Mutex
+	p := &voiceSyncPlatform{}
+	r := New(func(ctx context.Context, msg Message) (Response, error) {
+		mu.Lock()
+		handled = append(handled, msg.UserID+" "+msg.Text)
+		mu.Unlock()
+		if msg.UserID == "u1" {
+			return long(ctx, msg)
+		}
+		return Response{Text: "who: " + msg.UserID}, nil
+	})
+	r.Register(p)
+	r.SetConversationScope(ScopeChannel, nil)
+	r.SetACL(rules)
+	stt := &countingTranscriber{}
+	r.SetTranscriber(stt)
+
+	done := make(chan struct{})
+	go func() {
+		r.handleMessage(msgFor("long task"))
+		close(done)
+	}()
+	<-started
+
+	// A refused sender cannot stop the channel's turn, by text or by voice.
+	refused := Message{Platform: "fake", ChannelID: "c1", UserID: "u2", Text: "/stop"}
+	r.handleMessage(refused)
+	voice := refused
+	voice.Text, voice.MediaType, voice.MediaID = "[语音]", "voice", "m1"
+	r.handleMessage(voice)
+	select {
+	case <-done:
+		t.Fatal("refused sender stopped the turn")
+	default:
+	}
+	if stt.calls != 0 {
+		t.Errorf("refused voice was transcribed %d times", stt.calls)
+	}
+
+	// /whoami is answered at once, without queueing behind the turn.
+	refused.Text = "/whoami"
+	r.handleMessage(refused)
+
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("turn did not finish")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := strings.Join(handled, "|"); got != "u1 long task|u2 /whoami" {
+		t.Errorf("handled = %s", got)
+	}
+	sent := p.texts()
+	if len(sent) != 4 || !strings.Contains(sent[0], "没有使用此机器人的权限") || !strings.Contains(sent[1], "没有使用此机器人的权限") ||
+		sent[2] != "who: u2" || sent[3] != "done: long task" {
+		t.Errorf("sent = %q", sent)
+	}
+}
@@ -0,0 +1,155 @@
+package router
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ActionKind says how an Action is presented.
+type ActionKind string
+
+const (
+	ActionButton     ActionKind = "button"      // a button attached to the message
+	ActionSelect     ActionKind = "select"      // a drop-down menu of Options
+	ActionQuickReply ActionKind = "quick_reply" // a suggested reply, shown as a button where there is nothing better
+)
+
+// Action is an interactive element attached to a response. Platforms render
+// it natively (Slack blocks, Telegram inline keyboards, Discord components,
+// Feishu cards, DingTalk ActionCards, Teams Adaptive Cards); elsewhere the
+// router lists the choices in the text.
+type Action struct {
+	Kind    ActionKind
+	ID      string         // identifies the action in callbacks (default: "action<n>"); must not contain '|'
+	Label   string         // button text, or the placeholder of a select
+	Value   string         // reported back when pressed (default: Label)
+	Style   string         // "primary" or "danger"; buttons only
+	URL     string         // makes a button open a link instead of calling back
+	Options []ActionOption // choices of a select
+}
+
+// ActionOption is one choice of a select action.
+type ActionOption struct {
+	Label string
+	Value string // default: Label
+}
+
+// ActionEvent is a button press or menu choice. Platforms deliver it as a
+// Message whose Action is set and whose Text is the chosen value, so
+// handlers that only read Text still see the answer.
+type ActionEvent struct {
+	ID        string // Action.ID of the pressed element
+	Value     string // Action.Value, or the chosen ActionOption.Value
+	MessageID string // message the action was attached to, if the platform says
+}
+
+// NormalizeActions fills in default IDs and values. The router calls it
+// before Send, so platforms can rely on every action having both.
+func NormalizeActions(actions []Action) []Action {
+	if len(actions) == 0 {
+		return nil
+	}
+	out := make([]Action, len(actions))
+	for i, a := range actions {
+		if a.Kind == "" {
+			a.Kind = ActionButton
+		}
+		if a.ID == "" {
+			a.ID = fmt.Sprintf("action%d", i+1)
+		}
+		a.ID = strings.ReplaceAll(a.ID, "|", "_")
+		if a.Value == "" {
+			a.Value = a.Label
+		}
+		if len(a.Options) > 0 {
+			opts := make([]ActionOption, len(a.Options))
+			for j, o := range a.Options {
+				if o.Value == "" {
+					o.Value = o.Label
+				}
+				opts[j] = o
+			}
+			a.Options = opts
+		}
+		out[i] = a
+	}
+	return out
+}
+
+// ActionsText lists actions as text, for platforms that cannot show them.
+// Users answer by typing a choice.
+func ActionsText(actions []Action) string {
+	var b strings.Builder
+	n := 0
+	item := func(label string) {
+		n++
+		fmt.Fprintf(&b, "\n%d. %s", n, label)
+	}
+	for _, a := range actions {
+		switch {
+		case a.URL != "":
+			item(fmt.Sprintf("%s: %s", a.Label, a.URL))
+		case a.Kind == ActionSelect:
+			for _, o := range a.Options {
+				item(o.Label)
+			}
+		default:
+			item(a.Label)
+		}
+	}
+	if n == 0 {
+		return ""
+	}
+	return "\n\n可选操作（回复选项内容即可）：" + b.String()
+}
+
+// Callback payloads have to fit the platform's limit (64 bytes for Telegram
+// callback data, 100 for Discord custom IDs). Longer ones are kept here and
+// referenced by a short key; they are lost on restart, so a button on a very
+// old message may stop working.
+const maxStoredPayloads = 4096
+
+var (
+	payloadMu    sync.Mutex
+	payloads     = make(map[string][2]string)
+	payloadOrder []string
+)
+
+// EncodeActionPayload packs an action ID and value into a callback payload
+// of at most max bytes.
+func EncodeActionPayload(id, value string, max int) string {
+	s := id + "|" + value
+	if len(s) <= max && !strings.HasPrefix(s, "#") {
+		return s
+	}
+	sum := sha256.Sum256([]byte(s))
+	key := "#" + hex.EncodeToString(sum[:8])
+
+	payloadMu.Lock()
+	defer payloadMu.Unlock()
+	if _, ok := payloads[key]; !ok {
+		payloads[key] = [2]string{id, value}
+		payloadOrder = append(payloadOrder, key)
+		if len(payloadOrder) > maxStoredPayloads {
+			delete(payloads, payloadOrder[0])
+			payloadOrder = payloadOrder[1:]
+		}
+	}
+	return key
+}
+
+// DecodeActionPayload reverses EncodeActionPayload. It reports false for
+// payloads that are not ours or have been forgotten.
+func DecodeActionPayload(s string) (id, value string, ok bool) {
+	if strings.HasPrefix(s, "#") {
+		payloadMu.Lock()
+		p, found := payloads[s]
+		payloadMu.Unlock()
+		return p[0], p[1], found
+	}
+	id, value, ok = strings.Cut(s, "|")
+	return id, value, ok
+}
@@ -0,0 +1,100 @@
+package router
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeActions(t *testing.T) {
+	got := NormalizeActions([]Action{
+		{Label: "批准", Style: "primary"},
+		{ID: "a|b", Label: "拒绝", Value: "reject"},
+		{Kind: ActionSelect, ID: "env", Label: "选择环境", Options: []ActionOption{{Label: "prod"}, {Label: "测试", Value: "staging"}}},
+	})
+	if got[0].Kind != ActionButton || got[0].ID != "action1" || got[0].Value != "批准" {
+		t.Errorf("defaults not filled: %+v", got[0])
+	}
+	if got[1].ID != "a_b" || got[1].Value != "reject" {
+		t.Errorf("explicit fields changed: %+v", got[1])
+	}
+	if got[2].Options[0].Value != "prod" || got[2].Options[1].Value != "staging" {
+		t.Errorf("option values = %+v", got[2].Options)
+	}
+}
+
+func TestActionPayload(t *testing.T) {
+	long := strings.Repeat("很长的值", 10)
+	tests := []struct {
+		id, value string
+		max       int
+		inline    bool
+	}{
+		{"approve", "yes", 64, true},
+		{"select", long, 64, false},
+		{"x", "#not-a-key", 64, true},
+	}
+	for _, tt := range tests {
+		payload := EncodeActionPayload(tt.id, tt.value, tt.max)
+		if len(payload) > tt.max {
+			t.Errorf("payload for %q is %d bytes, max %d", tt.value, len(payload), tt.max)
+		}
+		if inline := !strings.HasPrefix(payload, "#"); inline != tt.inline {
+			t.Errorf("payload %q inline = %v, want %v", payload, inline, tt.inline)
+		}
+		id, value, ok := DecodeActionPayload(payload)
+		if !ok || id != tt.id || value != tt.value {
+			t.Errorf("DecodeActionPayload(%q) = %q, %q, %v", payload, id, value, ok)
+		}
+	}
+	if _, _, ok := DecodeActionPayload("#0000000000000000"); ok {
+		t.Error("unknown key decoded")
+	}
+}
+
+func TestDeliver_Actions(t *testing.T) {
+	actions := []Action{
+		{Label: "批准"},
+		{Label: "文档", URL: "https://example.com/doc"},
+		{Kind: ActionSelect, Label: "环境", Options: []ActionOption{{Label: "prod"}, {Label: "staging"}}},
+	}
+	text := strings.Repeat("paragraph text\n\n", 40)
+
+	native := &fakePlatform{caps: Capabilities{MaxMessageLength: 200, SupportsActions: true}}
+	if err := New(nil).deliver(native, "c1", Response{Text: text, Actions: actions}, ""); err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range native.sent[:len(native.sent)-1] {
+		if len(s.Actions) != 0 {
+			t.Errorf("part %d carries actions", i)
+		}
+	}
+	last := native.sent[len(native.sent)-1]
+	if len(last.Actions) != 3 || last.Actions[0].ID != "action1" {
+		t.Errorf("last part actions = %+v", last.Actions)
+	}
+
+	plain := &fakePlatform{}
+	if err := New(nil).deliver(plain, "c1", Response{Text: "要部署吗？", Actions: actions}, ""); err != nil {
+		t.Fatal(err)
+	}
+	want := "要部署吗？\n\n可选操作（回复选项内容即可）：\n1. 批准\n2. 文档: https://example.com/doc\n3. prod\n4. staging"
+	if len(plain.sent) != 1 || plain.sent[0].Text != want || plain.sent[0].Actions != nil {
+		t.Errorf("fallback sent %+v, want text %q", plain.sent, want)
+	}
+}
+
+func TestReplaceMessage_Actions(t *testing.T) {
+	p := &fakeEditor{}
+	id, _ := p.SendEditable(context.Background(), "c1", Response{Text: "⏳"})
+	resp := Response{Text: "要部署吗？", Actions: []Action{{Label: "批准"}}}
+	if err := replaceMessage(context.Background(), p, "c1", id, resp); err != nil {
+		t.Fatal(err)
+	}
+	if edits := p.edits[id]; edits[len(edits)-1] != "✅" {
+		t.Errorf("progress message edits = %q", edits)
+	}
+	if len(p.sent) != 1 || len(p.sent[0].Actions) != 1 {
+		t.Errorf("response with actions was not sent as a new message: %+v", p.sent)
+	}
+}
@@ -0,0 +1,175 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pltanton/lingti-bot/internal/identity"
+	"github.com/pltanton/lingti-bot/internal/logger"
+)
+
+// Conversation scopes decide which messages share agent history.
+const (
+	ScopeUser       = "user"        // each user per channel (default)
+	ScopeChannel    = "channel"     // everyone in a channel
+	ScopeThread     = "thread"      // everyone in a thread
+	ScopeUserThread = "user+thread" // each user per thread
+	ScopeIdentity   = "identity"    // each user across all their linked accounts
+)
+
+// ConversationKey identifies a conversation: each user has their own context
+// per channel.
+func ConversationKey(platform, channelID, userID string) string {
+	return platform + ":" + channelID + ":" + userID
+}
+
+// ScopedConversationKey returns the conversation key for msg under scope.
+// Thread scopes fall back to their channel-level equivalent for messages
+// that are not in a thread.
+func ScopedConversationKey(msg Message, scope string) string {
+	switch scope {
+	case ScopeChannel:
+		return msg.Platform + ":" + msg.ChannelID
+	case ScopeThread:
+		if msg.ThreadID == "" {
+			return msg.Platform + ":" + msg.ChannelID
+		}
+		return msg.Platform + ":" + msg.ChannelID + "#" + msg.ThreadID
+	case ScopeUserThread:
+		if msg.ThreadID == "" {
+			return ConversationKey(msg.Platform, msg.ChannelID, msg.UserID)
+		}
+		return ConversationKey(msg.Platform, msg.ChannelID, msg.UserID) + "#" + msg.ThreadID
+	case ScopeIdentity:
+		if msg.Identity == "" {
+			return "identity:" + msg.Platform + ":" + msg.UserID
+		}
+		return "identity:" + msg.Identity
+	default:
+		return ConversationKey(msg.Platform, msg.ChannelID, msg.UserID)
+	}
+}
+
+type conversationKeyType struct{}
+
+// ContextWithConversationKey attaches the message's conversation key to the
+// context, so the agent keys its history the same way the router does.
+func ContextWithConversationKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, conversationKeyType{}, key)
+}
+
+// ConversationKeyFromContext retrieves the conversation key, or "".
+func ConversationKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(conversationKeyType{}).(string)
+	return key
+}
+
+type conversationScopeType struct{}
+
+// ContextWithConversationScope attaches the scope the message's conversation
+// key was built with, so work scheduled from it can rebuild the key later.
+func ContextWithConversationScope(ctx context.Context, scope string) context.Context {
+	return context.WithValue(ctx, conversationScopeType{}, scope)
+}
+
+// ConversationScopeFromContext retrieves the conversation scope, or "".
+func ConversationScopeFromContext(ctx context.Context) string {
+	scope, _ := ctx.Value(conversationScopeType{}).(string)
+	return scope
+}
+
+// HistoryProvider is implemented by platforms that can fetch recent messages
+// from a channel. Implementations return nil for direct messages, where the
+// agent's own history already covers the conversation.
+type HistoryProvider interface {
+	// RecentMessages returns up to limit messages before the current one,
+	// oldest first. If threadID is set, only that thread is read.
+	RecentMessages(ctx context.Context, channelID, threadID string, limit int) ([]Message, error)
+}
+
+type channelHistoryKeyType struct{}
+
+// ContextWithChannelHistory attaches recent channel messages to the context.
+func ContextWithChannelHistory(ctx context.Context, msgs []Message) context.Context {
+	return context.WithValue(ctx, channelHistoryKeyType{}, msgs)
+}
+
+// ChannelHistoryFromContext retrieves recent channel messages, or nil.
+func ChannelHistoryFromContext(ctx context.Context) []Message {
+	msgs, _ := ctx.Value(channelHistoryKeyType{}).([]Message)
+	return msgs
+}
+
+// FormatChannelHistory renders channel messages as a transcript for the agent.
+func FormatChannelHistory(msgs []Message) string {
+	var sb strings.Builder
+	for _, m := range msgs {
+		name := m.Username
+		if name == "" {
+			name = m.UserID
+		}
+		fmt.Fprintf(&sb, "[%s] %s\n", name, strings.TrimSpace(m.Text))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// SetConversationScope sets the default conversation scope and per-platform
+// overrides. Empty values keep ScopeUser.
+func (r *Router) SetConversationScope(scope string, perPlatform map[string]string) {
+	r.scope = scope
+	r.platformScopes = perPlatform
+}
+
+// SetIdentities makes the router resolve each message's sender to a
+// canonical user, set as Message.Identity. Nil disables it.
+func (r *Router) SetIdentities(resolver identity.Resolver) {
+	r.identities = resolver
+}
+
+// SetChannelContext makes the router fetch up to n recent channel messages
+// for each incoming message on platforms that implement HistoryProvider.
+// Zero disables it.
+func (r *Router) SetChannelContext(n int) {
+	r.channelContext = n
+}
+
+// conversationScope returns the scope configured for a platform.
+func (r *Router) conversationScope(platform string) string {
+	if s, ok := r.platformScopes[platform]; ok && s != "" {
+		return s
+	}
+	return r.scope
+}
+
+// conversationKey returns msg's key under the configured scope.
+func (r *Router) conversationKey(msg Message) string {
+	return ScopedConversationKey(msg, r.conversationScope(msg.Platform))
+}
+
+// channelHistory fetches recent messages around msg, excluding msg itself.
+func (r *Router) channelHistory(platform Platform, msg Message) []Message {
+	hp, ok := platform.(HistoryProvider)
+	if !ok || r.channelContext <= 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	msgs, err := hp.RecentMessages(ctx, msg.ChannelID, msg.ThreadID, r.channelContext+1)
+	if err != nil {
+		logger.Warn("[Router] Failed to fetch channel history: %v", err)
+		return nil
+	}
+	out := make([]Message, 0, len(msgs))
+	for _, m := range msgs {
+		if m.ID != "" && m.ID == msg.ID {
+			continue
+		}
+		out = append(out, m)
+	}
+	if len(out) > r.channelContext {
+		out = out[len(out)-r.channelContext:]
+	}
+	return out
+}
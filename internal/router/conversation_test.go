@@ -0,0 +1,104 @@
+package router
+
+import (
+	"context"
+	"testing"
+)
+
+func TestScopedConversationKey(t *testing.T) {
+	inThread := Message{Platform: "slack", ChannelID: "C1", UserID: "U1", ThreadID: "123.456"}
+	topLevel := Message{Platform: "slack", ChannelID: "C1", UserID: "U1"}
+	tests := []struct {
+		scope string
+		msg   Message
+		want  string
+	}{
+		{"", inThread, "slack:C1:U1"},
+		{ScopeUser, inThread, "slack:C1:U1"},
+		{ScopeChannel, inThread, "slack:C1"},
+		{ScopeThread, inThread, "slack:C1#123.456"},
+		{ScopeThread, topLevel, "slack:C1"},
+		{ScopeUserThread, inThread, "slack:C1:U1#123.456"},
+		{ScopeUserThread, topLevel, "slack:C1:U1"},
+		{ScopeIdentity, inThread, "identity:slack:U1"},
+		{ScopeIdentity, Message{Platform: "slack", ChannelID: "C1", UserID: "U1", Identity: "user-1"}, "identity:user-1"},
+	}
+	for _, tt := range tests {
+		if got := ScopedConversationKey(tt.msg, tt.scope); got != tt.want {
+			t.Errorf("ScopedConversationKey(%+v, %q) = %q, want %q", tt.msg, tt.scope, got, tt.want)
+		}
+	}
+}
+
+type historyPlatform struct {
+	syncPlatform
+	history []Message
+}
+
+func (h *historyPlatform) RecentMessages(ctx context.Context, channelID, threadID string, limit int) ([]Message, error) {
+	msgs := h.history
+	if len(msgs) > limit {
+		msgs = msgs[len(msgs)-limit:]
+	}
+	return msgs, nil
+}
+
+func TestHandleMessage_ScopeAndChannelContext(t *testing.T) {
+	var gotKey string
+	var gotHistory []Message
+	r := New(func(ctx context.Context, msg Message) (Response, error) {
+		gotKey = ConversationKeyFromContext(ctx)
+		gotHistory = ChannelHistoryFromContext(ctx)
+		return Response{}, nil
+	})
+	r.SetConversationScope(ScopeUser, map[string]string{"fake": ScopeThread})
+	r.SetChannelContext(2)
+	r.Register(&historyPlatform{history: []Message{
+		{ID: "1", Username: "alice", Text: "old"},
+		{ID: "2", Username: "alice", Text: "is the build red?"},
+		{ID: "3", Username: "bob", Text: "yes, since noon"},
+		{ID: "4", Username: "alice", Text: "@bot why?"},
+	}})
+
+	r.handleMessage(Message{ID: "4", Platform: "fake", ChannelID: "c1", UserID: "u1", ThreadID: "t1", Text: "why?"})
+
+	if gotKey != "fake:c1#t1" {
+		t.Errorf("conversation key = %q", gotKey)
+	}
+	want := "[alice] is the build red?\n[bob] yes, since noon"
+	if got := FormatChannelHistory(gotHistory); got != want {
+		t.Errorf("channel history =\n%s\nwant\n%s", got, want)
+	}
+}
+
+type linkedAccounts map[string]string
+
+func (l linkedAccounts) Resolve(platform, userID string) string {
+	if id, ok := l[platform+":"+userID]; ok {
+		return id
+	}
+	return platform + ":" + userID
+}
+
+func TestHandleMessage_IdentityScope(t *testing.T) {
+	var keys, identities []string
+	r := New(func(ctx context.Context, msg Message) (Response, error) {
+		keys = append(keys, ConversationKeyFromContext(ctx))
+		identities = append(identities, msg.Identity)
+		return Response{}, nil
+	})
+	r.SetConversationScope(ScopeIdentity, nil)
+	r.SetIdentities(linkedAccounts{"slack:U1": "user-1", "feishu:ou_1": "user-1"})
+
+	r.handleMessage(Message{Platform: "slack", ChannelID: "D1", UserID: "U1", Text: "hi"})
+	r.handleMessage(Message{Platform: "feishu", ChannelID: "oc_1", UserID: "ou_1", Text: "hi again"})
+	r.handleMessage(Message{Platform: "feishu", ChannelID: "oc_1", UserID: "ou_2", Text: "someone else"})
+
+	wantKeys := []string{"identity:user-1", "identity:user-1", "identity:feishu:ou_2"}
+	wantIDs := []string{"user-1", "user-1", "feishu:ou_2"}
+	for i := range wantKeys {
+		if keys[i] != wantKeys[i] || identities[i] != wantIDs[i] {
+			t.Errorf("message %d: key %q identity %q, want %q %q", i, keys[i], identities[i], wantKeys[i], wantIDs[i])
+		}
+	}
+}
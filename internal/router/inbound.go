@@ -0,0 +1,100 @@
+package router
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pltanton/lingti-bot/internal/dedup"
+	"github.com/pltanton/lingti-bot/internal/logger"
+)
+
+// SetDedup makes the router drop messages whose ID it has already seen on
+// the same platform and channel, such as webhook callbacks a platform
+// redelivers because the bot answered too slowly. Messages without an ID
+// and button presses are never dropped. Nil disables it.
+func (r *Router) SetDedup(store *dedup.Store) {
+	r.dedup = store
+}
+
+// SetDebounce makes the router wait until a user has been quiet for d
+// before handling their text messages, and hand the agent the fragments
+// sent meanwhile as one message. Commands, media and button presses are
+// handled at once. Zero disables it.
+func (r *Router) SetDebounce(d time.Duration) {
+	r.debounce = d
+}
+
+// duplicate reports whether msg was already delivered.
+func (r *Router) duplicate(msg Message) bool {
+	if r.dedup == nil || msg.ID == "" || msg.Action != nil {
+		return false
+	}
+	seen, err := r.dedup.Seen(msg.Platform, msg.ChannelID, msg.ID)
+	if err != nil {
+		// Better to answer twice than not at all
+		logger.Warn("[Router] Failed to check message ID: %v", err)
+		return false
+	}
+	return seen
+}
+
+// debounceable reports whether msg may be merged with the user's next
+// messages. Commands are not, so stop words like "停止" act at once.
+func debounceable(msg Message) bool {
+	text := strings.TrimSpace(msg.Text)
+	return msg.Action == nil && msg.MediaID == "" && text != "" && !strings.HasPrefix(text, "/") && !isStopCommand(text)
+}
+
+// fragments collects the text messages a user sends in quick succession.
+type fragments struct {
+	msg   Message
+	texts []string
+	more  chan struct{} // signalled when a fragment is added
+}
+
+// collect merges msg with the fragments the same user sends until they have
+// been quiet for the debounce period. The first fragment's call returns the
+// merged message once the user is quiet; the calls of later fragments
+// return false at once.
+func (r *Router) collect(key string, msg Message) (Message, bool) {
+	key += "|" + msg.UserID
+
+	r.fragMu.Lock()
+	if f, ok := r.fragments[key]; ok {
+		// The latest fragment carries the freshest reply metadata.
+		f.msg = msg
+		f.texts = append(f.texts, msg.Text)
+		select {
+		case f.more <- struct{}{}:
+		default:
+		}
+		r.fragMu.Unlock()
+		return Message{}, false
+	}
+	f := &fragments{msg: msg, texts: []string{msg.Text}, more: make(chan struct{}, 1)}
+	r.fragments[key] = f
+	r.fragMu.Unlock()
+
+	timer := time.NewTimer(r.debounce)
+	defer timer.Stop()
+	for quiet := false; !quiet; {
+		select {
+		case <-f.more:
+			timer.Reset(r.debounce)
+		case <-timer.C:
+			quiet = true
+		}
+	}
+
+	r.fragMu.Lock()
+	delete(r.fragments, key)
+	merged := f.msg
+	merged.Text = strings.Join(f.texts, "\n")
+	n := len(f.texts)
+	r.fragMu.Unlock()
+
+	if n > 1 {
+		logger.Info("[Router] Merged %d messages from %s/%s", n, msg.Platform, msg.Username)
+	}
+	return merged, true
+}
@@ -0,0 +1,131 @@
+package router
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pltanton/lingti-bot/internal/dedup"
+)
+
+// echoHandler answers each message with its text and records what it saw.
+func echoHandler(mu *sync.Mutex, seen *[]string) MessageHandler {
+	return func(ctx context.Context, msg Message) (Response, error) {
+		mu.Lock()
+		*seen = append(*seen, msg.Text)
+		mu.Unlock()
+		return Response{Text: msg.Text}, nil
+	}
+}
+
+func TestDedup_DropsRedelivery(t *testing.T) {
+	store, err := dedup.NewStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	var mu sync.Mutex
+	var seen []string
+	r, _ := newTestRouter(echoHandler(&mu, &seen))
+	r.SetDedup(store)
+
+	msgs := []Message{
+		{ID: "m1", Platform: "fake", ChannelID: "c1", UserID: "u1", Text: "create a cron job"},
+		{ID: "m1", Platform: "fake", ChannelID: "c1", UserID: "u1", Text: "create a cron job"},
+		{ID: "m1", Platform: "fake", ChannelID: "c2", UserID: "u1", Text: "other chat"},
+		{Platform: "fake", ChannelID: "c1", UserID: "u1", Text: "no ID"},
+		{Platform: "fake", ChannelID: "c1", UserID: "u1", Text: "no ID"},
+		{ID: "m1", Platform: "fake", ChannelID: "c1", UserID: "u1", Action: &ActionEvent{ID: "ok"}},
+	}
+	for _, m := range msgs {
+		r.handleMessage(m)
+	}
+	want := []string{"create a cron job", "other chat", "no ID", "no ID", ""}
+	if len(seen) != len(want) {
+		t.Fatalf("handled %q, want %q", seen, want)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Errorf("handled[%d] = %q, want %q", i, seen[i], want[i])
+		}
+	}
+}
+
+func TestDebounce_MergesFragments(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	r, p := newTestRouter(echoHandler(&mu, &seen))
+	r.SetDebounce(100 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	send := func(m Message) {
+		wg.Add(1)
+		go func() { defer wg.Done(); r.handleMessage(m) }()
+		time.Sleep(30 * time.Millisecond)
+	}
+	send(msgFor("帮我查一下"))
+	send(msgFor("明天北京"))
+	send(Message{Platform: "fake", ChannelID: "c1", UserID: "u2", Text: "another user"})
+	send(msgFor("的天气"))
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != 2 {
+		t.Fatalf("handled %q, want two turns", seen)
+	}
+	got := map[string]bool{seen[0]: true, seen[1]: true}
+	if !got["帮我查一下\n明天北京\n的天气"] || !got["another user"] {
+		t.Errorf("handled %q", seen)
+	}
+	if sent := p.texts(); len(sent) != 2 {
+		t.Errorf("sent %q, want one reply per turn", sent)
+	}
+}
+
+func TestDebounce_StopIsImmediate(t *testing.T) {
+	started := make(chan string, 1)
+	r, _ := newTestRouter(blockingHandler(started, nil))
+	r.SetDebounce(time.Second)
+
+	done := make(chan struct{})
+	go func() {
+		r.handleMessage(msgFor("long task"))
+		close(done)
+	}()
+	<-started
+
+	begin := time.Now()
+	r.handleMessage(msgFor("停止"))
+	if waited := time.Since(begin); waited >= time.Second {
+		t.Errorf("停止 waited %s for the debounce period", waited)
+	}
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("turn was not cancelled")
+	}
+}
+
+func TestDebounceable(t *testing.T) {
+	tests := []struct {
+		msg  Message
+		want bool
+	}{
+		{Message{Text: "hello"}, true},
+		{Message{Text: "/stop"}, false},
+		{Message{Text: " 停止 "}, false},
+		{Message{Text: " /whoami"}, false},
+		{Message{Text: ""}, false},
+		{Message{Text: "[图片]", MediaID: "img1", MediaType: "image"}, false},
+		{Message{Action: &ActionEvent{ID: "ok"}}, false},
+	}
+	for _, tt := range tests {
+		if got := debounceable(tt.msg); got != tt.want {
+			t.Errorf("debounceable(%+v) = %v, want %v", tt.msg, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pltanton/lingti-bot/internal/logger"
+)
+
+const (
+	// progressMinInterval throttles edits so rapid tool steps don't hit
+	// platform rate limits; skipped updates are picked up by the next tick.
+	progressMinInterval = 2 * time.Second
+	// progressTick refreshes the elapsed time while the agent is working.
+	progressTick = 5 * time.Second
+	// maxProgressSteps is how many of the most recent steps are listed.
+	maxProgressSteps = 10
+	// maxProgressStatus caps the status text taken from the agent.
+	maxProgressStatus = 500
+)
+
+// progressState selects the header line of a progress message.
+type progressState int
+
+const (
+	progressRunning progressState = iota
+	progressDone
+	progressStopped
+)
+
+type progressStep struct {
+	name string
+	done bool
+}
+
+// progressMessage is a single chat message that is created on the first
+// progress update and then edited in place with the agent's latest status,
+// a checklist of tool steps and the elapsed time.
+//
+// Updates only record the new state; sends and edits happen on the tick
+// goroutine, so a slow platform API never holds up the agent's tool calls.
+type progressMessage struct {
+	editor    Editor
+	channelID string
+	template  Response
+	started   time.Time
+	wake      chan struct{}
+	quit      chan struct{}
+	done      chan struct{}
+
+	mu        sync.Mutex
+	messageID string
+	status    string
+	steps     []progressStep
+	lastEdit  time.Time
+	stopped   bool
+}
+
+func newProgressMessage(editor Editor, channelID string, template Response) *progressMessage {
+	p := &progressMessage{
+		editor:    editor,
+		channelID: channelID,
+		template:  template,
+		started:   time.Now(),
+		wake:      make(chan struct{}, 1),
+		quit:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go p.tick()
+	return p
+}
+
+// setStatus is the ProgressFunc for edit-capable platforms.
+func (p *progressMessage) setStatus(text string) {
+	if r := []rune(strings.TrimSpace(text)); len(r) > maxProgressStatus {
+		text = string(r[:maxProgressStatus]) + "…"
+	}
+	p.mu.Lock()
+	p.status = strings.TrimSpace(text)
+	p.mu.Unlock()
+	p.notify()
+}
+
+// step is the StepFunc for edit-capable platforms.
+func (p *progressMessage) step(name string, done bool) {
+	p.mu.Lock()
+	if !done {
+		p.steps = append(p.steps, progressStep{name: name})
+	} else {
+		for i := len(p.steps) - 1; i >= 0; i-- {
+			if p.steps[i].name == name && !p.steps[i].done {
+				p.steps[i].done = true
+				break
+			}
+		}
+	}
+	p.mu.Unlock()
+	p.notify()
+}
+
+// notify asks the tick goroutine to show the latest state.
+func (p *progressMessage) notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// stop ends the periodic refresh, waiting for a send or edit in flight, and
+// returns the ID of the progress message, or "" if none was sent. The caller
+// is expected to replace its content.
+func (p *progressMessage) stop() string {
+	p.mu.Lock()
+	if !p.stopped {
+		p.stopped = true
+		close(p.quit)
+	}
+	p.mu.Unlock()
+	<-p.done
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.messageID
+}
+
+// finish marks the progress message as complete or stopped, for turns that
+// have no reply to replace it with. It is called after stop.
+func (p *progressMessage) finish(state progressState) {
+	p.mu.Lock()
+	id, text := p.messageID, p.render(state)
+	p.mu.Unlock()
+	p.edit(id, text)
+}
+
+func (p *progressMessage) tick() {
+	defer close(p.done)
+	ticker := time.NewTicker(progressTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.quit:
+			return
+		case <-p.wake:
+			p.update(false)
+		case <-ticker.C:
+			p.update(true)
+		}
+	}
+}
+
+// update sends or edits the progress message. Unless force is set, edits
+// closer together than progressMinInterval are deferred to the next tick;
+// ticks only refresh a message that was already sent. Called only from the
+// tick goroutine.
+func (p *progressMessage) update(force bool) {
+	p.mu.Lock()
+	id := p.messageID
+	skip := p.stopped || (id == "" && force) ||
+		(id != "" && !force && time.Since(p.lastEdit) < progressMinInterval)
+	text := p.render(progressRunning)
+	p.mu.Unlock()
+	if skip {
+		return
+	}
+
+	if id != "" {
+		p.edit(id, text)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	resp := p.template
+	resp.Text = text
+	id, err := p.editor.SendEditable(ctx, p.channelID, resp)
+	if err != nil {
+		logger.Warn("[Router] Failed to send progress: %v", err)
+		return
+	}
+	p.mu.Lock()
+	p.messageID = id
+	p.lastEdit = time.Now()
+	p.mu.Unlock()
+}
+
+// edit writes text over the progress message id.
+func (p *progressMessage) edit(id, text string) {
+	if id == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	resp := p.template
+	resp.Text = text
+	if err := p.editor.Edit(ctx, p.channelID, id, resp); err != nil {
+		logger.Warn("[Router] Failed to update progress: %v", err)
+	}
+	p.mu.Lock()
+	p.lastEdit = time.Now()
+	p.mu.Unlock()
+}
+
+// render formats the progress message as Markdown.
+func (p *progressMessage) render(state progressState) string {
+	var sb strings.Builder
+	elapsed := time.Since(p.started).Round(time.Second)
+	switch state {
+	case progressDone:
+		fmt.Fprintf(&sb, "✅ 已完成（用时 %s）", elapsed)
+	case progressStopped:
+		fmt.Fprintf(&sb, "⏹ 已停止（用时 %s）", elapsed)
+	default:
+		fmt.Fprintf(&sb, "⏳ 处理中…（已用时 %s）", elapsed)
+	}
+
+	steps := p.steps
+	if len(steps) > 0 {
+		sb.WriteString("\n\n")
+		if hidden := len(steps) - maxProgressSteps; hidden > 0 {
+			// Parallel tool calls may leave an older step still running
+			done := 0
+			for _, s := range steps[:hidden] {
+				if s.done {
+					done++
+				}
+			}
+			if done == hidden {
+				fmt.Fprintf(&sb, "- … 前 %d 步已完成\n", hidden)
+			} else {
+				fmt.Fprintf(&sb, "- … 前 %d 步已折叠，其中 %d 步已完成\n", hidden, done)
+			}
+			steps = steps[hidden:]
+		}
+		for _, s := range steps {
+			mark := "⏳"
+			if s.done {
+				mark = "✅"
+			}
+			fmt.Fprintf(&sb, "- %s `%s`\n", mark, s.name)
+		}
+	}
+
+	if p.status != "" {
+		sb.WriteString("\n")
+		if len(steps) == 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(p.status)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,156 @@
+package router
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeEditor struct {
+	fakePlatform
+	mu     sync.Mutex
+	edits  map[string][]string
+	nextID int
+	block  chan struct{} // SendEditable waits on it when set
+}
+
+func (f *fakeEditor) SendEditable(ctx context.Context, channelID string, resp Response) (string, error) {
+	if f.block != nil {
+		<-f.block
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	id := string(rune('a' + f.nextID - 1))
+	if f.edits == nil {
+		f.edits = make(map[string][]string)
+	}
+	f.edits[id] = []string{resp.Text}
+	return id, nil
+}
+
+func (f *fakeEditor) Edit(ctx context.Context, channelID, messageID string, resp Response) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.edits[messageID] = append(f.edits[messageID], resp.Text)
+	return nil
+}
+
+func (f *fakeEditor) messages() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.edits)
+}
+
+// runWithProgress runs a turn that reports three tool steps, then calls
+// settle (if set) before answering.
+func runWithProgress(t *testing.T, p Platform, settle func()) {
+	t.Helper()
+	r := New(func(ctx context.Context, msg Message) (Response, error) {
+		step := StepFromContext(ctx)
+		progress := ProgressFromContext(ctx)
+		for _, name := range []string{"browser_navigate", "browser_snapshot", "browser_click"} {
+			if step != nil {
+				step(name, false)
+				step(name, true)
+			}
+			progress("working on " + name)
+		}
+		if settle != nil {
+			settle()
+		}
+		return Response{Text: "final answer"}, nil
+	})
+	r.Register(p)
+	r.handleMessage(Message{Platform: "fake", ChannelID: "c1", Text: "go"})
+}
+
+func TestProgress_EditsInPlace(t *testing.T) {
+	p := &fakeEditor{fakePlatform: fakePlatform{caps: Capabilities{SupportsEdit: true}}}
+	// The progress message is sent in the background; wait for it
+	runWithProgress(t, p, func() {
+		for deadline := time.Now().Add(2 * time.Second); p.messages() == 0 && time.Now().Before(deadline); {
+			time.Sleep(time.Millisecond)
+		}
+	})
+
+	if len(p.sent) != 0 {
+		t.Errorf("expected no plain sends, got %d", len(p.sent))
+	}
+	if len(p.edits) != 1 {
+		t.Fatalf("expected one progress message, got %d", len(p.edits))
+	}
+	history := p.edits["a"]
+	if !strings.Contains(history[0], "browser_navigate") {
+		t.Errorf("first progress text lacks the step: %q", history[0])
+	}
+	if last := history[len(history)-1]; last != "final answer" {
+		t.Errorf("progress message not replaced by the answer: %q", last)
+	}
+}
+
+func TestProgress_FallbackSendsMessages(t *testing.T) {
+	p := &fakePlatform{}
+	runWithProgress(t, p, nil)
+
+	if len(p.sent) != 4 {
+		t.Fatalf("expected 3 progress messages and the answer, got %d", len(p.sent))
+	}
+	if p.sent[3].Text != "final answer" {
+		t.Errorf("last message = %q", p.sent[3].Text)
+	}
+}
+
+func TestProgress_SlowPlatformDoesNotBlockSteps(t *testing.T) {
+	p := &fakeEditor{block: make(chan struct{})}
+	progress := newProgressMessage(p, "c1", Response{})
+
+	stepped := make(chan struct{})
+	go func() {
+		for i := range 20 {
+			progress.step("tool"+string(rune('A'+i)), false)
+			progress.setStatus("working")
+		}
+		close(stepped)
+	}()
+	select {
+	case <-stepped:
+	case <-time.After(time.Second):
+		t.Fatal("steps waited for the platform")
+	}
+
+	select {
+	case p.block <- struct{}{}: // the first step's message is being sent
+	case <-time.After(time.Second):
+		t.Fatal("progress message was never sent")
+	}
+	if id := progress.stop(); id != "a" {
+		t.Errorf("stop() = %q, want the progress message sent in the background", id)
+	}
+}
+
+func TestProgress_Render(t *testing.T) {
+	p := &progressMessage{}
+	for i := range maxProgressSteps + 2 {
+		p.steps = append(p.steps, progressStep{name: "tool" + string(rune('A'+i)), done: true})
+	}
+	p.steps[len(p.steps)-1].done = false
+	p.status = "almost there"
+
+	got := p.render(progressRunning)
+	for _, want := range []string{"前 2 步已完成", "- ✅ `toolC`", "- ⏳ `toolL`", "\n\nalmost there"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("render missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "`toolA`") {
+		t.Errorf("render should hide old steps:\n%s", got)
+	}
+
+	p.steps[0].done = false
+	if got := p.render(progressRunning); !strings.Contains(got, "前 2 步已折叠，其中 1 步已完成") {
+		t.Errorf("render counts a running hidden step as done:\n%s", got)
+	}
+}
@@ -0,0 +1,344 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pltanton/lingti-bot/internal/logger"
+	"github.com/pltanton/lingti-bot/internal/outbox"
+)
+
+// queuePoll is how often the outbound queue looks for messages it is not
+// already working on, such as dead letters requeued from the command line.
+const queuePoll = 30 * time.Second
+
+// RetryPolicy says how the outbound queue retries a message a platform did
+// not accept. Zero fields take the defaults.
+type RetryPolicy struct {
+	MaxAttempts int           // attempts before the message becomes a dead letter (default 8)
+	MinDelay    time.Duration // delay after the first failure, doubled after each one (default 2s)
+	MaxDelay    time.Duration // longest delay between attempts (default 5m)
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 8
+	}
+	if p.MinDelay <= 0 {
+		p.MinDelay = 2 * time.Second
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = 5 * time.Minute
+	}
+	return p
+}
+
+// backoff returns the delay after the given number of failed attempts.
+func (p RetryPolicy) backoff(attempts int) time.Duration {
+	d := p.MinDelay
+	for i := 1; i < attempts && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxDelay)
+}
+
+// RateLimitError is returned by Platform.Send when the platform asked the
+// bot to slow down, such as Telegram's 429 retry_after or Slack's
+// Retry-After header. The outbound queue sends nothing more to that
+// platform until RetryAfter has passed.
+type RateLimitError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited, retry after %s: %v", e.RetryAfter, e.Err)
+}
+
+func (e *RateLimitError) Unwrap() error { return e.Err }
+
+// queue is the outbound queue: it delivers each channel's messages in
+// order, one worker per channel, retrying until they are sent or dead.
+type queue struct {
+	router   *Router
+	store    *outbox.Store
+	policy   RetryPolicy
+	policies map[string]RetryPolicy
+
+	mu      sync.Mutex
+	ctx     context.Context // set by Start; workers wait for it
+	running map[outbox.Channel]bool
+	holds   map[string]time.Time // rate-limited platforms and when they may be used again
+}
+
+// SetOutbox makes the router queue outgoing messages in store and retry the
+// ones a platform does not accept, with policy or the platform's entry in
+// platformPolicies. Messages still queued from a previous run are sent once
+// the router starts. Without an outbox each message gets a single attempt.
+func (r *Router) SetOutbox(store *outbox.Store, policy RetryPolicy, platformPolicies map[string]RetryPolicy) {
+	r.queue = &queue{
+		router:   r,
+		store:    store,
+		policy:   policy.withDefaults(),
+		policies: make(map[string]RetryPolicy, len(platformPolicies)),
+		running:  make(map[outbox.Channel]bool),
+		holds:    make(map[string]time.Time),
+	}
+	for name, p := range platformPolicies {
+		r.queue.policies[name] = p.withDefaults()
+	}
+}
+
+// QueuedResponse decodes the payload of an outbox item, for display.
+func QueuedResponse(payload []byte) (Response, error) {
+	var m outgoing
+	err := json.Unmarshal(payload, &m)
+	return m.Response, err
+}
+
+// RemoveQueuedFiles deletes the attachments the queue keeps for an outbox
+// item, for when it is dropped.
+func RemoveQueuedFiles(payload []byte) {
+	var m outgoing
+	if json.Unmarshal(payload, &m) == nil {
+		removeFiles(m.TempFiles)
+	}
+}
+
+func (q *queue) policyFor(platform string) RetryPolicy {
+	if p, ok := q.policies[platform]; ok {
+		return p
+	}
+	return q.policy
+}
+
+// enqueue stores the messages of one response and wakes the channel. The
+// queue keeps its own copies of their attachments, and removes the
+// temporary files they were made from once the messages are stored.
+func (q *queue) enqueue(platform, channelID string, msgs []outgoing) error {
+	for _, m := range msgs {
+		owned, err := ownFiles(m)
+		if err != nil {
+			return err
+		}
+		payload, err := json.Marshal(owned)
+		if err == nil {
+			_, err = q.store.Enqueue(platform, channelID, payload)
+		}
+		if err != nil {
+			removeFiles(owned.TempFiles)
+			return err
+		}
+		removeFiles(m.TempFiles)
+	}
+	q.wake(outbox.Channel{Platform: platform, ChannelID: channelID})
+	return nil
+}
+
+// ownFiles returns m with its attachments copied, so a queued message still
+// has them when it is retried later. The copies are removed once the
+// message is sent or becomes a dead letter.
+func ownFiles(m outgoing) (outgoing, error) {
+	files := slices.Clone(m.Response.Files)
+	var copies []string
+	for i, f := range files {
+		path, err := copyToQueue(f.Path)
+		if err != nil {
+			removeFiles(copies)
+			return m, fmt.Errorf("failed to queue attachment %s: %w", f.Path, err)
+		}
+		copies = append(copies, path)
+		if f.Name == "" {
+			f.Name = filepath.Base(f.Path)
+		}
+		f.Path, f.Temp = path, true
+		files[i] = f
+	}
+	m.Response.Files = files
+	m.TempFiles = copies
+	return m, nil
+}
+
+// copyToQueue copies a file into the router's temporary directory.
+func copyToQueue(path string) (string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	dir := filepath.Join(os.TempDir(), "lingti-bot")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	dst, err := os.CreateTemp(dir, "outbox-*"+filepath.Ext(path))
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(dst, src)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst.Name())
+		return "", err
+	}
+	return dst.Name(), nil
+}
+
+// run starts workers for every channel with pending messages, now and
+// every queuePoll, until ctx is done.
+func (q *queue) run(ctx context.Context) {
+	q.mu.Lock()
+	q.ctx = ctx
+	q.mu.Unlock()
+
+	ticker := time.NewTicker(queuePoll)
+	defer ticker.Stop()
+	for {
+		channels, err := q.store.Channels()
+		if err != nil {
+			logger.Warn("[Router] Failed to read outbound queue: %v", err)
+		}
+		for _, ch := range channels {
+			q.wake(ch)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// wake starts a worker for a channel unless one is running.
+func (q *queue) wake(ch outbox.Channel) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.ctx == nil || q.running[ch] {
+		return
+	}
+	q.running[ch] = true
+	go q.drain(q.ctx, ch)
+}
+
+// drain sends a channel's messages in order until none are left. A message
+// that fails is retried before anything behind it is sent.
+func (q *queue) drain(ctx context.Context, ch outbox.Channel) {
+	for {
+		// Look for the next message under the lock, so a message queued as
+		// the worker exits is not left behind.
+		q.mu.Lock()
+		it, err := q.store.Next(ch.Platform, ch.ChannelID)
+		if err != nil || it == nil {
+			delete(q.running, ch)
+			q.mu.Unlock()
+			if err != nil {
+				logger.Warn("[Router] Failed to read outbound queue: %v", err)
+			}
+			return
+		}
+		hold := q.holds[ch.Platform]
+		q.mu.Unlock()
+
+		wait := time.Until(it.NextAttempt)
+		if w := time.Until(hold); w > wait {
+			wait = w
+		}
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				q.stopped(ch)
+				return
+			case <-timer.C:
+			}
+		}
+
+		platform, ok := q.router.platform(ch.Platform)
+		if !ok {
+			// Not registered in this run; the message waits for one that has it.
+			q.stopped(ch)
+			return
+		}
+		var m outgoing
+		if err := json.Unmarshal(it.Payload, &m); err != nil {
+			logger.Error("[Router] Dropping unreadable queued message %d: %v", it.ID, err)
+			if kerr := q.store.Kill(it.ID, err); kerr != nil {
+				// Next would return it again right away
+				logger.Warn("[Router] Failed to record dead letter %d: %v", it.ID, kerr)
+				q.stopped(ch)
+				return
+			}
+			continue
+		}
+		// A retried dead letter lost its attachments when it died.
+		m.Response.Files = slices.DeleteFunc(m.Response.Files, func(f FileAttachment) bool {
+			_, err := os.Stat(f.Path)
+			return err != nil
+		})
+
+		if err := sendOutgoing(platform, ch.ChannelID, m); err != nil {
+			q.failed(platform, ch.ChannelID, it, m, err)
+			continue
+		}
+		if err := q.store.Done(it.ID); err != nil {
+			logger.Warn("[Router] Failed to remove sent message %d from queue: %v", it.ID, err)
+		}
+		removeFiles(m.TempFiles)
+	}
+}
+
+func (q *queue) stopped(ch outbox.Channel) {
+	q.mu.Lock()
+	delete(q.running, ch)
+	q.mu.Unlock()
+}
+
+// failed schedules a retry of a message the platform did not accept, or
+// makes it a dead letter once its attempts are used up.
+func (q *queue) failed(platform Platform, channelID string, it *outbox.Item, m outgoing, err error) {
+	name := platform.Name()
+	policy := q.policyFor(name)
+	attempts := it.Attempts + 1
+
+	if attempts >= policy.MaxAttempts {
+		logger.Error("[Router] Giving up on message %d to %s/%s after %d attempts: %v", it.ID, name, channelID, attempts, err)
+		if kerr := q.store.Kill(it.ID, err); kerr != nil {
+			logger.Warn("[Router] Failed to record dead letter %d: %v", it.ID, kerr)
+		}
+		removeFiles(m.TempFiles)
+		// Tell the user, in case only this message was the problem
+		notice := Response{
+			Text:     fmt.Sprintf("[Error] %v", err),
+			ThreadID: m.Response.ThreadID,
+			Metadata: m.Response.Metadata, // Preserve routing metadata (e.g., kf)
+		}
+		if nerr := sendOutgoing(platform, channelID, outgoing{Response: notice}); nerr != nil {
+			logger.Error("[Router] Failed to send error notification: %v", nerr)
+		}
+		return
+	}
+
+	delay := policy.backoff(attempts)
+	var rl *RateLimitError
+	if errors.As(err, &rl) && rl.RetryAfter > 0 {
+		delay = rl.RetryAfter
+		q.mu.Lock()
+		q.holds[name] = time.Now().Add(delay)
+		q.mu.Unlock()
+	}
+	logger.Warn("[Router] Failed to send message %d to %s/%s (attempt %d/%d), retrying in %s: %v",
+		it.ID, name, channelID, attempts, policy.MaxAttempts, delay, err)
+	if rerr := q.store.Retry(it.ID, time.Now().Add(delay), err); rerr != nil {
+		logger.Warn("[Router] Failed to reschedule message %d: %v", it.ID, rerr)
+	}
+}
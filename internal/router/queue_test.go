@@ -0,0 +1,229 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pltanton/lingti-bot/internal/outbox"
+)
+
+// flakyPlatform fails the sends its fail function returns an error for.
+type flakyPlatform struct {
+	fakePlatform
+	mu       sync.Mutex
+	fail     func(attempt int, resp Response) error
+	attempts map[string]int
+	log      []string // "channel:text" of each accepted send
+	times    []time.Time
+}
+
+func (f *flakyPlatform) Send(ctx context.Context, channelID string, resp Response) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.attempts == nil {
+		f.attempts = make(map[string]int)
+	}
+	f.attempts[resp.Text]++
+	f.times = append(f.times, time.Now())
+	if f.fail != nil {
+		if err := f.fail(f.attempts[resp.Text], resp); err != nil {
+			return err
+		}
+	}
+	f.log = append(f.log, channelID+":"+resp.Text)
+	return nil
+}
+
+func (f *flakyPlatform) delivered() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.log...)
+}
+
+func newQueuedRouter(t *testing.T, p Platform, policy RetryPolicy) (*Router, *outbox.Store) {
+	t.Helper()
+	store, err := outbox.NewStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	r := New(nil)
+	r.Register(p)
+	r.SetOutbox(store, policy, nil)
+	return r, store
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestQueue_RetriesInChannelOrder(t *testing.T) {
+	p := &flakyPlatform{fail: func(attempt int, resp Response) error {
+		if resp.Text == "first" && attempt < 3 {
+			return errors.New("connection reset")
+		}
+		return nil
+	}}
+	r, store := newQueuedRouter(t, p, RetryPolicy{MinDelay: time.Millisecond})
+
+	// Queued before Start, as if left over from a previous run
+	r.SendToUser("fake", "c1", Response{Text: "first"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := r.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	r.SendToUser("fake", "c1", Response{Text: "second"})
+	r.SendToUser("fake", "c2", Response{Text: "other"})
+
+	waitFor(t, "delivery", func() bool { return len(p.delivered()) == 3 })
+	var c1 []string
+	for _, s := range p.delivered() {
+		if strings.HasPrefix(s, "c1:") {
+			c1 = append(c1, s)
+		}
+	}
+	if strings.Join(c1, ",") != "c1:first,c1:second" {
+		t.Errorf("c1 order = %v", c1)
+	}
+	if pending, _ := store.Pending(); len(pending) != 0 {
+		t.Errorf("%d messages left in queue", len(pending))
+	}
+}
+
+func TestQueue_RateLimit(t *testing.T) {
+	p := &flakyPlatform{fail: func(attempt int, resp Response) error {
+		if attempt == 1 {
+			return &RateLimitError{RetryAfter: 200 * time.Millisecond, Err: errors.New("Too Many Requests")}
+		}
+		return nil
+	}}
+	// The backoff alone would retry after a millisecond.
+	r, _ := newQueuedRouter(t, p, RetryPolicy{MinDelay: time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.Start(ctx)
+
+	r.SendToUser("fake", "c1", Response{Text: "hello"})
+	waitFor(t, "delivery", func() bool { return len(p.delivered()) == 1 })
+	p.mu.Lock()
+	gap := p.times[1].Sub(p.times[0])
+	p.mu.Unlock()
+	if gap < 200*time.Millisecond {
+		t.Errorf("retried after %s, want at least retry_after", gap)
+	}
+}
+
+func TestQueue_DeadLetter(t *testing.T) {
+	p := &flakyPlatform{fail: func(attempt int, resp Response) error {
+		if resp.Text == "bad" {
+			return errors.New("message is too long")
+		}
+		return nil
+	}}
+	r, store := newQueuedRouter(t, p, RetryPolicy{MaxAttempts: 2, MinDelay: time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.Start(ctx)
+
+	r.SendToUser("fake", "c1", Response{Text: "bad"})
+	r.SendToUser("fake", "c1", Response{Text: "good"})
+
+	waitFor(t, "delivery", func() bool { return len(p.delivered()) == 2 })
+	if got := p.delivered(); got[0] != "c1:[Error] message is too long" || got[1] != "c1:good" {
+		t.Errorf("sent = %v", got)
+	}
+	dead, err := store.Dead()
+	if err != nil || len(dead) != 1 || dead[0].Attempts != 2 {
+		t.Fatalf("Dead() = %+v, %v", dead, err)
+	}
+	if resp, err := QueuedResponse(dead[0].Payload); err != nil || resp.Text != "bad" {
+		t.Errorf("QueuedResponse() = %+v, %v", resp, err)
+	}
+}
+
+func TestQueue_OwnsAttachments(t *testing.T) {
+	dir := t.TempDir()
+	report := filepath.Join(dir, "report.pdf")
+	voice := filepath.Join(dir, "voice.ogg")
+	os.WriteFile(report, []byte("pdf"), 0644)
+	os.WriteFile(voice, []byte("ogg"), 0644)
+
+	var sentFiles []string // path=content of each attachment when it was sent
+	p := &flakyPlatform{fail: func(attempt int, resp Response) error {
+		for _, f := range resp.Files {
+			data, err := os.ReadFile(f.Path)
+			if err != nil {
+				return err
+			}
+			sentFiles = append(sentFiles, f.Name+"="+string(data))
+		}
+		if resp.Text == "bad" || resp.Text == "files" && attempt < 2 {
+			return errors.New("connection reset")
+		}
+		return nil
+	}}
+	r, store := newQueuedRouter(t, p, RetryPolicy{MaxAttempts: 2, MinDelay: time.Millisecond})
+
+	r.SendToUser("fake", "c1", Response{Text: "files", Files: []FileAttachment{
+		{Path: report},
+		{Path: voice, Name: "voice.ogg", MediaType: "voice", Temp: true},
+	}})
+	r.SendToUser("fake", "c1", Response{Text: "bad", Files: []FileAttachment{{Path: report}}})
+
+	// The queue has its copies; the caller's temporary file is gone and its
+	// own file may change.
+	if _, err := os.Stat(voice); !os.IsNotExist(err) {
+		t.Errorf("temporary attachment was kept: %v", err)
+	}
+	os.Remove(report)
+	var copies []string
+	items, _ := store.Pending()
+	for _, it := range items {
+		var m outgoing
+		json.Unmarshal(it.Payload, &m)
+		copies = append(copies, m.TempFiles...)
+	}
+	if len(copies) != 3 {
+		t.Fatalf("queued copies = %v", copies)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.Start(ctx)
+	waitFor(t, "delivery", func() bool { return len(p.delivered()) == 2 })
+
+	if got := strings.Join(sentFiles, ","); got != "report.pdf=pdf,voice.ogg=ogg,report.pdf=pdf,voice.ogg=ogg,report.pdf=pdf,report.pdf=pdf" {
+		t.Errorf("sent attachments = %s", got)
+	}
+	// Sent and dead messages no longer need their copies.
+	for _, path := range copies {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("copy %s was kept: %v", path, err)
+		}
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{MinDelay: time.Second, MaxDelay: 5 * time.Second}.withDefaults()
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := p.backoff(i + 1); got != w {
+			t.Errorf("backoff(%d) = %s, want %s", i+1, got, w)
+		}
+	}
+}
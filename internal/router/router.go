@@ -3,11 +3,16 @@ package router
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/pltanton/lingti-bot/internal/acl"
+	"github.com/pltanton/lingti-bot/internal/dedup"
+	"github.com/pltanton/lingti-bot/internal/identity"
 	"github.com/pltanton/lingti-bot/internal/logger"
+	"github.com/pltanton/lingti-bot/internal/speech"
 )
 
 // Message represents an incoming message from any platform
@@ -17,10 +22,13 @@ type Message struct {
 	ChannelID string            // Channel/Chat ID
 	UserID    string            // User who sent the message
 	Username  string            // Human-readable username
+	Identity  string            // Canonical user across linked accounts (set by the router)
 	Text      string            // Message content
 	ThreadID  string            // For threaded replies
 	MediaID   string            // Media file ID (for file/image/voice/video messages)
+	MediaType string            // "image", "voice", "video" or "file" when MediaID is set
 	FileName  string            // Original filename (for file messages)
+	Action    *ActionEvent      // Set when the message is a button press or menu choice
 	Metadata  map[string]string // Platform-specific metadata
 }
 
@@ -29,25 +37,54 @@ type FileAttachment struct {
 	Path      string // Local file path to upload and send
 	Name      string // Display name (defaults to filepath.Base)
 	MediaType string // "file", "image", "voice", "video" (default: "file")
+	Temp      bool   // Path is a temporary file the router removes once it is sent
 }
 
 // Response represents a response to send back
 type Response struct {
 	Text     string
 	Files    []FileAttachment  // File attachments to send
+	Actions  []Action          // Buttons, menus and quick replies
 	ThreadID string            // Reply in thread if set
 	Metadata map[string]string // Platform-specific options
 }
 
+// Capabilities describes the delivery limits and features of a platform.
+// The router uses it to split long responses before calling Send.
+type Capabilities struct {
+	MaxMessageLength int  // Max characters per message (0 = unlimited)
+	MaxMessageBytes  int  // Max UTF-8 bytes per message (0 = unlimited), for byte-limited APIs like WeCom
+	SupportsEdit     bool // Previously sent messages can be edited
+	SupportsThreads  bool // Responses can be posted as threaded replies
+	SupportsFiles    bool // Send delivers Response.Files attachments
+	SupportsActions  bool // Send renders Response.Actions natively
+	NoProgress       bool // Intermediate progress updates are not sent, e.g. on email
+
+	// Render converts Markdown into what Send puts on the wire, so long
+	// responses are split by their formatted size (nil = sent as is).
+	Render func(text string) string
+}
+
 // Platform interface for messaging platforms
 type Platform interface {
 	Name() string
+	Capabilities() Capabilities
 	Start(ctx context.Context) error
 	Stop() error
 	Send(ctx context.Context, channelID string, resp Response) error
 	SetMessageHandler(handler func(msg Message))
 }
 
+// Editor is implemented by platforms that can edit messages they have sent.
+// The router uses it, when Capabilities reports SupportsEdit, to show a
+// single progress message that is updated in place.
+type Editor interface {
+	// SendEditable sends resp and returns an ID that can be passed to Edit.
+	SendEditable(ctx context.Context, channelID string, resp Response) (string, error)
+	// Edit replaces the text of a message previously sent by SendEditable.
+	Edit(ctx context.Context, channelID, messageID string, resp Response) error
+}
+
 // ProgressFunc sends an intermediate progress message to the user.
 type ProgressFunc func(text string)
 
@@ -64,16 +101,48 @@ func ProgressFromContext(ctx context.Context) ProgressFunc {
 	return fn
 }
 
+// StepFunc reports a tool step to the user: it is called with done=false
+// when the step starts and done=true when it finishes.
+type StepFunc func(name string, done bool)
+
+type stepKeyType struct{}
+
+// ContextWithStep attaches a StepFunc to the context.
+func ContextWithStep(ctx context.Context, fn StepFunc) context.Context {
+	return context.WithValue(ctx, stepKeyType{}, fn)
+}
+
+// StepFromContext retrieves the StepFunc from the context, or nil.
+func StepFromContext(ctx context.Context) StepFunc {
+	fn, _ := ctx.Value(stepKeyType{}).(StepFunc)
+	return fn
+}
+
 // MessageHandler processes incoming messages and returns responses
 type MessageHandler func(ctx context.Context, msg Message) (Response, error)
 
 // Router manages multiple messaging platforms
 type Router struct {
-	platforms map[string]Platform
-	handler   MessageHandler
-	mu        sync.RWMutex
-	ctx       context.Context
-	cancel    context.CancelFunc
+	platforms      map[string]Platform
+	handler        MessageHandler
+	fileThreshold  int    // send as a file when a response needs more parts than this (0 = never)
+	queueMode      string // QueueWait or QueueInterrupt
+	scope          string // default conversation scope
+	platformScopes map[string]string
+	channelContext int // recent channel messages to fetch (0 = off)
+	transcriber    speech.Transcriber
+	identities     identity.Resolver
+	acl            *acl.ACL     // senders allowed in; nil allows everyone
+	queue          *queue       // outbound queue; nil sends each message once
+	dedup          *dedup.Store // message IDs already handled; nil handles every message
+	debounce       time.Duration
+	fragMu         sync.Mutex
+	fragments      map[string]*fragments // text messages waiting for the user to pause
+	mu             sync.RWMutex
+	convMu         sync.Mutex
+	convs          map[string]*conversation
+	ctx            context.Context
+	cancel         context.CancelFunc
 }
 
 // New creates a new Router
@@ -81,9 +150,18 @@ func New(handler MessageHandler) *Router {
 	return &Router{
 		platforms: make(map[string]Platform),
 		handler:   handler,
+		convs:     make(map[string]*conversation),
+		fragments: make(map[string]*fragments),
 	}
 }
 
+// SetFileThreshold makes the router deliver responses that would be split into
+// more than parts messages as a Markdown file attachment, on platforms that
+// support files. Zero disables the fallback.
+func (r *Router) SetFileThreshold(parts int) {
+	r.fileThreshold = parts
+}
+
 // Register adds a platform to the router
 func (r *Router) Register(platform Platform) {
 	r.mu.Lock()
@@ -100,52 +178,155 @@ func (r *Router) Register(platform Platform) {
 	logger.Info("[Router] Registered platform: %s", name)
 }
 
+// Platforms returns the registered platforms, sorted by name.
+func (r *Router) Platforms() []Platform {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	platforms := make([]Platform, 0, len(r.platforms))
+	for _, p := range r.platforms {
+		platforms = append(platforms, p)
+	}
+	sort.Slice(platforms, func(i, j int) bool { return platforms[i].Name() < platforms[j].Name() })
+	return platforms
+}
+
+// platform returns a registered platform by name.
+func (r *Router) platform(name string) (Platform, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.platforms[name]
+	return p, ok
+}
+
 // handleMessage processes an incoming message
 func (r *Router) handleMessage(msg Message) {
-	// Use a generous timeout for the agent work — browser automation tasks can take
-	// many rounds (each ~5s) so 2 minutes is far too short.
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
-	defer cancel()
-
-	logger.Info("[Router] Message from %s/%s: %s", msg.Platform, msg.Username, msg.Text)
+	if r.duplicate(msg) {
+		logger.Info("[Router] Ignored redelivered message %s from %s/%s", msg.ID, msg.Platform, msg.Username)
+		return
+	}
 
-	// Attach a progress callback so the agent can send intermediate updates.
 	r.mu.RLock()
 	plat, platOK := r.platforms[msg.Platform]
 	r.mu.RUnlock()
+
+	if r.identities != nil && msg.UserID != "" {
+		msg.Identity = r.identities.Resolve(msg.Platform, msg.UserID)
+	}
+	if !r.admit(plat, platOK, msg) {
+		return
+	}
+
+	// Voice is transcribed first, so commands can be spoken too
+	if platOK && msg.MediaType == "voice" {
+		r.transcribe(plat, &msg)
+	}
+
+	if msg.Action != nil {
+		logger.Info("[Router] Action from %s/%s: %s=%s", msg.Platform, msg.Username, msg.Action.ID, msg.Action.Value)
+	} else {
+		logger.Info("[Router] Message from %s/%s: %s", msg.Platform, msg.Username, msg.Text)
+	}
+
+	// Turns are serialised per conversation; /stop cancels the running one.
+	key := r.conversationKey(msg)
+	if r.debounce > 0 && debounceable(msg) {
+		merged, ok := r.collect(key, msg)
+		if !ok {
+			return
+		}
+		msg = merged
+	}
+	if isStopCommand(msg.Text) {
+		text := "当前没有正在执行的任务。"
+		if t := r.stopTurn(key); t != nil {
+			logger.Info("[Router] Stopped turn for %s", key)
+			text = t.summary()
+		}
+		if platOK {
+			r.reply(plat, msg, text)
+		}
+		return
+	}
+	t := r.beginTurn(key, func() {
+		if platOK {
+			r.reply(plat, msg, "⏳ 上一条消息仍在处理，本条已排队（发送 /stop 可取消）")
+		}
+	})
+	if t == nil {
+		logger.Info("[Router] Dropped queued message for %s", key)
+		return
+	}
+	defer r.endTurn(key, t)
+	ctx := ContextWithConversationKey(t.ctx, key)
+	ctx = ContextWithConversationScope(ctx, r.conversationScope(msg.Platform))
 	if platOK {
+		if history := r.channelHistory(plat, msg); len(history) > 0 {
+			ctx = ContextWithChannelHistory(ctx, history)
+		}
+	}
+
+	// Attach a progress callback so the agent can send intermediate updates.
+	// Platforms that can edit messages get a single message updated in place;
+	// others receive each update as a new message.
+	var progress *progressMessage
+	if platOK && !plat.Capabilities().NoProgress {
 		progressResp := Response{
 			ThreadID: msg.ThreadID,
 			Metadata: msg.Metadata,
 		}
-		ctx = ContextWithProgress(ctx, func(text string) {
-			progressResp.Text = text
-			sendCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-			defer cancel()
-			if err := plat.Send(sendCtx, msg.ChannelID, progressResp); err != nil {
-				logger.Warn("[Router] Failed to send progress: %v", err)
-			}
-		})
+		if editor, ok := plat.(Editor); ok && plat.Capabilities().SupportsEdit {
+			progress = newProgressMessage(editor, msg.ChannelID, progressResp)
+			ctx = ContextWithProgress(ctx, progress.setStatus)
+		} else {
+			ctx = ContextWithProgress(ctx, func(text string) {
+				progressResp.Text = text
+				sendCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+				defer cancel()
+				if err := plat.Send(sendCtx, msg.ChannelID, progressResp); err != nil {
+					logger.Warn("[Router] Failed to send progress: %v", err)
+				}
+			})
+		}
 	}
+	ctx = ContextWithStep(ctx, func(name string, done bool) {
+		if done {
+			t.stepDone(name)
+		}
+		if progress != nil {
+			progress.step(name, done)
+		}
+	})
 
 	// Call the message handler
 	resp, err := r.handler(ctx, msg)
+
+	// The final answer replaces the progress message, if one was sent.
+	replaceID := ""
+	if progress != nil {
+		replaceID = progress.stop()
+	}
+
+	// A stopped turn's outcome is reported by /stop or superseded by the
+	// newer message, so only close out its progress message.
+	if t.wasStopped() {
+		logger.Info("[Router] Turn for %s was stopped", key)
+		if replaceID != "" {
+			progress.finish(progressStopped)
+		}
+		return
+	}
 	if err != nil {
 		logger.Error("[Router] Error handling message: %v", err)
 		resp = Response{Text: friendlyError(err)}
 	}
 
-	// Use a fresh context for sending the response so that an expired agent context
-	// doesn't prevent the reply from being delivered.
-	sendCtx, sendCancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer sendCancel()
-
 	// Send response back to the platform
 	r.mu.RLock()
 	platform, ok := r.platforms[msg.Platform]
 	r.mu.RUnlock()
 
-	if ok && (resp.Text != "" || len(resp.Files) > 0) {
+	if ok && (resp.Text != "" || len(resp.Files) > 0 || len(resp.Actions) > 0) {
 		if msg.ThreadID != "" {
 			resp.ThreadID = msg.ThreadID
 		}
@@ -159,7 +340,7 @@ func (r *Router) handleMessage(msg Message) {
 				}
 			}
 		}
-		if err := platform.Send(sendCtx, msg.ChannelID, resp); err != nil {
+		if err := r.deliver(platform, msg.ChannelID, resp, replaceID); err != nil {
 			logger.Error("[Router] Error sending response: %v", err)
 			// Try to notify the user about the error in chat
 			errResp := Response{
@@ -167,10 +348,22 @@ func (r *Router) handleMessage(msg Message) {
 				ThreadID: resp.ThreadID,
 				Metadata: resp.Metadata, // Preserve routing metadata (e.g., kf)
 			}
+			sendCtx, sendCancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer sendCancel()
 			if notifyErr := platform.Send(sendCtx, msg.ChannelID, errResp); notifyErr != nil {
 				logger.Error("[Router] Failed to send error notification: %v", notifyErr)
 			}
 		}
+	} else if replaceID != "" {
+		progress.finish(progressDone)
+	}
+}
+
+// reply sends a short router-generated message back to where msg came from.
+func (r *Router) reply(platform Platform, msg Message, text string) {
+	resp := Response{Text: text, ThreadID: msg.ThreadID, Metadata: msg.Metadata}
+	if err := r.deliver(platform, msg.ChannelID, resp, ""); err != nil {
+		logger.Warn("[Router] Failed to send reply: %v", err)
 	}
 }
 
@@ -189,6 +382,9 @@ func (r *Router) Start(ctx context.Context) error {
 	}
 
 	logger.Info("[Router] All platforms started")
+	if r.queue != nil {
+		go r.queue.run(r.ctx)
+	}
 	return nil
 }
 
@@ -219,7 +415,7 @@ func (r *Router) SendToUser(platformName, channelID string, resp Response) error
 	if !ok {
 		return fmt.Errorf("platform %s not registered", platformName)
 	}
-	return platform.Send(context.Background(), channelID, resp)
+	return r.deliver(platform, channelID, resp, "")
 }
 
 // Wait blocks until the router is stopped
@@ -0,0 +1,426 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pltanton/lingti-bot/internal/logger"
+)
+
+// splitHeadroom is the share of a platform's limit kept free for markup the
+// platform formatter adds (escapes, tags) and for the "(i/n)" part header.
+// Parts that still exceed the limit once rendered are split again.
+const splitHeadroom = 10
+
+// partHeader is the longest "(i/n)" header put in front of a part.
+const partHeader = "(99/99)\n"
+
+// budget is a per-message size limit; zero fields mean unlimited.
+type budget struct {
+	runes int
+	bytes int
+}
+
+func newBudget(caps Capabilities) budget {
+	shrink := func(n int) int {
+		if n == 0 {
+			return 0
+		}
+		return n - n/splitHeadroom - len(partHeader)
+	}
+	return budget{runes: shrink(caps.MaxMessageLength), bytes: shrink(caps.MaxMessageBytes)}
+}
+
+// renderedBudget is the limit a part must meet after Capabilities.Render.
+func renderedBudget(caps Capabilities) budget {
+	limit := func(n int) int {
+		if n == 0 {
+			return 0
+		}
+		return n - len(partHeader)
+	}
+	return budget{runes: limit(caps.MaxMessageLength), bytes: limit(caps.MaxMessageBytes)}
+}
+
+func (b budget) unlimited() bool {
+	return b.runes <= 0 && b.bytes <= 0
+}
+
+func (b budget) fits(s string) bool {
+	return (b.runes <= 0 || utf8.RuneCountInString(s) <= b.runes) &&
+		(b.bytes <= 0 || len(s) <= b.bytes)
+}
+
+// SplitText splits Markdown text into messages that fit the platform's length
+// limits. It cuts at paragraph boundaries where possible, then at line and
+// word boundaries, and closes and reopens code fences so every part renders
+// on its own. When more than one part is produced, each is prefixed with an
+// "(i/n)" header.
+func SplitText(text string, caps Capabilities) []string {
+	parts := splitRendered(text, caps)
+	if len(parts) > 1 {
+		for i := range parts {
+			parts[i] = fmt.Sprintf("(%d/%d)\n", i+1, len(parts)) + parts[i]
+		}
+	}
+	return parts
+}
+
+func splitText(text string, b budget) []string {
+	if b.unlimited() || b.fits(text) {
+		return []string{text}
+	}
+
+	var parts []string
+	var cur string
+	add := func(piece string) {
+		if cur == "" {
+			cur = piece
+		} else if b.fits(cur + "\n\n" + piece) {
+			cur += "\n\n" + piece
+		} else {
+			parts = append(parts, cur)
+			cur = piece
+		}
+	}
+	for _, seg := range segments(text) {
+		if b.fits(seg.text) {
+			add(seg.text)
+			continue
+		}
+		for _, piece := range splitSegment(seg, b) {
+			add(piece)
+		}
+	}
+	if cur != "" {
+		parts = append(parts, cur)
+	}
+	return parts
+}
+
+// splitRendered splits text by its Markdown size, then splits again any part
+// whose rendered form is still over the platform's limit, e.g. after
+// Discord's backslash escapes or Telegram's HTML entities.
+func splitRendered(text string, caps Capabilities) []string {
+	b := newBudget(caps)
+	parts := splitText(text, b)
+	if caps.Render == nil || b.unlimited() {
+		return parts
+	}
+	limit := renderedBudget(caps)
+	var out []string
+	for _, part := range parts {
+		out = append(out, fitRendered(part, b, limit, caps.Render)...)
+	}
+	return out
+}
+
+// fitRendered re-splits part with a budget shrunk by how far its rendered
+// form overshoots the limit, until every piece fits.
+func fitRendered(part string, b, limit budget, render func(string) string) []string {
+	rendered := render(part)
+	if limit.fits(rendered) {
+		return []string{part}
+	}
+	shrink := func(n, max, got int) int {
+		if n <= 0 {
+			return n
+		}
+		if got > max {
+			n = n * max / got
+		}
+		return n - n/splitHeadroom - 1
+	}
+	smaller := budget{
+		runes: shrink(b.runes, limit.runes, utf8.RuneCountInString(rendered)),
+		bytes: shrink(b.bytes, limit.bytes, len(rendered)),
+	}
+	if (b.runes > 0 && smaller.runes < 1) || (b.bytes > 0 && smaller.bytes < utf8.UTFMax) {
+		return []string{part}
+	}
+	var out []string
+	for _, piece := range splitText(part, smaller) {
+		out = append(out, fitRendered(piece, smaller, limit, render)...)
+	}
+	return out
+}
+
+// segment is a paragraph or a fenced code block.
+type segment struct {
+	text  string
+	fence string // opening fence line for code blocks, "" for paragraphs
+}
+
+// segments breaks text into paragraphs separated by blank lines, keeping
+// fenced code blocks whole even if they contain blank lines.
+func segments(text string) []segment {
+	var segs []segment
+	var cur []string
+	fence := ""
+	flush := func() {
+		if len(cur) > 0 {
+			segs = append(segs, segment{text: strings.Join(cur, "\n"), fence: fence})
+		}
+		cur = nil
+	}
+	inFence := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		isFence := strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+		switch {
+		case inFence:
+			cur = append(cur, line)
+			if isFence && strings.Trim(trimmed, trimmed[:1]) == "" {
+				flush()
+				inFence, fence = false, ""
+			}
+		case isFence:
+			flush()
+			inFence, fence = true, line
+			cur = append(cur, line)
+		case trimmed == "":
+			flush()
+		default:
+			cur = append(cur, line)
+		}
+	}
+	flush()
+	return segs
+}
+
+// splitSegment splits an oversized segment on line boundaries. Code block
+// pieces are each wrapped in their own fences.
+func splitSegment(seg segment, b budget) []string {
+	lines := strings.Split(seg.text, "\n")
+	open, close := "", ""
+	if seg.fence != "" {
+		open = seg.fence + "\n"
+		fenceMarker := strings.TrimSpace(seg.fence)[:3]
+		close = "\n" + fenceMarker
+		lines = lines[1:]
+		if n := len(lines); n > 0 && strings.HasPrefix(strings.TrimSpace(lines[n-1]), fenceMarker) {
+			lines = lines[:n-1]
+		}
+	}
+	inner := budget{runes: b.runes, bytes: b.bytes}
+	if inner.runes > 0 {
+		inner.runes -= utf8.RuneCountInString(open + close)
+	}
+	if inner.bytes > 0 {
+		inner.bytes -= len(open + close)
+	}
+
+	var pieces []string
+	var cur string
+	started := false
+	flush := func() {
+		if started {
+			pieces = append(pieces, open+cur+close)
+		}
+		cur, started = "", false
+	}
+	for _, line := range lines {
+		for _, chunk := range splitLine(line, inner) {
+			switch {
+			case !started:
+				cur, started = chunk, true
+			case inner.fits(cur + "\n" + chunk):
+				cur += "\n" + chunk
+			default:
+				flush()
+				cur, started = chunk, true
+			}
+		}
+	}
+	flush()
+	return pieces
+}
+
+// splitLine hard-wraps a single line that exceeds the budget, preferring to
+// break after whitespace in the second half of a chunk.
+func splitLine(line string, b budget) []string {
+	if b.fits(line) {
+		return []string{line}
+	}
+	var chunks []string
+	for line != "" {
+		runes, bytes, cut, lastSpace := 0, 0, 0, -1
+		for i, r := range line {
+			size := utf8.RuneLen(r)
+			if (b.runes > 0 && runes+1 > b.runes) || (b.bytes > 0 && bytes+size > b.bytes) {
+				break
+			}
+			runes++
+			bytes += size
+			cut = i + size
+			if unicode.IsSpace(r) {
+				lastSpace = cut
+			}
+		}
+		if cut == 0 {
+			// Budget smaller than one rune; emit it anyway to make progress.
+			_, size := utf8.DecodeRuneInString(line)
+			cut = size
+		}
+		if cut < len(line) && lastSpace > cut/2 {
+			cut = lastSpace
+		}
+		chunks = append(chunks, line[:cut])
+		line = line[cut:]
+	}
+	return chunks
+}
+
+// outgoing is one message of a response, ready for Platform.Send. It is
+// what the outbound queue stores, so parts already sent are not repeated
+// when a later part is retried.
+type outgoing struct {
+	Response  Response
+	ReplaceID string   `json:",omitempty"` // progress message to write the response over
+	TempFiles []string `json:",omitempty"` // files to remove once the message is sent
+}
+
+// deliver sends resp to platform, splitting text that exceeds the platform's
+// limits into several messages. Files and actions are attached to the last
+// part; actions are listed in the text on platforms that cannot show them. Each
+// part gets its own send timeout so long replies are not cut short. If
+// replaceID is set, the first part is written over that message instead.
+//
+// With an outbound queue the parts are queued and retried until they are
+// sent, and deliver only fails if they cannot be queued.
+func (r *Router) deliver(platform Platform, channelID string, resp Response, replaceID string) error {
+	msgs := r.prepare(platform, resp, replaceID)
+	if r.queue != nil {
+		err := r.queue.enqueue(platform.Name(), channelID, msgs)
+		if err == nil {
+			return nil
+		}
+		logger.Warn("[Router] Failed to queue response, sending directly: %v", err)
+	}
+
+	defer removeFiles(msgs[len(msgs)-1].TempFiles)
+	for i, m := range msgs {
+		if err := sendOutgoing(platform, channelID, m); err != nil {
+			if len(msgs) > 1 {
+				return fmt.Errorf("part %d/%d: %w", i+1, len(msgs), err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// prepare splits a response into the messages deliver sends.
+func (r *Router) prepare(platform Platform, resp Response, replaceID string) []outgoing {
+	caps := platform.Capabilities()
+	resp.Actions = NormalizeActions(resp.Actions)
+	if len(resp.Actions) > 0 && !caps.SupportsActions {
+		resp.Text += ActionsText(resp.Actions)
+		resp.Actions = nil
+	}
+	parts := SplitText(resp.Text, caps)
+
+	if r.fileThreshold > 0 && len(parts) > r.fileThreshold && caps.SupportsFiles {
+		path, err := writeResponseFile(resp.Text)
+		if err != nil {
+			logger.Warn("[Router] Failed to write long response to file: %v", err)
+		} else {
+			preview := splitRendered(resp.Text, caps)[0]
+			resp.Text = preview + "\n\n……（内容较长，完整回复见附件 response.md）"
+			resp.Files = append(resp.Files, FileAttachment{Path: path, Name: "response.md", Temp: true})
+			parts = []string{resp.Text}
+		}
+	}
+	var tempFiles []string
+	for _, f := range resp.Files {
+		if f.Temp {
+			tempFiles = append(tempFiles, f.Path)
+		}
+	}
+
+	msgs := make([]outgoing, len(parts))
+	for i, part := range parts {
+		partResp := resp
+		partResp.Text = part
+		if i < len(parts)-1 {
+			partResp.Files = nil
+			partResp.Actions = nil
+		}
+		msgs[i] = outgoing{Response: partResp}
+	}
+	msgs[0].ReplaceID = replaceID
+	msgs[len(msgs)-1].TempFiles = tempFiles
+	return msgs
+}
+
+// sendOutgoing makes one attempt at sending a prepared message.
+func sendOutgoing(platform Platform, channelID string, m outgoing) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if m.ReplaceID != "" {
+		return replaceMessage(ctx, platform, channelID, m.ReplaceID, m.Response)
+	}
+	return platform.Send(ctx, channelID, m.Response)
+}
+
+func removeFiles(paths []string) {
+	for _, p := range paths {
+		os.Remove(p)
+	}
+}
+
+// replaceMessage edits messageID to show resp.Text and sends any files
+// separately, since edits cannot add attachments. A response with actions is
+// sent as a new message instead, and the progress message is closed out. It
+// falls back to a new message if the edit fails.
+func replaceMessage(ctx context.Context, platform Platform, channelID, messageID string, resp Response) error {
+	editor, ok := platform.(Editor)
+	if !ok {
+		return platform.Send(ctx, channelID, resp)
+	}
+	if len(resp.Actions) > 0 {
+		done := Response{Text: "✅", ThreadID: resp.ThreadID, Metadata: resp.Metadata}
+		if err := editor.Edit(ctx, channelID, messageID, done); err != nil {
+			logger.Warn("[Router] Failed to close progress message: %v", err)
+		}
+		return platform.Send(ctx, channelID, resp)
+	}
+	edit := resp
+	edit.Files = nil
+	if edit.Text == "" {
+		edit.Text = "✅"
+	}
+	if err := editor.Edit(ctx, channelID, messageID, edit); err != nil {
+		logger.Warn("[Router] Failed to replace progress message, sending instead: %v", err)
+		return platform.Send(ctx, channelID, resp)
+	}
+	if len(resp.Files) == 0 {
+		return nil
+	}
+	resp.Text = ""
+	return platform.Send(ctx, channelID, resp)
+}
+
+// writeResponseFile saves a long response as a Markdown file for upload.
+func writeResponseFile(text string) (string, error) {
+	dir := filepath.Join(os.TempDir(), "lingti-bot")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(dir, "response-*.md")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(text); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,153 @@
+package router
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitText_FitsUnchanged(t *testing.T) {
+	parts := SplitText("short reply", Capabilities{MaxMessageLength: 100})
+	if len(parts) != 1 || parts[0] != "short reply" {
+		t.Errorf("got %q", parts)
+	}
+	parts = SplitText(strings.Repeat("x", 10000), Capabilities{})
+	if len(parts) != 1 {
+		t.Errorf("unlimited platform split into %d parts", len(parts))
+	}
+}
+
+func TestSplitText_ParagraphBoundaries(t *testing.T) {
+	para := strings.Repeat("word ", 30) // 150 chars
+	text := para + "\n\n" + para + "\n\n" + para
+	parts := SplitText(text, Capabilities{MaxMessageLength: 400})
+	if len(parts) < 2 {
+		t.Fatalf("expected a split, got %d part(s)", len(parts))
+	}
+	for i, p := range parts {
+		if n := utf8.RuneCountInString(p); n > 400 {
+			t.Errorf("part %d has %d runes", i, n)
+		}
+		if !strings.HasPrefix(p, "(") || !strings.Contains(p[:8], "/") {
+			t.Errorf("part %d missing header: %q", i, p[:20])
+		}
+		body := p[strings.Index(p, "\n")+1:]
+		for _, chunk := range strings.Split(body, "\n\n") {
+			if chunk != para {
+				t.Errorf("part %d cut mid-paragraph: %q", i, chunk)
+			}
+		}
+	}
+}
+
+func TestSplitText_CodeBlockReopened(t *testing.T) {
+	var code []string
+	for range 80 {
+		code = append(code, "fmt.Println(\"line\")")
+	}
+	text := "Here:\n\n```go\n" + strings.Join(code, "\n") + "\n```\n\nDone."
+	parts := SplitText(text, Capabilities{MaxMessageLength: 500})
+	if len(parts) < 3 {
+		t.Fatalf("expected several parts, got %d", len(parts))
+	}
+	for i, p := range parts {
+		if strings.Count(p, "```")%2 != 0 {
+			t.Errorf("part %d has unbalanced fences:\n%s", i, p)
+		}
+	}
+	if !strings.Contains(parts[1], "```go\n") {
+		t.Errorf("continuation part does not reopen the go fence:\n%s", parts[1])
+	}
+}
+
+func TestSplitText_ByteLimit(t *testing.T) {
+	text := strings.Repeat("中文字符", 400) // 4800 bytes, one line
+	parts := SplitText(text, Capabilities{MaxMessageBytes: 2048})
+	if len(parts) < 3 {
+		t.Fatalf("expected at least 3 parts, got %d", len(parts))
+	}
+	var joined strings.Builder
+	for i, p := range parts {
+		if len(p) > 2048 {
+			t.Errorf("part %d is %d bytes", i, len(p))
+		}
+		if !utf8.ValidString(p) {
+			t.Errorf("part %d split inside a rune", i)
+		}
+		joined.WriteString(p[strings.Index(p, "\n")+1:])
+	}
+	if joined.String() != text {
+		t.Error("rejoined parts differ from the original text")
+	}
+}
+
+func TestSplitText_RenderedSize(t *testing.T) {
+	// Every "*" doubles once escaped, so the Markdown fits but the wire text
+	// would not without a second split.
+	escape := strings.NewReplacer("*", `\*`).Replace
+	text := strings.Repeat("a*b*c*d* ", 200)
+	caps := Capabilities{MaxMessageLength: 500, Render: escape}
+	parts := SplitText(text, caps)
+	var joined strings.Builder
+	for i, p := range parts {
+		if n := utf8.RuneCountInString(escape(p)); n > 500 {
+			t.Errorf("part %d renders to %d characters", i, n)
+		}
+		joined.WriteString(p[strings.Index(p, "\n")+1:])
+	}
+	if strings.ReplaceAll(joined.String(), " ", "") != strings.ReplaceAll(text, " ", "") {
+		t.Error("rejoined parts differ from the original text")
+	}
+}
+
+type fakePlatform struct {
+	caps Capabilities
+	sent []Response
+}
+
+func (f *fakePlatform) Name() string                    { return "fake" }
+func (f *fakePlatform) Capabilities() Capabilities      { return f.caps }
+func (f *fakePlatform) Start(ctx context.Context) error { return nil }
+func (f *fakePlatform) Stop() error                     { return nil }
+func (f *fakePlatform) SetMessageHandler(func(Message)) {}
+func (f *fakePlatform) Send(ctx context.Context, channelID string, resp Response) error {
+	f.sent = append(f.sent, resp)
+	return nil
+}
+
+func TestDeliver_FilesOnLastPart(t *testing.T) {
+	p := &fakePlatform{caps: Capabilities{MaxMessageLength: 200}}
+	r := New(nil)
+	text := strings.Repeat("paragraph text\n\n", 40)
+	resp := Response{Text: text, Files: []FileAttachment{{Path: "/tmp/report.pdf"}}}
+	if err := r.deliver(p, "c1", resp, ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.sent) < 2 {
+		t.Fatalf("expected multiple sends, got %d", len(p.sent))
+	}
+	for i, s := range p.sent[:len(p.sent)-1] {
+		if len(s.Files) != 0 {
+			t.Errorf("part %d carries files", i)
+		}
+	}
+	if len(p.sent[len(p.sent)-1].Files) != 1 {
+		t.Error("last part should carry the attachment")
+	}
+}
+
+func TestDeliver_SendAsFile(t *testing.T) {
+	p := &fakePlatform{caps: Capabilities{MaxMessageLength: 200, SupportsFiles: true}}
+	r := New(nil)
+	r.SetFileThreshold(2)
+	if err := r.deliver(p, "c1", Response{Text: strings.Repeat("paragraph text\n\n", 40)}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.sent) != 1 {
+		t.Fatalf("expected a single message, got %d", len(p.sent))
+	}
+	if len(p.sent[0].Files) != 1 || p.sent[0].Files[0].Name != "response.md" {
+		t.Errorf("expected response.md attachment, got %+v", p.sent[0].Files)
+	}
+}
@@ -0,0 +1,164 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Queue modes control what happens when a message arrives while the agent is
+// still working on an earlier message in the same conversation.
+const (
+	QueueWait      = "wait"      // the new message runs after the current turn
+	QueueInterrupt = "interrupt" // the new message cancels the current turn
+)
+
+// turnTimeout bounds a single agent turn. Browser automation tasks can take
+// many rounds (each ~5s), so this is generous.
+const turnTimeout = 10 * time.Minute
+
+// isStopCommand reports whether text asks to cancel the running turn.
+func isStopCommand(text string) bool {
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "/stop", "/cancel", "停止":
+		return true
+	}
+	return false
+}
+
+// turn is one agent call in progress.
+type turn struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	started time.Time
+
+	mu      sync.Mutex
+	steps   []string // completed tool steps
+	stopped bool     // cancelled by /stop or by a newer message
+}
+
+func (t *turn) stepDone(name string) {
+	t.mu.Lock()
+	t.steps = append(t.steps, name)
+	t.mu.Unlock()
+}
+
+func (t *turn) stop() {
+	t.mu.Lock()
+	t.stopped = true
+	t.mu.Unlock()
+	t.cancel()
+}
+
+func (t *turn) wasStopped() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stopped
+}
+
+// summary describes what the turn completed before it was stopped.
+func (t *turn) summary() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "⏹ 已停止当前任务（已运行 %s）", time.Since(t.started).Round(time.Second))
+	if len(t.steps) == 0 {
+		sb.WriteString("\n\n尚未完成任何步骤。")
+	} else {
+		fmt.Fprintf(&sb, "\n\n已完成 %d 步：", len(t.steps))
+		for _, s := range t.steps {
+			fmt.Fprintf(&sb, "\n- `%s`", s)
+		}
+	}
+	return sb.String()
+}
+
+// conversation serialises turns for one ConversationKey.
+type conversation struct {
+	slot    chan struct{} // holds a token while a turn runs
+	current *turn
+	gen     int // bumped to drop messages still waiting for the slot
+	refs    int // running plus waiting messages
+}
+
+// SetQueueMode sets how a message is handled while an earlier one in the same
+// conversation is still running: QueueWait (default) or QueueInterrupt.
+func (r *Router) SetQueueMode(mode string) {
+	r.queueMode = mode
+}
+
+// beginTurn waits until the conversation is free and starts a turn for it.
+// onWait is called if the message has to queue behind a running turn. It
+// returns nil if the message was dropped by /stop or superseded by a newer
+// message while waiting.
+func (r *Router) beginTurn(key string, onWait func()) *turn {
+	r.convMu.Lock()
+	c, ok := r.convs[key]
+	if !ok {
+		c = &conversation{slot: make(chan struct{}, 1)}
+		r.convs[key] = c
+	}
+	c.refs++
+	busy := c.refs > 1
+	if busy && r.queueMode == QueueInterrupt {
+		c.gen++
+		if c.current != nil {
+			c.current.stop()
+		}
+	}
+	gen := c.gen
+	r.convMu.Unlock()
+
+	if busy && r.queueMode != QueueInterrupt && onWait != nil {
+		onWait()
+	}
+	c.slot <- struct{}{}
+
+	r.convMu.Lock()
+	defer r.convMu.Unlock()
+	if c.gen != gen {
+		<-c.slot
+		r.releaseLocked(key, c)
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), turnTimeout)
+	t := &turn{ctx: ctx, cancel: cancel, started: time.Now()}
+	c.current = t
+	return t
+}
+
+// endTurn releases the conversation for the next waiting message.
+func (r *Router) endTurn(key string, t *turn) {
+	t.cancel()
+	r.convMu.Lock()
+	defer r.convMu.Unlock()
+	c := r.convs[key]
+	c.current = nil
+	<-c.slot
+	r.releaseLocked(key, c)
+}
+
+func (r *Router) releaseLocked(key string, c *conversation) {
+	c.refs--
+	if c.refs == 0 {
+		delete(r.convs, key)
+	}
+}
+
+// stopTurn cancels the running turn for key and drops any queued messages.
+// It returns the stopped turn, or nil if nothing was running.
+func (r *Router) stopTurn(key string) *turn {
+	r.convMu.Lock()
+	defer r.convMu.Unlock()
+	c, ok := r.convs[key]
+	if !ok {
+		return nil
+	}
+	c.gen++
+	if c.current != nil {
+		c.current.stop()
+	}
+	return c.current
+}
@@ -0,0 +1,152 @@
+package router
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncPlatform is a fakePlatform safe for concurrent sends.
+type syncPlatform struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (f *syncPlatform) Name() string                    { return "fake" }
+func (f *syncPlatform) Capabilities() Capabilities      { return Capabilities{} }
+func (f *syncPlatform) Start(ctx context.Context) error { return nil }
+func (f *syncPlatform) Stop() error                     { return nil }
+func (f *syncPlatform) SetMessageHandler(func(Message)) {}
+func (f *syncPlatform) Send(ctx context.Context, channelID string, resp Response) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, resp.Text)
+	return nil
+}
+
+func (f *syncPlatform) texts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.sent...)
+}
+
+// blockingHandler completes one step, then blocks until its context is
+// cancelled or release is closed.
+func blockingHandler(started chan<- string, release <-chan struct{}) MessageHandler {
+	return func(ctx context.Context, msg Message) (Response, error) {
+		StepFromContext(ctx)("browser_navigate", false)
+		StepFromContext(ctx)("browser_navigate", true)
+		started <- msg.Text
+		select {
+		case <-ctx.Done():
+			return Response{}, ctx.Err()
+		case <-release:
+			return Response{Text: "done: " + msg.Text}, nil
+		}
+	}
+}
+
+func newTestRouter(handler MessageHandler) (*Router, *syncPlatform) {
+	p := &syncPlatform{}
+	r := New(handler)
+	r.Register(p)
+	return r, p
+}
+
+func msgFor(text string) Message {
+	return Message{Platform: "fake", ChannelID: "c1", UserID: "u1", Text: text}
+}
+
+func TestStop_CancelsRunningTurn(t *testing.T) {
+	started := make(chan string, 1)
+	r, p := newTestRouter(blockingHandler(started, nil))
+
+	done := make(chan struct{})
+	go func() {
+		r.handleMessage(msgFor("long task"))
+		close(done)
+	}()
+	<-started
+
+	r.handleMessage(msgFor("/stop"))
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("turn was not cancelled")
+	}
+
+	sent := p.texts()
+	if len(sent) != 1 {
+		t.Fatalf("expected only the stop summary, got %q", sent)
+	}
+	if !strings.Contains(sent[0], "已停止") || !strings.Contains(sent[0], "browser_navigate") {
+		t.Errorf("summary = %q", sent[0])
+	}
+}
+
+func TestStop_NothingRunning(t *testing.T) {
+	r, p := newTestRouter(nil)
+	r.handleMessage(msgFor("停止"))
+	if sent := p.texts(); len(sent) != 1 || !strings.Contains(sent[0], "没有正在执行") {
+		t.Errorf("sent = %q", sent)
+	}
+}
+
+func TestQueue_WaitRunsInOrder(t *testing.T) {
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	r, p := newTestRouter(blockingHandler(started, release))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() { defer wg.Done(); r.handleMessage(msgFor("first")) }()
+	<-started
+	wg.Add(1)
+	go func() { defer wg.Done(); r.handleMessage(msgFor("second")) }()
+
+	// The second message must not start while the first is running.
+	select {
+	case text := <-started:
+		t.Fatalf("%q started concurrently", text)
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	if text := <-started; text != "second" {
+		t.Errorf("started %q", text)
+	}
+	wg.Wait()
+
+	sent := p.texts()
+	if len(sent) != 3 || !strings.Contains(sent[0], "排队") || sent[1] != "done: first" || sent[2] != "done: second" {
+		t.Errorf("sent = %q", sent)
+	}
+}
+
+func TestQueue_Interrupt(t *testing.T) {
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	r, p := newTestRouter(blockingHandler(started, release))
+	r.SetQueueMode(QueueInterrupt)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() { defer wg.Done(); r.handleMessage(msgFor("first")) }()
+	<-started
+	wg.Add(1)
+	go func() { defer wg.Done(); r.handleMessage(msgFor("second")) }()
+
+	if text := <-started; text != "second" {
+		t.Errorf("started %q", text)
+	}
+	close(release)
+	wg.Wait()
+
+	if sent := p.texts(); len(sent) != 1 || sent[0] != "done: second" {
+		t.Errorf("sent = %q", sent)
+	}
+	if len(r.convs) != 0 {
+		t.Errorf("%d conversations left behind", len(r.convs))
+	}
+}
@@ -0,0 +1,50 @@
+package router
+
+import (
+	"context"
+	"time"
+
+	"github.com/pltanton/lingti-bot/internal/logger"
+	"github.com/pltanton/lingti-bot/internal/speech"
+)
+
+// MediaDownloader is implemented by platforms that can fetch the media of
+// an incoming message. The router uses it to transcribe voice messages.
+type MediaDownloader interface {
+	// DownloadMedia returns the content of msg.MediaID.
+	DownloadMedia(ctx context.Context, msg Message) (speech.Audio, error)
+}
+
+// SetTranscriber makes the router transcribe incoming voice messages on
+// platforms that implement MediaDownloader, replacing their placeholder
+// text with what was said. Nil disables it.
+func (r *Router) SetTranscriber(t speech.Transcriber) {
+	r.transcriber = t
+}
+
+// transcribe replaces the text of a voice message with its transcript. On
+// failure the message keeps its placeholder text.
+func (r *Router) transcribe(platform Platform, msg *Message) {
+	md, ok := platform.(MediaDownloader)
+	if !ok || r.transcriber == nil || msg.MediaID == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	audio, err := md.DownloadMedia(ctx, *msg)
+	if err != nil {
+		logger.Warn("[Router] Failed to download voice message from %s: %v", msg.Platform, err)
+		return
+	}
+	text, err := r.transcriber.Transcribe(ctx, audio)
+	if err != nil {
+		logger.Warn("[Router] Failed to transcribe voice message from %s: %v", msg.Platform, err)
+		return
+	}
+	if text == "" {
+		return
+	}
+	logger.Info("[Router] Transcribed voice message from %s/%s (%d bytes %s)", msg.Platform, msg.Username, len(audio.Data), audio.Format)
+	msg.Text = text
+}
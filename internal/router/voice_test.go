@@ -0,0 +1,50 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pltanton/lingti-bot/internal/speech"
+)
+
+type voicePlatform struct {
+	fakePlatform
+	err error
+}
+
+func (v *voicePlatform) DownloadMedia(ctx context.Context, msg Message) (speech.Audio, error) {
+	return speech.Audio{Data: []byte(msg.MediaID), Format: "ogg"}, v.err
+}
+
+type fakeTranscriber struct{}
+
+func (fakeTranscriber) Transcribe(ctx context.Context, audio speech.Audio) (string, error) {
+	if string(audio.Data) == "silence" {
+		return "", nil
+	}
+	return "transcript of " + string(audio.Data), nil
+}
+
+func TestTranscribe(t *testing.T) {
+	tests := []struct {
+		name     string
+		platform Platform
+		mediaID  string
+		want     string
+	}{
+		{"transcribed", &voicePlatform{}, "m1", "transcript of m1"},
+		{"download fails", &voicePlatform{err: errors.New("gone")}, "m1", "[语音]"},
+		{"nothing said", &voicePlatform{}, "silence", "[语音]"},
+		{"no downloader", &fakePlatform{}, "m1", "[语音]"},
+	}
+	r := New(nil)
+	r.SetTranscriber(fakeTranscriber{})
+	for _, tt := range tests {
+		msg := Message{Text: "[语音]", MediaID: tt.mediaID, MediaType: "voice"}
+		r.transcribe(tt.platform, &msg)
+		if msg.Text != tt.want {
+			t.Errorf("%s: text = %q, want %q", tt.name, msg.Text, tt.want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 // Package routing implements priority-based agent route resolution.
-// It maps an incoming (platform, channelID, userID) triple to an agent ID
-// by scanning Bindings from most-specific to least-specific match.
+// It maps an incoming (platform, channelID, userID) triple, plus the
+// sender's canonical identity, to an agent ID by scanning Bindings from
+// most-specific to least-specific match.
 package routing
 
 import "github.com/pltanton/lingti-bot/internal/config"
@@ -16,16 +17,25 @@ type RouteResult struct {
 // Priority (highest to lowest):
 //  1. platform + channelID + userID  — most specific
 //  2. platform + userID
-//  3. platform + channelID
-//  4. platform only
-//  5. no match → AgentID == ""
+//  3. identity (with or without platform/channel)
+//  4. platform + channelID
+//  5. platform only
+//  6. no match → AgentID == ""
 //
 // Specificity is computed with power-of-two weights:
 //
-//	platform=1, channelID=2, userID=4
+//	platform=1, channelID=2, identity=4, userID=8
 //
-// so any combination with userID always outranks one without, etc.
+// so any combination with userID always outranks one without, etc. An
+// identity names a person across their linked accounts, so it ranks below
+// a single account but above a channel.
 func ResolveRoute(cfg *config.Config, platform, channelID, userID string) RouteResult {
+	return ResolveRouteWithIdentity(cfg, platform, channelID, userID, "")
+}
+
+// ResolveRouteWithIdentity is ResolveRoute for a sender whose canonical
+// identity is known, so bindings that match on identity can apply.
+func ResolveRouteWithIdentity(cfg *config.Config, platform, channelID, userID, identity string) RouteResult {
 	if len(cfg.Bindings) == 0 {
 		return RouteResult{}
 	}
@@ -46,6 +56,9 @@ func ResolveRoute(cfg *config.Config, platform, channelID, userID string) RouteR
 		if m.UserID != "" && m.UserID != userID {
 			continue
 		}
+		if m.Identity != "" && m.Identity != identity {
+			continue
+		}
 
 		// Compute specificity score.
 		score := 0
@@ -61,11 +74,18 @@ func ResolveRoute(cfg *config.Config, platform, channelID, userID string) RouteR
 			}
 			desc += "channel=" + m.ChannelID
 		}
-		if m.UserID != "" {
+		if m.Identity != "" {
 			score += 4
 			if desc != "" {
 				desc += " "
 			}
+			desc += "identity=" + m.Identity
+		}
+		if m.UserID != "" {
+			score += 8
+			if desc != "" {
+				desc += " "
+			}
 			desc += "user=" + m.UserID
 		}
 
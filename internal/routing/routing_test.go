@@ -78,3 +78,30 @@ func TestResolveRoute_WildcardBinding(t *testing.T) {
 		t.Errorf("expected catchall, got %q", r.AgentID)
 	}
 }
+
+func TestResolveRoute_Identity(t *testing.T) {
+	cfg := &config.Config{
+		Bindings: []config.AgentBinding{
+			{AgentID: "general", Match: config.AgentBindingMatch{Platform: "feishu"}},
+			{AgentID: "channel-bot", Match: config.AgentBindingMatch{Platform: "feishu", ChannelID: "oc_ops"}},
+			{AgentID: "personal", Match: config.AgentBindingMatch{Identity: "user-1"}},
+			{AgentID: "vip", Match: config.AgentBindingMatch{Platform: "feishu", UserID: "ou_vip"}},
+		},
+	}
+
+	// The identity binding follows the person onto any platform.
+	if r := ResolveRouteWithIdentity(cfg, "slack", "D1", "U1", "user-1"); r.AgentID != "personal" {
+		t.Errorf("slack: expected personal, got %q", r.AgentID)
+	}
+	// It beats channel and platform bindings...
+	if r := ResolveRouteWithIdentity(cfg, "feishu", "oc_ops", "ou_1", "user-1"); r.AgentID != "personal" {
+		t.Errorf("feishu channel: expected personal, got %q (%s)", r.AgentID, r.MatchedBy)
+	}
+	// ...but not a binding for the exact account.
+	if r := ResolveRouteWithIdentity(cfg, "feishu", "oc_ops", "ou_vip", "user-1"); r.AgentID != "vip" {
+		t.Errorf("feishu vip: expected vip, got %q", r.AgentID)
+	}
+	if r := ResolveRoute(cfg, "feishu", "oc_ops", "ou_1"); r.AgentID != "channel-bot" {
+		t.Errorf("without identity: expected channel-bot, got %q", r.AgentID)
+	}
+}
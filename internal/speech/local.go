@@ -0,0 +1,119 @@
+package speech
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Whisper transcribes with the whisper.cpp command line tool.
+type Whisper struct {
+	Binary   string // default: whisper-cli
+	Model    string // path to a ggml model, e.g. ggml-base.bin
+	Language string // default: auto
+}
+
+// Transcribe implements Transcriber.
+func (w *Whisper) Transcribe(ctx context.Context, audio Audio) (string, error) {
+	if w.Model == "" {
+		return "", fmt.Errorf("whisper: model path is required")
+	}
+	wav, err := Convert(ctx, audio, "wav")
+	if err != nil {
+		return "", err
+	}
+	path, err := WriteTemp(wav)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(path)
+
+	lang := w.Language
+	if lang == "" {
+		lang = "auto"
+	}
+	out, err := run(ctx, orDefault(w.Binary, "whisper-cli"), nil,
+		"-m", w.Model, "-f", path, "-l", lang, "-nt", "-np")
+	if err != nil {
+		return "", err
+	}
+	return joinLines(string(out)), nil
+}
+
+// Vosk transcribes with vosk-transcriber from the vosk Python package.
+type Vosk struct {
+	Binary string // default: vosk-transcriber
+	Model  string // model directory; empty lets vosk pick its default model
+}
+
+// Transcribe implements Transcriber.
+func (v *Vosk) Transcribe(ctx context.Context, audio Audio) (string, error) {
+	wav, err := Convert(ctx, audio, "wav")
+	if err != nil {
+		return "", err
+	}
+	path, err := WriteTemp(wav)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(path)
+
+	args := []string{"-i", path}
+	if v.Model != "" {
+		args = append(args, "-m", v.Model)
+	}
+	out, err := run(ctx, orDefault(v.Binary, "vosk-transcriber"), nil, args...)
+	if err != nil {
+		return "", err
+	}
+	return joinLines(string(out)), nil
+}
+
+// Piper synthesizes speech with the piper command line tool.
+type Piper struct {
+	Binary string // default: piper
+	Model  string // path to a voice model, e.g. zh_CN-huayan-medium.onnx
+}
+
+// Synthesize implements Synthesizer. The result is WAV.
+func (p *Piper) Synthesize(ctx context.Context, text string) (Audio, error) {
+	if p.Model == "" {
+		return Audio{}, fmt.Errorf("piper: model path is required")
+	}
+	dir, err := os.MkdirTemp("", "lingti-speech-")
+	if err != nil {
+		return Audio{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "out.wav")
+	if _, err := run(ctx, orDefault(p.Binary, "piper"), []byte(text),
+		"--model", p.Model, "--output_file", out); err != nil {
+		return Audio{}, err
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		return Audio{}, err
+	}
+	return Audio{Data: data, Format: "wav"}, nil
+}
+
+// joinLines joins the non-empty lines of engine output with spaces.
+func joinLines(s string) string {
+	var parts []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			parts = append(parts, line)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,107 @@
+package speech
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAI transcribes and synthesizes through an OpenAI-compatible /audio API
+// (OpenAI, Groq, SiliconFlow, a local whisper server, ...).
+type OpenAI struct {
+	BaseURL  string // default: https://api.openai.com/v1
+	APIKey   string
+	Model    string // default: whisper-1 for transcription, tts-1 for synthesis
+	Language string // transcription language hint
+	Voice    string // synthesis voice (default: alloy)
+	Client   *http.Client
+}
+
+// Transcribe implements Transcriber using POST /audio/transcriptions.
+func (o *OpenAI) Transcribe(ctx context.Context, audio Audio) (string, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", "voice."+extension(audio.Format))
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(audio.Data); err != nil {
+		return "", err
+	}
+	w.WriteField("model", orDefault(o.Model, "whisper-1"))
+	w.WriteField("response_format", "json")
+	if o.Language != "" {
+		w.WriteField("language", o.Language)
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	respBody, err := o.post(ctx, "/audio/transcriptions", w.FormDataContentType(), &body)
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", fmt.Errorf("failed to decode transcription: %w", err)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
+
+// Synthesize implements Synthesizer using POST /audio/speech. The result is
+// Ogg Opus, which chat apps show as a voice note.
+func (o *OpenAI) Synthesize(ctx context.Context, text string) (Audio, error) {
+	payload, err := json.Marshal(map[string]string{
+		"model":           orDefault(o.Model, "tts-1"),
+		"voice":           orDefault(o.Voice, "alloy"),
+		"input":           text,
+		"response_format": "opus",
+	})
+	if err != nil {
+		return Audio{}, err
+	}
+	data, err := o.post(ctx, "/audio/speech", "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return Audio{}, err
+	}
+	return Audio{Data: data, Format: "ogg"}, nil
+}
+
+func (o *OpenAI) post(ctx context.Context, path, contentType string, body io.Reader) ([]byte, error) {
+	base := strings.TrimRight(orDefault(o.BaseURL, "https://api.openai.com/v1"), "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if o.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.APIKey)
+	}
+
+	client := o.Client
+	if client == nil {
+		client = &http.Client{Timeout: 120 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("speech API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("speech API error %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
@@ -0,0 +1,175 @@
+// Package speech turns voice messages into text and replies into voice.
+//
+// Transcribers and synthesizers either run local engines (whisper.cpp, Vosk,
+// Piper) as subprocesses or call an OpenAI-compatible /audio API. Local
+// engines that need WAV input get it from ffmpeg, which must be on PATH for
+// anything but WAV audio.
+package speech
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Audio is an encoded audio clip.
+type Audio struct {
+	Data   []byte
+	Format string // container or codec as a file extension: "ogg", "amr", "mp3", "wav", ...
+}
+
+// MaxAudioSize caps downloaded voice messages. It is the largest file the
+// OpenAI transcription API accepts, and well above any platform's voice limit.
+const MaxAudioSize = 25 << 20
+
+// ReadAudio reads a voice download, failing once it exceeds MaxAudioSize.
+func ReadAudio(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxAudioSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxAudioSize {
+		return nil, fmt.Errorf("audio is larger than %d MB", MaxAudioSize>>20)
+	}
+	return data, nil
+}
+
+// Transcriber turns speech into text.
+type Transcriber interface {
+	Transcribe(ctx context.Context, audio Audio) (string, error)
+}
+
+// Synthesizer turns text into speech.
+type Synthesizer interface {
+	Synthesize(ctx context.Context, text string) (Audio, error)
+}
+
+// Config selects and configures a speech engine.
+type Config struct {
+	Engine   string // "whisper", "vosk", "piper" or "openai"
+	Binary   string // engine executable (default: whisper-cli, vosk-transcriber, piper)
+	Model    string // model file or directory for local engines, model name for openai
+	Language string // spoken language, e.g. "zh" (default: auto-detect where supported)
+	BaseURL  string // openai: API base URL (default: https://api.openai.com/v1)
+	APIKey   string // openai: API key
+	Voice    string // openai: voice for synthesis (default: alloy)
+}
+
+// NewTranscriber creates a Transcriber for cfg.Engine.
+func NewTranscriber(cfg Config) (Transcriber, error) {
+	switch strings.ToLower(cfg.Engine) {
+	case "whisper", "whisper.cpp":
+		return &Whisper{Binary: cfg.Binary, Model: cfg.Model, Language: cfg.Language}, nil
+	case "vosk":
+		return &Vosk{Binary: cfg.Binary, Model: cfg.Model}, nil
+	case "openai":
+		return &OpenAI{BaseURL: cfg.BaseURL, APIKey: cfg.APIKey, Model: cfg.Model, Language: cfg.Language}, nil
+	default:
+		return nil, fmt.Errorf("unknown transcription engine %q (want whisper, vosk or openai)", cfg.Engine)
+	}
+}
+
+// NewSynthesizer creates a Synthesizer for cfg.Engine.
+func NewSynthesizer(cfg Config) (Synthesizer, error) {
+	switch strings.ToLower(cfg.Engine) {
+	case "piper":
+		return &Piper{Binary: cfg.Binary, Model: cfg.Model}, nil
+	case "openai":
+		return &OpenAI{BaseURL: cfg.BaseURL, APIKey: cfg.APIKey, Model: cfg.Model, Voice: cfg.Voice}, nil
+	default:
+		return nil, fmt.Errorf("unknown synthesis engine %q (want piper or openai)", cfg.Engine)
+	}
+}
+
+// Convert re-encodes audio with ffmpeg. Formats ffmpeg knows by extension
+// work; "wav" gives 16 kHz mono PCM, which is what speech models expect, and
+// "ogg" gives Opus, which chat apps show as a voice note.
+func Convert(ctx context.Context, audio Audio, format string) (Audio, error) {
+	if audio.Format == format {
+		return audio, nil
+	}
+	dir, err := os.MkdirTemp("", "lingti-speech-")
+	if err != nil {
+		return Audio{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "in."+extension(audio.Format))
+	out := filepath.Join(dir, "out."+format)
+	if err := os.WriteFile(in, audio.Data, 0o600); err != nil {
+		return Audio{}, err
+	}
+
+	args := []string{"-hide_banner", "-loglevel", "error", "-y", "-i", in}
+	switch format {
+	case "wav":
+		args = append(args, "-ar", "16000", "-ac", "1", "-c:a", "pcm_s16le")
+	case "ogg":
+		args = append(args, "-c:a", "libopus")
+	case "amr":
+		args = append(args, "-ar", "8000", "-ac", "1", "-c:a", "libopencore_amrnb")
+	}
+	if _, err := run(ctx, "ffmpeg", nil, append(args, out)...); err != nil {
+		return Audio{}, err
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		return Audio{}, err
+	}
+	return Audio{Data: data, Format: format}, nil
+}
+
+// WriteTemp writes audio to a new temporary file named after its format and
+// returns the path. The caller removes the file.
+func WriteTemp(audio Audio) (string, error) {
+	f, err := os.CreateTemp("", "lingti-voice-*."+extension(audio.Format))
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(audio.Data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+// extension maps format names that are not file extensions to one that is.
+func extension(format string) string {
+	switch format {
+	case "", "opus":
+		return "ogg"
+	case "mpeg":
+		return "mp3"
+	}
+	return format
+}
+
+// run executes an engine binary and returns its standard output. Standard
+// error is included in the returned error, since that is where the engines
+// explain themselves.
+func run(ctx context.Context, name string, stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > 500 {
+			msg = msg[len(msg)-500:]
+		}
+		if msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,119 @@
+package speech
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestOpenAITranscribe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		if header.Filename != "voice.amr" || string(data) != "AMR" || r.FormValue("model") != "whisper-1" || r.FormValue("language") != "zh" {
+			http.Error(w, "bad form", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"text": " 你好 "})
+	}))
+	defer srv.Close()
+
+	o := &OpenAI{BaseURL: srv.URL + "/v1/", APIKey: "key", Language: "zh"}
+	text, err := o.Transcribe(context.Background(), Audio{Data: []byte("AMR"), Format: "amr"})
+	if err != nil || text != "你好" {
+		t.Errorf("Transcribe = %q, %v", text, err)
+	}
+}
+
+func TestOpenAISynthesize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/audio/speech" || req["input"] != "你好" || req["voice"] != "nova" || req["response_format"] != "opus" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("OggS"))
+	}))
+	defer srv.Close()
+
+	o := &OpenAI{BaseURL: srv.URL, Voice: "nova"}
+	audio, err := o.Synthesize(context.Background(), "你好")
+	if err != nil || string(audio.Data) != "OggS" || audio.Format != "ogg" {
+		t.Errorf("Synthesize = %+v, %v", audio, err)
+	}
+
+	o.BaseURL = srv.URL + "/broken"
+	if _, err := o.Synthesize(context.Background(), "你好"); err == nil {
+		t.Error("HTTP error not reported")
+	}
+}
+
+func TestWhisperRunsBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the engine")
+	}
+	// The fake engine prints its arguments, so the test sees how it was called
+	bin := filepath.Join(t.TempDir(), "whisper-cli")
+	script := "#!/bin/sh\nfor a in \"$@\"; do case \"$a\" in *.wav) ;; *) echo \"$a\";; esac; done\n"
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &Whisper{Binary: bin, Model: "ggml-base.bin"}
+	text, err := w.Transcribe(context.Background(), Audio{Data: []byte("RIFF"), Format: "wav"})
+	if want := "-m ggml-base.bin -f -l auto -nt -np"; err != nil || text != want {
+		t.Errorf("Transcribe = %q, %v; want %q", text, err, want)
+	}
+
+	w.Binary = filepath.Join(t.TempDir(), "missing")
+	if _, err := w.Transcribe(context.Background(), Audio{Data: []byte("RIFF"), Format: "wav"}); err == nil {
+		t.Error("missing binary not reported")
+	}
+}
+
+func TestNewTranscriber(t *testing.T) {
+	for _, engine := range []string{"whisper", "vosk", "openai"} {
+		if _, err := NewTranscriber(Config{Engine: engine}); err != nil {
+			t.Errorf("NewTranscriber(%q): %v", engine, err)
+		}
+	}
+	if _, err := NewTranscriber(Config{Engine: "piper"}); err == nil {
+		t.Error("piper accepted as a transcriber")
+	}
+	if _, err := NewSynthesizer(Config{Engine: "vosk"}); err == nil {
+		t.Error("vosk accepted as a synthesizer")
+	}
+}
+
+func TestReadAudioLimit(t *testing.T) {
+	data, err := ReadAudio(strings.NewReader("OggS"))
+	if err != nil || string(data) != "OggS" {
+		t.Errorf("ReadAudio = %q, %v", data, err)
+	}
+	if _, err := ReadAudio(io.LimitReader(zeros{}, MaxAudioSize+1)); err == nil {
+		t.Error("ReadAudio accepted audio over MaxAudioSize")
+	}
+}
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
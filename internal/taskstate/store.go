@@ -0,0 +1,223 @@
+// Package taskstate persists progress of long-running agent tasks — visited
+// URLs and named checkpoints — so they can resume after a restart.
+package taskstate
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Store is a SQLite-backed task state store. Every record belongs to a
+// scope (usually the conversation key) so tasks in different chats don't
+// see each other's progress.
+type Store struct {
+	db *sql.DB
+	mu sync.Mutex
+}
+
+// Checkpoint is the saved state of one task.
+type Checkpoint struct {
+	Task      string
+	Data      string
+	UpdatedAt time.Time
+}
+
+var (
+	defaultStore    *Store
+	defaultStoreErr error
+	defaultOnce     sync.Once
+)
+
+// Default returns the store in ~/.lingti.db, shared with the cron scheduler,
+// opening it on first use.
+func Default() (*Store, error) {
+	defaultOnce.Do(func() {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			defaultStoreErr = fmt.Errorf("failed to find home directory: %w", err)
+			return
+		}
+		defaultStore, defaultStoreErr = NewStore(filepath.Join(home, ".lingti.db"))
+	})
+	return defaultStore, defaultStoreErr
+}
+
+// NewStore opens (or creates) a task state store at the given path.
+func NewStore(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Other stores write to the same file; wait for their locks instead of
+	// failing with SQLITE_BUSY
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to set WAL mode: %w", err)
+	}
+
+	s := &Store{db: db}
+	if err := s.init(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	return s, nil
+}
+
+// init creates the tables if they don't exist
+func (s *Store) init() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS visited_urls (
+			scope      TEXT NOT NULL,
+			task       TEXT NOT NULL,
+			url        TEXT NOT NULL,
+			visited_at TEXT NOT NULL,
+			PRIMARY KEY (scope, task, url)
+		);
+		CREATE TABLE IF NOT EXISTS task_checkpoints (
+			scope      TEXT NOT NULL,
+			task       TEXT NOT NULL,
+			data       TEXT NOT NULL,
+			updated_at TEXT NOT NULL,
+			PRIMARY KEY (scope, task)
+		);
+	`)
+	return err
+}
+
+// MarkVisited records url as visited and returns how many URLs the task has
+// visited so far.
+func (s *Store) MarkVisited(scope, task, url string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`
+		INSERT INTO visited_urls (scope, task, url, visited_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(scope, task, url) DO NOTHING
+	`, scope, task, url, time.Now().Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	err = s.db.QueryRow("SELECT COUNT(*) FROM visited_urls WHERE scope = ? AND task = ?", scope, task).Scan(&count)
+	return count, err
+}
+
+// IsVisited reports whether url was marked visited for the task.
+func (s *Store) IsVisited(scope, task, url string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM visited_urls WHERE scope = ? AND task = ? AND url = ?", scope, task, url).Scan(&n)
+	return n > 0, err
+}
+
+// Visited lists the task's visited URLs, oldest first.
+func (s *Store) Visited(scope, task string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.Query("SELECT url FROM visited_urls WHERE scope = ? AND task = ? ORDER BY visited_at, rowid", scope, task)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query visited URLs: %w", err)
+	}
+	defer rows.Close()
+
+	var urls []string
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, err
+		}
+		urls = append(urls, u)
+	}
+	return urls, rows.Err()
+}
+
+// ClearVisited forgets the task's visited URLs.
+func (s *Store) ClearVisited(scope, task string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec("DELETE FROM visited_urls WHERE scope = ? AND task = ?", scope, task)
+	return err
+}
+
+// SaveCheckpoint stores data as the task's latest checkpoint.
+func (s *Store) SaveCheckpoint(scope, task, data string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`
+		INSERT INTO task_checkpoints (scope, task, data, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(scope, task) DO UPDATE SET data=excluded.data, updated_at=excluded.updated_at
+	`, scope, task, data, time.Now().Format(time.RFC3339))
+	return err
+}
+
+// LoadCheckpoint returns the task's latest checkpoint, or nil if none was saved.
+func (s *Store) LoadCheckpoint(scope, task string) (*Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var cp Checkpoint
+	var updatedAt string
+	err := s.db.QueryRow("SELECT task, data, updated_at FROM task_checkpoints WHERE scope = ? AND task = ?", scope, task).
+		Scan(&cp.Task, &cp.Data, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cp.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	return &cp, nil
+}
+
+// Checkpoints lists the scope's checkpoints, most recently updated first.
+func (s *Store) Checkpoints(scope string) ([]Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.Query("SELECT task, data, updated_at FROM task_checkpoints WHERE scope = ? ORDER BY updated_at DESC", scope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var cps []Checkpoint
+	for rows.Next() {
+		var cp Checkpoint
+		var updatedAt string
+		if err := rows.Scan(&cp.Task, &cp.Data, &updatedAt); err != nil {
+			return nil, err
+		}
+		cp.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+		cps = append(cps, cp)
+	}
+	return cps, rows.Err()
+}
+
+// DeleteCheckpoint removes the task's checkpoint.
+func (s *Store) DeleteCheckpoint(scope, task string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec("DELETE FROM task_checkpoints WHERE scope = ? AND task = ?", scope, task)
+	return err
+}
+
+// Close closes the database connection
+func (s *Store) Close() error {
+	return s.db.Close()
+}
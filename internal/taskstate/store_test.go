@@ -0,0 +1,74 @@
+package taskstate
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func openTestStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	return store, path
+}
+
+func TestStore_VisitedSurvivesReopen(t *testing.T) {
+	store, path := openTestStore(t)
+	store.MarkVisited("slack:C1", "comments", "https://a.example/1")
+	if n, _ := store.MarkVisited("slack:C1", "comments", "https://a.example/2"); n != 2 {
+		t.Errorf("count = %d, want 2", n)
+	}
+	if n, _ := store.MarkVisited("slack:C1", "comments", "https://a.example/2"); n != 2 {
+		t.Errorf("marking twice changed the count to %d", n)
+	}
+	store.Close()
+
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer store.Close()
+
+	if ok, _ := store.IsVisited("slack:C1", "comments", "https://a.example/1"); !ok {
+		t.Error("visited URL lost after reopen")
+	}
+	if ok, _ := store.IsVisited("slack:C2", "comments", "https://a.example/1"); ok {
+		t.Error("visited URL leaked into another scope")
+	}
+	urls, _ := store.Visited("slack:C1", "comments")
+	if len(urls) != 2 || urls[0] != "https://a.example/1" {
+		t.Errorf("Visited = %v", urls)
+	}
+
+	store.ClearVisited("slack:C1", "comments")
+	if urls, _ := store.Visited("slack:C1", "comments"); len(urls) != 0 {
+		t.Errorf("after clear: %v", urls)
+	}
+}
+
+func TestStore_Checkpoints(t *testing.T) {
+	store, _ := openTestStore(t)
+	defer store.Close()
+
+	if cp, err := store.LoadCheckpoint("s", "job"); cp != nil || err != nil {
+		t.Fatalf("LoadCheckpoint on empty store = %v, %v", cp, err)
+	}
+
+	store.SaveCheckpoint("s", "job", `{"done":3}`)
+	store.SaveCheckpoint("s", "job", `{"done":4}`)
+	cp, err := store.LoadCheckpoint("s", "job")
+	if err != nil || cp == nil || cp.Data != `{"done":4}` {
+		t.Fatalf("LoadCheckpoint = %+v, %v", cp, err)
+	}
+	if cps, _ := store.Checkpoints("s"); len(cps) != 1 {
+		t.Errorf("Checkpoints = %+v", cps)
+	}
+
+	store.DeleteCheckpoint("s", "job")
+	if cp, _ := store.LoadCheckpoint("s", "job"); cp != nil {
+		t.Errorf("checkpoint not deleted: %+v", cp)
+	}
+}
@@ -7,17 +7,19 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/go-rod/rod"
 	"github.com/go-rod/rod/lib/proto"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/pltanton/lingti-bot/internal/browser"
 	"github.com/pltanton/lingti-bot/internal/logger"
+	"github.com/pltanton/lingti-bot/internal/taskstate"
 )
 
-// BrowserStart launches a browser instance or connects to an existing Chrome.
-func BrowserStart(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+// BrowserStart launches a browser instance or connects to an existing Chrome,
+// optionally switching the calling conversation to a login profile.
+func BrowserStart(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	opts := browser.StartOptions{
 		Headless: false,
 	}
@@ -52,6 +54,13 @@ func BrowserStart(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResu
 		return mcp.NewToolResultError(fmt.Sprintf("failed to start browser: %v", startErr)), nil
 	}
 
+	profile, _ := req.Params.Arguments["profile"].(string)
+	if profile != "" {
+		if err := b.UseProfile(ctx, profile); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to use profile: %v", err)), nil
+		}
+	}
+
 	var msg string
 	if opts.ConnectURL != "" {
 		msg = fmt.Sprintf("Connected to existing Chrome at %s", opts.ConnectURL)
@@ -61,13 +70,25 @@ func BrowserStart(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResu
 	if opts.URL != "" {
 		msg += fmt.Sprintf(", navigated to %s", opts.URL)
 	}
+	if profile != "" {
+		msg += fmt.Sprintf(". This chat now uses login profile %q", profile)
+	}
 	logger.Debug("[browser_start] %s", msg)
 	return mcp.NewToolResultText(msg), nil
 }
 
-// BrowserStop closes the browser or disconnects from external Chrome.
-func BrowserStop(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+// BrowserStop closes the browser or disconnects from external Chrome. Called
+// from a chat it only closes that conversation's session, since the browser
+// is shared with every other conversation.
+func BrowserStop(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	b := browser.Instance()
+	if browser.InConversation(ctx) {
+		if !b.CloseSession(ctx) {
+			return mcp.NewToolResultText("This chat has no open browser session"), nil
+		}
+		logger.Debug("[browser_stop] closed the conversation's session")
+		return mcp.NewToolResultText("Closed this chat's browser session (the browser keeps running for other chats)"), nil
+	}
 	wasConnected := b.IsConnected()
 	logger.Debug("[browser_stop] connected=%v", wasConnected)
 	if err := b.Stop(); err != nil {
@@ -90,38 +111,49 @@ func BrowserStatus(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResul
 }
 
 // BrowserNavigate navigates to a URL.
-func BrowserNavigate(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func BrowserNavigate(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	url, ok := req.Params.Arguments["url"].(string)
 	if !ok || url == "" {
 		return mcp.NewToolResultError("url is required"), nil
 	}
 
 	logger.Debug("[browser_navigate] url=%q", url)
+	if err := browser.CheckNavigation(browser.LoadRecipes(), url); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
 	b := browser.Instance()
 	if err := b.EnsureRunning(); err != nil {
 		logger.Debug("[browser_navigate] EnsureRunning failed: %v", err)
 		return mcp.NewToolResultError(fmt.Sprintf("failed to start browser: %v", err)), nil
 	}
 
-	page, err := b.NavigationPage()
+	s, err := b.SessionFor(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get browser session: %v", err)), nil
+	}
+	page, err := s.NavigationPage()
 	if err != nil {
 		logger.Debug("[browser_navigate] NavigationPage failed: %v", err)
 		return mcp.NewToolResultError(fmt.Sprintf("failed to get page: %v", err)), nil
 	}
 
+	// Operations run on a copy bound to ctx so cancelling the agent turn
+	// aborts them; the unbound page is what gets recorded as current.
 	logger.Debug("[browser_navigate] navigating...")
-	if err := page.Navigate(url); err != nil {
+	if err := page.Context(ctx).Navigate(url); err != nil {
 		logger.Debug("[browser_navigate] Navigate failed: %v", err)
 		return mcp.NewToolResultError(fmt.Sprintf("failed to navigate: %v", err)), nil
 	}
 
 	logger.Debug("[browser_navigate] waiting for page load...")
 	// WaitLoad may return "navigated or closed" on redirects — non-fatal.
-	_ = page.WaitLoad()
+	_ = page.Context(ctx).WaitLoad()
 
 	// Record this as the bot's current working page so snapshot/click/type
 	// all operate on this tab rather than opening new ones.
-	b.SetCurrentPage(page)
+	s.SetCurrentPage(page)
+	s.Record(browser.MacroStep{Action: "navigate", URL: url})
 
 	info, err := page.Info()
 	if err != nil {
@@ -132,34 +164,84 @@ func BrowserNavigate(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolR
 	return mcp.NewToolResultText(fmt.Sprintf("Navigated to %s (title: %s)", info.URL, info.Title)), nil
 }
 
-// BrowserSnapshot captures the accessibility tree with numbered refs.
-func BrowserSnapshot(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	logger.Debug("[browser_snapshot] capturing accessibility tree...")
+// BrowserSnapshot captures the accessibility tree with numbered refs. It can
+// return only what changed since the previous snapshot (mode "diff"), and be
+// scoped to a subtree or to on-screen or interactive elements.
+func BrowserSnapshot(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	mode, _ := req.Params.Arguments["mode"].(string)
+	if mode == "" {
+		mode = "full"
+	}
+	if mode != "full" && mode != "diff" {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown mode %q (use full or diff)", mode)), nil
+	}
+	opts := browser.SnapshotOptions{}
+	opts.InteractiveOnly, _ = req.Params.Arguments["interactive_only"].(bool)
+	opts.ViewportOnly, _ = req.Params.Arguments["viewport_only"].(bool)
+	scopeRef, hasScopeRef := req.Params.Arguments["scope_ref"].(float64)
+	scopeSelector, _ := req.Params.Arguments["scope_selector"].(string)
+	if hasScopeRef && scopeSelector != "" {
+		return mcp.NewToolResultError("use either scope_ref or scope_selector, not both"), nil
+	}
+
+	logger.Debug("[browser_snapshot] capturing accessibility tree (mode=%s)...", mode)
 	b := browser.Instance()
 	if err := b.EnsureRunning(); err != nil {
 		logger.Debug("[browser_snapshot] EnsureRunning failed: %v", err)
 		return mcp.NewToolResultError(fmt.Sprintf("failed to start browser: %v", err)), nil
 	}
 
-	page, err := b.ActivePage()
+	s, err := b.SessionFor(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get browser session: %v", err)), nil
+	}
+	page, err := s.ActivePage()
 	if err != nil {
 		logger.Debug("[browser_snapshot] ActivePage failed: %v", err)
 		return mcp.NewToolResultError(fmt.Sprintf("failed to get page: %v", err)), nil
 	}
+	page = page.Context(ctx)
+
+	scope := ""
+	switch {
+	case hasScopeRef:
+		entry, ok := s.GetRef(int(scopeRef))
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("scope_ref %d not found in snapshot", int(scopeRef))), nil
+		}
+		opts.Root = entry.BackendDOMNodeID
+		scope = fmt.Sprintf("[%d] %s %q", int(scopeRef), entry.Role, entry.Name)
+	case scopeSelector != "":
+		els, err := page.Elements(scopeSelector)
+		if err != nil || len(els) == 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("no element matches scope_selector %q", scopeSelector)), nil
+		}
+		node, err := els.First().Describe(0, false)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to resolve scope_selector: %v", err)), nil
+		}
+		opts.Root = node.BackendNodeID
+		scope = scopeSelector
+	}
 
-	snapshot, refs, err := browser.Snapshot(page)
+	snap, err := s.Snapshot(page, opts)
 	if err != nil {
 		logger.Debug("[browser_snapshot] Snapshot failed: %v", err)
 		return mcp.NewToolResultError(fmt.Sprintf("failed to capture snapshot: %v", err)), nil
 	}
 
-	logger.Debug("[browser_snapshot] captured %d refs", len(refs))
-	b.SetRefs(refs)
+	logger.Debug("[browser_snapshot] captured %d refs", snap.Refs())
 
 	info, _ := page.Info()
 	header := ""
 	if info != nil {
-		header = fmt.Sprintf("URL: %s\nTitle: %s\nRefs: %d\n", info.URL, info.Title, len(refs))
+		header = fmt.Sprintf("URL: %s\nTitle: %s\nRefs: %d\n", info.URL, info.Title, snap.Refs())
+	}
+	if scope != "" {
+		header += fmt.Sprintf("Scope: %s\n", scope)
+	}
+	if filters := snapshotFilters(opts); filters != "" {
+		header += fmt.Sprintf("Showing: %s\n", filters)
 	}
 
 	// Save debug screenshot if debug mode is enabled
@@ -179,21 +261,58 @@ func BrowserSnapshot(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolRes
 	}
 
 	header += "\n"
-	return mcp.NewToolResultText(header + snapshot), nil
+	if mode == "diff" {
+		if snap.HasPrevious {
+			return mcp.NewToolResultText(header + snap.Diff()), nil
+		}
+		header += "No earlier snapshot of this page with the same scope; showing the full snapshot.\n\n"
+	}
+	return mcp.NewToolResultText(header + snap.String()), nil
+}
+
+// snapshotFilters describes the element filters of a snapshot for its header.
+func snapshotFilters(opts browser.SnapshotOptions) string {
+	var filters []string
+	if opts.InteractiveOnly {
+		filters = append(filters, "interactive elements only")
+	}
+	if opts.ViewportOnly {
+		filters = append(filters, "visible in viewport only")
+	}
+	return strings.Join(filters, ", ")
+}
+
+// snapshotForError returns the page state to show after a failed action:
+// the changes since the last full snapshot if there was one, else the full
+// snapshot. Refs stay valid across snapshots of the same page.
+func snapshotForError(s *browser.Session, page *rod.Page) (string, bool) {
+	snap, err := s.Snapshot(page, browser.SnapshotOptions{})
+	if err != nil {
+		return "", false
+	}
+	if snap.HasPrevious {
+		return snap.Diff(), true
+	}
+	return snap.String(), true
 }
 
 // BrowserScreenshot captures a screenshot of the current page.
-func BrowserScreenshot(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func BrowserScreenshot(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	logger.Debug("[browser_screenshot] capturing...")
 	b := browser.Instance()
 	if err := b.EnsureRunning(); err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to start browser: %v", err)), nil
 	}
 
-	page, err := b.ActivePage()
+	s, err := b.SessionFor(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get browser session: %v", err)), nil
+	}
+	page, err := s.ActivePage()
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to get page: %v", err)), nil
 	}
+	page = page.Context(ctx)
 
 	fullPage := false
 	if fp, ok := req.Params.Arguments["full_page"].(bool); ok {
@@ -258,7 +377,7 @@ func truncateSnapshot(snapshot string, maxLines int) string {
 }
 
 // BrowserClick clicks an element by ref number.
-func BrowserClick(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func BrowserClick(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	ref, ok := req.Params.Arguments["ref"].(float64)
 	if !ok {
 		return mcp.NewToolResultError("ref is required (number)"), nil
@@ -266,31 +385,39 @@ func BrowserClick(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResu
 
 	logger.Debug("[browser_click] ref=%d", int(ref))
 	b := browser.Instance()
-	page, err := b.ActivePage()
+	s, err := b.SessionFor(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get browser session: %v", err)), nil
+	}
+	page, err := s.ActivePage()
 	if err != nil {
 		logger.Debug("[browser_click] ActivePage failed: %v", err)
 		return mcp.NewToolResultError(fmt.Sprintf("failed to get page: %v", err)), nil
 	}
+	page = page.Context(ctx)
 
 	// Record tab count before the click so we can detect if a new tab opens.
-	tabsBefore := b.PageCount()
+	tabsBefore := s.PageCount()
+	// Locate before clicking, since the click may remove the element
+	target := s.Locate(page, int(ref))
 
 	// Try to click the element
-	if err := browser.Click(page, b, int(ref)); err != nil {
+	if err := browser.Click(page, s, int(ref)); err != nil {
 		logger.Debug("[browser_click] Click failed: %v", err)
 		// Check if this is a "ref not found" error - might need fresh snapshot
 		errStr := err.Error()
 		if containsString(errStr, "ref") && containsString(errStr, "not found") {
 			logger.Debug("[browser_click] ref not found, auto-refreshing snapshot...")
 			// Try automatic retry with fresh snapshot
-			_, newRefs, snapErr := browser.Snapshot(page)
+			snap, snapErr := s.Snapshot(page, browser.SnapshotOptions{})
 			if snapErr == nil {
-				b.SetRefs(newRefs)
-				logger.Debug("[browser_click] retrying click with %d new refs", len(newRefs))
+				logger.Debug("[browser_click] retrying click with %d refs", snap.Refs())
 
 				// Retry the click with updated refs
-				if retryErr := browser.Click(page, b, int(ref)); retryErr == nil {
-					entry, _ := b.GetRef(int(ref))
+				target = s.Locate(page, int(ref))
+				if retryErr := browser.Click(page, s, int(ref)); retryErr == nil {
+					s.Record(browser.MacroStep{Action: "click", Target: target})
+					entry, _ := s.GetRef(int(ref))
 					logger.Debug("[browser_click] retry succeeded: [%d] %s %q", int(ref), entry.Role, entry.Name)
 					return mcp.NewToolResultText(fmt.Sprintf("Clicked [%d] %s %q (after auto-refresh)", int(ref), entry.Role, entry.Name)), nil
 				}
@@ -299,11 +426,9 @@ func BrowserClick(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResu
 
 		// If retry failed or not applicable, capture fresh snapshot for AI to see current state
 		logger.Debug("[browser_click] capturing snapshot for error context")
-		snapshot, newRefs, snapErr := browser.Snapshot(page)
-		if snapErr == nil {
-			b.SetRefs(newRefs)
+		if snapshot, ok := snapshotForError(s, page); ok {
 			return mcp.NewToolResultError(fmt.Sprintf(
-				"Failed to click ref %d: %v\n\nCurrent page snapshot (refs may have changed):\n%s",
+				"Failed to click ref %d: %v\n\nCurrent page:\n%s",
 				int(ref), err, snapshot,
 			)), nil
 		}
@@ -311,33 +436,34 @@ func BrowserClick(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResu
 		return mcp.NewToolResultError(fmt.Sprintf("failed to click ref %d: %v", int(ref), err)), nil
 	}
 
-	entry, _ := b.GetRef(int(ref))
+	s.Record(browser.MacroStep{Action: "click", Target: target})
+	entry, _ := s.GetRef(int(ref))
 	logger.Debug("[browser_click] clicked [%d] %s %q", int(ref), entry.Role, entry.Name)
 	clickMsg := fmt.Sprintf("Clicked [%d] %s %q", int(ref), entry.Role, entry.Name)
 
 	// Wait a moment for any new tab to open, then detect and switch to it.
 	time.Sleep(500 * time.Millisecond)
-	if switched := b.SwitchToNewestPage(tabsBefore); switched {
+	if switched := s.SwitchToNewestPage(tabsBefore); switched {
 		logger.Debug("[browser_click] new tab detected, switched currentPage")
 		clickMsg += "\n\n⚠ A new tab was opened by this click. Bot is now tracking the new tab."
 	}
 
 	// Get page URL/title from the (possibly new) active page.
-	activePage, _ := b.ActivePage()
+	activePage, _ := s.ActivePage()
 	if activePage != nil {
 		info, _ := activePage.Info()
 		if info != nil {
 			clickMsg += fmt.Sprintf("\n\nNow on: %s\nTitle: %s", info.URL, info.Title)
 		}
 	}
-	clickMsg += "\n\nCall browser_snapshot to see the current page elements and continue with the next action."
+	clickMsg += "\n\nCall browser_snapshot (mode=\"diff\" to see only what changed) and continue with the next action."
 
 	logger.Debug("[browser_click] done, instructing model to snapshot")
 	return mcp.NewToolResultText(clickMsg), nil
 }
 
 // BrowserType types text into an element by ref number.
-func BrowserType(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func BrowserType(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	ref, ok := req.Params.Arguments["ref"].(float64)
 	if !ok {
 		return mcp.NewToolResultError("ref is required (number)"), nil
@@ -354,27 +480,35 @@ func BrowserType(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResul
 
 	logger.Debug("[browser_type] ref=%d text=%q submit=%v", int(ref), text, submit)
 	b := browser.Instance()
-	page, err := b.ActivePage()
+	s, err := b.SessionFor(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get browser session: %v", err)), nil
+	}
+	page, err := s.ActivePage()
 	if err != nil {
 		logger.Debug("[browser_type] ActivePage failed: %v", err)
 		return mcp.NewToolResultError(fmt.Sprintf("failed to get page: %v", err)), nil
 	}
+	page = page.Context(ctx)
+
+	target := s.Locate(page, int(ref))
 
 	// Try to type into the element
-	if err := browser.Type(page, b, int(ref), text, submit); err != nil {
+	if err := browser.Type(page, s, int(ref), text, submit); err != nil {
 		logger.Debug("[browser_type] Type failed: %v", err)
 		// Check if this is a "ref not found" error - might need fresh snapshot
 		errStr := err.Error()
 		if containsString(errStr, "ref") && containsString(errStr, "not found") {
 			logger.Debug("[browser_type] ref not found, auto-refreshing snapshot...")
 			// Try automatic retry with fresh snapshot
-			_, newRefs, snapErr := browser.Snapshot(page)
+			snap, snapErr := s.Snapshot(page, browser.SnapshotOptions{})
 			if snapErr == nil {
-				b.SetRefs(newRefs)
-				logger.Debug("[browser_type] retrying with %d new refs", len(newRefs))
+				logger.Debug("[browser_type] retrying with %d refs", snap.Refs())
 
 				// Retry the type with updated refs
-				if retryErr := browser.Type(page, b, int(ref), text, submit); retryErr == nil {
+				target = s.Locate(page, int(ref))
+				if retryErr := browser.Type(page, s, int(ref), text, submit); retryErr == nil {
+					s.Record(browser.MacroStep{Action: "type", Target: target, Text: text, Submit: submit})
 					msg := fmt.Sprintf("Typed %q into [%d] (after auto-refresh)", text, int(ref))
 					if submit {
 						msg += " and pressed Enter"
@@ -387,11 +521,9 @@ func BrowserType(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResul
 
 		// If retry failed or not applicable, capture fresh snapshot for AI to see current state
 		logger.Debug("[browser_type] capturing snapshot for error context")
-		snapshot, newRefs, snapErr := browser.Snapshot(page)
-		if snapErr == nil {
-			b.SetRefs(newRefs)
+		if snapshot, ok := snapshotForError(s, page); ok {
 			return mcp.NewToolResultError(fmt.Sprintf(
-				"Failed to type into ref %d: %v\n\nCurrent page snapshot (refs may have changed):\n%s",
+				"Failed to type into ref %d: %v\n\nCurrent page:\n%s",
 				int(ref), err, snapshot,
 			)), nil
 		}
@@ -399,6 +531,7 @@ func BrowserType(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResul
 		return mcp.NewToolResultError(fmt.Sprintf("failed to type into ref %d: %v", int(ref), err)), nil
 	}
 
+	s.Record(browser.MacroStep{Action: "type", Target: target, Text: text, Submit: submit})
 	typeMsg := fmt.Sprintf("Typed %q into [%d]", text, int(ref))
 	if submit {
 		typeMsg += " and pressed Enter"
@@ -420,17 +553,22 @@ func BrowserType(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResul
 }
 
 // BrowserPress presses a keyboard key.
-func BrowserPress(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func BrowserPress(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	key, ok := req.Params.Arguments["key"].(string)
 	if !ok || key == "" {
 		return mcp.NewToolResultError("key is required (e.g., Enter, Tab, Escape)"), nil
 	}
 
 	b := browser.Instance()
-	page, err := b.ActivePage()
+	s, err := b.SessionFor(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get browser session: %v", err)), nil
+	}
+	page, err := s.ActivePage()
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to get page: %v", err)), nil
 	}
+	page = page.Context(ctx)
 
 	logger.Debug("[browser_press] key=%q", key)
 	if err := browser.Press(page, key); err != nil {
@@ -438,372 +576,74 @@ func BrowserPress(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResu
 		return mcp.NewToolResultError(fmt.Sprintf("failed to press key: %v", err)), nil
 	}
 
+	s.Record(browser.MacroStep{Action: "press", Key: key})
 	logger.Debug("[browser_press] pressed %s", key)
 	return mcp.NewToolResultText(fmt.Sprintf("Pressed %s", key)), nil
 }
 
-// BrowserCommentZhihu posts a comment on a Zhihu answer using the verified JS recipe.
-// It expands the first answer's comment section, types the comment, and submits.
-// Optional reply_to param: username to reply to (nested reply) instead of posting a top-level comment.
-func BrowserCommentZhihu(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	comment, ok := req.Params.Arguments["comment"].(string)
-	if !ok || comment == "" {
-		return mcp.NewToolResultError("comment is required"), nil
+// BrowserSiteAction runs a named action from the site recipe matching the
+// current page (see browser.LoadRecipes).
+func BrowserSiteAction(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	action, ok := req.Params.Arguments["action"].(string)
+	if !ok || action == "" {
+		return mcp.NewToolResultError("action is required"), nil
 	}
-	replyTo, _ := req.Params.Arguments["reply_to"].(string)
+	site, _ := req.Params.Arguments["site"].(string)
+	args, _ := req.Params.Arguments["args"].(map[string]any)
 
 	b := browser.Instance()
-	page, err := b.ActivePage()
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to get page: %v", err)), nil
-	}
-
-	var r1 string
-
-	if replyTo != "" {
-		// Nested reply mode: find the 回复 button next to the specified user's comment and click it.
-		jsonReplyTo, _ := json.Marshal(replyTo)
-		r1, err = browser.ExecuteJS(page, fmt.Sprintf(`
-			var username = %s;
-			// Find links whose text exactly matches the username (avatar + name links both match)
-			var userEls = Array.from(document.querySelectorAll('a')).filter(function(a) {
-				return a.textContent.trim() === username;
-			});
-			if (!userEls.length) { return 'user not found: ' + username; }
-
-			// Collect all 回复 buttons on the page
-			var replyBtns = Array.from(document.querySelectorAll('button')).filter(function(b) {
-				return b.textContent.replace(/\u200b/g,'').trim() === '回复';
-			});
-			if (!replyBtns.length) { return 'no reply buttons found'; }
-
-			// Find the first 回复 button that follows the first username element in DOM order
-			var userEl = userEls[0];
-			var found = null;
-			for (var i = 0; i < replyBtns.length; i++) {
-				if (userEl.compareDocumentPosition(replyBtns[i]) & Node.DOCUMENT_POSITION_FOLLOWING) {
-					found = replyBtns[i];
-					break;
-				}
-			}
-			if (found) { found.click(); return 'clicked reply for: ' + username; }
-			return 'reply button not found after: ' + username;
-		`, string(jsonReplyTo)))
-		logger.Debug("[browser_comment_zhihu] reply_to step1: %s", r1)
-		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("reply_to step failed: %v", err)), nil
-		}
-		if !strings.HasPrefix(r1, "clicked") {
-			return mcp.NewToolResultError(r1), nil
-		}
-	} else {
-		// Top-level comment mode: open the article/answer comment editor.
-		// Step 1a: if editor is already open, skip opening it.
-		// Otherwise click "添加评论" directly if visible, else click "X条评论" to expand
-		// the comment section first, then click "添加评论" inside it.
-		r1, err = browser.ExecuteJS(page, `
-			// Already open?
-			if (document.querySelector('.public-DraftEditor-content')) { return 'editor already open'; }
-
-			// Prefer clicking "添加评论" directly (zhuanlan pages show this right away)
-			var addBtn = Array.from(document.querySelectorAll('button,span,a')).find(function(e) {
-				return e.textContent.replace(/\u200b/g,'').trim() === '添加评论';
-			});
-			if (addBtn) { addBtn.click(); return 'clicked 添加评论'; }
-
-			// On question pages "X条评论" toggles the list; "添加评论" appears inside.
-			// Click the first answer's comment toggle to expand it.
-			// Exclude "收起评论" (collapse) — we must not collapse an already-open section.
-			var toggleBtn = Array.from(document.querySelectorAll('button,span')).find(function(e) {
-				var t = e.textContent.replace(/\u200b/g,'').trim();
-				if (t.indexOf('收起') !== -1) return false;
-				return /^[\d]+\s*条评论$/.test(t) || t === '评论';
-			});
-			if (toggleBtn) { toggleBtn.click(); return 'expanded: ' + toggleBtn.textContent.trim(); }
-
-			return 'no comment button found';
-		`)
-		logger.Debug("[browser_comment_zhihu] step1a: %s", r1)
-		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("step1a failed: %v", err)), nil
-		}
-
-		// Step 1b: if we expanded comments (not directly clicked 添加评论), now find and
-		// click the "添加评论" input placeholder that appears inside the expanded section.
-		// Poll up to 4s for it to appear.
-		// IMPORTANT: textContent is recursive, so querying 'div' would match any ancestor
-		// that contains "添加评论" in its subtree. We restrict to elements that either:
-		//   (a) have no child elements (leaf nodes), or
-		//   (b) are known interactive types (button, span, a)
-		//   (c) are the DraftEditor placeholder div specifically
-		if r1 != "editor already open" && r1 != "clicked 添加评论" {
-			var addClicked bool
-			for range 20 {
-				time.Sleep(200 * time.Millisecond)
-				res, _ := browser.ExecuteJS(page, `
-					// Already open after expand?
-					if (document.querySelector('.public-DraftEditor-content')) { return 'editor appeared'; }
-
-					// Specific known selectors for the comment input area on Zhihu question pages
-					var specific = document.querySelector(
-						'.CommentInput, [class*="CommentInput"], ' +
-						'.DraftEditor-root, [class*="comment-input"], ' +
-						'[placeholder="添加评论"], [data-placeholder="添加评论"]'
-					);
-					if (specific) { specific.click(); return 'clicked specific'; }
-
-					// button/span/a with exact text — safe (not recursive parent match)
-					var btn = Array.from(document.querySelectorAll('button,span,a')).find(function(e) {
-						return e.textContent.replace(/\u200b/g,'').trim() === '添加评论';
-					});
-					if (btn) { btn.click(); return 'clicked btn: ' + btn.tagName; }
-
-					// Leaf div/label with exact text (no child elements that also contain the text)
-					var leaf = Array.from(document.querySelectorAll('div,label')).find(function(e) {
-						if (e.textContent.replace(/\u200b/g,'').trim() !== '添加评论') return false;
-						// Make sure no child element also has this text (i.e. we are the real target)
-						return !Array.from(e.children).some(function(c) {
-							return c.textContent.replace(/\u200b/g,'').trim() === '添加评论';
-						});
-					});
-					if (leaf) { leaf.click(); return 'clicked leaf: ' + leaf.className.slice(0,40); }
-
-					return 'waiting';
-				`)
-				if res != "waiting" {
-					addClicked = true
-					break
-				}
-			}
-			if !addClicked {
-				return mcp.NewToolResultError(fmt.Sprintf("could not find 添加评论 after expanding comments (step1=%s)", r1)), nil
-			}
-		}
-	}
-
-	// Step 1c: wait for an editor to appear (poll up to 4s).
-	// Zhihu article top-level comments use Draft.js (.public-DraftEditor-content).
-	// Nested replies use a plain textarea/contenteditable.
-	var editorType string
-	if r1 == "editor already open" {
-		editorType = "draftjs"
-	} else {
-		for range 20 {
-			time.Sleep(200 * time.Millisecond)
-			editorType, _ = browser.ExecuteJS(page, `
-				if (document.querySelector('.public-DraftEditor-content')) { return 'draftjs'; }
-				var el = document.activeElement;
-				if (el && (el.tagName === 'TEXTAREA' || el.contentEditable === 'true' || el.getAttribute('role') === 'textbox')) {
-					return 'plain:' + el.tagName;
-				}
-				var ta = document.querySelector('textarea');
-				if (ta) { return 'plain:TEXTAREA'; }
-				return 'waiting';
-			`)
-			if editorType != "waiting" {
-				break
-			}
-		}
+	if err := b.EnsureRunning(); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to start browser: %v", err)), nil
 	}
-	if editorType == "waiting" || editorType == "" {
-		return mcp.NewToolResultError(fmt.Sprintf("editor did not appear (step1=%s)", r1)), nil
-	}
-
-	// Step 2: insert text via ClipboardEvent paste.
-	// For Draft.js: targets .public-DraftEditor-content
-	// For plain textarea/contenteditable: targets the active element or first textarea.
-	// ClipboardEvent paste works for both Draft.js and React-controlled plain editors;
-	// execCommand('insertText') is used as fallback for plain editors.
-	jsonComment, _ := json.Marshal(comment)
-	var pasteScript string
-	if editorType == "draftjs" {
-		pasteScript = fmt.Sprintf(`
-			var ed = document.querySelector('.public-DraftEditor-content');
-			if (!ed) { return 'editor not found'; }
-			ed.click(); ed.focus();
-			document.execCommand('selectAll', false);
-			var dt = new DataTransfer();
-			dt.setData('text/plain', %s);
-			ed.dispatchEvent(new ClipboardEvent('paste', { clipboardData: dt, bubbles: true, cancelable: true }));
-			return 'pasted-draftjs';
-		`, string(jsonComment))
-	} else {
-		pasteScript = fmt.Sprintf(`
-			var ed = document.activeElement;
-			if (!ed || (ed.tagName !== 'TEXTAREA' && ed.contentEditable !== 'true' && ed.getAttribute('role') !== 'textbox')) {
-				ed = document.querySelector('textarea') || document.querySelector('[contenteditable="true"]');
-			}
-			if (!ed) { return 'editor not found'; }
-			ed.focus();
-			var dt = new DataTransfer();
-			dt.setData('text/plain', %s);
-			var pasted = ed.dispatchEvent(new ClipboardEvent('paste', { clipboardData: dt, bubbles: true, cancelable: true }));
-			// Fallback: execCommand for plain contenteditable
-			if (ed.tagName === 'TEXTAREA' || ed.contentEditable === 'true') {
-				document.execCommand('insertText', false, %s);
-			}
-			return 'pasted-plain';
-		`, string(jsonComment), string(jsonComment))
-	}
-	r2, err := browser.ExecuteJS(page, pasteScript)
-	logger.Debug("[browser_comment_zhihu] step2 paste: %s (editorType=%s)", r2, editorType)
-	if err != nil || r2 == "editor not found" {
-		return mcp.NewToolResultError(fmt.Sprintf("step2 paste failed: %v %s", err, r2)), nil
-	}
-
-	// Wait for the editor framework to process the paste and re-render
-	time.Sleep(600 * time.Millisecond)
-
-	// Step 3: click the 发布 submit button.
-	// IMPORTANT: document.querySelector('button.Button--primary') matches the search bar button first.
-	// Must find specifically the button with text "发布".
-	r3, err := browser.ExecuteJS(page, `
-		var btn = Array.from(document.querySelectorAll('button')).find(function(b){ return b.textContent.replace(/\u200b/g,'').trim() === '发布'; });
-		if (btn && !btn.disabled) { btn.click(); return 'submitted'; }
-		if (btn && btn.disabled) { return 'submit button is disabled (comment may be empty)'; }
-		return 'submit button not found';
-	`)
-	logger.Debug("[browser_comment_zhihu] step3 submit: %s", r3)
+	s, err := b.SessionFor(ctx)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("step3 submit failed: %v", err)), nil
-	}
-
-	if r3 == "submitted" {
-		if replyTo != "" {
-			return mcp.NewToolResultText(fmt.Sprintf("Reply to %q posted successfully: %q", replyTo, comment)), nil
-		}
-		return mcp.NewToolResultText(fmt.Sprintf("Comment posted successfully: %q", comment)), nil
-	}
-	return mcp.NewToolResultError(fmt.Sprintf("comment may not have submitted: %s (expand=%s, paste=%s)", r3, r1, r2)), nil
-}
-
-// BrowserCommentXiaohongshu posts a comment on a Xiaohongshu note using the verified JS recipe.
-// The note detail page must already be open. This tool handles the full flow:
-// 1. Click "说点什么..." or "评论" to activate the comment editor
-// 2. Paste text via ClipboardEvent (the only method that enables the 发送 button)
-// 3. Click 发送 to submit
-func BrowserCommentXiaohongshu(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	comment, ok := req.Params.Arguments["comment"].(string)
-	if !ok || comment == "" {
-		return mcp.NewToolResultError("comment is required"), nil
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get browser session: %v", err)), nil
 	}
-
-	b := browser.Instance()
-	page, err := b.ActivePage()
+	page, err := s.ActivePage()
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to get page: %v", err)), nil
 	}
+	page = page.Context(ctx)
 
-	// Step 1: activate the comment editor.
-	// The editor may not be focused/visible yet. Click "说点什么..." placeholder,
-	// "评论" button, or the editor itself to activate it.
-	r1, err := browser.ExecuteJS(page, `
-		// Already have editor?
-		var ed = document.querySelector('#content-textarea');
-		if (ed && ed.textContent.trim() !== '' && ed === document.activeElement) {
-			return 'editor already active';
-		}
-
-		// Try clicking "说点什么..." placeholder (the comment input area at the bottom)
-		var placeholder = document.querySelector('.comment-input, .input-box, [class*="comment-input"]');
-		if (placeholder) { placeholder.click(); return 'clicked comment input area'; }
-
-		// Try clicking "评论" button in the bottom action bar
-		var commentBtn = Array.from(document.querySelectorAll('span, button, div')).find(function(e) {
-			var t = e.textContent.trim();
-			return t === '评论' && e.children.length <= 2;
-		});
-		if (commentBtn) { commentBtn.click(); return 'clicked 评论 button'; }
-
-		// Editor exists but just needs focus
-		if (ed) { ed.click(); ed.focus(); return 'focused editor'; }
-
-		return 'no comment entry found';
-	`)
-	logger.Debug("[browser_comment_xiaohongshu] step1 activate: %s", r1)
+	info, err := page.Info()
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("step1 failed: %v", err)), nil
-	}
-	if r1 == "no comment entry found" {
-		return mcp.NewToolResultError("comment editor not found — make sure you are on a Xiaohongshu note detail page"), nil
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get page info: %v", err)), nil
 	}
-
-	// Wait for editor to appear/activate after clicking
-	if r1 != "editor already active" {
-		time.Sleep(500 * time.Millisecond)
+	matched := browser.MatchRecipes(browser.LoadRecipes(), info.URL)
+	if len(matched) == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("no site recipe matches %s", info.URL)), nil
 	}
-
-	// Step 1b: verify editor is present (poll up to 3s)
-	var editorReady bool
-	for range 15 {
-		check, _ := browser.ExecuteJS(page, `document.querySelector('#content-textarea') ? 'yes' : 'no'`)
-		if check == "yes" {
-			editorReady = true
+	recipe := matched[0]
+	for _, r := range matched {
+		if r.Name == site || (site == "" && r.Actions[action].Steps != nil) {
+			recipe = r
 			break
 		}
-		time.Sleep(200 * time.Millisecond)
-	}
-	if !editorReady {
-		return mcp.NewToolResultError("comment editor (#content-textarea) did not appear after activation"), nil
-	}
-
-	// Step 2: paste comment text via ClipboardEvent.
-	// Setting textContent or innerText directly does NOT update the framework state,
-	// so the 发送 button stays disabled. Dispatching a paste ClipboardEvent triggers
-	// the framework's internal handler which enables the button.
-	jsonComment, _ := json.Marshal(comment)
-	r2, err := browser.ExecuteJS(page, fmt.Sprintf(`
-		var ed = document.querySelector('#content-textarea');
-		if (!ed) { return 'editor not found'; }
-		ed.click();
-		ed.focus();
-		// Clear existing content first
-		ed.textContent = '';
-		ed.dispatchEvent(new Event('input', { bubbles: true }));
-		// Paste new content
-		var dt = new DataTransfer();
-		dt.setData('text/plain', %s);
-		ed.dispatchEvent(new ClipboardEvent('paste', { clipboardData: dt, bubbles: true, cancelable: true }));
-		return 'pasted';
-	`, string(jsonComment)))
-	logger.Debug("[browser_comment_xiaohongshu] step2 paste: %s", r2)
-	if err != nil || r2 == "editor not found" {
-		return mcp.NewToolResultError(fmt.Sprintf("step2 paste failed: %v %s", err, r2)), nil
-	}
-
-	// Wait for framework to process the paste event
-	time.Sleep(600 * time.Millisecond)
-
-	// Step 3: click the 发送 submit button.
-	r3, err := browser.ExecuteJS(page, `
-		var btn = Array.from(document.querySelectorAll('button')).find(function(b) {
-			return b.textContent.trim() === '发送';
-		});
-		if (btn && !btn.disabled) { btn.click(); return 'submitted'; }
-		if (btn && btn.disabled) { return 'submit button is disabled (comment may be empty or framework did not register paste)'; }
-		return 'submit button not found';
-	`)
-	logger.Debug("[browser_comment_xiaohongshu] step3 submit: %s", r3)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("step3 submit failed: %v", err)), nil
 	}
 
-	if r3 == "submitted" {
-		return mcp.NewToolResultText(fmt.Sprintf("Comment posted successfully on Xiaohongshu: %q", comment)), nil
+	logger.Debug("[browser_site_action] %s/%s args=%v", recipe.Name, action, args)
+	result, err := recipe.RunAction(page, action, args)
+	if err != nil {
+		logger.Debug("[browser_site_action] failed: %v", err)
+		return mcp.NewToolResultError(fmt.Sprintf("%s/%s failed: %v", recipe.Name, action, err)), nil
 	}
-	return mcp.NewToolResultError(fmt.Sprintf("comment may not have submitted: %s (paste=%s)", r3, r2)), nil
+	s.Record(browser.MacroStep{Action: "site_action", Site: recipe.Name, SiteAction: action, Args: args})
+	return mcp.NewToolResultText(fmt.Sprintf("%s/%s: %s", recipe.Name, action, result)), nil
 }
 
-// visitedURLs tracks URLs that have been processed during iterative browser operations.
-var visitedURLs = struct {
-	sync.Mutex
-	urls map[string]bool
-}{urls: make(map[string]bool)}
-
 // BrowserVisited checks or marks a URL as visited. Used for iterative tasks
-// (e.g., commenting on all articles in search results) to skip already-processed pages.
-func BrowserVisited(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+// (e.g., commenting on all articles in search results) to skip already-processed
+// pages. The set is kept in SQLite per conversation and optional task name, so
+// a job interrupted by a restart picks up where it stopped.
+func BrowserVisited(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	action, _ := req.Params.Arguments["action"].(string)
+	task, _ := req.Params.Arguments["task"].(string)
+
+	store, err := taskstate.Default()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to open task state: %v", err)), nil
+	}
+	scope := taskScope(ctx)
 
 	switch action {
 	case "check":
@@ -811,11 +651,10 @@ func BrowserVisited(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolRe
 		if url == "" {
 			return mcp.NewToolResultError("url is required for check action"), nil
 		}
-		// Normalize: strip query params for Xiaohongshu note URLs
-		normalized := normalizeXHSURL(url)
-		visitedURLs.Lock()
-		visited := visitedURLs.urls[normalized]
-		visitedURLs.Unlock()
+		visited, err := store.IsVisited(scope, task, browser.NormalizeURL(browser.LoadRecipes(), url))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to check URL: %v", err)), nil
+		}
 		if visited {
 			return mcp.NewToolResultText("visited"), nil
 		}
@@ -826,29 +665,26 @@ func BrowserVisited(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolRe
 		if url == "" {
 			return mcp.NewToolResultError("url is required for mark action"), nil
 		}
-		normalized := normalizeXHSURL(url)
-		visitedURLs.Lock()
-		visitedURLs.urls[normalized] = true
-		count := len(visitedURLs.urls)
-		visitedURLs.Unlock()
+		count, err := store.MarkVisited(scope, task, browser.NormalizeURL(browser.LoadRecipes(), url))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to mark URL: %v", err)), nil
+		}
 		return mcp.NewToolResultText(fmt.Sprintf("marked as visited (total: %d)", count)), nil
 
 	case "list":
-		visitedURLs.Lock()
-		urls := make([]string, 0, len(visitedURLs.urls))
-		for u := range visitedURLs.urls {
-			urls = append(urls, u)
+		urls, err := store.Visited(scope, task)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to list URLs: %v", err)), nil
 		}
-		visitedURLs.Unlock()
 		if len(urls) == 0 {
 			return mcp.NewToolResultText("no visited URLs"), nil
 		}
 		return mcp.NewToolResultText(fmt.Sprintf("visited URLs (%d):\n%s", len(urls), strings.Join(urls, "\n"))), nil
 
 	case "clear":
-		visitedURLs.Lock()
-		visitedURLs.urls = make(map[string]bool)
-		visitedURLs.Unlock()
+		if err := store.ClearVisited(scope, task); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to clear URLs: %v", err)), nil
+		}
 		return mcp.NewToolResultText("cleared all visited URLs"), nil
 
 	default:
@@ -856,22 +692,8 @@ func BrowserVisited(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolRe
 	}
 }
 
-// normalizeXHSURL strips xsec_token and other query params from Xiaohongshu URLs
-// so the same note is recognized regardless of how it was navigated to.
-func normalizeXHSURL(rawURL string) string {
-	// Extract the note ID path for xiaohongshu.com URLs
-	if strings.Contains(rawURL, "xiaohongshu.com") {
-		// URLs look like: https://www.xiaohongshu.com/explore/697ec7e7000000002202d5cc?xsec_token=...
-		// or: https://www.xiaohongshu.com/search_result/697ec7e7000000002202d5cc?xsec_token=...
-		if idx := strings.Index(rawURL, "?"); idx != -1 {
-			return rawURL[:idx]
-		}
-	}
-	return rawURL
-}
-
 // BrowserExecuteJS runs JavaScript on the active page.
-func BrowserExecuteJS(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func BrowserExecuteJS(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	script, ok := req.Params.Arguments["script"].(string)
 	if !ok || script == "" {
 		return mcp.NewToolResultError("script is required"), nil
@@ -882,10 +704,15 @@ func BrowserExecuteJS(_ context.Context, req mcp.CallToolRequest) (*mcp.CallTool
 		return mcp.NewToolResultError(fmt.Sprintf("failed to start browser: %v", err)), nil
 	}
 
-	page, err := b.ActivePage()
+	s, err := b.SessionFor(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get browser session: %v", err)), nil
+	}
+	page, err := s.ActivePage()
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to get page: %v", err)), nil
 	}
+	page = page.Context(ctx)
 
 	logger.Debug("[browser_execute_js] script=%q", script)
 	result, err := browser.ExecuteJS(page, script)
@@ -894,12 +721,13 @@ func BrowserExecuteJS(_ context.Context, req mcp.CallToolRequest) (*mcp.CallTool
 		return mcp.NewToolResultError(fmt.Sprintf("JS error: %v", err)), nil
 	}
 
+	s.Record(browser.MacroStep{Action: "execute_js", Script: script})
 	logger.Debug("[browser_execute_js] done, result length=%d", len(result))
 	return mcp.NewToolResultText(result), nil
 }
 
 // BrowserClickAll clicks all elements matching a CSS selector with delay.
-func BrowserClickAll(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func BrowserClickAll(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	selector, ok := req.Params.Arguments["selector"].(string)
 	if !ok || selector == "" {
 		return mcp.NewToolResultError("selector is required (CSS selector)"), nil
@@ -911,14 +739,19 @@ func BrowserClickAll(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolR
 	}
 
 	b := browser.Instance()
-	page, err := b.ActivePage()
+	s, err := b.SessionFor(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get browser session: %v", err)), nil
+	}
+	page, err := s.ActivePage()
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to get page: %v", err)), nil
 	}
+	page = page.Context(ctx)
 
 	skipSelector := ""
-	if s, ok := req.Params.Arguments["skip_selector"].(string); ok {
-		skipSelector = s
+	if skip, ok := req.Params.Arguments["skip_selector"].(string); ok {
+		skipSelector = skip
 	}
 
 	logger.Debug("[browser_click_all] selector=%q skip=%q delay=%v", selector, skipSelector, delay)
@@ -928,18 +761,23 @@ func BrowserClickAll(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolR
 		return mcp.NewToolResultError(fmt.Sprintf("failed to click elements: %v", err)), nil
 	}
 
+	s.Record(browser.MacroStep{Action: "click_all", Selector: selector, Skip: skipSelector, DelayMS: int(delay / time.Millisecond)})
 	logger.Debug("[browser_click_all] clicked %d elements", count)
 	return mcp.NewToolResultText(fmt.Sprintf("Clicked %d elements matching %q", count, selector)), nil
 }
 
-// BrowserTabs lists all open tabs.
-func BrowserTabs(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+// BrowserTabs lists the open tabs of the calling session.
+func BrowserTabs(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	b := browser.Instance()
 	if err := b.EnsureRunning(); err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to start browser: %v", err)), nil
 	}
+	s, err := b.SessionFor(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get browser session: %v", err)), nil
+	}
 
-	pages, err := b.Rod().Pages()
+	pages, err := s.Pages()
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to list tabs: %v", err)), nil
 	}
@@ -968,18 +806,23 @@ func BrowserTabs(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult,
 }
 
 // BrowserTabOpen opens a new tab.
-func BrowserTabOpen(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func BrowserTabOpen(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	b := browser.Instance()
 	if err := b.EnsureRunning(); err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to start browser: %v", err)), nil
 	}
 
+	s, err := b.SessionFor(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get browser session: %v", err)), nil
+	}
+
 	url := "about:blank"
 	if u, ok := req.Params.Arguments["url"].(string); ok && u != "" {
 		url = u
 	}
 
-	page, err := b.Rod().Page(proto.TargetCreateTarget{URL: url, Background: true})
+	page, err := s.OpenPage(url)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to open tab: %v", err)), nil
 	}
@@ -995,7 +838,7 @@ func BrowserTabOpen(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolRe
 }
 
 // BrowserTabClose closes a tab by target ID or the active tab.
-func BrowserTabClose(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func BrowserTabClose(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	b := browser.Instance()
 	if err := b.EnsureRunning(); err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to start browser: %v", err)), nil
@@ -1006,7 +849,12 @@ func BrowserTabClose(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolR
 		targetID = t
 	}
 
-	pages, err := b.Rod().Pages()
+	s, err := b.SessionFor(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get browser session: %v", err)), nil
+	}
+
+	pages, err := s.Pages()
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to list tabs: %v", err)), nil
 	}
@@ -0,0 +1,265 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/pltanton/lingti-bot/internal/browser"
+	"github.com/pltanton/lingti-bot/internal/logger"
+)
+
+// Defaults for browser_download and browser_network.
+const (
+	defaultDownloadTimeout = 60 * time.Second
+	defaultNetworkWait     = 10 * time.Second
+	networkBodyMaxLen      = 8000 // longer bodies must be saved with save_to
+)
+
+// BrowserFilePaths returns the local paths a browser tool call reads or
+// writes, so callers can apply allowed_paths and disable_file_tools before
+// running it. Returns nil for calls that touch no local files.
+func BrowserFilePaths(name string, args map[string]any) []string {
+	switch name {
+	case "browser_upload":
+		return UploadPaths(args)
+	case "browser_download":
+		dir, _ := args["dir"].(string)
+		return []string{browser.DownloadDir(dir)}
+	case "browser_network", "browser_extract":
+		if p, _ := args["save_to"].(string); p != "" {
+			return []string{p}
+		}
+	}
+	return nil
+}
+
+// UploadPaths returns the files named by browser_upload's "paths" (array) or
+// "path" (string) argument, with ~ expanded.
+func UploadPaths(args map[string]any) []string {
+	var paths []string
+	if list, ok := args["paths"].([]any); ok {
+		for _, v := range list {
+			if p, ok := v.(string); ok && p != "" {
+				paths = append(paths, p)
+			}
+		}
+	}
+	if p, ok := args["path"].(string); ok && p != "" {
+		paths = append(paths, p)
+	}
+	for i, p := range paths {
+		paths[i] = expandPath(p)
+	}
+	return paths
+}
+
+// expandPath expands a leading ~ to the home directory.
+func expandPath(path string) string {
+	if len(path) > 0 && path[0] == '~' {
+		home, _ := os.UserHomeDir()
+		path = filepath.Join(home, path[1:])
+	}
+	return path
+}
+
+// BrowserUpload attaches local files to a file input (or an upload button
+// that opens a file chooser) identified by ref.
+func BrowserUpload(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ref, ok := req.Params.Arguments["ref"].(float64)
+	if !ok {
+		return mcp.NewToolResultError("ref is required (number)"), nil
+	}
+	paths := UploadPaths(req.Params.Arguments)
+	if len(paths) == 0 {
+		return mcp.NewToolResultError("paths is required"), nil
+	}
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("file not found: %s", p)), nil
+		}
+		if info.IsDir() {
+			return mcp.NewToolResultError(fmt.Sprintf("%s is a directory, not a file", p)), nil
+		}
+	}
+
+	b := browser.Instance()
+	s, err := b.SessionFor(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get browser session: %v", err)), nil
+	}
+	page, err := s.ActivePage()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get page: %v", err)), nil
+	}
+	page = page.Context(ctx)
+
+	logger.Debug("[browser_upload] ref=%d paths=%v", int(ref), paths)
+	if err := browser.Upload(page, s, int(ref), paths); err != nil {
+		logger.Debug("[browser_upload] failed: %v", err)
+		return mcp.NewToolResultError(fmt.Sprintf("failed to upload to ref %d: %v", int(ref), err)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Attached %d file(s) to [%d]: %s\n\nMost sites still need a submit or confirm click.", len(paths), int(ref), strings.Join(paths, ", "))), nil
+}
+
+// BrowserDownload clicks a ref or opens a URL and saves the file it
+// downloads.
+func BrowserDownload(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	path, err := DownloadFile(ctx, req.Params.Arguments)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(DownloadMessage(path)), nil
+}
+
+// DownloadFile runs a browser_download call and returns the saved file's
+// path. The agent uses it directly to send the file to the user.
+func DownloadFile(ctx context.Context, args map[string]any) (string, error) {
+	ref, hasRef := args["ref"].(float64)
+	url, _ := args["url"].(string)
+	if !hasRef && url == "" {
+		return "", fmt.Errorf("ref or url is required")
+	}
+	dir, _ := args["dir"].(string)
+	dir = browser.DownloadDir(dir)
+	timeout := defaultDownloadTimeout
+	if t, ok := args["timeout_sec"].(float64); ok && t > 0 {
+		timeout = time.Duration(t) * time.Second
+	}
+
+	b := browser.Instance()
+	if err := b.EnsureRunning(); err != nil {
+		return "", fmt.Errorf("failed to start browser: %w", err)
+	}
+	s, err := b.SessionFor(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get browser session: %w", err)
+	}
+	page, err := s.ActivePage()
+	if err != nil {
+		return "", fmt.Errorf("failed to get page: %w", err)
+	}
+	page = page.Context(ctx)
+
+	logger.Debug("[browser_download] ref=%v url=%q dir=%s", args["ref"], url, dir)
+	path, err := s.Download(ctx, dir, timeout, func() error {
+		if hasRef {
+			return browser.Click(page, s, int(ref))
+		}
+		// Navigating to a download is reported as aborted; the download
+		// itself still starts, so the error is not useful here.
+		_ = page.Navigate(url)
+		return nil
+	})
+	if err != nil {
+		logger.Debug("[browser_download] failed: %v", err)
+		return "", fmt.Errorf("download failed: %w", err)
+	}
+	logger.Debug("[browser_download] saved %s", path)
+	return path, nil
+}
+
+// DownloadMessage describes a finished download as a tool result.
+func DownloadMessage(path string) string {
+	size := int64(0)
+	if info, err := os.Stat(path); err == nil {
+		size = info.Size()
+	}
+	return fmt.Sprintf("Downloaded %s (%d bytes)", path, size)
+}
+
+// BrowserNetwork records XHR/fetch responses of the current page so their
+// bodies can be read, e.g. the JSON behind a report table.
+func BrowserNetwork(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	action, _ := req.Params.Arguments["action"].(string)
+
+	b := browser.Instance()
+	if err := b.EnsureRunning(); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to start browser: %v", err)), nil
+	}
+	s, err := b.SessionFor(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get browser session: %v", err)), nil
+	}
+
+	switch action {
+	case "start":
+		pattern, _ := req.Params.Arguments["url_pattern"].(string)
+		page, err := s.ActivePage()
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get page: %v", err)), nil
+		}
+		if err := s.StartCapture(page, pattern); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to start capture: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Recording XHR/fetch responses matching %q on the current tab. Trigger the requests, then use action=wait or list.", pattern)), nil
+
+	case "stop":
+		s.StopCapture()
+		return mcp.NewToolResultText("Network capture stopped"), nil
+
+	case "list", "wait":
+		entries, ok := s.Captured()
+		if !ok {
+			return mcp.NewToolResultError("no network capture running (use action=start first)"), nil
+		}
+		if action == "wait" {
+			timeout := defaultNetworkWait
+			if t, ok := req.Params.Arguments["timeout_sec"].(float64); ok && t > 0 {
+				timeout = time.Duration(t) * time.Second
+			}
+			if entries, err = s.WaitCaptured(ctx, len(entries), timeout); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+		}
+		if len(entries) == 0 {
+			return mcp.NewToolResultText("no matching responses yet"), nil
+		}
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "captured responses (%d):", len(entries))
+		for i, e := range entries {
+			fmt.Fprintf(&sb, "\n%d. %s %s → %d %s (%d bytes)", i+1, e.Method, e.URL, e.Status, e.MIMEType, len(e.Body))
+		}
+		sb.WriteString("\n\nUse action=get with index to read a body.")
+		return mcp.NewToolResultText(sb.String()), nil
+
+	case "get":
+		entries, ok := s.Captured()
+		if !ok {
+			return mcp.NewToolResultError("no network capture running (use action=start first)"), nil
+		}
+		index, _ := req.Params.Arguments["index"].(float64)
+		if int(index) < 1 || int(index) > len(entries) {
+			return mcp.NewToolResultError(fmt.Sprintf("index must be between 1 and %d", len(entries))), nil
+		}
+		e := entries[int(index)-1]
+		header := fmt.Sprintf("%s %s → %d %s", e.Method, e.URL, e.Status, e.MIMEType)
+		if e.Truncated {
+			header += " (body cut at 1 MiB)"
+		}
+
+		if saveTo, _ := req.Params.Arguments["save_to"].(string); saveTo != "" {
+			saveTo = expandPath(saveTo)
+			if err := os.WriteFile(saveTo, []byte(e.Body), 0644); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to save body: %v", err)), nil
+			}
+			return mcp.NewToolResultText(fmt.Sprintf("%s\nBody saved to %s (%d bytes)", header, saveTo, len(e.Body))), nil
+		}
+		body := e.Body
+		if len(body) > networkBodyMaxLen {
+			body = body[:networkBodyMaxLen] + fmt.Sprintf("\n... (truncated, %d bytes total — use save_to for the full body)", len(e.Body))
+		}
+		if e.RequestBody != "" {
+			header += "\nRequest body: " + e.RequestBody
+		}
+		return mcp.NewToolResultText(header + "\n\n" + body), nil
+
+	default:
+		return mcp.NewToolResultError("action must be one of: start, wait, list, get, stop"), nil
+	}
+}
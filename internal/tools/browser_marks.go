@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/pltanton/lingti-bot/internal/browser"
+	"github.com/pltanton/lingti-bot/internal/logger"
+)
+
+// BrowserMarks screenshots the viewport with a numbered box over each
+// clickable element, for models that can look at images. It is the fallback
+// for canvas apps and unlabelled pages whose accessibility snapshot is empty.
+func BrowserMarks(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	b := browser.Instance()
+	if err := b.EnsureRunning(); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to start browser: %v", err)), nil
+	}
+	s, err := b.SessionFor(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get browser session: %v", err)), nil
+	}
+	page, err := s.ActivePage()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get page: %v", err)), nil
+	}
+	page = page.Context(ctx)
+
+	marks, shot, err := s.Marks(page)
+	if err != nil {
+		logger.Debug("[browser_marks] failed: %v", err)
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	logger.Debug("[browser_marks] %d marks, %d bytes", len(marks), len(shot))
+
+	header := ""
+	if info, _ := page.Info(); info != nil {
+		header = fmt.Sprintf("URL: %s\nTitle: %s\n", info.URL, info.Title)
+	}
+	text := fmt.Sprintf("%sMarks: %d (red numbered boxes in the screenshot; positions are screenshot pixels)\n\n%s\n"+
+		"Use browser_click_mark with a mark number, or browser_click_xy with screenshot coordinates for spots without a mark.",
+		header, len(marks), browser.FormatMarks(marks, s.MarkScale()))
+	return mcp.NewToolResultImage(text, base64.StdEncoding.EncodeToString(shot), "image/jpeg"), nil
+}
+
+// BrowserClickMark clicks an element by its mark number from browser_marks.
+func BrowserClickMark(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	number, ok := req.Params.Arguments["mark"].(float64)
+	if !ok {
+		return mcp.NewToolResultError("mark is required (number)"), nil
+	}
+
+	logger.Debug("[browser_click_mark] mark=%d", int(number))
+	s, page, errResult := markSession(ctx)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	tabsBefore := s.PageCount()
+	mark, loc, err := s.ClickMark(page, int(number))
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to click mark %d: %v", int(number), err)), nil
+	}
+	if loc != nil {
+		s.Record(browser.MacroStep{Action: "click", Target: loc})
+	}
+
+	msg := fmt.Sprintf("Clicked mark [%d] %s", mark.Number, mark.Tag)
+	if mark.Label != "" {
+		msg += fmt.Sprintf(" %q", mark.Label)
+	}
+	return mcp.NewToolResultText(msg + afterVisualClick(s, tabsBefore)), nil
+}
+
+// BrowserClickXY clicks a point given in pixels of the last browser_marks
+// screenshot (CSS pixels if there was none).
+func BrowserClickXY(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	x, okX := req.Params.Arguments["x"].(float64)
+	y, okY := req.Params.Arguments["y"].(float64)
+	if !okX || !okY {
+		return mcp.NewToolResultError("x and y are required (numbers)"), nil
+	}
+
+	logger.Debug("[browser_click_xy] x=%.0f y=%.0f", x, y)
+	s, page, errResult := markSession(ctx)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	tabsBefore := s.PageCount()
+	scale := s.MarkScale()
+	if err := browser.ClickAt(page, x/scale, y/scale); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to click: %v", err)), nil
+	}
+	msg := fmt.Sprintf("Clicked at %.0f,%.0f", x, y)
+	return mcp.NewToolResultText(msg + afterVisualClick(s, tabsBefore)), nil
+}
+
+// markSession returns the calling conversation's session and active page.
+func markSession(ctx context.Context) (*browser.Session, *rod.Page, *mcp.CallToolResult) {
+	s, err := browser.Instance().SessionFor(ctx)
+	if err != nil {
+		return nil, nil, mcp.NewToolResultError(fmt.Sprintf("failed to get browser session: %v", err))
+	}
+	page, err := s.ActivePage()
+	if err != nil {
+		return nil, nil, mcp.NewToolResultError(fmt.Sprintf("failed to get page: %v", err))
+	}
+	return s, page.Context(ctx), nil
+}
+
+// afterVisualClick follows a new tab if the click opened one and tells the
+// model how to see the result.
+func afterVisualClick(s *browser.Session, tabsBefore int) string {
+	msg := ""
+	time.Sleep(500 * time.Millisecond)
+	if s.SwitchToNewestPage(tabsBefore) {
+		msg += "\n\n⚠ A new tab was opened by this click. Bot is now tracking the new tab."
+	}
+	if page, _ := s.ActivePage(); page != nil {
+		if info, _ := page.Info(); info != nil {
+			msg += fmt.Sprintf("\n\nNow on: %s\nTitle: %s", info.URL, info.Title)
+		}
+	}
+	return msg + "\n\nCall browser_marks (or browser_snapshot) to see the result; marks go stale once the page changes."
+}
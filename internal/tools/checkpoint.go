@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/pltanton/lingti-bot/internal/router"
+	"github.com/pltanton/lingti-bot/internal/taskstate"
+)
+
+// taskScope returns the scope task state is kept under: the calling
+// conversation, or a shared default for MCP clients.
+func taskScope(ctx context.Context) string {
+	if key := router.ConversationKeyFromContext(ctx); key != "" {
+		return key
+	}
+	return "default"
+}
+
+// TaskCheckpoint saves and restores the progress of long multi-step tasks so
+// they can resume after a crash or restart.
+func TaskCheckpoint(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	action, _ := req.Params.Arguments["action"].(string)
+	task, _ := req.Params.Arguments["task"].(string)
+	if task == "" && action != "list" {
+		return mcp.NewToolResultError("task is required"), nil
+	}
+
+	store, err := taskstate.Default()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to open task state: %v", err)), nil
+	}
+	scope := taskScope(ctx)
+
+	switch action {
+	case "save":
+		data, _ := req.Params.Arguments["data"].(string)
+		if data == "" {
+			return mcp.NewToolResultError("data is required for save action"), nil
+		}
+		if err := store.SaveCheckpoint(scope, task, data); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save checkpoint: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("checkpoint saved for %s", task)), nil
+
+	case "load":
+		cp, err := store.LoadCheckpoint(scope, task)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to load checkpoint: %v", err)), nil
+		}
+		if cp == nil {
+			return mcp.NewToolResultText(fmt.Sprintf("no checkpoint for %s — start from the beginning", task)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("checkpoint for %s (saved %s):\n%s", task, cp.UpdatedAt.Format(time.DateTime), cp.Data)), nil
+
+	case "clear":
+		if err := store.DeleteCheckpoint(scope, task); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to clear checkpoint: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("checkpoint cleared for %s", task)), nil
+
+	case "list":
+		cps, err := store.Checkpoints(scope)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to list checkpoints: %v", err)), nil
+		}
+		if len(cps) == 0 {
+			return mcp.NewToolResultText("no checkpoints"), nil
+		}
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "checkpoints (%d):", len(cps))
+		for _, cp := range cps {
+			fmt.Fprintf(&sb, "\n- %s (saved %s)", cp.Task, cp.UpdatedAt.Format(time.DateTime))
+		}
+		return mcp.NewToolResultText(sb.String()), nil
+
+	default:
+		return mcp.NewToolResultError("action must be one of: save, load, clear, list"), nil
+	}
+}
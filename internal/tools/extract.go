@@ -0,0 +1,603 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/pltanton/lingti-bot/internal/browser"
+	"github.com/pltanton/lingti-bot/internal/logger"
+)
+
+// Limits for browser_extract.
+const (
+	extractMaxPages   = 50
+	extractPagedPages = 5       // default max_pages when pagination is requested
+	extractFetchLimit = 2 << 20 // bytes read per fetched page
+	extractMaxLen     = 8000    // longer results must be saved with save_to
+)
+
+// roleSelectors maps ARIA roles to the elements that have them implicitly
+// or explicitly, for "role:<name>" field specs.
+var roleSelectors = map[string]string{
+	"link":         "a[href], [role=link]",
+	"button":       "button, input[type=button], input[type=submit], [role=button]",
+	"heading":      "h1, h2, h3, h4, h5, h6, [role=heading]",
+	"img":          "img, [role=img]",
+	"cell":         "td, [role=cell], [role=gridcell]",
+	"columnheader": "th, [role=columnheader]",
+	"row":          "tr, [role=row]",
+	"listitem":     "li, [role=listitem]",
+	"textbox":      "input:not([type]), input[type=text], textarea, [role=textbox]",
+	"checkbox":     "input[type=checkbox], [role=checkbox]",
+	"time":         "time",
+}
+
+// extractField is one output column: where to find it within an item and
+// what JSON type to convert it to.
+type extractField struct {
+	Name     string
+	Selector string // CSS within the item; "" for the item itself
+	Attr     string // attribute to read instead of the text
+	Type     string // JSON schema type: string (default), number, integer, boolean
+	column   int    // table column index in table mode, -1 if unmatched
+}
+
+// extractSpec describes what to pull out of a page.
+type extractSpec struct {
+	Items  string // CSS selector of one record; "" for the largest table's rows
+	Fields []extractField
+}
+
+// extractResult is the rows found so far, with the column order.
+type extractResult struct {
+	Columns []string
+	Rows    []map[string]any
+	seen    map[string]bool
+	dedupe  []string
+}
+
+// parseFieldSpec splits "css@attr", "@attr", "role:link@href" or "css" into
+// a selector and attribute.
+func parseFieldSpec(spec string) (selector, attr string, err error) {
+	spec = strings.TrimSpace(spec)
+	if i := strings.LastIndex(spec, "@"); i >= 0 && !strings.ContainsAny(spec[i:], "]) ") {
+		spec, attr = strings.TrimSpace(spec[:i]), spec[i+1:]
+	}
+	if role, ok := strings.CutPrefix(spec, "role:"); ok {
+		sel, known := roleSelectors[role]
+		if !known {
+			return "", "", fmt.Errorf("unknown role %q", role)
+		}
+		spec = sel
+	}
+	if spec == "." {
+		spec = ""
+	}
+	return spec, attr, nil
+}
+
+// buildExtractSpec reads items, fields and schema from tool arguments.
+// Fields from schema without a selector are matched to table headers or,
+// in item mode, to itemprop/data-field/class names.
+func buildExtractSpec(args map[string]any) (*extractSpec, error) {
+	spec := &extractSpec{}
+	spec.Items, _ = args["items"].(string)
+
+	types := make(map[string]string)
+	specs := make(map[string]string)
+	if schema, ok := args["schema"].(map[string]any); ok {
+		if items, ok := schema["items"].(map[string]any); ok && schema["type"] == "array" {
+			schema = items
+		}
+		props, _ := schema["properties"].(map[string]any)
+		if len(props) == 0 {
+			return nil, fmt.Errorf("schema needs properties")
+		}
+		for name, p := range props {
+			prop, _ := p.(map[string]any)
+			t, _ := prop["type"].(string)
+			types[name] = t
+			if sel, ok := prop["selector"].(string); ok {
+				specs[name] = sel
+			} else {
+				specs[name] = ""
+			}
+		}
+	}
+	if fields, ok := args["fields"].(map[string]any); ok {
+		for name, v := range fields {
+			sel, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("field %s: selector must be a string", name)
+			}
+			specs[name] = sel
+		}
+	}
+
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f := extractField{Name: name, Type: types[name], column: -1}
+		if specs[name] != "" {
+			sel, attr, err := parseFieldSpec(specs[name])
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", name, err)
+			}
+			f.Selector, f.Attr = sel, attr
+		} else if spec.Items != "" {
+			q := cssString(name)
+			f.Selector = fmt.Sprintf("[itemprop=%s], [data-field=%s], .%s, [name=%s]", q, q, cssIdent(name), q)
+		}
+		spec.Fields = append(spec.Fields, f)
+	}
+	return spec, nil
+}
+
+// cssString quotes a name as a CSS string.
+func cssString(name string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name) + `"`
+}
+
+// cssIdent escapes a name for use as a CSS class selector.
+func cssIdent(name string) string {
+	var sb strings.Builder
+	for i, r := range name {
+		if r == '-' || r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r > 127 || (i > 0 && r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		} else {
+			fmt.Fprintf(&sb, "\\%x ", r)
+		}
+	}
+	return sb.String()
+}
+
+// newExtractResult starts an empty result deduplicating on the given
+// fields (all fields if none).
+func newExtractResult(dedupe []string) *extractResult {
+	return &extractResult{seen: make(map[string]bool), dedupe: dedupe}
+}
+
+// add appends row unless an equal row was seen. Returns true if added.
+func (r *extractResult) add(row map[string]any) bool {
+	keyFields := r.dedupe
+	if len(keyFields) == 0 {
+		keyFields = r.Columns
+	}
+	key := make([]any, len(keyFields))
+	empty := true
+	for i, f := range keyFields {
+		key[i] = row[f]
+		if row[f] != nil && row[f] != "" {
+			empty = false
+		}
+	}
+	if empty {
+		return false
+	}
+	data, _ := json.Marshal(key)
+	if r.seen[string(data)] {
+		return false
+	}
+	r.seen[string(data)] = true
+	r.Rows = append(r.Rows, row)
+	return true
+}
+
+// extractHTML adds the records in an HTML document to result and returns how
+// many were new. baseURL resolves relative href/src attributes.
+func extractHTML(html, baseURL string, spec *extractSpec, result *extractResult) (int, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	base, _ := url.Parse(baseURL)
+
+	if spec.Items == "" {
+		return extractTable(doc, base, spec, result)
+	}
+
+	items := doc.Find(spec.Items)
+	if items.Length() == 0 {
+		return 0, nil
+	}
+	if result.Columns == nil {
+		for _, f := range spec.Fields {
+			result.Columns = append(result.Columns, f.Name)
+		}
+		if len(result.Columns) == 0 {
+			result.Columns = []string{"text"}
+		}
+	}
+	added := 0
+	items.Each(func(_ int, item *goquery.Selection) {
+		row := make(map[string]any)
+		if len(spec.Fields) == 0 {
+			row["text"] = cleanText(item.Text())
+		}
+		for _, f := range spec.Fields {
+			sel := item
+			if f.Selector != "" {
+				sel = item.Find(f.Selector).First()
+				if sel.Length() == 0 && item.Is(f.Selector) {
+					sel = item
+				}
+			}
+			row[f.Name] = fieldValue(sel, f, base)
+		}
+		if result.add(row) {
+			added++
+		}
+	})
+	return added, nil
+}
+
+// extractTable reads the rows of the page's largest table, mapping fields to
+// columns by header text. Without fields, every column is returned.
+func extractTable(doc *goquery.Document, base *url.URL, spec *extractSpec, result *extractResult) (int, error) {
+	var table *goquery.Selection
+	most := 0
+	doc.Find("table").Each(func(_ int, t *goquery.Selection) {
+		if n := t.Find("tr").Length(); n > most {
+			table, most = t, n
+		}
+	})
+	if table == nil {
+		return 0, fmt.Errorf("no table on the page; pass items (a CSS selector for one record)")
+	}
+
+	var headers []string
+	table.Find("tr").EachWithBreak(func(_ int, tr *goquery.Selection) bool {
+		if ths := tr.Find("th"); ths.Length() > 0 {
+			ths.Each(func(_ int, th *goquery.Selection) { headers = append(headers, cleanText(th.Text())) })
+			return false
+		}
+		return true
+	})
+
+	fields := spec.Fields
+	if len(fields) == 0 {
+		n := len(headers)
+		if n == 0 {
+			n = table.Find("tr").First().Find("td").Length()
+		}
+		for i := 0; i < n; i++ {
+			name := fmt.Sprintf("col%d", i+1)
+			if i < len(headers) && headers[i] != "" {
+				name = headers[i]
+			}
+			fields = append(fields, extractField{Name: name, column: i})
+		}
+	} else {
+		fields = append([]extractField(nil), fields...)
+		for i := range fields {
+			if fields[i].Selector == "" {
+				fields[i].column = matchHeader(headers, fields[i].Name)
+			}
+		}
+	}
+	if result.Columns == nil {
+		for _, f := range fields {
+			result.Columns = append(result.Columns, f.Name)
+		}
+	}
+
+	added := 0
+	table.Find("tr").Each(func(_ int, tr *goquery.Selection) {
+		cells := tr.Find("td")
+		if cells.Length() == 0 {
+			return // header row
+		}
+		row := make(map[string]any)
+		for _, f := range fields {
+			switch {
+			case f.Selector != "":
+				row[f.Name] = fieldValue(tr.Find(f.Selector).First(), f, base)
+			case f.column >= 0 && f.column < cells.Length():
+				row[f.Name] = fieldValue(cells.Eq(f.column), f, base)
+			default:
+				row[f.Name] = nil
+			}
+		}
+		if result.add(row) {
+			added++
+		}
+	})
+	return added, nil
+}
+
+// matchHeader returns the index of the header matching name (exact, then
+// substring, case-insensitive), or -1.
+func matchHeader(headers []string, name string) int {
+	want := strings.ToLower(strings.ReplaceAll(name, "_", " "))
+	for i, h := range headers {
+		if strings.ToLower(h) == want {
+			return i
+		}
+	}
+	for i, h := range headers {
+		if h != "" && (strings.Contains(strings.ToLower(h), want) || strings.Contains(want, strings.ToLower(h))) {
+			return i
+		}
+	}
+	return -1
+}
+
+// fieldValue reads a field from sel and converts it to the field's type.
+// Missing elements and unparsable values yield nil.
+func fieldValue(sel *goquery.Selection, f extractField, base *url.URL) any {
+	if sel.Length() == 0 {
+		return nil
+	}
+	var raw string
+	if f.Attr != "" {
+		v, ok := sel.Attr(f.Attr)
+		if !ok {
+			return nil
+		}
+		raw = strings.TrimSpace(v)
+		if (f.Attr == "href" || f.Attr == "src") && base != nil {
+			if u, err := base.Parse(raw); err == nil {
+				raw = u.String()
+			}
+		}
+	} else {
+		raw = cleanText(sel.Text())
+	}
+
+	switch f.Type {
+	case "number", "integer":
+		n, err := strconv.ParseFloat(numericPart(raw), 64)
+		if err != nil {
+			return nil
+		}
+		if f.Type == "integer" {
+			return int64(n)
+		}
+		return n
+	case "boolean":
+		switch strings.ToLower(raw) {
+		case "true", "yes", "y", "1", "✓", "✔", "是":
+			return true
+		case "false", "no", "n", "0", "✗", "✘", "否", "":
+			return false
+		}
+		return nil
+	}
+	return raw
+}
+
+// numericPart strips currency signs, thousands separators and units from a
+// number like "¥1,234.50 元".
+func numericPart(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9', r == '.':
+			sb.WriteRune(r)
+		case r == '-' && sb.Len() == 0:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func cleanText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// formatExtract renders rows as a JSON array or CSV with a header line.
+func formatExtract(result *extractResult, format string) (string, error) {
+	if format == "csv" {
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		_ = w.Write(result.Columns)
+		for _, row := range result.Rows {
+			rec := make([]string, len(result.Columns))
+			for i, c := range result.Columns {
+				if v := row[c]; v != nil {
+					rec[i] = fmt.Sprint(v)
+				}
+			}
+			_ = w.Write(rec)
+		}
+		w.Flush()
+		return buf.String(), w.Error()
+	}
+	rows := result.Rows
+	if rows == nil {
+		rows = []map[string]any{}
+	}
+	data, err := json.MarshalIndent(rows, "", "  ")
+	return string(data), err
+}
+
+// BrowserExtract pulls structured rows out of the current browser page, a
+// URL fetched without the browser, or raw HTML, following pagination.
+func BrowserExtract(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	spec, err := buildExtractSpec(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var dedupe []string
+	if list, ok := args["dedupe_by"].([]any); ok {
+		for _, v := range list {
+			if s, ok := v.(string); ok {
+				dedupe = append(dedupe, s)
+			}
+		}
+	} else if s, ok := args["dedupe_by"].(string); ok && s != "" {
+		dedupe = strings.Split(s, ",")
+	}
+	result := newExtractResult(dedupe)
+
+	nextRef, hasNextRef := args["next_ref"].(float64)
+	nextSelector, _ := args["next_selector"].(string)
+	scroll, _ := args["scroll"].(bool)
+	maxPages := 1
+	if hasNextRef || nextSelector != "" || scroll {
+		maxPages = extractPagedPages
+	}
+	if n, ok := args["max_pages"].(float64); ok && n >= 1 {
+		maxPages = min(int(n), extractMaxPages)
+	}
+	limit := 0
+	if n, ok := args["limit"].(float64); ok && n > 0 {
+		limit = int(n)
+	}
+
+	rawHTML, _ := args["html"].(string)
+	pageURL, _ := args["url"].(string)
+	var pages int
+	switch {
+	case rawHTML != "":
+		pages = 1
+		_, err = extractHTML(rawHTML, pageURL, spec, result)
+	case pageURL != "":
+		pages, err = extractFetched(ctx, pageURL, nextSelector, maxPages, limit, spec, result)
+	default:
+		var next *browser.Locator
+		if nextSelector != "" {
+			next = &browser.Locator{Selector: nextSelector}
+		}
+		pages, err = extractBrowser(ctx, hasNextRef, int(nextRef), next, scroll, maxPages, limit, spec, result)
+	}
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if limit > 0 && len(result.Rows) > limit {
+		result.Rows = result.Rows[:limit]
+	}
+
+	format, _ := args["format"].(string)
+	out, err := formatExtract(result, format)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to format rows: %v", err)), nil
+	}
+	summary := fmt.Sprintf("Extracted %d row(s) from %d page(s)", len(result.Rows), pages)
+	logger.Debug("[browser_extract] %s", summary)
+
+	if saveTo, _ := args["save_to"].(string); saveTo != "" {
+		saveTo = expandPath(saveTo)
+		if err := os.WriteFile(saveTo, []byte(out), 0644); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("%s, saved to %s", summary, saveTo)), nil
+	}
+	if len(out) > extractMaxLen {
+		out = out[:extractMaxLen] + fmt.Sprintf("\n... (truncated, %d bytes total — use save_to for all rows)", len(out))
+	}
+	return mcp.NewToolResultText(summary + ":\n" + out), nil
+}
+
+// extractFetched extracts from pageURL over plain HTTP, following the href
+// of nextSelector for up to maxPages pages.
+func extractFetched(ctx context.Context, pageURL, nextSelector string, maxPages, limit int, spec *extractSpec, result *extractResult) (int, error) {
+	pages := 0
+	visited := make(map[string]bool)
+	for pageURL != "" && pages < maxPages && !visited[pageURL] {
+		visited[pageURL] = true
+		page, err := fetchURL(ctx, pageURL, extractFetchLimit)
+		if err != nil {
+			return pages, err
+		}
+		pages++
+		added, err := extractHTML(string(page.Body), page.URL, spec, result)
+		if err != nil {
+			return pages, err
+		}
+		if added == 0 || (limit > 0 && len(result.Rows) >= limit) || nextSelector == "" {
+			break
+		}
+
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page.Body))
+		if err != nil {
+			break
+		}
+		href, ok := doc.Find(nextSelector).First().Attr("href")
+		base, _ := url.Parse(page.URL)
+		if !ok || base == nil {
+			break
+		}
+		next, err := base.Parse(href)
+		if err != nil {
+			break
+		}
+		pageURL = next.String()
+	}
+	return pages, nil
+}
+
+// extractBrowser extracts from the session's current page, then clicks the
+// next-page control (next_ref or next_selector) or scrolls for more until a
+// page adds no new rows.
+func extractBrowser(ctx context.Context, hasNextRef bool, nextRef int, next *browser.Locator, scroll bool, maxPages, limit int, spec *extractSpec, result *extractResult) (int, error) {
+	b := browser.Instance()
+	if err := b.EnsureRunning(); err != nil {
+		return 0, fmt.Errorf("failed to start browser: %w", err)
+	}
+	s, err := b.SessionFor(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get browser session: %w", err)
+	}
+	page, err := s.ActivePage()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get page: %w", err)
+	}
+	page = page.Context(ctx)
+	if hasNextRef {
+		// Refs change with every page; follow the element by locator instead
+		if next = s.Locate(page, nextRef); next == nil {
+			return 0, fmt.Errorf("ref %d not found in snapshot (run browser_snapshot first)", nextRef)
+		}
+	}
+
+	pages := 0
+	for pages < maxPages {
+		html, err := page.HTML()
+		if err != nil {
+			return pages, fmt.Errorf("failed to read page: %w", err)
+		}
+		pageURL := ""
+		if info, err := page.Info(); err == nil {
+			pageURL = info.URL
+		}
+		pages++
+		added, err := extractHTML(html, pageURL, spec, result)
+		if err != nil {
+			return pages, err
+		}
+		if (added == 0 && pages > 1) || (limit > 0 && len(result.Rows) >= limit) || pages == maxPages {
+			break
+		}
+		if next == nil && !scroll {
+			break
+		}
+		more, err := browser.NextPage(ctx, page, next)
+		if err != nil {
+			return pages, fmt.Errorf("failed to go to page %d: %w", pages+1, err)
+		}
+		if !more {
+			break
+		}
+	}
+	if pages > 1 {
+		// Paging changed the page under any earlier snapshot
+		s.SetRefs(map[int]browser.RefEntry{})
+	}
+	return pages, nil
+}
@@ -0,0 +1,155 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const reportTable = `<html><body>
+<table><tr><td>layout</td></tr></table>
+<table>
+  <thead><tr><th>Product</th><th>Unit Price</th><th>In Stock</th></tr></thead>
+  <tbody>
+    <tr><td>Tea</td><td>¥1,200.50</td><td>yes</td></tr>
+    <tr><td>Coffee</td><td>¥35</td><td>no</td></tr>
+    <tr><td>Tea</td><td>¥1,200.50</td><td>yes</td></tr>
+  </tbody>
+</table></body></html>`
+
+func TestExtractHTML_Table(t *testing.T) {
+	spec, err := buildExtractSpec(map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := newExtractResult(nil)
+	if _, err := extractHTML(reportTable, "", spec, result); err != nil {
+		t.Fatalf("extractHTML: %v", err)
+	}
+	if got := strings.Join(result.Columns, ","); got != "Product,Unit Price,In Stock" {
+		t.Errorf("columns = %s", got)
+	}
+	if len(result.Rows) != 2 || result.Rows[1]["Product"] != "Coffee" {
+		t.Errorf("rows = %v (duplicate row should be dropped)", result.Rows)
+	}
+}
+
+func TestExtractHTML_SchemaTypes(t *testing.T) {
+	spec, err := buildExtractSpec(map[string]any{
+		"schema": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"properties": map[string]any{
+					"product":  map[string]any{"type": "string"},
+					"price":    map[string]any{"type": "number"},
+					"in_stock": map[string]any{"type": "boolean"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := newExtractResult(nil)
+	if _, err := extractHTML(reportTable, "", spec, result); err != nil {
+		t.Fatalf("extractHTML: %v", err)
+	}
+	if len(result.Rows) != 2 {
+		t.Fatalf("rows = %v", result.Rows)
+	}
+	row := result.Rows[0]
+	if row["product"] != "Tea" || row["price"] != 1200.5 || row["in_stock"] != true {
+		t.Errorf("row = %v", row)
+	}
+}
+
+func TestExtractHTML_Items(t *testing.T) {
+	html := `<ul>
+	  <li class="result"><h3><a href="/q/1">First  answer</a></h3><span class="votes">12 赞</span></li>
+	  <li class="result" data-id="2"><h3><a href="https://other.example/q/2">Second</a></h3></li>
+	  <li class="result"><h3><a href="/q/1">First answer</a></h3><span class="votes">12 赞</span></li>
+	</ul>`
+	spec, err := buildExtractSpec(map[string]any{
+		"items": "li.result",
+		"fields": map[string]any{
+			"title": "role:heading",
+			"url":   "h3 a@href",
+			"id":    "@data-id",
+		},
+		"schema": map[string]any{"properties": map[string]any{"votes": map[string]any{"type": "integer"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := newExtractResult([]string{"url"})
+	if _, err := extractHTML(html, "https://www.zhihu.com/search", spec, result); err != nil {
+		t.Fatalf("extractHTML: %v", err)
+	}
+	if len(result.Rows) != 2 {
+		t.Fatalf("rows = %v", result.Rows)
+	}
+	first, second := result.Rows[0], result.Rows[1]
+	if first["title"] != "First answer" || first["url"] != "https://www.zhihu.com/q/1" || first["votes"] != int64(12) || first["id"] != nil {
+		t.Errorf("first = %v", first)
+	}
+	if second["url"] != "https://other.example/q/2" || second["id"] != "2" || second["votes"] != nil {
+		t.Errorf("second = %v", second)
+	}
+
+	out, err := formatExtract(result, "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "id,title,url,votes\n,First answer,https://www.zhihu.com/q/1,12\n"; !strings.HasPrefix(out, want) {
+		t.Errorf("csv = %q", out)
+	}
+}
+
+func TestParseFieldSpec(t *testing.T) {
+	tests := []struct {
+		spec, selector, attr string
+	}{
+		{"h3 a", "h3 a", ""},
+		{"h3 a@href", "h3 a", "href"},
+		{"@data-id", "", "data-id"},
+		{".", "", ""},
+		{"role:link@href", roleSelectors["link"], "href"},
+		{"a[href*='@']", "a[href*='@']", ""},
+	}
+	for _, tt := range tests {
+		sel, attr, err := parseFieldSpec(tt.spec)
+		if err != nil || sel != tt.selector || attr != tt.attr {
+			t.Errorf("parseFieldSpec(%q) = %q, %q, %v", tt.spec, sel, attr, err)
+		}
+	}
+	if _, _, err := parseFieldSpec("role:banana"); err == nil {
+		t.Error("unknown role accepted")
+	}
+}
+
+func TestExtractFetched_Pagination(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		if page == "" {
+			page = "1"
+		}
+		fmt.Fprintf(w, `<div class="item">row %s-a</div><div class="item">row %s-b</div>`, page, page)
+		if page != "3" {
+			fmt.Fprintf(w, `<a class="next" href="?page=%c">next</a>`, page[0]+1)
+		}
+	}))
+	defer srv.Close()
+
+	spec, _ := buildExtractSpec(map[string]any{"items": ".item"})
+	result := newExtractResult(nil)
+	pages, err := extractFetched(context.Background(), srv.URL, "a.next", 10, 0, spec, result)
+	if err != nil {
+		t.Fatalf("extractFetched: %v", err)
+	}
+	if pages != 3 || len(result.Rows) != 6 || result.Rows[5]["text"] != "row 3-b" {
+		t.Errorf("pages = %d, rows = %v", pages, result.Rows)
+	}
+}
@@ -0,0 +1,133 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/pltanton/lingti-bot/internal/browser"
+	"github.com/pltanton/lingti-bot/internal/logger"
+)
+
+// BrowserMacroSave saves the browser actions recorded in the calling session
+// as a replayable macro and starts a new recording.
+func BrowserMacroSave(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, _ := req.Params.Arguments["name"].(string)
+	if name == "" {
+		return mcp.NewToolResultError("name is required"), nil
+	}
+	description, _ := req.Params.Arguments["description"].(string)
+
+	b := browser.Instance()
+	s, err := b.SessionFor(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get browser session: %v", err)), nil
+	}
+	steps := s.Trace()
+	if from, ok := req.Params.Arguments["from_step"].(float64); ok && from > 1 {
+		if int(from) > len(steps) {
+			return mcp.NewToolResultError(fmt.Sprintf("from_step %d is past the %d recorded steps", int(from), len(steps))), nil
+		}
+		steps = steps[int(from)-1:]
+	}
+	if len(steps) == 0 {
+		return mcp.NewToolResultError("no browser actions recorded yet — run the task with the browser tools first"), nil
+	}
+
+	m := &browser.Macro{Name: name, Description: description, Owner: browser.OwnerFromContext(ctx), CreatedAt: time.Now(), Steps: steps}
+	if err := browser.SaveMacro(m); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to save macro: %v", err)), nil
+	}
+	s.ClearTrace()
+
+	logger.Debug("[browser_macro_save] saved %s (%d steps)", name, len(steps))
+	return mcp.NewToolResultText(fmt.Sprintf("Saved macro %s with %d steps:\n%s", name, len(steps), formatSteps(steps, 1))), nil
+}
+
+// BrowserMacroRun replays a saved macro in the calling session.
+func BrowserMacroRun(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, _ := req.Params.Arguments["name"].(string)
+	if name == "" {
+		return mcp.NewToolResultError("name is required"), nil
+	}
+	result, err := RunMacro(ctx, name)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(result), nil
+}
+
+// BrowserMacroList lists the saved macros and the calling session's unsaved
+// recording.
+func BrowserMacroList(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var sb strings.Builder
+	macros := browser.ListMacros(browser.OwnerFromContext(ctx))
+	if len(macros) == 0 {
+		sb.WriteString("no saved macros")
+	} else {
+		fmt.Fprintf(&sb, "saved macros (%d):", len(macros))
+		for _, m := range macros {
+			fmt.Fprintf(&sb, "\n- %s (%d steps)", m.Name, len(m.Steps))
+			if m.Description != "" {
+				fmt.Fprintf(&sb, ": %s", m.Description)
+			}
+		}
+	}
+
+	if s, err := browser.Instance().SessionFor(ctx); err == nil {
+		if steps := s.Trace(); len(steps) > 0 {
+			fmt.Fprintf(&sb, "\n\nunsaved recording (%d steps):\n%s", len(steps), formatSteps(steps, 1))
+		}
+	}
+	return mcp.NewToolResultText(sb.String()), nil
+}
+
+// RunMacro replays the named macro in the browser session of ctx. When a step
+// fails the error lists the steps still to do, so the agent can finish the
+// task by hand. Used by browser_macro_run and scheduled macro jobs.
+func RunMacro(ctx context.Context, name string) (string, error) {
+	m, err := browser.LoadMacro(browser.OwnerFromContext(ctx), name)
+	if err != nil {
+		return "", err
+	}
+
+	b := browser.Instance()
+	if err := b.EnsureRunning(); err != nil {
+		return "", fmt.Errorf("failed to start browser: %w", err)
+	}
+	s, err := b.SessionFor(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get browser session: %w", err)
+	}
+
+	logger.Debug("[browser_macro_run] replaying %s (%d steps)", name, len(m.Steps))
+	if err := m.Replay(ctx, s); err != nil {
+		logger.Debug("[browser_macro_run] %v", err)
+		var macroErr *browser.MacroError
+		if !errors.As(err, &macroErr) {
+			return "", err
+		}
+		return "", fmt.Errorf("%w\n\nSteps not done yet:\n%s\n\nCall browser_snapshot and finish these steps with the browser tools.",
+			macroErr, formatSteps(macroErr.Remaining(), macroErr.Step))
+	}
+
+	msg := fmt.Sprintf("Replayed macro %s (%d steps)", name, len(m.Steps))
+	if page, err := s.ActivePage(); err == nil {
+		if info, err := page.Info(); err == nil {
+			msg += fmt.Sprintf("\nNow on: %s | Title: %s", info.URL, info.Title)
+		}
+	}
+	return msg, nil
+}
+
+// formatSteps renders macro steps as a numbered list starting at first.
+func formatSteps(steps []browser.MacroStep, first int) string {
+	lines := make([]string, len(steps))
+	for i, step := range steps {
+		lines[i] = fmt.Sprintf("%d. %s", first+i, step)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -134,42 +134,62 @@ func WebFetch(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult
 		return mcp.NewToolResultError("url is required"), nil
 	}
 
+	page, err := fetchURL(ctx, urlStr, 100*1024) // 100KB limit
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// For HTML, extract text content
+	content := string(page.Body)
+	if strings.Contains(page.ContentType, "text/html") {
+		content = extractTextFromHTML(content)
+	}
+
+	// Truncate if too long
+	if len(content) > 10000 {
+		content = content[:10000] + "\n... (truncated)"
+	}
+
+	return mcp.NewToolResultText(content), nil
+}
+
+// fetchedPage is the response to a fetchURL request.
+type fetchedPage struct {
+	URL         string // final URL after redirects
+	ContentType string
+	Body        []byte
+}
+
+// fetchURL GETs a URL (https:// is assumed without a scheme) and reads at
+// most limit bytes of the body.
+func fetchURL(ctx context.Context, urlStr string, limit int64) (*fetchedPage, error) {
 	// Ensure URL has scheme
 	if !strings.HasPrefix(urlStr, "http://") && !strings.HasPrefix(urlStr, "https://") {
 		urlStr = "https://" + urlStr
 	}
 
 	client := &http.Client{Timeout: 30 * time.Second}
-	req2, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("invalid URL: %v", err)), nil
+		return nil, fmt.Errorf("invalid URL: %v", err)
 	}
-	req2.Header.Set("User-Agent", "Mozilla/5.0 (compatible; LingtiBot/1.0)")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; LingtiBot/1.0)")
 
-	resp, err := client.Do(req2)
+	resp, err := client.Do(req)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("fetch failed: %v", err)), nil
+		return nil, fmt.Errorf("fetch failed: %v", err)
 	}
 	defer resp.Body.Close()
 
-	// Limit response size
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 100*1024)) // 100KB limit
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit))
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to read response: %v", err)), nil
-	}
-
-	// For HTML, extract text content
-	content := string(body)
-	if strings.Contains(resp.Header.Get("Content-Type"), "text/html") {
-		content = extractTextFromHTML(content)
+		return nil, fmt.Errorf("failed to read response: %v", err)
 	}
-
-	// Truncate if too long
-	if len(content) > 10000 {
-		content = content[:10000] + "\n... (truncated)"
-	}
-
-	return mcp.NewToolResultText(content), nil
+	return &fetchedPage{
+		URL:         resp.Request.URL.String(),
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        body,
+	}, nil
 }
 
 // extractTextFromHTML extracts readable text from HTML
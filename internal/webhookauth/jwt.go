@@ -0,0 +1,321 @@
+package webhookauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pltanton/lingti-bot/internal/logger"
+)
+
+// KeySource looks up the public key a token was signed with by key ID.
+type KeySource interface {
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// StaticKeys is a fixed KeySource, for tests and for pinning keys offline.
+type StaticKeys map[string]crypto.PublicKey
+
+// Key returns the key with the given ID.
+func (k StaticKeys) Key(_ context.Context, kid string) (crypto.PublicKey, error) {
+	key, ok := k[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+	}
+	return key, nil
+}
+
+// Defaults for JWKS caching.
+const (
+	DefaultJWKSTTL = 24 * time.Hour
+	// An unknown key ID triggers a refetch at most this often, so forged
+	// tokens cannot make us hammer the platform's key endpoint.
+	minJWKSRefresh = 5 * time.Minute
+)
+
+// JWKS is a KeySource backed by a JSON Web Key Set over HTTP. Keys are
+// cached for TTL and refetched early when a token names a key we do not
+// have, which is how platforms roll their keys.
+type JWKS struct {
+	// URL is the key set. MetadataURL is an OpenID configuration document
+	// whose jwks_uri is used when URL is empty.
+	URL         string
+	MetadataURL string
+	TTL         time.Duration // default: DefaultJWKSTTL
+	Client      *http.Client  // default: 30s timeout
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// NewJWKS returns a key source for the key set at url.
+func NewJWKS(url string) *JWKS {
+	return &JWKS{URL: url}
+}
+
+// NewOpenIDJWKS returns a key source for the key set named by an OpenID
+// configuration document.
+func NewOpenIDJWKS(metadataURL string) *JWKS {
+	return &JWKS{MetadataURL: metadataURL}
+}
+
+// Key returns the key with the given ID, fetching the key set if needed.
+func (j *JWKS) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	ttl := j.TTL
+	if ttl <= 0 {
+		ttl = DefaultJWKSTTL
+	}
+	age := time.Since(j.fetched)
+	_, known := j.keys[kid]
+	if j.keys == nil || age > ttl || (!known && age > minJWKSRefresh) {
+		keys, err := j.fetch(ctx)
+		switch {
+		case err == nil:
+			j.keys, j.fetched = keys, time.Now()
+		case j.keys == nil:
+			return nil, err
+		default:
+			// Keep serving the old keys rather than rejecting everything
+			// while the endpoint is down
+			logger.Warn("[Webhook] Failed to refresh JWKS, using cached keys: %v", err)
+		}
+	}
+
+	key, ok := j.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+	}
+	return key, nil
+}
+
+// fetch downloads and parses the key set.
+func (j *JWKS) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	url := j.URL
+	if url == "" {
+		var meta struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := j.getJSON(ctx, j.MetadataURL, &meta); err != nil {
+			return nil, fmt.Errorf("failed to fetch OpenID metadata: %w", err)
+		}
+		if meta.JWKSURI == "" {
+			return nil, fmt.Errorf("OpenID metadata at %s has no jwks_uri", j.MetadataURL)
+		}
+		url = meta.JWKSURI
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := j.getJSON(ctx, url, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no usable keys in JWKS at %s", url)
+	}
+	return keys, nil
+}
+
+func (j *JWKS) getJSON(ctx context.Context, url string, v any) error {
+	client := j.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: HTTP %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// jwk is one entry of a key set. Only RSA keys are used: both Bot
+// Framework and Google sign with RS256.
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func (k jwk) publicKey() (*rsa.PublicKey, error) {
+	if k.Kty != "RSA" {
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+}
+
+// Claims are the claims of a verified token.
+type Claims map[string]any
+
+// String returns a string claim, or "" if it is missing or not a string.
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// audiences returns the aud claim, which may be a string or a list.
+func (c Claims) audiences() []string {
+	switch aud := c["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []any:
+		var out []string
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// JWTVerifier checks RS256 bearer tokens against a key source.
+type JWTVerifier struct {
+	Keys     KeySource
+	Issuers  []string      // accepted iss values
+	Audience string        // required aud value
+	Leeway   time.Duration // allowed clock skew (default: 5 minutes)
+
+	now func() time.Time // for tests
+}
+
+// VerifyRequest verifies the bearer token in r's Authorization header.
+func (v *JWTVerifier) VerifyRequest(r *http.Request) (Claims, error) {
+	auth := r.Header.Get("Authorization")
+	token, ok := strings.CutPrefix(auth, "Bearer ")
+	if !ok || token == "" {
+		return nil, fmt.Errorf("%w: no bearer token", ErrMissingCredentials)
+	}
+	return v.Verify(r.Context(), token)
+}
+
+// Verify checks token's signature, issuer, audience and validity period
+// and returns its claims.
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: token does not have three parts", ErrMalformed)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrMalformed, err)
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("%w: algorithm %q not allowed", ErrBadSignature, header.Alg)
+	}
+
+	key, err := v.Keys.Key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: key %q is not an RSA key", ErrUnknownKey, header.Kid)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrMalformed, err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], sig); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadSignature, err)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrMalformed, err)
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (v *JWTVerifier) checkClaims(c Claims) error {
+	now := time.Now()
+	if v.now != nil {
+		now = v.now()
+	}
+	leeway := v.Leeway
+	if leeway <= 0 {
+		leeway = 5 * time.Minute
+	}
+
+	exp, ok := c["exp"].(float64)
+	if !ok {
+		return fmt.Errorf("%w: no exp claim", ErrMalformed)
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(leeway)) {
+		return fmt.Errorf("%w: expired at %s", ErrExpired, time.Unix(int64(exp), 0).UTC().Format(time.RFC3339))
+	}
+	if nbf, ok := c["nbf"].(float64); ok && now.Add(leeway).Before(time.Unix(int64(nbf), 0)) {
+		return fmt.Errorf("%w: not valid before %s", ErrExpired, time.Unix(int64(nbf), 0).UTC().Format(time.RFC3339))
+	}
+
+	iss := c.String("iss")
+	issuerOK := false
+	for _, want := range v.Issuers {
+		if iss == want {
+			issuerOK = true
+			break
+		}
+	}
+	if !issuerOK {
+		return fmt.Errorf("%w %q", ErrBadIssuer, iss)
+	}
+
+	for _, aud := range c.audiences() {
+		if aud == v.Audience {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w %v, want %q", ErrBadAudience, c.audiences(), v.Audience)
+}
+
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,150 @@
+package webhookauth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pltanton/lingti-bot/internal/webhookauth"
+	"github.com/pltanton/lingti-bot/internal/webhookauth/webhookauthtest"
+)
+
+func TestJWTVerifier(t *testing.T) {
+	signer := webhookauthtest.NewSigner(t)
+	other := webhookauthtest.NewSigner(t)
+	v := &webhookauth.JWTVerifier{
+		Keys:     signer.Keys(),
+		Issuers:  []string{"https://api.botframework.com"},
+		Audience: "app-id",
+	}
+	valid := map[string]any{"iss": "https://api.botframework.com", "aud": "app-id"}
+	with := func(k string, val any) map[string]any {
+		c := map[string]any{}
+		for key, v := range valid {
+			c[key] = v
+		}
+		c[k] = val
+		return c
+	}
+	hour := time.Hour
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"valid", signer.Token(t, valid), nil},
+		{"audience list", signer.Token(t, with("aud", []string{"other", "app-id"})), nil},
+		{"wrong key", other.Token(t, valid), webhookauth.ErrBadSignature},
+		{"wrong audience", signer.Token(t, with("aud", "someone-else")), webhookauth.ErrBadAudience},
+		{"wrong issuer", signer.Token(t, with("iss", "https://evil.example.com")), webhookauth.ErrBadIssuer},
+		{"expired", signer.Token(t, with("exp", time.Now().Add(-hour).Unix())), webhookauth.ErrExpired},
+		{"not yet valid", signer.Token(t, with("nbf", time.Now().Add(hour).Unix())), webhookauth.ErrExpired},
+		{"garbage", "not-a-jwt", webhookauth.ErrMalformed},
+		{"alg none", unsignedToken(valid), webhookauth.ErrBadSignature},
+	}
+	for _, tt := range tests {
+		_, err := v.Verify(context.Background(), tt.token)
+		if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	// Tampering with the claims breaks the signature
+	parts := strings.Split(signer.Token(t, valid), ".")
+	forged, _ := json.Marshal(with("aud", "app-id-2"))
+	parts[1] = base64.RawURLEncoding.EncodeToString(forged)
+	if _, err := v.Verify(context.Background(), strings.Join(parts, ".")); !errors.Is(err, webhookauth.ErrBadSignature) {
+		t.Errorf("tampered token: err = %v", err)
+	}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	signer := webhookauthtest.NewSigner(t)
+	v := &webhookauth.JWTVerifier{Keys: signer.Keys(), Issuers: []string{"iss"}, Audience: "aud"}
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	if _, err := v.VerifyRequest(req); !errors.Is(err, webhookauth.ErrMissingCredentials) {
+		t.Errorf("no header: err = %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+signer.Token(t, map[string]any{"iss": "iss", "aud": "aud", "serviceurl": "https://smba.example"}))
+	claims, err := v.VerifyRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := claims.String("serviceurl"); got != "https://smba.example" {
+		t.Errorf("serviceurl claim = %q", got)
+	}
+}
+
+func unsignedToken(claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "none", "kid": "test-key"})
+	payload, _ := json.Marshal(claims)
+	return base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload) + "."
+}
+
+func TestJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fetches atomic.Int32
+	mux := http.NewServeMux()
+	var srv *httptest.Server
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"jwks_uri": srv.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{"kty": "EC", "kid": "ec-key"},
+			{
+				"kty": "RSA",
+				"kid": "k1",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			},
+		}})
+	})
+	srv = httptest.NewServer(mux)
+	defer srv.Close()
+
+	j := webhookauth.NewOpenIDJWKS(srv.URL + "/.well-known/openid-configuration")
+	got, err := j.Key(context.Background(), "k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub, ok := got.(*rsa.PublicKey); !ok || !pub.Equal(&key.PublicKey) {
+		t.Fatalf("Key(k1) = %v", got)
+	}
+
+	// Cached: neither a known nor, right after a fetch, an unknown key ID
+	// goes back to the network
+	if _, err := j.Key(context.Background(), "k1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Key(context.Background(), "rolled"); !errors.Is(err, webhookauth.ErrUnknownKey) {
+		t.Errorf("unknown kid: err = %v", err)
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", n)
+	}
+
+	// Once stale, the set is fetched again; if that fails the cached keys
+	// are still served
+	j.TTL = time.Nanosecond
+	srv.Close()
+	if _, err := j.Key(context.Background(), "k1"); err != nil {
+		t.Errorf("cached key not served while the endpoint is down: %v", err)
+	}
+}
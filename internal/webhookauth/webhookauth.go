@@ -0,0 +1,123 @@
+// Package webhookauth checks that webhook requests really come from the
+// platform they claim to, and counts what it lets through and turns away.
+//
+// Platforms signing requests with a shared secret (WhatsApp, LINE, Zalo)
+// check the MAC themselves and report the outcome here; platforms sending a
+// signed JWT (Teams, Google Chat) verify it with a JWTVerifier.
+package webhookauth
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/pltanton/lingti-bot/internal/logger"
+)
+
+// Verification failures. Errors returned by this package wrap one of these,
+// and Reason maps them to the label used in metrics.
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrBadSignature       = errors.New("bad signature")
+	ErrUnknownKey         = errors.New("unknown signing key")
+	ErrExpired            = errors.New("token expired or not yet valid")
+	ErrBadIssuer          = errors.New("unexpected issuer")
+	ErrBadAudience        = errors.New("unexpected audience")
+	ErrMalformed          = errors.New("malformed credentials")
+)
+
+// Reason returns the metrics label for a verification error.
+func Reason(err error) string {
+	switch {
+	case errors.Is(err, ErrMissingCredentials):
+		return "missing"
+	case errors.Is(err, ErrBadSignature):
+		return "bad_signature"
+	case errors.Is(err, ErrUnknownKey):
+		return "unknown_key"
+	case errors.Is(err, ErrExpired):
+		return "expired"
+	case errors.Is(err, ErrBadIssuer):
+		return "issuer"
+	case errors.Is(err, ErrBadAudience):
+		return "audience"
+	case errors.Is(err, ErrMalformed):
+		return "malformed"
+	default:
+		return "error"
+	}
+}
+
+type counterKey struct {
+	platform string
+	result   string
+	reason   string
+}
+
+var (
+	countsMu sync.Mutex
+	counts   = make(map[counterKey]int64)
+)
+
+func count(k counterKey) {
+	countsMu.Lock()
+	counts[k]++
+	countsMu.Unlock()
+}
+
+// Accept records a request that passed verification.
+func Accept(platform string) {
+	count(counterKey{platform: platform, result: "accepted"})
+}
+
+// Reject records a request that failed verification, logs why and answers
+// it with status. The client only sees the status text, not the reason.
+func Reject(w http.ResponseWriter, r *http.Request, platform string, status int, err error) {
+	reason := Reason(err)
+	count(counterKey{platform: platform, result: "rejected", reason: reason})
+	logger.Warn("[%s] Rejected webhook from %s: %v", platform, r.RemoteAddr, err)
+	http.Error(w, http.StatusText(status), status)
+}
+
+// Count is one counter from Counts.
+type Count struct {
+	Platform string
+	Result   string // "accepted" or "rejected"
+	Reason   string // why a request was rejected, empty when accepted
+	Value    int64
+}
+
+// Counts returns every counter, sorted by platform, result and reason.
+func Counts() []Count {
+	countsMu.Lock()
+	out := make([]Count, 0, len(counts))
+	for k, v := range counts {
+		out = append(out, Count{Platform: k.platform, Result: k.result, Reason: k.reason, Value: v})
+	}
+	countsMu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Platform != b.Platform {
+			return a.Platform < b.Platform
+		}
+		if a.Result != b.Result {
+			return a.Result < b.Result
+		}
+		return a.Reason < b.Reason
+	})
+	return out
+}
+
+// WriteMetrics writes the counters in the Prometheus text format.
+func WriteMetrics(w io.Writer) {
+	fmt.Fprintln(w, "# HELP lingti_webhook_requests_total Webhook requests by verification result.")
+	fmt.Fprintln(w, "# TYPE lingti_webhook_requests_total counter")
+	for _, c := range Counts() {
+		fmt.Fprintf(w, "lingti_webhook_requests_total{platform=%q,result=%q,reason=%q} %d\n",
+			c.Platform, c.Result, c.Reason, c.Value)
+	}
+}
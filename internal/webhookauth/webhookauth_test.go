@@ -0,0 +1,51 @@
+package webhookauth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	countsMu.Lock()
+	counts = make(map[counterKey]int64)
+	countsMu.Unlock()
+
+	Accept("teams")
+	Accept("teams")
+	rec := httptest.NewRecorder()
+	Reject(rec, httptest.NewRequest(http.MethodPost, "/", nil), "whatsapp", http.StatusForbidden, fmt.Errorf("%w: mismatch", ErrBadSignature))
+	if rec.Code != http.StatusForbidden || strings.Contains(rec.Body.String(), "mismatch") {
+		t.Errorf("Reject answered %d %q", rec.Code, rec.Body.String())
+	}
+
+	var out strings.Builder
+	WriteMetrics(&out)
+	for _, want := range []string{
+		`lingti_webhook_requests_total{platform="teams",result="accepted",reason=""} 2`,
+		`lingti_webhook_requests_total{platform="whatsapp",result="rejected",reason="bad_signature"} 1`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("metrics missing %s:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestReason(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("%w: no bearer token", ErrMissingCredentials), "missing"},
+		{fmt.Errorf("%w \"k\"", ErrUnknownKey), "unknown_key"},
+		{ErrBadAudience, "audience"},
+		{fmt.Errorf("network down"), "error"},
+	}
+	for _, tt := range tests {
+		if got := Reason(tt.err); got != tt.want {
+			t.Errorf("Reason(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}